@@ -21,6 +21,7 @@ import (
 	"crypto/tls"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
@@ -30,13 +31,50 @@ import (
 	"github.com/grafeas/kritis/cmd/kritis/version"
 	"github.com/grafeas/kritis/pkg/kritis/admission"
 	"github.com/grafeas/kritis/pkg/kritis/admission/constants"
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/authz"
+	"github.com/grafeas/kritis/pkg/kritis/binauthz"
+	clientset "github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned"
+	"github.com/grafeas/kritis/pkg/kritis/crd/imagereview"
+	"github.com/grafeas/kritis/pkg/kritis/crd/informers"
 	"github.com/grafeas/kritis/pkg/kritis/crd/kritisconfig"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
 	"github.com/grafeas/kritis/pkg/kritis/cron"
+	"github.com/grafeas/kritis/pkg/kritis/decisionlog"
+	"github.com/grafeas/kritis/pkg/kritis/evaluation"
+	"github.com/grafeas/kritis/pkg/kritis/exemption"
+	"github.com/grafeas/kritis/pkg/kritis/faults"
+	"github.com/grafeas/kritis/pkg/kritis/gcpauth"
+	"github.com/grafeas/kritis/pkg/kritis/health"
 	kubernetesutil "github.com/grafeas/kritis/pkg/kritis/kubernetes"
+	"github.com/grafeas/kritis/pkg/kritis/leader"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/containeranalysis"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/file"
 	"github.com/grafeas/kritis/pkg/kritis/metadata/grafeas"
+	"github.com/grafeas/kritis/pkg/kritis/notify"
+	"github.com/grafeas/kritis/pkg/kritis/provenance"
+	"github.com/grafeas/kritis/pkg/kritis/records"
+	"github.com/grafeas/kritis/pkg/kritis/registryauth"
+	"github.com/grafeas/kritis/pkg/kritis/report"
+	"github.com/grafeas/kritis/pkg/kritis/review"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/tlsconfig"
+	"github.com/grafeas/kritis/pkg/kritis/tracing"
+	"github.com/grafeas/kritis/pkg/kritis/transparency"
+	"github.com/grafeas/kritis/pkg/kritis/violation"
+	"github.com/grafeas/kritis/pkg/kritis/webhookcert"
+	"github.com/grafeas/kritis/pkg/kritis/webhookpolicy"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/rest"
 )
 
 const (
@@ -44,6 +82,7 @@ const (
 	DefaultMetadataBackend = constants.ContainerAnalysisMetadata
 	DefaultCronInterval    = "1h"
 	DefaultServerAddr      = ":443"
+	DefaultHealthAddr      = ":8081"
 )
 
 var (
@@ -51,6 +90,8 @@ var (
 	tlsKeyFile  string
 	showVersion bool
 	runCron     bool
+	faultsPath  string
+	healthAddr  string
 )
 
 func main() {
@@ -58,6 +99,8 @@ func main() {
 	flag.StringVar(&tlsKeyFile, "tls-key-file", "/var/tls/tls.key", "TLS key file.")
 	flag.BoolVar(&showVersion, "version", false, "kritis-server version")
 	flag.BoolVar(&runCron, "run-cron", false, "Run cron job in foreground.")
+	flag.StringVar(&healthAddr, "health-addr", DefaultHealthAddr, "Address of the plain HTTP server of the health endpoints.")
+	flag.StringVar(&faultsPath, "fault-profile", "", "Fault injection profile. For testing fail-open/fail-closed behavior only, never set it in production.")
 	flag.Parse()
 	if err := flag.Set("logtostderr", "true"); err != nil {
 		glog.Fatal(errors.Wrap(err, "unable to set logtostderr"))
@@ -68,10 +111,30 @@ func main() {
 		return
 	}
 
+	if faultsPath != "" {
+		p, err := faults.Load(faultsPath)
+		if err != nil {
+			glog.Fatalf("failed to load fault profile: %v", err)
+		}
+		faults.Enable(p)
+	}
+
 	// Set the defaults that will be used if no KritisConfig is defined
 	metadataBackend := DefaultMetadataBackend
 	cronInterval := DefaultCronInterval
+	cronWorkers := cron.DefaultWorkers
+	leaderElection := kritisv1beta1.LeaderElectionSpec{}
+	complianceReport := kritisv1beta1.ComplianceReportSpec{}
+	violationRecords := kritisv1beta1.ViolationRecordsSpec{}
 	serverAddr := DefaultServerAddr
+	grpcAddr := ""
+	grpcReflection := false
+	tlsSpec := kritisv1beta1.TLSConfigSpec{}
+	tracingSpec := kritisv1beta1.TracingSpec{}
+	healthSpec := kritisv1beta1.HealthSpec{}
+	webhookFailurePolicies := kritisv1beta1.WebhookFailurePoliciesSpec{}
+	attestationLogPath := transparency.DefaultPath
+	attestationLogMaxRecords := 0
 
 	config := &admission.Config{
 		Metadata: metadataBackend,
@@ -93,9 +156,82 @@ func main() {
 		if kritisConfig.Spec.CronInterval != "" {
 			cronInterval = kritisConfig.Spec.CronInterval
 		}
+		if kritisConfig.Spec.CronWorkers > 0 {
+			cronWorkers = kritisConfig.Spec.CronWorkers
+		}
+		leaderElection = kritisConfig.Spec.LeaderElection
+		complianceReport = kritisConfig.Spec.ComplianceReport
+		violationRecords = kritisConfig.Spec.ViolationRecords
 		if kritisConfig.Spec.ServerAddr != "" {
 			serverAddr = kritisConfig.Spec.ServerAddr
 		}
+		grpcAddr = kritisConfig.Spec.GRPCAddr
+		grpcReflection = kritisConfig.Spec.GRPCReflection
+		tlsSpec = kritisConfig.Spec.TLS
+		tracingSpec = kritisConfig.Spec.Tracing
+		healthSpec = kritisConfig.Spec.Health
+		webhookFailurePolicies = kritisConfig.Spec.WebhookFailurePolicies
+		config.MetadataFile = kritisConfig.Spec.MetadataFile
+		config.VulnerabilityBundle = kritisConfig.Spec.VulnerabilityBundle
+		config.Azure = kritisConfig.Spec.Azure
+		config.Harbor = kritisConfig.Spec.Harbor
+		config.OSV = kritisConfig.Spec.OSV
+		if err := metadata.ValidateSkipMetadataKinds(kritisConfig.Spec.SkipMetadataKinds); err != nil {
+			glog.Fatal(err)
+		}
+		config.SkipMetadataKinds = kritisConfig.Spec.SkipMetadataKinds
+		if kritisConfig.Spec.MetadataTimeout != "" {
+			if config.MetadataTimeout, err = time.ParseDuration(kritisConfig.Spec.MetadataTimeout); err != nil {
+				glog.Fatalf("invalid metadataTimeout: %v", err)
+			}
+		}
+		if err := metadata.ValidateTimeoutFallback(kritisConfig.Spec.MetadataTimeoutFallback); err != nil {
+			glog.Fatal(err)
+		}
+		config.MetadataFallback = kritisConfig.Spec.MetadataTimeoutFallback
+		if err := metadata.ValidateFailurePolicy(kritisConfig.Spec.MetadataFailurePolicy); err != nil {
+			glog.Fatal(err)
+		}
+		config.FailurePolicy = kritisConfig.Spec.MetadataFailurePolicy
+		if config.RateLimiter, err = metadata.NewRateLimiter(kritisConfig.Spec.MetadataRateLimit); err != nil {
+			glog.Fatal(err)
+		}
+		if err := containeranalysis.Configure(kritisConfig.Spec.ContainerAnalysis); err != nil {
+			glog.Fatal(err)
+		}
+		if err := gcpauth.Configure(kritisConfig.Spec.GCPCredentials); err != nil {
+			glog.Fatal(err)
+		}
+		if err := secrets.Configure(kritisConfig.Spec); err != nil {
+			glog.Fatal(err)
+		}
+		if err := registryauth.Configure(kritisConfig.Spec.RegistryCredentials); err != nil {
+			glog.Fatalf("invalid registry credentials: %v", err)
+		}
+		if err := provenance.ConfigureSigstore(kritisConfig.Spec.Sigstore); err != nil {
+			glog.Fatalf("invalid sigstore trust roots: %v", err)
+		}
+		if err := provenance.ConfigureNotation(kritisConfig.Spec.Notation); err != nil {
+			glog.Fatalf("invalid notation trust store: %v", err)
+		}
+		config.AuditDecisions = kritisConfig.Spec.AuditDecisions
+		config.RecordImageReviews = kritisConfig.Spec.RecordImageReviews
+		if err := review.ValidatePromotions(kritisConfig.Spec.Promotions); err != nil {
+			glog.Fatal(err)
+		}
+		config.Promotions = kritisConfig.Spec.Promotions
+		config.AnnotateDecisions = kritisConfig.Spec.AnnotateDecisions
+		config.ReviewOnPolicyChange = kritisConfig.Spec.ReviewOnPolicyChange
+		if kritisConfig.Spec.AttestationLogPath != "" {
+			attestationLogPath = kritisConfig.Spec.AttestationLogPath
+		}
+		attestationLogMaxRecords = kritisConfig.Spec.AttestationLogMaxRecords
+		if bundle := config.VulnerabilityBundle; config.Metadata == constants.FileMetadata && (bundle.Path != "" || bundle.Image != "") {
+			if config.Bundle, err = file.NewBundle(bundle); err != nil {
+				glog.Fatalf("failed to load vulnerability bundle: %v", err)
+			}
+			go config.Bundle.Refresh(wait.NeverStop)
+		}
 		if config.Metadata == constants.GrafeasMetadata {
 			config.Grafeas = kritisConfig.Spec.Grafeas
 			if err := grafeas.ValidateConfig(config.Grafeas); err != nil {
@@ -104,6 +240,38 @@ func main() {
 		}
 	}
 
+	if kritisConfig != nil {
+		sender, err := notify.New(kritisConfig.Spec.Notifications)
+		if err != nil {
+			glog.Fatalf("invalid notifications: %v", err)
+		}
+		config.Notifier = notify.NewQueue(sender, notify.DefaultQueueSize)
+		if len(kritisConfig.Spec.ViolationStrategies) > 0 {
+			config.ViolationStrategy, err = violation.NewChain(kritisConfig.Spec.ViolationStrategies, config.Notifier)
+			if err != nil {
+				glog.Fatalf("invalid violationStrategies: %v", err)
+			}
+		}
+		config.DecisionLog, err = decisionlog.New(kritisConfig.Spec.DecisionLog)
+		if err != nil {
+			glog.Fatalf("invalid decision log: %v", err)
+		}
+		config.Exemptions, err = exemption.New(kritisConfig.Spec.Exemptions)
+		if err != nil {
+			glog.Fatalf("invalid exemptions: %v", err)
+		}
+	}
+
+	shutdownTracing, err := tracing.Init(tracingSpec)
+	if err != nil {
+		glog.Fatalf("failed to initialize tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			glog.Errorf("failed to flush traces: %v", err)
+		}
+	}()
+
 	// TODO: (tejaldesai) This is getting complicated. Use CLI Library.
 	if runCron {
 		cronConfig, err := getCronConfig(config)
@@ -115,44 +283,246 @@ func main() {
 		}
 		return
 	}
+	// Serve policies, authorities and the KritisConfig from informer caches.
+	if err := informers.Start(informers.DefaultResync, informers.DefaultSyncTimeout, wait.NeverStop); err != nil {
+		glog.Errorf("failed to start informers, reading resources from the API server: %v", err)
+	}
 	// Kick off back ground cron job.
-	if err := StartCronJob(config, cronInterval); err != nil {
+	elector, err := StartCronJob(config, cronInterval, cronWorkers, leaderElection, complianceReport, violationRecords)
+	if err != nil {
 		glog.Fatalf("failed to start background job: %v", err)
 	}
 
+	attestationLog, err := transparency.New(attestationLogPath, attestationLogMaxRecords)
+	if err != nil {
+		glog.Fatalf("failed to load attestation log: %v", err)
+	}
+	config.AttestationLog = attestationLog
+
 	// Start the Kritis Server.
+	tlsConfig, err := tlsconfig.New(tlsSpec)
+	if err != nil {
+		glog.Fatalf("invalid TLS configuration: %v", err)
+	}
+	certFile, keyFile := tlsCertFile, tlsKeyFile
+	if tlsSpec.Certificates.Mode != "" {
+		if err := startCertificateManager(tlsSpec.Certificates, tlsConfig); err != nil {
+			glog.Fatalf("failed to provision the webhook certificate: %v", err)
+		}
+		certFile, keyFile = "", ""
+	}
+	if err := startWebhookReconciler(webhookFailurePolicies); err != nil {
+		glog.Fatalf("invalid webhook failure policies: %v", err)
+	}
 	glog.Infof("running the server: %s", serverAddr)
 	http.HandleFunc("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		admission.ReviewHandler(w, r, config)
 	}))
-	httpsServer := NewServer(serverAddr)
-	glog.Fatal(httpsServer.ListenAndServeTLS(tlsCertFile, tlsKeyFile))
+	http.HandleFunc("/mutate", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		admission.MutateHandler(w, r, config)
+	}))
+	kubeClient, err := kubernetesutil.GetClientset()
+	if err != nil {
+		glog.Fatalf("failed to create the kubernetes client: %v", err)
+	}
+	authorizer := authz.New(kubeClient)
+	http.HandleFunc("/attestations", authorizer.Handler("/attestations", "get", transparency.Handler(attestationLog)))
+	http.HandleFunc("/simulate", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		admission.SimulateHandler(w, r, config, authorizer)
+	}))
+	checker, err := newHealthChecker(config, healthSpec)
+	if err != nil {
+		glog.Fatalf("invalid health configuration: %v", err)
+	}
+	// Kritis is alive as long as it serves requests, and ready once all of its dependencies can be reached.
+	// The probes are served over plain HTTP, as kubelet has no client certificate for the webhook.
+	probes := http.NewServeMux()
+	probes.HandleFunc("/healthz", health.Alive)
+	probes.HandleFunc("/readyz", checker.Handler())
+	if elector != nil {
+		probes.HandleFunc("/readyz/leader", elector.Handler)
+	}
+	glog.Infof("serving the health endpoints: %s", healthAddr)
+	go func() {
+		glog.Fatal(http.ListenAndServe(healthAddr, probes))
+	}()
+	if grpcAddr != "" {
+		if err := startEvaluationServer(grpcAddr, grpcReflection, tlsConfig, certFile, keyFile, config, authorizer); err != nil {
+			glog.Fatalf("failed to start the evaluation API: %v", err)
+		}
+	}
+	httpsServer := NewServer(serverAddr, tlsConfig)
+	glog.Fatal(httpsServer.ListenAndServeTLS(certFile, keyFile))
 }
 
-func NewServer(addr string) *http.Server {
-	return &http.Server{
-		Addr: addr,
-		TLSConfig: &tls.Config{
-			// TODO: Change this to tls.RequireAndVerifyClientCert
-			ClientAuth: tls.NoClientCert,
-		},
+// startCertificateManager serves the certificate provisioned according to spec,
+// which is renewed in the background.
+func startCertificateManager(spec kritisv1beta1.CertificatesSpec, tlsConfig *tls.Config) error {
+	client, err := kubernetesutil.GetClientset()
+	if err != nil {
+		return err
+	}
+	m, err := webhookcert.New(client, spec)
+	if err != nil {
+		return err
+	}
+	if err := m.Start(wait.NeverStop); err != nil {
+		return err
 	}
+	tlsConfig.GetCertificate = m.GetCertificate
+	return nil
 }
 
-// StartCron starts the cron.StartCronJob in background.
-func StartCronJob(config *admission.Config, cronInterval string) error {
-	d, err := time.ParseDuration(cronInterval)
+// startWebhookReconciler keeps the validating webhooks split according to the
+// failure policies of spec in the background.
+func startWebhookReconciler(spec kritisv1beta1.WebhookFailurePoliciesSpec) error {
+	client, err := kubernetesutil.GetClientset()
 	if err != nil {
 		return err
 	}
-	cronConfig, err := getCronConfig(config)
+	r, err := webhookpolicy.New(client, spec)
+	if err != nil {
+		return err
+	}
+	r.Start(wait.NeverStop)
+	return nil
+}
+
+// startEvaluationServer serves the gRPC policy evaluation API on addr in the background,
+// with the certificate of the webhook, read again when its files change. Callers are
+// authorized with their bearer token like those of /simulate.
+func startEvaluationServer(addr string, reflect bool, tlsConfig *tls.Config, certFile, keyFile string, config *admission.Config, authorizer *authz.Authorizer) error {
+	tlsConfig = tlsConfig.Clone()
+	if certFile != "" {
+		k, err := tlsconfig.NewKeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.GetCertificate = k.GetCertificate
+	}
+	lis, err := net.Listen("tcp", addr)
 	if err != nil {
 		return err
 	}
-	go cron.Start(context.Background(), *cronConfig, d)
+	g := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+	evaluation.Register(g, evaluation.NewServer(func(ctx context.Context, req admission.SimulationRequest) (*admission.SimulationResponse, error) {
+		return admission.Simulate(ctx, req, config, admission.TokenAuthorizer(authorizer, evaluation.Token(ctx)))
+	}))
+	healthpb.RegisterHealthServer(g, grpchealth.NewServer())
+	if reflect {
+		reflection.Register(g)
+	}
+	glog.Infof("serving the evaluation API: %s", addr)
+	go func() {
+		glog.Fatal(g.Serve(lis))
+	}()
 	return nil
 }
 
+// newHealthChecker returns a Checker probing the Kubernetes API server, and the Google APIs
+// Kritis uses when a project is configured.
+func newHealthChecker(config *admission.Config, spec kritisv1beta1.HealthSpec) (*health.Checker, error) {
+	client, err := kubernetesutil.GetClientset()
+	if err != nil {
+		return nil, err
+	}
+	probes := []health.Probe{health.KubernetesProbe(client)}
+	if spec.Project != "" && config.Metadata == constants.ContainerAnalysisMetadata {
+		ca, err := containeranalysis.New()
+		if err != nil {
+			return nil, err
+		}
+		probes = append(probes, health.Probe{
+			Name: health.ContainerAnalysis,
+			Check: func(ctx context.Context) error {
+				return ca.Ping(ctx, spec.Project)
+			},
+		})
+	}
+	if spec.BinaryAuthorization {
+		if spec.Project == "" {
+			return nil, fmt.Errorf("health.project is required to probe Binary Authorization")
+		}
+		ba, err := binauthz.New()
+		if err != nil {
+			return nil, err
+		}
+		probes = append(probes, health.Probe{
+			Name: health.BinaryAuthorization,
+			Check: func(ctx context.Context) error {
+				return ba.Ping(ctx, spec.Project)
+			},
+		})
+	}
+	return health.New(spec, probes...)
+}
+
+// NewServer returns the Kritis server, the TLS configuration is built by tlsconfig.New.
+func NewServer(addr string, tlsConfig *tls.Config) *http.Server {
+	return &http.Server{
+		Addr:      addr,
+		TLSConfig: tlsConfig,
+	}
+}
+
+// StartCron starts the cron.StartCronJob in background, writing compliance reports and
+// violation records as configured by reportSpec and recordsSpec. With leader election
+// enabled, it only runs while the returned Elector leads.
+func StartCronJob(config *admission.Config, cronInterval string, workers int, spec kritisv1beta1.LeaderElectionSpec, reportSpec kritisv1beta1.ComplianceReportSpec, recordsSpec kritisv1beta1.ViolationRecordsSpec) (*leader.Elector, error) {
+	d, err := time.ParseDuration(cronInterval)
+	if err != nil {
+		return nil, err
+	}
+	cronConfig, err := getCronConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	cronConfig.Workers = workers
+	if len(reportSpec.Sinks) > 0 {
+		client, err := kubernetesutil.GetClientset()
+		if err != nil {
+			return nil, err
+		}
+		if cronConfig.Reporter, err = report.New(reportSpec, client); err != nil {
+			return nil, errors.Wrap(err, "invalid compliance report")
+		}
+	}
+	if recordsSpec.Store != "" {
+		restConfig, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, errors.Wrap(err, "error building config")
+		}
+		client, err := clientset.NewForConfig(restConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "error building clientset")
+		}
+		if cronConfig.Records, err = records.New(recordsSpec, client); err != nil {
+			return nil, errors.Wrap(err, "invalid violation records")
+		}
+	}
+	if !spec.Enabled {
+		go cron.Start(context.Background(), *cronConfig, d)
+		return nil, nil
+	}
+	client, err := kubernetesutil.GetClientset()
+	if err != nil {
+		return nil, err
+	}
+	elector, err := leader.New(client, spec, func(stop <-chan struct{}) {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-stop
+			cancel()
+		}()
+		cron.Start(ctx, *cronConfig, d)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid leader election")
+	}
+	go elector.Run()
+	return elector, nil
+}
+
 func getCronConfig(config *admission.Config) (*cron.Config, error) {
 	ki, err := kubernetesutil.GetClientset()
 	if err != nil {
@@ -163,5 +533,24 @@ func getCronConfig(config *admission.Config) (*cron.Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	return cron.NewCronConfig(kcs, client), nil
+	attestors, err := securitypolicy.NewAttestorFetcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating attestor fetcher")
+	}
+	cronConfig := cron.NewCronConfig(kcs, client, attestors)
+	cronConfig.ReviewConfig.Strategy = violation.WithNotifications(cronConfig.ReviewConfig.Strategy, config.Notifier)
+	if config.ViolationStrategy != nil {
+		cronConfig.ReviewConfig.Strategy = config.ViolationStrategy
+	}
+	cronConfig.ReviewConfig.Notifier = config.Notifier
+	cronConfig.ReviewConfig.AuditDecisions = config.AuditDecisions
+	cronConfig.ReviewConfig.Promotions = config.Promotions
+	if config.RecordImageReviews {
+		cronConfig.ReviewConfig.RecordReview = imagereview.Record
+	}
+	if config.ReviewOnPolicyChange {
+		cronConfig.SecurityPolicyWatcher = securitypolicy.WatchImageSecurityPolicies
+	}
+	cronConfig.Exemptions = config.Exemptions
+	return cronConfig, nil
 }
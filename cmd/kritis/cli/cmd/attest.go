@@ -0,0 +1,210 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/crd/kritisconfig"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/containeranalysis"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/util"
+)
+
+var (
+	// flag values
+	attestImage          string
+	attestAuthorityName  string
+	attestNamespace      string
+	attestAuthorityFile  string
+	attestPgpKey         string
+	attestPassphraseFile string
+	attestKMSKey         string
+
+	// For testing
+	attestMetadataClient = func() (metadata.Fetcher, error) {
+		return containeranalysis.New()
+	}
+	attestAuthority = func(namespace, name string) (*v1beta1.AttestationAuthority, error) {
+		client, _, err := authorityClients()
+		if err != nil {
+			return nil, err
+		}
+		return client.KritisV1beta1().AttestationAuthorities(namespace).Get(name, metav1.GetOptions{})
+	}
+	attestKritisConfig = func() (*v1beta1.KritisConfig, error) {
+		client, _, err := authorityClients()
+		if err != nil {
+			return nil, err
+		}
+		return kritisconfig.ListKritisConfig(client)
+	}
+	attestSecret = secrets.Fetch
+)
+
+// pgpPassphraseEnv is the environment variable the passphrase of a PGP private key is read from
+// when no passphrase file is given, so that it is not visible in the process list.
+const pgpPassphraseEnv = "KRITIS_PGP_PASSPHRASE"
+
+func init() {
+	attestCmd.Flags().StringVar(&attestImage, "image", "", "Image to attest, e.g. gcr.io/my-project/my-image@sha256:<DIGEST>.")
+	attestCmd.Flags().StringVar(&attestAuthorityName, "authority", "", "Name of the AttestationAuthority attesting the image.")
+	attestCmd.Flags().StringVarP(&attestNamespace, "namespace", "n", "default", "Namespace of the AttestationAuthority and of its secret.")
+	attestCmd.Flags().StringVar(&attestAuthorityFile, "authority-file", "", "YAML or JSON file to read the AttestationAuthority from, instead of the cluster.")
+	attestCmd.Flags().StringVar(&attestPgpKey, "pgp-key", "", "File of the armored PGP private key to sign with, instead of the secret of the AttestationAuthority.")
+	attestCmd.Flags().StringVar(&attestPassphraseFile, "pgp-passphrase-file", "", "File of the passphrase of the PGP private key. Defaults to the "+pgpPassphraseEnv+" environment variable.")
+	attestCmd.Flags().StringVar(&attestKMSKey, "kms-key", "", "KMS key to sign with, as the kmsKeyName of an AttestationAuthority.")
+	attestCmd.MarkFlagRequired("image")
+	attestCmd.MarkFlagRequired("authority")
+	RootCmd.AddCommand(attestCmd)
+}
+
+var attestCmd = &cobra.Command{
+	Use:   "attest --image IMAGE --authority NAME",
+	Short: "Attest an image on behalf of an AttestationAuthority",
+	Long: `attest creates the note of an AttestationAuthority if needed, and an attestation occurrence of the
+image in Container Analysis, as the webhook does for images satisfying an ImageSecurityPolicy.
+The authority is read from the cluster, or from --authority-file. The image is signed with the key of
+the authority, its secret, Vault key or KMS key, unless --pgp-key or --kms-key is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if attestPgpKey != "" && attestKMSKey != "" {
+			return fmt.Errorf("only one of --pgp-key and --kms-key may be given")
+		}
+		auth, err := readAttestAuthority()
+		if err != nil {
+			return err
+		}
+		secret, err := attestSigningSecret(auth)
+		if err != nil {
+			return err
+		}
+
+		client, err := attestMetadataClient()
+		if err != nil {
+			return fmt.Errorf("unable to create metadata client: %v", err)
+		}
+		defer client.Close()
+		ctx := context.Background()
+		n, err := util.GetOrCreateAttestationNote(ctx, client, auth)
+		if err != nil {
+			return fmt.Errorf("unable to get note of %s: %v", auth.Name, err)
+		}
+		occ, err := client.CreateAttestationOccurence(ctx, n, attestImage, secret)
+		if err != nil {
+			return fmt.Errorf("unable to attest %s: %v", attestImage, err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "created attestation %s of %s by %s with key %s\n", occ.GetName(), attestImage, auth.Name, util.GetAttestationKeyFingerprint(secret))
+		return nil
+	},
+}
+
+// readAttestAuthority reads the AttestationAuthority named by --authority from the cluster or from --authority-file.
+func readAttestAuthority() (*v1beta1.AttestationAuthority, error) {
+	if attestAuthorityFile == "" {
+		auth, err := attestAuthority(attestNamespace, attestAuthorityName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get AttestationAuthority %s/%s: %v", attestNamespace, attestAuthorityName, err)
+		}
+		return auth, nil
+	}
+	f, err := os.Open(attestAuthorityFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	d := yaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		var auth v1beta1.AttestationAuthority
+		if err := d.Decode(&auth); err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("AttestationAuthority %s not found in %s", attestAuthorityName, attestAuthorityFile)
+			}
+			return nil, fmt.Errorf("unable to read %s: %v", attestAuthorityFile, err)
+		}
+		if auth.Kind == "AttestationAuthority" && auth.Name == attestAuthorityName {
+			return &auth, nil
+		}
+	}
+}
+
+// attestSigningSecret returns the key given by the flags, or the key of the authority.
+func attestSigningSecret(auth *v1beta1.AttestationAuthority) (*secrets.PGPSigningSecret, error) {
+	switch {
+	case attestKMSKey != "":
+		return &secrets.PGPSigningSecret{SecretName: attestKMSKey, KMSKeyName: attestKMSKey}, nil
+	case attestPgpKey != "":
+		priv, err := ioutil.ReadFile(attestPgpKey)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := base64.StdEncoding.DecodeString(auth.Spec.PublicKeyData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid publicKeyData of %s: %v", auth.Name, err)
+		}
+		passphrase, err := pgpPassphrase(attestPassphraseFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err := secrets.NewPgpKey(string(priv), passphrase, string(pub))
+		if err != nil {
+			return nil, fmt.Errorf("invalid PGP key %s: %v", attestPgpKey, err)
+		}
+		return &secrets.PGPSigningSecret{PgpKey: key, SecretName: attestPgpKey}, nil
+	default:
+		// Vault and KMS keys are restricted by the KritisConfig of the cluster, as in the webhook.
+		config, err := attestKritisConfig()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get KritisConfig: %v", err)
+		}
+		if config != nil {
+			if err := secrets.Configure(config.Spec); err != nil {
+				return nil, err
+			}
+		}
+		secret, err := secrets.FetchForAuthority(attestSecret, attestNamespace, *auth)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get signing key of %s: %v", auth.Name, err)
+		}
+		return secret, nil
+	}
+}
+
+// pgpPassphrase returns the passphrase of a PGP private key read from file, or from the
+// environment if file is empty.
+func pgpPassphrase(file string) (string, error) {
+	if file == "" {
+		return os.Getenv(pgpPassphraseEnv), nil
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("unable to read passphrase: %v", err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/file"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func Test_Attest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "attest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pub, priv := testutil.CreateKeyPair(t, "release")
+	key := filepath.Join(dir, "release.key")
+	if err := ioutil.WriteFile(key, []byte(priv), 0600); err != nil {
+		t.Fatal(err)
+	}
+	auth := v1beta1.AttestationAuthority{
+		TypeMeta:   metav1.TypeMeta{Kind: "AttestationAuthority"},
+		ObjectMeta: metav1.ObjectMeta{Name: "release", Namespace: "ci"},
+		Spec: v1beta1.AttestationAuthoritySpec{
+			NoteReference:        "v1alpha1/projects/p",
+			PrivateKeySecretName: "release-key",
+			PublicKeyData:        base64.StdEncoding.EncodeToString([]byte(pub)),
+		},
+	}
+	authFile := filepath.Join(dir, "authority.yaml")
+	if err := ioutil.WriteFile(authFile, []byte(`apiVersion: kritis.grafeas.io/v1beta1
+kind: AttestationAuthority
+metadata:
+  name: release
+spec:
+  noteReference: v1alpha1/projects/p
+  publicKeyData: `+auth.Spec.PublicKeyData+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	attestAuthority = func(namespace, name string) (*v1beta1.AttestationAuthority, error) {
+		if namespace != "ci" || name != "release" {
+			t.Errorf("unexpected AttestationAuthority %s/%s", namespace, name)
+		}
+		return &auth, nil
+	}
+	attestSecret = func(namespace, name string) (*secrets.PGPSigningSecret, error) {
+		pgpKey, err := secrets.NewPgpKey(priv, "", pub)
+		return &secrets.PGPSigningSecret{PgpKey: pgpKey, SecretName: name}, err
+	}
+	attestKritisConfig = func() (*v1beta1.KritisConfig, error) {
+		return nil, nil
+	}
+	defer func() {
+		attestNamespace, attestAuthorityFile, attestPgpKey, attestKMSKey = "default", "", "", ""
+	}()
+
+	tests := []struct {
+		name   string
+		args   []string
+		shdErr bool
+	}{
+		{"authority of the cluster", []string{"-n", "ci"}, false},
+		{"authority file and PGP key", []string{"--authority-file", authFile, "--pgp-key", key}, false},
+		{"missing authority file", []string{"--authority-file", filepath.Join(dir, "missing.yaml")}, true},
+		{"PGP and KMS key", []string{"--pgp-key", key, "--kms-key", "projects/p/k"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attestNamespace, attestAuthorityFile, attestPgpKey, attestKMSKey = "default", "", "", ""
+			client := file.NewFromFixtures(nil)
+			attestMetadataClient = func() (metadata.Fetcher, error) {
+				return client, nil
+			}
+			var output bytes.Buffer
+			RootCmd.SetOutput(&output)
+			RootCmd.SetArgs(append([]string{"attest", "--image", testutil.QualifiedImage, "--authority", "release"}, test.args...))
+			err := RootCmd.Execute()
+			testutil.CheckError(t, test.shdErr, err)
+			if test.shdErr {
+				return
+			}
+			atts, err := client.Attestations(context.Background(), testutil.QualifiedImage)
+			if err != nil || len(atts) != 1 {
+				t.Fatalf("expected one attestation, got %v: %v", atts, err)
+			}
+			if !strings.Contains(output.String(), "with key "+atts[0].KeyID) {
+				t.Errorf("unexpected output %q", output.String())
+			}
+		})
+	}
+}
+
+func TestPgpPassphrase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "passphrase")
+	if err := ioutil.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv(pgpPassphraseEnv, "from-env")
+	defer os.Unsetenv(pgpPassphraseEnv)
+
+	tests := []struct {
+		name     string
+		file     string
+		expected string
+		shdErr   bool
+	}{
+		{"file", file, "from-file", false},
+		{"environment", "", "from-env", false},
+		{"missing file", filepath.Join(dir, "missing"), "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			passphrase, err := pgpPassphrase(test.file)
+			testutil.CheckErrorAndDeepEqual(t, test.shdErr, err, test.expected, passphrase)
+		})
+	}
+}
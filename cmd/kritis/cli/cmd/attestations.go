@@ -0,0 +1,90 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+
+	"github.com/grafeas/kritis/pkg/kritis/metadata/containeranalysis"
+)
+
+// attestationCopier copies attestations between projects, see containeranalysis.Client.CopyAttestations.
+type attestationCopier interface {
+	CopyAttestations(ctx context.Context, image, src, target, dst string) ([]*grafeas.Occurrence, error)
+	Close()
+}
+
+var (
+	// flag values
+	copyFromProject string
+	copyTarget      string
+	copyToProject   string
+
+	// For testing
+	attestationsCopier = func() (attestationCopier, error) {
+		return containeranalysis.New()
+	}
+)
+
+func init() {
+	attestationsCopyCmd.Flags().StringVar(&copyFromProject, "from-project", "", "Project to copy the attestations from. Defaults to the project of IMAGE.")
+	attestationsCopyCmd.Flags().StringVar(&copyTarget, "to", "", "Image to attest, with the digest of IMAGE, e.g. the image promoted to another registry. Defaults to IMAGE.")
+	attestationsCopyCmd.Flags().StringVar(&copyToProject, "to-project", "", "Project to copy the attestations to. Defaults to the project of the image attested.")
+	attestationsCmd.AddCommand(attestationsCopyCmd)
+	RootCmd.AddCommand(attestationsCmd)
+}
+
+var attestationsCmd = &cobra.Command{
+	Use:   "attestations",
+	Short: "Work with the attestations of images in Container Analysis",
+}
+
+var attestationsCopyCmd = &cobra.Command{
+	Use:   "copy IMAGE",
+	Short: "Copy the attestations of an image to another project",
+	Long: `copy copies the attestation occurrences of IMAGE to another project, as attestations of the image given
+by --to, e.g. when images are promoted to the registry of another project. Attestations already copied are skipped.
+The notes of the attestations are not copied, the destination project must be allowed to attach occurrences to them.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		image, target := args[0], copyTarget
+		if target == "" {
+			target = image
+		}
+		if target == image && copyToProject == "" {
+			return fmt.Errorf("give the image or the project to copy the attestations to with --to or --to-project")
+		}
+		client, err := attestationsCopier()
+		if err != nil {
+			return fmt.Errorf("unable to create metadata client: %v", err)
+		}
+		defer client.Close()
+		created, err := client.CopyAttestations(context.Background(), image, copyFromProject, target, copyToProject)
+		for _, occ := range created {
+			fmt.Fprintf(cmd.OutOrStdout(), "copied attestation of %s to %s\n", occ.GetNoteName(), occ.GetName())
+		}
+		if err != nil {
+			return fmt.Errorf("unable to copy attestations of %s: %v", image, err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "copied %d attestations of %s to %s\n", len(created), image, target)
+		return nil
+	},
+}
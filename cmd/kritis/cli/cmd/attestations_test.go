@@ -0,0 +1,65 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+type fakeCopier struct {
+	args []string
+}
+
+func (f *fakeCopier) CopyAttestations(ctx context.Context, image, src, target, dst string) ([]*grafeas.Occurrence, error) {
+	f.args = []string{image, src, target, dst}
+	return []*grafeas.Occurrence{{Name: "projects/prod/occurrences/1", NoteName: "projects/attestors/notes/qa"}}, nil
+}
+
+func (f *fakeCopier) Close() {}
+
+func Test_AttestationsCopy(t *testing.T) {
+	copier := &fakeCopier{}
+	attestationsCopier = func() (attestationCopier, error) {
+		return copier, nil
+	}
+	defer func() { copyFromProject, copyTarget, copyToProject = "", "", "" }()
+	target := "gcr.io/prod/app@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+	var output bytes.Buffer
+	RootCmd.SetOutput(&output)
+	RootCmd.SetArgs([]string{"attestations", "copy", testutil.QualifiedImage, "--to", target})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("error executing command: %v", err)
+	}
+	testutil.DeepEqual(t, []string{testutil.QualifiedImage, "", target, ""}, copier.args)
+	if !strings.Contains(output.String(), "copied 1 attestations") {
+		t.Errorf("unexpected output %q", output.String())
+	}
+
+	copyTarget = ""
+	RootCmd.SetArgs([]string{"attestations", "copy", testutil.QualifiedImage})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("expected an error without a destination")
+	}
+}
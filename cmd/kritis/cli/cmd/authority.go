@@ -0,0 +1,174 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	clientset "github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/containeranalysis"
+	"github.com/grafeas/kritis/pkg/kritis/rotation"
+)
+
+var (
+	// flag values
+	authorityNamespace    string
+	rotateGracePeriod     time.Duration
+	rotateReattestSince   time.Duration
+	rotateImages          []string
+	retireAllPreviousKeys bool
+
+	// For testing
+	authorityClients = func() (clientset.Interface, kubernetes.Interface, error) {
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to load kubeconfig: %v", err)
+		}
+		kritis, err := clientset.NewForConfig(config)
+		if err != nil {
+			return nil, nil, err
+		}
+		kube, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, nil, err
+		}
+		return kritis, kube, nil
+	}
+	authorityMetadataClient = func() (metadata.Fetcher, error) {
+		return containeranalysis.New()
+	}
+	authorityNow = time.Now
+)
+
+func init() {
+	authorityCmd.PersistentFlags().StringVarP(&authorityNamespace, "namespace", "n", "default", "Namespace of the AttestationAuthority and of its secret.")
+	authorityRotateCmd.Flags().DurationVar(&rotateGracePeriod, "grace-period", rotation.DefaultGracePeriod, "How long the attestations of the previous key are still verified.")
+	for _, c := range []*cobra.Command{authorityRotateCmd, authorityReattestCmd} {
+		c.Flags().DurationVar(&rotateReattestSince, "reattest-since", 30*24*time.Hour, "Attest the images of the ImageReviews of the namespace reviewed since then again with the new key. 0 skips ImageReviews.")
+		c.Flags().StringSliceVar(&rotateImages, "image", nil, "Image to attest again with the new key, in addition to those of ImageReviews. May be repeated.")
+	}
+	authorityRetireCmd.Flags().BoolVar(&retireAllPreviousKeys, "all", false, "Retire all the previous keys, including those in their grace period.")
+	authorityCmd.AddCommand(authorityRotateCmd)
+	authorityCmd.AddCommand(authorityReattestCmd)
+	authorityCmd.AddCommand(authorityRetireCmd)
+	RootCmd.AddCommand(authorityCmd)
+}
+
+var authorityCmd = &cobra.Command{
+	Use:   "authority",
+	Short: "Manage the keys of AttestationAuthorities",
+}
+
+var authorityRotateCmd = &cobra.Command{
+	Use:   "rotate NAME",
+	Short: "Rotate the PGP key of an AttestationAuthority",
+	Long: `rotate generates a new PGP key pair for the AttestationAuthority NAME, stores it in the secret of the
+authority and makes it its publicKeyData. The previous public key is kept in previousKeys, and its attestations
+are still verified for --grace-period. Images attested with the previous key are attested again with the new key:
+those of recent ImageReviews in the namespace, and those given with --image.
+Only authorities signing with the key of a Kubernetes Secret can be rotated.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kritis, kube, err := authorityClients()
+		if err != nil {
+			return err
+		}
+		now := authorityNow()
+		r, err := rotation.Rotate(kritis, kube, authorityNamespace, args[0], rotateGracePeriod, now)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "rotated the key of %s/%s from %s to %s, the previous key retires at %s\n", authorityNamespace, args[0], r.OldFingerprint, r.NewFingerprint, r.RetireTime.Format(time.RFC3339))
+		return reattest(cmd, kritis, args[0], r, now)
+	},
+}
+
+var authorityReattestCmd = &cobra.Command{
+	Use:   "reattest NAME",
+	Short: "Attest images again with the key of the last rotation of an AttestationAuthority",
+	Long: `reattest attests the images attested with the newest previous key of the AttestationAuthority NAME again with
+its current key, as rotate does, without rotating the key again. It resumes a rotation whose images failed to be
+attested again: those of recent ImageReviews in the namespace, and those given with --image.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kritis, kube, err := authorityClients()
+		if err != nil {
+			return err
+		}
+		r, err := rotation.Current(kritis, kube, authorityNamespace, args[0])
+		if err != nil {
+			return err
+		}
+		return reattest(cmd, kritis, args[0], r, authorityNow())
+	},
+}
+
+// reattest attests the images of the ImageReviews of the namespace and of --image, signed with
+// the previous key of the rotation r of the authority name, again with its new key.
+func reattest(cmd *cobra.Command, kritis clientset.Interface, name string, r *rotation.Result, now time.Time) error {
+	images := rotateImages
+	if rotateReattestSince > 0 {
+		recent, err := rotation.RecentImages(kritis, authorityNamespace, now.Add(-rotateReattestSince))
+		if err != nil {
+			return err
+		}
+		images = append(images, recent...)
+	}
+	client, err := authorityMetadataClient()
+	if err != nil {
+		return fmt.Errorf("unable to create metadata client: %v", err)
+	}
+	defer client.Close()
+	out := cmd.OutOrStdout()
+	attested, err := rotation.Reattest(context.Background(), client, r.Authority, r.Secret, r.OldFingerprint, r.NewFingerprint, images)
+	for _, image := range attested {
+		fmt.Fprintf(out, "attested %s with the new key\n", image)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to attest images again, run `kritis authority reattest %s -n %s` to attest them before %s: %v", name, authorityNamespace, r.RetireTime.Format(time.RFC3339), err)
+	}
+	fmt.Fprintf(out, "attested %d of %d images again\n", len(attested), len(images))
+	return nil
+}
+
+var authorityRetireCmd = &cobra.Command{
+	Use:   "retire NAME",
+	Short: "Remove the retired previous keys of an AttestationAuthority",
+	Long: `retire removes the previous keys of the AttestationAuthority NAME whose grace period is over. Their
+attestations are no longer verified in any case. With --all, keys in their grace period are removed too.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kritis, _, err := authorityClients()
+		if err != nil {
+			return err
+		}
+		removed, err := rotation.Retire(kritis, authorityNamespace, args[0], authorityNow(), retireAllPreviousKeys)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "retired %d previous keys of %s/%s\n", removed, authorityNamespace, args[0])
+		return nil
+	},
+}
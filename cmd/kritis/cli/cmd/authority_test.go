@@ -0,0 +1,157 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	clientset "github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned"
+	"github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned/fake"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func Test_AuthorityRotateAndRetire(t *testing.T) {
+	old, pub := testutil.CreateSecret(t, "qa")
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	kritis := fake.NewSimpleClientset(
+		&v1beta1.AttestationAuthority{
+			ObjectMeta: metav1.ObjectMeta{Name: "qa-attestator", Namespace: "qa"},
+			Spec: v1beta1.AttestationAuthoritySpec{
+				NoteReference:        "v1beta1/projects/qa",
+				PrivateKeySecretName: "qa-key",
+				PublicKeyData:        base64.StdEncoding.EncodeToString([]byte(pub)),
+			},
+		},
+		&v1beta1.ImageReview{
+			ObjectMeta: metav1.ObjectMeta{Name: "review", Namespace: "qa"},
+			Spec:       v1beta1.ImageReviewSpec{Image: testutil.QualifiedImage},
+			Status:     v1beta1.ImageReviewStatus{ReviewTime: metav1.NewTime(now.Add(-time.Hour))},
+		},
+	)
+	kube := kubefake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "qa-key", Namespace: "qa"},
+		Data:       map[string][]byte{secrets.PublicKey: []byte(pub)},
+	})
+	client := &testutil.MockMetadataClient{
+		PGPAttestations: []metadata.PGPAttestation{{KeyID: old.PgpKey.Fingerprint()}},
+	}
+	authorityClients = func() (clientset.Interface, kubernetes.Interface, error) {
+		return kritis, kube, nil
+	}
+	authorityMetadataClient = func() (metadata.Fetcher, error) {
+		return client, nil
+	}
+	authorityNow = func() time.Time { return now }
+	defer func() { authorityNow = time.Now }()
+
+	var output bytes.Buffer
+	RootCmd.SetOutput(&output)
+	RootCmd.SetArgs([]string{"authority", "rotate", "qa-attestator", "-n", "qa", "--grace-period", "1h"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("error executing command: %v", err)
+	}
+	if !strings.Contains(output.String(), "attested 1 of 1 images again") {
+		t.Errorf("unexpected output %q", output.String())
+	}
+	testutil.DeepEqual(t, 1, client.Calls("CreateAttestationOccurence", testutil.QualifiedImage))
+
+	output.Reset()
+	authorityNow = func() time.Time { return now.Add(time.Hour) }
+	RootCmd.SetArgs([]string{"authority", "retire", "qa-attestator", "-n", "qa"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("error executing command: %v", err)
+	}
+	if !strings.Contains(output.String(), "retired 1 previous keys of qa/qa-attestator") {
+		t.Errorf("unexpected output %q", output.String())
+	}
+}
+
+func Test_AuthorityReattest(t *testing.T) {
+	old, pub := testutil.CreateSecret(t, "qa")
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	review := func(name, image string) *v1beta1.ImageReview {
+		return &v1beta1.ImageReview{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "qa"},
+			Spec:       v1beta1.ImageReviewSpec{Image: image},
+			Status:     v1beta1.ImageReviewStatus{ReviewTime: metav1.NewTime(now.Add(-time.Hour))},
+		}
+	}
+	kritis := fake.NewSimpleClientset(
+		&v1beta1.AttestationAuthority{
+			ObjectMeta: metav1.ObjectMeta{Name: "qa-attestator", Namespace: "qa"},
+			Spec: v1beta1.AttestationAuthoritySpec{
+				NoteReference:        "v1beta1/projects/qa",
+				PrivateKeySecretName: "qa-key",
+				PublicKeyData:        base64.StdEncoding.EncodeToString([]byte(pub)),
+			},
+		},
+		review("a", testutil.QualifiedImage),
+		review("b", testutil.IntTestImage),
+	)
+	kube := kubefake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "qa-key", Namespace: "qa"},
+		Data:       map[string][]byte{secrets.PublicKey: []byte(pub)},
+	})
+	// The first attestation fails, so the rotation stops before attesting the other image.
+	client := &testutil.MockMetadataClient{
+		PGPAttestations: []metadata.PGPAttestation{{KeyID: old.PgpKey.Fingerprint()}},
+		Errors:          map[string][]error{"CreateAttestationOccurence": {fmt.Errorf("unavailable")}},
+	}
+	authorityClients = func() (clientset.Interface, kubernetes.Interface, error) {
+		return kritis, kube, nil
+	}
+	authorityMetadataClient = func() (metadata.Fetcher, error) {
+		return client, nil
+	}
+	authorityNow = func() time.Time { return now }
+	defer func() { authorityNow = time.Now }()
+
+	var output bytes.Buffer
+	RootCmd.SetOutput(&output)
+	RootCmd.SetArgs([]string{"authority", "rotate", "qa-attestator", "-n", "qa", "--grace-period", "1h"})
+	err := RootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "kritis authority reattest qa-attestator -n qa") {
+		t.Fatalf("expected error pointing to reattest, got %v", err)
+	}
+	rotated, _ := kritis.KritisV1beta1().AttestationAuthorities("qa").Get("qa-attestator", metav1.GetOptions{})
+
+	output.Reset()
+	RootCmd.SetArgs([]string{"authority", "reattest", "qa-attestator", "-n", "qa"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("error executing command: %v", err)
+	}
+	if !strings.Contains(output.String(), "attested 2 of 2 images again") {
+		t.Errorf("unexpected output %q", output.String())
+	}
+	testutil.DeepEqual(t, 1, client.Calls("CreateAttestationOccurence", testutil.IntTestImage))
+	// The key is not rotated again
+	resumed, _ := kritis.KritisV1beta1().AttestationAuthorities("qa").Get("qa-attestator", metav1.GetOptions{})
+	testutil.DeepEqual(t, rotated.Spec, resumed.Spec)
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grafeas/kritis/pkg/kritis/metadata/file"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+var (
+	// flag values
+	bundleOutput         string
+	bundleVersion        string
+	bundlePgpKey         string
+	bundlePassphraseFile string
+	bundlePublicKey      string
+	bundleMaxAge         time.Duration
+
+	// For testing
+	bundleNow = time.Now
+)
+
+func init() {
+	bundleExportCmd.Flags().StringVarP(&bundleOutput, "output", "o", "", "File to write the bundle to. Defaults to STDOUT.")
+	bundleExportCmd.Flags().StringVar(&bundleVersion, "version", "", "Version of the bundle. Defaults to its creation time.")
+	bundleExportCmd.Flags().StringVar(&bundlePgpKey, "pgp-key", "", "File of the armored PGP private key to sign the bundle with.")
+	bundleExportCmd.Flags().StringVar(&bundlePassphraseFile, "pgp-passphrase-file", "", "File of the passphrase of the PGP private key. Defaults to the "+pgpPassphraseEnv+" environment variable.")
+	bundleExportCmd.MarkFlagRequired("pgp-key")
+	bundleVerifyCmd.Flags().StringVar(&bundlePublicKey, "public-key", "", "File of the armored PGP public keys trusted to sign bundles.")
+	bundleVerifyCmd.Flags().DurationVar(&bundleMaxAge, "max-age", 0, "Maximum age of the bundle. 0 disables the freshness check.")
+	bundleVerifyCmd.MarkFlagRequired("public-key")
+	bundleCmd.AddCommand(bundleExportCmd, bundleVerifyCmd)
+	RootCmd.AddCommand(bundleCmd)
+}
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Work with signed metadata bundles read by the file metadata backend in air-gapped clusters",
+}
+
+var bundleExportCmd = &cobra.Command{
+	Use:   "export IMAGE...",
+	Short: "Export metadata for images from Container Analysis as a signed bundle",
+	Long: `export fetches vulnerabilities, attestations, builds and V1 occurrences for each image
+as "kritis fixtures export" does, and writes them as a JSON bundle signed with the PGP key.
+The bundle is read by the "file" metadata backend when set as the vulnerabilityBundle.path of the KritisConfig.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		priv, err := ioutil.ReadFile(bundlePgpKey)
+		if err != nil {
+			return err
+		}
+		passphrase, err := pgpPassphrase(bundlePassphraseFile)
+		if err != nil {
+			return err
+		}
+		key, err := secrets.NewPgpKey(string(priv), passphrase, "")
+		if err != nil {
+			return fmt.Errorf("invalid PGP key %s: %v", bundlePgpKey, err)
+		}
+
+		client, err := fixturesMetadataClient()
+		if err != nil {
+			return fmt.Errorf("unable to create metadata client: %v", err)
+		}
+		defer client.Close()
+
+		fixtures := []file.Fixture{}
+		for _, image := range args {
+			f, err := file.Export(context.Background(), client, image)
+			if err != nil {
+				return fmt.Errorf("unable to export %s: %v", image, err)
+			}
+			fixtures = append(fixtures, *f)
+		}
+
+		manifest := file.BundleManifest{Version: bundleVersion, CreateTime: bundleNow().UTC()}
+		if manifest.Version == "" {
+			manifest.Version = manifest.CreateTime.Format(time.RFC3339)
+		}
+		var w io.Writer = cmd.OutOrStdout()
+		if bundleOutput != "" {
+			out, err := os.Create(bundleOutput)
+			if err != nil {
+				return err
+			}
+			defer out.Close()
+			w = out
+		}
+		return file.WriteSignedBundle(w, manifest, fixtures, key)
+	},
+}
+
+var bundleVerifyCmd = &cobra.Command{
+	Use:   "verify FILE",
+	Short: "Verify the signature and age of a signed bundle",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		b, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		keys, err := ioutil.ReadFile(bundlePublicKey)
+		if err != nil {
+			return err
+		}
+		payload, err := file.ReadSignedBundle(b, keys)
+		if err != nil {
+			return err
+		}
+		if bundleMaxAge > 0 && bundleNow().After(payload.CreateTime.Add(bundleMaxAge)) {
+			return fmt.Errorf("bundle %q created at %s is older than %s", payload.Version, payload.CreateTime.Format(time.RFC3339), bundleMaxAge)
+		}
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "bundle %q created at %s is valid\n", payload.Version, payload.CreateTime.Format(time.RFC3339))
+		for _, f := range payload.Fixtures {
+			fmt.Fprintf(out, "%s: %d vulnerabilities, %d attestations, %d builds\n", f.Image, len(f.Vulnerabilities), len(f.Attestations), len(f.Builds))
+		}
+		return nil
+	},
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func Test_Bundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pub, priv := testutil.CreateKeyPair(t, "bundle")
+	key := filepath.Join(dir, "bundle.key")
+	pubKey := filepath.Join(dir, "bundle.pub")
+	if err := ioutil.WriteFile(key, []byte(priv), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(pubKey, []byte(pub), 0644); err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	defer func() { bundleNow = time.Now }()
+	bundleNow = func() time.Time { return created }
+	fixturesMetadataClient = func() (metadata.Fetcher, error) {
+		return &testutil.MockMetadataClient{
+			Vulnz: []metadata.Vulnerability{{CVE: "c", Severity: "CRITICAL"}},
+		}, nil
+	}
+
+	bundle := filepath.Join(dir, "bundle.json")
+	var output bytes.Buffer
+	RootCmd.SetOutput(&output)
+	RootCmd.SetArgs([]string{"bundle", "export", testutil.QualifiedImage, "--pgp-key", key, "-o", bundle})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("error exporting bundle: %v", err)
+	}
+
+	tcs := []struct {
+		name      string
+		maxAge    string
+		expected  string
+		shouldErr bool
+	}{
+		{"fresh bundle", "48h", `bundle "2018-06-01T00:00:00Z" created at 2018-06-01T00:00:00Z is valid
+` + testutil.QualifiedImage + `: 1 vulnerabilities, 0 attestations, 0 builds
+`, false},
+		{"stale bundle", "1h", "", true},
+	}
+	bundleNow = func() time.Time { return created.Add(24 * time.Hour) }
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			output.Reset()
+			RootCmd.SetArgs([]string{"bundle", "verify", bundle, "--public-key", pubKey, "--max-age", tc.maxAge})
+			err := RootCmd.Execute()
+			testutil.CheckError(t, tc.shouldErr, err)
+			if !tc.shouldErr {
+				testutil.DeepEqual(t, tc.expected, output.String())
+			}
+		})
+	}
+}
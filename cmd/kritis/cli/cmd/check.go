@@ -0,0 +1,167 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/grafeas/kritis/cmd/kritis/version"
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/containeranalysis"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/review"
+	"github.com/grafeas/kritis/pkg/kritis/sarif"
+)
+
+var (
+	// flag values
+	checkImage  string
+	checkPolicy string
+	checkOutput string
+
+	// For testing
+	checkMetadataClient = func() (metadata.Fetcher, error) {
+		return containeranalysis.New()
+	}
+	checkAttestorFetcher = securitypolicy.NewAttestorFetcher
+)
+
+func init() {
+	checkCmd.Flags().StringVar(&checkImage, "image", "", "Image to check, e.g. gcr.io/my-project/my-image@sha256:<DIGEST>.")
+	checkCmd.Flags().StringVar(&checkPolicy, "policy", "", "YAML or JSON file of the ImageSecurityPolicies to check the image against.")
+	checkCmd.Flags().StringVarP(&checkOutput, "output", "o", "table", "Output format of the violations, table, json or sarif.")
+	checkCmd.MarkFlagRequired("image")
+	checkCmd.MarkFlagRequired("policy")
+	RootCmd.AddCommand(checkCmd)
+}
+
+// checkStrategy collects the violations found by check, to print them.
+type checkStrategy struct {
+	violations []policy.Violation
+}
+
+func (s *checkStrategy) HandleViolation(image string, pod *v1.Pod, violations []policy.Violation) error {
+	s.violations = append(s.violations, violations...)
+	return nil
+}
+
+func (s *checkStrategy) HandleAttestation(image string, pod *v1.Pod, isAttested bool) error {
+	return nil
+}
+
+// checkViolation is a violation of an ImageSecurityPolicy, as printed by check.
+type checkViolation struct {
+	Policy string `json:"policy"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+var checkCmd = &cobra.Command{
+	Use:   "check --image IMAGE --policy FILE",
+	Short: "Check an image against ImageSecurityPolicies before deploying it",
+	Long: `check validates an image against the ImageSecurityPolicies of a file, as the webhook would,
+using metadata from Container Analysis and Application Default Credentials.
+It prints the violations found and exits with an error if there are any, so it can run in CI.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if checkOutput != "table" && checkOutput != "json" && checkOutput != "sarif" {
+			return fmt.Errorf("unsupported output format %q, expected table, json or sarif", checkOutput)
+		}
+		f, err := os.Open(checkPolicy)
+		if err != nil {
+			return err
+		}
+		isps, err := securitypolicy.ReadImageSecurityPolicies(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %v", checkPolicy, err)
+		}
+		if len(isps) == 0 {
+			return fmt.Errorf("no ImageSecurityPolicies found in %s", checkPolicy)
+		}
+
+		client, err := checkMetadataClient()
+		if err != nil {
+			return fmt.Errorf("unable to create metadata client: %v", err)
+		}
+		defer client.Close()
+		attestors, err := checkAttestorFetcher()
+		if err != nil {
+			return fmt.Errorf("unable to create attestor fetcher: %v", err)
+		}
+
+		// Images are reviewed as by the cron job, without attesting them or reading the
+		// resources of a cluster, and the violations are collected rather than handled.
+		strategy := &checkStrategy{}
+		r := review.New(client, &review.Config{
+			Validate:  securitypolicy.ValidateImageSecurityPolicy,
+			Attestors: attestors,
+			Strategy:  strategy,
+		})
+		violations := []checkViolation{}
+		report := sarif.NewReport(version.Version)
+		report.ArtifactURI = checkPolicy
+		for _, isp := range isps {
+			// Each policy is reviewed on its own, as reviews stop at the first policy violated.
+			isp.Spec.ViolationStrategies = nil
+			strategy.violations = nil
+			err := r.ReviewContext(context.Background(), []string{checkImage}, []v1beta1.ImageSecurityPolicy{isp}, nil)
+			if err != nil && len(strategy.violations) == 0 {
+				return fmt.Errorf("unable to check %s against %s: %v", checkImage, isp.Name, err)
+			}
+			for _, v := range strategy.violations {
+				violations = append(violations, checkViolation{Policy: isp.Name, Type: v.Type().ToString(), Reason: string(v.Reason())})
+			}
+			report.Add(checkImage, isp.Name, strategy.violations)
+		}
+
+		if checkOutput == "sarif" {
+			if err := report.Write(cmd.OutOrStdout()); err != nil {
+				return err
+			}
+		} else if checkOutput == "json" {
+			e := json.NewEncoder(cmd.OutOrStdout())
+			e.SetIndent("", "  ")
+			if err := e.Encode(violations); err != nil {
+				return err
+			}
+		} else if len(violations) > 0 {
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "POLICY\tTYPE\tREASON")
+			for _, v := range violations {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", v.Policy, v.Type, v.Reason)
+			}
+			w.Flush()
+		}
+		if len(violations) > 0 {
+			return fmt.Errorf("found %d violations of %d ImageSecurityPolicies", len(violations), len(isps))
+		}
+		if checkOutput == "table" {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s satisfies %d ImageSecurityPolicies\n", checkImage, len(isps))
+		}
+		return nil
+	},
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+const checkPolicyYAML = `apiVersion: kritis.grafeas.io/v1beta1
+kind: ImageSecurityPolicy
+metadata:
+  name: my-isp
+spec:
+  packageVulnerabilityRequirements:
+    maximumSeverity: MEDIUM
+---
+apiVersion: kritis.grafeas.io/v1beta1
+kind: ImageSecurityPolicy
+metadata:
+  name: notified-isp
+spec:
+  violationStrategies: ["notify"]
+  packageVulnerabilityRequirements:
+    maximumSeverity: HIGH
+`
+
+func Test_Check(t *testing.T) {
+	dir, err := ioutil.TempDir("", "check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	policy := filepath.Join(dir, "isp.yaml")
+	if err := ioutil.WriteFile(policy, []byte(checkPolicyYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { checkOutput = "table" }()
+	checkAttestorFetcher = func() (securitypolicy.AttestorFetcher, error) {
+		return nil, nil
+	}
+
+	tests := []struct {
+		name     string
+		vulnz    []metadata.Vulnerability
+		output   string
+		shdErr   bool
+		expected string
+	}{
+		{"no violations", nil, "table", false, "satisfies 2 ImageSecurityPolicies"},
+		{"table", []metadata.Vulnerability{{CVE: "CVE-1", Severity: "CRITICAL", HasFixAvailable: true}}, "table", true, "notified-isp  SeverityViolation"},
+		{"json", []metadata.Vulnerability{{CVE: "CVE-1", Severity: "CRITICAL", HasFixAvailable: true}}, "json", true, `"type": "SeverityViolation"`},
+		{"sarif", []metadata.Vulnerability{{CVE: "CVE-1", Severity: "CRITICAL", HasFixAvailable: true}}, "sarif", true, `"ruleId": "SeverityViolation"`},
+		{"unknown output", nil, "yaml", true, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkMetadataClient = func() (metadata.Fetcher, error) {
+				return &testutil.MockMetadataClient{Vulnz: test.vulnz}, nil
+			}
+			var output bytes.Buffer
+			RootCmd.SetOutput(&output)
+			RootCmd.SetArgs([]string{"check", "--image", testutil.QualifiedImage, "--policy", policy, "-o", test.output})
+			err := RootCmd.Execute()
+			testutil.CheckError(t, test.shdErr, err)
+			if !strings.Contains(output.String(), test.expected) {
+				t.Errorf("expected output to contain %q, got %q", test.expected, output.String())
+			}
+			if test.output == "json" {
+				var violations []checkViolation
+				// cobra prints the returned error after the violations.
+				// Both policies are violated, including the one with violation strategies.
+				if err := json.NewDecoder(&output).Decode(&violations); err != nil || len(violations) != 2 {
+					t.Errorf("unexpected output %s: %v", output.String(), err)
+				}
+			}
+		})
+	}
+}
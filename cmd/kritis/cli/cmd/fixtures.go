@@ -0,0 +1,86 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/containeranalysis"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/file"
+)
+
+var (
+	// flag values
+	fixturesOutput string
+
+	// For testing
+	fixturesMetadataClient = func() (metadata.Fetcher, error) {
+		return containeranalysis.New()
+	}
+)
+
+func init() {
+	fixturesExportCmd.Flags().StringVarP(&fixturesOutput, "output", "o", "", "File to write fixtures to. Defaults to STDOUT.")
+	fixturesCmd.AddCommand(fixturesExportCmd)
+	RootCmd.AddCommand(fixturesCmd)
+}
+
+var fixturesCmd = &cobra.Command{
+	Use:   "fixtures",
+	Short: "Work with metadata fixtures used by the file metadata backend",
+}
+
+var fixturesExportCmd = &cobra.Command{
+	Use:   "export IMAGE...",
+	Short: "Export sanitized metadata for images from Container Analysis as fixtures",
+	Long: `export fetches vulnerabilities, attestations, builds and V1 occurrences for each image
+using Application Default Credentials, and writes them in the format read by the "file" metadata backend.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := fixturesMetadataClient()
+		if err != nil {
+			return fmt.Errorf("unable to create metadata client: %v", err)
+		}
+		defer client.Close()
+
+		fixtures := []file.Fixture{}
+		for _, image := range args {
+			f, err := file.Export(context.Background(), client, image)
+			if err != nil {
+				return fmt.Errorf("unable to export %s: %v", image, err)
+			}
+			fixtures = append(fixtures, *f)
+		}
+
+		var w io.Writer = cmd.OutOrStdout()
+		if fixturesOutput != "" {
+			out, err := os.Create(fixturesOutput)
+			if err != nil {
+				return err
+			}
+			defer out.Close()
+			w = out
+		}
+		return file.Write(w, fixtures)
+	},
+}
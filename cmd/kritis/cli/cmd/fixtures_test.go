@@ -0,0 +1,48 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/file"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func Test_FixturesExport(t *testing.T) {
+	fixturesMetadataClient = func() (metadata.Fetcher, error) {
+		return &testutil.MockMetadataClient{
+			Vulnz: []metadata.Vulnerability{{CVE: "c", Severity: "CRITICAL"}},
+		}, nil
+	}
+	var output bytes.Buffer
+	RootCmd.SetOutput(&output)
+	RootCmd.SetArgs([]string{"fixtures", "export", testutil.QualifiedImage})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("error executing command: %v", err)
+	}
+	var fixtures []file.Fixture
+	if err := json.Unmarshal(output.Bytes(), &fixtures); err != nil {
+		t.Fatalf("unexpected output %s: %v", output.String(), err)
+	}
+	if len(fixtures) != 1 || fixtures[0].Image != testutil.QualifiedImage || len(fixtures[0].Vulnerabilities) != 1 {
+		t.Errorf("unexpected fixtures: %+v", fixtures)
+	}
+}
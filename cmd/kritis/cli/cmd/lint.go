@@ -0,0 +1,83 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+)
+
+var (
+	// flag values
+	lintCluster bool
+
+	// For testing
+	lintClusterPolicies = securitypolicy.ImageSecurityPolicies
+)
+
+func init() {
+	lintCmd.Flags().BoolVar(&lintCluster, "cluster", false, "Lint the ImageSecurityPolicies of all namespaces in the current cluster.")
+	RootCmd.AddCommand(lintCmd)
+}
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [FILE...]",
+	Short: "Report ImageSecurityPolicy settings shadowed by another policy in the same namespace",
+	Long: `lint reads ImageSecurityPolicies from YAML or JSON files, or from the cluster with --cluster,
+and reports settings with no effect because an image is reviewed against every policy of its namespace,
+e.g. an image whitelisted by one policy while another requires attestations for it.
+It exits with an error if any conflict is found.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if lintCluster == (len(args) > 0) {
+			return fmt.Errorf("either files or --cluster must be given")
+		}
+		var isps []v1beta1.ImageSecurityPolicy
+		if lintCluster {
+			var err error
+			if isps, err = lintClusterPolicies(""); err != nil {
+				return fmt.Errorf("unable to list ImageSecurityPolicies: %v", err)
+			}
+		}
+		for _, path := range args {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			read, err := securitypolicy.ReadImageSecurityPolicies(f)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("unable to read %s: %v", path, err)
+			}
+			isps = append(isps, read...)
+		}
+
+		conflicts := securitypolicy.Conflicts(isps)
+		for _, c := range conflicts {
+			fmt.Fprintln(cmd.OutOrStdout(), c)
+		}
+		if len(conflicts) > 0 {
+			return fmt.Errorf("found %d conflicts in %d ImageSecurityPolicies", len(conflicts), len(isps))
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "no conflicts found in %d ImageSecurityPolicies\n", len(isps))
+		return nil
+	},
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_LintCluster(t *testing.T) {
+	defer func() { lintCluster = false }()
+	lintClusterPolicies = func(namespace string) ([]v1beta1.ImageSecurityPolicy, error) {
+		return []v1beta1.ImageSecurityPolicy{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "ns"},
+				Spec:       v1beta1.ImageSecurityPolicySpec{ImageWhitelist: []string{"gcr.io/p/i@sha256:abc"}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "ns"},
+			},
+		}, nil
+	}
+	var output bytes.Buffer
+	RootCmd.SetOutput(&output)
+	RootCmd.SetArgs([]string{"lint", "--cluster"})
+	if err := RootCmd.Execute(); err == nil {
+		t.Fatal("expected an error for conflicting policies")
+	}
+	expected := `ns/a: image "gcr.io/p/i@sha256:abc" is whitelisted, but is still reviewed against "b"`
+	if !strings.Contains(output.String(), expected) {
+		t.Errorf("expected output to contain %q, got %q", expected, output.String())
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"flag"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	// Populate Go flags into pflags so that glog -v works
+	RootCmd.PersistentFlags().AddGoFlagSet(flag.CommandLine)
+}
+
+// RootCmd implements the kritis command.
+var RootCmd = &cobra.Command{
+	Use:   "kritis",
+	Short: "kritis is a tool for working with kritis policies and metadata outside the cluster",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Place here so it is first run before anything else, but after init() so that
+		// it does not silently break tests.
+		return flag.CommandLine.Parse([]string{})
+	},
+	// Otherwise, the default Run() shows usage if RunE returns an error.
+	SilenceUsage: true,
+}
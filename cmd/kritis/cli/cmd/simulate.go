@@ -0,0 +1,171 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/grafeas/kritis/pkg/kritis/admission"
+)
+
+var (
+	// flag values
+	simulateServer    string
+	simulateNamespace string
+	simulatePod       string
+	simulateImages    []string
+	simulatePolicy    string
+	simulateCAFile    string
+	simulateInsecure  bool
+	simulateOutput    string
+	simulateToken     string
+)
+
+func init() {
+	simulateCmd.Flags().StringVar(&simulateServer, "server", "", "URL of the kritis webhook, e.g. https://localhost:8443 through kubectl port-forward.")
+	simulateCmd.Flags().StringVarP(&simulateNamespace, "namespace", "n", "", "Namespace whose ImageSecurityPolicies are evaluated. Defaults to the namespace of the pod.")
+	simulateCmd.Flags().StringVar(&simulatePod, "pod", "", "YAML or JSON file of the pod to evaluate.")
+	simulateCmd.Flags().StringSliceVar(&simulateImages, "image", nil, "Image to evaluate, in addition to those of the pod. Can be repeated.")
+	simulateCmd.Flags().StringVar(&simulatePolicy, "policy", "", "YAML or JSON file of proposed ImageSecurityPolicies, evaluated instead of those of the namespace.")
+	simulateCmd.Flags().StringVar(&simulateCAFile, "ca-file", "", "CA certificate file of the webhook certificate.")
+	simulateCmd.Flags().BoolVar(&simulateInsecure, "insecure-skip-tls-verify", false, "Do not verify the webhook certificate.")
+	simulateCmd.Flags().StringVarP(&simulateOutput, "output", "o", "table", "Output format of the violations, table or json.")
+	simulateCmd.Flags().StringVar(&simulateToken, "token", os.Getenv("KRITIS_TOKEN"), "Kubernetes bearer token authorizing the simulation, e.g. from kubectl create token. Defaults to $KRITIS_TOKEN.")
+	simulateCmd.MarkFlagRequired("server")
+	RootCmd.AddCommand(simulateCmd)
+}
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate --server URL [--pod FILE] [--image IMAGE...] [--policy FILE]",
+	Short: "Report the violations a pod or images would have, without deploying them",
+	Long: `simulate asks the kritis webhook which violations a pod or images would have against the
+ImageSecurityPolicies of their namespace, or against proposed policies with --policy, using the metadata
+the webhook reviews production workloads with. Nothing is attested, recorded or notified.
+The --token user must be allowed to list the ImageSecurityPolicies of the namespace, or to create the
+proposed ones. It exits with an error if there are any violations.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if simulateOutput != "table" && simulateOutput != "json" {
+			return fmt.Errorf("unsupported output format %q, expected table or json", simulateOutput)
+		}
+		req := admission.SimulationRequest{Namespace: simulateNamespace, Images: simulateImages}
+		if simulatePod != "" {
+			f, err := os.Open(simulatePod)
+			if err != nil {
+				return err
+			}
+			req.Pod = &v1.Pod{}
+			err = yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(req.Pod)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("unable to read %s: %v", simulatePod, err)
+			}
+		}
+		if simulatePolicy != "" {
+			b, err := ioutil.ReadFile(simulatePolicy)
+			if err != nil {
+				return err
+			}
+			req.Policies = string(b)
+		}
+
+		resp, err := postSimulation(req)
+		if err != nil {
+			return err
+		}
+		if simulateOutput == "json" {
+			e := json.NewEncoder(cmd.OutOrStdout())
+			e.SetIndent("", "  ")
+			if err := e.Encode(resp); err != nil {
+				return err
+			}
+		} else if len(resp.Violations) > 0 {
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "IMAGE\tPOLICY\tTYPE\tREASON")
+			for _, v := range resp.Violations {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Image, v.Policy, v.Type, v.Reason)
+			}
+			w.Flush()
+		}
+		if len(resp.Violations) > 0 {
+			return fmt.Errorf("found %d violations of %d ImageSecurityPolicies", len(resp.Violations), len(resp.Policies))
+		}
+		if simulateOutput == "table" {
+			fmt.Fprintf(cmd.OutOrStdout(), "%d images satisfy %d ImageSecurityPolicies\n", len(resp.Images), len(resp.Policies))
+		}
+		return nil
+	},
+}
+
+// postSimulation sends req to the simulation endpoint of the webhook.
+func postSimulation(req admission.SimulationRequest) (*admission.SimulationResponse, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: simulateInsecure}
+	if simulateCAFile != "" {
+		pem, err := ioutil.ReadFile(simulateCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", simulateCAFile)
+		}
+	}
+	client := &http.Client{
+		Timeout:   time.Minute,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	hr, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(simulateServer, "/")+"/simulate", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	hr.Header.Set("Content-Type", "application/json")
+	if simulateToken != "" {
+		hr.Header.Set("Authorization", "Bearer "+simulateToken)
+	}
+	r, err := client.Do(hr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach %s: %v", simulateServer, err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(r.Body)
+		return nil, fmt.Errorf("simulation failed: %s: %s", r.Status, strings.TrimSpace(string(msg)))
+	}
+	resp := &admission.SimulationResponse{}
+	if err := json.NewDecoder(r.Body).Decode(resp); err != nil {
+		return nil, fmt.Errorf("invalid simulation response: %v", err)
+	}
+	return resp, nil
+}
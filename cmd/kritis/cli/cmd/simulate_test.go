@@ -0,0 +1,141 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/admission"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+const simulatePodYAML = `apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+  namespace: prod
+spec:
+  containers:
+  - name: app
+    image: gcr.io/my-project/app@sha256:0000000000000000000000000000000000000000000000000000000000000000
+`
+
+func Test_Simulate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "simulate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pod := filepath.Join(dir, "pod.yaml")
+	policy := filepath.Join(dir, "isp.yaml")
+	if err := ioutil.WriteFile(pod, []byte(simulatePodYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(policy, []byte(checkPolicyYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		simulateNamespace, simulatePod, simulatePolicy, simulateImages, simulateInsecure, simulateOutput, simulateToken = "", "", "", nil, false, "table", ""
+	}()
+
+	var received admission.SimulationRequest
+	var authorization string
+	var resp admission.SimulationResponse
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/simulate" {
+			http.NotFound(w, r)
+			return
+		}
+		authorization = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer s.Close()
+
+	violation := admission.SimulatedViolation{Image: testutil.QualifiedImage, Policy: "prod/my-isp", Type: "SeverityViolation", Reason: "found CVE"}
+	tests := []struct {
+		name     string
+		args     []string
+		resp     admission.SimulationResponse
+		shdErr   bool
+		expected string
+	}{
+		{
+			name:     "allowed",
+			args:     []string{"--pod", pod, "--policy", policy, "--insecure-skip-tls-verify"},
+			resp:     admission.SimulationResponse{Allowed: true, Images: []string{testutil.QualifiedImage}, Policies: []string{"prod/my-isp"}},
+			expected: "1 images satisfy 1 ImageSecurityPolicies",
+		},
+		{
+			name:     "violations",
+			args:     []string{"--image", testutil.QualifiedImage, "-n", "prod", "--insecure-skip-tls-verify"},
+			resp:     admission.SimulationResponse{Images: []string{testutil.QualifiedImage}, Policies: []string{"prod/my-isp"}, Violations: []admission.SimulatedViolation{violation}},
+			shdErr:   true,
+			expected: "prod/my-isp  SeverityViolation  found CVE",
+		},
+		{
+			name:     "json",
+			args:     []string{"--image", testutil.QualifiedImage, "-n", "prod", "--insecure-skip-tls-verify", "-o", "json"},
+			resp:     admission.SimulationResponse{Images: []string{testutil.QualifiedImage}, Policies: []string{"prod/my-isp"}, Violations: []admission.SimulatedViolation{violation}},
+			shdErr:   true,
+			expected: `"type": "SeverityViolation"`,
+		},
+		{
+			name:   "unverified certificate",
+			args:   []string{"--image", testutil.QualifiedImage, "-n", "prod"},
+			shdErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			simulateNamespace, simulatePod, simulatePolicy, simulateImages, simulateInsecure, simulateOutput, simulateToken = "", "", "", nil, false, "table", ""
+			resp = test.resp
+			var output bytes.Buffer
+			RootCmd.SetOutput(&output)
+			RootCmd.SetArgs(append([]string{"simulate", "--server", s.URL}, test.args...))
+			err := RootCmd.Execute()
+			testutil.CheckError(t, test.shdErr, err)
+			if !strings.Contains(output.String(), test.expected) {
+				t.Errorf("expected output to contain %q, got %q", test.expected, output.String())
+			}
+		})
+	}
+
+	// The pod, proposed policies and token are sent to the webhook
+	simulateNamespace, simulatePod, simulatePolicy, simulateImages, simulateInsecure, simulateOutput, simulateToken = "", "", "", nil, false, "table", ""
+	received = admission.SimulationRequest{}
+	RootCmd.SetArgs([]string{"simulate", "--server", s.URL, "--pod", pod, "--policy", policy, "--insecure-skip-tls-verify", "--token", "dev"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if received.Pod == nil || received.Pod.Namespace != "prod" || len(received.Pod.Spec.Containers) != 1 {
+		t.Errorf("unexpected pod %+v", received.Pod)
+	}
+	testutil.DeepEqual(t, checkPolicyYAML, received.Policies)
+	testutil.DeepEqual(t, "Bearer dev", authorization)
+}
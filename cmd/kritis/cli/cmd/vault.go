@@ -0,0 +1,79 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+var (
+	// flag values
+	vaultAddress string
+	vaultMount   string
+
+	// For testing
+	vaultFetch = func(address string, spec v1beta1.VaultKeySpec) (*secrets.PGPSigningSecret, error) {
+		v, err := secrets.NewVault(v1beta1.VaultConfigSpec{Address: address})
+		if err != nil {
+			return nil, err
+		}
+		return v.Key(spec)
+	}
+)
+
+func init() {
+	vaultPublicKeyCmd.Flags().StringVar(&vaultAddress, "address", os.Getenv("VAULT_ADDR"), "Address of the Vault server. Defaults to VAULT_ADDR.")
+	vaultPublicKeyCmd.Flags().StringVar(&vaultMount, "mount", "transit", "Path of the transit secrets engine.")
+	vaultCmd.AddCommand(vaultPublicKeyCmd)
+	RootCmd.AddCommand(vaultCmd)
+}
+
+var vaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Work with signing keys held in HashiCorp Vault",
+}
+
+var vaultPublicKeyCmd = &cobra.Command{
+	Use:   "public-key KEY",
+	Short: "Print the publicKeyData of an AttestationAuthority signing with a Vault transit key",
+	Long: `public-key reads the latest version of the transit key with VAULT_TOKEN, and prints its PGP
+public key self-signed by Vault and base64 encoded, as expected in the publicKeyData of AttestationAuthorities.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sec, err := vaultFetch(vaultAddress, v1beta1.VaultKeySpec{
+			Engine: secrets.VaultTransit,
+			Mount:  vaultMount,
+			Path:   args[0],
+		})
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %v", args[0], err)
+		}
+		pub, err := sec.PgpKey.ArmoredPublicKey()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), base64.StdEncoding.EncodeToString([]byte(pub)))
+		return nil
+	},
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+func Test_VaultPublicKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	pgpKey := secrets.NewPgpKeyFromSigner(time.Now(), key)
+	var address string
+	var spec v1beta1.VaultKeySpec
+	vaultFetch = func(a string, s v1beta1.VaultKeySpec) (*secrets.PGPSigningSecret, error) {
+		address, spec = a, s
+		return &secrets.PGPSigningSecret{PgpKey: pgpKey}, nil
+	}
+	var output bytes.Buffer
+	RootCmd.SetOutput(&output)
+	RootCmd.SetArgs([]string{"vault", "public-key", "kritis", "--address", "https://vault:8200"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("error executing command: %v", err)
+	}
+	if address != "https://vault:8200" || spec.Engine != secrets.VaultTransit || spec.Mount != "transit" || spec.Path != "kritis" {
+		t.Errorf("unexpected vault key %+v", spec)
+	}
+	pub, err := base64.StdEncoding.DecodeString(strings.TrimSpace(output.String()))
+	if err != nil {
+		t.Fatalf("unexpected output %s: %v", output.String(), err)
+	}
+	parsed, err := secrets.NewPgpKey("", "", string(pub))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if parsed.Fingerprint() != pgpKey.Fingerprint() {
+		t.Errorf("expected fingerprint %s, got %s", pgpKey.Fingerprint(), parsed.Fingerprint())
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kritis-gate evaluates built images against the rules of ImageSecurityPolicies as a CI
+// step, e.g. a Tekton task or a Cloud Build step, and exits nonzero if they violate them.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/grafeas/kritis/cmd/kritis/version"
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	clientset "github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/gate"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/containeranalysis"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/file"
+	"github.com/grafeas/kritis/pkg/kritis/sarif"
+)
+
+// Exit codes of kritis-gate.
+const (
+	exitViolations = 1
+	exitError      = 2
+)
+
+// For testing
+var (
+	clusterPolicies = func(namespace string) ([]v1beta1.ImageSecurityPolicy, error) {
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("unable to load kubeconfig: %v", err)
+		}
+		client, err := clientset.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		list, err := client.KritisV1beta1().ImageSecurityPolicies(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return list.Items, nil
+	}
+	metadataClient = func(path string) (metadata.Fetcher, error) {
+		if path != "" {
+			return file.New(path)
+		}
+		return containeranalysis.New()
+	}
+	attestorFetcher = securitypolicy.NewAttestorFetcher
+)
+
+// options are the flags of kritis-gate.
+type options struct {
+	images       []string
+	policyFile   string
+	namespace    string
+	metadataFile string
+	junitFile    string
+	sarifFile    string
+}
+
+// imagesFlag is a flag which may be repeated.
+type imagesFlag []string
+
+func (f *imagesFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *imagesFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+func main() {
+	var images imagesFlag
+	var opts options
+	flag.Var(&images, "image", "Image to evaluate, e.g. gcr.io/my-project/my-image@sha256:<DIGEST>. May be repeated, images may also be given as arguments.")
+	flag.StringVar(&opts.policyFile, "policy", "", "YAML or JSON file of the ImageSecurityPolicies to evaluate the images against.")
+	flag.StringVar(&opts.namespace, "namespace", "", "Namespace whose ImageSecurityPolicies are read from the cluster of the kubeconfig, if -policy is not set.")
+	flag.StringVar(&opts.metadataFile, "metadata-file", "", "Fixtures file read instead of Container Analysis.")
+	flag.StringVar(&opts.junitFile, "junit", "", "File a JUnit XML report of the evaluation is written to.")
+	flag.StringVar(&opts.sarifFile, "sarif", "", "File a SARIF log of the violations is written to, e.g. for GitHub code scanning.")
+	flag.Parse()
+	opts.images = append(images, flag.Args()...)
+	os.Exit(run(context.Background(), opts, os.Stdout))
+}
+
+// run evaluates the images of opts and returns the exit code of kritis-gate.
+func run(ctx context.Context, opts options, out io.Writer) int {
+	results, err := evaluate(ctx, opts)
+	if err != nil {
+		glog.Errorf("kritis-gate failed: %v", err)
+		fmt.Fprintf(out, "error: %v\n", err)
+		return exitError
+	}
+	if opts.junitFile != "" {
+		if err := writeJUnit(opts.junitFile, results); err != nil {
+			fmt.Fprintf(out, "error: unable to write %s: %v\n", opts.junitFile, err)
+			return exitError
+		}
+	}
+	if opts.sarifFile != "" {
+		if err := writeSARIF(opts.sarifFile, opts.policyFile, results); err != nil {
+			fmt.Fprintf(out, "error: unable to write %s: %v\n", opts.sarifFile, err)
+			return exitError
+		}
+	}
+	for _, r := range results {
+		for _, v := range r.Violations {
+			fmt.Fprintf(out, "%s: %s: %s: %s\n", r.Image, r.Policy, v.Type().ToString(), v.Reason())
+		}
+	}
+	if n := gate.Violations(results); n > 0 {
+		fmt.Fprintf(out, "found %d violations\n", n)
+		return exitViolations
+	}
+	fmt.Fprintf(out, "%d images satisfy all ImageSecurityPolicies\n", len(opts.images))
+	fmt.Fprintf(out, "warning: %s\n", gate.NotChecked)
+	return 0
+}
+
+func evaluate(ctx context.Context, opts options) ([]gate.Result, error) {
+	if len(opts.images) == 0 {
+		return nil, fmt.Errorf("no images to evaluate")
+	}
+	isps, err := policies(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(isps) == 0 {
+		return nil, fmt.Errorf("no ImageSecurityPolicies to evaluate the images against")
+	}
+	client, err := metadataClient(opts.metadataFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create metadata client: %v", err)
+	}
+	defer client.Close()
+	attestors, err := attestorFetcher()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create attestor fetcher: %v", err)
+	}
+	return gate.Evaluate(ctx, client, attestors, opts.images, isps)
+}
+
+func policies(opts options) ([]v1beta1.ImageSecurityPolicy, error) {
+	if opts.policyFile == "" {
+		if opts.namespace == "" {
+			return nil, fmt.Errorf("either -policy or -namespace is required")
+		}
+		isps, err := clusterPolicies(opts.namespace)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the ImageSecurityPolicies of %s: %v", opts.namespace, err)
+		}
+		return isps, nil
+	}
+	f, err := os.Open(opts.policyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	isps, err := securitypolicy.ReadImageSecurityPolicies(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %v", opts.policyFile, err)
+	}
+	return isps, nil
+}
+
+func writeSARIF(path, policyFile string, results []gate.Result) error {
+	report := sarif.NewReport(version.Version)
+	report.ArtifactURI = policyFile
+	for _, r := range results {
+		report.Add(r.Image, r.Policy, r.Violations)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := report.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeJUnit(path string, results []gate.Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gate.WriteJUnit(f, results); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
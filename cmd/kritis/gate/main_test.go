@@ -0,0 +1,170 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/file"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+const policyYAML = `apiVersion: kritis.grafeas.io/v1beta1
+kind: ImageSecurityPolicy
+metadata:
+  name: my-isp
+spec:
+  packageVulnerabilityRequirements:
+    maximumSeverity: MEDIUM
+`
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	policy := filepath.Join(dir, "isp.yaml")
+	if err := ioutil.WriteFile(policy, []byte(policyYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fixtures := filepath.Join(dir, "fixtures.json")
+	f, err := os.Create(fixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Write(f, []file.Fixture{{
+		Image:           testutil.QualifiedImage,
+		Vulnerabilities: []metadata.Vulnerability{{CVE: "CVE-1", Severity: "CRITICAL", HasFixAvailable: true}},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	defer func(f func() (securitypolicy.AttestorFetcher, error)) { attestorFetcher = f }(attestorFetcher)
+	attestorFetcher = func() (securitypolicy.AttestorFetcher, error) {
+		return nil, nil
+	}
+	defer func(f func(string) ([]v1beta1.ImageSecurityPolicy, error)) { clusterPolicies = f }(clusterPolicies)
+	clusterPolicies = func(namespace string) ([]v1beta1.ImageSecurityPolicy, error) {
+		return []v1beta1.ImageSecurityPolicy{{ObjectMeta: metav1.ObjectMeta{Name: "cluster-isp", Namespace: namespace}}}, nil
+	}
+	clean := "gcr.io/my-project/clean@sha256:" + strings.Repeat("0", 64)
+
+	tests := []struct {
+		name     string
+		opts     options
+		code     int
+		expected string
+	}{
+		{
+			name:     "violations",
+			opts:     options{images: []string{testutil.QualifiedImage, clean}, policyFile: policy, metadataFile: fixtures},
+			code:     exitViolations,
+			expected: testutil.QualifiedImage + ": my-isp: SeverityViolation",
+		},
+		{
+			name:     "no violations",
+			opts:     options{images: []string{clean}, policyFile: policy, metadataFile: fixtures},
+			code:     0,
+			expected: "1 images satisfy all ImageSecurityPolicies",
+		},
+		{
+			name:     "attestation-based policies are not checked",
+			opts:     options{images: []string{clean}, policyFile: policy, metadataFile: fixtures},
+			code:     0,
+			expected: "warning: AttestationAuthorities, GenericAttestationPolicies and the whitelist and exemptions of the cluster were not checked",
+		},
+		{
+			name:     "cluster policies",
+			opts:     options{images: []string{testutil.QualifiedImage}, namespace: "prod", metadataFile: fixtures},
+			code:     0,
+			expected: "1 images satisfy all ImageSecurityPolicies",
+		},
+		{
+			name:     "no policies",
+			opts:     options{images: []string{clean}, metadataFile: fixtures},
+			code:     exitError,
+			expected: "either -policy or -namespace is required",
+		},
+		{
+			name:     "no images",
+			opts:     options{policyFile: policy, metadataFile: fixtures},
+			code:     exitError,
+			expected: "no images to evaluate",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			code := run(context.Background(), test.opts, &out)
+			testutil.DeepEqual(t, test.code, code)
+			if !strings.Contains(out.String(), test.expected) {
+				t.Errorf("expected output to contain %q, got %q", test.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestRunReports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	policy := filepath.Join(dir, "isp.yaml")
+	if err := ioutil.WriteFile(policy, []byte(policyYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(f func(string) (metadata.Fetcher, error)) { metadataClient = f }(metadataClient)
+	metadataClient = func(string) (metadata.Fetcher, error) {
+		return &testutil.MockMetadataClient{Vulnz: []metadata.Vulnerability{{CVE: "CVE-1", Severity: "CRITICAL", HasFixAvailable: true}}}, nil
+	}
+	defer func(f func() (securitypolicy.AttestorFetcher, error)) { attestorFetcher = f }(attestorFetcher)
+	attestorFetcher = func() (securitypolicy.AttestorFetcher, error) {
+		return nil, nil
+	}
+	junit := filepath.Join(dir, "report.xml")
+	sarifLog := filepath.Join(dir, "report.sarif")
+	code := run(context.Background(), options{images: []string{testutil.QualifiedImage}, policyFile: policy, junitFile: junit, sarifFile: sarifLog}, ioutil.Discard)
+	testutil.DeepEqual(t, exitViolations, code)
+	b, err := ioutil.ReadFile(junit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `<testcase name="my-isp" classname="`+testutil.QualifiedImage+`">`) {
+		t.Errorf("unexpected JUnit report %s", b)
+	}
+	b, err = ioutil.ReadFile(sarifLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"cve": "CVE-1"`) || !strings.Contains(string(b), `"uri": "`+policy+`"`) {
+		t.Errorf("unexpected SARIF log %s", b)
+	}
+}
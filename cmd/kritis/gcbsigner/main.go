@@ -22,6 +22,7 @@ import (
 
 	"github.com/golang/glog"
 	"github.com/grafeas/kritis/pkg/kritis/crd/buildpolicy"
+	"github.com/grafeas/kritis/pkg/kritis/crd/kritisconfig"
 	"github.com/grafeas/kritis/pkg/kritis/gcbsigner"
 	"github.com/grafeas/kritis/pkg/kritis/metadata/containeranalysis"
 	"github.com/grafeas/kritis/pkg/kritis/secrets"
@@ -37,7 +38,18 @@ func main() {
 	resourceNamespace := flag.String("resource_namespace", os.Getenv("SIGNER_NAMESPACE"), "Namespace the signer CRDs and secrets are stored in")
 	flag.Parse()
 
-	err := run(context.Background(), *gcbProject, *gcbSubscription, *resourceNamespace)
+	// Authorities sign with the Vault server and the keys allowed by the KritisConfig.
+	kritisConfig, err := kritisconfig.KritisConfig()
+	if err != nil {
+		glog.Fatalf("failed to get kritis config: %v", err)
+	}
+	if kritisConfig != nil {
+		if err := secrets.Configure(kritisConfig.Spec); err != nil {
+			glog.Fatal(err)
+		}
+	}
+
+	err = run(context.Background(), *gcbProject, *gcbSubscription, *resourceNamespace)
 	if err != nil {
 		glog.Fatalf("Error running signer: %v", err)
 	}
@@ -53,7 +65,7 @@ func run(ctx context.Context, project string, subscription string, ns string) er
 	for err == nil {
 		glog.Infof("Listening")
 		err = sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
-			if err := process(ns, msg); err != nil {
+			if err := process(ctx, ns, msg); err != nil {
 				glog.Errorf("Error signing: %v", err)
 				msg.Nack()
 			} else {
@@ -64,7 +76,7 @@ func run(ctx context.Context, project string, subscription string, ns string) er
 	return fmt.Errorf("Error receiving message: %v", err)
 }
 
-func process(ns string, msg *pubsub.Message) error {
+func process(ctx context.Context, ns string, msg *pubsub.Message) error {
 	provenance, err := gcbsigner.ExtractBuildProvenanceFromEvent(msg)
 	if err != nil {
 		return fmt.Errorf("Error extracting images from message: %v", err)
@@ -87,7 +99,7 @@ func process(ns string, msg *pubsub.Message) error {
 		Validate: buildpolicy.ValidateBuildPolicy,
 	})
 	for _, prov := range provenance {
-		if err := r.ValidateAndSign(prov, bps); err != nil {
+		if err := r.ValidateAndSign(ctx, prov, bps); err != nil {
 			return fmt.Errorf("Error creating signature: %v", err)
 		}
 	}
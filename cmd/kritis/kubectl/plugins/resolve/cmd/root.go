@@ -33,18 +33,24 @@ import (
 const (
 	localFlagFilenameEnv = "KUBECTL_PLUGINS_LOCAL_FLAG_FILENAME"
 	localFlagApplyEnv    = "KUBECTL_PLUGINS_LOCAL_FLAG_APPLY"
+	localFlagLockfileEnv = "KUBECTL_PLUGINS_LOCAL_FLAG_LOCKFILE"
+	localFlagVerifyEnv   = "KUBECTL_PLUGINS_LOCAL_FLAG_VERIFY"
 	callerEnv            = "KUBECTL_PLUGINS_CALLER"
 )
 
 var (
 	// flag values
-	files multiArg
-	apply bool
+	files    multiArg
+	apply    bool
+	lockfile string
+	verify   string
 )
 
 func init() {
-	RootCmd.PersistentFlags().VarP(&files, "filename", "f", "Filename to resolve. Set it repeatedly for multiple filenames.")
+	RootCmd.PersistentFlags().VarP(&files, "filename", "f", "Filename or directory to resolve. Set it repeatedly for multiple filenames.")
 	RootCmd.PersistentFlags().BoolVarP(&apply, "apply", "a", false, "Apply changes using 'kubectl apply -f'.")
+	RootCmd.PersistentFlags().StringVar(&lockfile, "lockfile", "", "Write the digests the tags resolved to to this lockfile.")
+	RootCmd.PersistentFlags().StringVar(&verify, "verify", "", "Verify that all images are pinned to digests of this lockfile, instead of resolving them.")
 
 	// Populate Go flags into pflags so that glog -v works
 	RootCmd.PersistentFlags().AddGoFlagSet(flag.CommandLine)
@@ -54,8 +60,9 @@ func init() {
 var RootCmd = &cobra.Command{
 	Use:   "resolve-tags",
 	Short: "resolve-tags is a tool for replacing tagged images with fully qualified images in Kubernetes yamls",
-	Long: `resolve-tags can be run as either a kubectl plugin or as a binary. It takes in paths to file and
-		   prints new manifests to STDOUT.
+	Long: `resolve-tags can be run as either a kubectl plugin or as a binary. It takes in paths to files and
+		   directories and prints new manifests to STDOUT. Directories are walked recursively, and
+		   kustomizations and Helm charts in them are rendered before resolving.
 
 		   Note: When running as a binary, if the KUBECTL_PLUGINS_LOCAL_FLAG_FILENAME env variable is set,
 		   it will override any files passed in.`,
@@ -66,6 +73,7 @@ var RootCmd = &cobra.Command{
 			return err
 		}
 		resolveApply()
+		resolveLockfile()
 		cwd, err := os.Getwd()
 		if err != nil {
 			return err
@@ -73,10 +81,18 @@ var RootCmd = &cobra.Command{
 		return resolveFilepaths(cwd)
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		substitutes, err := resolve.Execute(files)
+		if verify != "" {
+			return verifyLockfile(cmd.OutOrStdout())
+		}
+		substitutes, lock, err := resolve.ExecuteWithLockfile(files)
 		if err != nil {
 			return fmt.Errorf("unable to resolve: %v", err)
 		}
+		if lockfile != "" {
+			if err := writeLockfile(lock); err != nil {
+				return fmt.Errorf("unable to write lockfile %s: %v", lockfile, err)
+			}
+		}
 		return outputResults(substitutes, cmd.OutOrStdout())
 	},
 	// Otherwise, the default Run() shows usage if RunE returns an error.
@@ -87,6 +103,48 @@ func resolveApply() {
 	apply = apply || (os.Getenv(localFlagApplyEnv) != "")
 }
 
+func resolveLockfile() {
+	if l := os.Getenv(localFlagLockfileEnv); l != "" {
+		lockfile = l
+	}
+	if v := os.Getenv(localFlagVerifyEnv); v != "" {
+		verify = v
+	}
+}
+
+func writeLockfile(lock *resolve.Lockfile) error {
+	f, err := os.Create(lockfile)
+	if err != nil {
+		return err
+	}
+	if err := lock.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// verifyLockfile prints the images which are not pinned to digests of the lockfile,
+// and fails if there are any.
+func verifyLockfile(writer io.Writer) error {
+	lock, err := resolve.ReadLockfile(verify)
+	if err != nil {
+		return err
+	}
+	problems, err := resolve.Verify(files, lock)
+	if err != nil {
+		return fmt.Errorf("unable to verify: %v", err)
+	}
+	for _, p := range problems {
+		fmt.Fprintln(writer, p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d images are not pinned to digests of %s", len(problems), verify)
+	}
+	fmt.Fprintf(writer, "all images are pinned to digests of %s\n", verify)
+	return nil
+}
+
 func resolveFilepaths(relativeDir string) error {
 	if pluginFile := os.Getenv(localFlagFilenameEnv); pluginFile != "" {
 		files = []string{pluginFile}
@@ -22,6 +22,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/grafeas/kritis/pkg/kritis/testutil"
@@ -80,3 +81,43 @@ func Test_resolveFilepaths(t *testing.T) {
 	err = resolveFilepaths(dir)
 	testutil.CheckErrorAndDeepEqual(t, false, err, expected, files)
 }
+
+func Test_Verify(t *testing.T) {
+	digest := "gcr.io/kritis-int-test/resolve-tags-test-image@sha256:3e2e946cb834c4538b789312d566eb16f4a27734fc6b140a3b3f85baafce965f"
+	dir, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	lock := filepath.Join(dir, "kritis.lock")
+	if err := ioutil.WriteFile(lock, []byte("images:\n  gcr.io/kritis-int-test/resolve-tags-test-image:latest: "+digest+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { verify = "" }()
+	tests := []struct {
+		name     string
+		image    string
+		shdErr   bool
+		expected string
+	}{
+		{"pinned", digest, false, "all images are pinned to digests of " + lock},
+		{"tagged", "gcr.io/kritis-int-test/resolve-tags-test-image", true, "is not pinned by digest"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manifest := filepath.Join(dir, test.name+".yaml")
+			if err := ioutil.WriteFile(manifest, []byte(fmt.Sprintf(testYaml, test.image)), 0644); err != nil {
+				t.Fatal(err)
+			}
+			files = nil
+			var output bytes.Buffer
+			RootCmd.SetOutput(&output)
+			RootCmd.SetArgs([]string{"--filename=" + manifest, "--verify=" + lock})
+			err := RootCmd.Execute()
+			testutil.CheckError(t, test.shdErr, err)
+			if !strings.Contains(output.String(), test.expected) {
+				t.Errorf("expected output to contain %q, got %q", test.expected, output.String())
+			}
+		})
+	}
+}
@@ -30,6 +30,7 @@ var (
 	certificate           string
 	webhookName           string
 	deploymentWebhookName string
+	mutationWebhookName   string
 	kritisInstallLabel    string
 	serviceName           string
 )
@@ -37,6 +38,7 @@ var (
 func init() {
 	flag.StringVar(&webhookName, "webhook-name", "", "The name of the validation webhook.")
 	flag.StringVar(&deploymentWebhookName, "deployment-webhook-name", "", "The name of the deployment validation webhook.")
+	flag.StringVar(&mutationWebhookName, "mutation-webhook-name", "", "The name of the image digest mutation webhook, not created if empty.")
 	flag.StringVar(&serviceName, "service-name", "", "The name of the service for the webhook.")
 	flag.StringVar(&tlsSecretName, "tls-secret-name", "", "The name of the kritis tls secret.")
 	flag.StringVar(&kritisInstallLabel, "kritis-install-label", "", "The label to indicate a resource has been created by kritis")
@@ -52,4 +54,7 @@ func main() {
 	getCaBundle()
 	createValidationWebhook()
 	createValidationDeploymentWebhook()
+	if mutationWebhookName != "" {
+		createMutationWebhook()
+	}
 }
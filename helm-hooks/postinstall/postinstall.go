@@ -54,6 +54,7 @@ webhooks:
           - UPDATE
         resources:
           - pods
+          - pods/ephemeralcontainers
     failurePolicy: Fail
     namespaceSelector:
       matchExpressions:
@@ -91,6 +92,10 @@ webhooks:
         resources:
           - deployments
           - replicasets
+          - statefulsets
+          - daemonsets
+          - jobs
+          - cronjobs
     failurePolicy: Fail
     namespaceSelector:
       matchExpressions:
@@ -106,3 +111,38 @@ webhooks:
 	webhookCmd.Stdin = bytes.NewReader([]byte(webhookSpec))
 	install.RunCommand(webhookCmd)
 }
+
+func createMutationWebhook() {
+	webhookSpec := `apiVersion: admissionregistration.k8s.io/v1beta1
+kind: MutatingWebhookConfiguration
+metadata:
+  name: %s
+  labels:
+    %s: ""
+webhooks:
+  - name: kritis-mutation-hook.grafeas.io
+    rules:
+      - apiGroups:
+          - ""
+        apiVersions:
+          - v1
+        operations:
+          - CREATE
+        resources:
+          - pods
+    failurePolicy: Ignore
+    namespaceSelector:
+      matchExpressions:
+      - {key: kritis-validation, operator: NotIn, values: [disabled]}
+    clientConfig:
+      caBundle: %s
+      service:
+        name: %s
+        namespace: %s
+        path: /mutate`
+	webhookSpec = fmt.Sprintf(webhookSpec, mutationWebhookName, kritisInstallLabel, certificate, serviceName, namespace)
+	fmt.Println(webhookSpec)
+	webhookCmd := exec.Command("kubectl", "apply", "-f", "-")
+	webhookCmd.Stdin = bytes.NewReader([]byte(webhookSpec))
+	install.RunCommand(webhookCmd)
+}
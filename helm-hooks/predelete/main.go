@@ -28,6 +28,7 @@ var (
 	csrName               string
 	webhookName           string
 	deploymentWebhookName string
+	mutationWebhookName   string
 	deleteCRD             bool
 	deleteCsr             bool
 )
@@ -35,6 +36,7 @@ var (
 func init() {
 	flag.StringVar(&webhookName, "webhook-name", "", "The name of the validation webhook.")
 	flag.StringVar(&deploymentWebhookName, "deployment-webhook-name", "", "The name of the validation webhook.")
+	flag.StringVar(&mutationWebhookName, "mutation-webhook-name", "", "The name of the mutation webhook.")
 	flag.StringVar(&tlsSecretName, "tls-secret-name", "", "The name of the kritis tls secret.")
 	flag.StringVar(&csrName, "csr-name", "", "The name of the kritis csr.")
 	flag.BoolVar(&deleteCsr, "delete-csr", true, "Delete kritis csr")
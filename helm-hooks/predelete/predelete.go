@@ -27,6 +27,9 @@ import (
 func deleteWebhooks() {
 	deleteObject("validatingwebhookconfiguration", webhookName)
 	deleteObject("validatingwebhookconfiguration", deploymentWebhookName)
+	if mutationWebhookName != "" {
+		deleteObject("mutatingwebhookconfiguration", mutationWebhookName)
+	}
 }
 
 func deleteTLSSecret() {
@@ -40,6 +43,10 @@ func deleteCSR() {
 func deleteCRDs() {
 	deleteObject("crd", "attestationauthorities.kritis.grafeas.io")
 	deleteObject("crd", "imagesecuritypolicies.kritis.grafeas.io")
+	deleteObject("crd", "genericattestationpolicies.kritis.grafeas.io")
+	deleteObject("crd", "imagereviews.kritis.grafeas.io")
+	deleteObject("crd", "violationrecords.kritis.grafeas.io")
+	deleteObject("crd", "attestors.kritis.grafeas.io")
 }
 
 func deleteObject(object, name string) {
@@ -44,7 +44,84 @@ spec:
     scope: Namespaced
     names:
         kind: ImageSecurityPolicy
-        plural: imagesecuritypolicies`
+        plural: imagesecuritypolicies
+    subresources:
+        status: {}
+    additionalPrinterColumns:
+    - name: Images
+      type: integer
+      JSONPath: .status.scannedImages
+    - name: Violating
+      type: integer
+      JSONPath: .status.violatingPods
+    - name: Last Scan
+      type: date
+      JSONPath: .status.lastScanTime`
+
+	genericAttestationPolicyCRD = `apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+    name: genericattestationpolicies.kritis.grafeas.io
+    labels:
+        %s: ""
+spec:
+    group: kritis.grafeas.io
+    version: v1beta1
+    scope: Namespaced
+    names:
+        kind: GenericAttestationPolicy
+        plural: genericattestationpolicies`
+
+	imageReviewCRD = `apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+    name: imagereviews.kritis.grafeas.io
+    labels:
+        %s: ""
+spec:
+    group: kritis.grafeas.io
+    version: v1beta1
+    scope: Namespaced
+    names:
+        kind: ImageReview
+        plural: imagereviews
+    additionalPrinterColumns:
+    - name: Image
+      type: string
+      JSONPath: .spec.image
+    - name: Policy
+      type: string
+      JSONPath: .spec.policy
+    - name: Allowed
+      type: boolean
+      JSONPath: .status.allowed
+    - name: Reviewed
+      type: date
+      JSONPath: .status.reviewTime`
+
+	violationRecordCRD = `apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+    name: violationrecords.kritis.grafeas.io
+    labels:
+        %s: ""
+spec:
+    group: kritis.grafeas.io
+    version: v1beta1
+    scope: Namespaced
+    names:
+        kind: ViolationRecord
+        plural: violationrecords
+    additionalPrinterColumns:
+    - name: Pods
+      type: integer
+      JSONPath: .spec.pods
+    - name: Violating
+      type: integer
+      JSONPath: .spec.violatingPods
+    - name: Reviewed
+      type: date
+      JSONPath: .spec.reviewTime`
 
 	kritisConfigCRD = `apiVersion: apiextensions.k8s.io/v1beta1
 kind: CustomResourceDefinition
@@ -60,4 +137,19 @@ spec:
     kind: KritisConfig
     plural: kritisconfigs
     singular: kritisconfig`
+
+	attestorCRD = `apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: attestors.kritis.grafeas.io
+  labels:
+      %s: ""
+spec:
+  group: kritis.grafeas.io
+  version: v1beta1
+  scope: Cluster
+  names:
+    kind: Attestor
+    plural: attestors
+    singular: attestor`
 )
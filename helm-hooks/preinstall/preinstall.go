@@ -228,8 +228,28 @@ func installCRDs() {
 	ispCommand.Stdin = bytes.NewReader([]byte(crd))
 	install.RunCommand(ispCommand)
 
+	gapCommand := exec.Command("kubectl", "apply", "-f", "-")
+	crd = fmt.Sprintf(genericAttestationPolicyCRD, kritisInstallLabel)
+	gapCommand.Stdin = bytes.NewReader([]byte(crd))
+	install.RunCommand(gapCommand)
+
+	imageReviewCommand := exec.Command("kubectl", "apply", "-f", "-")
+	crd = fmt.Sprintf(imageReviewCRD, kritisInstallLabel)
+	imageReviewCommand.Stdin = bytes.NewReader([]byte(crd))
+	install.RunCommand(imageReviewCommand)
+
+	violationRecordCommand := exec.Command("kubectl", "apply", "-f", "-")
+	crd = fmt.Sprintf(violationRecordCRD, kritisInstallLabel)
+	violationRecordCommand.Stdin = bytes.NewReader([]byte(crd))
+	install.RunCommand(violationRecordCommand)
+
 	kritisConfigCommand := exec.Command("kubectl", "apply", "-f", "-")
 	crd = fmt.Sprintf(kritisConfigCRD, kritisInstallLabel)
 	kritisConfigCommand.Stdin = bytes.NewReader([]byte(crd))
 	install.RunCommand(kritisConfigCommand)
+
+	attestorCommand := exec.Command("kubectl", "apply", "-f", "-")
+	crd = fmt.Sprintf(attestorCRD, kritisInstallLabel)
+	attestorCommand.Stdin = bytes.NewReader([]byte(crd))
+	install.RunCommand(attestorCommand)
 }
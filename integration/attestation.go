@@ -5,7 +5,7 @@ Licensed under the Apache License, Version 2.0 (the "License");
 you may not use this file except in compliance with the License.
 You may obtain a copy of the License at
 
-    http://www.apache.org/licenses/LICENSE-2.0
+	http://www.apache.org/licenses/LICENSE-2.0
 
 Unless required by applicable law or agreed to in writing, software
 distributed under the License is distributed on an "AS IS" BASIS,
@@ -16,6 +16,7 @@ limitations under the License.
 package integration
 
 import (
+	"context"
 	"testing"
 
 	"github.com/grafeas/kritis/pkg/kritis/metadata/containeranalysis"
@@ -36,7 +37,7 @@ func getAttestations(t *testing.T, images []string) map[string]bool {
 		t.Fatalf("Unexpected error while fetching remote client %v", err)
 	}
 	for _, i := range images {
-		occs, err := client.Attestations(i)
+		occs, err := client.Attestations(context.Background(), i)
 		if err != nil {
 			t.Fatalf("Unexpected error while listing attestations for image %s, %v", i, err)
 		}
@@ -44,7 +45,7 @@ func getAttestations(t *testing.T, images []string) map[string]bool {
 			m[i] = true
 		}
 		for _, o := range occs {
-			if err := remote.DeleteOccurrence(o.OccID); err != nil {
+			if err := remote.DeleteOccurrence(context.Background(), o.OccID); err != nil {
 				t.Logf("could not delete attestations occurrence %s due to %v", o.OccID, err)
 			}
 		}
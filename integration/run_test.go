@@ -154,6 +154,9 @@ func installKritis(cs kubernetes.Interface, ns *v1.Namespace) (func(*testing.T),
 		"--set", fmt.Sprintf("tlsSecretName=tls-webhook-secret-%s", ns.Name),
 		"--set", fmt.Sprintf("clusterRoleBindingName=kritis-clusterrolebinding-%s", ns.Name),
 		"--set", fmt.Sprintf("clusterRoleName=kritis-clusterrole-%s", ns.Name),
+		"--set", fmt.Sprintf("serverClusterRoleBindingName=kritis-server-clusterrolebinding-%s", ns.Name),
+		"--set", fmt.Sprintf("serverClusterRoleName=kritis-server-clusterrole-%s", ns.Name),
+		"--set", fmt.Sprintf("attestationsReaderClusterRoleName=kritis-attestations-reader-%s", ns.Name),
 		"--set", fmt.Sprintf("serviceName=kritis-validation-hook-%s", ns.Name),
 		"--set", fmt.Sprintf("serviceNameDeployments=kritis-validation-hook-deployments-%s", ns.Name),
 	)
@@ -17,28 +17,53 @@ limitations under the License.
 package admission
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 
+	"github.com/grafeas/kritis/pkg/kritis/metadata/azure"
 	"github.com/grafeas/kritis/pkg/kritis/metadata/containeranalysis"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/ecr"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/file"
 	"github.com/grafeas/kritis/pkg/kritis/metadata/grafeas"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/harbor"
+	"github.com/grafeas/kritis/pkg/kritis/metadata/osv"
 	"github.com/grafeas/kritis/pkg/kritis/util"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/grafeas/kritis/cmd/kritis/version"
 	"github.com/grafeas/kritis/pkg/kritis/admission/constants"
 	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
-	kritisconstants "github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/crd/attestationpolicy"
 	"github.com/grafeas/kritis/pkg/kritis/crd/authority"
+	"github.com/grafeas/kritis/pkg/kritis/crd/imagereview"
+	"github.com/grafeas/kritis/pkg/kritis/crd/informers"
 	"github.com/grafeas/kritis/pkg/kritis/crd/kritisconfig"
 	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/decisionlog"
+	"github.com/grafeas/kritis/pkg/kritis/exemption"
+	"github.com/grafeas/kritis/pkg/kritis/faults"
+	kubernetesutil "github.com/grafeas/kritis/pkg/kritis/kubernetes"
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/metrics"
+	"github.com/grafeas/kritis/pkg/kritis/notify"
+	"github.com/grafeas/kritis/pkg/kritis/registryauth"
 	"github.com/grafeas/kritis/pkg/kritis/review"
 	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/tracing"
+	"github.com/grafeas/kritis/pkg/kritis/transparency"
 	"github.com/grafeas/kritis/pkg/kritis/violation"
+	"golang.org/x/time/rate"
 	"k8s.io/api/admission/v1beta1"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
@@ -47,12 +72,21 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 )
 
+// defaultWebhookTimeout is the timeout of admissionregistration.k8s.io/v1beta1 webhooks
+// without timeoutSeconds, for API servers which don't send it.
+const defaultWebhookTimeout = 30 * time.Second
+
 type config struct {
 	retrievePod                func(r *http.Request) (*v1.Pod, v1beta1.AdmissionReview, error)
 	retrieveDeployment         func(r *http.Request) (*appsv1.Deployment, v1beta1.AdmissionReview, error)
 	fetchMetadataClient        func(config *Config) (metadata.Fetcher, error)
 	fetchImageSecurityPolicies func(namespace string) ([]kritisv1beta1.ImageSecurityPolicy, error)
-	reviewer                   func(metadata.Fetcher) reviewer
+	fetchAttestationPolicies   func(namespace string) ([]kritisv1beta1.GenericAttestationPolicy, error)
+	reviewer                   func(metadata.Fetcher, securitypolicy.AttestorFetcher, *Config) review.Interface
+	fetchNamespace             func(name string) (*v1.Namespace, error)
+	fetchPod                   func(namespace, name string) (*v1.Pod, error)
+	recordEvent                func(event *v1.Event) error
+	fetchAttestors             func() (securitypolicy.AttestorFetcher, error)
 }
 
 var (
@@ -62,7 +96,12 @@ var (
 		retrieveDeployment:         unmarshalDeployment,
 		fetchMetadataClient:        MetadataClient,
 		fetchImageSecurityPolicies: securitypolicy.ImageSecurityPolicies,
+		fetchAttestationPolicies:   attestationpolicy.GenericAttestationPolicies,
 		reviewer:                   getReviewer,
+		fetchNamespace:             informers.Namespace,
+		fetchPod:                   kubernetesutil.Pod,
+		recordEvent:                kubernetesutil.CreateEvent,
+		fetchAttestors:             securitypolicy.NewAttestorFetcher,
 	}
 
 	defaultViolationStrategy = &violation.LoggingStrategy{}
@@ -75,28 +114,90 @@ var (
 
 // Config is the metadata client configuration
 type Config struct {
-	Metadata string // Metadata is the name of the metadata client fetcher
-	Grafeas  kritisv1beta1.GrafeasConfigSpec
+	Metadata             string // Metadata is the name of the metadata client fetcher
+	Grafeas              kritisv1beta1.GrafeasConfigSpec
+	Azure                kritisv1beta1.AzureConfigSpec
+	Harbor               []kritisv1beta1.HarborRegistrySpec
+	OSV                  kritisv1beta1.OSVConfigSpec             // OSV enriches the vulnerabilities of the backend, if enabled
+	MetadataFile         string                                  // MetadataFile is the fixtures file read by the file backend
+	VulnerabilityBundle  kritisv1beta1.VulnerabilityBundleSpec   // VulnerabilityBundle is read by the file backend instead of MetadataFile
+	Bundle               *file.Bundle                            // Bundle serves the VulnerabilityBundle loaded at startup, if set
+	SkipMetadataKinds    []string                                // SkipMetadataKinds are metadata kinds never fetched from the backend
+	MetadataTimeout      time.Duration                           // MetadataTimeout bounds each call to the backend, if set
+	MetadataFallback     string                                  // MetadataFallback of the calls which time out, see metadata.NewTimeoutFetcher
+	RateLimiter          *rate.Limiter                           // RateLimiter is shared by all calls to the backend, if set
+	FailurePolicy        kritisv1beta1.MetadataFailurePolicySpec // FailurePolicy sets whether reviews failing because of the backend admit the images
+	AttestationLog       *transparency.Log                       // AttestationLog records the attestations created by the webhook
+	Notifier             notify.Sender                           // Notifier is sent the violations found, if set
+	ViolationStrategy    *violation.Chain                        // ViolationStrategy handles violations instead of the default strategy, if set
+	AuditDecisions       bool                                    // AuditDecisions records the violations found as Discovery occurrences
+	RecordImageReviews   bool                                    // RecordImageReviews creates an ImageReview for each image and policy reviewed
+	Promotions           []kritisv1beta1.PromotionSpec           // Promotions attest the images passing the policies of staging namespaces
+	AnnotateDecisions    bool                                    // AnnotateDecisions has the mutating webhook annotate the pods it admits with the decision
+	ReviewOnPolicyChange bool                                    // ReviewOnPolicyChange has the cron job review pods again when their ImageSecurityPolicies change
+	DecisionLog          *decisionlog.Log                        // DecisionLog is written every admission decision, if set
+	Exemptions           *exemption.Matcher                      // Exemptions select the objects admitted without review, if set
 }
 
 // MetadataClient returns metadata.Fetcher based on the admission control config
 func MetadataClient(config *Config) (metadata.Fetcher, error) {
+	client, err := metadataBackend(config)
+	if err != nil {
+		return nil, err
+	}
+	client = metadata.NewRateLimitedFetcher(client, config.RateLimiter)
+	client = metadata.NewTimeoutFetcher(faults.NewFetcher(client), config.MetadataTimeout, config.MetadataFallback)
+	client = metadata.NewErrorFetcher(client, config.Metadata)
+	if config.OSV.Enabled {
+		client = osv.NewEnrichingFetcher(client, config.OSV)
+	}
+	if client, err = metadata.NewSkippingFetcher(client, config.SkipMetadataKinds); err != nil {
+		return nil, err
+	}
+	return tracing.NewFetcher(client), nil
+}
+
+func metadataBackend(config *Config) (metadata.Fetcher, error) {
 	if config.Metadata == constants.GrafeasMetadata {
 		return grafeas.New(config.Grafeas)
 	}
 	if config.Metadata == constants.ContainerAnalysisMetadata {
 		return containeranalysis.NewCache()
 	}
+	if config.Metadata == constants.FileMetadata {
+		if config.Bundle != nil {
+			return config.Bundle.Client(), nil
+		}
+		if config.VulnerabilityBundle.Path != "" || config.VulnerabilityBundle.Image != "" {
+			return file.LoadBundle(config.VulnerabilityBundle)
+		}
+		return file.New(config.MetadataFile)
+	}
+	if config.Metadata == constants.ECRMetadata {
+		return ecr.New()
+	}
+	if config.Metadata == constants.AzureMetadata {
+		return azure.New(config.Azure)
+	}
+	if config.Metadata == constants.HarborMetadata {
+		return harbor.New(config.Harbor)
+	}
 	return nil, fmt.Errorf("unsupported backend %q", config.Metadata)
 }
 
-var handlers = map[string]func(*v1beta1.AdmissionReview, *v1beta1.AdmissionReview, *Config) error{
-	"Deployment": handleDeployment,
-	"Pod":        handlePod,
-	"ReplicaSet": handleReplicaSet,
+var handlers = map[string]func(context.Context, *v1beta1.AdmissionReview, *v1beta1.AdmissionReview, *Config) error{
+	"Deployment":  handleDeployment,
+	"Pod":         handlePod,
+	"ReplicaSet":  handleReplicaSet,
+	"StatefulSet": handlePodTemplate,
+	"DaemonSet":   handlePodTemplate,
+	"Job":         handlePodTemplate,
+	"CronJob":     handlePodTemplate,
+
+	"EphemeralContainers": handleEphemeralContainers,
 }
 
-func handleDeployment(ar *v1beta1.AdmissionReview, admitResponse *v1beta1.AdmissionReview, config *Config) error {
+func handleDeployment(ctx context.Context, ar *v1beta1.AdmissionReview, admitResponse *v1beta1.AdmissionReview, config *Config) error {
 	deployment := appsv1.Deployment{}
 	if err := json.Unmarshal(ar.Request.Object.Raw, &deployment); err != nil {
 		return err
@@ -121,21 +222,51 @@ func handleDeployment(ar *v1beta1.AdmissionReview, admitResponse *v1beta1.Admiss
 		}
 	}
 
-	reviewDeployment(&deployment, admitResponse, config)
+	reviewDeployment(ctx, &deployment, admitResponse, config)
 	return nil
 }
 
-func handlePod(ar *v1beta1.AdmissionReview, admitResponse *v1beta1.AdmissionReview, config *Config) error {
+func handlePod(ctx context.Context, ar *v1beta1.AdmissionReview, admitResponse *v1beta1.AdmissionReview, config *Config) error {
 	pod := v1.Pod{}
 	if err := json.Unmarshal(ar.Request.Object.Raw, &pod); err != nil {
 		return err
 	}
 	glog.Infof("handling pod %q", pod.Name)
-	reviewPod(&pod, admitResponse, config)
+	// Ephemeral containers are not decoded into the Pod, read their images from the object.
+	ephemeral, err := EphemeralContainerImages(ar.Request.Object.Raw)
+	if err != nil {
+		return err
+	}
+	reviewPod(ctx, &pod, append(PodImages(pod), ephemeral...), admitResponse, config)
+	return nil
+}
+
+// handleEphemeralContainers reviews the images of ephemeral containers added to a
+// running pod, e.g. by kubectl debug, on clusters which send them as EphemeralContainers.
+func handleEphemeralContainers(ctx context.Context, ar *v1beta1.AdmissionReview, admitResponse *v1beta1.AdmissionReview, config *Config) error {
+	ec := ephemeralContainers{}
+	if err := json.Unmarshal(ar.Request.Object.Raw, &ec); err != nil {
+		return err
+	}
+	glog.Infof("handling ephemeral containers of pod %q", ec.Name)
+	pod := v1.Pod{ObjectMeta: ec.ObjectMeta}
+	if ec.Namespace == "" {
+		pod.Namespace = ar.Request.Namespace
+	}
+	if p, err := admissionConfig.fetchPod(pod.Namespace, pod.Name); err == nil {
+		pod = *p
+	} else {
+		glog.Warningf("reviewing ephemeral containers without the labels of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	}
+	images := []string{}
+	for _, c := range ec.EphemeralContainers {
+		images = append(images, c.Image)
+	}
+	reviewPod(ctx, &pod, images, admitResponse, config)
 	return nil
 }
 
-func handleReplicaSet(ar *v1beta1.AdmissionReview, admitResponse *v1beta1.AdmissionReview, config *Config) error {
+func handleReplicaSet(ctx context.Context, ar *v1beta1.AdmissionReview, admitResponse *v1beta1.AdmissionReview, config *Config) error {
 	replicaSet := appsv1.ReplicaSet{}
 	if err := json.Unmarshal(ar.Request.Object.Raw, &replicaSet); err != nil {
 		return err
@@ -160,7 +291,7 @@ func handleReplicaSet(ar *v1beta1.AdmissionReview, admitResponse *v1beta1.Admiss
 		}
 	}
 
-	reviewReplicaSet(&replicaSet, admitResponse, config)
+	reviewReplicaSet(ctx, &replicaSet, admitResponse, config)
 	return nil
 }
 
@@ -212,6 +343,35 @@ func ReviewHandler(w http.ResponseWriter, r *http.Request, config *Config) {
 		return
 	}
 
+	// Continue the trace of the API server, if it sent one, and stop reviewing once
+	// the API server gives up on the webhook. The deadline also bounds the waits of all
+	// policies for scans, which end shortly before it.
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx = securitypolicy.WithEvents(ctx)
+	timeout, err := time.ParseDuration(r.URL.Query().Get("timeout"))
+	if err != nil || timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "admission.Review",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("kritis.kind", ar.Request.Kind.Kind),
+			attribute.String("kritis.namespace", ar.Request.Namespace),
+			attribute.String("kritis.operation", string(ar.Request.Operation)),
+		))
+	defer span.End()
+
+	decision := &decisionlog.Decision{
+		Time:      time.Now(),
+		Kind:      ar.Request.Kind.Kind,
+		Namespace: ar.Request.Namespace,
+		Name:      ar.Request.Name,
+		Operation: string(ar.Request.Operation),
+	}
+	ctx = decisionlog.NewContext(ctx, decision)
+
 	admitResponse := &v1beta1.AdmissionReview{
 		Response: &v1beta1.AdmissionResponse{
 			UID:     ar.Request.UID,
@@ -225,18 +385,29 @@ func ReviewHandler(w http.ResponseWriter, r *http.Request, config *Config) {
 
 	for k8sType, handler := range handlers {
 		if ar.Request.Kind.Kind == k8sType {
-			if err := handler(&ar, admitResponse, config); err != nil {
+			if err := handler(ctx, &ar, admitResponse, config); err != nil {
 				glog.Errorf("handler failed: %v", err)
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
 				http.Error(w, "Whoops! The handler failed!", http.StatusInternalServerError)
+				decision.Error = err.Error()
+				decision.LatencyMillis = time.Since(decision.Time).Milliseconds()
+				config.DecisionLog.Record(decision)
 				return
 			}
 
 		}
 	}
 
+	span.SetAttributes(attribute.Bool("kritis.allowed", admitResponse.Response.Allowed))
+	decision.Allowed = admitResponse.Response.Allowed
+	decision.Message = admitResponse.Response.Result.Message
+	decision.LatencyMillis = time.Since(decision.Time).Milliseconds()
+	config.DecisionLog.Record(decision)
+
 	// Send response
 	w.Header().Set("Content-Type", "application/json")
-	payload, err := json.Marshal(admitResponse)
+	payload, err := json.Marshal(withWarnings(admitResponse, decision.Warnings))
 	if err != nil {
 		glog.Errorf("failed to marshal response: %v", err)
 	}
@@ -245,7 +416,29 @@ func ReviewHandler(w http.ResponseWriter, r *http.Request, config *Config) {
 	}
 }
 
-func reviewDeployment(deployment *appsv1.Deployment, ar *v1beta1.AdmissionReview, config *Config) {
+// admissionReview is a v1beta1.AdmissionReview whose response has the warnings of
+// admission.k8s.io/v1, which the vendored v1beta1 API lacks. API servers before 1.19
+// ignore them.
+type admissionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *v1beta1.AdmissionRequest `json:"request,omitempty"`
+	Response        *admissionResponse        `json:"response,omitempty"`
+}
+
+type admissionResponse struct {
+	*v1beta1.AdmissionResponse
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func withWarnings(ar *v1beta1.AdmissionReview, warnings []string) *admissionReview {
+	return &admissionReview{
+		TypeMeta: ar.TypeMeta,
+		Request:  ar.Request,
+		Response: &admissionResponse{AdmissionResponse: ar.Response, Warnings: warnings},
+	}
+}
+
+func reviewDeployment(ctx context.Context, deployment *appsv1.Deployment, ar *v1beta1.AdmissionReview, config *Config) {
 	images := DeploymentImages(*deployment)
 
 	// Commented out (dragon3)
@@ -256,12 +449,36 @@ func reviewDeployment(deployment *appsv1.Deployment, ar *v1beta1.AdmissionReview
 	// 	return
 	// }
 
-	// check for a breakglass annotation on the deployment
-	if checkBreakglass(&deployment.ObjectMeta) {
-		glog.Infof("found breakglass annotation for %q, returning successful status", deployment.Name)
+	if exempt(ar, constants.Deployment, &deployment.ObjectMeta, images, deployment.Spec.Template.Spec.ServiceAccountName, config) {
 		return
 	}
-	reviewImages(images, deployment.Namespace, nil, ar, config)
+	// check for a breakglass annotation on the deployment or its namespace
+	if breakglassed(&deployment.ObjectMeta) {
+		reviewBreakglass(ctx, constants.Deployment, &deployment.ObjectMeta, images, nil, deployment.Spec.Template.Labels, ar, config)
+		return
+	}
+	reviewImages(ctx, images, deployment.Namespace, nil, deployment.Spec.Template.Labels, ar, config)
+	describeDenial(ar, constants.Deployment, &deployment.ObjectMeta, deployment.Spec.Template.Spec)
+}
+
+// exempt returns true, and admits the object, if its pods, with images and running as
+// serviceAccount, match an exemption of the KritisConfig. Objects are reviewed if
+// exemptions can't be checked.
+func exempt(ar *v1beta1.AdmissionReview, kind string, meta *metav1.ObjectMeta, images []string, serviceAccount string, config *Config) bool {
+	ok, err := config.Exemptions.Exempt(meta.Namespace, images, serviceAccount)
+	if err != nil {
+		glog.Errorf("failed to check exemptions of %s %s/%s, reviewing it: %v", kind, meta.Namespace, objectName(meta), err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	glog.Infof("%s %s/%s is exempt from review", kind, meta.Namespace, objectName(meta))
+	ar.Response.Result = &metav1.Status{
+		Status:  string(constants.SuccessStatus),
+		Message: constants.ExemptMessage,
+	}
+	return true
 }
 
 func createDeniedResponse(ar *v1beta1.AdmissionReview, message string) {
@@ -272,7 +489,8 @@ func createDeniedResponse(ar *v1beta1.AdmissionReview, message string) {
 	}
 }
 
-func reviewImages(images []string, ns string, pod *v1.Pod, ar *v1beta1.AdmissionReview, config *Config) {
+// reviewImages reviews images against the policies of the namespace which select podLabels.
+func reviewImages(ctx context.Context, images []string, ns string, pod *v1.Pod, podLabels map[string]string, ar *v1beta1.AdmissionReview, config *Config) {
 	// NOTE: pod may be nil if we are reviewing images for a replica set.
 	glog.Infof("reviewing images for pod in namespace %s: %s", ns, images)
 	isps, err := admissionConfig.fetchImageSecurityPolicies(ns)
@@ -282,14 +500,43 @@ func reviewImages(images []string, ns string, pod *v1.Pod, ar *v1beta1.Admission
 		createDeniedResponse(ar, errMsg)
 		return
 	}
-	if len(isps) == 0 {
-		glog.Infof("no ImageSecurityPolicy found in namespace %s, skip reviewing", ns)
+	isps, err = securitypolicy.SelectImageSecurityPolicies(isps, podLabels)
+	if err != nil {
+		errMsg := fmt.Sprintf("error selecting image security policies: %v", err)
+		glog.Errorf(errMsg)
+		createDeniedResponse(ar, errMsg)
+		return
+	}
+	gaps, err := admissionConfig.fetchAttestationPolicies(ns)
+	if err != nil {
+		errMsg := fmt.Sprintf("error getting generic attestation policies: %v", err)
+		glog.Errorf(errMsg)
+		createDeniedResponse(ar, errMsg)
+		return
+	}
+	gaps, err = attestationpolicy.SelectGenericAttestationPolicies(gaps, podLabels)
+	if err != nil {
+		errMsg := fmt.Sprintf("error selecting generic attestation policies: %v", err)
+		glog.Errorf(errMsg)
+		createDeniedResponse(ar, errMsg)
+		return
+	}
+	if len(isps) == 0 && len(gaps) == 0 {
+		glog.Infof("no ImageSecurityPolicy or GenericAttestationPolicy found in namespace %s, skip reviewing", ns)
 		return
 	}
 
-	glog.Infof("found %d ImageSecurityPolicy to review image against", len(isps))
+	glog.Infof("found %d ImageSecurityPolicy and %d GenericAttestationPolicy to review image against", len(isps), len(gaps))
+	decision := decisionlog.FromContext(ctx)
+	decision.AddImages(images...)
+	for _, isp := range isps {
+		decision.AddPolicy("ImageSecurityPolicy", isp.Namespace, isp.Name, isp.ResourceVersion, securitypolicy.Hash(isp))
+	}
+	for _, gap := range gaps {
+		decision.AddPolicy("GenericAttestationPolicy", gap.Namespace, gap.Name, gap.ResourceVersion, "")
+	}
 
-	resolvedImages, err := resolveImagesToDigest(images)
+	resolvedImages, err := resolveImagesForPolicies(images, isps, podKeychain(ns, pod))
 	if err != nil {
 		errMsg := fmt.Sprintf("error resolving tagged images into digest: %v", err)
 		glog.Errorf(errMsg)
@@ -306,15 +553,35 @@ func reviewImages(images []string, ns string, pod *v1.Pod, ar *v1beta1.Admission
 		createDeniedResponse(ar, errMsg)
 		return
 	}
-	r := admissionConfig.reviewer(client)
-	if err := r.Review(resolvedImages, isps, pod); err != nil {
+	attestors, err := admissionConfig.fetchAttestors()
+	if err != nil {
+		errMsg := fmt.Sprintf("error creating attestor fetcher: %v", err)
+		glog.Errorf(errMsg)
+		createDeniedResponse(ar, errMsg)
+		return
+	}
+	r := admissionConfig.reviewer(client, attestors, config)
+	err = r.ReviewContext(ctx, resolvedImages, isps, pod)
+	if err == nil {
+		err = r.ReviewGAP(ctx, resolvedImages, gaps, pod)
+	}
+	if err == nil {
+		r.Promote(ctx, resolvedImages, isps, pod)
+	}
+	if err != nil {
+		if metadata.FailsOpen(config.FailurePolicy, err) {
+			glog.Warningf("admitting %s in namespace %s as the metadata backend failed: %v", resolvedImages, ns, err)
+			metrics.FailOpenAdmissions.Add(ns, 1)
+			decision.SetFailedOpen()
+			return
+		}
 		glog.Infof("denying %s in namespace %s: %v", resolvedImages, ns, err)
 		createDeniedResponse(ar, err.Error())
 	}
 }
 
-func reviewPod(pod *v1.Pod, ar *v1beta1.AdmissionReview, config *Config) {
-	images := PodImages(*pod)
+// reviewPod reviews the images of a pod, including those of its ephemeral containers.
+func reviewPod(ctx context.Context, pod *v1.Pod, images []string, ar *v1beta1.AdmissionReview, config *Config) {
 
 	// Commented out (dragon3)
 	//
@@ -324,15 +591,18 @@ func reviewPod(pod *v1.Pod, ar *v1beta1.AdmissionReview, config *Config) {
 	// 	return
 	// }
 
-	// check for a breakglass annotation on the pod
-	if checkBreakglass(&pod.ObjectMeta) {
-		glog.Infof("found breakglass annotation for %q, returning successful status", pod.Name)
+	if exempt(ar, constants.Pod, &pod.ObjectMeta, images, pod.Spec.ServiceAccountName, config) {
 		return
 	}
-	reviewImages(images, pod.Namespace, pod, ar, config)
+	// check for a breakglass annotation on the pod or its namespace
+	if breakglassed(&pod.ObjectMeta) {
+		reviewBreakglass(ctx, constants.Pod, &pod.ObjectMeta, images, pod, pod.Labels, ar, config)
+		return
+	}
+	reviewImages(ctx, images, pod.Namespace, pod, pod.Labels, ar, config)
 }
 
-func reviewReplicaSet(replicaSet *appsv1.ReplicaSet, ar *v1beta1.AdmissionReview, config *Config) {
+func reviewReplicaSet(ctx context.Context, replicaSet *appsv1.ReplicaSet, ar *v1beta1.AdmissionReview, config *Config) {
 	images := ReplicaSetImages(*replicaSet)
 
 	// Commented out (dragon3)
@@ -343,12 +613,16 @@ func reviewReplicaSet(replicaSet *appsv1.ReplicaSet, ar *v1beta1.AdmissionReview
 	// 	return
 	// }
 
-	// check for a breakglass annotation on the replica set
-	if checkBreakglass(&replicaSet.ObjectMeta) {
-		glog.Infof("found breakglass annotation for %q, returning successful status", replicaSet.Name)
+	if exempt(ar, constants.ReplicaSet, &replicaSet.ObjectMeta, images, replicaSet.Spec.Template.Spec.ServiceAccountName, config) {
 		return
 	}
-	reviewImages(images, replicaSet.Namespace, nil, ar, config)
+	// check for a breakglass annotation on the replica set or its namespace
+	if breakglassed(&replicaSet.ObjectMeta) {
+		reviewBreakglass(ctx, constants.ReplicaSet, &replicaSet.ObjectMeta, images, nil, replicaSet.Spec.Template.Labels, ar, config)
+		return
+	}
+	reviewImages(ctx, images, replicaSet.Namespace, nil, replicaSet.Spec.Template.Labels, ar, config)
+	describeDenial(ar, constants.ReplicaSet, &replicaSet.ObjectMeta, replicaSet.Spec.Template.Spec)
 }
 
 // TODO(aaron-prindle) remove these functions
@@ -384,43 +658,64 @@ func unmarshalDeployment(r *http.Request) (*appsv1.Deployment, v1beta1.Admission
 	return &deployment, ar, nil
 }
 
-func checkBreakglass(meta *metav1.ObjectMeta) bool {
-	annotations := meta.GetAnnotations()
-	if annotations == nil {
-		return false
+func getReviewer(client metadata.Fetcher, attestors securitypolicy.AttestorFetcher, config *Config) review.Interface {
+	var recordAttestation func(transparency.Record) error
+	if config.AttestationLog != nil {
+		recordAttestation = config.AttestationLog.Append
+	}
+	var recordReview func(*kritisv1beta1.ImageReview) error
+	if config.RecordImageReviews {
+		recordReview = imagereview.Record
 	}
-	_, ok := annotations[kritisconstants.Breakglass]
-	return ok
-}
 
-func getReviewer(client metadata.Fetcher) reviewer {
-	attestorFetcher, err := securitypolicy.NewAttestorFetcher()
-	if err != nil {
-		glog.Fatalf("failed to create an attestorFetcher: %v", err)
+	strategy := violation.WithNotifications(defaultViolationStrategy, config.Notifier)
+	if config.ViolationStrategy != nil {
+		strategy = config.ViolationStrategy
 	}
 
 	return review.New(client, &review.Config{
-		Strategy:                        defaultViolationStrategy,
+		Strategy:                        strategy,
+		Notifier:                        config.Notifier,
 		IsWebhook:                       true,
 		Secret:                          secrets.Fetch,
 		Auths:                           authority.Authority,
+		AuthLister:                      authority.Authorities,
+		DefaultAuth:                     authority.DefaultAuthority,
 		Validate:                        securitypolicy.ValidateImageSecurityPolicy,
-		Attestors:                       attestorFetcher,
+		Attestors:                       attestors,
 		ClusterWhitelistedImagesRemover: kritisconfig.RemoveWhitelistedImages,
+		RecordAttestation:               recordAttestation,
+		AuditDecisions:                  config.AuditDecisions,
+		RecordReview:                    recordReview,
+		Promotions:                      config.Promotions,
 	})
 }
 
-// reviewer interface defines an Kritis Reviewer Struct.
-// TODO: This will be removed in future refactoring.
-type reviewer interface {
-	Review(images []string, isps []kritisv1beta1.ImageSecurityPolicy, pod *v1.Pod) error
+// podKeychain returns the registry credentials of the pod, or the default ones if pod is nil.
+func podKeychain(ns string, pod *v1.Pod) authn.Keychain {
+	if pod == nil {
+		return registryauth.Keychain()
+	}
+	return registryauth.ForPod(ns, pod.Spec.ServiceAccountName, pod.Spec.ImagePullSecrets)
+}
+
+// resolveImagesForPolicies resolves tagged images into digest, unless a policy requires
+// images to be pinned by digest.
+func resolveImagesForPolicies(images []string, isps []kritisv1beta1.ImageSecurityPolicy, kc authn.Keychain) ([]string, error) {
+	for _, isp := range isps {
+		if isp.Spec.RequireImageDigest {
+			glog.Infof("ImageSecurityPolicy %q requires image digests, not resolving tagged images", isp.Name)
+			return images, nil
+		}
+	}
+	return resolveImagesToDigest(images, kc)
 }
 
-func resolveImagesToDigest(images []string) ([]string, error) {
+func resolveImagesToDigest(images []string, kc authn.Keychain) ([]string, error) {
 	resolved := []string{}
 
 	for _, image := range images {
-		resolvedImage, err := util.ResolveImageToDigest(image)
+		resolvedImage, err := util.ResolveImageToDigest(image, kc)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to resolve image into digest")
 		}
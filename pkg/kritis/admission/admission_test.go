@@ -18,21 +18,35 @@ package admission
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"expvar"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/grafeas/kritis/cmd/kritis/version"
 	"github.com/grafeas/kritis/pkg/kritis/admission/constants"
 	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/decisionlog"
+	"github.com/grafeas/kritis/pkg/kritis/exemption"
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/metrics"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/review"
 	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	"github.com/pkg/errors"
 	"k8s.io/api/admission/v1beta1"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 )
 
@@ -44,24 +58,99 @@ type testConfig struct {
 	message    string
 }
 
+func nilAttestorFetcher() (securitypolicy.AttestorFetcher, error) {
+	return nil, nil
+}
+
 func Test_BreakglassAnnotation(t *testing.T) {
-	mockPod := func(r *http.Request) (*v1.Pod, v1beta1.AdmissionReview, error) {
-		return &v1.Pod{
-			ObjectMeta: metav1.ObjectMeta{
-				Annotations: map[string]string{"kritis.grafeas.io/breakglass": "true"},
-			},
-		}, v1beta1.AdmissionReview{}, nil
+	tcs := []struct {
+		name        string
+		annotations map[string]string
+		nsAnnots    map[string]string
+		reviewErr   bool
+		expectedMsg string
+	}{
+		{
+			name:        "pod annotation with violations",
+			annotations: map[string]string{"kritis.grafeas.io/breakglass": "true"},
+			reviewErr:   true,
+			expectedMsg: fmt.Sprintf("Pod test/pod admitted with breakglass annotation despite violations: found violations in %s", testutil.QualifiedImage),
+		},
+		{
+			name:        "namespace annotation without violations",
+			nsAnnots:    map[string]string{"kritis.grafeas.io/breakglass": "true"},
+			expectedMsg: "Pod test/pod admitted with breakglass annotation",
+		},
 	}
-	mockConfig := config{
-		retrievePod: mockPod,
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			mockPod := func(r *http.Request) (*v1.Pod, v1beta1.AdmissionReview, error) {
+				return &v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "pod",
+						Namespace:   "test",
+						Annotations: tc.annotations,
+					},
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{{Image: testutil.QualifiedImage}},
+					},
+				}, v1beta1.AdmissionReview{}, nil
+			}
+			client := &testutil.MockMetadataClient{}
+			var events []*v1.Event
+			mockConfig := config{
+				retrievePod: mockPod,
+				fetchMetadataClient: func(config *Config) (metadata.Fetcher, error) {
+					return client, nil
+				},
+				fetchImageSecurityPolicies: func(namespace string) ([]kritisv1beta1.ImageSecurityPolicy, error) {
+					return []kritisv1beta1.ImageSecurityPolicy{{}}, nil
+				},
+				fetchAttestationPolicies: noAttestationPolicies,
+				fetchAttestors:           nilAttestorFetcher,
+				reviewer: func(client metadata.Fetcher, attestors securitypolicy.AttestorFetcher, config *Config) review.Interface {
+					return testutil.NewReviewer(tc.reviewErr, fmt.Sprintf("found violations in %s", testutil.QualifiedImage))
+				},
+				fetchNamespace: func(name string) (*v1.Namespace, error) {
+					return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: tc.nsAnnots}}, nil
+				},
+				recordEvent: func(event *v1.Event) error {
+					events = append(events, event)
+					return nil
+				},
+			}
+			before := breakglassCount(t, "test")
+			RunTest(t, testConfig{
+				mockConfig: mockConfig,
+				httpStatus: http.StatusOK,
+				allowed:    true,
+				status:     constants.SuccessStatus,
+				message:    constants.BreakglassMessage,
+			})
+			if len(events) != 1 {
+				t.Fatalf("expected 1 event, got %d", len(events))
+			}
+			if events[0].Reason != constants.BreakglassEventReason || events[0].Message != tc.expectedMsg {
+				t.Errorf("unexpected event %s: %s", events[0].Reason, events[0].Message)
+			}
+			expectedOcc := map[string]string{
+				fmt.Sprintf("%s-%s", testutil.QualifiedImage, constants.BreakglassNoteID): tc.expectedMsg,
+			}
+			testutil.DeepEqual(t, expectedOcc, client.Discovery)
+			if after := breakglassCount(t, "test"); after != before+1 {
+				t.Errorf("expected breakglass metric to be %d, got %d", before+1, after)
+			}
+		})
 	}
-	RunTest(t, testConfig{
-		mockConfig: mockConfig,
-		httpStatus: http.StatusOK,
-		allowed:    true,
-		status:     constants.SuccessStatus,
-		message:    constants.SuccessMessage,
-	})
+}
+
+func breakglassCount(t *testing.T, namespace string) int64 {
+	t.Helper()
+	v, ok := metrics.BreakglassAdmissions.Get(namespace).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	return v.Value()
 }
 
 func TestReviewHandler(t *testing.T) {
@@ -95,6 +184,417 @@ func TestReviewHandler(t *testing.T) {
 	}
 }
 
+func TestWithWarnings(t *testing.T) {
+	ar := &v1beta1.AdmissionReview{
+		Response: &v1beta1.AdmissionResponse{UID: "uid", Allowed: true},
+	}
+	b, err := json.Marshal(withWarnings(ar, []string{"key failed to parse"}))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var actual map[string]map[string]interface{}
+	if err := json.Unmarshal(b, &actual); err != nil {
+		t.Fatalf("%v", err)
+	}
+	expected := map[string]interface{}{
+		"uid":      "uid",
+		"allowed":  true,
+		"warnings": []interface{}{"key failed to parse"},
+	}
+	testutil.DeepEqual(t, expected, actual["response"])
+}
+
+// deadlineReviewer records the time left before the deadline of the context of reviews.
+type deadlineReviewer struct {
+	deadlines *[]time.Duration
+}
+
+func (d deadlineReviewer) ReviewContext(ctx context.Context, images []string, isps []kritisv1beta1.ImageSecurityPolicy, pod *v1.Pod) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		*d.deadlines = append(*d.deadlines, time.Until(deadline).Round(time.Second))
+	}
+	return nil
+}
+
+func (d deadlineReviewer) ReviewGAP(ctx context.Context, images []string, gaps []kritisv1beta1.GenericAttestationPolicy, pod *v1.Pod) error {
+	return nil
+}
+
+func (d deadlineReviewer) Validate(ctx context.Context, image string, isps []kritisv1beta1.ImageSecurityPolicy) ([][]policy.Violation, error) {
+	return make([][]policy.Violation, len(isps)), nil
+}
+
+func (d deadlineReviewer) ValidateGAP(ctx context.Context, image string, gaps []kritisv1beta1.GenericAttestationPolicy) ([][]policy.Violation, error) {
+	return make([][]policy.Violation, len(gaps)), nil
+}
+
+func (d deadlineReviewer) Promote(ctx context.Context, images []string, isps []kritisv1beta1.ImageSecurityPolicy, pod *v1.Pod) {
+}
+
+func TestReviewHandlerDeadline(t *testing.T) {
+	var deadlines []time.Duration
+	original := admissionConfig
+	defer func() {
+		admissionConfig = original
+	}()
+	admissionConfig = config{
+		fetchMetadataClient: func(config *Config) (metadata.Fetcher, error) {
+			return &testutil.MockMetadataClient{}, nil
+		},
+		fetchImageSecurityPolicies: func(namespace string) ([]kritisv1beta1.ImageSecurityPolicy, error) {
+			return []kritisv1beta1.ImageSecurityPolicy{{}}, nil
+		},
+		fetchAttestationPolicies: noAttestationPolicies,
+		fetchAttestors:           nilAttestorFetcher,
+		reviewer: func(client metadata.Fetcher, attestors securitypolicy.AttestorFetcher, config *Config) review.Interface {
+			return deadlineReviewer{deadlines: &deadlines}
+		},
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ReviewHandler(w, r, &Config{})
+	}))
+	defer s.Close()
+
+	pod, err := json.Marshal(v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Image: testutil.QualifiedImage}}}})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	blob, err := json.Marshal(v1beta1.AdmissionReview{
+		Request: &v1beta1.AdmissionRequest{
+			Kind:   metav1.GroupVersionKind{Kind: "Pod"},
+			Object: runtime.RawExtension{Raw: pod},
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// The API server sets the timeout of the webhook as query parameter, 30s by default.
+	for _, path := range []string{"/", "/?timeout=10s"} {
+		resp, err := http.Post(s.URL+path, "", bytes.NewReader(blob))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected OK status code, actual %s", resp.Status)
+		}
+	}
+	testutil.DeepEqual(t, []time.Duration{30 * time.Second, 10 * time.Second}, deadlines)
+}
+
+func TestReviewHandlerDecisionLog(t *testing.T) {
+	original := admissionConfig
+	defer func() {
+		admissionConfig = original
+	}()
+	admissionConfig = config{
+		fetchMetadataClient: func(config *Config) (metadata.Fetcher, error) {
+			return &testutil.MockMetadataClient{}, nil
+		},
+		fetchImageSecurityPolicies: func(namespace string) ([]kritisv1beta1.ImageSecurityPolicy, error) {
+			return []kritisv1beta1.ImageSecurityPolicy{{ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: namespace}}}, nil
+		},
+		fetchAttestationPolicies: noAttestationPolicies,
+		fetchAttestors:           nilAttestorFetcher,
+		reviewer: func(client metadata.Fetcher, attestors securitypolicy.AttestorFetcher, config *Config) review.Interface {
+			return errReviewer{err: fmt.Errorf("found violations")}
+		},
+		fetchNamespace: func(name string) (*v1.Namespace, error) {
+			return &v1.Namespace{}, nil
+		},
+	}
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	decisionLog, err := decisionlog.New([]kritisv1beta1.DecisionLogSinkSpec{{Type: decisionlog.File, Path: path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ReviewHandler(w, r, &Config{DecisionLog: decisionLog})
+	}))
+	defer s.Close()
+
+	pod, err := json.Marshal(v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Image: testutil.QualifiedImage}}},
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	blob, err := json.Marshal(v1beta1.AdmissionReview{
+		Request: &v1beta1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Kind: "Pod"},
+			Namespace: "default",
+			Name:      "pod",
+			Operation: v1beta1.Create,
+			Object:    runtime.RawExtension{Raw: pod},
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	resp, err := http.Post(s.URL, "", bytes.NewReader(blob))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	resp.Body.Close()
+	decisionLog.Close()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d decisionlog.Decision
+	if err := json.Unmarshal(b, &d); err != nil {
+		t.Fatalf("invalid decision %q: %v", b, err)
+	}
+	testutil.DeepEqual(t, "Pod default/pod CREATE", fmt.Sprintf("%s %s/%s %s", d.Kind, d.Namespace, d.Name, d.Operation))
+	testutil.DeepEqual(t, false, d.Allowed)
+	testutil.DeepEqual(t, "found violations", d.Message)
+	testutil.DeepEqual(t, []string{testutil.QualifiedImage}, d.Images)
+	testutil.DeepEqual(t, []decisionlog.Policy{{Kind: "ImageSecurityPolicy", Name: "default/isp", Hash: securitypolicy.Hash(kritisv1beta1.ImageSecurityPolicy{})}}, d.Policies)
+}
+
+func TestReviewHandlerDecisionLogFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	decisionLog, err := decisionlog.New([]kritisv1beta1.DecisionLogSinkSpec{{Type: decisionlog.File, Path: path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ReviewHandler(w, r, &Config{DecisionLog: decisionLog})
+	}))
+	defer s.Close()
+
+	// The object is not a pod, so the handler fails.
+	blob, err := json.Marshal(v1beta1.AdmissionReview{
+		Request: &v1beta1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Kind: "Pod"},
+			Namespace: "default",
+			Name:      "pod",
+			Operation: v1beta1.Create,
+			Object:    runtime.RawExtension{Raw: []byte(`"pod"`)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	resp, err := http.Post(s.URL, "", bytes.NewReader(blob))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	resp.Body.Close()
+	decisionLog.Close()
+	testutil.DeepEqual(t, http.StatusInternalServerError, resp.StatusCode)
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d decisionlog.Decision
+	if err := json.Unmarshal(b, &d); err != nil {
+		t.Fatalf("invalid decision %q: %v", b, err)
+	}
+	testutil.DeepEqual(t, "Pod default/pod CREATE", fmt.Sprintf("%s %s/%s %s", d.Kind, d.Namespace, d.Name, d.Operation))
+	testutil.DeepEqual(t, false, d.Allowed)
+	if d.Error == "" {
+		t.Errorf("expected the failure to be recorded, got %q", b)
+	}
+}
+
+func TestReviewExemptions(t *testing.T) {
+	original := admissionConfig
+	defer func() {
+		admissionConfig = original
+	}()
+	admissionConfig = config{
+		fetchMetadataClient: func(config *Config) (metadata.Fetcher, error) {
+			return &testutil.MockMetadataClient{}, nil
+		},
+		fetchImageSecurityPolicies: func(namespace string) ([]kritisv1beta1.ImageSecurityPolicy, error) {
+			return []kritisv1beta1.ImageSecurityPolicy{{}}, nil
+		},
+		fetchAttestationPolicies: noAttestationPolicies,
+		fetchNamespace: func(name string) (*v1.Namespace, error) {
+			return &v1.Namespace{}, nil
+		},
+		fetchAttestors: nilAttestorFetcher,
+		reviewer: func(client metadata.Fetcher, attestors securitypolicy.AttestorFetcher, config *Config) review.Interface {
+			return errReviewer{err: fmt.Errorf("found violations")}
+		},
+	}
+	exemptions, err := exemption.New([]kritisv1beta1.ExemptionSpec{{Namespaces: []string{"kube-system"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		namespace string
+		allowed   bool
+		message   string
+	}{
+		{"kube-system", true, constants.ExemptMessage},
+		{"default", false, "found violations"},
+	} {
+		t.Run(test.namespace, func(t *testing.T) {
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: test.namespace},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Image: testutil.QualifiedImage}}},
+			}
+			ar := &v1beta1.AdmissionReview{Response: &v1beta1.AdmissionResponse{Allowed: true}}
+			reviewPod(context.Background(), pod, PodImages(*pod), ar, &Config{Exemptions: exemptions})
+			testutil.DeepEqual(t, test.allowed, ar.Response.Allowed)
+			testutil.DeepEqual(t, test.message, ar.Response.Result.Message)
+		})
+	}
+}
+
+// errReviewer fails all reviews with err.
+type errReviewer struct {
+	err error
+}
+
+func (e errReviewer) ReviewContext(ctx context.Context, images []string, isps []kritisv1beta1.ImageSecurityPolicy, pod *v1.Pod) error {
+	return e.err
+}
+
+func (e errReviewer) ReviewGAP(ctx context.Context, images []string, gaps []kritisv1beta1.GenericAttestationPolicy, pod *v1.Pod) error {
+	return nil
+}
+
+func (e errReviewer) Validate(ctx context.Context, image string, isps []kritisv1beta1.ImageSecurityPolicy) ([][]policy.Violation, error) {
+	return make([][]policy.Violation, len(isps)), nil
+}
+
+func (e errReviewer) ValidateGAP(ctx context.Context, image string, gaps []kritisv1beta1.GenericAttestationPolicy) ([][]policy.Violation, error) {
+	return make([][]policy.Violation, len(gaps)), nil
+}
+
+func (e errReviewer) Promote(ctx context.Context, images []string, isps []kritisv1beta1.ImageSecurityPolicy, pod *v1.Pod) {
+}
+
+func noAttestationPolicies(namespace string) ([]kritisv1beta1.GenericAttestationPolicy, error) {
+	return nil, nil
+}
+
+// gapReviewer passes ISP reviews and fails reviews of generic attestation policies with err.
+type gapReviewer struct {
+	err  error
+	isps *int
+	gaps *int
+}
+
+func (g gapReviewer) ReviewContext(ctx context.Context, images []string, isps []kritisv1beta1.ImageSecurityPolicy, pod *v1.Pod) error {
+	*g.isps += len(isps)
+	return nil
+}
+
+func (g gapReviewer) ReviewGAP(ctx context.Context, images []string, gaps []kritisv1beta1.GenericAttestationPolicy, pod *v1.Pod) error {
+	*g.gaps += len(gaps)
+	return g.err
+}
+
+func (g gapReviewer) Validate(ctx context.Context, image string, isps []kritisv1beta1.ImageSecurityPolicy) ([][]policy.Violation, error) {
+	return make([][]policy.Violation, len(isps)), nil
+}
+
+func (g gapReviewer) ValidateGAP(ctx context.Context, image string, gaps []kritisv1beta1.GenericAttestationPolicy) ([][]policy.Violation, error) {
+	return make([][]policy.Violation, len(gaps)), nil
+}
+
+func (g gapReviewer) Promote(ctx context.Context, images []string, isps []kritisv1beta1.ImageSecurityPolicy, pod *v1.Pod) {
+}
+
+func TestReviewImagesGenericAttestationPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		gaps    []kritisv1beta1.GenericAttestationPolicy
+		err     error
+		allowed bool
+		reviews int
+	}{
+		{"no policies", nil, nil, true, 0},
+		{"attested images", []kritisv1beta1.GenericAttestationPolicy{{}}, nil, true, 1},
+		{"unattested images", []kritisv1beta1.GenericAttestationPolicy{{}}, fmt.Errorf("found violations"), false, 1},
+		{"policies for other pods", []kritisv1beta1.GenericAttestationPolicy{{
+			Spec: kritisv1beta1.GenericAttestationPolicySpec{
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "other"}},
+			},
+		}}, fmt.Errorf("found violations"), true, 0},
+	}
+	original := admissionConfig
+	defer func() {
+		admissionConfig = original
+	}()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var isps, gaps int
+			admissionConfig = config{
+				fetchMetadataClient: func(config *Config) (metadata.Fetcher, error) {
+					return &testutil.MockMetadataClient{}, nil
+				},
+				fetchImageSecurityPolicies: func(namespace string) ([]kritisv1beta1.ImageSecurityPolicy, error) {
+					return nil, nil
+				},
+				fetchAttestationPolicies: func(namespace string) ([]kritisv1beta1.GenericAttestationPolicy, error) {
+					return test.gaps, nil
+				},
+				fetchAttestors: nilAttestorFetcher,
+				reviewer: func(client metadata.Fetcher, attestors securitypolicy.AttestorFetcher, config *Config) review.Interface {
+					return gapReviewer{err: test.err, isps: &isps, gaps: &gaps}
+				},
+			}
+			ar := &v1beta1.AdmissionReview{Response: &v1beta1.AdmissionResponse{Allowed: true}}
+			reviewImages(context.Background(), []string{testutil.QualifiedImage}, "default", nil, map[string]string{"app": "web"}, ar, &Config{})
+			if ar.Response.Allowed != test.allowed {
+				t.Errorf("expected allowed %t, got %t", test.allowed, ar.Response.Allowed)
+			}
+			if gaps != test.reviews {
+				t.Errorf("expected %d policies reviewed, got %d", test.reviews, gaps)
+			}
+		})
+	}
+}
+
+func TestReviewImagesFailurePolicy(t *testing.T) {
+	backendErr := errors.Wrap(&metadata.Error{Backend: constants.GrafeasMetadata, Method: "Vulnerabilities", Err: fmt.Errorf("unavailable")}, "failed validating image security policy")
+	tests := []struct {
+		name    string
+		policy  kritisv1beta1.MetadataFailurePolicySpec
+		err     error
+		allowed bool
+	}{
+		{"fails closed by default", kritisv1beta1.MetadataFailurePolicySpec{}, backendErr, false},
+		{"fails open", kritisv1beta1.MetadataFailurePolicySpec{OnError: metadata.FailOpen}, backendErr, true},
+		{"fails open for the backend", kritisv1beta1.MetadataFailurePolicySpec{OnError: metadata.FailOpen, Backends: []string{constants.GrafeasMetadata}}, backendErr, true},
+		{"fails closed for other backends", kritisv1beta1.MetadataFailurePolicySpec{OnError: metadata.FailOpen, Backends: []string{constants.HarborMetadata}}, backendErr, false},
+		{"violations are denied", kritisv1beta1.MetadataFailurePolicySpec{OnError: metadata.FailOpen}, fmt.Errorf("found violations"), false},
+	}
+	original := admissionConfig
+	defer func() {
+		admissionConfig = original
+	}()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			admissionConfig = config{
+				fetchMetadataClient: func(config *Config) (metadata.Fetcher, error) {
+					return &testutil.MockMetadataClient{}, nil
+				},
+				fetchImageSecurityPolicies: func(namespace string) ([]kritisv1beta1.ImageSecurityPolicy, error) {
+					return []kritisv1beta1.ImageSecurityPolicy{{}}, nil
+				},
+				fetchAttestationPolicies: noAttestationPolicies,
+				fetchAttestors:           nilAttestorFetcher,
+				reviewer: func(client metadata.Fetcher, attestors securitypolicy.AttestorFetcher, config *Config) review.Interface {
+					return errReviewer{err: test.err}
+				},
+			}
+			ar := &v1beta1.AdmissionReview{Response: &v1beta1.AdmissionResponse{Allowed: true}}
+			reviewImages(context.Background(), []string{testutil.QualifiedImage}, "default", nil, nil, ar, &Config{FailurePolicy: test.policy})
+			if ar.Response.Allowed != test.allowed {
+				t.Errorf("expected allowed %t, got %t", test.allowed, ar.Response.Allowed)
+			}
+		})
+	}
+}
+
 func Test_AdmissionResponse(t *testing.T) {
 	tcs := []struct {
 		name        string
@@ -110,7 +610,7 @@ func Test_AdmissionResponse(t *testing.T) {
 	}
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
-			mReviewer := func(client metadata.Fetcher) reviewer {
+			mReviewer := func(client metadata.Fetcher, attestors securitypolicy.AttestorFetcher, config *Config) review.Interface {
 				return testutil.NewReviewer(tc.reviewErr, tc.expectedMsg)
 			}
 			mockConfig := config{
@@ -119,7 +619,9 @@ func Test_AdmissionResponse(t *testing.T) {
 					return testutil.NilFetcher()()
 				},
 				fetchImageSecurityPolicies: mockISP,
+				fetchAttestationPolicies:   noAttestationPolicies,
 				reviewer:                   mReviewer,
+				fetchAttestors:             nilAttestorFetcher,
 			}
 			RunTest(t, testConfig{
 				mockConfig: mockConfig,
@@ -199,7 +701,7 @@ func PodTestReviewHandler(w http.ResponseWriter, r *http.Request) {
 			},
 		},
 	}
-	reviewPod(pod, admitResponse, &Config{Metadata: constants.ContainerAnalysisMetadata})
+	reviewPod(context.Background(), pod, PodImages(*pod), admitResponse, &Config{Metadata: constants.ContainerAnalysisMetadata})
 	// Send response
 	w.Header().Set("Content-Type", "application/json")
 	payload, err := json.Marshal(admitResponse)
@@ -210,3 +712,14 @@ func PodTestReviewHandler(w http.ResponseWriter, r *http.Request) {
 		glog.Errorf("unable to write payload: %v", err)
 	}
 }
+
+func Test_ResolveImagesForPolicies(t *testing.T) {
+	// Tagged images are kept as is when a policy requires digests, so the policy can reject them
+	images := []string{"gcr.io/kritis-project/image:latest"}
+	isps := []kritisv1beta1.ImageSecurityPolicy{
+		{},
+		{Spec: kritisv1beta1.ImageSecurityPolicySpec{RequireImageDigest: true}},
+	}
+	resolved, err := resolveImagesForPolicies(images, isps, authn.DefaultKeychain)
+	testutil.CheckErrorAndDeepEqual(t, false, err, images, resolved)
+}
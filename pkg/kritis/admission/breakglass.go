@@ -0,0 +1,127 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/grafeas/kritis/pkg/kritis/admission/constants"
+	kritisconstants "github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/decisionlog"
+	kubernetesutil "github.com/grafeas/kritis/pkg/kritis/kubernetes"
+	"github.com/grafeas/kritis/pkg/kritis/metrics"
+	"github.com/grafeas/kritis/pkg/kritis/registryauth"
+	"github.com/grafeas/kritis/pkg/kritis/util"
+	"k8s.io/api/admission/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// breakglassed returns true if the object or its namespace has a breakglass annotation.
+func breakglassed(meta *metav1.ObjectMeta) bool {
+	if checkBreakglass(meta) {
+		return true
+	}
+	if meta.Namespace == "" {
+		return false
+	}
+	ns, err := admissionConfig.fetchNamespace(meta.Namespace)
+	if err != nil {
+		glog.Errorf("error getting namespace %s: %v", meta.Namespace, err)
+		return false
+	}
+	return checkBreakglass(&ns.ObjectMeta)
+}
+
+// reviewBreakglass admits an object with a breakglass annotation. Its images are
+// still reviewed, and the outcome is recorded as an event, a Discovery occurrence
+// per image and a metric.
+func reviewBreakglass(ctx context.Context, kind string, meta *metav1.ObjectMeta, images []string, pod *v1.Pod, podLabels map[string]string, ar *v1beta1.AdmissionReview, config *Config) {
+	glog.Infof("found breakglass annotation for %q, admitting %s", objectName(meta), kind)
+	review := &v1beta1.AdmissionReview{
+		Response: &v1beta1.AdmissionResponse{Allowed: true},
+	}
+	decisionlog.FromContext(ctx).SetBreakglass()
+	reviewImages(ctx, images, meta.Namespace, pod, podLabels, review, config)
+
+	message := fmt.Sprintf("%s %s/%s admitted with breakglass annotation", kind, meta.Namespace, objectName(meta))
+	if !review.Response.Allowed {
+		message = fmt.Sprintf("%s despite violations: %s", message, review.Response.Result.Message)
+	}
+	auditBreakglass(ctx, kind, meta, images, message, config)
+
+	ar.Response.Result = &metav1.Status{
+		Status:  string(constants.SuccessStatus),
+		Message: constants.BreakglassMessage,
+	}
+}
+
+// auditBreakglass records a breakglass admission. Failures are logged, since the
+// object is admitted either way.
+func auditBreakglass(ctx context.Context, kind string, meta *metav1.ObjectMeta, images []string, message string, config *Config) {
+	glog.Warning(message)
+	metrics.BreakglassAdmissions.Add(meta.Namespace, 1)
+
+	event := kubernetesutil.NewEvent(v1.ObjectReference{
+		Kind:      kind,
+		Namespace: meta.Namespace,
+		Name:      objectName(meta),
+		UID:       meta.UID,
+	}, v1.EventTypeWarning, constants.BreakglassEventReason, message)
+	if err := admissionConfig.recordEvent(event); err != nil {
+		glog.Errorf("error recording breakglass event for %s: %v", objectName(meta), err)
+	}
+
+	client, err := admissionConfig.fetchMetadataClient(config)
+	if err != nil {
+		glog.Errorf("error getting metadata client: %v", err)
+		return
+	}
+	defer client.Close()
+	for _, image := range images {
+		if resolved, err := util.ResolveImageToDigest(image, registryauth.Keychain()); err == nil {
+			image = resolved
+		}
+		n, err := util.GetOrCreateDiscoveryNote(ctx, client, image, constants.BreakglassNoteID)
+		if err != nil {
+			glog.Errorf("error getting breakglass note for %s: %v", image, err)
+			continue
+		}
+		if _, err := client.CreateDiscoveryOccurrence(ctx, n, image, message); err != nil {
+			glog.Errorf("error recording breakglass occurrence for %s: %v", image, err)
+		}
+	}
+}
+
+func checkBreakglass(meta *metav1.ObjectMeta) bool {
+	annotations := meta.GetAnnotations()
+	if annotations == nil {
+		return false
+	}
+	_, ok := annotations[kritisconstants.Breakglass]
+	return ok
+}
+
+// objectName returns the name of the object, or its generate name if it has not been named yet.
+func objectName(meta *metav1.ObjectMeta) string {
+	if meta.Name != "" {
+		return meta.Name
+	}
+	return meta.GenerateName
+}
@@ -27,7 +27,15 @@ const (
 )
 
 const (
-	SuccessMessage = "Successfully admitted."
+	SuccessMessage    = "Successfully admitted."
+	BreakglassMessage = "Admitted with breakglass annotation."
+	ExemptMessage     = "Admitted without review, exempt by the KritisConfig."
+)
+
+// Audit records written for breakglass admissions
+const (
+	BreakglassEventReason = "Breakglass"
+	BreakglassNoteID      = "kritis-breakglass"
 )
 
 const (
@@ -36,18 +44,26 @@ const (
 
 // A list of all Kubernetes objects kritis can validate
 const (
-	Pod        = "Pod"
-	ReplicaSet = "ReplicaSet"
-	Deployment = "Deployment"
+	Pod         = "Pod"
+	ReplicaSet  = "ReplicaSet"
+	Deployment  = "Deployment"
+	StatefulSet = "StatefulSet"
+	DaemonSet   = "DaemonSet"
+	Job         = "Job"
+	CronJob     = "CronJob"
 )
 
 var (
 	// SupportedTypes is a list of Kubernetes types that kritis can validate
-	SupportedTypes = []string{Pod, ReplicaSet, Deployment}
+	SupportedTypes = []string{Pod, ReplicaSet, Deployment, StatefulSet, DaemonSet, Job, CronJob}
 )
 
 // Types of supported metadata fetchers
 const (
 	GrafeasMetadata           = "grafeas"
 	ContainerAnalysisMetadata = "containerAnalysis"
+	FileMetadata              = "file"
+	ECRMetadata               = "ecr"
+	AzureMetadata             = "azure"
+	HarborMetadata            = "harbor"
 )
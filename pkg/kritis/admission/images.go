@@ -17,44 +17,60 @@ limitations under the License.
 package admission
 
 import (
+	"encoding/json"
+
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/util"
 )
 
-// PodImages returns a list of images in a pod
-func PodImages(pod v1.Pod) []string {
-	images := []string{}
-	for _, ic := range pod.Spec.InitContainers {
-		images = append(images, ic.Image)
+// ephemeralContainers is the object sent to the webhook for the ephemeralcontainers
+// subresource of pods, by clusters from 1.16 to 1.21.
+type ephemeralContainers struct {
+	metav1.ObjectMeta   `json:"metadata,omitempty"`
+	EphemeralContainers []ephemeralContainer `json:"ephemeralContainers"`
+}
+
+// ephemeralContainer holds the fields of ephemeral containers reviewed by Kritis,
+// which are not in the Pod types vendored.
+type ephemeralContainer struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
+// EphemeralContainerImages returns a list of images of the ephemeral containers
+// of the raw JSON of a pod
+func EphemeralContainerImages(raw []byte) ([]string, error) {
+	pod := struct {
+		Spec struct {
+			EphemeralContainers []ephemeralContainer `json:"ephemeralContainers"`
+		} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(raw, &pod); err != nil {
+		return nil, err
 	}
-	for _, c := range pod.Spec.Containers {
+	images := []string{}
+	for _, c := range pod.Spec.EphemeralContainers {
 		images = append(images, c.Image)
 	}
-	return images
+	return images, nil
+}
+
+// PodImages returns a list of images in a pod
+func PodImages(pod v1.Pod) []string {
+	return util.PodSpecImages(pod.Spec)
 }
 
 // DeploymentImages returns a list of images in a deployment
 func DeploymentImages(deployment appsv1.Deployment) []string {
-	images := []string{}
-	for _, ic := range deployment.Spec.Template.Spec.InitContainers {
-		images = append(images, ic.Image)
-	}
-	for _, c := range deployment.Spec.Template.Spec.Containers {
-		images = append(images, c.Image)
-	}
-	return images
+	return util.PodSpecImages(deployment.Spec.Template.Spec)
 }
 
 // ReplicaSetImages returns a list of images in a replica set
 func ReplicaSetImages(rs appsv1.ReplicaSet) []string {
-	images := []string{}
-	for _, ic := range rs.Spec.Template.Spec.InitContainers {
-		images = append(images, ic.Image)
-	}
-	for _, c := range rs.Spec.Template.Spec.Containers {
-		images = append(images, c.Image)
-	}
-	return images
+	return util.PodSpecImages(rs.Spec.Template.Spec)
 }
 
 func hasNewImage(images, oldImages []string) bool {
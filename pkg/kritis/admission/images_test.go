@@ -47,6 +47,15 @@ func Test_PodImages(t *testing.T) {
 	testutil.CheckErrorAndDeepEqual(t, false, nil, expected, actual)
 }
 
+func Test_EphemeralContainerImages(t *testing.T) {
+	raw := []byte(`{"spec": {"containers": [{"image": "image1"}], "ephemeralContainers": [{"name": "debugger", "image": "busybox"}]}}`)
+	actual, err := EphemeralContainerImages(raw)
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{"busybox"}, actual)
+
+	actual, err = EphemeralContainerImages([]byte(`{"spec": {"containers": [{"image": "image1"}]}}`))
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{}, actual)
+}
+
 func Test_DeploymentImages(t *testing.T) {
 	deployment := appsv1.Deployment{
 		Spec: appsv1.DeploymentSpec{
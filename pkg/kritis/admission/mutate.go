@@ -0,0 +1,277 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/grafeas/kritis/pkg/kritis/admission/constants"
+	kritisconstants "github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/crd/attestationpolicy"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/decisionlog"
+	"github.com/grafeas/kritis/pkg/kritis/kubectl/plugins/resolve"
+	"github.com/grafeas/kritis/pkg/kritis/registryauth"
+	"k8s.io/api/admission/v1beta1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// For testing
+var (
+	resolveDigest = resolve.ResolveDigestWithKeychain
+	reviewedAt    = time.Now
+)
+
+// decisionAnnotations are the annotations set by decisionPatch, in patch order.
+var decisionAnnotations = []string{
+	kritisconstants.ReviewedAt,
+	kritisconstants.ReviewedPolicies,
+	kritisconstants.VerifiedAttestations,
+}
+
+// patchOperation is a RFC 6902 JSON patch operation.
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MutateHandler resolves the tagged images of a pod to their digest and patches
+// the pod spec accordingly, so the reviewed image is the one run by the kubelet.
+// Only pods are mutated: patching the templates of deployments or replica sets
+// would make their controllers roll out new revisions.
+// With AnnotateDecisions, admitted pods are also annotated with the decision, see decisionPatch.
+func MutateHandler(w http.ResponseWriter, r *http.Request, config *Config) {
+	ar, err := deserializeRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := &v1beta1.AdmissionResponse{
+		UID:     ar.Request.UID,
+		Allowed: true,
+		Result: &metav1.Status{
+			Status:  string(constants.SuccessStatus),
+			Message: constants.SuccessMessage,
+		},
+	}
+	if ar.Request.Kind.Kind == "Pod" {
+		pod := v1.Pod{}
+		if err := json.Unmarshal(ar.Request.Object.Raw, &pod); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ops := digestPatch(ar.Request.Namespace, &pod)
+		if config.AnnotateDecisions {
+			ops = append(ops, decisionPatch(r.Context(), ar, &pod, config)...)
+		}
+		if len(ops) > 0 {
+			patch, err := json.Marshal(ops)
+			if err != nil {
+				glog.Errorf("failed to marshal patch: %v", err)
+			} else {
+				pt := v1beta1.PatchTypeJSONPatch
+				resp.Patch = patch
+				resp.PatchType = &pt
+			}
+		}
+	}
+	payload, err := json.Marshal(&v1beta1.AdmissionReview{Response: resp})
+	if err != nil {
+		glog.Errorf("failed to marshal response: %v", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(payload); err != nil {
+		glog.Errorf("failed to write payload: %v", err)
+	}
+}
+
+// digestPatch returns the operations replacing the tagged images of the pod by their digest,
+// and replaces them in pod. Images which can not be resolved are left as is, and reviewed
+// by the validating webhook.
+func digestPatch(namespace string, pod *v1.Pod) []patchOperation {
+	ops := []patchOperation{}
+	kc := registryauth.ForPod(namespace, pod.Spec.ServiceAccountName, pod.Spec.ImagePullSecrets)
+	add := func(field string, containers []v1.Container) {
+		for i := range containers {
+			c := &containers[i]
+			if resolve.FullyQualifiedImage(c.Image) {
+				continue
+			}
+			digest, err := resolveDigest(c.Image, kc)
+			if err != nil {
+				glog.Warningf("failed to resolve %s of pod %q: %v", c.Image, pod.Name, err)
+				continue
+			}
+			ops = append(ops, patchOperation{
+				Op:    "replace",
+				Path:  fmt.Sprintf("/spec/%s/%d/image", field, i),
+				Value: digest,
+			})
+			c.Image = digest
+		}
+	}
+	add("initContainers", pod.Spec.InitContainers)
+	add("containers", pod.Spec.Containers)
+	return ops
+}
+
+// decisionPatch validates the pod against its policies and, if it passes them, returns the
+// operations annotating it with the review time, the reviewed policies at their
+// resourceVersion and the IDs of the verified attestations. The pod is not reviewed, as
+// the validating webhook reviews it: violations are not handled, and neither recorded nor
+// attested.
+// Otherwise, decision annotations set by the pod creator are removed, so they can't be forged.
+// Pods admitted by breakglass, exemptions or a failing metadata backend are not annotated.
+func decisionPatch(ctx context.Context, ar v1beta1.AdmissionReview, pod *v1.Pod, config *Config) []patchOperation {
+	if breakglassed(&pod.ObjectMeta) {
+		return annotationPatch(pod.Annotations, nil)
+	}
+	ephemeral, err := EphemeralContainerImages(ar.Request.Object.Raw)
+	if err != nil {
+		glog.Errorf("failed to read the ephemeral containers of pod %q: %v", pod.Name, err)
+		return annotationPatch(pod.Annotations, nil)
+	}
+	images := append(PodImages(*pod), ephemeral...)
+	review := &v1beta1.AdmissionReview{
+		Request:  ar.Request,
+		Response: &v1beta1.AdmissionResponse{Allowed: true},
+	}
+	if exempt(review, constants.Pod, &pod.ObjectMeta, images, pod.Spec.ServiceAccountName, config) {
+		return annotationPatch(pod.Annotations, nil)
+	}
+	decision := &decisionlog.Decision{}
+	if err := validatePod(decisionlog.NewContext(ctx, decision), pod, images, config); err != nil {
+		glog.Infof("not annotating pod %q: %v", pod.Name, err)
+		return annotationPatch(pod.Annotations, nil)
+	}
+	if len(decision.Policies) == 0 {
+		return annotationPatch(pod.Annotations, nil)
+	}
+
+	policies := make([]string, len(decision.Policies))
+	for i, p := range decision.Policies {
+		policies[i] = fmt.Sprintf("%s/%s@%s", p.Kind, p.Name, p.Version)
+	}
+	var ids []string
+	seen := map[string]bool{}
+	for _, a := range decision.Attestations {
+		if a.Attested && a.ID != "" && !seen[a.ID] {
+			seen[a.ID] = true
+			ids = append(ids, a.ID)
+		}
+	}
+	annotations := map[string]string{
+		kritisconstants.ReviewedAt:       reviewedAt().UTC().Format(time.RFC3339),
+		kritisconstants.ReviewedPolicies: strings.Join(policies, ","),
+	}
+	if len(ids) > 0 {
+		annotations[kritisconstants.VerifiedAttestations] = strings.Join(ids, ",")
+	}
+	return annotationPatch(pod.Annotations, annotations)
+}
+
+// validatePod validates the images of pod against the policies selecting it, as reviewImages
+// reviews them but without side effects, and fails if they violate a policy. The policies
+// and the verified attestations are added to the decision of ctx.
+func validatePod(ctx context.Context, pod *v1.Pod, images []string, config *Config) error {
+	ns := pod.Namespace
+	isps, err := admissionConfig.fetchImageSecurityPolicies(ns)
+	if err != nil {
+		return fmt.Errorf("error getting image security policies: %v", err)
+	}
+	if isps, err = securitypolicy.SelectImageSecurityPolicies(isps, pod.Labels); err != nil {
+		return fmt.Errorf("error selecting image security policies: %v", err)
+	}
+	gaps, err := admissionConfig.fetchAttestationPolicies(ns)
+	if err != nil {
+		return fmt.Errorf("error getting generic attestation policies: %v", err)
+	}
+	if gaps, err = attestationpolicy.SelectGenericAttestationPolicies(gaps, pod.Labels); err != nil {
+		return fmt.Errorf("error selecting generic attestation policies: %v", err)
+	}
+	if len(isps) == 0 && len(gaps) == 0 {
+		return nil
+	}
+	decision := decisionlog.FromContext(ctx)
+	for _, isp := range isps {
+		decision.AddPolicy("ImageSecurityPolicy", isp.Namespace, isp.Name, isp.ResourceVersion, securitypolicy.Hash(isp))
+	}
+	for _, gap := range gaps {
+		decision.AddPolicy("GenericAttestationPolicy", gap.Namespace, gap.Name, gap.ResourceVersion, "")
+	}
+	resolved, err := resolveImagesForPolicies(images, isps, podKeychain(ns, pod))
+	if err != nil {
+		return fmt.Errorf("error resolving tagged images into digest: %v", err)
+	}
+	client, err := admissionConfig.fetchMetadataClient(config)
+	if err != nil {
+		return fmt.Errorf("error getting metadata client: %v", err)
+	}
+	defer client.Close()
+	attestors, err := admissionConfig.fetchAttestors()
+	if err != nil {
+		return fmt.Errorf("error creating attestor fetcher: %v", err)
+	}
+	r := admissionConfig.reviewer(client, attestors, config)
+	ctx = securitypolicy.WithDryRun(securitypolicy.WithPod(ctx, pod))
+	for _, image := range resolved {
+		vss, err := r.Validate(ctx, image, isps)
+		if err != nil {
+			return fmt.Errorf("error validating %s: %v", image, err)
+		}
+		gvss, err := r.ValidateGAP(ctx, image, gaps)
+		if err != nil {
+			return fmt.Errorf("error validating %s: %v", image, err)
+		}
+		for _, vs := range append(vss, gvss...) {
+			if len(vs) > 0 {
+				return fmt.Errorf("%s has violations: %s", image, vs[0].Reason())
+			}
+		}
+	}
+	return nil
+}
+
+// annotationPatch returns the operations setting the decision annotations of existing
+// to annotations, removing those it doesn't hold.
+func annotationPatch(existing, annotations map[string]string) []patchOperation {
+	if existing == nil {
+		if len(annotations) == 0 {
+			return nil
+		}
+		return []patchOperation{{Op: "add", Path: "/metadata/annotations", Value: annotations}}
+	}
+	escape := strings.NewReplacer("~", "~0", "/", "~1")
+	var ops []patchOperation
+	for _, key := range decisionAnnotations {
+		path := "/metadata/annotations/" + escape.Replace(key)
+		if value, ok := annotations[key]; ok {
+			ops = append(ops, patchOperation{Op: "add", Path: path, Value: value})
+		} else if _, ok := existing[key]; ok {
+			ops = append(ops, patchOperation{Op: "remove", Path: path})
+		}
+	}
+	return ops
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/decisionlog"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/review"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	"k8s.io/api/admission/v1beta1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func Test_MutateHandler(t *testing.T) {
+	orig := resolveDigest
+	defer func() { resolveDigest = orig }()
+	resolveDigest = func(image string, kc authn.Keychain) (string, error) {
+		if image == "gcr.io/missing:latest" {
+			return "", fmt.Errorf("not found")
+		}
+		return "gcr.io/resolved@sha256:0000000000000000000000000000000000000000000000000000000000000000", nil
+	}
+	resolved := "gcr.io/resolved@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+	tcs := []struct {
+		name     string
+		kind     string
+		pod      v1.Pod
+		expected []patchOperation
+	}{
+		{
+			name: "tagged images are resolved",
+			kind: "Pod",
+			pod: v1.Pod{Spec: v1.PodSpec{
+				InitContainers: []v1.Container{{Image: "gcr.io/init:1.0"}},
+				Containers:     []v1.Container{{Image: testutil.QualifiedImage}, {Image: "gcr.io/app:latest"}},
+			}},
+			expected: []patchOperation{
+				{Op: "replace", Path: "/spec/initContainers/0/image", Value: resolved},
+				{Op: "replace", Path: "/spec/containers/1/image", Value: resolved},
+			},
+		},
+		{
+			name: "unresolvable images are left as is",
+			kind: "Pod",
+			pod: v1.Pod{Spec: v1.PodSpec{
+				Containers: []v1.Container{{Image: "gcr.io/missing:latest"}},
+			}},
+		},
+		{
+			name: "replica sets are not mutated",
+			kind: "ReplicaSet",
+			pod: v1.Pod{Spec: v1.PodSpec{
+				Containers: []v1.Container{{Image: "gcr.io/app:latest"}},
+			}},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, err := json.Marshal(tc.pod)
+			if err != nil {
+				t.Fatal(err)
+			}
+			blob, err := json.Marshal(v1beta1.AdmissionReview{
+				Request: &v1beta1.AdmissionRequest{
+					Kind:   metav1.GroupVersionKind{Kind: tc.kind},
+					Object: runtime.RawExtension{Raw: raw},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest("POST", "/mutate", bytes.NewReader(blob))
+			rr := httptest.NewRecorder()
+			MutateHandler(rr, req, &Config{})
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected OK status code, actual %d", rr.Code)
+			}
+			ar := v1beta1.AdmissionReview{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &ar); err != nil {
+				t.Fatal(err)
+			}
+			if !ar.Response.Allowed {
+				t.Errorf("expected pod to be allowed")
+			}
+			var actual []patchOperation
+			if ar.Response.Patch != nil {
+				if err := json.Unmarshal(ar.Response.Patch, &actual); err != nil {
+					t.Fatal(err)
+				}
+			}
+			testutil.DeepEqual(t, tc.expected, actual)
+		})
+	}
+}
+
+// attestingReviewer validates images with a verified attestation, violating the policies
+// with err if set. Reviews fail, since the mutating webhook must not review images.
+type attestingReviewer struct {
+	err error
+}
+
+func (r attestingReviewer) ReviewContext(ctx context.Context, images []string, isps []kritisv1beta1.ImageSecurityPolicy, pod *v1.Pod) error {
+	return fmt.Errorf("unexpected review of %s", images)
+}
+
+func (r attestingReviewer) ReviewGAP(ctx context.Context, images []string, gaps []kritisv1beta1.GenericAttestationPolicy, pod *v1.Pod) error {
+	return nil
+}
+
+func (r attestingReviewer) Validate(ctx context.Context, image string, isps []kritisv1beta1.ImageSecurityPolicy) ([][]policy.Violation, error) {
+	decisionlog.FromContext(ctx).AddAttestation(image, "occ-1", true)
+	violations := make([][]policy.Violation, len(isps))
+	if r.err != nil {
+		for i := range violations {
+			violations[i] = []policy.Violation{securitypolicy.NewViolation(nil, policy.UnqualifiedImageViolation, policy.Reason(r.err.Error()))}
+		}
+	}
+	return violations, nil
+}
+
+func (r attestingReviewer) ValidateGAP(ctx context.Context, image string, gaps []kritisv1beta1.GenericAttestationPolicy) ([][]policy.Violation, error) {
+	return make([][]policy.Violation, len(gaps)), nil
+}
+
+func (r attestingReviewer) Promote(ctx context.Context, images []string, isps []kritisv1beta1.ImageSecurityPolicy, pod *v1.Pod) {
+}
+
+func Test_MutateHandlerAnnotations(t *testing.T) {
+	original := admissionConfig
+	defer func() { admissionConfig = original }()
+	origNow := reviewedAt
+	defer func() { reviewedAt = origNow }()
+	reviewedAt = func() time.Time { return time.Date(2019, 3, 1, 10, 0, 0, 0, time.FixedZone("JST", 9*3600)) }
+
+	isp := kritisv1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "isp", ResourceVersion: "42"}}
+	forged := map[string]string{constants.ReviewedAt: "forged", constants.VerifiedAttestations: "forged", "app": "web"}
+	tcs := []struct {
+		name        string
+		isps        []kritisv1beta1.ImageSecurityPolicy
+		err         error
+		annotations map[string]string
+		expected    []patchOperation
+	}{
+		{
+			name: "admitted pod is annotated",
+			isps: []kritisv1beta1.ImageSecurityPolicy{isp},
+			expected: []patchOperation{{Op: "add", Path: "/metadata/annotations", Value: map[string]interface{}{
+				constants.ReviewedAt:           "2019-03-01T01:00:00Z",
+				constants.ReviewedPolicies:     "ImageSecurityPolicy/default/isp@42",
+				constants.VerifiedAttestations: "occ-1",
+			}}},
+		},
+		{
+			name:        "annotations of admitted pod are replaced",
+			isps:        []kritisv1beta1.ImageSecurityPolicy{isp},
+			annotations: forged,
+			expected: []patchOperation{
+				{Op: "add", Path: "/metadata/annotations/kritis.grafeas.io~1reviewed-at", Value: "2019-03-01T01:00:00Z"},
+				{Op: "add", Path: "/metadata/annotations/kritis.grafeas.io~1policies", Value: "ImageSecurityPolicy/default/isp@42"},
+				{Op: "add", Path: "/metadata/annotations/kritis.grafeas.io~1attestations", Value: "occ-1"},
+			},
+		},
+		{
+			name:        "forged annotations of denied pod are removed",
+			isps:        []kritisv1beta1.ImageSecurityPolicy{isp},
+			err:         fmt.Errorf("found violations"),
+			annotations: forged,
+			expected: []patchOperation{
+				{Op: "remove", Path: "/metadata/annotations/kritis.grafeas.io~1reviewed-at"},
+				{Op: "remove", Path: "/metadata/annotations/kritis.grafeas.io~1attestations"},
+			},
+		},
+		{
+			name: "pod without policies is not annotated",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			admissionConfig = config{
+				fetchMetadataClient: func(config *Config) (metadata.Fetcher, error) {
+					return &testutil.MockMetadataClient{}, nil
+				},
+				fetchImageSecurityPolicies: func(namespace string) ([]kritisv1beta1.ImageSecurityPolicy, error) {
+					return tc.isps, nil
+				},
+				fetchAttestationPolicies: noAttestationPolicies,
+				fetchAttestors:           nilAttestorFetcher,
+				reviewer: func(client metadata.Fetcher, attestors securitypolicy.AttestorFetcher, config *Config) review.Interface {
+					return attestingReviewer{err: tc.err}
+				},
+			}
+			raw, err := json.Marshal(v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Image: testutil.QualifiedImage}}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			blob, err := json.Marshal(v1beta1.AdmissionReview{
+				Request: &v1beta1.AdmissionRequest{
+					Kind:   metav1.GroupVersionKind{Kind: "Pod"},
+					Object: runtime.RawExtension{Raw: raw},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest("POST", "/mutate", bytes.NewReader(blob))
+			rr := httptest.NewRecorder()
+			MutateHandler(rr, req, &Config{AnnotateDecisions: true})
+			ar := v1beta1.AdmissionReview{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &ar); err != nil {
+				t.Fatal(err)
+			}
+			if !ar.Response.Allowed {
+				t.Errorf("expected the mutating webhook to allow the pod")
+			}
+			var actual []patchOperation
+			if ar.Response.Patch != nil {
+				if err := json.Unmarshal(ar.Response.Patch, &actual); err != nil {
+					t.Fatal(err)
+				}
+			}
+			testutil.DeepEqual(t, tc.expected, actual)
+		})
+	}
+}
@@ -23,7 +23,6 @@ import (
 	"github.com/grafeas/kritis/pkg/kritis/admission/constants"
 	"github.com/grafeas/kritis/pkg/kritis/kubectl/plugins/resolve"
 	kubernetesutil "github.com/grafeas/kritis/pkg/kritis/kubernetes"
-	"github.com/grafeas/kritis/pkg/kritis/util"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -77,7 +76,6 @@ func retrieveOwnersImages(ns string, or metav1.OwnerReference) ([]string, error)
 }
 
 func imagesAreValid(dependentImages, ownerImages []string) bool {
-	dependentImages = util.RemoveGloballyWhitelistedImages(dependentImages)
 	for _, d := range dependentImages {
 		if !resolve.FullyQualifiedImage(d) {
 			return false
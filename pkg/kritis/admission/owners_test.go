@@ -41,7 +41,7 @@ func Test_imagesAreValid(t *testing.T) {
 			expected: true,
 		},
 		{
-			name: "all dependent images are valid or globally whitelisted",
+			name: "globally whitelisted dependent image not in owner",
 			dependentImages: []string{
 				testutil.QualifiedImage,
 				"gcr.io/kritis-project/postinstall",
@@ -50,7 +50,7 @@ func Test_imagesAreValid(t *testing.T) {
 				testutil.QualifiedImage,
 				"gcr.io/another/image",
 			},
-			expected: true,
+			expected: false,
 		},
 		{
 			name: "dependent has an unqualified image",
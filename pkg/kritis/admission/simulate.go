@@ -0,0 +1,252 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/authz"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/review"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// maxSimulationRequestBytes bounds the body of a simulation request.
+const maxSimulationRequestBytes = 1 << 20
+
+// SimulationRequest is the body of a policy simulation, see SimulateHandler.
+type SimulationRequest struct {
+	// Namespace whose ImageSecurityPolicies are evaluated, defaults to the namespace of Pod.
+	Namespace string `json:"namespace,omitempty"`
+	// Pod whose images are evaluated, and whose labels select the policies.
+	Pod *v1.Pod `json:"pod,omitempty"`
+	// Images evaluated in addition to those of Pod.
+	Images []string `json:"images,omitempty"`
+	// Policies are YAML or JSON ImageSecurityPolicies evaluated instead of those of the namespace.
+	Policies string `json:"policies,omitempty"`
+}
+
+// SimulationResponse lists the violations a simulated review found.
+type SimulationResponse struct {
+	Allowed    bool                 `json:"allowed"`
+	Images     []string             `json:"images"`
+	Policies   []string             `json:"policies"`
+	Violations []SimulatedViolation `json:"violations"`
+}
+
+// SimulatedViolation is a violation of a policy found by a simulated review.
+type SimulatedViolation struct {
+	Image  string `json:"image"`
+	Policy string `json:"policy"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// SimulationAuthorizer returns an error unless the caller of a simulation is allowed
+// the verb on the ImageSecurityPolicies of namespace.
+type SimulationAuthorizer func(namespace, verb string) error
+
+// TokenAuthorizer returns a SimulationAuthorizer checking the user of a bearer token
+// with a, so that callers can only see the policies they could read with kubectl.
+func TokenAuthorizer(a *authz.Authorizer, token string) SimulationAuthorizer {
+	return func(namespace, verb string) error {
+		_, err := a.Authorize(token, authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verb,
+				Group:     kritisv1beta1.SchemeGroupVersion.Group,
+				Resource:  "imagesecuritypolicies",
+			},
+		})
+		return err
+	}
+}
+
+// SimulateHandler evaluates the images of a SimulationRequest against the current or
+// proposed ImageSecurityPolicies and writes the violations which would occur. Unlike
+// ReviewHandler, nothing is attested, recorded or notified. The bearer token of the
+// request must allow to list the ImageSecurityPolicies of the namespace, or to create
+// the proposed ones.
+func SimulateHandler(w http.ResponseWriter, r *http.Request, config *Config, a *authz.Authorizer) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	var req SimulationRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSimulationRequestBytes)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid simulation request: %v", err), http.StatusBadRequest)
+		return
+	}
+	resp, err := Simulate(r.Context(), req, config, TokenAuthorizer(a, authz.Token(r)))
+	if IsInvalidSimulation(err) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := err.(*authz.Error); ok {
+		http.Error(w, err.Error(), authz.StatusCode(err))
+		return
+	}
+	if err != nil {
+		glog.Errorf("policy simulation failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		glog.Errorf("failed to write simulation response: %v", err)
+	}
+}
+
+// invalidSimulationError is returned by Simulate for requests it can't evaluate.
+type invalidSimulationError struct {
+	error
+}
+
+// IsInvalidSimulation returns true if err was caused by the request given to Simulate.
+func IsInvalidSimulation(err error) bool {
+	_, ok := err.(invalidSimulationError)
+	return ok
+}
+
+// Simulate evaluates the images of req as SimulateHandler does, once authorize allowed
+// the caller to read the policies evaluated.
+func Simulate(ctx context.Context, req SimulationRequest, config *Config, authorize SimulationAuthorizer) (*SimulationResponse, error) {
+	isps, images, err := simulationInput(req, authorize)
+	if err != nil {
+		return nil, err
+	}
+	return simulate(ctx, images, isps, req.Pod, config)
+}
+
+// simulationInput returns the policies selecting the pod of req, and the images to evaluate.
+// The errors of authorize are returned as is, the others are invalidSimulationErrors.
+func simulationInput(req SimulationRequest, authorize SimulationAuthorizer) ([]kritisv1beta1.ImageSecurityPolicy, []string, error) {
+	var images []string
+	var podLabels map[string]string
+	ns := req.Namespace
+	if req.Pod != nil {
+		images = PodImages(*req.Pod)
+		podLabels = req.Pod.Labels
+		if ns == "" {
+			ns = req.Pod.Namespace
+		}
+	}
+	images = append(images, req.Images...)
+	if len(images) == 0 {
+		return nil, nil, invalidSimulationError{fmt.Errorf("no images to evaluate, expected a pod or images")}
+	}
+
+	var isps []kritisv1beta1.ImageSecurityPolicy
+	var err error
+	if req.Policies != "" {
+		if isps, err = securitypolicy.ReadImageSecurityPolicies(strings.NewReader(req.Policies)); err != nil {
+			return nil, nil, invalidSimulationError{fmt.Errorf("invalid policies: %v", err)}
+		}
+		authorized := map[string]bool{}
+		for i := range isps {
+			if isps[i].Namespace == "" {
+				isps[i].Namespace = ns
+			}
+			if isps[i].Namespace == "" {
+				return nil, nil, invalidSimulationError{fmt.Errorf("a namespace is required to evaluate policy %q", isps[i].Name)}
+			}
+			if authorized[isps[i].Namespace] {
+				continue
+			}
+			if err := authorize(isps[i].Namespace, "create"); err != nil {
+				return nil, nil, err
+			}
+			authorized[isps[i].Namespace] = true
+		}
+	} else {
+		if ns == "" {
+			return nil, nil, invalidSimulationError{fmt.Errorf("a namespace is required to evaluate its policies")}
+		}
+		if err := authorize(ns, "list"); err != nil {
+			return nil, nil, err
+		}
+		if isps, err = admissionConfig.fetchImageSecurityPolicies(ns); err != nil {
+			return nil, nil, invalidSimulationError{fmt.Errorf("error getting image security policies: %v", err)}
+		}
+	}
+	if isps, err = securitypolicy.SelectImageSecurityPolicies(isps, podLabels); err != nil {
+		return nil, nil, invalidSimulationError{fmt.Errorf("error selecting image security policies: %v", err)}
+	}
+	return isps, images, nil
+}
+
+// simulate validates images against isps without handling the violations found.
+func simulate(ctx context.Context, images []string, isps []kritisv1beta1.ImageSecurityPolicy, pod *v1.Pod, config *Config) (*SimulationResponse, error) {
+	resp := &SimulationResponse{Allowed: true, Images: images, Policies: []string{}, Violations: []SimulatedViolation{}}
+	for _, isp := range isps {
+		resp.Policies = append(resp.Policies, isp.Namespace+"/"+isp.Name)
+	}
+	if len(isps) == 0 {
+		return resp, nil
+	}
+	ns := isps[0].Namespace
+	if pod != nil && pod.Namespace != "" {
+		ns = pod.Namespace
+	}
+	resolved, err := resolveImagesForPolicies(images, isps, podKeychain(ns, pod))
+	if err != nil {
+		return nil, fmt.Errorf("error resolving tagged images into digest: %v", err)
+	}
+	client, err := admissionConfig.fetchMetadataClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("error getting metadata client: %v", err)
+	}
+	defer client.Close()
+	attestors, err := admissionConfig.fetchAttestors()
+	if err != nil {
+		return nil, fmt.Errorf("error creating attestor fetcher: %v", err)
+	}
+	r := review.New(client, &review.Config{
+		Validate:  securitypolicy.ValidateImageSecurityPolicy,
+		Attestors: attestors,
+	})
+	ctx = securitypolicy.WithDryRun(ctx)
+	if pod != nil {
+		ctx = securitypolicy.WithPod(ctx, pod)
+	}
+	resp.Images = resolved
+	for _, image := range resolved {
+		vss, err := r.Validate(ctx, image, isps)
+		if err != nil {
+			return nil, fmt.Errorf("error validating %s: %v", image, err)
+		}
+		for i, vs := range vss {
+			for _, v := range vs {
+				resp.Violations = append(resp.Violations, SimulatedViolation{
+					Image:  image,
+					Policy: resp.Policies[i],
+					Type:   v.Type().ToString(),
+					Reason: string(v.Reason()),
+				})
+			}
+		}
+	}
+	resp.Allowed = len(resp.Violations) == 0
+	return resp, nil
+}
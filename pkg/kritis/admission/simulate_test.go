@@ -0,0 +1,235 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/authz"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const proposedISP = `apiVersion: kritis.grafeas.io/v1beta1
+kind: ImageSecurityPolicy
+metadata:
+  name: proposed
+spec:
+  packageVulnerabilityRequirements:
+    maximumSeverity: HIGH
+`
+
+// newSimulationAuthorizer returns an Authorizer authenticating the "dev" token, whose
+// user may only access the prod and staging namespaces, and records the accesses checked.
+func newSimulationAuthorizer(accesses *[]string) *authz.Authorizer {
+	cs := fake.NewSimpleClientset()
+	cs.PrependReactor("create", "tokenreviews", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		tr := a.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if tr.Spec.Token == "dev" {
+			tr.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: "dev"}}
+		}
+		return true, tr, nil
+	})
+	cs.PrependReactor("create", "subjectaccessreviews", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		sar := a.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		r := sar.Spec.ResourceAttributes
+		*accesses = append(*accesses, r.Verb+" "+r.Resource+"."+r.Group+" "+r.Namespace)
+		sar.Status.Allowed = r.Namespace == "prod" || r.Namespace == "staging"
+		return true, sar, nil
+	})
+	return authz.New(cs)
+}
+
+func TestSimulateHandler(t *testing.T) {
+	original := admissionConfig
+	defer func() {
+		admissionConfig = original
+	}()
+	vuln := metadata.Vulnerability{CVE: "CVE-1", Severity: "HIGH", HasFixAvailable: true}
+	current := kritisv1beta1.ImageSecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "current", Namespace: "prod"},
+		Spec: kritisv1beta1.ImageSecurityPolicySpec{
+			PackageVulnerabilityRequirements: kritisv1beta1.PackageVulnerabilityRequirements{MaximumSeverity: "MEDIUM"},
+		},
+	}
+	var namespaces []string
+	admissionConfig = config{
+		fetchMetadataClient: func(config *Config) (metadata.Fetcher, error) {
+			return &testutil.MockMetadataClient{Vulnz: []metadata.Vulnerability{vuln}}, nil
+		},
+		fetchImageSecurityPolicies: func(namespace string) ([]kritisv1beta1.ImageSecurityPolicy, error) {
+			namespaces = append(namespaces, namespace)
+			return []kritisv1beta1.ImageSecurityPolicy{current}, nil
+		},
+		fetchAttestors: func() (securitypolicy.AttestorFetcher, error) {
+			return nil, nil
+		},
+	}
+	var accesses []string
+	a := newSimulationAuthorizer(&accesses)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SimulateHandler(w, r, &Config{}, a)
+	}))
+	defer s.Close()
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "prod"},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Image: testutil.QualifiedImage}}},
+	}
+	tests := []struct {
+		name       string
+		token      string
+		req        SimulationRequest
+		status     int
+		expected   *SimulationResponse
+		namespaces []string
+		accesses   []string
+	}{
+		{
+			name:   "current policies",
+			req:    SimulationRequest{Pod: pod},
+			status: http.StatusOK,
+			expected: &SimulationResponse{
+				Allowed:  false,
+				Images:   []string{testutil.QualifiedImage},
+				Policies: []string{"prod/current"},
+				Violations: []SimulatedViolation{{
+					Image:  testutil.QualifiedImage,
+					Policy: "prod/current",
+					Type:   "SeverityViolation",
+					Reason: string(securitypolicy.SeverityReason(testutil.QualifiedImage, vuln, current)),
+				}},
+			},
+			namespaces: []string{"prod"},
+			accesses:   []string{"list imagesecuritypolicies.kritis.grafeas.io prod"},
+		},
+		{
+			name:   "proposed policies",
+			req:    SimulationRequest{Namespace: "staging", Images: []string{testutil.QualifiedImage}, Policies: proposedISP},
+			status: http.StatusOK,
+			expected: &SimulationResponse{
+				Allowed:    true,
+				Images:     []string{testutil.QualifiedImage},
+				Policies:   []string{"staging/proposed"},
+				Violations: []SimulatedViolation{},
+			},
+			accesses: []string{"create imagesecuritypolicies.kritis.grafeas.io staging"},
+		},
+		{
+			name:     "unauthenticated",
+			token:    "unknown",
+			req:      SimulationRequest{Pod: pod},
+			status:   http.StatusUnauthorized,
+			accesses: []string{},
+		},
+		{
+			name:     "forbidden namespace",
+			req:      SimulationRequest{Namespace: "billing", Images: []string{testutil.QualifiedImage}},
+			status:   http.StatusForbidden,
+			accesses: []string{"list imagesecuritypolicies.kritis.grafeas.io billing"},
+		},
+		{
+			name:     "forbidden proposed policies",
+			req:      SimulationRequest{Namespace: "staging", Images: []string{testutil.QualifiedImage}, Policies: strings.Replace(proposedISP, "name: proposed", "name: proposed\n  namespace: billing", 1)},
+			status:   http.StatusForbidden,
+			accesses: []string{"create imagesecuritypolicies.kritis.grafeas.io billing"},
+		},
+		{
+			name:   "proposed policies without namespace",
+			req:    SimulationRequest{Images: []string{testutil.QualifiedImage}, Policies: proposedISP},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "no images",
+			req:    SimulationRequest{Namespace: "prod"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "no namespace",
+			req:    SimulationRequest{Images: []string{testutil.QualifiedImage}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "invalid policies",
+			req:    SimulationRequest{Pod: pod, Policies: "kind: ["},
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			namespaces = nil
+			accesses = []string{}
+			body, err := json.Marshal(test.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token := test.token
+			if token == "" {
+				token = "dev"
+			}
+			r, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Authorization", "Bearer "+token)
+			resp, err := http.DefaultClient.Do(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != test.status {
+				t.Fatalf("expected status %d, got %s", test.status, resp.Status)
+			}
+			if test.accesses != nil {
+				testutil.DeepEqual(t, test.accesses, accesses)
+			}
+			if test.expected == nil {
+				return
+			}
+			actual := &SimulationResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(actual); err != nil {
+				t.Fatal(err)
+			}
+			testutil.DeepEqual(t, test.expected, actual)
+			testutil.DeepEqual(t, test.namespaces, namespaces)
+		})
+	}
+
+	resp, err := http.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %s", http.StatusMethodNotAllowed, resp.Status)
+	}
+}
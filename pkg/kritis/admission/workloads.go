@@ -0,0 +1,126 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	"k8s.io/api/admission/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/admission/constants"
+	"github.com/grafeas/kritis/pkg/kritis/util"
+)
+
+// podTemplate decodes a workload of kind and returns its metadata and the template of its pods.
+func podTemplate(kind string, raw []byte) (*metav1.ObjectMeta, *v1.PodTemplateSpec, error) {
+	switch kind {
+	case constants.StatefulSet:
+		s := appsv1.StatefulSet{}
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, nil, err
+		}
+		return &s.ObjectMeta, &s.Spec.Template, nil
+	case constants.DaemonSet:
+		d := appsv1.DaemonSet{}
+		if err := json.Unmarshal(raw, &d); err != nil {
+			return nil, nil, err
+		}
+		return &d.ObjectMeta, &d.Spec.Template, nil
+	case constants.Job:
+		j := batchv1.Job{}
+		if err := json.Unmarshal(raw, &j); err != nil {
+			return nil, nil, err
+		}
+		return &j.ObjectMeta, &j.Spec.Template, nil
+	case constants.CronJob:
+		c := batchv1beta1.CronJob{}
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return nil, nil, err
+		}
+		return &c.ObjectMeta, &c.Spec.JobTemplate.Spec.Template, nil
+	default:
+		return nil, nil, fmt.Errorf("%s is not a supported kind", kind)
+	}
+}
+
+// handlePodTemplate reviews the pod template of StatefulSets, DaemonSets, Jobs and CronJobs,
+// so their images are rejected when the workload is applied rather than when its
+// pods are created.
+func handlePodTemplate(ctx context.Context, ar *v1beta1.AdmissionReview, admitResponse *v1beta1.AdmissionReview, config *Config) error {
+	kind := ar.Request.Kind.Kind
+	meta, template, err := podTemplate(kind, ar.Request.Object.Raw)
+	if err != nil {
+		return err
+	}
+	glog.Infof("handling %s %q", kind, meta.Name)
+	images := util.PodSpecImages(template.Spec)
+
+	if ar.Request.Operation == v1beta1.Update {
+		_, oldTemplate, err := podTemplate(kind, ar.Request.OldObject.Raw)
+		if err != nil {
+			return err
+		}
+		// For UPDATE events, if there is no new image added, we can skip the check,
+		// e.g. when scaling the workload or before deleting it.
+		if !hasNewImage(images, util.PodSpecImages(oldTemplate.Spec)) {
+			glog.Infof("ignoring %s %q as no new image has been added", kind, meta.Name)
+			return nil
+		}
+	}
+
+	if exempt(admitResponse, kind, meta, images, template.Spec.ServiceAccountName, config) {
+		return nil
+	}
+	// check for a breakglass annotation on the workload or its namespace
+	if breakglassed(meta) {
+		reviewBreakglass(ctx, kind, meta, images, nil, template.Labels, admitResponse, config)
+		return nil
+	}
+	reviewImages(ctx, images, meta.Namespace, nil, template.Labels, admitResponse, config)
+	describeDenial(admitResponse, kind, meta, template.Spec)
+	return nil
+}
+
+// describeDenial prefixes the message of a denied workload with the workload and
+// the containers whose images are named in the message, so that users applying
+// it know what to fix.
+func describeDenial(ar *v1beta1.AdmissionReview, kind string, meta *metav1.ObjectMeta, spec v1.PodSpec) {
+	if ar.Response.Allowed || ar.Response.Result == nil {
+		return
+	}
+	message := ar.Response.Result.Message
+	var containers []string
+	for _, c := range append(append([]v1.Container{}, spec.InitContainers...), spec.Containers...) {
+		if strings.Contains(message, c.Image) {
+			containers = append(containers, fmt.Sprintf("%s (%s)", c.Name, c.Image))
+		}
+	}
+	prefix := fmt.Sprintf("%s %s/%s is denied", kind, meta.Namespace, objectName(meta))
+	if len(containers) > 0 {
+		prefix = fmt.Sprintf("%s, fix the images of containers %s", prefix, strings.Join(containers, ", "))
+	}
+	ar.Response.Result.Message = fmt.Sprintf("%s: %s", prefix, message)
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"k8s.io/api/admission/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/review"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func testTemplate(image string) v1.PodTemplateSpec {
+	return v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+		Spec: v1.PodSpec{
+			InitContainers: []v1.Container{{Name: "init", Image: "gcr.io/kritis-project/init@sha256:0000000000000000000000000000000000000000000000000000000000000000"}},
+			Containers:     []v1.Container{{Name: "web", Image: image}},
+		},
+	}
+}
+
+func TestHandlePodTemplate(t *testing.T) {
+	meta := metav1.ObjectMeta{Name: "web", Namespace: "default"}
+	template := testTemplate(testutil.QualifiedImage)
+	tests := []struct {
+		kind string
+		obj  interface{}
+	}{
+		{"StatefulSet", appsv1.StatefulSet{ObjectMeta: meta, Spec: appsv1.StatefulSetSpec{Template: template}}},
+		{"DaemonSet", appsv1.DaemonSet{ObjectMeta: meta, Spec: appsv1.DaemonSetSpec{Template: template}}},
+		{"Job", batchv1.Job{ObjectMeta: meta, Spec: batchv1.JobSpec{Template: template}}},
+		{"CronJob", batchv1beta1.CronJob{ObjectMeta: meta, Spec: batchv1beta1.CronJobSpec{
+			JobTemplate: batchv1beta1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: template}},
+		}}},
+	}
+	original := admissionConfig
+	defer func() {
+		admissionConfig = original
+	}()
+	admissionConfig = config{
+		fetchMetadataClient: func(config *Config) (metadata.Fetcher, error) {
+			return &testutil.MockMetadataClient{}, nil
+		},
+		fetchImageSecurityPolicies: func(namespace string) ([]kritisv1beta1.ImageSecurityPolicy, error) {
+			return []kritisv1beta1.ImageSecurityPolicy{{}}, nil
+		},
+		fetchAttestationPolicies: noAttestationPolicies,
+		fetchNamespace: func(name string) (*v1.Namespace, error) {
+			return &v1.Namespace{}, nil
+		},
+		fetchAttestors: nilAttestorFetcher,
+		reviewer: func(client metadata.Fetcher, attestors securitypolicy.AttestorFetcher, config *Config) review.Interface {
+			return errReviewer{err: fmt.Errorf("found violations in %q", testutil.QualifiedImage)}
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.kind, func(t *testing.T) {
+			raw, err := json.Marshal(test.obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ar := &v1beta1.AdmissionReview{Request: &v1beta1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Kind: test.kind},
+				Operation: v1beta1.Create,
+				Object:    runtime.RawExtension{Raw: raw},
+			}}
+			resp := &v1beta1.AdmissionReview{Response: &v1beta1.AdmissionResponse{Allowed: true}}
+			if err := handlers[test.kind](context.Background(), ar, resp, &Config{}); err != nil {
+				t.Fatal(err)
+			}
+			testutil.DeepEqual(t, false, resp.Response.Allowed)
+			expected := fmt.Sprintf("%s default/web is denied, fix the images of containers web (%s): found violations in %q",
+				test.kind, testutil.QualifiedImage, testutil.QualifiedImage)
+			testutil.DeepEqual(t, expected, resp.Response.Result.Message)
+
+			// Updates adding no image, e.g. scaling, are not reviewed.
+			ar.Request.Operation = v1beta1.Update
+			ar.Request.OldObject = runtime.RawExtension{Raw: raw}
+			resp = &v1beta1.AdmissionReview{Response: &v1beta1.AdmissionResponse{Allowed: true}}
+			if err := handlers[test.kind](context.Background(), ar, resp, &Config{}); err != nil {
+				t.Fatal(err)
+			}
+			testutil.DeepEqual(t, true, resp.Response.Allowed)
+		})
+	}
+}
+
+func TestHandleEphemeralContainers(t *testing.T) {
+	original := admissionConfig
+	defer func() {
+		admissionConfig = original
+	}()
+	var reviewed []string
+	var selected map[string]string
+	admissionConfig = config{
+		fetchMetadataClient: func(config *Config) (metadata.Fetcher, error) {
+			return &testutil.MockMetadataClient{}, nil
+		},
+		fetchImageSecurityPolicies: func(namespace string) ([]kritisv1beta1.ImageSecurityPolicy, error) {
+			return []kritisv1beta1.ImageSecurityPolicy{{}}, nil
+		},
+		fetchAttestationPolicies: noAttestationPolicies,
+		fetchNamespace: func(name string) (*v1.Namespace, error) {
+			return &v1.Namespace{}, nil
+		},
+		fetchPod: func(namespace, name string) (*v1.Pod, error) {
+			return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": "web"}}}, nil
+		},
+		fetchAttestors: nilAttestorFetcher,
+		reviewer: func(client metadata.Fetcher, attestors securitypolicy.AttestorFetcher, config *Config) review.Interface {
+			return recordingReviewer{images: &reviewed, labels: &selected}
+		},
+	}
+	tests := []struct {
+		name string
+		kind string
+		raw  string
+	}{
+		{
+			name: "pod",
+			kind: "Pod",
+			raw:  `{"metadata": {"name": "web", "namespace": "default"}, "spec": {"containers": [{"name": "web", "image": "gcr.io/kritis-project/web@sha256:0000000000000000000000000000000000000000000000000000000000000000"}], "ephemeralContainers": [{"name": "debugger", "image": "gcr.io/kritis-project/debugger@sha256:0000000000000000000000000000000000000000000000000000000000000000"}]}}`,
+		},
+		{
+			name: "ephemeral containers",
+			kind: "EphemeralContainers",
+			raw:  `{"metadata": {"name": "web", "namespace": "default"}, "ephemeralContainers": [{"name": "debugger", "image": "gcr.io/kritis-project/debugger@sha256:0000000000000000000000000000000000000000000000000000000000000000"}]}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reviewed = nil
+			ar := &v1beta1.AdmissionReview{Request: &v1beta1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Kind: test.kind},
+				Operation: v1beta1.Update,
+				Object:    runtime.RawExtension{Raw: []byte(test.raw)},
+			}}
+			resp := &v1beta1.AdmissionReview{Response: &v1beta1.AdmissionResponse{Allowed: true}}
+			if err := handlers[test.kind](context.Background(), ar, resp, &Config{}); err != nil {
+				t.Fatal(err)
+			}
+			if !contains(reviewed, "gcr.io/kritis-project/debugger@sha256:0000000000000000000000000000000000000000000000000000000000000000") {
+				t.Errorf("expected the image of the ephemeral container to be reviewed, got %v", reviewed)
+			}
+		})
+	}
+	// The pod of ephemeral containers is fetched for its labels.
+	testutil.DeepEqual(t, map[string]string{"app": "web"}, selected)
+}
+
+// recordingReviewer records the images reviewed and the labels of their pod.
+type recordingReviewer struct {
+	images *[]string
+	labels *map[string]string
+}
+
+func (r recordingReviewer) ReviewContext(ctx context.Context, images []string, isps []kritisv1beta1.ImageSecurityPolicy, pod *v1.Pod) error {
+	*r.images = append(*r.images, images...)
+	if pod != nil {
+		*r.labels = pod.Labels
+	}
+	return nil
+}
+
+func (r recordingReviewer) ReviewGAP(ctx context.Context, images []string, gaps []kritisv1beta1.GenericAttestationPolicy, pod *v1.Pod) error {
+	return nil
+}
+
+func (r recordingReviewer) Validate(ctx context.Context, image string, isps []kritisv1beta1.ImageSecurityPolicy) ([][]policy.Violation, error) {
+	return make([][]policy.Violation, len(isps)), nil
+}
+
+func (r recordingReviewer) ValidateGAP(ctx context.Context, image string, gaps []kritisv1beta1.GenericAttestationPolicy) ([][]policy.Violation, error) {
+	return make([][]policy.Violation, len(gaps)), nil
+}
+
+func (r recordingReviewer) Promote(ctx context.Context, images []string, isps []kritisv1beta1.ImageSecurityPolicy, pod *v1.Pod) {
+}
+
+func contains(images []string, image string) bool {
+	for _, i := range images {
+		if i == image {
+			return true
+		}
+	}
+	return false
+}
@@ -37,6 +37,67 @@ type AttestationAuthoritySpec struct {
 	PrivateKeySecretName string `json:"privateKeySecretName"`
 	PublicKeyData        string `json:"publicKeyData"`
 	PolicyType           string `json:"policyType"`
+
+	// KMSKeyName signs attestations with the Cloud KMS CryptoKeyVersion instead of a PGP key, e.g.
+	// projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1. The publicKeyData is
+	// then the base64 encoded PEM public key of the version. Keys of AWS KMS and Azure Key Vault
+	// are prefixed with awskms:// and azurekv://.
+	KMSKeyName string `json:"kmsKeyName,omitempty"`
+
+	// PublicKeyID verifies attestations signed with a PKIX key outside of kritis, e.g. the ID of
+	// a key of a Binary Authorization attestor. The publicKeyData is then the base64 encoded PEM
+	// public key.
+	PublicKeyID string `json:"publicKeyId,omitempty"`
+
+	// VaultKey reads the signing key from HashiCorp Vault instead of the privateKeySecretName Secret.
+	VaultKey *VaultKeySpec `json:"vaultKey,omitempty"`
+
+	// SecretManagerKey reads the signing key from Google Secret Manager instead of the
+	// privateKeySecretName Secret.
+	SecretManagerKey *SecretManagerKeySpec `json:"secretManagerKey,omitempty"`
+
+	// PassphraseSecretName is a Secret, in the namespace of the signing key Secret, holding
+	// the passphrase of an encrypted private key in its "passphrase" field, or in
+	// PassphraseSecretKey. It is only read if the key isn't decrypted with its own passphrase.
+	PassphraseSecretName string `json:"passphraseSecretName,omitempty"`
+	PassphraseSecretKey  string `json:"passphraseSecretKey,omitempty"`
+
+	// PreviousKeys are the PGP public keys the authority signed with before its key was
+	// rotated, see kritis authority rotate.
+	PreviousKeys []PreviousKey `json:"previousKeys,omitempty"`
+}
+
+// PreviousKey is a retiring PGP public key of an AttestationAuthority, whose attestations are
+// still verified until RetireTime.
+type PreviousKey struct {
+	// PublicKeyData is the base64 encoded public key, as the publicKeyData of the authority.
+	PublicKeyData string `json:"publicKeyData"`
+	// RetireTime is when attestations signed with the key stop being verified.
+	RetireTime metav1.Time `json:"retireTime"`
+}
+
+// VaultKeySpec locates the signing key of an AttestationAuthority in HashiCorp Vault.
+// With the kv engine, the private and public keys and the passphrase are read from the
+// "private", "public" and "passphrase" fields of a KV version 2 secret. With the
+// transit engine, images are signed by Vault and the private key never leaves it.
+// The Vault server and the auth method are set in the KritisConfig.
+type VaultKeySpec struct {
+	// Engine is either kv or transit.
+	Engine string `json:"engine"`
+	// Mount is the path of the secrets engine, "secret" or "transit" by default.
+	Mount string `json:"mount,omitempty"`
+	// Path is the path of the KV secret, or the name of the transit key.
+	Path string `json:"path"`
+}
+
+// SecretManagerKeySpec locates the signing key of an AttestationAuthority in Google Secret
+// Manager. The payload of the secret version is a JSON object with the "private" and "public"
+// keys and the "passphrase", like the fields of the privateKeySecretName Secret.
+type SecretManagerKeySpec struct {
+	// Secret is the resource name of the secret, e.g. projects/p/secrets/kritis-qa.
+	Secret string `json:"secret"`
+	// Version of the secret, "latest" by default.
+	Version string `json:"version,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
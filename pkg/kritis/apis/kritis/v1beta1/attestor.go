@@ -0,0 +1,61 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:noStatus
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Attestor defines the public keys of an attestor in the cluster, for clusters which
+// can't use Binary Authorization attestors in requireAttestationsBy.
+type Attestor struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AttestorSpec `json:"spec"`
+}
+
+// AttestorSpec is the spec for a Attestor resource
+type AttestorSpec struct {
+	// PublicKeys verify the attestations of the attestor, any one of them is sufficient.
+	PublicKeys []AttestorPublicKey `json:"publicKeys"`
+}
+
+// AttestorPublicKey is a PGP or PKIX public key of an Attestor.
+type AttestorPublicKey struct {
+	// ID of the signatures made with the key. Defaults to the fingerprint of PGP keys.
+	ID string `json:"id,omitempty"`
+	// AsciiArmoredPgpPublicKey is the ASCII armored PGP public key.
+	AsciiArmoredPgpPublicKey string `json:"asciiArmoredPgpPublicKey,omitempty"`
+	// PkixPublicKeyPem is the PEM encoded PKIX public key, verifying generic signed attestations.
+	PkixPublicKeyPem string `json:"pkixPublicKeyPem,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AttestorList is a list of Attestor resources
+type AttestorList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []Attestor `json:"items"`
+}
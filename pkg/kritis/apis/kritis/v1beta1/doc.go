@@ -17,5 +17,5 @@ limitations under the License.
 // +k8s:deepcopy-gen=package
 
 // Package v1beta1 is the v1beta1 version of the API.
-// +groupName=kritis
+// +groupName=kritis.grafeas.io
 package v1beta1
@@ -0,0 +1,66 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// GenericAttestationPolicy requires images to be attested, independently of the
+// vulnerabilities checked by ImageSecurityPolicies.
+type GenericAttestationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GenericAttestationPolicySpec `json:"spec"`
+}
+
+// GenericAttestationPolicySpec is the spec for a GenericAttestationPolicy resource
+type GenericAttestationPolicySpec struct {
+	// ImageWhitelist lists images admitted without attestations.
+	ImageWhitelist []string `json:"imageWhitelist,omitempty"`
+
+	// AttestationAuthorityNames lists the AttestationAuthorities of the namespace trusted
+	// to attest images. Images need a valid attestation of one of them.
+	AttestationAuthorityNames []string `json:"attestationAuthorityNames,omitempty"`
+
+	// AttestationAuthoritySelector adds the AttestationAuthorities of the namespace matching the
+	// selector to those listed by name.
+	AttestationAuthoritySelector *metav1.LabelSelector `json:"attestationAuthoritySelector,omitempty"`
+
+	// RequireAttestationsBy lists Binary Authorization attestors which must all have attested images.
+	// An entry "M of a, b, c" requires attestations of M of the listed attestors.
+	RequireAttestationsBy []string `json:"requireAttestationsBy,omitempty"`
+
+	// PodSelector limits the policy to pods matching the selector.
+	// The policy applies to all pods of its namespace if it is not set.
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// GenericAttestationPolicyList is a list of GenericAttestationPolicy resources
+type GenericAttestationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []GenericAttestationPolicy `json:"items"`
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageReview records the outcome of reviewing an image against a policy.
+// ImageReviews are written by kritis and are not read back by it.
+type ImageReview struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageReviewSpec   `json:"spec"`
+	Status ImageReviewStatus `json:"status,omitempty"`
+}
+
+// ImageReviewSpec is the spec for a ImageReview resource
+type ImageReviewSpec struct {
+	// Image is the reviewed image.
+	Image string `json:"image"`
+	// PolicyKind is ImageSecurityPolicy or GenericAttestationPolicy.
+	PolicyKind string `json:"policyKind"`
+	// Policy is the name of the reviewed policy, in the namespace of the ImageReview.
+	Policy string `json:"policy"`
+	// Pod is the name of the reviewed pod, if any.
+	Pod string `json:"pod,omitempty"`
+	// Source is "webhook" for admission reviews and "cron" for background checks.
+	Source string `json:"source,omitempty"`
+}
+
+// ImageReviewStatus is the outcome of a review.
+type ImageReviewStatus struct {
+	// Allowed is true if the image doesn't violate the policy.
+	Allowed bool `json:"allowed"`
+	// Attested is true if the image had a valid attestation, skipping the policy checks.
+	Attested bool `json:"attested,omitempty"`
+	// Violations are the policy violations found for the image.
+	Violations []ImageReviewViolation `json:"violations,omitempty"`
+	// ReviewTime is the time of the review.
+	ReviewTime metav1.Time `json:"reviewTime"`
+}
+
+// ImageReviewViolation is a single policy violation.
+type ImageReviewViolation struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageReviewList is a list of ImageReview resources
+type ImageReviewList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ImageReview `json:"items"`
+}
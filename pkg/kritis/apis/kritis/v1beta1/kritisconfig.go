@@ -36,15 +36,149 @@ type KritisConfig struct {
 type KritisConfigSpec struct {
 	// The backend to use for storing security metadata
 	MetadataBackend string `json:"metadataBackend"`
+	// Fixtures file used when MetadataBackend is "file"
+	MetadataFile string `json:"metadataFile,omitempty"`
+	// Signed vulnerability bundle used instead of MetadataFile when MetadataBackend is "file"
+	VulnerabilityBundle VulnerabilityBundleSpec `json:"vulnerabilityBundle,omitempty"`
 	// Cron job time interval, as Duration e.g. "1h", "2s"
 	CronInterval string `json:"cronInterval"`
+	// Number of namespaces reviewed concurrently by the cron job, defaults to 2
+	CronWorkers int `json:"cronWorkers,omitempty"`
+	// LeaderElection has replicas elect the only one running the cron job
+	LeaderElection LeaderElectionSpec `json:"leaderElection,omitempty"`
+	// ComplianceReport has the cron job periodically write the compliance of each namespace
+	ComplianceReport ComplianceReportSpec `json:"complianceReport,omitempty"`
+	// ViolationRecords has the cron job keep the violations of each namespace reviewed
+	ViolationRecords ViolationRecordsSpec `json:"violationRecords,omitempty"`
 	// Server address, with the preceding colon
 	ServerAddr string `json:"serverAddr"`
+	// Address of the gRPC policy evaluation API, with the preceding colon. Not served if empty
+	GRPCAddr string `json:"grpcAddr,omitempty"`
+	// GRPCReflection serves the gRPC reflection service along with the evaluation API
+	GRPCReflection bool `json:"grpcReflection,omitempty"`
 	// Grafeas configuration used for communicating with Grafeas backend
 	Grafeas GrafeasConfigSpec `json:"grafeas"`
+	// ContainerAnalysis configuration used when MetadataBackend is "containerAnalysis"
+	ContainerAnalysis ContainerAnalysisConfigSpec `json:"containerAnalysis,omitempty"`
+	// GCPCredentials of the Container Analysis, Binary Authorization and Cloud KMS clients,
+	// which use Application Default Credentials if not set
+	GCPCredentials GCPCredentialsSpec `json:"gcpCredentials,omitempty"`
+	// Azure configuration used when MetadataBackend is "azure"
+	Azure AzureConfigSpec `json:"azure,omitempty"`
+	// RegistryCredentials used to resolve tags and read manifests, besides the image pull secrets of pods
+	RegistryCredentials RegistryCredentialsSpec `json:"registryCredentials,omitempty"`
+	// Sigstore trust roots verifying keyless cosign signatures, see ImageSecurityPolicySpec.PublisherWhitelist
+	Sigstore SigstoreSpec `json:"sigstore,omitempty"`
+	// Notation trust store verifying Notary Project signatures, see ImageSecurityPolicySpec.PublisherWhitelist
+	Notation NotationSpec `json:"notation,omitempty"`
+	// OSV enrichment of the vulnerabilities returned by the metadata backend
+	OSV OSVConfigSpec `json:"osv,omitempty"`
+	// Harbor registries queried when MetadataBackend is "harbor"
+	Harbor []HarborRegistrySpec `json:"harbor,omitempty"`
+	// TLS configuration applied to all servers run by Kritis
+	TLS TLSConfigSpec `json:"tls,omitempty"`
+	// Tracing configures the export of the traces of admission reviews
+	Tracing TracingSpec `json:"tracing,omitempty"`
+	// Health configures the probes run by the /readyz endpoint
+	Health HealthSpec `json:"health,omitempty"`
+	// AuditDecisions records the violations found by the webhook and the cron job as
+	// Discovery occurrences on the images, in the metadata backend
+	AuditDecisions bool `json:"auditDecisions,omitempty"`
+	// RecordImageReviews creates an ImageReview for each image and policy reviewed by
+	// the webhook and the cron job
+	RecordImageReviews bool `json:"recordImageReviews,omitempty"`
+	// Promotions attest the images passing the ImageSecurityPolicies of staging namespaces
+	// on behalf of an AttestationAuthority
+	Promotions []PromotionSpec `json:"promotions,omitempty"`
+	// AnnotateDecisions has the mutating webhook annotate the pods it admits with the
+	// review time, the reviewed policies and the verified attestations
+	AnnotateDecisions bool `json:"annotateDecisions,omitempty"`
+	// ReviewOnPolicyChange has the cron job watch ImageSecurityPolicies and review the
+	// pods of their namespace as soon as their spec changes, besides every CronInterval
+	ReviewOnPolicyChange bool `json:"reviewOnPolicyChange,omitempty"`
+	// Receivers of the summaries of violations found by the webhook and the cron job
+	Notifications []NotificationSpec `json:"notifications,omitempty"`
+	// ViolationStrategies is the ordered chain of strategies handling the violations of all
+	// policies, instead of the default ones, see ImageSecurityPolicySpec.ViolationStrategies
+	ViolationStrategies []string `json:"violationStrategies,omitempty"`
 
 	// ImageWhitelist used for admit docker images without validating
 	ImageWhitelist []string `json:"imageWhitelist"`
+	// DefaultAttestationAuthority is used by ImageSecurityPolicies which list no
+	// AttestationAuthority, as the name of an authority in the policy namespace
+	DefaultAttestationAuthority string `json:"defaultAttestationAuthority,omitempty"`
+	// SkipMetadataKinds lists metadata kinds (VULNERABILITY, BUILD, OCCURRENCE_V1)
+	// which are never fetched from the metadata backend, for any policy
+	SkipMetadataKinds []string `json:"skipMetadataKinds,omitempty"`
+	// MetadataTimeout bounds each call to the metadata backend, as Duration e.g. "5s"
+	MetadataTimeout string `json:"metadataTimeout,omitempty"`
+	// MetadataTimeoutFallback is "fail" to fail the review when a call times out, or
+	// "skip" to review the image without the metadata which timed out
+	MetadataTimeoutFallback string `json:"metadataTimeoutFallback,omitempty"`
+	// MetadataRateLimit bounds the calls made to the metadata backend by the webhook and the cron job together
+	MetadataRateLimit RateLimitSpec `json:"metadataRateLimit,omitempty"`
+	// MetadataFailurePolicy sets whether the webhook denies or allows admission when
+	// the metadata backend fails. Admission is denied if not set
+	MetadataFailurePolicy MetadataFailurePolicySpec `json:"metadataFailurePolicy,omitempty"`
+	// WebhookFailurePolicies set the failurePolicy the API server applies when the
+	// webhook can't be reached, per namespace
+	WebhookFailurePolicies WebhookFailurePoliciesSpec `json:"webhookFailurePolicies,omitempty"`
+	// AttestationLogPath is the file where attestations created by Kritis are
+	// recorded, /var/lib/kritis/attestations.log if empty
+	AttestationLogPath string `json:"attestationLogPath,omitempty"`
+	// AttestationLogMaxRecords is the number of latest attestations kept, 10000 if not set
+	AttestationLogMaxRecords int `json:"attestationLogMaxRecords,omitempty"`
+	// DecisionLog lists the sinks every admission decision is written to, as JSON
+	DecisionLog []DecisionLogSinkSpec `json:"decisionLog,omitempty"`
+	// Exemptions select objects which are admitted, and pods which are not
+	// remediated, without review
+	Exemptions []ExemptionSpec `json:"exemptions,omitempty"`
+	// Vault is the HashiCorp Vault server holding the vaultKey of AttestationAuthorities
+	Vault VaultConfigSpec `json:"vault,omitempty"`
+	// KMSKeys lists the KMS keys the AttestationAuthorities of each namespace may sign
+	// with, by kmsKeyName. Authorities of other namespaces can't sign with a KMS
+	KMSKeys []KeyAllowlistSpec `json:"kmsKeys,omitempty"`
+	// SecretManagerKeys lists the Secret Manager secrets, as projects/p/secrets/s, the
+	// AttestationAuthorities of each namespace may read their secretManagerKey from.
+	// Authorities of other namespaces can't use Secret Manager
+	SecretManagerKeys []KeyAllowlistSpec `json:"secretManagerKeys,omitempty"`
+}
+
+// VaultConfigSpec configures the Vault server and the keys each namespace may sign with
+type VaultConfigSpec struct {
+	// Address of the Vault server, e.g. https://vault.example.com:8200
+	Address string `json:"address,omitempty"`
+	// Role of the Kubernetes auth method the service account of kritis logs in with,
+	// not needed if the VAULT_TOKEN environment variable is set
+	Role string `json:"role,omitempty"`
+	// AuthMount is the path of the Kubernetes auth method, "kubernetes" by default
+	AuthMount string `json:"authMount,omitempty"`
+	// Timeout of the requests to Vault, as Duration, defaults to "10s"
+	Timeout string `json:"timeout,omitempty"`
+	// AllowedKeys lists the keys, as mount/path, the AttestationAuthorities of each
+	// namespace may use. Authorities of other namespaces can't use Vault
+	AllowedKeys []KeyAllowlistSpec `json:"allowedKeys,omitempty"`
+}
+
+// KeyAllowlistSpec lists the signing keys the AttestationAuthorities of a namespace may use
+type KeyAllowlistSpec struct {
+	// Namespace of the AttestationAuthorities
+	Namespace string `json:"namespace"`
+	// Keys are the names of the keys, a name ending with "*" matches the keys starting
+	// with the rest of it
+	Keys []string `json:"keys"`
+}
+
+// ExemptionSpec selects objects exempt from review, those matching all of its fields
+type ExemptionSpec struct {
+	// Namespaces are the names of the namespaces of the objects
+	Namespaces []string `json:"namespaces,omitempty"`
+	// NamespaceSelector matches the labels of the namespaces of the objects
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// Images are the names of the images of the objects, which must all be listed
+	Images []string `json:"images,omitempty"`
+	// ServiceAccounts are the service accounts pods run as, as "namespace/name"
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
 }
 
 // GrafeasConfigSpec holds the configuration required for connecting to grafeas instance
@@ -55,6 +189,342 @@ type GrafeasConfigSpec struct {
 	ClientCertPath string `json:"clientCertPath"`
 }
 
+// ContainerAnalysisConfigSpec holds the configuration of the Container Analysis backend
+type ContainerAnalysisConfigSpec struct {
+	// Retry of the calls failing with transient errors
+	Retry RetrySpec `json:"retry,omitempty"`
+	// MaxOccurrences listed for an image, defaults to 1000. Reviews fail for images with more occurrences
+	MaxOccurrences int `json:"maxOccurrences,omitempty"`
+	// Projects maps image prefixes to the project holding the metadata of their images,
+	// instead of the project in the path of GCR and Artifact Registry images
+	Projects []ProjectMappingSpec `json:"projects,omitempty"`
+	// DefaultProject holds the metadata of the images whose project is neither mapped nor in their path
+	DefaultProject string `json:"defaultProject,omitempty"`
+	// Connection of the gRPC clients, shared by all reviews
+	Connection GRPCConnectionSpec `json:"connection,omitempty"`
+}
+
+// GRPCConnectionSpec tunes the gRPC connections to an API
+type GRPCConnectionSpec struct {
+	// PoolSize is the number of connections calls are balanced over, defaults to 1
+	PoolSize int `json:"poolSize,omitempty"`
+	// KeepaliveTime between pings of idle connections, as Duration, defaults to "5m"
+	KeepaliveTime string `json:"keepaliveTime,omitempty"`
+	// KeepaliveTimeout after which connections whose ping is not answered are closed, as Duration, defaults to "20s"
+	KeepaliveTimeout string `json:"keepaliveTimeout,omitempty"`
+	// MaxRecvMsgSize is the largest response in bytes, responses are not limited if not set
+	MaxRecvMsgSize int `json:"maxRecvMsgSize,omitempty"`
+	// MaxSendMsgSize is the largest request in bytes, requests are not limited if not set
+	MaxSendMsgSize int `json:"maxSendMsgSize,omitempty"`
+}
+
+// ProjectMappingSpec maps the images under a prefix to a project
+type ProjectMappingSpec struct {
+	// Prefix of the images, as registry or registry/repository, e.g. "mirror.example.com/gcr"
+	Prefix string `json:"prefix"`
+	// Project holding the metadata of the images
+	Project string `json:"project"`
+}
+
+// GCPCredentialsSpec sets the credentials of all Google Cloud clients, or of each client
+type GCPCredentialsSpec struct {
+	GCPClientCredentialsSpec `json:",inline"`
+	// ContainerAnalysis credentials, used instead of those of all clients
+	ContainerAnalysis *GCPClientCredentialsSpec `json:"containerAnalysis,omitempty"`
+	// BinaryAuthorization credentials, used instead of those of all clients
+	BinaryAuthorization *GCPClientCredentialsSpec `json:"binaryAuthorization,omitempty"`
+	// KMS credentials of the Cloud KMS signers, used instead of those of all clients
+	KMS *GCPClientCredentialsSpec `json:"kms,omitempty"`
+	// SecretManager credentials, reading the signing keys of authorities, used instead of those of all clients
+	SecretManager *GCPClientCredentialsSpec `json:"secretManager,omitempty"`
+	// Storage credentials, writing the compliance reports, violation records and decision log to Cloud Storage, used instead of those of all clients
+	Storage *GCPClientCredentialsSpec `json:"storage,omitempty"`
+	// BigQuery credentials, streaming the decision log into BigQuery, used instead of those of all clients
+	BigQuery *GCPClientCredentialsSpec `json:"bigQuery,omitempty"`
+}
+
+// GCPClientCredentialsSpec sets the credentials of a Google Cloud client
+type GCPClientCredentialsSpec struct {
+	// KeySecret holds a service account key in "key.json", as "namespace/name".
+	// Application Default Credentials are used if not set
+	KeySecret string `json:"keySecret,omitempty"`
+	// ImpersonateServiceAccount is the email of a service account impersonated with the credentials
+	ImpersonateServiceAccount string `json:"impersonateServiceAccount,omitempty"`
+	// Delegates are the service accounts in the delegation chain to ImpersonateServiceAccount
+	Delegates []string `json:"delegates,omitempty"`
+	// QuotaProject is billed for the calls
+	QuotaProject string `json:"quotaProject,omitempty"`
+}
+
+// RetrySpec sets how calls failing with transient errors are retried, and when
+// the circuit breaker stops making them
+type RetrySpec struct {
+	// MaxAttempts of each call, defaults to 3. 1 disables retries
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// InitialBackoff before the first retry, as Duration, defaults to "200ms". It doubles for each retry
+	InitialBackoff string `json:"initialBackoff,omitempty"`
+	// MaxBackoff between retries, as Duration, defaults to "2s"
+	MaxBackoff string `json:"maxBackoff,omitempty"`
+	// Budget caps the time spent retrying a call, as Duration, defaults to "5s"
+	Budget string `json:"budget,omitempty"`
+	// BreakerThreshold is the number of consecutive failed calls opening the circuit breaker, defaults to 5
+	BreakerThreshold int `json:"breakerThreshold,omitempty"`
+	// BreakerCooldown the circuit breaker stays open for, as Duration, defaults to "30s"
+	BreakerCooldown string `json:"breakerCooldown,omitempty"`
+	// BreakerFallback of the calls made while the circuit breaker is open: "fail", the default, or "skip" to return no metadata
+	BreakerFallback string `json:"breakerFallback,omitempty"`
+}
+
+// OSVConfigSpec holds the configuration of the OSV.dev enrichment of vulnerabilities
+type OSVConfigSpec struct {
+	// Enabled enables the enrichment
+	Enabled bool `json:"enabled"`
+	// URL of the OSV API, defaults to "https://api.osv.dev"
+	URL string `json:"url,omitempty"`
+}
+
+// HarborRegistrySpec holds the configuration required for querying a Harbor registry
+type HarborRegistrySpec struct {
+	// Host of the registry in image references, e.g. "harbor.example.com"
+	Host string `json:"host"`
+	// URL of the Harbor API, defaults to "https://<host>"
+	URL string `json:"url,omitempty"`
+	// CredentialsSecret holds the "username" and "password" of a robot account, as "namespace/name"
+	CredentialsSecret string `json:"credentialsSecret"`
+}
+
+// AzureConfigSpec holds the configuration required for querying Defender for Cloud
+type AzureConfigSpec struct {
+	// Subscriptions whose container image assessments are queried
+	Subscriptions []string `json:"subscriptions"`
+}
+
+// NotificationSpec holds the configuration of a receiver of violation notifications
+type NotificationSpec struct {
+	// Type of the receiver: "slack", "webhook" or "pagerduty"
+	Type string `json:"type"`
+	// URL notifications are posted to, defaults to the Events API for "pagerduty"
+	URL string `json:"url,omitempty"`
+	// Secret holding the "url", overriding URL, and the "token": the bearer token
+	// of webhooks or the routing key of PagerDuty, as "namespace/name"
+	Secret string `json:"secret,omitempty"`
+}
+
+// DecisionLogSinkSpec holds the configuration of a sink of the admission decision log
+type DecisionLogSinkSpec struct {
+	// Type of the sink: "stdout", "file", "gcs" or "bigquery"
+	Type string `json:"type"`
+	// Path of the "file" decisions are appended to, one JSON object per line
+	Path string `json:"path,omitempty"`
+	// Bucket of "gcs", each decision is written to an object under Prefix
+	Bucket string `json:"bucket,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	// Project, Dataset and Table of "bigquery" decisions are streamed into
+	Project string `json:"project,omitempty"`
+	Dataset string `json:"dataset,omitempty"`
+	Table   string `json:"table,omitempty"`
+}
+
+// TLSConfigSpec holds the TLS settings of the servers run by Kritis
+type TLSConfigSpec struct {
+	// Minimum TLS version, "1.2" or "1.3"
+	MinVersion string `json:"minVersion,omitempty"`
+	// Allowed TLS 1.2 cipher suites, using their IANA names
+	CipherSuites []string `json:"cipherSuites,omitempty"`
+	// Client certificate policy: "none", "request", "require", "verifyIfGiven" or "requireAndVerify"
+	ClientAuth string `json:"clientAuth,omitempty"`
+	// CA bundle used to verify client certificates
+	ClientCAPath string `json:"clientCAPath,omitempty"`
+	// Certificates provisions and rotates the serving certificate, instead of reading it from files
+	Certificates CertificatesSpec `json:"certificates,omitempty"`
+}
+
+// CertificatesSpec configures how the serving certificate of the webhooks is provisioned and rotated
+type CertificatesSpec struct {
+	// Mode is "selfSigned" to issue certificates from a CA generated by Kritis, or "certManager"
+	// to serve the certificate cert-manager keeps in the secret. Certificates are read from files if not set
+	Mode string `json:"mode,omitempty"`
+	// SecretName is the secret holding the certificate, defaults to tls-webhook-secret
+	SecretName string `json:"secretName,omitempty"`
+	// Namespace is the namespace of the secret, defaults to the namespace of Kritis
+	Namespace string `json:"namespace,omitempty"`
+	// Services are the webhook services the certificate is issued for,
+	// defaults to kritis-validation-hook and kritis-validation-hook-deployments
+	Services []string `json:"services,omitempty"`
+	// ValidatingWebhooks are the ValidatingWebhookConfigurations whose caBundle is kept up to date,
+	// defaults to kritis-validation-hook and kritis-validation-hook-deployments
+	ValidatingWebhooks []string `json:"validatingWebhooks,omitempty"`
+	// MutatingWebhooks are the MutatingWebhookConfigurations whose caBundle is kept up to date,
+	// defaults to kritis-mutation-hook
+	MutatingWebhooks []string `json:"mutatingWebhooks,omitempty"`
+	// Validity is how long issued certificates are valid, defaults to 2160h
+	Validity string `json:"validity,omitempty"`
+	// RenewBefore is how long before they expire certificates are renewed, defaults to 720h
+	RenewBefore string `json:"renewBefore,omitempty"`
+}
+
+// HealthSpec configures the probes of the services Kritis depends on
+type HealthSpec struct {
+	// Project queried by the Container Analysis and Binary Authorization probes. The
+	// Container Analysis probe is only run when it is set and the metadata backend is containerAnalysis
+	Project string `json:"project,omitempty"`
+	// BinaryAuthorization probes the Binary Authorization API, for policies using its attestors
+	BinaryAuthorization bool `json:"binaryAuthorization,omitempty"`
+	// Timeout of each probe, as Duration, defaults to "5s"
+	Timeout string `json:"timeout,omitempty"`
+	// Optional lists the probes which don't mark Kritis unready when they fail:
+	// kubernetes, containerAnalysis or binaryAuthorization
+	Optional []string `json:"optional,omitempty"`
+}
+
+// LeaderElectionSpec configures the election of the replica running the cron job
+type LeaderElectionSpec struct {
+	// Enabled has every replica run the cron job only while it holds the lock
+	Enabled bool `json:"enabled,omitempty"`
+	// Namespace of the lock ConfigMap, defaults to the namespace of Kritis
+	Namespace string `json:"namespace,omitempty"`
+	// LockName is the name of the lock ConfigMap, defaults to kritis-cron
+	LockName string `json:"lockName,omitempty"`
+	// LeaseDuration non-leaders wait before taking over the lock, as Duration, defaults to "15s"
+	LeaseDuration string `json:"leaseDuration,omitempty"`
+	// RenewDeadline the leader retries renewing the lock for before it stops leading, as Duration, defaults to "10s"
+	RenewDeadline string `json:"renewDeadline,omitempty"`
+	// RetryPeriod between attempts to take or renew the lock, as Duration, defaults to "2s"
+	RetryPeriod string `json:"retryPeriod,omitempty"`
+}
+
+// ComplianceReportSpec configures the periodic compliance report of the cron job
+type ComplianceReportSpec struct {
+	// Interval between reports, as Duration, defaults to "24h"
+	Interval string `json:"interval,omitempty"`
+	// Sinks the report is written to, no report is written if empty
+	Sinks []ComplianceReportSinkSpec `json:"sinks,omitempty"`
+}
+
+// ComplianceReportSinkSpec holds the configuration of a destination of compliance reports
+type ComplianceReportSinkSpec struct {
+	// Type of the sink: "configmap", "gcs" or "smtp"
+	Type string `json:"type"`
+	// Namespace and Name of the "configmap" holding the latest report, default to
+	// the namespace of Kritis and kritis-compliance-report
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Bucket of "gcs", each report is written to an object under Prefix
+	Bucket string `json:"bucket,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	// Address of the "smtp" server, as host:port
+	Address string `json:"address,omitempty"`
+	// From and To are the sender and recipients of "smtp" reports
+	From string   `json:"from,omitempty"`
+	To   []string `json:"to,omitempty"`
+	// Secret holding the "username" and "password" of "smtp", as "namespace/name"
+	Secret string `json:"secret,omitempty"`
+}
+
+// PromotionSpec has the images reviewed in Namespaces attested by Authority once they pass
+// all their ImageSecurityPolicies
+type PromotionSpec struct {
+	// Namespaces whose images are promoted, e.g. staging namespaces
+	Namespaces []string `json:"namespaces"`
+	// Authority attesting the images, as "namespace/name" of an AttestationAuthority
+	Authority string `json:"authority"`
+}
+
+// ViolationRecordsSpec configures the records of the violations found by the cron job
+type ViolationRecordsSpec struct {
+	// Store of the records: "crd" or "gcs", no record is kept if empty
+	Store string `json:"store,omitempty"`
+	// Retention of the records, as Duration, defaults to "720h"
+	Retention string `json:"retention,omitempty"`
+	// Bucket of "gcs", each record is written to an object under Prefix
+	Bucket string `json:"bucket,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// RateLimitSpec sets the token bucket limiting the rate of calls
+type RateLimitSpec struct {
+	// QPS is the number of calls per second, calls are not limited if not set
+	QPS float64 `json:"qps,omitempty"`
+	// Burst is the number of calls made at once above the QPS, defaults to the QPS rounded up
+	Burst int `json:"burst,omitempty"`
+}
+
+// WebhookFailurePoliciesSpec splits the validating webhooks into one webhook per failure policy
+type WebhookFailurePoliciesSpec struct {
+	// ValidatingWebhooks are the ValidatingWebhookConfigurations whose webhooks are split,
+	// defaults to kritis-validation-hook and kritis-validation-hook-deployments
+	ValidatingWebhooks []string `json:"validatingWebhooks,omitempty"`
+	// Namespaces set the failurePolicy of the namespaces they select, the first matching one
+	// applies. Other namespaces keep the failurePolicy of the webhooks
+	Namespaces []NamespaceFailurePolicySpec `json:"namespaces,omitempty"`
+}
+
+// NamespaceFailurePolicySpec sets the failurePolicy of the webhooks for a set of namespaces
+type NamespaceFailurePolicySpec struct {
+	// Name prefixes the names of the webhooks of the namespaces, and must be unique
+	Name string `json:"name"`
+	// NamespaceSelector matches the labels of the namespaces, with exactly one requirement
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector"`
+	// FailurePolicy is "Fail" or "Ignore"
+	FailurePolicy string `json:"failurePolicy"`
+}
+
+// MetadataFailurePolicySpec sets the outcome of reviews failing because of the metadata backend
+type MetadataFailurePolicySpec struct {
+	// OnError is "failClosed" to deny admission when a call to the backend fails, or "failOpen" to allow it
+	OnError string `json:"onError,omitempty"`
+	// OnTimeout is OnError for the calls which time out, defaults to OnError
+	OnTimeout string `json:"onTimeout,omitempty"`
+	// Backends restricts failing open to these metadata backends, e.g. "harbor"
+	Backends []string `json:"backends,omitempty"`
+}
+
+// TracingSpec holds the OpenTelemetry collector traces are exported to
+type TracingSpec struct {
+	// OTLP gRPC endpoint of the collector, as host:port. Tracing is disabled if not set
+	Endpoint string `json:"endpoint,omitempty"`
+	// Insecure disables TLS when connecting to the collector
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// RegistryCredentialsSpec holds the credentials of private container registries
+type RegistryCredentialsSpec struct {
+	// PullSecrets are docker config Secrets, as namespace/name, used for all images
+	PullSecrets []string `json:"pullSecrets,omitempty"`
+}
+
+// SigstoreSpec holds the trust roots of the Sigstore instance issuing keyless signatures
+type SigstoreSpec struct {
+	// PEM encoded root and intermediate certificates of the Fulcio certificate authority
+	FulcioRoots []string `json:"fulcioRoots,omitempty"`
+	// PEM encoded public keys of the Rekor transparency log
+	RekorPublicKeys []string `json:"rekorPublicKeys,omitempty"`
+}
+
+// NotationSpec holds the trust store of the certificate authorities issuing the
+// signing certificates of Notary Project signatures
+type NotationSpec struct {
+	// PEM encoded root and intermediate certificates of the trusted certificate authorities
+	TrustedCertificates []string `json:"trustedCertificates,omitempty"`
+}
+
+// VulnerabilityBundleSpec holds the location of a signed vulnerability bundle,
+// read from a mounted volume or pulled as an OCI artifact
+type VulnerabilityBundleSpec struct {
+	// Path of the bundle tarball on a mounted volume
+	Path string `json:"path,omitempty"`
+	// Path of the detached signature, defaults to Path with a ".sig" suffix
+	SignaturePath string `json:"signaturePath,omitempty"`
+	// OCI artifact holding the bundle tarball and its signature as layers
+	Image string `json:"image,omitempty"`
+	// Armored PGP public keys trusted to sign bundles
+	PublicKeyPath string `json:"publicKeyPath"`
+	// Maximum age of the bundle, as Duration e.g. "72h"
+	MaxAge string `json:"maxAge,omitempty"`
+	// Interval at which the bundle is loaded again, as Duration, defaults to "1h"
+	RefreshInterval string `json:"refreshInterval,omitempty"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // KritisConfigList is a list of BuildPolicy resources
@@ -49,8 +49,16 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&ImageSecurityPolicyList{},
 		&BuildPolicy{},
 		&BuildPolicyList{},
+		&GenericAttestationPolicy{},
+		&GenericAttestationPolicyList{},
+		&ImageReview{},
+		&ImageReviewList{},
+		&ViolationRecord{},
+		&ViolationRecordList{},
 		&AttestationAuthority{},
 		&AttestationAuthorityList{},
+		&Attestor{},
+		&AttestorList{},
 		&KritisConfig{},
 		&KritisConfigList{},
 	)
@@ -17,6 +17,7 @@ limitations under the License.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -28,7 +29,40 @@ type ImageSecurityPolicy struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec ImageSecurityPolicySpec `json:"spec"`
+	Spec   ImageSecurityPolicySpec   `json:"spec"`
+	Status ImageSecurityPolicyStatus `json:"status,omitempty"`
+}
+
+// ImageSecurityPolicyStatus is the compliance of the pods selected by an
+// ImageSecurityPolicy, as of the last scan of the cron controller
+type ImageSecurityPolicyStatus struct {
+	// ScannedImages is the number of distinct images of the pods reviewed
+	ScannedImages int `json:"scannedImages"`
+	// ViolatingPods is the number of pods found violating the policy
+	ViolatingPods int `json:"violatingPods"`
+	// LastScanTime is when the pods were last reviewed
+	LastScanTime *metav1.Time `json:"lastScanTime,omitempty"`
+	// Violations counts the violations found per type, e.g. SeverityViolation
+	Violations map[string]int `json:"violations,omitempty"`
+	// Conditions are the latest observations of the state of the policy
+	Conditions []ImageSecurityPolicyCondition `json:"conditions,omitempty"`
+}
+
+// ImageSecurityPolicyConditionType is the type of a condition of an ImageSecurityPolicy
+type ImageSecurityPolicyConditionType string
+
+// ImageSecurityPolicyReconciled is true once the cron controller reviewed the pods of
+// the policy, and false with the error while it fails to
+const ImageSecurityPolicyReconciled ImageSecurityPolicyConditionType = "Reconciled"
+
+// ImageSecurityPolicyCondition is an observation of the state of an ImageSecurityPolicy
+type ImageSecurityPolicyCondition struct {
+	Type   ImageSecurityPolicyConditionType `json:"type"`
+	Status corev1.ConditionStatus           `json:"status"`
+	// LastTransitionTime is when the condition last changed status
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	Reason             string      `json:"reason,omitempty"`
+	Message            string      `json:"message,omitempty"`
 }
 
 // ImageSecurityPolicySpec is the spec for a ImageSecurityPolicy resource
@@ -37,8 +71,101 @@ type ImageSecurityPolicySpec struct {
 	PackageVulnerabilityRequirements PackageVulnerabilityRequirements `json:"packageVulnerabilityRequirements"`
 	AttestationAuthorityNames        []string                         `json:"attestationAuthorityNames"`
 
-	BuiltProjectIDs       []string `json:"builtProjectIDs"`
+	// AttestationAuthoritySelector adds the AttestationAuthorities of the namespace matching the
+	// selector to those listed by name, so new authorities don't need every policy to be edited.
+	AttestationAuthoritySelector *metav1.LabelSelector `json:"attestationAuthoritySelector,omitempty"`
+
+	// PublisherWhitelist exempts images from vulnerability checks if they carry a keyless cosign
+	// or Notary Project signature whose certificate identity matches one of the publishers, e.g.
+	// of official vendor images.
+	PublisherWhitelist []PublisherIdentity `json:"publisherWhitelist,omitempty"`
+
+	// BuiltProjectIDs rejects images not hosted in the GCR repositories of the projects,
+	// unless signed by ArkCI for one of them. Deprecated: use AllowedRepositories.
+	BuiltProjectIDs []string `json:"builtProjectIDs"`
+
+	// RequireAttestationsBy lists Binary Authorization attestors which must all have attested images.
+	// An entry "M of a, b, c" requires attestations of M of the listed attestors.
+	// Deprecated: use a GenericAttestationPolicy.
 	RequireAttestationsBy []string `json:"requireAttestationsBy"`
+
+	// MaximumAttestationAgeDays ignores Kritis attestations older than the number of days,
+	// so that images are validated and attested again, by the cron job too.
+	// Attestations whose creation time is unknown count as expired.
+	MaximumAttestationAgeDays int `json:"maximumAttestationAgeDays,omitempty"`
+
+	// AllowedRepositories rejects images not hosted in one of the listed repositories.
+	// A * matches any characters, e.g. europe-docker.pkg.dev/my-project/* or registry.example.com/*.
+	AllowedRepositories []string `json:"allowedRepositories,omitempty"`
+
+	// RequireImageDigest rejects images referenced by a mutable tag instead of a digest.
+	// Tagged images are not resolved to their digest by the webhook when it is set.
+	RequireImageDigest bool `json:"requireImageDigest,omitempty"`
+
+	// DockerHubImages decides whether Docker Hub images are admissible:
+	// "ALLOW" (default), "OFFICIAL_ONLY" for official images only, or "DENY".
+	DockerHubImages string `json:"dockerHubImages,omitempty"`
+
+	// PlatformValidation checks the vulnerabilities of each platform of multi-arch images:
+	// "ALL" for all platforms, or "NODE_SELECTOR" for those selected by the nodeSelector of the pod.
+	// Images are checked by the digest they are referenced by if unset.
+	PlatformValidation string `json:"platformValidation,omitempty"`
+
+	// RequireScanResults rejects images without any vulnerability, which usually were never
+	// scanned, with a NotScannedViolation, unless the backend reports their scan finished.
+	RequireScanResults bool `json:"requireScanResults,omitempty"`
+
+	// ScanResultsTimeoutSeconds keeps reading the vulnerabilities of images without any for up to
+	// the given seconds when RequireScanResults is set, for scans still in progress. Admission
+	// requests stop waiting shortly before the webhook times out.
+	ScanResultsTimeoutSeconds int `json:"scanResultsTimeoutSeconds,omitempty"`
+
+	// WaitForScanSeconds waits up to the given seconds for the vulnerability scans of images in
+	// progress, as reported by the metadata backend, to finish. Images whose scan is still in
+	// progress are rejected with a NotScannedViolation. Admission requests stop waiting
+	// shortly before the webhook times out.
+	WaitForScanSeconds int `json:"waitForScanSeconds,omitempty"`
+
+	// ArkCISignatureRequirements configures how ArkCI signatures are verified.
+	ArkCISignatureRequirements ArkCISignatureRequirements `json:"arkCISignatureRequirements,omitempty"`
+
+	// RequireSBOM rejects images without an SBOM in one of the listed formats (SPDX, CycloneDX).
+	// SBOMs are looked up in attestation occurrences and in SBOMs attached to the image in its registry.
+	RequireSBOM []string `json:"requireSBOM,omitempty"`
+
+	// AllowedBaseImages rejects images which were not built from one of the listed base images.
+	// Repositories without tag or digest, e.g. gcr.io/distroless/base, allow all their images.
+	AllowedBaseImages []string `json:"allowedBaseImages,omitempty"`
+
+	// ProvenanceRequirements rejects images without SLSA build provenance meeting the requirements.
+	ProvenanceRequirements ProvenanceRequirements `json:"provenanceRequirements,omitempty"`
+
+	// LicenseRequirements restricts the licenses of the packages listed in the SBOMs of the image.
+	LicenseRequirements LicenseRequirements `json:"licenseRequirements,omitempty"`
+
+	// RegoRequirements evaluates custom rules written in Rego against the metadata of images.
+	RegoRequirements RegoRequirements `json:"regoRequirements,omitempty"`
+
+	// CustomRules are CEL expressions which must hold for images, see CustomRule.
+	CustomRules []CustomRule `json:"customRules,omitempty"`
+
+	// SkipMetadataKinds lists metadata kinds (VULNERABILITY, BUILD, OCCURRENCE_V1)
+	// which are never fetched when validating against this policy.
+	SkipMetadataKinds []string `json:"skipMetadataKinds,omitempty"`
+
+	// RemediationAction is taken by the cron job on running pods out of policy, besides labeling them:
+	// "NONE" (default), "QUARANTINE" to add a quarantine label, "SCALE_TO_ZERO" to scale
+	// the owning Deployment down, or "EVICT" to evict the pod.
+	RemediationAction string `json:"remediationAction,omitempty"`
+
+	// PodSelector limits the policy to pods matching the selector.
+	// The policy applies to all pods of its namespace if it is not set.
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+
+	// ViolationStrategies is the ordered chain of strategies handling violations of the policy,
+	// overriding the violationStrategies of the KritisConfig: "log", "event", "annotate",
+	// "remediate", "notify" and "deny". Images are only rejected if the chain has "deny".
+	ViolationStrategies []string `json:"violationStrategies,omitempty"`
 }
 
 // PackageVulnerabilityRequirements is the requirements for package vulnz for an ImageSecurityPolicy
@@ -46,8 +173,103 @@ type PackageVulnerabilityRequirements struct {
 	// CVE's with fixes.
 	MaximumSeverity string `json:"maximumSeverity"`
 	// CVE's without fixes.
-	MaximumFixUnavailableSeverity string   `json:"maximumFixNotAvailableSeverity"`
+	MaximumFixUnavailableSeverity string   `json:"maximumFixUnavailableSeverity"`
 	WhitelistCVEs                 []string `json:"whitelistCVEs"`
+	// CVE's with an unknown severity are evaluated as the given severity, e.g. HIGH,
+	// or are always allowed (ALLOW) or blocked (BLOCK, the default). Values are case-insensitive.
+	TreatUnknownSeverityAs string `json:"treatUnknownSeverityAs,omitempty"`
+	// WhitelistPackages exempts the vulnerabilities of packages, e.g. of a base image until it is updated.
+	WhitelistPackages []PackageWhitelist `json:"whitelistPackages,omitempty"`
+	// MaximumCounts caps the number of CVE's per severity, e.g. HIGH: 5, besides the severity ceilings.
+	MaximumCounts map[string]int `json:"maximumCounts,omitempty"`
+	// MaximumFixAvailableDays tolerates CVE's exceeding MaximumSeverity whose fix has been available
+	// for at most this many days, giving time to rebuild images. CVE's whose fix date is unknown are not tolerated.
+	MaximumFixAvailableDays int `json:"maximumFixAvailableDays,omitempty"`
+}
+
+// PackageWhitelist exempts the vulnerabilities of a package from an ImageSecurityPolicy
+type PackageWhitelist struct {
+	// Package is the name of the package.
+	Package string `json:"package"`
+	// CVEs are the exempted vulnerabilities of the package, all of them if empty.
+	CVEs []string `json:"cves,omitempty"`
+	// MaxVersion limits the exemption to package versions up to and including it.
+	// Vulnerabilities of packages whose version is unknown are not exempted then.
+	MaxVersion string `json:"maxVersion,omitempty"`
+}
+
+// PublisherIdentity is the identity of the Fulcio certificate of cosign signatures, or
+// the distinguished names of the certificate of Notary Project signatures, where *
+// matches any characters in both fields.
+type PublisherIdentity struct {
+	// Issuer is the OIDC issuer of the identity, e.g. https://token.actions.githubusercontent.com.
+	Issuer string `json:"issuer"`
+	// Subject is the email or URI of the identity, e.g. https://github.com/my-vendor/*.
+	Subject string `json:"subject"`
+}
+
+// ProvenanceRequirements is the requirements for the build provenance of images for an ImageSecurityPolicy
+type ProvenanceRequirements struct {
+	// Builders are the builders trusted to build images, all builders if empty.
+	Builders []TrustedBuilder `json:"builders,omitempty"`
+	// SourceURIs are patterns of the source repositories images must be built from,
+	// where * matches any characters, e.g. https://github.com/my-org/*.
+	SourceURIs []string `json:"sourceURIs,omitempty"`
+	// SLSALevel is the minimum SLSA build level of the builder of images.
+	SLSALevel int `json:"slsaLevel,omitempty"`
+	// PublicKeys are PEM encoded public keys verifying provenance attestations,
+	// signed with cosign or recorded as DSSE attestation occurrences.
+	PublicKeys []string `json:"publicKeys,omitempty"`
+}
+
+// TrustedBuilder is a builder trusted to generate the provenance of images
+type TrustedBuilder struct {
+	// ID is the builder ID of the provenance, * matches any characters,
+	// e.g. https://cloudbuild.googleapis.com/GoogleHostedWorker*.
+	ID string `json:"id"`
+	// SLSALevel is the SLSA build level met by the builder, 1 if not set.
+	SLSALevel int `json:"slsaLevel,omitempty"`
+}
+
+// LicenseRequirements is the requirements for package licenses for an ImageSecurityPolicy
+type LicenseRequirements struct {
+	// DeniedLicenses lists SPDX license IDs which are not allowed, e.g. GPL-3.0-only.
+	// A trailing * matches all IDs with the prefix, e.g. GPL-3.0*.
+	DeniedLicenses []string `json:"deniedLicenses,omitempty"`
+	// AllowedLicenses lists the only SPDX license IDs allowed, if set.
+	// Packages without a known license are then not allowed either.
+	AllowedLicenses []string `json:"allowedLicenses,omitempty"`
+}
+
+// RegoRequirements is the custom Rego rules of an ImageSecurityPolicy
+type RegoRequirements struct {
+	// ConfigMap is the name of a ConfigMap in the namespace of the policy, whose
+	// keys ending in .rego are Rego modules.
+	ConfigMap string `json:"configMap,omitempty"`
+	// Query evaluates to the violations of the image, "data.kritis.deny" if not set.
+	// Violations are messages, or objects with a "msg" and an optional "type".
+	Query string `json:"query,omitempty"`
+}
+
+// CustomRule is a CEL expression of an ImageSecurityPolicy, over the variables image,
+// vulnerabilities, builds and attestations. Images for which it is false violate the policy.
+type CustomRule struct {
+	// Name identifies the rule in violations.
+	Name string `json:"name"`
+	// Expression is the CEL expression, e.g. vulnerabilities.all(v, v.severity != "CRITICAL").
+	Expression string `json:"expression"`
+	// Message is the reason of the violations of the rule, if set.
+	Message string `json:"message,omitempty"`
+}
+
+// ArkCISignatureRequirements is the requirements for ArkCI JWT signatures for an ImageSecurityPolicy
+type ArkCISignatureRequirements struct {
+	// Algorithm is the expected JWT signing algorithm of the KMS key: RS256 (default), ES256 or PS256.
+	Algorithm string `json:"algorithm,omitempty"`
+	// RequiredClaims maps JWT claim names (e.g. repository, branch, builder) to the values they must have.
+	RequiredClaims map[string]string `json:"requiredClaims,omitempty"`
+	// MaxTokenAge is the maximum age of the JWT based on its "iat" claim, as Duration e.g. "24h".
+	MaxTokenAge string `json:"maxTokenAge,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -0,0 +1,65 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ViolationRecord holds the violations found by a run of the cron job in its namespace.
+// ViolationRecords are written by kritis and deleted once older than their retention.
+type ViolationRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ViolationRecordSpec `json:"spec"`
+}
+
+// ViolationRecordSpec is the spec for a ViolationRecord resource
+type ViolationRecordSpec struct {
+	// ReviewTime is when the pods of the namespace were reviewed.
+	ReviewTime metav1.Time `json:"reviewTime"`
+	// Pods and Images are the number of pods and images reviewed.
+	Pods   int `json:"pods"`
+	Images int `json:"images"`
+	// ViolatingPods is the number of pods with an image violating a policy.
+	ViolatingPods int `json:"violatingPods"`
+	// Violations counts the violations found by type, e.g. SeverityViolation.
+	Violations map[string]int `json:"violations,omitempty"`
+	// ViolatingImages are the images violating a policy.
+	ViolatingImages []ViolatingImageRecord `json:"violatingImages,omitempty"`
+}
+
+// ViolatingImageRecord is the number of violations found for an image.
+type ViolatingImageRecord struct {
+	Image      string `json:"image"`
+	Violations int    `json:"violations"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ViolationRecordList is a list of ViolationRecord resources
+type ViolationRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ViolationRecord `json:"items"`
+}
@@ -21,15 +21,39 @@ limitations under the License.
 package v1beta1
 
 import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArkCISignatureRequirements) DeepCopyInto(out *ArkCISignatureRequirements) {
+	*out = *in
+	if in.RequiredClaims != nil {
+		in, out := &in.RequiredClaims, &out.RequiredClaims
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArkCISignatureRequirements.
+func (in *ArkCISignatureRequirements) DeepCopy() *ArkCISignatureRequirements {
+	if in == nil {
+		return nil
+	}
+	out := new(ArkCISignatureRequirements)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AttestationAuthority) DeepCopyInto(out *AttestationAuthority) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	return
 }
 
@@ -87,6 +111,23 @@ func (in *AttestationAuthorityList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AttestationAuthoritySpec) DeepCopyInto(out *AttestationAuthoritySpec) {
 	*out = *in
+	if in.VaultKey != nil {
+		in, out := &in.VaultKey, &out.VaultKey
+		*out = new(VaultKeySpec)
+		**out = **in
+	}
+	if in.SecretManagerKey != nil {
+		in, out := &in.SecretManagerKey, &out.SecretManagerKey
+		*out = new(SecretManagerKeySpec)
+		**out = **in
+	}
+	if in.PreviousKeys != nil {
+		in, out := &in.PreviousKeys, &out.PreviousKeys
+		*out = make([]PreviousKey, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -101,26 +142,26 @@ func (in *AttestationAuthoritySpec) DeepCopy() *AttestationAuthoritySpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BuildPolicy) DeepCopyInto(out *BuildPolicy) {
+func (in *Attestor) DeepCopyInto(out *Attestor) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildPolicy.
-func (in *BuildPolicy) DeepCopy() *BuildPolicy {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Attestor.
+func (in *Attestor) DeepCopy() *Attestor {
 	if in == nil {
 		return nil
 	}
-	out := new(BuildPolicy)
+	out := new(Attestor)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *BuildPolicy) DeepCopyObject() runtime.Object {
+func (in *Attestor) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -128,13 +169,13 @@ func (in *BuildPolicy) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BuildPolicyList) DeepCopyInto(out *BuildPolicyList) {
+func (in *AttestorList) DeepCopyInto(out *AttestorList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	out.ListMeta = in.ListMeta
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]BuildPolicy, len(*in))
+		*out = make([]Attestor, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -142,18 +183,18 @@ func (in *BuildPolicyList) DeepCopyInto(out *BuildPolicyList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildPolicyList.
-func (in *BuildPolicyList) DeepCopy() *BuildPolicyList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AttestorList.
+func (in *AttestorList) DeepCopy() *AttestorList {
 	if in == nil {
 		return nil
 	}
-	out := new(BuildPolicyList)
+	out := new(AttestorList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *BuildPolicyList) DeepCopyObject() runtime.Object {
+func (in *AttestorList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -161,75 +202,84 @@ func (in *BuildPolicyList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BuildPolicySpec) DeepCopyInto(out *BuildPolicySpec) {
+func (in *AttestorPublicKey) DeepCopyInto(out *AttestorPublicKey) {
 	*out = *in
-	out.BuildRequirements = in.BuildRequirements
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildPolicySpec.
-func (in *BuildPolicySpec) DeepCopy() *BuildPolicySpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AttestorPublicKey.
+func (in *AttestorPublicKey) DeepCopy() *AttestorPublicKey {
 	if in == nil {
 		return nil
 	}
-	out := new(BuildPolicySpec)
+	out := new(AttestorPublicKey)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BuildRequirements) DeepCopyInto(out *BuildRequirements) {
+func (in *AttestorSpec) DeepCopyInto(out *AttestorSpec) {
 	*out = *in
+	if in.PublicKeys != nil {
+		in, out := &in.PublicKeys, &out.PublicKeys
+		*out = make([]AttestorPublicKey, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildRequirements.
-func (in *BuildRequirements) DeepCopy() *BuildRequirements {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AttestorSpec.
+func (in *AttestorSpec) DeepCopy() *AttestorSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(BuildRequirements)
+	out := new(AttestorSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *GrafeasConfigSpec) DeepCopyInto(out *GrafeasConfigSpec) {
+func (in *AzureConfigSpec) DeepCopyInto(out *AzureConfigSpec) {
 	*out = *in
+	if in.Subscriptions != nil {
+		in, out := &in.Subscriptions, &out.Subscriptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafeasConfigSpec.
-func (in *GrafeasConfigSpec) DeepCopy() *GrafeasConfigSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureConfigSpec.
+func (in *AzureConfigSpec) DeepCopy() *AzureConfigSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(GrafeasConfigSpec)
+	out := new(AzureConfigSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageSecurityPolicy) DeepCopyInto(out *ImageSecurityPolicy) {
+func (in *BuildPolicy) DeepCopyInto(out *BuildPolicy) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
+	out.Spec = in.Spec
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSecurityPolicy.
-func (in *ImageSecurityPolicy) DeepCopy() *ImageSecurityPolicy {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildPolicy.
+func (in *BuildPolicy) DeepCopy() *BuildPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageSecurityPolicy)
+	out := new(BuildPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ImageSecurityPolicy) DeepCopyObject() runtime.Object {
+func (in *BuildPolicy) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -237,13 +287,13 @@ func (in *ImageSecurityPolicy) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageSecurityPolicyList) DeepCopyInto(out *ImageSecurityPolicyList) {
+func (in *BuildPolicyList) DeepCopyInto(out *BuildPolicyList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	out.ListMeta = in.ListMeta
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]ImageSecurityPolicy, len(*in))
+		*out = make([]BuildPolicy, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -251,18 +301,18 @@ func (in *ImageSecurityPolicyList) DeepCopyInto(out *ImageSecurityPolicyList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSecurityPolicyList.
-func (in *ImageSecurityPolicyList) DeepCopy() *ImageSecurityPolicyList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildPolicyList.
+func (in *BuildPolicyList) DeepCopy() *BuildPolicyList {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageSecurityPolicyList)
+	out := new(BuildPolicyList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ImageSecurityPolicyList) DeepCopyObject() runtime.Object {
+func (in *BuildPolicyList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -270,141 +320,1588 @@ func (in *ImageSecurityPolicyList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageSecurityPolicySpec) DeepCopyInto(out *ImageSecurityPolicySpec) {
+func (in *BuildPolicySpec) DeepCopyInto(out *BuildPolicySpec) {
 	*out = *in
-	if in.ImageWhitelist != nil {
-		in, out := &in.ImageWhitelist, &out.ImageWhitelist
+	out.BuildRequirements = in.BuildRequirements
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildPolicySpec.
+func (in *BuildPolicySpec) DeepCopy() *BuildPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildRequirements) DeepCopyInto(out *BuildRequirements) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildRequirements.
+func (in *BuildRequirements) DeepCopy() *BuildRequirements {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildRequirements)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificatesSpec) DeepCopyInto(out *CertificatesSpec) {
+	*out = *in
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	in.PackageVulnerabilityRequirements.DeepCopyInto(&out.PackageVulnerabilityRequirements)
-	if in.AttestationAuthorityNames != nil {
-		in, out := &in.AttestationAuthorityNames, &out.AttestationAuthorityNames
+	if in.ValidatingWebhooks != nil {
+		in, out := &in.ValidatingWebhooks, &out.ValidatingWebhooks
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.BuiltProjectIDs != nil {
-		in, out := &in.BuiltProjectIDs, &out.BuiltProjectIDs
+	if in.MutatingWebhooks != nil {
+		in, out := &in.MutatingWebhooks, &out.MutatingWebhooks
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.RequireAttestationsBy != nil {
-		in, out := &in.RequireAttestationsBy, &out.RequireAttestationsBy
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificatesSpec.
+func (in *CertificatesSpec) DeepCopy() *CertificatesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificatesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComplianceReportSinkSpec) DeepCopyInto(out *ComplianceReportSinkSpec) {
+	*out = *in
+	if in.To != nil {
+		in, out := &in.To, &out.To
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSecurityPolicySpec.
-func (in *ImageSecurityPolicySpec) DeepCopy() *ImageSecurityPolicySpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComplianceReportSinkSpec.
+func (in *ComplianceReportSinkSpec) DeepCopy() *ComplianceReportSinkSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageSecurityPolicySpec)
+	out := new(ComplianceReportSinkSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KritisConfig) DeepCopyInto(out *KritisConfig) {
+func (in *ComplianceReportSpec) DeepCopyInto(out *ComplianceReportSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
+	if in.Sinks != nil {
+		in, out := &in.Sinks, &out.Sinks
+		*out = make([]ComplianceReportSinkSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KritisConfig.
-func (in *KritisConfig) DeepCopy() *KritisConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComplianceReportSpec.
+func (in *ComplianceReportSpec) DeepCopy() *ComplianceReportSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(KritisConfig)
+	out := new(ComplianceReportSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *KritisConfig) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerAnalysisConfigSpec) DeepCopyInto(out *ContainerAnalysisConfigSpec) {
+	*out = *in
+	out.Retry = in.Retry
+	if in.Projects != nil {
+		in, out := &in.Projects, &out.Projects
+		*out = make([]ProjectMappingSpec, len(*in))
+		copy(*out, *in)
 	}
-	return nil
+	out.Connection = in.Connection
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerAnalysisConfigSpec.
+func (in *ContainerAnalysisConfigSpec) DeepCopy() *ContainerAnalysisConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerAnalysisConfigSpec)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KritisConfigList) DeepCopyInto(out *KritisConfigList) {
+func (in *CustomRule) DeepCopyInto(out *CustomRule) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	out.ListMeta = in.ListMeta
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]KritisConfig, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KritisConfigList.
-func (in *KritisConfigList) DeepCopy() *KritisConfigList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomRule.
+func (in *CustomRule) DeepCopy() *CustomRule {
 	if in == nil {
 		return nil
 	}
-	out := new(KritisConfigList)
+	out := new(CustomRule)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *KritisConfigList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DecisionLogSinkSpec) DeepCopyInto(out *DecisionLogSinkSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecisionLogSinkSpec.
+func (in *DecisionLogSinkSpec) DeepCopy() *DecisionLogSinkSpec {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(DecisionLogSinkSpec)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KritisConfigSpec) DeepCopyInto(out *KritisConfigSpec) {
+func (in *ExemptionSpec) DeepCopyInto(out *ExemptionSpec) {
 	*out = *in
-	out.Grafeas = in.Grafeas
-	if in.ImageWhitelist != nil {
-		in, out := &in.ImageWhitelist, &out.ImageWhitelist
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServiceAccounts != nil {
+		in, out := &in.ServiceAccounts, &out.ServiceAccounts
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KritisConfigSpec.
-func (in *KritisConfigSpec) DeepCopy() *KritisConfigSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExemptionSpec.
+func (in *ExemptionSpec) DeepCopy() *ExemptionSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(KritisConfigSpec)
+	out := new(ExemptionSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PackageVulnerabilityRequirements) DeepCopyInto(out *PackageVulnerabilityRequirements) {
+func (in *GCPClientCredentialsSpec) DeepCopyInto(out *GCPClientCredentialsSpec) {
 	*out = *in
-	if in.WhitelistCVEs != nil {
-		in, out := &in.WhitelistCVEs, &out.WhitelistCVEs
+	if in.Delegates != nil {
+		in, out := &in.Delegates, &out.Delegates
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageVulnerabilityRequirements.
-func (in *PackageVulnerabilityRequirements) DeepCopy() *PackageVulnerabilityRequirements {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCPClientCredentialsSpec.
+func (in *GCPClientCredentialsSpec) DeepCopy() *GCPClientCredentialsSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PackageVulnerabilityRequirements)
+	out := new(GCPClientCredentialsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCPCredentialsSpec) DeepCopyInto(out *GCPCredentialsSpec) {
+	*out = *in
+	in.GCPClientCredentialsSpec.DeepCopyInto(&out.GCPClientCredentialsSpec)
+	if in.ContainerAnalysis != nil {
+		in, out := &in.ContainerAnalysis, &out.ContainerAnalysis
+		*out = new(GCPClientCredentialsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BinaryAuthorization != nil {
+		in, out := &in.BinaryAuthorization, &out.BinaryAuthorization
+		*out = new(GCPClientCredentialsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KMS != nil {
+		in, out := &in.KMS, &out.KMS
+		*out = new(GCPClientCredentialsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretManager != nil {
+		in, out := &in.SecretManager, &out.SecretManager
+		*out = new(GCPClientCredentialsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(GCPClientCredentialsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BigQuery != nil {
+		in, out := &in.BigQuery, &out.BigQuery
+		*out = new(GCPClientCredentialsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCPCredentialsSpec.
+func (in *GCPCredentialsSpec) DeepCopy() *GCPCredentialsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GCPCredentialsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRPCConnectionSpec) DeepCopyInto(out *GRPCConnectionSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCConnectionSpec.
+func (in *GRPCConnectionSpec) DeepCopy() *GRPCConnectionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GRPCConnectionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GenericAttestationPolicy) DeepCopyInto(out *GenericAttestationPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GenericAttestationPolicy.
+func (in *GenericAttestationPolicy) DeepCopy() *GenericAttestationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(GenericAttestationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GenericAttestationPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GenericAttestationPolicyList) DeepCopyInto(out *GenericAttestationPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GenericAttestationPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GenericAttestationPolicyList.
+func (in *GenericAttestationPolicyList) DeepCopy() *GenericAttestationPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(GenericAttestationPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GenericAttestationPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GenericAttestationPolicySpec) DeepCopyInto(out *GenericAttestationPolicySpec) {
+	*out = *in
+	if in.ImageWhitelist != nil {
+		in, out := &in.ImageWhitelist, &out.ImageWhitelist
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AttestationAuthorityNames != nil {
+		in, out := &in.AttestationAuthorityNames, &out.AttestationAuthorityNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AttestationAuthoritySelector != nil {
+		in, out := &in.AttestationAuthoritySelector, &out.AttestationAuthoritySelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RequireAttestationsBy != nil {
+		in, out := &in.RequireAttestationsBy, &out.RequireAttestationsBy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GenericAttestationPolicySpec.
+func (in *GenericAttestationPolicySpec) DeepCopy() *GenericAttestationPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GenericAttestationPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafeasConfigSpec) DeepCopyInto(out *GrafeasConfigSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafeasConfigSpec.
+func (in *GrafeasConfigSpec) DeepCopy() *GrafeasConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafeasConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HarborRegistrySpec) DeepCopyInto(out *HarborRegistrySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborRegistrySpec.
+func (in *HarborRegistrySpec) DeepCopy() *HarborRegistrySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HarborRegistrySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthSpec) DeepCopyInto(out *HealthSpec) {
+	*out = *in
+	if in.Optional != nil {
+		in, out := &in.Optional, &out.Optional
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthSpec.
+func (in *HealthSpec) DeepCopy() *HealthSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageReview) DeepCopyInto(out *ImageReview) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageReview.
+func (in *ImageReview) DeepCopy() *ImageReview {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageReview)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageReview) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageReviewList) DeepCopyInto(out *ImageReviewList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageReview, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageReviewList.
+func (in *ImageReviewList) DeepCopy() *ImageReviewList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageReviewList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageReviewList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageReviewSpec) DeepCopyInto(out *ImageReviewSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageReviewSpec.
+func (in *ImageReviewSpec) DeepCopy() *ImageReviewSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageReviewSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageReviewStatus) DeepCopyInto(out *ImageReviewStatus) {
+	*out = *in
+	if in.Violations != nil {
+		in, out := &in.Violations, &out.Violations
+		*out = make([]ImageReviewViolation, len(*in))
+		copy(*out, *in)
+	}
+	in.ReviewTime.DeepCopyInto(&out.ReviewTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageReviewStatus.
+func (in *ImageReviewStatus) DeepCopy() *ImageReviewStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageReviewStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageReviewViolation) DeepCopyInto(out *ImageReviewViolation) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageReviewViolation.
+func (in *ImageReviewViolation) DeepCopy() *ImageReviewViolation {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageReviewViolation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSecurityPolicy) DeepCopyInto(out *ImageSecurityPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSecurityPolicy.
+func (in *ImageSecurityPolicy) DeepCopy() *ImageSecurityPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSecurityPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageSecurityPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSecurityPolicyCondition) DeepCopyInto(out *ImageSecurityPolicyCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSecurityPolicyCondition.
+func (in *ImageSecurityPolicyCondition) DeepCopy() *ImageSecurityPolicyCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSecurityPolicyCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSecurityPolicyList) DeepCopyInto(out *ImageSecurityPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageSecurityPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSecurityPolicyList.
+func (in *ImageSecurityPolicyList) DeepCopy() *ImageSecurityPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSecurityPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageSecurityPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSecurityPolicySpec) DeepCopyInto(out *ImageSecurityPolicySpec) {
+	*out = *in
+	if in.ImageWhitelist != nil {
+		in, out := &in.ImageWhitelist, &out.ImageWhitelist
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.PackageVulnerabilityRequirements.DeepCopyInto(&out.PackageVulnerabilityRequirements)
+	if in.AttestationAuthorityNames != nil {
+		in, out := &in.AttestationAuthorityNames, &out.AttestationAuthorityNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AttestationAuthoritySelector != nil {
+		in, out := &in.AttestationAuthoritySelector, &out.AttestationAuthoritySelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PublisherWhitelist != nil {
+		in, out := &in.PublisherWhitelist, &out.PublisherWhitelist
+		*out = make([]PublisherIdentity, len(*in))
+		copy(*out, *in)
+	}
+	if in.BuiltProjectIDs != nil {
+		in, out := &in.BuiltProjectIDs, &out.BuiltProjectIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequireAttestationsBy != nil {
+		in, out := &in.RequireAttestationsBy, &out.RequireAttestationsBy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedRepositories != nil {
+		in, out := &in.AllowedRepositories, &out.AllowedRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.ArkCISignatureRequirements.DeepCopyInto(&out.ArkCISignatureRequirements)
+	if in.RequireSBOM != nil {
+		in, out := &in.RequireSBOM, &out.RequireSBOM
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedBaseImages != nil {
+		in, out := &in.AllowedBaseImages, &out.AllowedBaseImages
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.ProvenanceRequirements.DeepCopyInto(&out.ProvenanceRequirements)
+	in.LicenseRequirements.DeepCopyInto(&out.LicenseRequirements)
+	out.RegoRequirements = in.RegoRequirements
+	if in.CustomRules != nil {
+		in, out := &in.CustomRules, &out.CustomRules
+		*out = make([]CustomRule, len(*in))
+		copy(*out, *in)
+	}
+	if in.SkipMetadataKinds != nil {
+		in, out := &in.SkipMetadataKinds, &out.SkipMetadataKinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ViolationStrategies != nil {
+		in, out := &in.ViolationStrategies, &out.ViolationStrategies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSecurityPolicySpec.
+func (in *ImageSecurityPolicySpec) DeepCopy() *ImageSecurityPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSecurityPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSecurityPolicyStatus) DeepCopyInto(out *ImageSecurityPolicyStatus) {
+	*out = *in
+	if in.LastScanTime != nil {
+		in, out := &in.LastScanTime, &out.LastScanTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Violations != nil {
+		in, out := &in.Violations, &out.Violations
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]ImageSecurityPolicyCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSecurityPolicyStatus.
+func (in *ImageSecurityPolicyStatus) DeepCopy() *ImageSecurityPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSecurityPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyAllowlistSpec) DeepCopyInto(out *KeyAllowlistSpec) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyAllowlistSpec.
+func (in *KeyAllowlistSpec) DeepCopy() *KeyAllowlistSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyAllowlistSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KritisConfig) DeepCopyInto(out *KritisConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KritisConfig.
+func (in *KritisConfig) DeepCopy() *KritisConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KritisConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KritisConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KritisConfigList) DeepCopyInto(out *KritisConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KritisConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KritisConfigList.
+func (in *KritisConfigList) DeepCopy() *KritisConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(KritisConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KritisConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KritisConfigSpec) DeepCopyInto(out *KritisConfigSpec) {
+	*out = *in
+	out.VulnerabilityBundle = in.VulnerabilityBundle
+	out.LeaderElection = in.LeaderElection
+	in.ComplianceReport.DeepCopyInto(&out.ComplianceReport)
+	out.ViolationRecords = in.ViolationRecords
+	out.Grafeas = in.Grafeas
+	in.ContainerAnalysis.DeepCopyInto(&out.ContainerAnalysis)
+	in.GCPCredentials.DeepCopyInto(&out.GCPCredentials)
+	in.Azure.DeepCopyInto(&out.Azure)
+	in.RegistryCredentials.DeepCopyInto(&out.RegistryCredentials)
+	in.Sigstore.DeepCopyInto(&out.Sigstore)
+	in.Notation.DeepCopyInto(&out.Notation)
+	out.OSV = in.OSV
+	if in.Harbor != nil {
+		in, out := &in.Harbor, &out.Harbor
+		*out = make([]HarborRegistrySpec, len(*in))
+		copy(*out, *in)
+	}
+	in.TLS.DeepCopyInto(&out.TLS)
+	in.Health.DeepCopyInto(&out.Health)
+	out.MetadataRateLimit = in.MetadataRateLimit
+	in.MetadataFailurePolicy.DeepCopyInto(&out.MetadataFailurePolicy)
+	in.WebhookFailurePolicies.DeepCopyInto(&out.WebhookFailurePolicies)
+	out.Tracing = in.Tracing
+	if in.Promotions != nil {
+		in, out := &in.Promotions, &out.Promotions
+		*out = make([]PromotionSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Notifications != nil {
+		in, out := &in.Notifications, &out.Notifications
+		*out = make([]NotificationSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.ViolationStrategies != nil {
+		in, out := &in.ViolationStrategies, &out.ViolationStrategies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImageWhitelist != nil {
+		in, out := &in.ImageWhitelist, &out.ImageWhitelist
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SkipMetadataKinds != nil {
+		in, out := &in.SkipMetadataKinds, &out.SkipMetadataKinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DecisionLog != nil {
+		in, out := &in.DecisionLog, &out.DecisionLog
+		*out = make([]DecisionLogSinkSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exemptions != nil {
+		in, out := &in.Exemptions, &out.Exemptions
+		*out = make([]ExemptionSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Vault.DeepCopyInto(&out.Vault)
+	if in.KMSKeys != nil {
+		in, out := &in.KMSKeys, &out.KMSKeys
+		*out = make([]KeyAllowlistSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SecretManagerKeys != nil {
+		in, out := &in.SecretManagerKeys, &out.SecretManagerKeys
+		*out = make([]KeyAllowlistSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KritisConfigSpec.
+func (in *KritisConfigSpec) DeepCopy() *KritisConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KritisConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaderElectionSpec) DeepCopyInto(out *LeaderElectionSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaderElectionSpec.
+func (in *LeaderElectionSpec) DeepCopy() *LeaderElectionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaderElectionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LicenseRequirements) DeepCopyInto(out *LicenseRequirements) {
+	*out = *in
+	if in.DeniedLicenses != nil {
+		in, out := &in.DeniedLicenses, &out.DeniedLicenses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedLicenses != nil {
+		in, out := &in.AllowedLicenses, &out.AllowedLicenses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LicenseRequirements.
+func (in *LicenseRequirements) DeepCopy() *LicenseRequirements {
+	if in == nil {
+		return nil
+	}
+	out := new(LicenseRequirements)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetadataFailurePolicySpec) DeepCopyInto(out *MetadataFailurePolicySpec) {
+	*out = *in
+	if in.Backends != nil {
+		in, out := &in.Backends, &out.Backends
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetadataFailurePolicySpec.
+func (in *MetadataFailurePolicySpec) DeepCopy() *MetadataFailurePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MetadataFailurePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceFailurePolicySpec) DeepCopyInto(out *NamespaceFailurePolicySpec) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceFailurePolicySpec.
+func (in *NamespaceFailurePolicySpec) DeepCopy() *NamespaceFailurePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceFailurePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotationSpec) DeepCopyInto(out *NotationSpec) {
+	*out = *in
+	if in.TrustedCertificates != nil {
+		in, out := &in.TrustedCertificates, &out.TrustedCertificates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotationSpec.
+func (in *NotationSpec) DeepCopy() *NotationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NotationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationSpec) DeepCopyInto(out *NotificationSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationSpec.
+func (in *NotificationSpec) DeepCopy() *NotificationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OSVConfigSpec) DeepCopyInto(out *OSVConfigSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OSVConfigSpec.
+func (in *OSVConfigSpec) DeepCopy() *OSVConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OSVConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageVulnerabilityRequirements) DeepCopyInto(out *PackageVulnerabilityRequirements) {
+	*out = *in
+	if in.WhitelistCVEs != nil {
+		in, out := &in.WhitelistCVEs, &out.WhitelistCVEs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.WhitelistPackages != nil {
+		in, out := &in.WhitelistPackages, &out.WhitelistPackages
+		*out = make([]PackageWhitelist, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MaximumCounts != nil {
+		in, out := &in.MaximumCounts, &out.MaximumCounts
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageVulnerabilityRequirements.
+func (in *PackageVulnerabilityRequirements) DeepCopy() *PackageVulnerabilityRequirements {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageVulnerabilityRequirements)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageWhitelist) DeepCopyInto(out *PackageWhitelist) {
+	*out = *in
+	if in.CVEs != nil {
+		in, out := &in.CVEs, &out.CVEs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageWhitelist.
+func (in *PackageWhitelist) DeepCopy() *PackageWhitelist {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageWhitelist)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreviousKey) DeepCopyInto(out *PreviousKey) {
+	*out = *in
+	in.RetireTime.DeepCopyInto(&out.RetireTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreviousKey.
+func (in *PreviousKey) DeepCopy() *PreviousKey {
+	if in == nil {
+		return nil
+	}
+	out := new(PreviousKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectMappingSpec) DeepCopyInto(out *ProjectMappingSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectMappingSpec.
+func (in *ProjectMappingSpec) DeepCopy() *ProjectMappingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectMappingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PromotionSpec) DeepCopyInto(out *PromotionSpec) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PromotionSpec.
+func (in *PromotionSpec) DeepCopy() *PromotionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PromotionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvenanceRequirements) DeepCopyInto(out *ProvenanceRequirements) {
+	*out = *in
+	if in.Builders != nil {
+		in, out := &in.Builders, &out.Builders
+		*out = make([]TrustedBuilder, len(*in))
+		copy(*out, *in)
+	}
+	if in.SourceURIs != nil {
+		in, out := &in.SourceURIs, &out.SourceURIs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PublicKeys != nil {
+		in, out := &in.PublicKeys, &out.PublicKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvenanceRequirements.
+func (in *ProvenanceRequirements) DeepCopy() *ProvenanceRequirements {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvenanceRequirements)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PublisherIdentity) DeepCopyInto(out *PublisherIdentity) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublisherIdentity.
+func (in *PublisherIdentity) DeepCopy() *PublisherIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(PublisherIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimitSpec) DeepCopyInto(out *RateLimitSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitSpec.
+func (in *RateLimitSpec) DeepCopy() *RateLimitSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimitSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryCredentialsSpec) DeepCopyInto(out *RegistryCredentialsSpec) {
+	*out = *in
+	if in.PullSecrets != nil {
+		in, out := &in.PullSecrets, &out.PullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryCredentialsSpec.
+func (in *RegistryCredentialsSpec) DeepCopy() *RegistryCredentialsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryCredentialsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegoRequirements) DeepCopyInto(out *RegoRequirements) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegoRequirements.
+func (in *RegoRequirements) DeepCopy() *RegoRequirements {
+	if in == nil {
+		return nil
+	}
+	out := new(RegoRequirements)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetrySpec) DeepCopyInto(out *RetrySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetrySpec.
+func (in *RetrySpec) DeepCopy() *RetrySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RetrySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretManagerKeySpec) DeepCopyInto(out *SecretManagerKeySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretManagerKeySpec.
+func (in *SecretManagerKeySpec) DeepCopy() *SecretManagerKeySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretManagerKeySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SigstoreSpec) DeepCopyInto(out *SigstoreSpec) {
+	*out = *in
+	if in.FulcioRoots != nil {
+		in, out := &in.FulcioRoots, &out.FulcioRoots
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RekorPublicKeys != nil {
+		in, out := &in.RekorPublicKeys, &out.RekorPublicKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SigstoreSpec.
+func (in *SigstoreSpec) DeepCopy() *SigstoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SigstoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSConfigSpec) DeepCopyInto(out *TLSConfigSpec) {
+	*out = *in
+	if in.CipherSuites != nil {
+		in, out := &in.CipherSuites, &out.CipherSuites
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Certificates.DeepCopyInto(&out.Certificates)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSConfigSpec.
+func (in *TLSConfigSpec) DeepCopy() *TLSConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracingSpec) DeepCopyInto(out *TracingSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracingSpec.
+func (in *TracingSpec) DeepCopy() *TracingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TracingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrustedBuilder) DeepCopyInto(out *TrustedBuilder) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrustedBuilder.
+func (in *TrustedBuilder) DeepCopy() *TrustedBuilder {
+	if in == nil {
+		return nil
+	}
+	out := new(TrustedBuilder)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultConfigSpec) DeepCopyInto(out *VaultConfigSpec) {
+	*out = *in
+	if in.AllowedKeys != nil {
+		in, out := &in.AllowedKeys, &out.AllowedKeys
+		*out = make([]KeyAllowlistSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultConfigSpec.
+func (in *VaultConfigSpec) DeepCopy() *VaultConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultKeySpec) DeepCopyInto(out *VaultKeySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultKeySpec.
+func (in *VaultKeySpec) DeepCopy() *VaultKeySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultKeySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ViolatingImageRecord) DeepCopyInto(out *ViolatingImageRecord) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ViolatingImageRecord.
+func (in *ViolatingImageRecord) DeepCopy() *ViolatingImageRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(ViolatingImageRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ViolationRecord) DeepCopyInto(out *ViolationRecord) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ViolationRecord.
+func (in *ViolationRecord) DeepCopy() *ViolationRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(ViolationRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ViolationRecord) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ViolationRecordList) DeepCopyInto(out *ViolationRecordList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ViolationRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ViolationRecordList.
+func (in *ViolationRecordList) DeepCopy() *ViolationRecordList {
+	if in == nil {
+		return nil
+	}
+	out := new(ViolationRecordList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ViolationRecordList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ViolationRecordSpec) DeepCopyInto(out *ViolationRecordSpec) {
+	*out = *in
+	in.ReviewTime.DeepCopyInto(&out.ReviewTime)
+	if in.Violations != nil {
+		in, out := &in.Violations, &out.Violations
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ViolatingImages != nil {
+		in, out := &in.ViolatingImages, &out.ViolatingImages
+		*out = make([]ViolatingImageRecord, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ViolationRecordSpec.
+func (in *ViolationRecordSpec) DeepCopy() *ViolationRecordSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ViolationRecordSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ViolationRecordsSpec) DeepCopyInto(out *ViolationRecordsSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ViolationRecordsSpec.
+func (in *ViolationRecordsSpec) DeepCopy() *ViolationRecordsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ViolationRecordsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VulnerabilityBundleSpec) DeepCopyInto(out *VulnerabilityBundleSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VulnerabilityBundleSpec.
+func (in *VulnerabilityBundleSpec) DeepCopy() *VulnerabilityBundleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VulnerabilityBundleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookFailurePoliciesSpec) DeepCopyInto(out *WebhookFailurePoliciesSpec) {
+	*out = *in
+	if in.ValidatingWebhooks != nil {
+		in, out := &in.ValidatingWebhooks, &out.ValidatingWebhooks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]NamespaceFailurePolicySpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookFailurePoliciesSpec.
+func (in *WebhookFailurePoliciesSpec) DeepCopy() *WebhookFailurePoliciesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookFailurePoliciesSpec)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -0,0 +1,71 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package attestation
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// VerifyPKIXSignature verifies sig over payload with the PEM encoded PKIX public key.
+// ECDSA signatures are ASN.1 encoded and use the digest of the size of the curve,
+// RSA signatures may be PKCS #1 v1.5 or PSS with a SHA-256 or SHA-512 digest.
+func VerifyPKIXSignature(publicKey string, payload []byte, sig []byte) error {
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		return fmt.Errorf("invalid PEM public key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return errors.Wrap(err, "invalid PKIX public key")
+	}
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		hash := crypto.SHA256
+		switch pub.Curve {
+		case elliptic.P384():
+			hash = crypto.SHA384
+		case elliptic.P521():
+			hash = crypto.SHA512
+		}
+		h := hash.New()
+		h.Write(payload)
+		if !ecdsa.VerifyASN1(pub, h.Sum(nil), sig) {
+			return fmt.Errorf("invalid ECDSA signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		for _, hash := range []crypto.Hash{crypto.SHA256, crypto.SHA512} {
+			h := hash.New()
+			h.Write(payload)
+			digest := h.Sum(nil)
+			if rsa.VerifyPKCS1v15(pub, hash, digest, sig) == nil || rsa.VerifyPSS(pub, hash, digest, sig, nil) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid RSA signature")
+	default:
+		return fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
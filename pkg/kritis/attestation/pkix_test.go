@@ -0,0 +1,87 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package attestation
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func pemPublicKey(t *testing.T, pub crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestVerifyPKIXSignature(t *testing.T) {
+	payload := []byte("payload")
+	digest := sha256.Sum256(payload)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	pkcs1, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	pss, err := rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, digest[:], nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	digest384 := crypto.SHA384.New()
+	digest384.Write(payload)
+	ec, err := ecdsa.SignASN1(rand.Reader, ecKey, digest384.Sum(nil))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	rsaPub, ecPub := pemPublicKey(t, &rsaKey.PublicKey), pemPublicKey(t, &ecKey.PublicKey)
+	tests := []struct {
+		name    string
+		key     string
+		payload []byte
+		sig     []byte
+		shdErr  bool
+	}{
+		{"rsa pkcs1", rsaPub, payload, pkcs1, false},
+		{"rsa pss", rsaPub, payload, pss, false},
+		{"ecdsa p384", ecPub, payload, ec, false},
+		{"other payload", rsaPub, []byte("other"), pkcs1, true},
+		{"other key", ecPub, payload, pkcs1, true},
+		{"invalid key", "invalid", payload, pkcs1, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := VerifyPKIXSignature(test.key, test.payload, test.sig)
+			if (err != nil) != test.shdErr {
+				t.Errorf("expected error %t, got %v", test.shdErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package authz authenticates the callers of the Kritis APIs with their
+// Kubernetes bearer token, and authorizes them with the cluster RBAC.
+package authz
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Authorizer authenticates a token with a TokenReview, and checks its user
+// is allowed an access with a SubjectAccessReview.
+type Authorizer struct {
+	client kubernetes.Interface
+}
+
+// New returns an Authorizer sending the reviews with client.
+func New(client kubernetes.Interface) *Authorizer {
+	return &Authorizer{client: client}
+}
+
+// Error is returned for requests which are not authenticated or not allowed.
+type Error struct {
+	// Code is the HTTP status of the error.
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// StatusCode returns the HTTP status of an error returned by Authorize.
+func StatusCode(err error) int {
+	if e, ok := err.(*Error); ok {
+		return e.Code
+	}
+	return http.StatusInternalServerError
+}
+
+// Authorize returns the user of token if it is allowed the access described
+// by attrs, i.e. its ResourceAttributes or NonResourceAttributes.
+func (a *Authorizer) Authorize(token string, attrs authorizationv1.SubjectAccessReviewSpec) (authenticationv1.UserInfo, error) {
+	if token == "" {
+		return authenticationv1.UserInfo{}, &Error{Code: http.StatusUnauthorized, Message: "missing bearer token"}
+	}
+	tr, err := a.client.AuthenticationV1().TokenReviews().Create(&authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	})
+	if err != nil {
+		return authenticationv1.UserInfo{}, fmt.Errorf("failed to review token: %v", err)
+	}
+	if !tr.Status.Authenticated {
+		return authenticationv1.UserInfo{}, &Error{Code: http.StatusUnauthorized, Message: "invalid bearer token"}
+	}
+	user := tr.Status.User
+	attrs.User = user.Username
+	attrs.UID = user.UID
+	attrs.Groups = user.Groups
+	if len(user.Extra) > 0 {
+		attrs.Extra = map[string]authorizationv1.ExtraValue{}
+		for k, v := range user.Extra {
+			attrs.Extra[k] = authorizationv1.ExtraValue(v)
+		}
+	}
+	sar, err := a.client.AuthorizationV1().SubjectAccessReviews().Create(&authorizationv1.SubjectAccessReview{Spec: attrs})
+	if err != nil {
+		return user, fmt.Errorf("failed to review access: %v", err)
+	}
+	if !sar.Status.Allowed {
+		return user, &Error{Code: http.StatusForbidden, Message: fmt.Sprintf("user %q is not allowed to %s", user.Username, describe(attrs))}
+	}
+	return user, nil
+}
+
+func describe(attrs authorizationv1.SubjectAccessReviewSpec) string {
+	if r := attrs.NonResourceAttributes; r != nil {
+		return r.Verb + " " + r.Path
+	}
+	if r := attrs.ResourceAttributes; r != nil {
+		s := r.Verb + " " + r.Resource
+		if r.Group != "" {
+			s += "." + r.Group
+		}
+		if r.Namespace != "" {
+			s += " in namespace " + r.Namespace
+		}
+		return s
+	}
+	return "access the API"
+}
+
+// Token returns the bearer token of a request.
+func Token(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+}
+
+// Handler serves requests to h only if their user is allowed to access path
+// with the verb, e.g. "get".
+func (a *Authorizer) Handler(path, verb string, h http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		attrs := authorizationv1.SubjectAccessReviewSpec{
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{Path: path, Verb: verb},
+		}
+		if _, err := a.Authorize(Token(r), attrs); err != nil {
+			if StatusCode(err) == http.StatusInternalServerError {
+				glog.Errorf("failed to authorize request to %s: %v", path, err)
+			}
+			http.Error(w, err.Error(), StatusCode(err))
+			return
+		}
+		h.ServeHTTP(w, r)
+	}
+}
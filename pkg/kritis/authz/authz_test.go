@@ -0,0 +1,85 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authz
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func newAuthorizer() *Authorizer {
+	cs := fake.NewSimpleClientset()
+	cs.PrependReactor("create", "tokenreviews", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		tr := a.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		switch tr.Spec.Token {
+		case "admin":
+			tr.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: "admin"}}
+		case "dev":
+			tr.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: "dev"}}
+		}
+		return true, tr, nil
+	})
+	cs.PrependReactor("create", "subjectaccessreviews", func(a k8stesting.Action) (bool, runtime.Object, error) {
+		sar := a.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		sar.Status.Allowed = sar.Spec.User == "admin"
+		return true, sar, nil
+	})
+	return New(cs)
+}
+
+func TestHandler(t *testing.T) {
+	var tests = []struct {
+		name     string
+		header   string
+		expected int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"invalid token", "Bearer unknown", http.StatusUnauthorized},
+		{"forbidden", "Bearer dev", http.StatusForbidden},
+		{"allowed", "Bearer admin", http.StatusOK},
+	}
+	h := newAuthorizer().Handler("/attestations", "get", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/attestations", nil)
+			if test.header != "" {
+				r.Header.Set("Authorization", test.header)
+			}
+			w := httptest.NewRecorder()
+			h(w, r)
+			testutil.DeepEqual(t, test.expected, w.Code)
+		})
+	}
+}
+
+func TestAuthorizeResource(t *testing.T) {
+	attrs := authorizationv1.SubjectAccessReviewSpec{
+		ResourceAttributes: &authorizationv1.ResourceAttributes{Namespace: "qa", Verb: "get", Group: "kritis.grafeas.io", Resource: "imagesecuritypolicies"},
+	}
+	user, err := newAuthorizer().Authorize("dev", attrs)
+	testutil.CheckErrorAndDeepEqual(t, true, err, "dev", user.Username)
+	testutil.DeepEqual(t, `user "dev" is not allowed to get imagesecuritypolicies.kritis.grafeas.io in namespace qa`, err.Error())
+}
@@ -2,14 +2,20 @@ package binauthz
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/pkg/errors"
 	binaryauthorization "google.golang.org/api/binaryauthorization/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/faults"
+	"github.com/grafeas/kritis/pkg/kritis/gcpauth"
 )
 
 type Client interface {
 	// GetAttestor gets an Attestor for given name. (name=projects/{projectID}/attestors/{attestorName})
 	GetAttestor(ctx context.Context, name string) (*binaryauthorization.Attestor, error)
+	// Ping gets the policy of a project, to check that Binary Authorization can be reached.
+	Ping(ctx context.Context, project string) error
 }
 
 type client struct {
@@ -17,9 +23,12 @@ type client struct {
 }
 
 func New() (Client, error) {
-	service, err := binaryauthorization.NewService(
-		context.Background(),
-	)
+	ctx := context.Background()
+	opts, err := gcpauth.ClientOptions(ctx, gcpauth.BinaryAuthorization)
+	if err != nil {
+		return nil, err
+	}
+	service, err := binaryauthorization.NewService(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -29,6 +38,9 @@ func New() (Client, error) {
 }
 
 func (c *client) GetAttestor(ctx context.Context, name string) (*binaryauthorization.Attestor, error) {
+	if err := faults.Inject(ctx, faults.Binauthz); err != nil {
+		return nil, errors.Wrapf(err, "failed to get an attestor: %s", name)
+	}
 	attestorSvc := binaryauthorization.NewProjectsAttestorsService(c.service)
 	call := attestorSvc.Get(name).Context(ctx)
 	attestor, err := call.Do()
@@ -37,3 +49,11 @@ func (c *client) GetAttestor(ctx context.Context, name string) (*binaryauthoriza
 	}
 	return attestor, nil
 }
+
+func (c *client) Ping(ctx context.Context, project string) error {
+	name := fmt.Sprintf("projects/%s/policy", project)
+	if _, err := c.service.Projects.GetPolicy(name).Context(ctx).Do(); err != nil {
+		return errors.Wrapf(err, "failed to get the policy: %s", name)
+	}
+	return nil
+}
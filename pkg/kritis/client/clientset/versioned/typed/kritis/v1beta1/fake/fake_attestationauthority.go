@@ -34,9 +34,9 @@ type FakeAttestationAuthorities struct {
 	ns   string
 }
 
-var attestationauthoritiesResource = schema.GroupVersionResource{Group: "kritis", Version: "v1beta1", Resource: "attestationauthorities"}
+var attestationauthoritiesResource = schema.GroupVersionResource{Group: "kritis.grafeas.io", Version: "v1beta1", Resource: "attestationauthorities"}
 
-var attestationauthoritiesKind = schema.GroupVersionKind{Group: "kritis", Version: "v1beta1", Kind: "AttestationAuthority"}
+var attestationauthoritiesKind = schema.GroupVersionKind{Group: "kritis.grafeas.io", Version: "v1beta1", Kind: "AttestationAuthority"}
 
 // Get takes name of the attestationAuthority, and returns the corresponding attestationAuthority object, and an error if there is any.
 func (c *FakeAttestationAuthorities) Get(name string, options v1.GetOptions) (result *v1beta1.AttestationAuthority, err error) {
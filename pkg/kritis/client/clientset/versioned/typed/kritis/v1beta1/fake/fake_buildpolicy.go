@@ -34,9 +34,9 @@ type FakeBuildPolicies struct {
 	ns   string
 }
 
-var buildpoliciesResource = schema.GroupVersionResource{Group: "kritis", Version: "v1beta1", Resource: "buildpolicies"}
+var buildpoliciesResource = schema.GroupVersionResource{Group: "kritis.grafeas.io", Version: "v1beta1", Resource: "buildpolicies"}
 
-var buildpoliciesKind = schema.GroupVersionKind{Group: "kritis", Version: "v1beta1", Kind: "BuildPolicy"}
+var buildpoliciesKind = schema.GroupVersionKind{Group: "kritis.grafeas.io", Version: "v1beta1", Kind: "BuildPolicy"}
 
 // Get takes name of the buildPolicy, and returns the corresponding buildPolicy object, and an error if there is any.
 func (c *FakeBuildPolicies) Get(name string, options v1.GetOptions) (result *v1beta1.BuildPolicy, err error) {
@@ -34,9 +34,9 @@ type FakeImageSecurityPolicies struct {
 	ns   string
 }
 
-var imagesecuritypoliciesResource = schema.GroupVersionResource{Group: "kritis", Version: "v1beta1", Resource: "imagesecuritypolicies"}
+var imagesecuritypoliciesResource = schema.GroupVersionResource{Group: "kritis.grafeas.io", Version: "v1beta1", Resource: "imagesecuritypolicies"}
 
-var imagesecuritypoliciesKind = schema.GroupVersionKind{Group: "kritis", Version: "v1beta1", Kind: "ImageSecurityPolicy"}
+var imagesecuritypoliciesKind = schema.GroupVersionKind{Group: "kritis.grafeas.io", Version: "v1beta1", Kind: "ImageSecurityPolicy"}
 
 // Get takes name of the imageSecurityPolicy, and returns the corresponding imageSecurityPolicy object, and an error if there is any.
 func (c *FakeImageSecurityPolicies) Get(name string, options v1.GetOptions) (result *v1beta1.ImageSecurityPolicy, err error) {
@@ -100,6 +100,18 @@ func (c *FakeImageSecurityPolicies) Update(imageSecurityPolicy *v1beta1.ImageSec
 	return obj.(*v1beta1.ImageSecurityPolicy), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeImageSecurityPolicies) UpdateStatus(imageSecurityPolicy *v1beta1.ImageSecurityPolicy) (*v1beta1.ImageSecurityPolicy, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(imagesecuritypoliciesResource, "status", c.ns, imageSecurityPolicy), &v1beta1.ImageSecurityPolicy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.ImageSecurityPolicy), err
+}
+
 // Delete takes name of the imageSecurityPolicy and deletes it. Returns an error if one occurs.
 func (c *FakeImageSecurityPolicies) Delete(name string, options *v1.DeleteOptions) error {
 	_, err := c.Fake.
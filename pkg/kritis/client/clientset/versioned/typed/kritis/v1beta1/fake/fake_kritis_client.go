@@ -32,10 +32,22 @@ func (c *FakeKritisV1beta1) AttestationAuthorities(namespace string) v1beta1.Att
 	return &FakeAttestationAuthorities{c, namespace}
 }
 
+func (c *FakeKritisV1beta1) Attestors() v1beta1.AttestorInterface {
+	return &FakeAttestors{c}
+}
+
 func (c *FakeKritisV1beta1) BuildPolicies(namespace string) v1beta1.BuildPolicyInterface {
 	return &FakeBuildPolicies{c, namespace}
 }
 
+func (c *FakeKritisV1beta1) GenericAttestationPolicies(namespace string) v1beta1.GenericAttestationPolicyInterface {
+	return &FakeGenericAttestationPolicies{c, namespace}
+}
+
+func (c *FakeKritisV1beta1) ImageReviews(namespace string) v1beta1.ImageReviewInterface {
+	return &FakeImageReviews{c, namespace}
+}
+
 func (c *FakeKritisV1beta1) ImageSecurityPolicies(namespace string) v1beta1.ImageSecurityPolicyInterface {
 	return &FakeImageSecurityPolicies{c, namespace}
 }
@@ -44,6 +56,10 @@ func (c *FakeKritisV1beta1) KritisConfigs() v1beta1.KritisConfigInterface {
 	return &FakeKritisConfigs{c}
 }
 
+func (c *FakeKritisV1beta1) ViolationRecords(namespace string) v1beta1.ViolationRecordInterface {
+	return &FakeViolationRecords{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeKritisV1beta1) RESTClient() rest.Interface {
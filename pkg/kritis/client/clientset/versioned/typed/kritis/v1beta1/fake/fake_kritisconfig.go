@@ -33,9 +33,9 @@ type FakeKritisConfigs struct {
 	Fake *FakeKritisV1beta1
 }
 
-var kritisconfigsResource = schema.GroupVersionResource{Group: "kritis", Version: "v1beta1", Resource: "kritisconfigs"}
+var kritisconfigsResource = schema.GroupVersionResource{Group: "kritis.grafeas.io", Version: "v1beta1", Resource: "kritisconfigs"}
 
-var kritisconfigsKind = schema.GroupVersionKind{Group: "kritis", Version: "v1beta1", Kind: "KritisConfig"}
+var kritisconfigsKind = schema.GroupVersionKind{Group: "kritis.grafeas.io", Version: "v1beta1", Kind: "KritisConfig"}
 
 // Get takes name of the kritisConfig, and returns the corresponding kritisConfig object, and an error if there is any.
 func (c *FakeKritisConfigs) Get(name string, options v1.GetOptions) (result *v1beta1.KritisConfig, err error) {
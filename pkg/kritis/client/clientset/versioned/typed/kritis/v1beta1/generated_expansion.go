@@ -20,8 +20,16 @@ package v1beta1
 
 type AttestationAuthorityExpansion interface{}
 
+type AttestorExpansion interface{}
+
 type BuildPolicyExpansion interface{}
 
+type GenericAttestationPolicyExpansion interface{}
+
+type ImageReviewExpansion interface{}
+
 type ImageSecurityPolicyExpansion interface{}
 
 type KritisConfigExpansion interface{}
+
+type ViolationRecordExpansion interface{}
@@ -37,6 +37,7 @@ type ImageSecurityPoliciesGetter interface {
 type ImageSecurityPolicyInterface interface {
 	Create(*v1beta1.ImageSecurityPolicy) (*v1beta1.ImageSecurityPolicy, error)
 	Update(*v1beta1.ImageSecurityPolicy) (*v1beta1.ImageSecurityPolicy, error)
+	UpdateStatus(*v1beta1.ImageSecurityPolicy) (*v1beta1.ImageSecurityPolicy, error)
 	Delete(name string, options *v1.DeleteOptions) error
 	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
 	Get(name string, options v1.GetOptions) (*v1beta1.ImageSecurityPolicy, error)
@@ -120,6 +121,22 @@ func (c *imageSecurityPolicies) Update(imageSecurityPolicy *v1beta1.ImageSecurit
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *imageSecurityPolicies) UpdateStatus(imageSecurityPolicy *v1beta1.ImageSecurityPolicy) (result *v1beta1.ImageSecurityPolicy, err error) {
+	result = &v1beta1.ImageSecurityPolicy{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("imagesecuritypolicies").
+		Name(imageSecurityPolicy.Name).
+		SubResource("status").
+		Body(imageSecurityPolicy).
+		Do().
+		Into(result)
+	return
+}
+
 // Delete takes name of the imageSecurityPolicy and deletes it. Returns an error if one occurs.
 func (c *imageSecurityPolicies) Delete(name string, options *v1.DeleteOptions) error {
 	return c.client.Delete().
@@ -28,9 +28,13 @@ import (
 type KritisV1beta1Interface interface {
 	RESTClient() rest.Interface
 	AttestationAuthoritiesGetter
+	AttestorsGetter
 	BuildPoliciesGetter
+	GenericAttestationPoliciesGetter
+	ImageReviewsGetter
 	ImageSecurityPoliciesGetter
 	KritisConfigsGetter
+	ViolationRecordsGetter
 }
 
 // KritisV1beta1Client is used to interact with features provided by the kritis group.
@@ -42,10 +46,22 @@ func (c *KritisV1beta1Client) AttestationAuthorities(namespace string) Attestati
 	return newAttestationAuthorities(c, namespace)
 }
 
+func (c *KritisV1beta1Client) Attestors() AttestorInterface {
+	return newAttestors(c)
+}
+
 func (c *KritisV1beta1Client) BuildPolicies(namespace string) BuildPolicyInterface {
 	return newBuildPolicies(c, namespace)
 }
 
+func (c *KritisV1beta1Client) GenericAttestationPolicies(namespace string) GenericAttestationPolicyInterface {
+	return newGenericAttestationPolicies(c, namespace)
+}
+
+func (c *KritisV1beta1Client) ImageReviews(namespace string) ImageReviewInterface {
+	return newImageReviews(c, namespace)
+}
+
 func (c *KritisV1beta1Client) ImageSecurityPolicies(namespace string) ImageSecurityPolicyInterface {
 	return newImageSecurityPolicies(c, namespace)
 }
@@ -54,6 +70,10 @@ func (c *KritisV1beta1Client) KritisConfigs() KritisConfigInterface {
 	return newKritisConfigs(c)
 }
 
+func (c *KritisV1beta1Client) ViolationRecords(namespace string) ViolationRecordInterface {
+	return newViolationRecords(c, namespace)
+}
+
 // NewForConfig creates a new KritisV1beta1Client for the given config.
 func NewForConfig(c *rest.Config) (*KritisV1beta1Client, error) {
 	config := *c
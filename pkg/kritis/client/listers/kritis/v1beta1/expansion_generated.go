@@ -26,6 +26,10 @@ type AttestationAuthorityListerExpansion interface{}
 // AttestationAuthorityNamespaceLister.
 type AttestationAuthorityNamespaceListerExpansion interface{}
 
+// AttestorListerExpansion allows custom methods to be added to
+// AttestorLister.
+type AttestorListerExpansion interface{}
+
 // BuildPolicyListerExpansion allows custom methods to be added to
 // BuildPolicyLister.
 type BuildPolicyListerExpansion interface{}
@@ -34,6 +38,22 @@ type BuildPolicyListerExpansion interface{}
 // BuildPolicyNamespaceLister.
 type BuildPolicyNamespaceListerExpansion interface{}
 
+// GenericAttestationPolicyListerExpansion allows custom methods to be added to
+// GenericAttestationPolicyLister.
+type GenericAttestationPolicyListerExpansion interface{}
+
+// GenericAttestationPolicyNamespaceListerExpansion allows custom methods to be added to
+// GenericAttestationPolicyNamespaceLister.
+type GenericAttestationPolicyNamespaceListerExpansion interface{}
+
+// ImageReviewListerExpansion allows custom methods to be added to
+// ImageReviewLister.
+type ImageReviewListerExpansion interface{}
+
+// ImageReviewNamespaceListerExpansion allows custom methods to be added to
+// ImageReviewNamespaceLister.
+type ImageReviewNamespaceListerExpansion interface{}
+
 // ImageSecurityPolicyListerExpansion allows custom methods to be added to
 // ImageSecurityPolicyLister.
 type ImageSecurityPolicyListerExpansion interface{}
@@ -45,3 +65,11 @@ type ImageSecurityPolicyNamespaceListerExpansion interface{}
 // KritisConfigListerExpansion allows custom methods to be added to
 // KritisConfigLister.
 type KritisConfigListerExpansion interface{}
+
+// ViolationRecordListerExpansion allows custom methods to be added to
+// ViolationRecordLister.
+type ViolationRecordListerExpansion interface{}
+
+// ViolationRecordNamespaceListerExpansion allows custom methods to be added to
+// ViolationRecordNamespaceLister.
+type ViolationRecordNamespaceListerExpansion interface{}
@@ -25,33 +25,86 @@ const (
 	// InvalidImageSecPolicy is the key for labels and annotations
 	InvalidImageSecPolicy           = "kritis.grafeas.io/invalidImageSecPolicy"
 	InvalidImageSecPolicyLabelValue = "invalidImageSecPolicy"
+	// ViolationDetected is the annotation holding when a pod was first found out of policy.
+	ViolationDetected = "kritis.grafeas.io/violationDetected"
+	// Quarantined is the label of pods quarantined by the QUARANTINE remediation action.
+	Quarantined           = "kritis.grafeas.io/quarantined"
+	QuarantinedLabelValue = "true"
 
 	// ImageAttestation is the key for labels for indication attestaions.
 	ImageAttestation             = "kritis.grafeas.io/attestation"
 	NoAttestationsLabelValue     = "notAttested"
 	PreviouslyAttestedLabelValue = "attested"
 
+	// ImageReviewPolicy and ImageReviewAllowed are the labels of ImageReviews, holding
+	// the name of the reviewed policy and whether the image was allowed.
+	ImageReviewPolicy  = "kritis.grafeas.io/policy"
+	ImageReviewAllowed = "kritis.grafeas.io/allowed"
+
+	// ReviewedAt, ReviewedPolicies and VerifiedAttestations are the annotations
+	// recording the decision of the webhook on the pods it admits.
+	ReviewedAt           = "kritis.grafeas.io/reviewed-at"
+	ReviewedPolicies     = "kritis.grafeas.io/policies"
+	VerifiedAttestations = "kritis.grafeas.io/attestations"
+
+	// AuditNoteID is the Discovery note of the policy decisions recorded by Kritis
+	AuditNoteID = "kritis-audit"
+
 	// Breakglass is the key for the breakglass annotation
 	Breakglass = "kritis.grafeas.io/breakglass"
 
+	// DefaultAttestationAuthority is the key for the namespace annotation naming the
+	// AttestationAuthority used by ImageSecurityPolicies which list none
+	DefaultAttestationAuthority = "kritis.grafeas.io/defaultAttestationAuthority"
+
 	// A list of label values
 	PreviouslyAttestedAnnotation = "Previously attested."
 	NoAttestationsAnnotation     = "No valid attestations present. This pod will not be able to restart in future"
 
 	// Atomic Container Signature type
 	AtomicContainerSigType = "atomic container signature"
+	// BinAuthzContainerSigType is the type of the payloads signed for Binary Authorization, e.g. by gcloud
+	BinAuthzContainerSigType = "Google cloud binauthz container signature"
 
 	// Constants for Metadata Library
 	PageSize          = int32(100)
 	ResourceURLPrefix = "https://"
 
+	// Values of ImageSecurityPolicy treatUnknownSeverityAs, besides severities
+	UnknownSeverityAllow = "ALLOW"
+	UnknownSeverityBlock = "BLOCK"
+
+	// Values of ImageSecurityPolicy dockerHubImages
+	DockerHubAllow        = "ALLOW"
+	DockerHubOfficialOnly = "OFFICIAL_ONLY"
+	DockerHubDeny         = "DENY"
+
+	// Values of ImageSecurityPolicy platformValidation
+	PlatformValidationAll          = "ALL"
+	PlatformValidationNodeSelector = "NODE_SELECTOR"
+
+	// Values of ImageSecurityPolicy remediationAction
+	RemediationNone        = "NONE"
+	RemediationQuarantine  = "QUARANTINE"
+	RemediationScaleToZero = "SCALE_TO_ZERO"
+	RemediationEvict       = "EVICT"
+
+	// SBOM formats accepted by ImageSecurityPolicy requireSBOM
+	SBOMFormatSPDX      = "SPDX"
+	SBOMFormatCycloneDX = "CycloneDX"
+
+	// Metadata kinds which can be skipped by ImageSecurityPolicy or KritisConfig
+	VulnerabilityMetadataKind = "VULNERABILITY"
+	BuildMetadataKind         = "BUILD"
+	OccurrenceV1MetadataKind  = "OCCURRENCE_V1"
+
 	// Constants relevant for the GCB event parser
 	CloudSourceRepoPattern = "https://source.developers.google.com/p/%s/r/%s%s"
 )
 
 var (
 	// GlobalImageWhitelist is a list of images that are globally whitelisted
-	// They should always pass the webhook check
+	// Objects running only these images are exempt from review, see exemption.Matcher
 	GlobalImageWhitelist = []string{"gcr.io/kritis-project/kritis-server",
 		"gcr.io/kritis-project/preinstall",
 		"gcr.io/kritis-project/postinstall",
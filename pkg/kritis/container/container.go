@@ -20,9 +20,11 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+
 	"github.com/grafeas/kritis/pkg/kritis/attestation"
 	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
 	"github.com/grafeas/kritis/pkg/kritis/secrets"
 )
 
@@ -62,7 +64,7 @@ type critical struct {
 }
 
 func newCritical(image string) (*critical, error) {
-	digest, err := name.NewDigest(image, name.StrictValidation)
+	digest, err := reference.ParseDigest(image)
 	if err != nil {
 		return nil, err
 	}
@@ -114,21 +116,44 @@ func (acs *AtomicContainerSig) CreateAttestationSignature(pgpSigningKey *secrets
 	return attestation.CreateMessageAttestation(pgpSigningKey.PgpKey, hostStr)
 }
 
+// VerifyGenericAttestationSignature verifies a generic signed attestation of the host, whose
+// signature over payload is verified with the PEM encoded PKIX public key.
+func (acs *AtomicContainerSig) VerifyGenericAttestationSignature(publicKey string, payload []byte, sig []byte) error {
+	if err := attestation.VerifyPKIXSignature(publicKey, payload, sig); err != nil {
+		return err
+	}
+	return acs.verifyHost(payload)
+}
+
 func (acs *AtomicContainerSig) VerifyAttestationSignature(publicKey string, sig string) error {
 	hostSig, err := attestation.GetPlainMessage(publicKey, sig)
 	if err != nil {
 		return err
 	}
+	return acs.verifyHost(hostSig)
+}
+
+// verifyHost verifies that the signed payload is an attestation of the same image: the
+// type, docker-manifest-digest and docker-reference of its critical section must match the host.
+// Payloads signed for Binary Authorization have their own type, and are accepted too.
+// A valid signature is not enough, since it may have been made for another image.
+func (acs *AtomicContainerSig) verifyHost(hostSig []byte) error {
 	// Unmarshall the json host string to get AtomicContainerSig struct
 	var host AtomicContainerSig
 	if err := json.Unmarshal(hostSig, &host); err != nil {
-		return err
+		return errors.Wrap(err, "invalid attestation payload")
 	}
-
-	if !host.Equals(acs) {
-		h1, _ := host.JSON()
-		h2, _ := acs.JSON()
-		return fmt.Errorf("sig not verified. Expected %s, Got %s", h1, h2)
+	if host.Critical == nil || host.Critical.Identity == nil || host.Critical.Image == nil {
+		return fmt.Errorf("attestation payload has no critical identity and image")
+	}
+	if t := host.Critical.Type; t != acs.Critical.Type && t != constants.BinAuthzContainerSigType {
+		return fmt.Errorf("attestation payload has type %q, not %q", t, acs.Critical.Type)
+	}
+	if got, want := host.Critical.Image.Digest, acs.Critical.Image.Digest; got != want {
+		return fmt.Errorf("attestation payload is for digest %q, not %q", got, want)
+	}
+	if got, want := host.Critical.Identity.DockerRef, acs.Critical.Identity.DockerRef; got != want {
+		return fmt.Errorf("attestation payload is for docker-reference %q, not %q", got, want)
 	}
 	return nil
 }
@@ -214,6 +214,41 @@ func TestContainerEquals(t *testing.T) {
 	}
 }
 
+func TestVerifyHost(t *testing.T) {
+	host, err := NewAtomicContainerSig(goodImage, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	digest := "sha256:b3f3eccfd27c9864312af3796067e7db28007a1566e1e042c5862eed3ff1b2c8"
+	otherDigest := "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	payloadOfType := func(ref, digest, typ string) string {
+		return `{"critical":{"identity":{"docker-reference":"` + ref + `"},"image":{"docker-manifest-digest":"` + digest + `"},"type":"` + typ + `"}}`
+	}
+	payload := func(ref, digest string) string {
+		return payloadOfType(ref, digest, "Google cloud binauthz container signature")
+	}
+	tcs := []struct {
+		name      string
+		payload   string
+		shouldErr bool
+	}{
+		{"same image", payload("gcr.io/kritis-project/kritis-server", digest), false},
+		{"other digest", payload("gcr.io/kritis-project/kritis-server", otherDigest), true},
+		{"other docker-reference", payload("gcr.io/kritis-project/other", digest), true},
+		{"atomic type", payloadOfType("gcr.io/kritis-project/kritis-server", digest, "atomic container signature"), false},
+		{"other type", payloadOfType("gcr.io/kritis-project/kritis-server", digest, "docker container signature"), true},
+		{"no type", `{"critical":{"identity":{"docker-reference":"gcr.io/kritis-project/kritis-server"},"image":{"docker-manifest-digest":"` + digest + `"}}}`, true},
+		{"no critical section", `{"optional":{"a":"b"}}`, true},
+		{"no identity", `{"critical":{"image":{"docker-manifest-digest":"` + digest + `"}}}`, true},
+		{"not json", "payload", true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			testutil.CheckError(t, tc.shouldErr, host.verifyHost([]byte(tc.payload)))
+		})
+	}
+}
+
 // Created using gpg --armor --sign -u test@kritis.org <atomic_host_json_representation.txt> | base64
 var expectedSig = `-----BEGIN PGP MESSAGE-----
 
@@ -0,0 +1,131 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package controller runs the background reconcilers of Kritis on rate
+// limited work queues. Failed keys are retried with exponential backoff,
+// and every controller publishes its counters in the metrics package.
+package controller
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/grafeas/kritis/pkg/kritis/metrics"
+)
+
+const (
+	// DefaultMaxRetries is the number of times a failing key is retried before it is dropped.
+	DefaultMaxRetries = 5
+
+	baseRetryDelay = 5 * time.Second
+	maxRetryDelay  = 5 * time.Minute
+)
+
+// Reconciler brings the state of the object identified by key in line with the policies.
+type Reconciler interface {
+	Reconcile(key string) error
+}
+
+// ReconcilerFunc adapts a function to a Reconciler.
+type ReconcilerFunc func(key string) error
+
+// Reconcile calls f(key).
+func (f ReconcilerFunc) Reconcile(key string) error {
+	return f(key)
+}
+
+// Controller reconciles the keys added to its work queue.
+type Controller struct {
+	Name       string
+	MaxRetries int
+
+	reconciler Reconciler
+	queue      *queue
+}
+
+// New returns a Controller reconciling keys with r.
+func New(name string, r Reconciler) *Controller {
+	return &Controller{
+		Name:       name,
+		MaxRetries: DefaultMaxRetries,
+		reconciler: r,
+		queue:      newQueue(baseRetryDelay, maxRetryDelay),
+	}
+}
+
+// Enqueue adds key to the work queue, keys already queued are reconciled once.
+func (c *Controller) Enqueue(key string) {
+	c.queue.add(key)
+	metrics.ControllerQueueDepth.Set(c.Name, queueDepth(c.queue.len()))
+}
+
+// AddRateLimited adds key to the work queue after a backoff growing with the
+// number of times it was rate limited since it last reconciled successfully.
+// Until then, Enqueue does not add the key.
+func (c *Controller) AddRateLimited(key string) {
+	c.queue.addRateLimited(key)
+}
+
+// Run processes the work queue with the given number of workers until ctx is done.
+func (c *Controller) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c.processNextItem() {
+			}
+		}()
+	}
+	<-ctx.Done()
+	c.queue.shutDown()
+	wg.Wait()
+}
+
+// processNextItem reconciles the next key, and returns false once the queue is shut down.
+func (c *Controller) processNextItem() bool {
+	key, ok := c.queue.get()
+	if !ok {
+		return false
+	}
+	defer c.queue.done(key)
+	defer func() { metrics.ControllerQueueDepth.Set(c.Name, queueDepth(c.queue.len())) }()
+
+	metrics.ControllerReconciles.Add(c.Name, 1)
+	err := c.reconciler.Reconcile(key)
+	if err == nil {
+		c.queue.forget(key)
+		return true
+	}
+	metrics.ControllerErrors.Add(c.Name, 1)
+	if c.queue.numRequeues(key) < c.MaxRetries {
+		glog.Warningf("%s: failed to reconcile %q, retrying: %v", c.Name, key, err)
+		c.AddRateLimited(key)
+		return true
+	}
+	glog.Errorf("%s: dropping %q after %d retries: %v", c.Name, key, c.MaxRetries, err)
+	c.queue.forget(key)
+	return true
+}
+
+func queueDepth(n int) *expvar.Int {
+	v := new(expvar.Int)
+	v.Set(int64(n))
+	return v
+}
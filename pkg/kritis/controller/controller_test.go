@@ -0,0 +1,127 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafeas/kritis/pkg/kritis/metrics"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+type recorder struct {
+	mu       sync.Mutex
+	calls    map[string]int
+	failures map[string]int
+	done     chan string
+}
+
+func (r *recorder) Reconcile(key string) error {
+	r.mu.Lock()
+	r.calls[key]++
+	fail := r.calls[key] <= r.failures[key]
+	r.mu.Unlock()
+	r.done <- key
+	if fail {
+		return fmt.Errorf("failed to reconcile %s", key)
+	}
+	return nil
+}
+
+func TestController(t *testing.T) {
+	r := &recorder{
+		calls:    map[string]int{},
+		failures: map[string]int{"flaky": 2, "broken": 10},
+		done:     make(chan string, 100),
+	}
+	c := New("test", r)
+	c.MaxRetries = 3
+	c.queue = newQueue(time.Millisecond, 5*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		c.Run(ctx, 2)
+		close(stopped)
+	}()
+
+	c.Enqueue("ok")
+	c.Enqueue("flaky")
+	c.Enqueue("broken")
+	// ok once, flaky until it succeeds, broken until it is dropped.
+	for i := 0; i < 1+3+4; i++ {
+		select {
+		case <-r.done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for reconciliations: %v", r.calls)
+		}
+	}
+	cancel()
+	<-stopped
+
+	testutil.DeepEqual(t, map[string]int{"ok": 1, "flaky": 3, "broken": 4}, r.calls)
+	if v := metrics.ControllerErrors.Get("test"); v == nil || v.String() != "6" {
+		t.Errorf("expected 6 errors, got %v", v)
+	}
+}
+
+func TestQueueDeduplicates(t *testing.T) {
+	q := newQueue(time.Millisecond, time.Millisecond)
+	q.add("a")
+	q.add("a")
+	q.add("b")
+	if q.len() != 2 {
+		t.Fatalf("expected 2 queued keys, got %d", q.len())
+	}
+	key, _ := q.get()
+	// Added while processing, queued again once done.
+	q.add(key)
+	if q.len() != 1 {
+		t.Fatalf("expected key being processed not to be queued, got %d keys", q.len())
+	}
+	q.done(key)
+	if q.len() != 2 {
+		t.Fatalf("expected key to be queued again once done, got %d keys", q.len())
+	}
+	q.shutDown()
+	if _, ok := q.get(); ok {
+		t.Errorf("expected no key after shut down")
+	}
+}
+
+func TestQueueRateLimited(t *testing.T) {
+	q := newQueue(50*time.Millisecond, time.Second)
+	q.addRateLimited("a")
+	// Keys waiting for a retry are not queued again before their backoff expires
+	q.add("a")
+	q.addRateLimited("a")
+	if q.len() != 0 {
+		t.Fatalf("expected no queued key during the backoff, got %d", q.len())
+	}
+	testutil.DeepEqual(t, 1, q.numRequeues("a"))
+	deadline := time.Now().Add(5 * time.Second)
+	for q.len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the key to be retried")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	testutil.DeepEqual(t, 1, q.len())
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// queue is a work queue of keys. A key is queued at most once, and a key added
+// while it is processed is queued again once done, so no key is reconciled
+// concurrently. A key waiting for a rate limited retry is only queued once its
+// backoff expires.
+type queue struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	keys       []string
+	queued     map[string]bool
+	processing map[string]bool
+	dirty      map[string]bool
+	waiting    map[string]bool
+	failures   map[string]int
+	shutdown   bool
+
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+func newQueue(baseDelay, maxDelay time.Duration) *queue {
+	q := &queue{
+		queued:     map[string]bool{},
+		processing: map[string]bool{},
+		dirty:      map[string]bool{},
+		waiting:    map[string]bool{},
+		failures:   map[string]int{},
+		baseDelay:  baseDelay,
+		maxDelay:   maxDelay,
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// add queues key unless it is already queued.
+func (q *queue) add(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.shutdown || q.queued[key] || q.waiting[key] {
+		return
+	}
+	if q.processing[key] {
+		q.dirty[key] = true
+		return
+	}
+	q.queued[key] = true
+	q.keys = append(q.keys, key)
+	q.cond.Signal()
+}
+
+// addRateLimited queues key after an exponential backoff based on its failures.
+func (q *queue) addRateLimited(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.shutdown || q.waiting[key] {
+		return
+	}
+	delay := q.baseDelay << uint(q.failures[key])
+	if delay > q.maxDelay || delay <= 0 {
+		delay = q.maxDelay
+	}
+	q.failures[key]++
+	q.waiting[key] = true
+	time.AfterFunc(delay, func() {
+		q.mu.Lock()
+		delete(q.waiting, key)
+		q.mu.Unlock()
+		q.add(key)
+	})
+}
+
+// numRequeues returns the number of failures of key since it was last forgotten.
+func (q *queue) numRequeues(key string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.failures[key]
+}
+
+// forget resets the failures of key.
+func (q *queue) forget(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.failures, key)
+}
+
+// get blocks until a key is queued, and returns false once the queue is shut down.
+func (q *queue) get() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.keys) == 0 && !q.shutdown {
+		q.cond.Wait()
+	}
+	if q.shutdown {
+		return "", false
+	}
+	key := q.keys[0]
+	q.keys = q.keys[1:]
+	delete(q.queued, key)
+	q.processing[key] = true
+	return key, true
+}
+
+// done marks key as processed, queuing it again if it was added meanwhile.
+func (q *queue) done(key string) {
+	q.mu.Lock()
+	delete(q.processing, key)
+	dirty := q.dirty[key]
+	delete(q.dirty, key)
+	q.mu.Unlock()
+	if dirty {
+		q.add(key)
+	}
+}
+
+// len returns the number of queued keys.
+func (q *queue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.keys)
+}
+
+// shutDown stops the queue and wakes up all waiting workers.
+func (q *queue) shutDown() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shutdown = true
+	q.cond.Broadcast()
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package attestationpolicy reads the GenericAttestationPolicies of namespaces.
+package attestationpolicy
+
+import (
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	clientset "github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+)
+
+// GenericAttestationPolicies returns all GenericAttestationPolicies in the specified namespace
+// Pass in an empty string to get all policies in all namespaces
+func GenericAttestationPolicies(namespace string) ([]v1beta1.GenericAttestationPolicy, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building config")
+	}
+
+	client, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building clientset")
+	}
+	list, err := client.KritisV1beta1().GenericAttestationPolicies(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing all generic attestation policies")
+	}
+	return list.Items, nil
+}
+
+// SelectGenericAttestationPolicies returns the policies which apply to pods with the given labels.
+func SelectGenericAttestationPolicies(gaps []v1beta1.GenericAttestationPolicy, podLabels map[string]string) ([]v1beta1.GenericAttestationPolicy, error) {
+	var selected []v1beta1.GenericAttestationPolicy
+	for _, gap := range gaps {
+		if gap.Spec.PodSelector == nil {
+			selected = append(selected, gap)
+			continue
+		}
+		s, err := metav1.LabelSelectorAsSelector(gap.Spec.PodSelector)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pod selector in GenericAttestationPolicy %q", gap.Name)
+		}
+		if s.Matches(labels.Set(podLabels)) {
+			selected = append(selected, gap)
+		}
+	}
+	return selected, nil
+}
+
+// Whitelisted returns true if the image is admitted by the policy without attestations.
+func Whitelisted(gap v1beta1.GenericAttestationPolicy, image string) bool {
+	for _, i := range gap.Spec.ImageWhitelist {
+		if i == image || reference.Equal(i, image) {
+			return true
+		}
+	}
+	return false
+}
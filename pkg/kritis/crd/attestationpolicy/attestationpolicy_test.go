@@ -0,0 +1,78 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package attestationpolicy
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func TestSelectGenericAttestationPolicies(t *testing.T) {
+	gaps := []v1beta1.GenericAttestationPolicy{
+		{ObjectMeta: metav1.ObjectMeta{Name: "all"}},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "frontend"},
+			Spec: v1beta1.GenericAttestationPolicySpec{
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "frontend"}},
+			},
+		},
+	}
+	invalid := []v1beta1.GenericAttestationPolicy{{
+		Spec: v1beta1.GenericAttestationPolicySpec{
+			PodSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "tier", Operator: "bogus"}}},
+		},
+	}}
+	tests := []struct {
+		name     string
+		gaps     []v1beta1.GenericAttestationPolicy
+		labels   map[string]string
+		expected []string
+		shdErr   bool
+	}{
+		{"matching pods", gaps, map[string]string{"tier": "frontend"}, []string{"all", "frontend"}, false},
+		{"other pods", gaps, map[string]string{"tier": "batch"}, []string{"all"}, false},
+		{"invalid selector", invalid, nil, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected, err := SelectGenericAttestationPolicies(test.gaps, test.labels)
+			var names []string
+			for _, gap := range selected {
+				names = append(names, gap.Name)
+			}
+			testutil.CheckErrorAndDeepEqual(t, test.shdErr, err, test.expected, names)
+		})
+	}
+}
+
+func TestWhitelisted(t *testing.T) {
+	gap := v1beta1.GenericAttestationPolicy{
+		Spec: v1beta1.GenericAttestationPolicySpec{
+			ImageWhitelist: []string{testutil.QualifiedImage},
+		},
+	}
+	if !Whitelisted(gap, testutil.QualifiedImage) {
+		t.Errorf("expected %s to be whitelisted", testutil.QualifiedImage)
+	}
+	if Whitelisted(gap, testutil.IntTestImage) {
+		t.Errorf("expected %s not to be whitelisted", testutil.IntTestImage)
+	}
+}
@@ -17,12 +17,16 @@ limitations under the License.
 package authority
 
 import (
+	"sort"
+
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/rest"
 
 	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
 	clientset "github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned"
+	"github.com/grafeas/kritis/pkg/kritis/crd/informers"
 )
 
 type Lister func(namespace string) ([]v1beta1.AttestationAuthority, error)
@@ -30,7 +34,11 @@ type Fetcher func(namespace string, name string) (*v1beta1.AttestationAuthority,
 
 // Authorities returns all AttestationAuthorities in the specified namespaces
 // Pass in an empty string to get all AttestationAuthorities in all namespaces
+// AttestationAuthorities are read from the informer cache once started, see informers.Start.
 func Authorities(namespace string) ([]v1beta1.AttestationAuthority, error) {
+	if l := informers.Get(); l != nil {
+		return cachedAuthorities(l, namespace)
+	}
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, errors.Wrap(err, "error building config")
@@ -50,6 +58,13 @@ func Authorities(namespace string) ([]v1beta1.AttestationAuthority, error) {
 // Authority returns the AttestationAuthority in the specified namespace and with the given name
 // Returns error if AttestationAuthority is not found
 func Authority(namespace string, name string) (*v1beta1.AttestationAuthority, error) {
+	if l := informers.Get(); l != nil {
+		a, err := l.AttestationAuthorities.AttestationAuthorities(namespace).Get(name)
+		if err != nil {
+			return nil, err
+		}
+		return a.DeepCopy(), nil
+	}
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, errors.Wrap(err, "error building config")
@@ -61,3 +76,29 @@ func Authority(namespace string, name string) (*v1beta1.AttestationAuthority, er
 	}
 	return client.KritisV1beta1().AttestationAuthorities(namespace).Get(name, metav1.GetOptions{})
 }
+
+// cachedAuthorities returns copies of the cached AttestationAuthorities, sorted by
+// namespace and name like the API server lists them.
+func cachedAuthorities(l *informers.Listers, namespace string) ([]v1beta1.AttestationAuthority, error) {
+	var list []*v1beta1.AttestationAuthority
+	var err error
+	if namespace == "" {
+		list, err = l.AttestationAuthorities.List(labels.Everything())
+	} else {
+		list, err = l.AttestationAuthorities.AttestationAuthorities(namespace).List(labels.Everything())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing cached attestation authorities")
+	}
+	auths := make([]v1beta1.AttestationAuthority, len(list))
+	for i, a := range list {
+		auths[i] = *a.DeepCopy()
+	}
+	sort.Slice(auths, func(i, j int) bool {
+		if auths[i].Namespace != auths[j].Namespace {
+			return auths[i].Namespace < auths[j].Namespace
+		}
+		return auths[i].Name < auths[j].Name
+	})
+	return auths, nil
+}
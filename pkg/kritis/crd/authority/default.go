@@ -0,0 +1,93 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authority
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/crd/informers"
+	"github.com/grafeas/kritis/pkg/kritis/crd/kritisconfig"
+)
+
+// DefaultFetcher returns the default AttestationAuthority of a namespace, or nil if it has none.
+type DefaultFetcher func(namespace string) (*v1beta1.AttestationAuthority, error)
+
+var (
+	// For testing
+	namespaceAnnotations = func(namespace string) (map[string]string, error) {
+		ns, err := informers.Namespace(namespace)
+		if err != nil {
+			return nil, err
+		}
+		return ns.Annotations, nil
+	}
+	clusterDefault = func() (string, error) {
+		config, err := kritisconfig.KritisConfig()
+		if err != nil || config == nil {
+			return "", err
+		}
+		return config.Spec.DefaultAttestationAuthority, nil
+	}
+	authority = Authority
+)
+
+// DefaultAuthority returns the AttestationAuthority named by the
+// kritis.grafeas.io/defaultAttestationAuthority annotation of the namespace,
+// falling back to the default of the KritisConfig.
+func DefaultAuthority(namespace string) (*v1beta1.AttestationAuthority, error) {
+	annotations, err := namespaceAnnotations(namespace)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get namespace %s", namespace)
+	}
+	ref := annotations[constants.DefaultAttestationAuthority]
+	if ref == "" {
+		if ref, err = clusterDefault(); err != nil {
+			return nil, errors.Wrap(err, "failed to get KritisConfig")
+		}
+	}
+	if ref == "" {
+		return nil, nil
+	}
+	name, err := parseReference(namespace, ref)
+	if err != nil {
+		return nil, err
+	}
+	a, err := authority(namespace, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get default attestation authority %s", ref)
+	}
+	return a, nil
+}
+
+// parseReference returns the name of a "name" or "namespace/name" reference.
+// Authorities are only resolved in the given namespace, so that a namespace
+// cannot use the keys of another one.
+func parseReference(namespace string, ref string) (string, error) {
+	i := strings.Index(ref, "/")
+	if i < 0 {
+		return ref, nil
+	}
+	if ref[:i] != namespace {
+		return "", fmt.Errorf("default attestation authority %s is not in namespace %s", ref, namespace)
+	}
+	return ref[i+1:], nil
+}
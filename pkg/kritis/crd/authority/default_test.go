@@ -0,0 +1,67 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authority
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDefaultAuthority(t *testing.T) {
+	origAnnotations, origDefault, origAuthority := namespaceAnnotations, clusterDefault, authority
+	defer func() {
+		namespaceAnnotations, clusterDefault, authority = origAnnotations, origDefault, origAuthority
+	}()
+	authority = func(namespace string, name string) (*v1beta1.AttestationAuthority, error) {
+		if name == "missing" {
+			return nil, fmt.Errorf("not found")
+		}
+		return &v1beta1.AttestationAuthority{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}, nil
+	}
+
+	tcs := []struct {
+		name        string
+		annotations map[string]string
+		cluster     string
+		shouldErr   bool
+		expected    string
+	}{
+		{"no default", nil, "", false, ""},
+		{"namespace annotation", map[string]string{"kritis.grafeas.io/defaultAttestationAuthority": "ns-aa"}, "cluster-aa", false, "qa/ns-aa"},
+		{"cluster default", nil, "cluster-aa", false, "qa/cluster-aa"},
+		{"same namespace reference", map[string]string{"kritis.grafeas.io/defaultAttestationAuthority": "qa/ns-aa"}, "", false, "qa/ns-aa"},
+		{"other namespace annotation", map[string]string{"kritis.grafeas.io/defaultAttestationAuthority": "kritis/ns-aa"}, "", true, ""},
+		{"other namespace cluster default", nil, "kritis/cluster-aa", true, ""},
+		{"missing authority", map[string]string{"kritis.grafeas.io/defaultAttestationAuthority": "missing"}, "", true, ""},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			namespaceAnnotations = func(string) (map[string]string, error) { return tc.annotations, nil }
+			clusterDefault = func() (string, error) { return tc.cluster, nil }
+			a, err := DefaultAuthority("qa")
+			actual := ""
+			if a != nil {
+				actual = a.Namespace + "/" + a.Name
+			}
+			testutil.CheckErrorAndDeepEqual(t, tc.shouldErr, err, tc.expected, actual)
+		})
+	}
+}
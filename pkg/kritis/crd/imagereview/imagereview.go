@@ -0,0 +1,213 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagereview
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	clientset "github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+)
+
+// For testing
+var now = time.Now
+
+// maxPending is the number of ImageReviews waiting to be written, beyond which
+// reviews of other images and policies are dropped.
+const maxPending = 1000
+
+// New returns the ImageReview recording the review of image against the policy
+// kind/namespace/name. pod is the name of the reviewed pod, or empty.
+// Reviews of the same image and policy have the same name, so the latest one
+// replaces the previous ones.
+func New(kind, namespace, name, image, pod, source string, attested bool, violations []policy.Violation) *v1beta1.ImageReview {
+	labels := map[string]string{
+		constants.ImageReviewAllowed: strconv.FormatBool(len(violations) == 0),
+	}
+	// Policy names can be longer than label values allow, these are only found by the spec.
+	if len(validation.IsValidLabelValue(name)) == 0 {
+		labels[constants.ImageReviewPolicy] = name
+	}
+	ir := &v1beta1.ImageReview{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      reviewName(kind, name, image),
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: v1beta1.ImageReviewSpec{
+			Image:      image,
+			PolicyKind: kind,
+			Policy:     name,
+			Pod:        pod,
+			Source:     source,
+		},
+		Status: v1beta1.ImageReviewStatus{
+			Allowed:    len(violations) == 0,
+			Attested:   attested,
+			ReviewTime: metav1.NewTime(now()),
+		},
+	}
+	for _, v := range violations {
+		ir.Status.Violations = append(ir.Status.Violations, v1beta1.ImageReviewViolation{
+			Type:   v.Type().ToString(),
+			Reason: string(v.Reason()),
+		})
+	}
+	return ir
+}
+
+// reviewName returns the name of the ImageReviews of image against the policy kind/name:
+// the policy name, shortened to fit, and a hash of the kind and image.
+func reviewName(kind, name, image string) string {
+	sum := sha256.Sum256([]byte(kind + "\x00" + image))
+	suffix := hex.EncodeToString(sum[:8])
+	if max := validation.DNS1123SubdomainMaxLength - len(suffix) - 1; len(name) > max {
+		name = strings.TrimRight(name[:max], "-.")
+	}
+	return name + "-" + suffix
+}
+
+// Recorder writes ImageReviews in the background, so reviews don't wait for the API server.
+// Reviews of the same image and policy waiting to be written are replaced by the latest one.
+type Recorder struct {
+	client  clientset.Interface
+	mu      sync.Mutex
+	pending map[string]*v1beta1.ImageReview
+	wake    chan struct{}
+}
+
+// NewRecorder returns a Recorder writing ImageReviews with client.
+func NewRecorder(client clientset.Interface) *Recorder {
+	r := newRecorder(client)
+	go r.run()
+	return r
+}
+
+func newRecorder(client clientset.Interface) *Recorder {
+	return &Recorder{
+		client:  client,
+		pending: map[string]*v1beta1.ImageReview{},
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Record queues ir to be written. It fails if too many reviews are waiting.
+func (r *Recorder) Record(ir *v1beta1.ImageReview) error {
+	key := ir.Namespace + "/" + ir.Name
+	r.mu.Lock()
+	if _, ok := r.pending[key]; !ok && len(r.pending) >= maxPending {
+		r.mu.Unlock()
+		return errors.Errorf("too many image reviews waiting to be written, dropping review of %s", ir.Spec.Image)
+	}
+	r.pending[key] = ir
+	r.mu.Unlock()
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (r *Recorder) run() {
+	for range r.wake {
+		r.flush()
+	}
+}
+
+// flush writes the pending ImageReviews.
+func (r *Recorder) flush() {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = map[string]*v1beta1.ImageReview{}
+	r.mu.Unlock()
+	for _, ir := range pending {
+		if err := upsert(r.client, ir); err != nil {
+			glog.Errorf("error recording review: %v", err)
+		}
+	}
+}
+
+// upsert creates ir, or updates the ImageReview of the same name.
+func upsert(client clientset.Interface, ir *v1beta1.ImageReview) error {
+	irs := client.KritisV1beta1().ImageReviews(ir.Namespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := irs.Get(ir.Name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			_, err = irs.Create(ir)
+			if k8serrors.IsAlreadyExists(err) {
+				// Written by another replica in the meantime, update it instead.
+				return k8serrors.NewConflict(v1beta1.Resource("imagereviews"), ir.Name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		current.Labels = ir.Labels
+		current.Spec = ir.Spec
+		current.Status = ir.Status
+		_, err = irs.Update(current)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error writing image review of %s", ir.Spec.Image)
+	}
+	return nil
+}
+
+// recorder is the Recorder Record queues reviews to, built once.
+var recorder = struct {
+	once     sync.Once
+	recorder *Recorder
+	err      error
+}{}
+
+// Record queues ir to be written to its namespace by a Recorder shared by all reviews.
+func Record(ir *v1beta1.ImageReview) error {
+	recorder.once.Do(func() {
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			recorder.err = errors.Wrap(err, "error building config")
+			return
+		}
+		client, err := clientset.NewForConfig(config)
+		if err != nil {
+			recorder.err = errors.Wrap(err, "error building clientset")
+			return
+		}
+		recorder.recorder = NewRecorder(client)
+	})
+	if recorder.err != nil {
+		return recorder.err
+	}
+	return recorder.recorder.Record(ir)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagereview
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned/fake"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func TestNew(t *testing.T) {
+	reviewTime := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return reviewTime }
+	defer func() { now = time.Now }()
+
+	tests := []struct {
+		name       string
+		policy     string
+		violations []policy.Violation
+		expected   *v1beta1.ImageReview
+	}{
+		{
+			name:   "allowed",
+			policy: "isp",
+			expected: &v1beta1.ImageReview{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      reviewName("ImageSecurityPolicy", "isp", testutil.QualifiedImage),
+					Namespace: "foo",
+					Labels: map[string]string{
+						constants.ImageReviewAllowed: "true",
+						constants.ImageReviewPolicy:  "isp",
+					},
+				},
+				Spec: v1beta1.ImageReviewSpec{
+					Image:      testutil.QualifiedImage,
+					PolicyKind: "ImageSecurityPolicy",
+					Policy:     "isp",
+					Pod:        "pod",
+					Source:     "webhook",
+				},
+				Status: v1beta1.ImageReviewStatus{
+					Allowed:    true,
+					ReviewTime: metav1.NewTime(reviewTime),
+				},
+			},
+		},
+		{
+			name:   "violations and long policy name",
+			policy: "a-policy-name-which-is-much-too-long-to-be-the-value-of-a-label-of-the-review",
+			violations: []policy.Violation{
+				securitypolicy.NewViolation(nil, policy.UnqualifiedImageViolation, "bad image"),
+			},
+			expected: &v1beta1.ImageReview{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      reviewName("ImageSecurityPolicy", "a-policy-name-which-is-much-too-long-to-be-the-value-of-a-label-of-the-review", testutil.QualifiedImage),
+					Namespace: "foo",
+					Labels: map[string]string{
+						constants.ImageReviewAllowed: "false",
+					},
+				},
+				Spec: v1beta1.ImageReviewSpec{
+					Image:      testutil.QualifiedImage,
+					PolicyKind: "ImageSecurityPolicy",
+					Policy:     "a-policy-name-which-is-much-too-long-to-be-the-value-of-a-label-of-the-review",
+					Pod:        "pod",
+					Source:     "webhook",
+				},
+				Status: v1beta1.ImageReviewStatus{
+					Violations: []v1beta1.ImageReviewViolation{
+						{Type: "UnqualifiedImageViolation", Reason: "bad image"},
+					},
+					ReviewTime: metav1.NewTime(reviewTime),
+				},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := New("ImageSecurityPolicy", "foo", tc.policy, testutil.QualifiedImage, "pod", "webhook", false, tc.violations)
+			testutil.DeepEqual(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestReviewName(t *testing.T) {
+	name := reviewName("ImageSecurityPolicy", "isp", testutil.QualifiedImage)
+	testutil.DeepEqual(t, name, reviewName("ImageSecurityPolicy", "isp", testutil.QualifiedImage))
+	for _, other := range []string{
+		reviewName("GenericAttestationPolicy", "isp", testutil.QualifiedImage),
+		reviewName("ImageSecurityPolicy", "isp", testutil.IntTestImage),
+	} {
+		if other == name {
+			t.Errorf("expected different names for different images and policies, got %s", name)
+		}
+	}
+	long := reviewName("ImageSecurityPolicy", strings.Repeat("a", 250)+".b", testutil.QualifiedImage)
+	if errs := validation.IsDNS1123Subdomain(long); len(errs) > 0 {
+		t.Errorf("invalid name %s: %v", long, errs)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	r := newRecorder(client)
+	ir := New("ImageSecurityPolicy", "foo", "isp", testutil.QualifiedImage, "pod", "cron", false, nil)
+	if err := r.Record(ir); err != nil {
+		t.Fatal(err)
+	}
+	r.flush()
+
+	// A second review of the image replaces the first one.
+	ir = New("ImageSecurityPolicy", "foo", "isp", testutil.QualifiedImage, "other", "webhook", false, []policy.Violation{
+		securitypolicy.NewViolation(nil, policy.UnqualifiedImageViolation, "bad image"),
+	})
+	if err := r.Record(ir); err != nil {
+		t.Fatal(err)
+	}
+	r.flush()
+
+	irs, err := client.KritisV1beta1().ImageReviews("foo").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(irs.Items) != 1 {
+		t.Fatalf("expected 1 image review, got %d", len(irs.Items))
+	}
+	testutil.DeepEqual(t, ir.Spec, irs.Items[0].Spec)
+	testutil.DeepEqual(t, ir.Status, irs.Items[0].Status)
+}
+
+func TestRecorderDropsReviewsWhenFull(t *testing.T) {
+	r := newRecorder(fake.NewSimpleClientset())
+	for i := 0; i < maxPending; i++ {
+		ir := New("ImageSecurityPolicy", "foo", "isp", testutil.QualifiedImage+strings.Repeat("0", i), "", "cron", false, nil)
+		if err := r.Record(ir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Reviews of a pending image and policy replace it.
+	err := r.Record(New("ImageSecurityPolicy", "foo", "isp", testutil.QualifiedImage, "", "cron", false, nil))
+	testutil.CheckError(t, false, err)
+	err = r.Record(New("ImageSecurityPolicy", "foo", "isp", testutil.IntTestImage, "", "cron", false, nil))
+	testutil.CheckError(t, true, err)
+}
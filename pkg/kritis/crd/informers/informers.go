@@ -0,0 +1,177 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package informers caches ImageSecurityPolicies, AttestationAuthorities,
+// KritisConfigs and namespaces in shared informers, so reviews read them from memory instead of
+// listing them from the API server. The crd packages use the cache once Start
+// has synced it, and the API server otherwise.
+package informers
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	clientset "github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned"
+	listers "github.com/grafeas/kritis/pkg/kritis/client/listers/kritis/v1beta1"
+	kubernetesutil "github.com/grafeas/kritis/pkg/kritis/kubernetes"
+)
+
+const (
+	// DefaultResync is the period at which informers resync their cache.
+	DefaultResync = 10 * time.Minute
+	// DefaultSyncTimeout is how long Start waits for the caches to sync, e.g. while the
+	// CRDs are missing or Kritis can't list them.
+	DefaultSyncTimeout = time.Minute
+)
+
+// Listers serve the cached resources.
+type Listers struct {
+	ImageSecurityPolicies  listers.ImageSecurityPolicyLister
+	AttestationAuthorities listers.AttestationAuthorityLister
+	KritisConfigs          listers.KritisConfigLister
+	Namespaces             corelisters.NamespaceLister
+}
+
+var (
+	mu      sync.RWMutex
+	started *Listers
+)
+
+// Start builds the informers with the in-cluster client, runs them until stopCh is
+// closed and waits for their caches to sync. Once it returns, Get serves their listers.
+// If the caches don't sync within syncTimeout, the informers are stopped and Start fails,
+// so the resources are read from the API server.
+func Start(resync, syncTimeout time.Duration, stopCh <-chan struct{}) error {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return errors.Wrap(err, "error building config")
+	}
+	client, err := clientset.NewForConfig(config)
+	if err != nil {
+		return errors.Wrap(err, "error building clientset")
+	}
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return errors.Wrap(err, "error building kubernetes clientset")
+	}
+	l, err := start(client, kubeClient, resync, syncTimeout, stopCh)
+	if err != nil {
+		return err
+	}
+	Set(l)
+	return nil
+}
+
+func start(client clientset.Interface, kubeClient kubernetes.Interface, resync, syncTimeout time.Duration, stopCh <-chan struct{}) (*Listers, error) {
+	kritis := client.KritisV1beta1()
+	isps := newInformer(&v1beta1.ImageSecurityPolicy{}, resync,
+		func(o metav1.ListOptions) (runtime.Object, error) { return kritis.ImageSecurityPolicies("").List(o) },
+		func(o metav1.ListOptions) (watch.Interface, error) { return kritis.ImageSecurityPolicies("").Watch(o) })
+	auths := newInformer(&v1beta1.AttestationAuthority{}, resync,
+		func(o metav1.ListOptions) (runtime.Object, error) { return kritis.AttestationAuthorities("").List(o) },
+		func(o metav1.ListOptions) (watch.Interface, error) { return kritis.AttestationAuthorities("").Watch(o) })
+	configs := newInformer(&v1beta1.KritisConfig{}, resync,
+		func(o metav1.ListOptions) (runtime.Object, error) { return kritis.KritisConfigs().List(o) },
+		func(o metav1.ListOptions) (watch.Interface, error) { return kritis.KritisConfigs().Watch(o) })
+	core := kubeClient.CoreV1()
+	namespaces := newInformer(&corev1.Namespace{}, resync,
+		func(o metav1.ListOptions) (runtime.Object, error) { return core.Namespaces().List(o) },
+		func(o metav1.ListOptions) (watch.Interface, error) { return core.Namespaces().Watch(o) })
+
+	// The informers run until stopCh is closed, or their caches failed to sync.
+	run := make(chan struct{})
+	failed := make(chan struct{})
+	go func() {
+		select {
+		case <-stopCh:
+		case <-failed:
+		}
+		close(run)
+	}()
+	informers := []cache.SharedIndexInformer{isps, auths, configs, namespaces}
+	hasSynced := make([]cache.InformerSynced, len(informers))
+	for i, informer := range informers {
+		go informer.Run(run)
+		hasSynced[i] = informer.HasSynced
+	}
+	// wait is closed when stopCh is, once syncTimeout has passed or once the caches synced.
+	wait := make(chan struct{})
+	synced := make(chan struct{})
+	defer close(synced)
+	go func() {
+		defer close(wait)
+		select {
+		case <-stopCh:
+		case <-time.After(syncTimeout):
+		case <-synced:
+		}
+	}()
+	if !cache.WaitForCacheSync(wait, hasSynced...) {
+		close(failed)
+		return nil, fmt.Errorf("informer caches did not sync within %s", syncTimeout)
+	}
+	return &Listers{
+		ImageSecurityPolicies:  listers.NewImageSecurityPolicyLister(isps.GetIndexer()),
+		AttestationAuthorities: listers.NewAttestationAuthorityLister(auths.GetIndexer()),
+		KritisConfigs:          listers.NewKritisConfigLister(configs.GetIndexer()),
+		Namespaces:             corelisters.NewNamespaceLister(namespaces.GetIndexer()),
+	}, nil
+}
+
+func newInformer(obj runtime.Object, resync time.Duration, list cache.ListFunc, watchFunc cache.WatchFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{ListFunc: list, WatchFunc: watchFunc},
+		obj, resync,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+}
+
+// Get returns the listers of the started informers, or nil if they are not started.
+func Get() *Listers {
+	mu.RLock()
+	defer mu.RUnlock()
+	return started
+}
+
+// Namespace returns the namespace with the given name, from the cache once started.
+func Namespace(name string) (*corev1.Namespace, error) {
+	if l := Get(); l != nil {
+		ns, err := l.Namespaces.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		return ns.DeepCopy(), nil
+	}
+	return kubernetesutil.Namespace(name)
+}
+
+// Set replaces the listers returned by Get, nil stops using the cache.
+func Set(l *Listers) {
+	mu.Lock()
+	defer mu.Unlock()
+	started = l
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned/fake"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func TestStart(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&v1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "isp"}},
+		&v1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "bar", Name: "isp"}},
+		&v1beta1.AttestationAuthority{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "aa"}},
+		&v1beta1.KritisConfig{ObjectMeta: metav1.ObjectMeta{Name: "kritis-config"}},
+	)
+	kubeClient := kubefake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo", Annotations: map[string]string{"a": "b"}}},
+	)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	l, err := start(client, kubeClient, 0, time.Minute, stopCh)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	isps, err := l.ImageSecurityPolicies.ImageSecurityPolicies("foo").List(labels.Everything())
+	testutil.CheckError(t, false, err)
+	if len(isps) != 1 || isps[0].Namespace != "foo" {
+		t.Errorf("expected the ISP of namespace foo, got %v", isps)
+	}
+	aa, err := l.AttestationAuthorities.AttestationAuthorities("foo").Get("aa")
+	testutil.CheckError(t, false, err)
+	testutil.DeepEqual(t, "aa", aa.Name)
+	if _, err := l.AttestationAuthorities.AttestationAuthorities("bar").Get("aa"); err == nil {
+		t.Errorf("expected no AttestationAuthority in namespace bar")
+	}
+	configs, err := l.KritisConfigs.List(labels.Everything())
+	testutil.CheckError(t, false, err)
+	testutil.DeepEqual(t, 1, len(configs))
+	ns, err := l.Namespaces.Get("foo")
+	testutil.CheckError(t, false, err)
+	testutil.DeepEqual(t, "b", ns.Annotations["a"])
+
+	defer Set(nil)
+	Set(l)
+	ns, err = Namespace("foo")
+	testutil.CheckError(t, false, err)
+	testutil.DeepEqual(t, "foo", ns.Name)
+}
+
+func TestStartSyncTimeout(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	// e.g. the CRD is missing, or Kritis can't list the resource.
+	client.PrependReactor("list", "imagesecuritypolicies", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("forbidden")
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	begin := time.Now()
+	_, err := start(client, kubefake.NewSimpleClientset(), 0, 100*time.Millisecond, stopCh)
+	testutil.CheckError(t, true, err)
+	if elapsed := time.Since(begin); elapsed > 10*time.Second {
+		t.Errorf("expected start to fail after its sync timeout, took %s", elapsed)
+	}
+}
+
+func TestGet(t *testing.T) {
+	defer Set(nil)
+	if Get() != nil {
+		t.Fatalf("expected no listers before Start")
+	}
+	l := &Listers{}
+	Set(l)
+	if Get() != l {
+		t.Errorf("expected the listers set")
+	}
+}
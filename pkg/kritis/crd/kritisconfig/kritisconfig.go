@@ -20,17 +20,32 @@ import (
 	"github.com/pkg/errors"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/rest"
 
 	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
 	clientset "github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned"
+	"github.com/grafeas/kritis/pkg/kritis/crd/informers"
 	"github.com/grafeas/kritis/pkg/kritis/util"
 )
 
 type ClusterWhitelistedImagesRemover func(images []string) ([]string, error)
 
 // KritisConfig returns KritisConfig in the cluster
+// It is read from the informer cache once started, see informers.Start.
 func KritisConfig() (*v1beta1.KritisConfig, error) {
+	if l := informers.Get(); l != nil {
+		list, err := l.KritisConfigs.List(labels.Everything())
+		if err != nil {
+			return nil, errors.Wrap(err, "error listing cached kritis configs")
+		}
+		items := make([]v1beta1.KritisConfig, len(list))
+		for i, c := range list {
+			items[i] = *c.DeepCopy()
+		}
+		return single(items)
+	}
+
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, errors.Wrap(err, "error building config")
@@ -40,19 +55,27 @@ func KritisConfig() (*v1beta1.KritisConfig, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "error building clientset")
 	}
+	return ListKritisConfig(client)
+}
 
+// ListKritisConfig returns the KritisConfig listed with client, e.g. from outside the cluster.
+func ListKritisConfig(client clientset.Interface) (*v1beta1.KritisConfig, error) {
 	list, err := client.KritisV1beta1().KritisConfigs().List(metav1.ListOptions{})
 	if err != nil {
 		return nil, errors.Wrap(err, "error listing all kritis configs")
 	}
+	return single(list.Items)
+}
 
-	if len(list.Items) > 1 {
+// single returns the only KritisConfig of items, or nil if there is none.
+func single(items []v1beta1.KritisConfig) (*v1beta1.KritisConfig, error) {
+	if len(items) > 1 {
 		return nil, errors.New("more than 1 KritisConfig found, expected to have only 1 in the cluster")
-	} else if len(list.Items) == 0 {
+	} else if len(items) == 0 {
 		return nil, nil
 	}
 
-	return &list.Items[0], nil
+	return &items[0], nil
 }
 
 func RemoveWhitelistedImages(images []string) ([]string, error) {
@@ -0,0 +1,97 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitypolicy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	clientset "github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+// For testing
+var clusterAttestor = func(name string) (*v1beta1.Attestor, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building config")
+	}
+	client, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building clientset")
+	}
+	a, err := client.KritisV1beta1().Attestors().Get(name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return a, err
+}
+
+// clusterAttestorFetcher gets attestors named without a slash from the Attestor resources
+// of the cluster, and Binary Authorization attestors, projects/<project>/attestors/<name>,
+// from binauthz if it is available.
+type clusterAttestorFetcher struct {
+	binauthz AttestorFetcher
+}
+
+func (f *clusterAttestorFetcher) GetAttestor(name string) (*Attestor, error) {
+	if strings.Contains(name, "/") {
+		if f.binauthz == nil {
+			return nil, fmt.Errorf("Binary Authorization is not available to get attestor %s", name)
+		}
+		return f.binauthz.GetAttestor(name)
+	}
+	a, err := clusterAttestor(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get Attestor %s", name)
+	}
+	if a == nil {
+		return nil, nil
+	}
+	return attestorFromResource(a)
+}
+
+// attestorFromResource converts an Attestor resource, identifying PGP keys without an ID
+// by their fingerprint.
+func attestorFromResource(a *v1beta1.Attestor) (*Attestor, error) {
+	attestor := &Attestor{Name: a.Name}
+	for _, k := range a.Spec.PublicKeys {
+		key := &AttestorPublicKey{
+			ID:         k.ID,
+			AsciiArmor: k.AsciiArmoredPgpPublicKey,
+			PkixPem:    k.PkixPublicKeyPem,
+		}
+		if key.ID == "" {
+			if key.AsciiArmor == "" {
+				return nil, fmt.Errorf("PKIX public key of Attestor %s has no id", a.Name)
+			}
+			pgp, err := secrets.NewPgpKey("", "", key.AsciiArmor)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid public key of Attestor %s", a.Name)
+			}
+			key.ID = pgp.Fingerprint()
+		}
+		attestor.PublicKeys = append(attestor.PublicKeys, key)
+	}
+	return attestor, nil
+}
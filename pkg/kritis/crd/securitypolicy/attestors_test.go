@@ -0,0 +1,97 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitypolicy
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func TestClusterAttestorFetcher(t *testing.T) {
+	original := clusterAttestor
+	defer func() { clusterAttestor = original }()
+	publicKey := testutil.Base64PublicTestKey(t)
+	resources := map[string]*v1beta1.Attestor{
+		"qa": {
+			ObjectMeta: metav1.ObjectMeta{Name: "qa"},
+			Spec: v1beta1.AttestorSpec{PublicKeys: []v1beta1.AttestorPublicKey{
+				{AsciiArmoredPgpPublicKey: publicKey},
+				{ID: "qa-kms", PkixPublicKeyPem: "pem"},
+			}},
+		},
+		"no-id": {
+			ObjectMeta: metav1.ObjectMeta{Name: "no-id"},
+			Spec:       v1beta1.AttestorSpec{PublicKeys: []v1beta1.AttestorPublicKey{{PkixPublicKeyPem: "pem"}}},
+		},
+	}
+	clusterAttestor = func(name string) (*v1beta1.Attestor, error) {
+		return resources[name], nil
+	}
+	binauthz := staticAttestorFetcher{
+		"projects/p/attestors/qa": {Name: "projects/p/attestors/qa"},
+	}
+	tests := []struct {
+		name     string
+		fetcher  *clusterAttestorFetcher
+		attestor string
+		expected *Attestor
+		shdErr   bool
+	}{
+		{
+			name:     "Attestor resource",
+			fetcher:  &clusterAttestorFetcher{},
+			attestor: "qa",
+			expected: &Attestor{Name: "qa", PublicKeys: []*AttestorPublicKey{
+				{ID: testutil.PgpKeyFingerprint, AsciiArmor: publicKey},
+				{ID: "qa-kms", PkixPem: "pem"},
+			}},
+		},
+		{
+			name:     "missing Attestor resource",
+			fetcher:  &clusterAttestorFetcher{binauthz: binauthz},
+			attestor: "security",
+		},
+		{
+			name:     "PKIX key without id",
+			fetcher:  &clusterAttestorFetcher{},
+			attestor: "no-id",
+			shdErr:   true,
+		},
+		{
+			name:     "Binary Authorization attestor",
+			fetcher:  &clusterAttestorFetcher{binauthz: binauthz},
+			attestor: "projects/p/attestors/qa",
+			expected: &Attestor{Name: "projects/p/attestors/qa"},
+		},
+		{
+			name:     "Binary Authorization unavailable",
+			fetcher:  &clusterAttestorFetcher{},
+			attestor: "projects/p/attestors/qa",
+			shdErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := test.fetcher.GetAttestor(test.attestor)
+			testutil.CheckErrorAndDeepEqual(t, test.shdErr, err, test.expected, a)
+		})
+	}
+}
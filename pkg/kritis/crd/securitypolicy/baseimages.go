@@ -0,0 +1,104 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitypolicy
+
+import (
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+	"github.com/grafeas/kritis/pkg/kritis/registryauth"
+)
+
+// OCI annotations of the base image an image was built from
+const (
+	baseNameAnnotation   = "org.opencontainers.image.base.name"
+	baseDigestAnnotation = "org.opencontainers.image.base.digest"
+)
+
+// For testing
+var imageManifest = func(image string) (*v1.Manifest, error) {
+	ref, err := reference.ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+	img, err := remote.Image(ref, remote.WithAuthFromKeychain(registryauth.Keychain()))
+	if err != nil {
+		return nil, err
+	}
+	return img.Manifest()
+}
+
+// baseImages returns the base images the image was built from, read from the Grafeas
+// derived image occurrences or, if there are none, the OCI annotations of the image.
+// Each base image is returned as one or more references, e.g. by tag and by digest.
+func baseImages(image string, occs []*metadata.OccurenceV1) ([][]string, error) {
+	var bases [][]string
+	for _, occ := range occs {
+		if occ == nil || occ.Image == nil || occ.Image.BaseResourceUrl == "" {
+			continue
+		}
+		bases = append(bases, []string{strings.TrimPrefix(occ.Image.BaseResourceUrl, constants.ResourceURLPrefix)})
+	}
+	if len(bases) > 0 {
+		return bases, nil
+	}
+	m, err := imageManifest(image)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get manifest of %s", image)
+	}
+	base := m.Annotations[baseNameAnnotation]
+	if base == "" {
+		return nil, nil
+	}
+	refs := []string{base}
+	if digest := m.Annotations[baseDigestAnnotation]; digest != "" {
+		if r, err := reference.Parse(base); err == nil {
+			refs = append(refs, r.Name()+"@"+digest)
+		}
+	}
+	return append(bases, refs), nil
+}
+
+// baseImageAllowed returns true if one of the references of a base image matches an allowed image.
+func baseImageAllowed(isp v1beta1.ImageSecurityPolicy, refs []string) bool {
+	for _, allowed := range isp.Spec.AllowedBaseImages {
+		for _, ref := range refs {
+			if baseImageMatches(allowed, ref) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// baseImageMatches compares references by digest or tag, or only by repository
+// if the allowed reference has neither.
+func baseImageMatches(allowed, ref string) bool {
+	// The tag is after the last colon which isn't part of the registry
+	hasTag := strings.LastIndex(allowed, ":") > strings.LastIndex(allowed, "/")
+	if hasTag || strings.Contains(allowed, "@") {
+		return reference.Equal(allowed, ref)
+	}
+	return reference.SameRepository(allowed, ref)
+}
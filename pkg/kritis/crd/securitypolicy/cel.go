@@ -0,0 +1,218 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitypolicy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/container"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+)
+
+// celEnv declares the variables of custom rules, once.
+var celEnv = struct {
+	once sync.Once
+	env  *cel.Env
+	err  error
+}{}
+
+const (
+	// celCostLimit bounds the cost of evaluating a custom rule, e.g. of nested
+	// comprehensions over the vulnerabilities.
+	celCostLimit = 1000000
+	// maxCELPrograms is the number of compiled custom rules kept.
+	maxCELPrograms = 1000
+)
+
+// celPrograms caches the compiled custom rules by expression.
+var celPrograms = struct {
+	sync.Mutex
+	m map[string]cel.Program
+}{m: map[string]cel.Program{}}
+
+func customRulesEnv() (*cel.Env, error) {
+	celEnv.once.Do(func() {
+		objects := cel.ListType(cel.MapType(cel.StringType, cel.DynType))
+		celEnv.env, celEnv.err = cel.NewEnv(
+			cel.Variable("image", cel.StringType),
+			cel.Variable("vulnerabilities", objects),
+			cel.Variable("builds", objects),
+			cel.Variable("attestations", objects),
+		)
+	})
+	return celEnv.env, celEnv.err
+}
+
+// CompileCustomRule returns the program of a custom rule, which must be a boolean expression.
+func CompileCustomRule(expression string) (cel.Program, error) {
+	celPrograms.Lock()
+	p, ok := celPrograms.m[expression]
+	celPrograms.Unlock()
+	if ok {
+		return p, nil
+	}
+	env, err := customRulesEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, iss := env.Compile(expression)
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expected a bool expression, got %s", ast.OutputType())
+	}
+	p, err = env.Program(ast, cel.CostLimit(celCostLimit))
+	if err != nil {
+		return nil, err
+	}
+	celPrograms.Lock()
+	if len(celPrograms.m) >= maxCELPrograms {
+		// Drop any program, the rules of the current policies are compiled again.
+		for e := range celPrograms.m {
+			delete(celPrograms.m, e)
+			break
+		}
+	}
+	celPrograms.m[expression] = p
+	celPrograms.Unlock()
+	return p, nil
+}
+
+// customRuleViolations evaluates the custom rules of the policy, returning a violation
+// for each rule which doesn't hold for the image.
+func customRuleViolations(ctx context.Context, isp v1beta1.ImageSecurityPolicy, image string, vulnz []metadata.Vulnerability, f metadata.Fetcher, attestorFetcher AttestorFetcher) ([]policy.Violation, error) {
+	if len(isp.Spec.CustomRules) == 0 {
+		return nil, nil
+	}
+	builds, err := f.Builds(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	atts, err := f.Attestations(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	attestations, err := celAttestations(image, isp.Spec.RequireAttestationsBy, atts, attestorFetcher)
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]interface{}{
+		"image":           image,
+		"vulnerabilities": celVulnerabilities(vulnz),
+		"builds":          celBuilds(builds),
+		"attestations":    attestations,
+	}
+	var violations []policy.Violation
+	for _, r := range isp.Spec.CustomRules {
+		p, err := CompileCustomRule(r.Expression)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid custom rule %s", r.Name)
+		}
+		out, _, err := p.Eval(vars)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to evaluate custom rule %s", r.Name)
+		}
+		if ok, _ := out.Value().(bool); !ok {
+			violations = append(violations, NewViolation(nil, policy.CustomRuleViolation, CustomRuleReason(image, r)))
+		}
+	}
+	return violations, nil
+}
+
+func celVulnerabilities(vulnz []metadata.Vulnerability) []interface{} {
+	l := make([]interface{}, len(vulnz))
+	for i, v := range vulnz {
+		l[i] = map[string]interface{}{
+			"cve":             v.CVE,
+			"severity":        v.Severity,
+			"hasFixAvailable": v.HasFixAvailable,
+			"package":         v.Package,
+			"version":         v.Version,
+			"fixedVersion":    v.FixedVersion,
+		}
+	}
+	return l
+}
+
+func celBuilds(builds []metadata.Build) []interface{} {
+	l := make([]interface{}, len(builds))
+	for i, b := range builds {
+		m := map[string]interface{}{"projectID": "", "creator": ""}
+		if b.Provenance != nil {
+			m["projectID"] = b.Provenance.ProjectID
+			m["creator"] = b.Provenance.Creator
+		}
+		l[i] = m
+	}
+	return l
+}
+
+// celAttestations returns the attestations of the image verified by one of the attestors
+// of required, the RequireAttestationsBy of the policy. Others could be written by anyone.
+func celAttestations(image string, required []string, atts []metadata.PGPAttestation, attestorFetcher AttestorFetcher) ([]interface{}, error) {
+	l := []interface{}{}
+	if len(required) == 0 || len(atts) == 0 {
+		return l, nil
+	}
+	sig, err := container.NewAtomicContainerSig(image, map[string]string{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to initialize attestation signature: %s", image)
+	}
+	var names []string
+	for _, entry := range required {
+		_, ns, err := ParseAttestorRequirement(entry)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range ns {
+			if !stringInSlice(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	for _, name := range names {
+		attestor, err := attestorFetcher.GetAttestor(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get an attestor: %s", name)
+		}
+		if attestor == nil {
+			return nil, fmt.Errorf("attestor not found: %s", name)
+		}
+		for _, a := range atts {
+			for _, pubKey := range attestor.PublicKeys {
+				if pubKey.ID != a.KeyID || verifyAttestorSignature(sig, pubKey, a) != nil {
+					continue
+				}
+				l = append(l, map[string]interface{}{
+					"attestor": name,
+					"keyID":    a.KeyID,
+					"payload":  a.Payload,
+				})
+				break
+			}
+		}
+	}
+	return l, nil
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitypolicy
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	kubernetesutil "github.com/grafeas/kritis/pkg/kritis/kubernetes"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/vulnerability"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PolicyConflictEventReason is the reason of events recorded for conflicting policies
+const PolicyConflictEventReason = "PolicyConflict"
+
+// Conflict describes a setting of an ImageSecurityPolicy which is shadowed by
+// another policy in the same namespace. Images are reviewed against every policy
+// of their namespace, so the stricter setting always wins.
+type Conflict struct {
+	Namespace string
+	// Policy is the policy whose setting has no effect
+	Policy string
+	// ShadowedBy is the policy which makes the setting ineffective
+	ShadowedBy string
+	Message    string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s/%s: %s", c.Namespace, c.Policy, c.Message)
+}
+
+// Conflicts returns the conflicts between policies of the same namespace.
+func Conflicts(isps []v1beta1.ImageSecurityPolicy) []Conflict {
+	byNamespace := map[string][]v1beta1.ImageSecurityPolicy{}
+	var namespaces []string
+	for _, isp := range isps {
+		if _, ok := byNamespace[isp.Namespace]; !ok {
+			namespaces = append(namespaces, isp.Namespace)
+		}
+		byNamespace[isp.Namespace] = append(byNamespace[isp.Namespace], isp)
+	}
+	sort.Strings(namespaces)
+
+	var conflicts []Conflict
+	for _, ns := range namespaces {
+		policies := byNamespace[ns]
+		sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+		for _, a := range policies {
+			for _, b := range policies {
+				if a.Name == b.Name {
+					continue
+				}
+				conflicts = append(conflicts, pairConflicts(a, b)...)
+			}
+		}
+	}
+	return conflicts
+}
+
+// pairConflicts returns the settings of a which are shadowed by b.
+func pairConflicts(a, b v1beta1.ImageSecurityPolicy) []Conflict {
+	var messages []string
+	for _, image := range a.Spec.ImageWhitelist {
+		if imageInWhitelist(b, image) {
+			continue
+		}
+		msg := fmt.Sprintf("image %q is whitelisted, but is still reviewed against %q", image, b.Name)
+		if len(b.Spec.RequireAttestationsBy) > 0 {
+			msg = fmt.Sprintf("%s which requires attestations by %v", msg, b.Spec.RequireAttestationsBy)
+		}
+		messages = append(messages, msg)
+	}
+
+	bVulnz := b.Spec.PackageVulnerabilityRequirements
+	if !allowsAllVulnz(b) {
+		for _, cve := range a.Spec.PackageVulnerabilityRequirements.WhitelistCVEs {
+			if !cveInWhitelist(b, cve) {
+				messages = append(messages, fmt.Sprintf("CVE %q is whitelisted, but not by %q", cve, b.Name))
+			}
+		}
+	}
+
+	aVulnz := a.Spec.PackageVulnerabilityRequirements
+	if severityRank(maxSeverity(aVulnz)) > severityRank(maxSeverity(bVulnz)) {
+		messages = append(messages, fmt.Sprintf("maximumSeverity %s is shadowed by %s of %q",
+			maxSeverity(aVulnz), maxSeverity(bVulnz), b.Name))
+	}
+	if severityRank(maxFixUnavailableSeverity(aVulnz)) > severityRank(maxFixUnavailableSeverity(bVulnz)) {
+		messages = append(messages, fmt.Sprintf("maximumFixUnavailableSeverity %s is shadowed by %s of %q",
+			maxFixUnavailableSeverity(aVulnz), maxFixUnavailableSeverity(bVulnz), b.Name))
+	}
+
+	if b.Spec.PodSelector != nil {
+		for i := range messages {
+			messages[i] = fmt.Sprintf("%s, for pods matching %s", messages[i], metav1.FormatLabelSelector(b.Spec.PodSelector))
+		}
+	}
+
+	var conflicts []Conflict
+	for _, m := range messages {
+		conflicts = append(conflicts, Conflict{
+			Namespace:  a.Namespace,
+			Policy:     a.Name,
+			ShadowedBy: b.Name,
+			Message:    m,
+		})
+	}
+	return conflicts
+}
+
+// allowsAllVulnz returns true if isp allows all vulnerabilities, so CVE whitelists don't matter.
+func allowsAllVulnz(isp v1beta1.ImageSecurityPolicy) bool {
+	reqs := isp.Spec.PackageVulnerabilityRequirements
+	return severityRank(maxSeverity(reqs)) >= severityRank(constants.AllowAll) &&
+		severityRank(maxFixUnavailableSeverity(reqs)) >= severityRank(constants.AllowAll)
+}
+
+func maxSeverity(reqs v1beta1.PackageVulnerabilityRequirements) string {
+	if reqs.MaximumSeverity == "" {
+		return "CRITICAL"
+	}
+	return reqs.MaximumSeverity
+}
+
+func maxFixUnavailableSeverity(reqs v1beta1.PackageVulnerabilityRequirements) string {
+	if reqs.MaximumFixUnavailableSeverity == "" {
+		return constants.AllowAll
+	}
+	return reqs.MaximumFixUnavailableSeverity
+}
+
+// severityRank orders max severities from the strictest to the most permissive.
+// CRITICAL allows all known severities, so it ranks with ALLOW_ALL.
+func severityRank(sev string) int32 {
+	switch sev {
+	case constants.BlockAll:
+		return -1
+	case constants.AllowAll:
+		return vulnerability.Severity_value["CRITICAL"]
+	}
+	return vulnerability.Severity_value[sev]
+}
+
+// recorded holds the conflicts whose event was recorded, so that the cron job only
+// records a conflict again once it was resolved in between.
+var recorded = struct {
+	sync.Mutex
+	conflicts map[Conflict]bool
+}{conflicts: map[Conflict]bool{}}
+
+// RecordConflicts records a warning event on each policy with a shadowed setting,
+// once per conflict until it is resolved.
+func RecordConflicts(conflicts []Conflict) {
+	recorded.Lock()
+	defer recorded.Unlock()
+	current := make(map[Conflict]bool, len(conflicts))
+	for _, c := range conflicts {
+		current[c] = true
+		if recorded.conflicts[c] {
+			continue
+		}
+		glog.Warning(c.String())
+		event := kubernetesutil.NewEvent(corev1.ObjectReference{
+			Kind:       "ImageSecurityPolicy",
+			APIVersion: v1beta1.SchemeGroupVersion.String(),
+			Namespace:  c.Namespace,
+			Name:       c.Policy,
+		}, corev1.EventTypeWarning, PolicyConflictEventReason, c.Message)
+		if err := recordEvent(event); err != nil {
+			glog.Errorf("error recording conflict event for %s: %v", c.Policy, err)
+			delete(current, c)
+		}
+	}
+	recorded.conflicts = current
+}
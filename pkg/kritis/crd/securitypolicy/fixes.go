@@ -0,0 +1,132 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitypolicy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	kubernetesutil "github.com/grafeas/kritis/pkg/kritis/kubernetes"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// FixAvailableEventReason is the reason of events recorded when a fix ships for a CVE
+const FixAvailableEventReason = "FixAvailable"
+
+var (
+	// For testing
+	recordEvent = kubernetesutil.CreateEvent
+
+	fixes = newFixTracker()
+)
+
+const (
+	// maxTrackedImages bounds the policies and images whose unfixed CVEs are kept.
+	maxTrackedImages = 10000
+	// trackedImageTTL expires the images no longer observed, e.g. once their pods are gone.
+	trackedImageTTL = 24 * time.Hour
+)
+
+// fixTracker remembers the CVEs without a fix found for each policy and image, so
+// the policy can be notified once a fix ships and the CVE is no longer evaluated
+// against MaximumFixUnavailableSeverity. State is only kept in memory.
+type fixTracker struct {
+	mu      sync.Mutex
+	unfixed map[string]trackedImage
+	now     func() time.Time
+}
+
+type trackedImage struct {
+	cves map[string]bool
+	seen time.Time
+}
+
+func newFixTracker() *fixTracker {
+	return &fixTracker{unfixed: map[string]trackedImage{}, now: time.Now}
+}
+
+// observe records the non whitelisted vulnerabilities of the image and returns the
+// ones which had no fix available when the image was last observed for isp.
+func (t *fixTracker) observe(isp v1beta1.ImageSecurityPolicy, image string, vulnz []metadata.Vulnerability) []metadata.Vulnerability {
+	key := fmt.Sprintf("%s/%s/%s", isp.Namespace, isp.Name, image)
+	unfixed := map[string]bool{}
+	var fixed []metadata.Vulnerability
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	previous := t.unfixed[key].cves
+	for _, v := range vulnz {
+		if vulnerabilityInWhitelist(isp, v) {
+			continue
+		}
+		if !v.HasFixAvailable {
+			unfixed[v.CVE] = true
+		} else if previous[v.CVE] {
+			fixed = append(fixed, v)
+		}
+	}
+	if len(unfixed) == 0 {
+		delete(t.unfixed, key)
+		return fixed
+	}
+	if _, ok := t.unfixed[key]; !ok && len(t.unfixed) >= maxTrackedImages {
+		t.evict(now)
+	}
+	t.unfixed[key] = trackedImage{cves: unfixed, seen: now}
+	return fixed
+}
+
+// evict drops the expired images, or the least recently observed one if none expired.
+func (t *fixTracker) evict(now time.Time) {
+	var oldest string
+	for key, image := range t.unfixed {
+		if now.Sub(image.seen) > trackedImageTTL {
+			delete(t.unfixed, key)
+		} else if oldest == "" || image.seen.Before(t.unfixed[oldest].seen) {
+			oldest = key
+		}
+	}
+	if len(t.unfixed) >= maxTrackedImages {
+		delete(t.unfixed, oldest)
+	}
+}
+
+// recordFixAvailable records an event on isp for a CVE which gained a fix.
+func recordFixAvailable(isp v1beta1.ImageSecurityPolicy, image string, v metadata.Vulnerability, maxSev string, violates bool) {
+	eventType := corev1.EventTypeNormal
+	message := fmt.Sprintf("CVE %q in %q now has a fix available, severity %s is within max severity %s", v.CVE, image, v.Severity, maxSev)
+	if violates {
+		eventType = corev1.EventTypeWarning
+		message = fmt.Sprintf("CVE %q in %q now has a fix available, severity %s exceeds max severity %s", v.CVE, image, v.Severity, maxSev)
+	}
+	glog.Info(message)
+	event := kubernetesutil.NewEvent(corev1.ObjectReference{
+		Kind:       "ImageSecurityPolicy",
+		APIVersion: v1beta1.SchemeGroupVersion.String(),
+		Namespace:  isp.Namespace,
+		Name:       isp.Name,
+		UID:        isp.UID,
+	}, eventType, FixAvailableEventReason, message)
+	if err := recordEvent(event); err != nil {
+		glog.Errorf("error recording fix available event for %s: %v", isp.Name, err)
+	}
+}
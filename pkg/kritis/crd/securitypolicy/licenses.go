@@ -0,0 +1,89 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitypolicy
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/sbom"
+)
+
+// For testing
+var loadSBOMs = sbom.Documents
+
+// licenseViolations checks the licenses of the packages listed in the SBOMs of the image.
+// Images without SBOM have no license violations, use requireSBOM to reject them.
+func licenseViolations(isp v1beta1.ImageSecurityPolicy, image string, occs []*metadata.OccurenceV1) ([]policy.Violation, error) {
+	reqs := isp.Spec.LicenseRequirements
+	if len(reqs.DeniedLicenses) == 0 && len(reqs.AllowedLicenses) == 0 {
+		return nil, nil
+	}
+	docs, err := loadSBOMs(image, occs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get SBOMs: %s", image)
+	}
+	var violations []policy.Violation
+	// The same package may be listed by several SBOMs of the image
+	seen := map[sbom.Package]bool{}
+	for _, d := range docs {
+		for _, p := range d.Packages {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			if ok, err := licensePermitted(p.License, reqs); !ok {
+				violations = append(violations, NewViolation(nil, policy.LicenseViolation, LicenseReason(image, p, err)))
+			}
+		}
+	}
+	return violations, nil
+}
+
+// licensePermitted returns true if the license expression satisfies the requirements.
+// Unknown licenses are only permitted without allow list.
+func licensePermitted(license string, reqs v1beta1.LicenseRequirements) (bool, error) {
+	if license == "" {
+		return len(reqs.AllowedLicenses) == 0, nil
+	}
+	return sbom.Satisfies(license, func(l string) bool {
+		if licenseMatches(reqs.DeniedLicenses, l) {
+			return false
+		}
+		return len(reqs.AllowedLicenses) == 0 || licenseMatches(reqs.AllowedLicenses, l)
+	})
+}
+
+// licenseMatches matches a license ID against a list of IDs, ignoring case.
+// A trailing * in the list matches all IDs with the prefix.
+func licenseMatches(list []string, license string) bool {
+	license = strings.ToLower(license)
+	for _, l := range list {
+		l = strings.ToLower(l)
+		if strings.HasSuffix(l, "*") && strings.HasPrefix(license, strings.TrimSuffix(l, "*")) {
+			return true
+		}
+		if l == license {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitypolicy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+	"github.com/grafeas/kritis/pkg/kritis/registryauth"
+)
+
+// Node labels of the platform of the node, and their deprecated names.
+var (
+	archLabels = []string{"kubernetes.io/arch", "beta.kubernetes.io/arch"}
+	osLabels   = []string{"kubernetes.io/os", "beta.kubernetes.io/os"}
+)
+
+// Index manifests of images referenced by digest are immutable, and cached until
+// maxCachedIndexes are cached.
+const maxCachedIndexes = 1000
+
+var (
+	indexesMu     sync.Mutex
+	cachedIndexes = map[string]*v1.IndexManifest{}
+)
+
+// For testing
+var (
+	indexManifest      = cachedIndexManifest
+	fetchIndexManifest = remoteIndexManifest
+)
+
+// cachedIndexManifest returns the index manifest of image, or nil if it has a single
+// platform, cached if the image is referenced by digest.
+func cachedIndexManifest(image string) (*v1.IndexManifest, error) {
+	if !reference.HasDigest(image) {
+		return fetchIndexManifest(image)
+	}
+	d, err := reference.ParseDigest(image)
+	if err != nil {
+		return nil, err
+	}
+	key := d.String()
+	indexesMu.Lock()
+	m, ok := cachedIndexes[key]
+	indexesMu.Unlock()
+	if ok {
+		return m, nil
+	}
+	m, err = fetchIndexManifest(image)
+	if err != nil {
+		return nil, err
+	}
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+	if _, ok := cachedIndexes[key]; !ok && len(cachedIndexes) >= maxCachedIndexes {
+		for k := range cachedIndexes {
+			delete(cachedIndexes, k)
+			break
+		}
+	}
+	cachedIndexes[key] = m
+	return m, nil
+}
+
+// remoteIndexManifest fetches the index manifest of image, or nil if it has a single platform.
+func remoteIndexManifest(image string) (*v1.IndexManifest, error) {
+	ref, err := reference.ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+	index, err := remote.Index(ref, remote.WithAuthFromKeychain(registryauth.Keychain()))
+	if err != nil {
+		return nil, err
+	}
+	m, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	// Registries return the manifest of single platform images regardless of the accepted types
+	if m.MediaType != types.DockerManifestList && m.MediaType != types.OCIImageIndex && len(m.Manifests) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// platformImage is the image of a platform of a multi-arch image, referenced by digest.
+type platformImage struct {
+	platform string
+	image    string
+}
+
+// platformImages returns the images of the platforms of image checked by the policy,
+// or nil if the policy doesn't check platforms or the image has a single platform.
+func platformImages(ctx context.Context, isp v1beta1.ImageSecurityPolicy, image string) ([]platformImage, error) {
+	var arch, os string
+	switch isp.Spec.PlatformValidation {
+	case "":
+		return nil, nil
+	case constants.PlatformValidationAll:
+	case constants.PlatformValidationNodeSelector:
+		if pod := podFromContext(ctx); pod != nil {
+			arch, os = nodeSelectorPlatform(pod)
+		}
+	default:
+		return nil, fmt.Errorf("invalid platformValidation: %s", isp.Spec.PlatformValidation)
+	}
+	m, err := indexManifest(image)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get platforms of %s", image)
+	}
+	if m == nil {
+		return nil, nil
+	}
+	repo := image
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo = repo[:i]
+	}
+	var images []platformImage
+	for _, d := range m.Manifests {
+		p := d.Platform
+		// Attestation manifests of buildkit have an unknown platform
+		if p == nil || p.OS == "unknown" || p.Architecture == "unknown" {
+			continue
+		}
+		if (arch != "" && p.Architecture != arch) || (os != "" && p.OS != os) {
+			continue
+		}
+		images = append(images, platformImage{
+			platform: platformString(p),
+			image:    fmt.Sprintf("%s@%s", repo, d.Digest),
+		})
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("%s has no image for the platforms of the pod", image)
+	}
+	return images, nil
+}
+
+// nodeSelectorPlatform returns the architecture and OS the nodeSelector of the pod requires, if any.
+func nodeSelectorPlatform(pod *corev1.Pod) (arch, os string) {
+	for _, l := range archLabels {
+		if v, ok := pod.Spec.NodeSelector[l]; ok {
+			arch = v
+			break
+		}
+	}
+	for _, l := range osLabels {
+		if v, ok := pod.Spec.NodeSelector[l]; ok {
+			os = v
+			break
+		}
+	}
+	return arch, os
+}
+
+// platformString formats a platform as os/architecture[/variant].
+func platformString(p *v1.Platform) string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// withPlatform adds the platform of the image to the reasons of its violations, if any.
+func withPlatform(violations []policy.Violation, platform string) []policy.Violation {
+	if platform == "" {
+		return violations
+	}
+	for i, v := range violations {
+		vuln, _ := v.Details().(metadata.Vulnerability)
+		violations[i] = NewViolation(&vuln, v.Type(), PlatformReason(v.Reason(), platform))
+	}
+	return violations
+}
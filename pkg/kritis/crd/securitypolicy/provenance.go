@@ -0,0 +1,153 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitypolicy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/provenance"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+)
+
+// For testing
+var (
+	cosignProvenance   = provenance.FromCosign
+	cosignIdentities   = provenance.CosignIdentities
+	notationIdentities = provenance.NotationIdentities
+)
+
+// publisherWhitelisted returns true if the image carries a keyless cosign signature or a
+// Notary Project signature of one of the publishers whitelisted by the policy. Images whose
+// signatures can not be verified are not.
+func publisherWhitelisted(isp v1beta1.ImageSecurityPolicy, image string) bool {
+	if len(isp.Spec.PublisherWhitelist) == 0 {
+		return false
+	}
+	var ids []provenance.Identity
+	for _, identities := range []func(string) ([]provenance.Identity, error){cosignIdentities, notationIdentities} {
+		found, err := identities(image)
+		if err != nil {
+			glog.Warningf("failed to verify the publisher of %s: %v", image, err)
+			continue
+		}
+		ids = append(ids, found...)
+	}
+	for _, id := range ids {
+		for _, p := range isp.Spec.PublisherWhitelist {
+			if globMatch(p.Issuer, id.Issuer) && globMatch(p.Subject, id.Subject) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// provenanceViolations checks the build provenance of the image. Images pass if one
+// of their provenances meets all requirements of the policy.
+func provenanceViolations(isp v1beta1.ImageSecurityPolicy, image string, occs []*metadata.OccurenceV1) ([]policy.Violation, error) {
+	reqs := isp.Spec.ProvenanceRequirements
+	if len(reqs.Builders) == 0 && len(reqs.SourceURIs) == 0 && reqs.SLSALevel == 0 {
+		return nil, nil
+	}
+	keys, err := provenance.ParseKeys(reqs.PublicKeys)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid provenance public keys")
+	}
+	ref, err := reference.Parse(image)
+	if err != nil {
+		return nil, err
+	}
+	provs := provenance.FromOccurrences(occs, ref.Digest, keys)
+	signed, err := cosignProvenance(image, keys)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get provenance: %s", image)
+	}
+	provs = append(provs, signed...)
+	if len(provs) == 0 {
+		return []policy.Violation{NewViolation(nil, policy.ProvenanceViolation, ProvenanceReason(image, []string{"no verified provenance"}))}, nil
+	}
+	var mismatches []string
+	for _, p := range provs {
+		m := provenanceMismatch(p, reqs)
+		if m == "" {
+			return nil, nil
+		}
+		mismatches = append(mismatches, m)
+	}
+	return []policy.Violation{NewViolation(nil, policy.ProvenanceViolation, ProvenanceReason(image, mismatches))}, nil
+}
+
+// provenanceMismatch returns the first requirement the provenance doesn't meet, or an empty string.
+func provenanceMismatch(p provenance.Provenance, reqs v1beta1.ProvenanceRequirements) string {
+	level, trusted := builderLevel(p.BuilderID, reqs.Builders)
+	if !trusted {
+		return fmt.Sprintf("builder %q is not trusted", p.BuilderID)
+	}
+	if level < reqs.SLSALevel {
+		return fmt.Sprintf("builder %q meets SLSA level %d, less than %d", p.BuilderID, level, reqs.SLSALevel)
+	}
+	if len(reqs.SourceURIs) == 0 {
+		return ""
+	}
+	for _, uri := range p.SourceURIs {
+		for _, pattern := range reqs.SourceURIs {
+			// Git sources are often recorded as git+https://...
+			if globMatch(pattern, uri) || globMatch(pattern, strings.TrimPrefix(uri, "git+")) {
+				return ""
+			}
+		}
+	}
+	return fmt.Sprintf("sources [%s] are not allowed", strings.Join(p.SourceURIs, ","))
+}
+
+// builderLevel returns the SLSA level of a builder and whether it is trusted.
+// All builders are trusted at level 1 if the policy lists none.
+func builderLevel(id string, builders []v1beta1.TrustedBuilder) (int, bool) {
+	if len(builders) == 0 {
+		return 1, true
+	}
+	level, trusted := 0, false
+	for _, b := range builders {
+		if !globMatch(b.ID, id) {
+			continue
+		}
+		trusted = true
+		l := b.SLSALevel
+		if l == 0 {
+			l = 1
+		}
+		if l > level {
+			level = l
+		}
+	}
+	return level, trusted
+}
+
+// globMatch matches s against a pattern where * matches any characters.
+func globMatch(pattern, s string) bool {
+	re := "^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, ".*", -1) + "$"
+	ok, err := regexp.MatchString(re, s)
+	return err == nil && ok
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitypolicy
+
+import (
+	"io"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// ReadImageSecurityPolicies decodes the ImageSecurityPolicies in a stream of YAML
+// or JSON documents. Documents of other kinds are ignored.
+func ReadImageSecurityPolicies(r io.Reader) ([]v1beta1.ImageSecurityPolicy, error) {
+	var isps []v1beta1.ImageSecurityPolicy
+	d := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var isp v1beta1.ImageSecurityPolicy
+		if err := d.Decode(&isp); err != nil {
+			if err == io.EOF {
+				return isps, nil
+			}
+			return nil, errors.Wrap(err, "failed to decode ImageSecurityPolicy")
+		}
+		if isp.Kind == "ImageSecurityPolicy" {
+			isps = append(isps, isp)
+		}
+	}
+}
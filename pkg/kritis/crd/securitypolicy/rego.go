@@ -0,0 +1,251 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitypolicy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	kubernetesutil "github.com/grafeas/kritis/pkg/kritis/kubernetes"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+)
+
+const (
+	defaultRegoQuery = "data.kritis.deny"
+	// maxRegoQueries is the number of prepared queries kept.
+	maxRegoQueries = 100
+)
+
+// For testing
+var (
+	fetchConfigMap = kubernetesutil.ConfigMap
+	regoNow        = time.Now
+	// regoEvalTimeout bounds the evaluation of the Rego rules of a policy for an image.
+	regoEvalTimeout = time.Second
+	// regoConfigMapTTL is how long a ConfigMap is used before it is read again.
+	regoConfigMapTTL = 30 * time.Second
+)
+
+// regoDeniedBuiltins are the builtins Rego rules can't call: they reach the network,
+// or expose the environment of Kritis.
+var regoDeniedBuiltins = map[string]bool{
+	ast.HTTPSend.Name:   true,
+	ast.OPARuntime.Name: true,
+}
+
+// regoCapabilities are the capabilities of the builtins of OPA, without regoDeniedBuiltins.
+var regoCapabilities = func() *ast.Capabilities {
+	caps := ast.CapabilitiesForThisVersion()
+	builtins := caps.Builtins[:0]
+	for _, b := range caps.Builtins {
+		if !regoDeniedBuiltins[b.Name] {
+			builtins = append(builtins, b)
+		}
+	}
+	caps.Builtins = builtins
+	return caps
+}()
+
+type regoEntry struct {
+	resourceVersion string
+	query           rego.PreparedEvalQuery
+	expires         time.Time
+}
+
+// regoQueries caches the prepared queries of the ConfigMaps with their resourceVersion.
+var regoQueries = struct {
+	sync.Mutex
+	m map[string]regoEntry
+}{m: map[string]regoEntry{}}
+
+type podContextKey struct{}
+
+// WithPod returns a copy of ctx holding the pod whose images are validated,
+// passed to the Rego rules of the policies.
+func WithPod(ctx context.Context, pod *v1.Pod) context.Context {
+	return context.WithValue(ctx, podContextKey{}, pod)
+}
+
+func podFromContext(ctx context.Context) *v1.Pod {
+	pod, _ := ctx.Value(podContextKey{}).(*v1.Pod)
+	return pod
+}
+
+// regoInput is the input document of the Rego rules.
+type regoInput struct {
+	Image           string                    `json:"image"`
+	Policy          regoPolicy                `json:"policy"`
+	Vulnerabilities []metadata.Vulnerability  `json:"vulnerabilities"`
+	Occurrences     []*metadata.OccurenceV1   `json:"occurrences"`
+	Attestations    []metadata.PGPAttestation `json:"attestations"`
+	Pod             *v1.Pod                   `json:"pod,omitempty"`
+}
+
+type regoPolicy struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// regoViolations evaluates the Rego rules of the policy against the metadata of the image.
+func regoViolations(ctx context.Context, isp v1beta1.ImageSecurityPolicy, image string, vulnz []metadata.Vulnerability, occs []*metadata.OccurenceV1, f metadata.Fetcher) ([]policy.Violation, error) {
+	reqs := isp.Spec.RegoRequirements
+	if reqs.ConfigMap == "" {
+		return nil, nil
+	}
+	query, err := regoQuery(ctx, isp.Namespace, reqs)
+	if err != nil {
+		return nil, err
+	}
+	atts, err := f.Attestations(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	in := regoInput{
+		Image:           image,
+		Policy:          regoPolicy{Namespace: isp.Namespace, Name: isp.Name},
+		Vulnerabilities: vulnz,
+		Occurrences:     occs,
+		Attestations:    atts,
+		Pod:             podFromContext(ctx),
+	}
+	// Evaluate plain JSON documents, as OPA does for the input of its API.
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	var input interface{}
+	if err := json.Unmarshal(b, &input); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, regoEvalTimeout)
+	defer cancel()
+	rs, err := query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to evaluate Rego rules of ConfigMap %s", reqs.ConfigMap)
+	}
+	var violations []policy.Violation
+	for _, r := range rs {
+		for _, e := range r.Expressions {
+			vs, err := regoResultViolations(image, e.Value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid result of %s", e.Text)
+			}
+			violations = append(violations, vs...)
+		}
+	}
+	return violations, nil
+}
+
+// regoQuery returns the prepared query of the Rego modules of the ConfigMap. The ConfigMap
+// is read again once regoConfigMapTTL has passed, and its modules compiled again if it changed.
+func regoQuery(ctx context.Context, namespace string, reqs v1beta1.RegoRequirements) (rego.PreparedEvalQuery, error) {
+	q := reqs.Query
+	if q == "" {
+		q = defaultRegoQuery
+	}
+	key := strings.Join([]string{namespace, reqs.ConfigMap, q}, "/")
+	regoQueries.Lock()
+	defer regoQueries.Unlock()
+	e, cached := regoQueries.m[key]
+	if cached && regoNow().Before(e.expires) {
+		return e.query, nil
+	}
+	cm, err := fetchConfigMap(namespace, reqs.ConfigMap)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, errors.Wrapf(err, "failed to get ConfigMap %s of Rego rules", reqs.ConfigMap)
+	}
+	if cached && cm.ResourceVersion != "" && cm.ResourceVersion == e.resourceVersion {
+		e.expires = regoNow().Add(regoConfigMapTTL)
+		regoQueries.m[key] = e
+		return e.query, nil
+	}
+	opts := []func(*rego.Rego){
+		rego.Query(q),
+		rego.Compiler(ast.NewCompiler().WithCapabilities(regoCapabilities)),
+		rego.StrictBuiltinErrors(true),
+	}
+	var names []string
+	for name := range cm.Data {
+		if strings.HasSuffix(name, ".rego") {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return rego.PreparedEvalQuery{}, fmt.Errorf("ConfigMap %s has no Rego modules", reqs.ConfigMap)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		opts = append(opts, rego.Module(name, cm.Data[name]))
+	}
+	pq, err := rego.New(opts...).PrepareForEval(ctx)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, errors.Wrapf(err, "failed to compile Rego rules of ConfigMap %s", reqs.ConfigMap)
+	}
+	if !cached && len(regoQueries.m) >= maxRegoQueries {
+		evictRegoQuery()
+	}
+	regoQueries.m[key] = regoEntry{resourceVersion: cm.ResourceVersion, query: pq, expires: regoNow().Add(regoConfigMapTTL)}
+	return pq, nil
+}
+
+// evictRegoQuery removes the query expiring first from regoQueries, which must be locked.
+func evictRegoQuery() {
+	var oldest string
+	for k, e := range regoQueries.m {
+		if oldest == "" || e.expires.Before(regoQueries.m[oldest].expires) {
+			oldest = k
+		}
+	}
+	delete(regoQueries.m, oldest)
+}
+
+// regoResultViolations converts the value of the query, a set or array of messages
+// or of objects with "msg" and "type", into violations.
+func regoResultViolations(image string, value interface{}) ([]policy.Violation, error) {
+	results, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a set of violations, got %T", value)
+	}
+	var violations []policy.Violation
+	for _, r := range results {
+		var typ, msg string
+		switch v := r.(type) {
+		case string:
+			msg = v
+		case map[string]interface{}:
+			msg, _ = v["msg"].(string)
+			typ, _ = v["type"].(string)
+		}
+		if msg == "" {
+			return nil, fmt.Errorf("violation %v has no message", r)
+		}
+		violations = append(violations, NewViolation(nil, policy.RegoViolation, RegoReason(image, typ, msg)))
+	}
+	return violations, nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitypolicy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+)
+
+// gcrHosts are the registries searched for the images of builtProjectIDs.
+var gcrHosts = []string{
+	"gcr.io",
+	"asia.gcr.io",
+	"eu.gcr.io",
+	"us.gcr.io",
+}
+
+// builtProjectRepositories returns the repository patterns of the GCR projects.
+func builtProjectRepositories(projectIDs []string) []string {
+	var patterns []string
+	for _, p := range projectIDs {
+		for _, h := range gcrHosts {
+			patterns = append(patterns, fmt.Sprintf("%s/%s/*", h, p))
+		}
+	}
+	return patterns
+}
+
+// repositoryAllowed returns true if the repository of the image matches one of the patterns.
+// Patterns with a * are matched against the canonical repository name, e.g.
+// "*-docker.pkg.dev/my-project/*". Other patterns name a single repository, e.g. "nginx".
+func repositoryAllowed(image string, patterns []string) bool {
+	ref, err := reference.Parse(image)
+	if err != nil {
+		return false
+	}
+	for _, p := range patterns {
+		if strings.Contains(p, "*") {
+			if globMatch(p, ref.Name()) {
+				return true
+			}
+			continue
+		}
+		if reference.SameRepository(p, image) {
+			return true
+		}
+	}
+	return false
+}
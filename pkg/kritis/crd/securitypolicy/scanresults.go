@@ -0,0 +1,148 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitypolicy
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+)
+
+// For testing
+var scanResultsInterval = 5 * time.Second
+
+// scanWaitReserve is the time left to finish a review after waiting for scans, before
+// the deadline of its context, e.g. the timeout of the webhook.
+const scanWaitReserve = 2 * time.Second
+
+type noWaitContextKey struct{}
+
+// WithoutWaiting returns a copy of ctx validating policies without waiting for the
+// results of scans, for the cron job which reviews the pods again on its next pass.
+func WithoutWaiting(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noWaitContextKey{}, true)
+}
+
+// waitTimeout returns how long to wait for a scan, up to timeout and until scanWaitReserve
+// before the deadline of ctx, or 0 if it must not wait.
+func waitTimeout(ctx context.Context, timeout time.Duration) time.Duration {
+	if noWait, _ := ctx.Value(noWaitContextKey{}).(bool); noWait {
+		return 0
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := time.Until(deadline) - scanWaitReserve; left < timeout {
+			timeout = left
+		}
+	}
+	return timeout
+}
+
+// awaitScan waits up to WaitForScanSeconds for the scan of an image in progress to finish,
+// and until shortly before the deadline of ctx, which all the waits of a review share.
+// It returns whether the image was being scanned, and whether it still is.
+func awaitScan(ctx context.Context, isp v1beta1.ImageSecurityPolicy, image string, f metadata.Fetcher) (waited, scanning bool, err error) {
+	timeout := waitTimeout(ctx, time.Duration(isp.Spec.WaitForScanSeconds)*time.Second)
+	if timeout <= 0 {
+		return false, false, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		status, err := f.ScanStatus(ctx, image)
+		if err != nil {
+			if ctx.Err() != nil {
+				return waited, true, nil
+			}
+			return waited, false, errors.Wrapf(err, "failed to get scan status of %s", image)
+		}
+		if !status.InProgress() {
+			return waited, false, nil
+		}
+		if !waited {
+			glog.Infof("waiting up to %s for the scan of %q, which is %s", timeout, image, status)
+			waited = true
+		}
+		select {
+		case <-ctx.Done():
+			glog.Infof("scan of %q still %s after %s", image, status, timeout)
+			return waited, true, nil
+		case <-time.After(scanResultsInterval):
+		}
+	}
+}
+
+// requiresScanResults returns true if the policy rejects images without vulnerabilities.
+// Policies skipping vulnerabilities never read them, so their images can't be required to have any.
+func requiresScanResults(isp v1beta1.ImageSecurityPolicy) bool {
+	return isp.Spec.RequireScanResults && !stringInSlice(isp.Spec.SkipMetadataKinds, constants.VulnerabilityMetadataKind)
+}
+
+// awaitScanResults reads the vulnerabilities of an image without any again, until there are
+// some or ScanResultsTimeoutSeconds passed, unless its scan ended, e.g. without finding any.
+// It returns whether the image was scanned, and no vulnerabilities once timed out.
+func awaitScanResults(ctx context.Context, isp v1beta1.ImageSecurityPolicy, image string, f metadata.Fetcher) ([]metadata.Vulnerability, bool, error) {
+	if ended, scanned, err := scanEnded(ctx, image, f); err != nil || ended {
+		return nil, scanned, err
+	}
+	timeout := waitTimeout(ctx, time.Duration(isp.Spec.ScanResultsTimeoutSeconds)*time.Second)
+	if timeout <= 0 {
+		return nil, false, nil
+	}
+	ctx, cancel := context.WithTimeout(metadata.WithRefresh(ctx), timeout)
+	defer cancel()
+	glog.Infof("waiting up to %s for the scan results of %q", timeout, image)
+	for {
+		select {
+		case <-ctx.Done():
+			glog.Infof("no scan results for %q after %s", image, timeout)
+			return nil, false, nil
+		case <-time.After(scanResultsInterval):
+		}
+		vulnz, err := f.Vulnerabilities(ctx, image)
+		if err == nil && len(vulnz) == 0 {
+			var ended, scanned bool
+			if ended, scanned, err = scanEnded(ctx, image, f); ended {
+				return nil, scanned, nil
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			return nil, false, err
+		}
+		if len(vulnz) > 0 {
+			return vulnz, true, nil
+		}
+	}
+}
+
+// scanEnded returns whether the scan of an image ended, and whether it succeeded, so
+// that an image without vulnerabilities was scanned without finding any.
+func scanEnded(ctx context.Context, image string, f metadata.Fetcher) (ended, scanned bool, err error) {
+	status, err := f.ScanStatus(ctx, image)
+	if err != nil {
+		return false, false, errors.Wrapf(err, "failed to get scan status of %s", image)
+	}
+	return status != metadata.ScanStatusUnknown && !status.InProgress(), status == metadata.ScanStatusFinished, nil
+}
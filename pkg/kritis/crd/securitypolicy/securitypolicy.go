@@ -18,10 +18,17 @@ package securitypolicy
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/golang/glog"
@@ -29,24 +36,66 @@ import (
 	gcpjwt "github.com/someone1/gcp-jwt-go"
 	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/vulnerability"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
 
 	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
 	"github.com/grafeas/kritis/pkg/kritis/binauthz"
 	clientset "github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned"
 	"github.com/grafeas/kritis/pkg/kritis/constants"
 	"github.com/grafeas/kritis/pkg/kritis/container"
+	"github.com/grafeas/kritis/pkg/kritis/crd/informers"
+	"github.com/grafeas/kritis/pkg/kritis/faults"
 	"github.com/grafeas/kritis/pkg/kritis/kubectl/plugins/resolve"
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
 	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+	"github.com/grafeas/kritis/pkg/kritis/sbom"
+	"github.com/grafeas/kritis/pkg/kritis/util"
 )
 
-// ValidateFunc defines the type for Validating Image Security Policies
-type ValidateFunc func(isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher AttestorFetcher) ([]policy.Violation, error)
+// For testing
+var findSBOM = sbom.Find
+
+type dryRunContextKey struct{}
+
+type eventsContextKey struct{}
+
+// WithEvents returns a copy of ctx recording events on the policies validated,
+// e.g. when a CVE gains a fix. Only the webhook and the cron job record them, so
+// that checks from the CLI or a CI gate leave no trace in the cluster.
+func WithEvents(ctx context.Context) context.Context {
+	return context.WithValue(ctx, eventsContextKey{}, true)
+}
+
+func recordsEvents(ctx context.Context) bool {
+	events, _ := ctx.Value(eventsContextKey{}).(bool)
+	return events && !isDryRun(ctx)
+}
+
+// WithDryRun returns a copy of ctx validating policies without side effects, e.g.
+// without recording the CVEs which gained a fix, for policy simulations.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunContextKey{}, true)
+}
+
+func isDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunContextKey{}).(bool)
+	return dryRun
+}
+
+// ValidateFunc defines the type for Validating Image Security Policies at the time now
+type ValidateFunc func(ctx context.Context, now time.Time, isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher AttestorFetcher) ([]policy.Violation, error)
 
 // ImageSecurityPolicies returns all ISPs in the specified namespaces
 // Pass in an empty string to get all ISPs in all namespaces
+// ISPs are read from the informer cache once started, see informers.Start.
 func ImageSecurityPolicies(namespace string) ([]v1beta1.ImageSecurityPolicy, error) {
+	if l := informers.Get(); l != nil {
+		return cachedImageSecurityPolicies(l, namespace)
+	}
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, errors.Wrap(err, "error building config")
@@ -63,72 +112,199 @@ func ImageSecurityPolicies(namespace string) ([]v1beta1.ImageSecurityPolicy, err
 	return list.Items, nil
 }
 
-// ValidateImageSecurityPolicy checks if an image satisfies ISP requirements
+// cachedImageSecurityPolicies returns copies of the cached ISPs, sorted by
+// namespace and name like the API server lists them.
+func cachedImageSecurityPolicies(l *informers.Listers, namespace string) ([]v1beta1.ImageSecurityPolicy, error) {
+	var list []*v1beta1.ImageSecurityPolicy
+	var err error
+	if namespace == "" {
+		list, err = l.ImageSecurityPolicies.List(labels.Everything())
+	} else {
+		list, err = l.ImageSecurityPolicies.ImageSecurityPolicies(namespace).List(labels.Everything())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing cached image security policies")
+	}
+	isps := make([]v1beta1.ImageSecurityPolicy, len(list))
+	for i, isp := range list {
+		isps[i] = *isp.DeepCopy()
+	}
+	sort.Slice(isps, func(i, j int) bool {
+		if isps[i].Namespace != isps[j].Namespace {
+			return isps[i].Namespace < isps[j].Namespace
+		}
+		return isps[i].Name < isps[j].Name
+	})
+	return isps, nil
+}
+
+// WatchImageSecurityPolicies watches the ISPs of all namespaces.
+func WatchImageSecurityPolicies() (watch.Interface, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "error building config")
+	}
+	client, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "error building clientset")
+	}
+	w, err := client.KritisV1beta1().ImageSecurityPolicies("").Watch(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error watching image security policies")
+	}
+	return w, nil
+}
+
+// Hash returns the SHA-256 of the spec of an ISP, identifying the version of the
+// policy used by a decision independently of its metadata and status.
+func Hash(isp v1beta1.ImageSecurityPolicy) string {
+	b, err := json.Marshal(isp.Spec)
+	if err != nil {
+		glog.Errorf("failed to hash image security policy %s: %v", isp.Name, err)
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// statusClient is the client UpdateStatus writes with, built once.
+var statusClient = struct {
+	once   sync.Once
+	client clientset.Interface
+	err    error
+}{}
+
+// UpdateStatus writes the status of an ISP
+func UpdateStatus(isp *v1beta1.ImageSecurityPolicy) error {
+	statusClient.once.Do(func() {
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			statusClient.err = errors.Wrap(err, "error building config")
+			return
+		}
+		statusClient.client, statusClient.err = clientset.NewForConfig(config)
+		if statusClient.err != nil {
+			statusClient.err = errors.Wrap(statusClient.err, "error building clientset")
+		}
+	})
+	if statusClient.err != nil {
+		return statusClient.err
+	}
+	return updateStatus(statusClient.client, isp)
+}
+
+// updateStatus writes the status of isp onto the current ISP of the cluster, so its
+// spec is not reverted, and tries again if the ISP changed in the meantime.
+func updateStatus(client clientset.Interface, isp *v1beta1.ImageSecurityPolicy) error {
+	isps := client.KritisV1beta1().ImageSecurityPolicies(isp.Namespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := isps.Get(isp.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		current.Status = isp.Status
+		_, err = isps.UpdateStatus(current)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error updating status of image security policy %s", isp.Name)
+	}
+	return nil
+}
+
+// ValidateImageSecurityPolicy checks if an image satisfies ISP requirements at the time now
 // It returns a list of vulnerabilities that don't pass
-func ValidateImageSecurityPolicy(isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher AttestorFetcher) ([]policy.Violation, error) {
+func ValidateImageSecurityPolicy(ctx context.Context, now time.Time, isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher AttestorFetcher) ([]policy.Violation, error) {
+	if _, err := treatUnknownSeverityAs(isp.Spec.PackageVulnerabilityRequirements); err != nil {
+		return nil, err
+	}
+	if _, err := maxTokenAge(isp.Spec.ArkCISignatureRequirements); err != nil {
+		return nil, err
+	}
+	// Don't query the backend for metadata kinds this policy doesn't need
+	metadataFetcher, err := metadata.NewSkippingFetcher(metadataFetcher, isp.Spec.SkipMetadataKinds)
+	if err != nil {
+		return nil, err
+	}
 	// First, check if image is whitelisted
 	if imageInWhitelist(isp, image) {
 		glog.Infof("%q is whitelisted in ImageSecurityPolicy", image)
 		return nil, nil
 	}
 	var violations []policy.Violation
+	// Next, check if the image registry is allowed
+	if !dockerHubAllowed(isp, image) {
+		violations = append(violations, Violation{
+			vType:  policy.DockerHubViolation,
+			reason: DockerHubReason(image, isp),
+		})
+		return violations, nil
+	}
 	// Next, check if image in qualified
 	if !resolve.FullyQualifiedImage(image) {
+		if isp.Spec.RequireImageDigest {
+			violations = append(violations, Violation{
+				vType:  policy.TagNotPinnedViolation,
+				reason: TagNotPinnedReason(image),
+			})
+			return violations, nil
+		}
 		violations = append(violations, Violation{
 			vType:  policy.UnqualifiedImageViolation,
 			reason: UnqualifiedImageReason(image),
 		})
 		return violations, nil
 	}
-	// Now, check vulnz in the image
-	vulnz, err := metadataFetcher.Vulnerabilities(image)
-	if err != nil {
-		return nil, err
-	}
-	maxSev := isp.Spec.PackageVulnerabilityRequirements.MaximumSeverity
-	if maxSev == "" {
-		maxSev = "CRITICAL"
-	}
-
-	maxNoFixSev := isp.Spec.PackageVulnerabilityRequirements.MaximumFixUnavailableSeverity
-	if maxNoFixSev == "" {
-		maxNoFixSev = "ALLOW_ALL"
-	}
 
-	for _, v := range vulnz {
-		// First, check if the vulnerability is whitelisted
-		if cveInWhitelist(isp, v.CVE) {
-			continue
+	// Now, check vulnz in the image, unless it is signed by a whitelisted publisher.
+	// Multi-arch images are checked platform by platform if the policy asks for it.
+	var vulnz []metadata.Vulnerability
+	if publisherWhitelisted(isp, image) {
+		glog.Infof("%q is signed by a whitelisted publisher, skipping vulnerability checks", image)
+	} else {
+		platforms, err := platformImages(ctx, isp, image)
+		if err != nil {
+			return nil, err
 		}
-
-		// Allow operators to set a higher threshold for CVE's that have no fix available.
-		if !v.HasFixAvailable {
-			ok, err := severityWithinThreshold(maxNoFixSev, v.Severity)
+		if len(platforms) == 0 {
+			platforms = []platformImage{{image: image}}
+		}
+		for _, p := range platforms {
+			waited, scanning, err := awaitScan(ctx, isp, p.image, metadataFetcher)
 			if err != nil {
-				return violations, err
+				return nil, err
 			}
-			if ok {
+			if scanning {
+				violations = append(violations, withPlatform([]policy.Violation{
+					NewViolation(nil, policy.NotScannedViolation, ScanInProgressReason(p.image)),
+				}, p.platform)...)
 				continue
 			}
-			violations = append(violations, Violation{
-				vulnerability: v,
-				vType:         policy.FixUnavailableViolation,
-				reason:        FixUnavailableReason(image, v, isp),
-			})
-			continue
-		}
-		ok, err := severityWithinThreshold(maxSev, v.Severity)
-		if err != nil {
-			return violations, err
-		}
-		if ok {
-			continue
+			readCtx := ctx
+			if waited {
+				readCtx = metadata.WithRefresh(ctx)
+			}
+			pvulnz, err := metadataFetcher.Vulnerabilities(readCtx, p.image)
+			scanned := len(pvulnz) > 0
+			if err == nil && !scanned && requiresScanResults(isp) {
+				pvulnz, scanned, err = awaitScanResults(ctx, isp, p.image, metadataFetcher)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !scanned && requiresScanResults(isp) {
+				violations = append(violations, withPlatform([]policy.Violation{
+					NewViolation(nil, policy.NotScannedViolation, NotScannedReason(p.image)),
+				}, p.platform)...)
+				continue
+			}
+			vs, err := vulnerabilityViolations(ctx, now, isp, p.image, pvulnz)
+			violations = append(violations, withPlatform(vs, p.platform)...)
+			if err != nil {
+				return violations, err
+			}
+			vulnz = append(vulnz, pvulnz...)
 		}
-		violations = append(violations, Violation{
-			vulnerability: v,
-			vType:         policy.SeverityViolation,
-			reason:        SeverityReason(image, v, isp),
-		})
 	}
 
 	// Check if image has ArkCI signature
@@ -137,13 +313,13 @@ func ValidateImageSecurityPolicy(isp v1beta1.ImageSecurityPolicy, image string,
 
 	var signedProjectID string
 
-	occs, err := metadataFetcher.OccurencesV1(image)
+	occs, err := metadataFetcher.OccurencesV1(ctx, image)
 	for _, occ := range occs {
 		if occ.NoteName == arkciSignatureNote {
 			b, _ := json.Marshal(occ)
 			glog.Infof("ArkCI signature = %v", string(b))
 
-			token, err := verifyArkSignature(context.Background(), occ, arkciSignerKeyPath)
+			token, err := verifyArkSignature(ctx, occ, arkciSignerKeyPath, isp.Spec.ArkCISignatureRequirements.Algorithm)
 			if err != nil {
 				violations = append(
 					violations,
@@ -161,22 +337,89 @@ func ValidateImageSecurityPolicy(isp v1beta1.ImageSecurityPolicy, image string,
 			glog.Info("ArkCI signature verified")
 			if claims, ok := token.Claims.(jwt.MapClaims); ok {
 				signedProjectID, _ = claims["gcp_project"].(string)
+				for _, reason := range arkClaimViolations(claims, isp.Spec.ArkCISignatureRequirements, now) {
+					violations = append(violations, NewViolation(nil, policy.ArkCIClaimViolation, reason))
+				}
+			}
+		}
+	}
+
+	// Check the image has an SBOM in one of the accepted formats
+	if len(isp.Spec.RequireSBOM) > 0 {
+		for _, f := range isp.Spec.RequireSBOM {
+			if sbom.Format(f) == "" {
+				return nil, fmt.Errorf("invalid SBOM format %q, expected %s or %s", f, constants.SBOMFormatSPDX, constants.SBOMFormatCycloneDX)
 			}
 		}
+		format, err := findSBOM(image, occs, isp.Spec.RequireSBOM)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find an SBOM: %s", image)
+		}
+		if format == "" {
+			violations = append(violations, NewViolation(nil, policy.MissingSBOMViolation, MissingSBOMReason(image, isp)))
+		}
 	}
 
+	// Check the image was built from an allowed base image
+	if len(isp.Spec.AllowedBaseImages) > 0 {
+		bases, err := baseImages(image, occs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get base images: %s", image)
+		}
+		allowed := false
+		for _, refs := range bases {
+			if baseImageAllowed(isp, refs) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			violations = append(violations, NewViolation(nil, policy.DisallowedBaseImageViolation, DisallowedBaseImageReason(image, bases, isp)))
+		}
+	}
+
+	// Check the build provenance of the image
+	pvs, err := provenanceViolations(isp, image, occs)
+	if err != nil {
+		return nil, err
+	}
+	violations = append(violations, pvs...)
+
+	// Check the licenses of the packages listed in the SBOMs
+	lvs, err := licenseViolations(isp, image, occs)
+	if err != nil {
+		return nil, err
+	}
+	violations = append(violations, lvs...)
+
+	// Evaluate the custom Rego rules of the policy
+	rvs, err := regoViolations(ctx, isp, image, vulnz, occs, metadataFetcher)
+	if err != nil {
+		return nil, err
+	}
+	violations = append(violations, rvs...)
+
+	// Evaluate the custom CEL rules of the policy
+	cvs, err := customRuleViolations(ctx, isp, image, vulnz, metadataFetcher, attestorFetcher)
+	if err != nil {
+		return nil, err
+	}
+	violations = append(violations, cvs...)
+
 	// Check image namespace against BuiltProjectIDs
 	// Previously this was checking against build.Provenance.ProjectID, but that is no longer available
 	glog.Infof("isp.Spec.BuiltProjectIDs = %v", isp.Spec.BuiltProjectIDs)
 	if len(isp.Spec.BuiltProjectIDs) > 0 {
 		hasBuildProjectID := false
 		for _, projectID := range isp.Spec.BuiltProjectIDs {
-			// imageInGCR should be deprecated in the future, replaced by ArkCI signature
-			if projectID == signedProjectID || imageInGCR(projectID, image) {
+			if projectID == signedProjectID {
 				hasBuildProjectID = true
 				break
 			}
 		}
+		if !hasBuildProjectID {
+			hasBuildProjectID = repositoryAllowed(image, builtProjectRepositories(isp.Spec.BuiltProjectIDs))
+		}
 
 		if !hasBuildProjectID {
 			violations = append(
@@ -196,49 +439,110 @@ func ValidateImageSecurityPolicy(isp v1beta1.ImageSecurityPolicy, image string,
 		}
 	}
 
+	// Check the image is hosted in an allowed repository
+	if len(isp.Spec.AllowedRepositories) > 0 && !repositoryAllowed(image, isp.Spec.AllowedRepositories) {
+		violations = append(violations, NewViolation(nil, policy.DisallowedRepositoryViolation, DisallowedRepositoryReason(image, isp)))
+	}
+
 	// Check required attestations
 	glog.Infof("isp.Spec.RequireAttestationsBy = %v", isp.Spec.RequireAttestationsBy)
 	if len(isp.Spec.RequireAttestationsBy) > 0 {
-		attestations, err := metadataFetcher.Attestations(image)
+		attestations, err := metadataFetcher.Attestations(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+		vs, err := RequiredAttestationViolations(image, isp.Spec.RequireAttestationsBy, attestations, attestorFetcher)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, vs...)
+	}
+
+	return violations, nil
+}
+
+// RequiredAttestationViolations returns a violation for each of the required Binary
+// Authorization attestors which has not attested the image. An entry may also require
+// M of N attestors, as "2 of projects/p/attestors/qa, projects/p/attestors/security",
+// and is then violated by images attested by fewer of them.
+func RequiredAttestationViolations(image string, required []string, attestations []metadata.PGPAttestation, attestorFetcher AttestorFetcher) ([]policy.Violation, error) {
+	var violations []policy.Violation
+	seen := map[string]bool{}
+	for _, entry := range required {
+		if seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		threshold, names, err := ParseAttestorRequirement(entry)
 		if err != nil {
 			return nil, err
 		}
-		for _, required := range isp.Spec.RequireAttestationsBy {
-			requiredAttestor, err := attestorFetcher.GetAttestor(required)
+		var missing []string
+		for _, name := range names {
+			requiredAttestor, err := attestorFetcher.GetAttestor(name)
 			if err != nil {
-				return nil, errors.Wrapf(err, "failed to get an attestor: %s", required)
+				return nil, errors.Wrapf(err, "failed to get an attestor: %s", name)
 			}
 			if requiredAttestor == nil {
-				return nil, fmt.Errorf("attestor not found: %s", required)
+				return nil, fmt.Errorf("attestor not found: %s", name)
 			}
 
 			ok, err := hasRequiredAttestation(image, requiredAttestor, attestations)
 			if err != nil {
-				return nil, errors.Wrapf(err, "failed to check if required attestation exist: %s, %s", image, required)
+				return nil, errors.Wrapf(err, "failed to check if required attestation exist: %s, %s", image, name)
 			}
 			if !ok {
-				violations = append(
-					violations,
-					NewViolation(
-						nil,
-						policy.RequiredAttestationViolation,
-						policy.Reason(
-							fmt.Sprintf(
-								"%q doesn't have a required attestation: [%s]",
-								image,
-								required,
-							),
-						),
-					),
-				)
+				missing = append(missing, name)
 			}
 		}
+		if len(names)-len(missing) >= threshold {
+			continue
+		}
+		reason := fmt.Sprintf("%q doesn't have a required attestation: [%s]", image, strings.Join(missing, ", "))
+		if len(names) > 1 {
+			reason = fmt.Sprintf("%q has %d of the %d required attestations of [%s], missing: [%s]", image, len(names)-len(missing), threshold, strings.Join(names, ", "), strings.Join(missing, ", "))
+		}
+		violations = append(violations, NewViolation(nil, policy.RequiredAttestationViolation, policy.Reason(reason)))
 	}
-
 	return violations, nil
 }
 
-func verifyArkSignature(ctx context.Context, occ *metadata.OccurenceV1, keyPath string) (*jwt.Token, error) {
+// ParseAttestorRequirement parses an entry of RequireAttestationsBy into the number of
+// attestors which must attest images and the attestors. A plain attestor name requires
+// 1 of 1, "M of a, b, c" requires M of the listed attestors, each counted once.
+func ParseAttestorRequirement(entry string) (int, []string, error) {
+	parts := strings.SplitN(strings.TrimSpace(entry), " of ", 2)
+	if len(parts) == 1 {
+		return 1, []string{parts[0]}, nil
+	}
+	threshold, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid attestation requirement %q: %v", entry, err)
+	}
+	var names []string
+	for _, name := range strings.Split(parts[1], ",") {
+		if name = strings.TrimSpace(name); name != "" && !stringInSlice(names, name) {
+			names = append(names, name)
+		}
+	}
+	if threshold < 1 || threshold > len(names) {
+		return 0, nil, fmt.Errorf("invalid attestation requirement %q: requires %d of %d attestors", entry, threshold, len(names))
+	}
+	return threshold, names, nil
+}
+
+func verifyArkSignature(ctx context.Context, occ *metadata.OccurenceV1, keyPath string, algorithm string) (*jwt.Token, error) {
+	if algorithm == "" {
+		algorithm = jwt.SigningMethodRS256.Alg()
+	}
+	method, ok := arkSigningMethods[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported ArkCI signature algorithm: %s", algorithm)
+	}
+
+	if err := faults.Inject(ctx, faults.KMS); err != nil {
+		return nil, err
+	}
 	config := &gcpjwt.KMSConfig{
 		KeyPath: keyPath,
 	}
@@ -248,14 +552,18 @@ func verifyArkSignature(ctx context.Context, occ *metadata.OccurenceV1, keyPath
 		return nil, err
 	}
 
+	return parseArkSignature(occ, algorithm, method, keyFunc)
+}
+
+func parseArkSignature(occ *metadata.OccurenceV1, algorithm string, method jwt.SigningMethod, keyFunc jwt.Keyfunc) (*jwt.Token, error) {
 	for _, j := range occ.Attestation.Jwts {
 		token, err := jwt.Parse(j.CompactJwt, func(token *jwt.Token) (interface{}, error) {
-			if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Method.Alg())
+			if token.Method.Alg() != algorithm {
+				return nil, fmt.Errorf("unexpected signing method: %v, policy requires %v", token.Method.Alg(), algorithm)
 			}
 
 			// To bypass signing method check in gcpjwt
-			token.Method = gcpjwt.SigningMethodKMSRS256
+			token.Method = method
 
 			return keyFunc(token)
 		})
@@ -274,43 +582,250 @@ func verifyArkSignature(ctx context.Context, occ *metadata.OccurenceV1, keyPath
 	return nil, fmt.Errorf("no jwt found")
 }
 
+// arkClaimViolations checks the claims of a verified ArkCI signature against the
+// required claims and maximum token age of the ISP, returning one reason per failed claim.
+func arkClaimViolations(claims jwt.MapClaims, reqs v1beta1.ArkCISignatureRequirements, now time.Time) []policy.Reason {
+	var reasons []policy.Reason
+	names := make([]string, 0, len(reqs.RequiredClaims))
+	for name := range reqs.RequiredClaims {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		want := reqs.RequiredClaims[name]
+		got, ok := claims[name]
+		if !ok {
+			reasons = append(reasons, policy.Reason(fmt.Sprintf("ArkCI signature is missing required claim %q", name)))
+			continue
+		}
+		if fmt.Sprint(got) != want {
+			reasons = append(reasons, policy.Reason(fmt.Sprintf("ArkCI signature claim %q is %q, expected %q", name, fmt.Sprint(got), want)))
+		}
+	}
+
+	// An invalid maxTokenAge fails ValidateImageSecurityPolicy before images are checked.
+	maxAge, err := maxTokenAge(reqs)
+	if err != nil || maxAge == 0 {
+		return reasons
+	}
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return append(reasons, policy.Reason("ArkCI signature is missing required claim \"iat\""))
+	}
+	if age := now.Sub(time.Unix(int64(iat), 0)); age > maxAge {
+		reasons = append(reasons, policy.Reason(fmt.Sprintf("ArkCI signature was issued %s ago, exceeding max token age %s", age.Round(time.Second), maxAge)))
+	}
+	return reasons
+}
+
+// maxTokenAge returns the maxTokenAge of reqs, 0 if it is not set, or an error if it is
+// not a positive duration.
+func maxTokenAge(reqs v1beta1.ArkCISignatureRequirements) (time.Duration, error) {
+	if reqs.MaxTokenAge == "" {
+		return 0, nil
+	}
+	maxAge, err := time.ParseDuration(reqs.MaxTokenAge)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid ArkCI maxTokenAge %q", reqs.MaxTokenAge)
+	}
+	if maxAge <= 0 {
+		return 0, fmt.Errorf("invalid ArkCI maxTokenAge %q, expected a positive duration", reqs.MaxTokenAge)
+	}
+	return maxAge, nil
+}
+
+// vulnerabilityViolations returns the violations of the vulnerabilities of an image.
+func vulnerabilityViolations(ctx context.Context, now time.Time, isp v1beta1.ImageSecurityPolicy, image string, vulnz []metadata.Vulnerability) ([]policy.Violation, error) {
+	var violations []policy.Violation
+	maxSev := isp.Spec.PackageVulnerabilityRequirements.MaximumSeverity
+	if maxSev == "" {
+		maxSev = "CRITICAL"
+	}
+
+	maxNoFixSev := isp.Spec.PackageVulnerabilityRequirements.MaximumFixUnavailableSeverity
+	if maxNoFixSev == "" {
+		maxNoFixSev = "ALLOW_ALL"
+	}
+	unknownSev, err := treatUnknownSeverityAs(isp.Spec.PackageVulnerabilityRequirements)
+	if err != nil {
+		return violations, err
+	}
+
+	var counted []metadata.Vulnerability
+	for _, v := range vulnz {
+		// First, check if the vulnerability is whitelisted
+		if vulnerabilityInWhitelist(isp, v) {
+			continue
+		}
+		counted = append(counted, v)
+
+		// Allow operators to set a higher threshold for CVE's that have no fix available.
+		if !v.HasFixAvailable {
+			ok, err := severityWithinThreshold(maxNoFixSev, v.CanonicalSeverity(), unknownSev)
+			if err != nil {
+				return violations, err
+			}
+			if ok {
+				continue
+			}
+			violations = append(violations, Violation{
+				vulnerability: v,
+				vType:         policy.FixUnavailableViolation,
+				reason:        FixUnavailableReason(image, v, isp),
+			})
+			continue
+		}
+		ok, err := severityWithinThreshold(maxSev, v.CanonicalSeverity(), unknownSev)
+		if err != nil {
+			return violations, err
+		}
+		if ok {
+			continue
+		}
+		// Tolerate CVE's whose fix is more recent than the grace period, if known.
+		if days := isp.Spec.PackageVulnerabilityRequirements.MaximumFixAvailableDays; days > 0 && v.FixAvailableSince != nil {
+			age := int(now.Sub(*v.FixAvailableSince).Hours() / 24)
+			if age <= days {
+				glog.Infof("tolerating CVE %q in %q, whose fix is available for %d days", v.CVE, image, age)
+				continue
+			}
+			violations = append(violations, Violation{
+				vulnerability: v,
+				vType:         policy.SeverityViolation,
+				reason:        FixAgeReason(image, v, age, isp),
+			})
+			continue
+		}
+		violations = append(violations, Violation{
+			vulnerability: v,
+			vType:         policy.SeverityViolation,
+			reason:        SeverityReason(image, v, isp),
+		})
+	}
+
+	countViolations, err := severityCountViolations(isp, image, counted)
+	if err != nil {
+		return violations, err
+	}
+	violations = append(violations, countViolations...)
+
+	// Notify the policy of CVEs which gained a fix since the image was last reviewed,
+	// as they are no longer covered by MaximumFixUnavailableSeverity
+	if recordsEvents(ctx) {
+		for _, v := range fixes.observe(isp, image, vulnz) {
+			ok, err := severityWithinThreshold(maxSev, v.CanonicalSeverity(), unknownSev)
+			if err != nil {
+				return violations, err
+			}
+			recordFixAvailable(isp, image, v, maxSev, !ok)
+		}
+	}
+	return violations, nil
+}
+
+// arkSigningMethods maps the JWT algorithms allowed for ArkCI signatures to KMS signing methods.
+var arkSigningMethods = map[string]*gcpjwt.SigningMethodKMS{
+	jwt.SigningMethodRS256.Alg(): gcpjwt.SigningMethodKMSRS256,
+	jwt.SigningMethodES256.Alg(): gcpjwt.SigningMethodKMSES256,
+	jwt.SigningMethodPS256.Alg(): gcpjwt.SigningMethodKMSPS256,
+}
+
+// ImageWhitelisted returns true if image is in the imageWhitelist of isp.
+func ImageWhitelisted(isp v1beta1.ImageSecurityPolicy, image string) bool {
+	return imageInWhitelist(isp, image)
+}
+
 func imageInWhitelist(isp v1beta1.ImageSecurityPolicy, image string) bool {
 	for _, i := range isp.Spec.ImageWhitelist {
-		if i == image {
+		if i == image || reference.Equal(i, image) {
 			return true
 		}
 	}
 	return false
 }
 
-func imageInGCR(projectID string, image string) bool {
-	prefixes := []string{
-		"gcr.io",
-		"asia.gcr.io",
-		"eu.gcr.io",
-		"us.gcr.io",
+// dockerHubAllowed returns false if the image is hosted on Docker Hub and the policy does not allow it.
+func dockerHubAllowed(isp v1beta1.ImageSecurityPolicy, image string) bool {
+	setting := isp.Spec.DockerHubImages
+	if setting == "" || setting == constants.DockerHubAllow {
+		return true
+	}
+	ref, err := reference.Parse(image)
+	if err != nil || !ref.IsDockerHub() {
+		return true
 	}
+	return setting == constants.DockerHubOfficialOnly && ref.IsDockerHubOfficial()
+}
 
-	for _, p := range prefixes {
-		fullPrefix := fmt.Sprintf("%s/%s/", p, projectID)
-		if strings.HasPrefix(image, fullPrefix) {
+func cveInWhitelist(isp v1beta1.ImageSecurityPolicy, cve string) bool {
+	for _, w := range isp.Spec.PackageVulnerabilityRequirements.WhitelistCVEs {
+		if w == cve {
 			return true
 		}
 	}
+	return false
+}
 
+// vulnerabilityInWhitelist returns true if the CVE or the vulnerable package version is whitelisted.
+func vulnerabilityInWhitelist(isp v1beta1.ImageSecurityPolicy, v metadata.Vulnerability) bool {
+	if cveInWhitelist(isp, v.CVE) {
+		return true
+	}
+	for _, w := range isp.Spec.PackageVulnerabilityRequirements.WhitelistPackages {
+		if w.Package == "" || w.Package != v.Package {
+			continue
+		}
+		if len(w.CVEs) > 0 && !stringInSlice(w.CVEs, v.CVE) {
+			continue
+		}
+		if w.MaxVersion != "" && (v.Version == "" || util.CompareVersions(v.Version, w.MaxVersion) > 0) {
+			continue
+		}
+		return true
+	}
 	return false
 }
 
-func cveInWhitelist(isp v1beta1.ImageSecurityPolicy, cve string) bool {
-	for _, w := range isp.Spec.PackageVulnerabilityRequirements.WhitelistCVEs {
-		if w == cve {
+// WaivedCVEs returns the CVEs of vulnz whitelisted by isp, by CVE or package.
+func WaivedCVEs(isp v1beta1.ImageSecurityPolicy, vulnz []metadata.Vulnerability) []string {
+	var cves []string
+	for _, v := range vulnz {
+		if vulnerabilityInWhitelist(isp, v) && !stringInSlice(cves, v.CVE) {
+			cves = append(cves, v.CVE)
+		}
+	}
+	return cves
+}
+
+func stringInSlice(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
 			return true
 		}
 	}
 	return false
 }
 
-func severityWithinThreshold(maxSeverity string, severity string) (bool, error) {
+// treatUnknownSeverityAs returns the upper-cased treatUnknownSeverityAs of reqs,
+// BLOCK if it is not set, or an error if it is neither ALLOW, BLOCK nor a severity.
+func treatUnknownSeverityAs(reqs v1beta1.PackageVulnerabilityRequirements) (string, error) {
+	unknownAs := strings.ToUpper(reqs.TreatUnknownSeverityAs)
+	switch unknownAs {
+	case "":
+		return constants.UnknownSeverityBlock, nil
+	case constants.UnknownSeverityAllow, constants.UnknownSeverityBlock:
+		return unknownAs, nil
+	}
+	if _, ok := vulnerability.Severity_value[unknownAs]; !ok || unknownAs == vulnerability.Severity_SEVERITY_UNSPECIFIED.String() {
+		return "", fmt.Errorf("invalid treatUnknownSeverityAs: %s", reqs.TreatUnknownSeverityAs)
+	}
+	return unknownAs, nil
+}
+
+// severityWithinThreshold returns true if severity, on the Grafeas scale, doesn't
+// exceed maxSeverity. Unknown severities are evaluated as set by unknownAs,
+// as returned by treatUnknownSeverityAs.
+func severityWithinThreshold(maxSeverity string, severity string, unknownAs string) (bool, error) {
 	if maxSeverity == constants.BlockAll {
 		return false, nil
 	}
@@ -320,12 +835,56 @@ func severityWithinThreshold(maxSeverity string, severity string) (bool, error)
 	if _, ok := vulnerability.Severity_value[maxSeverity]; !ok {
 		return false, fmt.Errorf("invalid max severity level: %s", maxSeverity)
 	}
-	if _, ok := vulnerability.Severity_value[severity]; !ok {
-		return false, fmt.Errorf("invalid severity level: %s", severity)
+	if severity == vulnerability.Severity_SEVERITY_UNSPECIFIED.String() {
+		switch unknownAs {
+		case constants.UnknownSeverityAllow:
+			return true, nil
+		case constants.UnknownSeverityBlock:
+			return false, nil
+		}
+		severity = unknownAs
 	}
 	return vulnerability.Severity_value[severity] <= vulnerability.Severity_value[maxSeverity], nil
 }
 
+// severityCountViolations returns a violation for each severity with more CVEs than its
+// maximum count. CVEs of an unknown severity are counted as treatUnknownSeverityAs, if set to a severity.
+func severityCountViolations(isp v1beta1.ImageSecurityPolicy, image string, vulnz []metadata.Vulnerability) ([]policy.Violation, error) {
+	reqs := isp.Spec.PackageVulnerabilityRequirements
+	if len(reqs.MaximumCounts) == 0 {
+		return nil, nil
+	}
+	var severities []string
+	for s := range reqs.MaximumCounts {
+		if _, ok := vulnerability.Severity_value[s]; !ok || s == vulnerability.Severity_SEVERITY_UNSPECIFIED.String() {
+			return nil, fmt.Errorf("invalid severity of maximumCounts: %s", s)
+		}
+		severities = append(severities, s)
+	}
+	sort.Slice(severities, func(i, j int) bool {
+		return vulnerability.Severity_value[severities[i]] > vulnerability.Severity_value[severities[j]]
+	})
+	unknownAs, err := treatUnknownSeverityAs(reqs)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, v := range vulnz {
+		s := v.CanonicalSeverity()
+		if s == vulnerability.Severity_SEVERITY_UNSPECIFIED.String() {
+			s = unknownAs
+		}
+		counts[s]++
+	}
+	var violations []policy.Violation
+	for _, s := range severities {
+		if max := reqs.MaximumCounts[s]; counts[s] > max {
+			violations = append(violations, NewViolation(nil, policy.SeverityCountViolation, SeverityCountReason(image, s, counts[s], max)))
+		}
+	}
+	return violations, nil
+}
+
 type Attestor struct {
 	Name       string
 	PublicKeys []*AttestorPublicKey
@@ -334,6 +893,8 @@ type Attestor struct {
 type AttestorPublicKey struct {
 	ID         string // ID = Fingerprint
 	AsciiArmor string
+	// PkixPem is set instead of AsciiArmor for PKIX public keys, which verify generic signed attestations.
+	PkixPem string
 }
 
 type AttestorFetcher interface {
@@ -344,13 +905,19 @@ type binauthzAttestorFetcher struct {
 	client binauthz.Client
 }
 
+// NewAttestorFetcher returns a fetcher of the Attestor resources of the cluster and of
+// Binary Authorization attestors. Only Attestor resources are used if Binary Authorization
+// is not available, e.g. without Google credentials.
 func NewAttestorFetcher() (AttestorFetcher, error) {
 	client, err := binauthz.New()
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to create a binauthz client")
+		glog.Warningf("failed to create a binauthz client, only Attestor resources are used: %v", err)
+		return &clusterAttestorFetcher{}, nil
 	}
-	return &binauthzAttestorFetcher{
-		client: client,
+	return &clusterAttestorFetcher{
+		binauthz: &binauthzAttestorFetcher{
+			client: client,
+		},
 	}, nil
 }
 
@@ -365,10 +932,14 @@ func (f *binauthzAttestorFetcher) GetAttestor(name string) (*Attestor, error) {
 
 	pubKeys := []*AttestorPublicKey{}
 	for _, pubKey := range a.UserOwnedGrafeasNote.PublicKeys {
-		pubKeys = append(pubKeys, &AttestorPublicKey{
+		k := &AttestorPublicKey{
 			ID:         pubKey.Id,
 			AsciiArmor: pubKey.AsciiArmoredPgpPublicKey,
-		})
+		}
+		if pubKey.PkixPublicKey != nil {
+			k.PkixPem = pubKey.PkixPublicKey.PublicKeyPem
+		}
+		pubKeys = append(pubKeys, k)
 	}
 
 	attestor := &Attestor{
@@ -389,7 +960,7 @@ func hasRequiredAttestation(image string, attestor *Attestor, attestations []met
 	for _, attestation := range attestations {
 		for _, pubKey := range attestor.PublicKeys {
 			if pubKey.ID == attestation.KeyID {
-				if err := sig.VerifyAttestationSignature(pubKey.AsciiArmor, attestation.Signature); err == nil {
+				if err := verifyAttestorSignature(sig, pubKey, attestation); err == nil {
 					verified = true
 					break
 				}
@@ -399,3 +970,18 @@ func hasRequiredAttestation(image string, attestor *Attestor, attestations []met
 	}
 	return verified, nil
 }
+
+// verifyAttestorSignature verifies a PGP attestation, or a generic signed attestation with a PKIX key.
+func verifyAttestorSignature(sig *container.AtomicContainerSig, pubKey *AttestorPublicKey, a metadata.PGPAttestation) error {
+	if a.Payload == "" {
+		return sig.VerifyAttestationSignature(pubKey.AsciiArmor, a.Signature)
+	}
+	if pubKey.PkixPem == "" {
+		return fmt.Errorf("key %s is not a PKIX public key", pubKey.ID)
+	}
+	signature, err := base64.StdEncoding.DecodeString(a.Signature)
+	if err != nil {
+		return err
+	}
+	return sig.VerifyGenericAttestationSignature(pubKey.PkixPem, []byte(a.Payload), signature)
+}
@@ -17,14 +17,46 @@ limitations under the License.
 package securitypolicy
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"errors"
+	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	cav1 "google.golang.org/api/containeranalysis/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned/fake"
+	listers "github.com/grafeas/kritis/pkg/kritis/client/listers/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/container"
+	"github.com/grafeas/kritis/pkg/kritis/crd/informers"
+	kubernetesutil "github.com/grafeas/kritis/pkg/kritis/kubernetes"
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
 	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/provenance"
+	"github.com/grafeas/kritis/pkg/kritis/sbom"
 	"github.com/grafeas/kritis/pkg/kritis/testutil"
 )
 
@@ -43,21 +75,22 @@ func Test_ValidISP(t *testing.T) {
 	}{
 		{"ok", "MEDIUM", "MEDIUM", false},
 		{"bad maxSeverity", "!", "MEDIUM", true},
-		{"bad severity", "MEDIUM", "?", true},
+		{"unknown severity", "MEDIUM", "?", false},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			isp := v1beta1.ImageSecurityPolicy{
 				Spec: v1beta1.ImageSecurityPolicySpec{
 					PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{
-						MaximumSeverity: test.maxSeverity,
+						MaximumSeverity:        test.maxSeverity,
+						TreatUnknownSeverityAs: constants.UnknownSeverityAllow,
 					},
 				},
 			}
 			mc := &testutil.MockMetadataClient{
 				Vulnz: []metadata.Vulnerability{{CVE: "m", Severity: test.cveSeverity, HasFixAvailable: true}},
 			}
-			violations, err := ValidateImageSecurityPolicy(
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(),
 				isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
 			if test.expectErr {
 				if err == nil {
@@ -75,6 +108,85 @@ func Test_ValidISP(t *testing.T) {
 	}
 }
 
+func Test_UnknownSeverity(t *testing.T) {
+	var tests = []struct {
+		name        string
+		unknownAs   string
+		cveSeverity string
+		violates    bool
+		shouldErr   bool
+	}{
+		{"blocked by default", "", "SEVERITY_UNSPECIFIED", true, false},
+		{"scanner severity blocked by default", "", "Unknown", true, false},
+		{"allow", constants.UnknownSeverityAllow, "SEVERITY_UNSPECIFIED", false, false},
+		{"allow is case-insensitive", "allow", "SEVERITY_UNSPECIFIED", false, false},
+		{"block", constants.UnknownSeverityBlock, "Untriaged", true, false},
+		{"as severity above threshold", "HIGH", "SEVERITY_UNSPECIFIED", true, false},
+		{"as severity within threshold", "low", "SEVERITY_UNSPECIFIED", false, false},
+		{"invalid", "SEVERE", "SEVERITY_UNSPECIFIED", false, true},
+		{"invalid without unknown severities", "SEVERE", "LOW", false, true},
+		{"unspecified is not a severity", "SEVERITY_UNSPECIFIED", "SEVERITY_UNSPECIFIED", false, true},
+		{"known severities are not affected", constants.UnknownSeverityBlock, "LOW", false, false},
+		{"vendor severities are mapped", "", "Important", true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isp := v1beta1.ImageSecurityPolicy{
+				Spec: v1beta1.ImageSecurityPolicySpec{
+					PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{
+						MaximumSeverity:        "MEDIUM",
+						TreatUnknownSeverityAs: test.unknownAs,
+					},
+				},
+			}
+			mc := &testutil.MockMetadataClient{
+				Vulnz: []metadata.Vulnerability{{CVE: "c", Severity: test.cveSeverity, HasFixAvailable: true}},
+			}
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+			testutil.CheckError(t, test.shouldErr, err)
+			if (len(violations) != 0) != test.violates {
+				t.Errorf("expected violations: %t, got %v", test.violates, violations)
+			}
+		})
+	}
+}
+
+func Test_CVSSSeverity(t *testing.T) {
+	var tests = []struct {
+		name     string
+		vuln     metadata.Vulnerability
+		violates bool
+	}{
+		{"score above threshold", metadata.Vulnerability{Severity: "Unknown", CVSSScore: 7.5}, true},
+		{"score within threshold", metadata.Vulnerability{Severity: "Unknown", CVSSScore: 3.1}, false},
+		{"vector above threshold", metadata.Vulnerability{CVSSVector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}, true},
+		{"severity takes precedence", metadata.Vulnerability{Severity: "LOW", CVSSScore: 9.8}, false},
+		{"unknown as set by policy", metadata.Vulnerability{Severity: "Unknown", CVSSVector: "AV:N/AC:L/Au:N/C:P/I:P/A:P"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isp := v1beta1.ImageSecurityPolicy{
+				Spec: v1beta1.ImageSecurityPolicySpec{
+					PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{
+						MaximumSeverity:        "MEDIUM",
+						TreatUnknownSeverityAs: constants.UnknownSeverityBlock,
+					},
+				},
+			}
+			test.vuln.CVE = "c"
+			test.vuln.HasFixAvailable = true
+			mc := &testutil.MockMetadataClient{Vulnz: []metadata.Vulnerability{test.vuln}}
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (len(violations) != 0) != test.violates {
+				t.Errorf("expected violations: %t, got %v", test.violates, violations)
+			}
+		})
+	}
+}
+
 func Test_UnqualifiedImage(t *testing.T) {
 	isp := v1beta1.ImageSecurityPolicy{
 		Spec: v1beta1.ImageSecurityPolicySpec{
@@ -83,7 +195,7 @@ func Test_UnqualifiedImage(t *testing.T) {
 			},
 		},
 	}
-	violations, err := ValidateImageSecurityPolicy(isp, "", &testutil.MockMetadataClient{}, returnNilAttestorFetcher{})
+	violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, "", &testutil.MockMetadataClient{}, returnNilAttestorFetcher{})
 	expected := []policy.Violation{}
 	expected = append(expected, Violation{
 		vType:  policy.UnqualifiedImageViolation,
@@ -92,6 +204,565 @@ func Test_UnqualifiedImage(t *testing.T) {
 	testutil.CheckErrorAndDeepEqual(t, false, err, expected, violations)
 }
 
+func Test_TagNotPinned(t *testing.T) {
+	isp := v1beta1.ImageSecurityPolicy{
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			RequireImageDigest: true,
+		},
+	}
+	image := "gcr.io/kritis-project/image:latest"
+	violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, image, &testutil.MockMetadataClient{}, returnNilAttestorFetcher{})
+	expected := []policy.Violation{
+		Violation{
+			vType:  policy.TagNotPinnedViolation,
+			reason: TagNotPinnedReason(image),
+		},
+	}
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected, violations)
+
+	// Images pinned by digest pass
+	violations, err = ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, &testutil.MockMetadataClient{}, returnNilAttestorFetcher{})
+	testutil.CheckErrorAndDeepEqual(t, false, err, 0, len(violations))
+}
+
+func Test_DockerHubImages(t *testing.T) {
+	const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	var tests = []struct {
+		name     string
+		setting  string
+		image    string
+		rejected bool
+	}{
+		{"allow by default", "", "bitnami/nginx@" + digest, false},
+		{"allow", constants.DockerHubAllow, "bitnami/nginx@" + digest, false},
+		{"official only allows official", constants.DockerHubOfficialOnly, "nginx@" + digest, false},
+		{"official only rejects others", constants.DockerHubOfficialOnly, "docker.io/bitnami/nginx@" + digest, true},
+		{"deny rejects official", constants.DockerHubDeny, "index.docker.io/library/nginx@" + digest, true},
+		{"deny allows other registries", constants.DockerHubDeny, testutil.QualifiedImage, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isp := v1beta1.ImageSecurityPolicy{
+				Spec: v1beta1.ImageSecurityPolicySpec{
+					DockerHubImages: test.setting,
+				},
+			}
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, test.image, &testutil.MockMetadataClient{}, returnNilAttestorFetcher{})
+			var expected []policy.Violation
+			if test.rejected {
+				expected = []policy.Violation{
+					Violation{
+						vType:  policy.DockerHubViolation,
+						reason: DockerHubReason(test.image, isp),
+					},
+				}
+			}
+			testutil.CheckErrorAndDeepEqual(t, false, err, expected, violations)
+		})
+	}
+}
+
+func Test_RequireSBOM(t *testing.T) {
+	var tests = []struct {
+		name      string
+		formats   []string
+		found     string
+		findErr   error
+		rejected  bool
+		shouldErr bool
+	}{
+		{"not required", nil, "", nil, false, false},
+		{"found", []string{constants.SBOMFormatSPDX}, constants.SBOMFormatSPDX, nil, false, false},
+		{"missing", []string{constants.SBOMFormatSPDX, constants.SBOMFormatCycloneDX}, "", nil, true, false},
+		{"invalid format", []string{"SWID"}, "", nil, false, true},
+		{"lookup error", []string{constants.SBOMFormatCycloneDX}, "", errors.New("registry unavailable"), false, true},
+	}
+	original := findSBOM
+	defer func() { findSBOM = original }()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			called := false
+			findSBOM = func(image string, occs []*metadata.OccurenceV1, accepted []string) (string, error) {
+				called = true
+				return test.found, test.findErr
+			}
+			isp := v1beta1.ImageSecurityPolicy{
+				Spec: v1beta1.ImageSecurityPolicySpec{
+					RequireSBOM: test.formats,
+				},
+			}
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, &testutil.MockMetadataClient{}, returnNilAttestorFetcher{})
+			var expected []policy.Violation
+			if test.rejected {
+				expected = []policy.Violation{
+					NewViolation(nil, policy.MissingSBOMViolation, MissingSBOMReason(testutil.QualifiedImage, isp)),
+				}
+			}
+			if test.shouldErr {
+				testutil.CheckError(t, true, err)
+				return
+			}
+			testutil.CheckErrorAndDeepEqual(t, false, err, expected, violations)
+			if called != (len(test.formats) > 0) {
+				t.Errorf("expected SBOM lookup only when required, called: %v", called)
+			}
+		})
+	}
+}
+
+func Test_AllowedBaseImages(t *testing.T) {
+	const digest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	derived := []*metadata.OccurenceV1{
+		{Kind: "IMAGE", NoteName: "projects/goog-analysis/notes/distroless-base", Image: &cav1.ImageOccurrence{BaseResourceUrl: "https://gcr.io/distroless/base@" + digest}},
+	}
+	annotated := map[string]string{
+		baseNameAnnotation:   "docker.io/library/alpine:3.16",
+		baseDigestAnnotation: digest,
+	}
+	var tests = []struct {
+		name        string
+		allowed     []string
+		occs        []*metadata.OccurenceV1
+		annotations map[string]string
+		rejected    bool
+	}{
+		{"not required", nil, nil, nil, false},
+		{"occurrence repository", []string{"gcr.io/distroless/base"}, derived, nil, false},
+		{"occurrence digest", []string{"gcr.io/distroless/base@" + digest}, derived, nil, false},
+		{"occurrence other tag", []string{"gcr.io/distroless/base:debug"}, derived, nil, true},
+		{"occurrence other repository", []string{"gcr.io/distroless/static"}, derived, nil, true},
+		{"annotation shorthand", []string{"alpine"}, nil, annotated, false},
+		{"annotation tag", []string{"alpine:3.16"}, nil, annotated, false},
+		{"annotation digest", []string{"alpine@" + digest}, nil, annotated, false},
+		{"annotation other tag", []string{"alpine:3.17"}, nil, annotated, true},
+		{"occurrences before annotations", []string{"alpine"}, derived, annotated, true},
+		{"unknown base image", []string{"alpine"}, nil, nil, true},
+	}
+	original := imageManifest
+	defer func() { imageManifest = original }()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			imageManifest = func(image string) (*v1.Manifest, error) {
+				return &v1.Manifest{Annotations: test.annotations}, nil
+			}
+			isp := v1beta1.ImageSecurityPolicy{
+				Spec: v1beta1.ImageSecurityPolicySpec{
+					AllowedBaseImages: test.allowed,
+				},
+			}
+			mc := &testutil.MockMetadataClient{OccurrencesV1: test.occs}
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+			testutil.CheckError(t, false, err)
+			if test.rejected != (len(violations) == 1 && violations[0].Type() == policy.DisallowedBaseImageViolation) {
+				t.Errorf("expected rejected: %v, got violations: %v", test.rejected, violations)
+			}
+		})
+	}
+
+	imageManifest = func(image string) (*v1.Manifest, error) {
+		return nil, errors.New("registry unavailable")
+	}
+	isp := v1beta1.ImageSecurityPolicy{
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			AllowedBaseImages: []string{"alpine"},
+		},
+	}
+	_, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, &testutil.MockMetadataClient{}, returnNilAttestorFetcher{})
+	testutil.CheckError(t, true, err)
+}
+
+func Test_ProvenanceRequirements(t *testing.T) {
+	built := []*metadata.OccurenceV1{
+		{Kind: "BUILD", NoteName: "projects/my-project/notes/build", Build: &cav1.BuildOccurrence{IntotoStatement: &cav1.InTotoStatement{
+			SlsaProvenanceZeroTwo: &cav1.SlsaProvenanceZeroTwo{
+				Builder: &cav1.GrafeasV1SlsaProvenanceZeroTwoSlsaBuilder{Id: "https://cloudbuild.googleapis.com/GoogleHostedWorker"},
+				Invocation: &cav1.GrafeasV1SlsaProvenanceZeroTwoSlsaInvocation{
+					ConfigSource: &cav1.GrafeasV1SlsaProvenanceZeroTwoSlsaConfigSource{Uri: "git+https://github.com/my-org/app@refs/heads/main"},
+				},
+			},
+		}}},
+	}
+	signed := []provenance.Provenance{
+		{BuilderID: "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_container_slsa3.yml@refs/tags/v1.4.0", SourceURIs: []string{"git+https://github.com/my-org/lib"}},
+	}
+	googleBuilder := v1beta1.TrustedBuilder{ID: "https://cloudbuild.googleapis.com/*", SLSALevel: 3}
+	githubBuilder := v1beta1.TrustedBuilder{ID: "https://github.com/slsa-framework/slsa-github-generator/*"}
+	var tests = []struct {
+		name     string
+		reqs     v1beta1.ProvenanceRequirements
+		occs     []*metadata.OccurenceV1
+		signed   []provenance.Provenance
+		rejected bool
+	}{
+		{"not required", v1beta1.ProvenanceRequirements{}, nil, nil, false},
+		{"no provenance", v1beta1.ProvenanceRequirements{SLSALevel: 1}, nil, nil, true},
+		{"any builder", v1beta1.ProvenanceRequirements{SLSALevel: 1}, built, nil, false},
+		{"trusted builder", v1beta1.ProvenanceRequirements{Builders: []v1beta1.TrustedBuilder{googleBuilder}, SLSALevel: 3}, built, nil, false},
+		{"untrusted builder", v1beta1.ProvenanceRequirements{Builders: []v1beta1.TrustedBuilder{githubBuilder}}, built, nil, true},
+		{"level too low", v1beta1.ProvenanceRequirements{Builders: []v1beta1.TrustedBuilder{googleBuilder, githubBuilder}, SLSALevel: 2}, nil, signed, true},
+		{"source pattern", v1beta1.ProvenanceRequirements{SourceURIs: []string{"https://github.com/my-org/*"}}, built, nil, false},
+		{"other source", v1beta1.ProvenanceRequirements{SourceURIs: []string{"https://github.com/other-org/*"}}, built, signed, true},
+		{"one provenance matches", v1beta1.ProvenanceRequirements{Builders: []v1beta1.TrustedBuilder{githubBuilder}, SourceURIs: []string{"*/my-org/lib"}}, built, signed, false},
+	}
+	original := cosignProvenance
+	defer func() { cosignProvenance = original }()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cosignProvenance = func(image string, keys []provenance.Key) ([]provenance.Provenance, error) {
+				return test.signed, nil
+			}
+			isp := v1beta1.ImageSecurityPolicy{
+				Spec: v1beta1.ImageSecurityPolicySpec{
+					ProvenanceRequirements: test.reqs,
+				},
+			}
+			mc := &testutil.MockMetadataClient{OccurrencesV1: test.occs}
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+			testutil.CheckError(t, false, err)
+			if test.rejected != (len(violations) == 1 && violations[0].Type() == policy.ProvenanceViolation) {
+				t.Errorf("expected rejected: %v, got violations: %v", test.rejected, violations)
+			}
+		})
+	}
+
+	isp := v1beta1.ImageSecurityPolicy{
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			ProvenanceRequirements: v1beta1.ProvenanceRequirements{SLSALevel: 1, PublicKeys: []string{"not a key"}},
+		},
+	}
+	_, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, &testutil.MockMetadataClient{}, returnNilAttestorFetcher{})
+	testutil.CheckError(t, true, err)
+}
+
+func Test_PublisherWhitelist(t *testing.T) {
+	vendor := v1beta1.PublisherIdentity{Issuer: "https://token.actions.githubusercontent.com", Subject: "https://github.com/my-vendor/*"}
+	signed := []provenance.Identity{{Issuer: "https://token.actions.githubusercontent.com", Subject: "https://github.com/my-vendor/app/.github/workflows/release.yml@refs/tags/v1.0.0"}}
+	var tests = []struct {
+		name       string
+		publishers []v1beta1.PublisherIdentity
+		signed     []provenance.Identity
+		verifyErr  error
+		notarized  []provenance.Identity
+		rejected   bool
+	}{
+		{"no whitelist", nil, signed, nil, nil, true},
+		{"whitelisted publisher", []v1beta1.PublisherIdentity{vendor}, signed, nil, nil, false},
+		{"other issuer", []v1beta1.PublisherIdentity{{Issuer: "https://accounts.google.com", Subject: vendor.Subject}}, signed, nil, nil, true},
+		{"other subject", []v1beta1.PublisherIdentity{{Issuer: vendor.Issuer, Subject: "https://github.com/other-vendor/*"}}, signed, nil, nil, true},
+		{"not signed", []v1beta1.PublisherIdentity{vendor}, nil, nil, nil, true},
+		{"verification failure", []v1beta1.PublisherIdentity{vendor}, signed, errors.New("no Sigstore trust roots are configured"), nil, true},
+		{"notation signature", []v1beta1.PublisherIdentity{{Issuer: "CN=Vendor CA,O=Vendor", Subject: "CN=*,O=Vendor"}}, nil, errors.New("no Sigstore trust roots are configured"), []provenance.Identity{{Issuer: "CN=Vendor CA,O=Vendor", Subject: "CN=release,O=Vendor"}}, false},
+	}
+	original, originalNotation := cosignIdentities, notationIdentities
+	defer func() { cosignIdentities, notationIdentities = original, originalNotation }()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cosignIdentities = func(image string) ([]provenance.Identity, error) {
+				return test.signed, test.verifyErr
+			}
+			notationIdentities = func(image string) ([]provenance.Identity, error) {
+				return test.notarized, nil
+			}
+			isp := v1beta1.ImageSecurityPolicy{
+				Spec: v1beta1.ImageSecurityPolicySpec{
+					PublisherWhitelist:               test.publishers,
+					PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{MaximumSeverity: "MEDIUM"},
+				},
+			}
+			mc := &testutil.MockMetadataClient{Vulnz: []metadata.Vulnerability{{CVE: "CVE-2022-1", Severity: "CRITICAL", HasFixAvailable: true}}}
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+			testutil.CheckError(t, false, err)
+			if test.rejected != (len(violations) == 1 && violations[0].Type() == policy.SeverityViolation) {
+				t.Errorf("expected rejected: %v, got violations: %v", test.rejected, violations)
+			}
+		})
+	}
+}
+
+func Test_PlatformValidation(t *testing.T) {
+	arm64 := "gcr.io/image/digest@sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	index := &v1.IndexManifest{
+		MediaType: types.OCIImageIndex,
+		Manifests: []v1.Descriptor{
+			{Digest: v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("1", 64)}, Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}},
+			{Digest: v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("2", 64)}, Platform: &v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}},
+			{Digest: v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("3", 64)}, Platform: &v1.Platform{OS: "unknown", Architecture: "unknown"}},
+		},
+	}
+	vuln := metadata.Vulnerability{CVE: "CVE-2022-1", Severity: "CRITICAL", HasFixAvailable: true}
+	mc := &testutil.MockMetadataClient{Images: map[string]testutil.MockImage{
+		arm64: {Vulnz: []metadata.Vulnerability{vuln}},
+	}}
+	armPod := &corev1.Pod{Spec: corev1.PodSpec{NodeSelector: map[string]string{"kubernetes.io/arch": "arm64"}}}
+	amdPod := &corev1.Pod{Spec: corev1.PodSpec{NodeSelector: map[string]string{"kubernetes.io/arch": "amd64"}}}
+	var tests = []struct {
+		name       string
+		validation string
+		index      *v1.IndexManifest
+		pod        *corev1.Pod
+		rejected   bool
+		shouldErr  bool
+	}{
+		{"disabled", "", index, nil, false, false},
+		{"all platforms", constants.PlatformValidationAll, index, amdPod, true, false},
+		{"selected platform", constants.PlatformValidationNodeSelector, index, armPod, true, false},
+		{"other platform", constants.PlatformValidationNodeSelector, index, amdPod, false, false},
+		{"no node selector", constants.PlatformValidationNodeSelector, index, nil, true, false},
+		{"no image for platform", constants.PlatformValidationNodeSelector, index, &corev1.Pod{Spec: corev1.PodSpec{NodeSelector: map[string]string{"kubernetes.io/arch": "s390x"}}}, false, true},
+		{"single platform", constants.PlatformValidationAll, nil, nil, false, false},
+		{"invalid", "SOME", index, nil, false, true},
+	}
+	original := indexManifest
+	defer func() { indexManifest = original }()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			indexManifest = func(image string) (*v1.IndexManifest, error) {
+				return test.index, nil
+			}
+			isp := v1beta1.ImageSecurityPolicy{
+				Spec: v1beta1.ImageSecurityPolicySpec{
+					PlatformValidation:               test.validation,
+					PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{MaximumSeverity: "MEDIUM"},
+				},
+			}
+			ctx := context.Background()
+			if test.pod != nil {
+				ctx = WithPod(ctx, test.pod)
+			}
+			violations, err := ValidateImageSecurityPolicy(ctx, time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+			var expected []policy.Reason
+			if test.rejected {
+				expected = []policy.Reason{PlatformReason(SeverityReason(arm64, vuln, isp), "linux/arm64/v8")}
+			}
+			var reasons []policy.Reason
+			for _, v := range violations {
+				reasons = append(reasons, v.Reason())
+			}
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, expected, reasons)
+		})
+	}
+}
+
+func Test_IndexManifestCache(t *testing.T) {
+	original := fetchIndexManifest
+	defer func() {
+		fetchIndexManifest = original
+		cachedIndexes = map[string]*v1.IndexManifest{}
+	}()
+	fetches := 0
+	fetchIndexManifest = func(image string) (*v1.IndexManifest, error) {
+		fetches++
+		return nil, nil
+	}
+	// Images referenced by digest are fetched once, by tag every time
+	for _, image := range []string{testutil.QualifiedImage, testutil.QualifiedImage, "gcr.io/image/tag:1", "gcr.io/image/tag:1"} {
+		if _, err := indexManifest(image); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testutil.DeepEqual(t, 3, fetches)
+}
+
+// scanningFetcher returns no vulnerabilities until it was read a number of times.
+type scanningFetcher struct {
+	*testutil.MockMetadataClient
+	reads int
+}
+
+func (f *scanningFetcher) Vulnerabilities(ctx context.Context, image string) ([]metadata.Vulnerability, error) {
+	if f.reads--; f.reads >= 0 {
+		return nil, nil
+	}
+	return f.MockMetadataClient.Vulnerabilities(ctx, image)
+}
+
+func Test_RequireScanResults(t *testing.T) {
+	vulnz := []metadata.Vulnerability{{CVE: "CVE-2022-1", Severity: "LOW", HasFixAvailable: true}}
+	var tests = []struct {
+		name     string
+		spec     v1beta1.ImageSecurityPolicySpec
+		vulnz    []metadata.Vulnerability
+		reads    int
+		expected []policy.Reason
+	}{
+		{"not required", v1beta1.ImageSecurityPolicySpec{}, nil, 0, nil},
+		{"not scanned", v1beta1.ImageSecurityPolicySpec{RequireScanResults: true}, nil, 0, []policy.Reason{NotScannedReason(testutil.QualifiedImage)}},
+		{"scanned", v1beta1.ImageSecurityPolicySpec{RequireScanResults: true}, vulnz, 0, nil},
+		{"vulnerabilities skipped", v1beta1.ImageSecurityPolicySpec{RequireScanResults: true, SkipMetadataKinds: []string{constants.VulnerabilityMetadataKind}}, vulnz, 0, nil},
+		{"scan in progress", v1beta1.ImageSecurityPolicySpec{RequireScanResults: true}, vulnz, 1, []policy.Reason{NotScannedReason(testutil.QualifiedImage)}},
+		{"scan completes in time", v1beta1.ImageSecurityPolicySpec{RequireScanResults: true, ScanResultsTimeoutSeconds: 1}, vulnz, 3, nil},
+		{"scan times out", v1beta1.ImageSecurityPolicySpec{RequireScanResults: true, ScanResultsTimeoutSeconds: 1}, vulnz, 1000, []policy.Reason{NotScannedReason(testutil.QualifiedImage)}},
+	}
+	original := scanResultsInterval
+	defer func() { scanResultsInterval = original }()
+	scanResultsInterval = 10 * time.Millisecond
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := &scanningFetcher{MockMetadataClient: &testutil.MockMetadataClient{Vulnz: test.vulnz}, reads: test.reads}
+			isp := v1beta1.ImageSecurityPolicy{Spec: test.spec}
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, f, returnNilAttestorFetcher{})
+			var reasons []policy.Reason
+			for _, v := range violations {
+				reasons = append(reasons, v.Reason())
+			}
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, reasons)
+		})
+	}
+}
+
+func Test_ScanResultsWait(t *testing.T) {
+	vulnz := []metadata.Vulnerability{{CVE: "CVE-2022-1", Severity: "LOW", HasFixAvailable: true}}
+	deadline, cancel := context.WithTimeout(context.Background(), scanWaitReserve/2)
+	defer cancel()
+	var tests = []struct {
+		name     string
+		ctx      context.Context
+		vulnz    []metadata.Vulnerability
+		statuses []metadata.ScanStatus
+		scanned  bool
+	}{
+		{"scanned without vulnerabilities", context.Background(), nil, []metadata.ScanStatus{metadata.ScanStatusFinished}, true},
+		{"scan finishes without vulnerabilities", context.Background(), nil, []metadata.ScanStatus{metadata.ScanStatusScanning, metadata.ScanStatusFinished}, true},
+		{"scan failed", context.Background(), nil, []metadata.ScanStatus{metadata.ScanStatusFailed}, false},
+		{"waits until the deadline", deadline, vulnz, nil, false},
+		{"without waiting", WithoutWaiting(context.Background()), vulnz, nil, false},
+	}
+	original := scanResultsInterval
+	defer func() { scanResultsInterval = original }()
+	scanResultsInterval = 10 * time.Millisecond
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := &scanningFetcher{MockMetadataClient: &testutil.MockMetadataClient{Vulnz: test.vulnz, ScanStatuses: test.statuses}, reads: 1}
+			isp := v1beta1.ImageSecurityPolicy{Spec: v1beta1.ImageSecurityPolicySpec{RequireScanResults: true, ScanResultsTimeoutSeconds: 60}}
+			start := time.Now()
+			violations, err := ValidateImageSecurityPolicy(test.ctx, time.Now(), isp, testutil.QualifiedImage, f, returnNilAttestorFetcher{})
+			var expected []policy.Reason
+			if !test.scanned {
+				expected = []policy.Reason{NotScannedReason(testutil.QualifiedImage)}
+			}
+			var reasons []policy.Reason
+			for _, v := range violations {
+				reasons = append(reasons, v.Reason())
+			}
+			testutil.CheckErrorAndDeepEqual(t, false, err, expected, reasons)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("waited %s for the scan results", elapsed)
+			}
+		})
+	}
+}
+
+func Test_WaitForScan(t *testing.T) {
+	vulnz := []metadata.Vulnerability{{CVE: "CVE-2022-1", Severity: "CRITICAL", HasFixAvailable: true}}
+	scanning := []metadata.ScanStatus{metadata.ScanStatusScanning}
+	finishing := []metadata.ScanStatus{metadata.ScanStatusPending, metadata.ScanStatusScanning, metadata.ScanStatusFinished}
+	var tests = []struct {
+		name     string
+		wait     int
+		statuses []metadata.ScanStatus
+		expected []policy.ViolationType
+		reads    int
+	}{
+		{"no wait", 0, scanning, []policy.ViolationType{policy.SeverityViolation}, 0},
+		{"unknown status", 1, nil, []policy.ViolationType{policy.SeverityViolation}, 1},
+		{"scan finishes", 1, finishing, []policy.ViolationType{policy.SeverityViolation}, 3},
+		{"scan times out", 1, scanning, []policy.ViolationType{policy.NotScannedViolation}, -1},
+	}
+	original := scanResultsInterval
+	defer func() { scanResultsInterval = original }()
+	scanResultsInterval = 10 * time.Millisecond
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mc := &testutil.MockMetadataClient{Vulnz: vulnz, ScanStatuses: test.statuses}
+			isp := v1beta1.ImageSecurityPolicy{Spec: v1beta1.ImageSecurityPolicySpec{
+				WaitForScanSeconds:               test.wait,
+				PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{MaximumSeverity: "MEDIUM"},
+			}}
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+			var types []policy.ViolationType
+			for _, v := range violations {
+				types = append(types, v.Type())
+			}
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, types)
+			if test.reads >= 0 {
+				testutil.DeepEqual(t, test.reads, mc.Calls("ScanStatus", ""))
+			}
+		})
+	}
+
+	// Reviews don't wait past shortly before the deadline of their context
+	ctx, cancel := context.WithTimeout(context.Background(), scanWaitReserve/2)
+	defer cancel()
+	isp := v1beta1.ImageSecurityPolicy{Spec: v1beta1.ImageSecurityPolicySpec{WaitForScanSeconds: 60}}
+	waited, stillScanning, err := awaitScan(ctx, isp, testutil.QualifiedImage, &testutil.MockMetadataClient{ScanStatuses: scanning})
+	testutil.CheckErrorAndDeepEqual(t, false, err, []bool{false, false}, []bool{waited, stillScanning})
+}
+
+func Test_LicenseRequirements(t *testing.T) {
+	docs := []sbom.Document{
+		{Format: constants.SBOMFormatSPDX, Packages: []sbom.Package{
+			{Name: "openssl", Version: "3.0.2", License: "Apache-2.0"},
+			{Name: "bash", Version: "5.1", License: "GPL-3.0-or-later"},
+			{Name: "busybox"},
+		}},
+		{Format: constants.SBOMFormatCycloneDX, Packages: []sbom.Package{
+			{Name: "bash", Version: "5.1", License: "GPL-3.0-or-later"},
+			{Name: "app", Version: "2.0", License: "MIT OR GPL-3.0-only"},
+			{Name: "broken", License: "MIT AND"},
+		}},
+	}
+	var tests = []struct {
+		name     string
+		reqs     v1beta1.LicenseRequirements
+		expected []string
+	}{
+		{"no requirements", v1beta1.LicenseRequirements{}, nil},
+		{"deny list", v1beta1.LicenseRequirements{DeniedLicenses: []string{"GPL-3.0*"}}, []string{"bash", "broken"}},
+		{"deny list ignores case", v1beta1.LicenseRequirements{DeniedLicenses: []string{"gpl-3.0-or-later", "mit"}}, []string{"bash", "broken"}},
+		{"allow list", v1beta1.LicenseRequirements{AllowedLicenses: []string{"Apache-2.0", "MIT"}}, []string{"bash", "busybox", "broken"}},
+		{"allow and deny list", v1beta1.LicenseRequirements{AllowedLicenses: []string{"Apache-2.0", "MIT", "GPL-3.0-only"}, DeniedLicenses: []string{"MIT"}}, []string{"bash", "busybox", "broken"}},
+	}
+	original := loadSBOMs
+	defer func() { loadSBOMs = original }()
+	loadSBOMs = func(image string, occs []*metadata.OccurenceV1) ([]sbom.Document, error) {
+		return docs, nil
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isp := v1beta1.ImageSecurityPolicy{
+				Spec: v1beta1.ImageSecurityPolicySpec{
+					LicenseRequirements: test.reqs,
+				},
+			}
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, &testutil.MockMetadataClient{}, returnNilAttestorFetcher{})
+			testutil.CheckError(t, false, err)
+			var got []string
+			for _, v := range violations {
+				if v.Type() != policy.LicenseViolation {
+					t.Errorf("unexpected violation %s", v.Reason())
+				}
+				for _, pkg := range []string{"openssl", "bash", "busybox", "app", "broken"} {
+					if strings.HasPrefix(string(v.Reason()), "package \""+pkg) {
+						got = append(got, pkg)
+					}
+				}
+			}
+			testutil.DeepEqual(t, test.expected, got)
+		})
+	}
+
+	loadSBOMs = func(image string, occs []*metadata.OccurenceV1) ([]sbom.Document, error) {
+		return nil, errors.New("registry unavailable")
+	}
+	isp := v1beta1.ImageSecurityPolicy{
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			LicenseRequirements: v1beta1.LicenseRequirements{DeniedLicenses: []string{"GPL-3.0-only"}},
+		},
+	}
+	_, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, &testutil.MockMetadataClient{}, returnNilAttestorFetcher{})
+	testutil.CheckError(t, true, err)
+}
+
 func Test_SeverityThresholds(t *testing.T) {
 	mc := &testutil.MockMetadataClient{
 		Vulnz: []metadata.Vulnerability{
@@ -133,7 +804,7 @@ func Test_SeverityThresholds(t *testing.T) {
 					},
 				},
 			}
-			vs, err := ValidateImageSecurityPolicy(isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+			vs, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
 			if err != nil {
 				t.Errorf("%s: error validating isp: %v", test.name, err)
 			}
@@ -163,7 +834,7 @@ func Test_WhitelistedImage(t *testing.T) {
 	mc := &testutil.MockMetadataClient{
 		Vulnz: []metadata.Vulnerability{{CVE: "l", Severity: "LOW"}},
 	}
-	violations, err := ValidateImageSecurityPolicy(isp, "image", mc, returnNilAttestorFetcher{})
+	violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, "image", mc, returnNilAttestorFetcher{})
 	if err != nil {
 		t.Errorf("error validating isp: %v", err)
 	}
@@ -172,6 +843,22 @@ func Test_WhitelistedImage(t *testing.T) {
 	}
 }
 
+func Test_WhitelistedImageCanonical(t *testing.T) {
+	isp := v1beta1.ImageSecurityPolicy{
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			ImageWhitelist: []string{"nginx:1.15"},
+		},
+	}
+	for _, image := range []string{"nginx:1.15", "library/nginx:1.15", "docker.io/library/nginx:1.15"} {
+		if !imageInWhitelist(isp, image) {
+			t.Errorf("expected %q to be whitelisted", image)
+		}
+	}
+	if imageInWhitelist(isp, "nginx:1.16") {
+		t.Error("expected nginx:1.16 not to be whitelisted")
+	}
+}
+
 func Test_WhitelistedCVEAboveSeverityThreshold(t *testing.T) {
 	isp := v1beta1.ImageSecurityPolicy{
 		Spec: v1beta1.ImageSecurityPolicySpec{
@@ -187,7 +874,7 @@ func Test_WhitelistedCVEAboveSeverityThreshold(t *testing.T) {
 			{CVE: "c", Severity: "CRITICAL"},
 		},
 	}
-	violations, err := ValidateImageSecurityPolicy(isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+	violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
 	if err != nil {
 		t.Errorf("error validating isp: %v", err)
 	}
@@ -195,20 +882,84 @@ func Test_WhitelistedCVEAboveSeverityThreshold(t *testing.T) {
 		t.Errorf("got unexpected violations: %v", violations)
 	}
 }
-func Test_OnlyFixesNotAvailablePassWithWhitelist(t *testing.T) {
-	isp := v1beta1.ImageSecurityPolicy{
-		Spec: v1beta1.ImageSecurityPolicySpec{
-			PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{
-				MaximumSeverity:               "CRITICAL",
-				MaximumFixUnavailableSeverity: "BLOCK_ALL",
-				WhitelistCVEs:                 []string{"c"},
-			},
-		},
-	}
+func Test_WhitelistedPackages(t *testing.T) {
 	mc := &testutil.MockMetadataClient{
-		Vulnz: []metadata.Vulnerability{{CVE: "c", Severity: "CRITICAL", HasFixAvailable: true}},
+		Vulnz: []metadata.Vulnerability{
+			{CVE: "CVE-1", Severity: "CRITICAL", HasFixAvailable: true, Package: "openssl", Version: "1.1.1n-0+deb11u3"},
+			{CVE: "CVE-2", Severity: "CRITICAL", HasFixAvailable: true, Package: "openssl", Version: "1.1.1n-0+deb11u3"},
+			{CVE: "CVE-1", Severity: "CRITICAL", HasFixAvailable: true, Package: "libssl", Version: "1.1.1n-0+deb11u3"},
+			{CVE: "CVE-3", Severity: "CRITICAL", HasFixAvailable: true, Package: "zlib"},
+		},
 	}
-	violations, err := ValidateImageSecurityPolicy(isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+	var tests = []struct {
+		name      string
+		whitelist []v1beta1.PackageWhitelist
+		want      []string
+	}{
+		{"no whitelist", nil, []string{"openssl/CVE-1", "openssl/CVE-2", "libssl/CVE-1", "zlib/CVE-3"}},
+		{"package", []v1beta1.PackageWhitelist{{Package: "openssl"}}, []string{"libssl/CVE-1", "zlib/CVE-3"}},
+		{"package and CVE", []v1beta1.PackageWhitelist{{Package: "openssl", CVEs: []string{"CVE-1"}}}, []string{"openssl/CVE-2", "libssl/CVE-1", "zlib/CVE-3"}},
+		{"package up to version", []v1beta1.PackageWhitelist{{Package: "openssl", MaxVersion: "1.1.1n-0+deb11u3"}}, []string{"libssl/CVE-1", "zlib/CVE-3"}},
+		{"package version above max", []v1beta1.PackageWhitelist{{Package: "openssl", MaxVersion: "1.1.1n-0+deb11u2"}}, []string{"openssl/CVE-1", "openssl/CVE-2", "libssl/CVE-1", "zlib/CVE-3"}},
+		{"unknown version", []v1beta1.PackageWhitelist{{Package: "zlib", MaxVersion: "1.2.13"}}, []string{"openssl/CVE-1", "openssl/CVE-2", "libssl/CVE-1", "zlib/CVE-3"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isp := v1beta1.ImageSecurityPolicy{
+				Spec: v1beta1.ImageSecurityPolicySpec{
+					PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{
+						MaximumSeverity:   "HIGH",
+						WhitelistPackages: test.whitelist,
+					},
+				},
+			}
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+			testutil.CheckError(t, false, err)
+			got := []string{}
+			for _, v := range violations {
+				vuln := v.Details().(metadata.Vulnerability)
+				got = append(got, vuln.Package+"/"+vuln.CVE)
+			}
+			testutil.DeepEqual(t, test.want, got)
+		})
+	}
+}
+
+func Test_OnlyFixesNotAvailablePassWithWhitelist(t *testing.T) {
+	isp := v1beta1.ImageSecurityPolicy{
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{
+				MaximumSeverity:               "CRITICAL",
+				MaximumFixUnavailableSeverity: "BLOCK_ALL",
+				WhitelistCVEs:                 []string{"c"},
+			},
+		},
+	}
+	mc := &testutil.MockMetadataClient{
+		Vulnz: []metadata.Vulnerability{{CVE: "c", Severity: "CRITICAL", HasFixAvailable: true}},
+	}
+	violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+	if err != nil {
+		t.Errorf("error validating isp: %v", err)
+	}
+	if violations != nil {
+		t.Errorf("got unexpected violations: %v", violations)
+	}
+}
+
+func Test_SkipMetadataKinds(t *testing.T) {
+	isp := v1beta1.ImageSecurityPolicy{
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{
+				MaximumSeverity: "BLOCK_ALL",
+			},
+			SkipMetadataKinds: []string{"VULNERABILITY"},
+		},
+	}
+	mc := &testutil.MockMetadataClient{
+		Vulnz: []metadata.Vulnerability{{CVE: "c", Severity: "CRITICAL", HasFixAvailable: true}},
+	}
+	violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
 	if err != nil {
 		t.Errorf("error validating isp: %v", err)
 	}
@@ -217,6 +968,91 @@ func Test_OnlyFixesNotAvailablePassWithWhitelist(t *testing.T) {
 	}
 }
 
+func Test_FixAvailableTransition(t *testing.T) {
+	original := recordEvent
+	originalFixes := fixes
+	defer func() {
+		recordEvent = original
+		fixes = originalFixes
+	}()
+	var events []*corev1.Event
+	recordEvent = func(event *corev1.Event) error {
+		events = append(events, event)
+		return nil
+	}
+	fixes = newFixTracker()
+
+	isp := v1beta1.ImageSecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: "test"},
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{
+				MaximumSeverity:               "MEDIUM",
+				MaximumFixUnavailableSeverity: "ALLOW_ALL",
+			},
+		},
+	}
+	mc := &testutil.MockMetadataClient{
+		Vulnz: []metadata.Vulnerability{
+			{CVE: "c1", Severity: "HIGH", HasFixAvailable: false},
+			{CVE: "c2", Severity: "LOW", HasFixAvailable: false},
+		},
+	}
+	ctx := WithEvents(context.Background())
+	violations, err := ValidateImageSecurityPolicy(ctx, time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+	testutil.CheckErrorAndDeepEqual(t, false, err, 0, len(violations))
+	testutil.DeepEqual(t, 0, len(events))
+
+	// A fix ships for both CVEs
+	mc.Vulnz = []metadata.Vulnerability{
+		{CVE: "c1", Severity: "HIGH", HasFixAvailable: true},
+		{CVE: "c2", Severity: "LOW", HasFixAvailable: true},
+	}
+	// Simulations neither report nor consume transitions
+	violations, err = ValidateImageSecurityPolicy(WithDryRun(ctx), time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+	testutil.CheckErrorAndDeepEqual(t, false, err, 1, len(violations))
+	testutil.DeepEqual(t, 0, len(events))
+	// Neither do validations outside of the webhook and the cron job
+	violations, err = ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+	testutil.CheckErrorAndDeepEqual(t, false, err, 1, len(violations))
+	testutil.DeepEqual(t, 0, len(events))
+	violations, err = ValidateImageSecurityPolicy(ctx, time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{})
+	testutil.CheckErrorAndDeepEqual(t, false, err, 1, len(violations))
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	testutil.DeepEqual(t, []string{corev1.EventTypeWarning, corev1.EventTypeNormal}, []string{events[0].Type, events[1].Type})
+	testutil.DeepEqual(t, "isp", events[0].InvolvedObject.Name)
+	testutil.DeepEqual(t, FixAvailableEventReason, events[0].Reason)
+
+	// Transitions are only reported once
+	if _, err := ValidateImageSecurityPolicy(ctx, time.Now(), isp, testutil.QualifiedImage, mc, returnNilAttestorFetcher{}); err != nil {
+		t.Fatal(err)
+	}
+	testutil.DeepEqual(t, 2, len(events))
+}
+
+func Test_FixTrackerEviction(t *testing.T) {
+	tracker := newFixTracker()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+	isp := v1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: "test"}}
+	unfixed := []metadata.Vulnerability{{CVE: "c1", Severity: "HIGH"}}
+	for i := 0; i < maxTrackedImages; i++ {
+		tracker.observe(isp, fmt.Sprintf("image-%d", i), unfixed)
+		now = now.Add(time.Second)
+	}
+	// The least recently observed image makes room for a new one
+	tracker.observe(isp, "new", unfixed)
+	testutil.DeepEqual(t, maxTrackedImages, len(tracker.unfixed))
+	if _, ok := tracker.unfixed["test/isp/image-0"]; ok {
+		t.Errorf("expected the oldest image to be evicted")
+	}
+	// Images no longer observed expire
+	now = now.Add(trackedImageTTL)
+	tracker.observe(isp, "newer", unfixed)
+	testutil.DeepEqual(t, 2, len(tracker.unfixed))
+}
+
 func Test_BuiltProjectIDs(t *testing.T) {
 	type subCase struct {
 		name         string
@@ -264,7 +1100,7 @@ func Test_BuiltProjectIDs(t *testing.T) {
 					mc := &testutil.MockMetadataClient{
 						Build: builds,
 					}
-					violations, err := ValidateImageSecurityPolicy(
+					violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(),
 						isp, sc.image, mc, returnNilAttestorFetcher{})
 					if err != nil {
 						t.Errorf("error validating isp: %v", err)
@@ -284,6 +1120,45 @@ func Test_BuiltProjectIDs(t *testing.T) {
 	}
 }
 
+func Test_AllowedRepositories(t *testing.T) {
+	const digest = "@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	allowed := []string{
+		"gcr.io/my-project/*",
+		"*-docker.pkg.dev/my-project/*",
+		"registry.example.com:5000/team/*",
+		"nginx",
+	}
+	var tests = []struct {
+		image    string
+		rejected bool
+	}{
+		{"gcr.io/my-project/app" + digest, false},
+		{"gcr.io/my-project/nested/app" + digest, false},
+		{"gcr.io/other-project/app" + digest, true},
+		{"eu.gcr.io/my-project/app" + digest, true},
+		{"europe-west1-docker.pkg.dev/my-project/repo/app" + digest, false},
+		{"europe-west1-docker.pkg.dev/other-project/repo/app" + digest, true},
+		{"registry.example.com:5000/team/app" + digest, false},
+		{"registry.example.com/team/app" + digest, true},
+		{"docker.io/library/nginx" + digest, false},
+		{"nginx-unprivileged" + digest, true},
+	}
+	isp := v1beta1.ImageSecurityPolicy{
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			AllowedRepositories: allowed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, test.image, &testutil.MockMetadataClient{}, returnNilAttestorFetcher{})
+			testutil.CheckError(t, false, err)
+			if test.rejected != (len(violations) == 1 && violations[0].Type() == policy.DisallowedRepositoryViolation) {
+				t.Errorf("expected rejected: %v, got violations: %v", test.rejected, violations)
+			}
+		})
+	}
+}
+
 type testAttestorFetcher struct {
 	getAttestor func(name string) (*Attestor, error)
 }
@@ -368,7 +1243,7 @@ func Test_RequireAttestationsBy(t *testing.T) {
 				},
 			}
 
-			violations, err := ValidateImageSecurityPolicy(
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(),
 				isp,
 				goodImage,
 				mc,
@@ -420,3 +1295,906 @@ n2HdhN2nqz4+uhG7Um+VVGT1yQKzn8uu+HBfvat3LVHv09ETQu1C15qmZvayuV1i
 =eOFW
 -----END PGP MESSAGE-----`
 )
+
+func Test_ParseArkSignature(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	sign := func(method jwt.SigningMethod, key interface{}) string {
+		s, err := jwt.New(method).SignedString(key)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return s
+	}
+	cases := []struct {
+		name      string
+		algorithm string
+		jwt       string
+		key       interface{}
+		shouldErr bool
+	}{
+		{"RS256", "RS256", sign(jwt.SigningMethodRS256, rsaKey), &rsaKey.PublicKey, false},
+		{"ES256", "ES256", sign(jwt.SigningMethodES256, ecKey), &ecKey.PublicKey, false},
+		{"PS256", "PS256", sign(jwt.SigningMethodPS256, rsaKey), &rsaKey.PublicKey, false},
+		{"ES256 token for RS256 policy", "RS256", sign(jwt.SigningMethodES256, ecKey), &ecKey.PublicKey, true},
+		{"RS256 token for PS256 policy", "PS256", sign(jwt.SigningMethodRS256, rsaKey), &rsaKey.PublicKey, true},
+		{"wrong key", "ES256", sign(jwt.SigningMethodES256, ecKey), &rsaKey.PublicKey, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			occ := &metadata.OccurenceV1{
+				Attestation: &cav1.AttestationOccurrence{
+					Jwts: []*cav1.Jwt{{CompactJwt: c.jwt}},
+				},
+			}
+			keyFunc := func(*jwt.Token) (interface{}, error) {
+				return c.key, nil
+			}
+			_, err := parseArkSignature(occ, c.algorithm, arkSigningMethods[c.algorithm], keyFunc)
+			testutil.CheckError(t, c.shouldErr, err)
+		})
+	}
+}
+
+func Test_VerifyArkSignatureUnsupportedAlgorithm(t *testing.T) {
+	_, err := verifyArkSignature(context.Background(), &metadata.OccurenceV1{}, "key", "HS256")
+	testutil.CheckError(t, true, err)
+}
+
+func Test_ArkClaimViolations(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	claims := jwt.MapClaims{
+		"repository": "github.com/org/repo",
+		"branch":     "main",
+		"iat":        float64(now.Add(-2 * time.Hour).Unix()),
+	}
+	cases := []struct {
+		name     string
+		reqs     v1beta1.ArkCISignatureRequirements
+		expected int
+	}{
+		{"no requirements", v1beta1.ArkCISignatureRequirements{}, 0},
+		{"matching claims", v1beta1.ArkCISignatureRequirements{
+			RequiredClaims: map[string]string{"repository": "github.com/org/repo", "branch": "main"},
+			MaxTokenAge:    "3h",
+		}, 0},
+		{"mismatched claim", v1beta1.ArkCISignatureRequirements{
+			RequiredClaims: map[string]string{"branch": "release"},
+		}, 1},
+		{"missing claims", v1beta1.ArkCISignatureRequirements{
+			RequiredClaims: map[string]string{"builder": "ci", "branch": "main", "tag": "v1"},
+		}, 2},
+		{"token too old", v1beta1.ArkCISignatureRequirements{MaxTokenAge: "1h"}, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			reasons := arkClaimViolations(claims, c.reqs, now)
+			if len(reasons) != c.expected {
+				t.Errorf("expected %d violations, got %v", c.expected, reasons)
+			}
+		})
+	}
+}
+
+func Test_InvalidMaxTokenAge(t *testing.T) {
+	for _, age := range []string{"soon", "-1h", "0s"} {
+		t.Run(age, func(t *testing.T) {
+			isp := v1beta1.ImageSecurityPolicy{
+				Spec: v1beta1.ImageSecurityPolicySpec{
+					ArkCISignatureRequirements: v1beta1.ArkCISignatureRequirements{MaxTokenAge: age},
+				},
+			}
+			// The policy is invalid, rather than the image violating it
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, &testutil.MockMetadataClient{}, returnNilAttestorFetcher{})
+			testutil.CheckErrorAndDeepEqual(t, true, err, []policy.Violation(nil), violations)
+		})
+	}
+}
+
+func Test_Conflicts(t *testing.T) {
+	isp := func(name, ns string, spec v1beta1.ImageSecurityPolicySpec) v1beta1.ImageSecurityPolicy {
+		return v1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns}, Spec: spec}
+	}
+	isps := []v1beta1.ImageSecurityPolicy{
+		isp("strict", "prod", v1beta1.ImageSecurityPolicySpec{
+			PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{MaximumSeverity: "MEDIUM"},
+			RequireAttestationsBy:            []string{"projects/p/attestors/qa"},
+		}),
+		isp("loose", "prod", v1beta1.ImageSecurityPolicySpec{
+			ImageWhitelist: []string{"gcr.io/p/debug@sha256:abc"},
+			PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{
+				MaximumSeverity: "HIGH",
+				WhitelistCVEs:   []string{"CVE-1"},
+			},
+		}),
+		// Only policies of the same namespace conflict
+		isp("other", "dev", v1beta1.ImageSecurityPolicySpec{
+			PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{MaximumSeverity: "BLOCK_ALL"},
+		}),
+	}
+	expected := []Conflict{
+		{"prod", "loose", "strict", `image "gcr.io/p/debug@sha256:abc" is whitelisted, but is still reviewed against "strict" which requires attestations by [projects/p/attestors/qa]`},
+		{"prod", "loose", "strict", `CVE "CVE-1" is whitelisted, but not by "strict"`},
+		{"prod", "loose", "strict", `maximumSeverity HIGH is shadowed by MEDIUM of "strict"`},
+	}
+	testutil.DeepEqual(t, expected, Conflicts(isps))
+}
+
+func Test_RecordConflicts(t *testing.T) {
+	original := recordEvent
+	defer func() {
+		recordEvent = original
+		recorded.conflicts = map[Conflict]bool{}
+	}()
+	var events []*corev1.Event
+	recordEvent = func(event *corev1.Event) error {
+		events = append(events, event)
+		return nil
+	}
+	a := Conflict{"prod", "loose", "strict", `CVE "CVE-1" is whitelisted, but not by "strict"`}
+	b := Conflict{"prod", "loose", "strict", `CVE "CVE-2" is whitelisted, but not by "strict"`}
+
+	RecordConflicts([]Conflict{a})
+	testutil.DeepEqual(t, 1, len(events))
+	// Conflicts are recorded once across cron ticks
+	RecordConflicts([]Conflict{a, b})
+	testutil.DeepEqual(t, 2, len(events))
+	testutil.DeepEqual(t, b.Message, events[1].Message)
+	// and again once they are back after being resolved
+	RecordConflicts([]Conflict{b})
+	RecordConflicts([]Conflict{a, b})
+	testutil.DeepEqual(t, 3, len(events))
+	testutil.DeepEqual(t, a.Message, events[2].Message)
+}
+
+func Test_ReadImageSecurityPolicies(t *testing.T) {
+	input := `apiVersion: kritis.grafeas.io/v1beta1
+kind: ImageSecurityPolicy
+metadata:
+  name: my-isp
+  namespace: example
+spec:
+  imageWhitelist:
+  - gcr.io/p/image@sha256:abc
+---
+apiVersion: kritis.grafeas.io/v1beta1
+kind: AttestationAuthority
+metadata:
+  name: my-aa
+`
+	isps, err := ReadImageSecurityPolicies(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(isps) != 1 || isps[0].Name != "my-isp" || isps[0].Namespace != "example" {
+		t.Fatalf("unexpected policies %+v", isps)
+	}
+	testutil.DeepEqual(t, []string{"gcr.io/p/image@sha256:abc"}, isps[0].Spec.ImageWhitelist)
+}
+
+func Test_SelectImageSecurityPolicies(t *testing.T) {
+	isps := []v1beta1.ImageSecurityPolicy{
+		{ObjectMeta: metav1.ObjectMeta{Name: "all"}},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "frontend"},
+			Spec: v1beta1.ImageSecurityPolicySpec{
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "frontend"}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "not-batch"},
+			Spec: v1beta1.ImageSecurityPolicySpec{
+				PodSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"batch"}},
+				}},
+			},
+		},
+	}
+	names := func(isps []v1beta1.ImageSecurityPolicy) []string {
+		var n []string
+		for _, isp := range isps {
+			n = append(n, isp.Name)
+		}
+		return n
+	}
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		expected []string
+	}{
+		{"frontend", map[string]string{"tier": "frontend"}, []string{"all", "frontend", "not-batch"}},
+		{"batch", map[string]string{"tier": "batch"}, []string{"all"}},
+		{"no labels", nil, []string{"all", "not-batch"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			selected, err := SelectImageSecurityPolicies(isps, tc.labels)
+			testutil.CheckErrorAndDeepEqual(t, false, err, tc.expected, names(selected))
+		})
+	}
+
+	invalid := []v1beta1.ImageSecurityPolicy{{
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			PodSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "tier", Operator: "Near"},
+			}},
+		},
+	}}
+	_, err := SelectImageSecurityPolicies(invalid, nil)
+	testutil.CheckError(t, true, err)
+}
+
+type staticAttestorFetcher map[string]*Attestor
+
+func (f staticAttestorFetcher) GetAttestor(name string) (*Attestor, error) {
+	return f[name], nil
+}
+
+func Test_RequiredAttestationViolationsPKIX(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	fetcher := staticAttestorFetcher{
+		"projects/p/attestors/a": {
+			Name: "projects/p/attestors/a",
+			PublicKeys: []*AttestorPublicKey{{
+				ID:      "ni:///sha-256;key",
+				PkixPem: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+			}},
+		},
+	}
+	sign := func(image string) metadata.PGPAttestation {
+		host, err := container.NewAtomicContainerSig(image, map[string]string{})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		payload, err := host.JSON()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		digest := sha256.Sum256([]byte(payload))
+		sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		return metadata.PGPAttestation{
+			Signature: base64.StdEncoding.EncodeToString(sig),
+			KeyID:     "ni:///sha-256;key",
+			Payload:   payload,
+		}
+	}
+	pgp := sign(testutil.QualifiedImage)
+	pgp.Payload = ""
+	tests := []struct {
+		name         string
+		attestations []metadata.PGPAttestation
+		violations   int
+	}{
+		{"valid signature", []metadata.PGPAttestation{sign(testutil.QualifiedImage)}, 0},
+		{"signature of another image", []metadata.PGPAttestation{sign(testutil.IntTestImage)}, 1},
+		{"PGP signature for PKIX key", []metadata.PGPAttestation{pgp}, 1},
+		{"no attestations", nil, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vs, err := RequiredAttestationViolations(testutil.QualifiedImage, []string{"projects/p/attestors/a"}, test.attestations, fetcher)
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.violations, len(vs))
+		})
+	}
+}
+
+func Test_ParseAttestorRequirement(t *testing.T) {
+	tests := []struct {
+		entry     string
+		threshold int
+		names     []string
+		shouldErr bool
+	}{
+		{"projects/p/attestors/qa", 1, []string{"projects/p/attestors/qa"}, false},
+		{"2 of projects/p/attestors/qa, projects/p/attestors/security,projects/p/attestors/release", 2, []string{"projects/p/attestors/qa", "projects/p/attestors/security", "projects/p/attestors/release"}, false},
+		{"4 of projects/p/attestors/qa, projects/p/attestors/security", 0, nil, true},
+		{"0 of projects/p/attestors/qa", 0, nil, true},
+		{"1 of projects/p/attestors/qa, projects/p/attestors/qa, projects/p/attestors/security", 1, []string{"projects/p/attestors/qa", "projects/p/attestors/security"}, false},
+		{"2 of projects/p/attestors/qa, projects/p/attestors/qa", 0, nil, true},
+		{"two of projects/p/attestors/qa", 0, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.entry, func(t *testing.T) {
+			threshold, names, err := ParseAttestorRequirement(test.entry)
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.names, names)
+			if threshold != test.threshold {
+				t.Errorf("expected threshold %d, got %d", test.threshold, threshold)
+			}
+		})
+	}
+}
+
+func Test_RequiredAttestationViolationsThreshold(t *testing.T) {
+	fetcher := staticAttestorFetcher{}
+	attestations := []metadata.PGPAttestation{}
+	for _, name := range []string{"qa", "security", "release"} {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		fetcher["projects/p/attestors/"+name] = &Attestor{
+			Name:       "projects/p/attestors/" + name,
+			PublicKeys: []*AttestorPublicKey{{ID: name, PkixPem: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))}},
+		}
+		if name == "release" {
+			continue
+		}
+		host, err := container.NewAtomicContainerSig(testutil.QualifiedImage, map[string]string{})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		payload, err := host.JSON()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		digest := sha256.Sum256([]byte(payload))
+		sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		attestations = append(attestations, metadata.PGPAttestation{
+			Signature: base64.StdEncoding.EncodeToString(sig),
+			KeyID:     name,
+			Payload:   payload,
+		})
+	}
+	all := "projects/p/attestors/qa, projects/p/attestors/security, projects/p/attestors/release"
+	tests := []struct {
+		name     string
+		required []string
+		reasons  []string
+	}{
+		{"2 of 3 attested", []string{"2 of " + all}, nil},
+		{"3 of 3 required", []string{"3 of " + all}, []string{
+			fmt.Sprintf("%q has 2 of the 3 required attestations of [%s], missing: [projects/p/attestors/release]", testutil.QualifiedImage, all),
+		}},
+		{"all required", []string{"projects/p/attestors/qa", "projects/p/attestors/release"}, []string{
+			fmt.Sprintf("%q doesn't have a required attestation: [projects/p/attestors/release]", testutil.QualifiedImage),
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vs, err := RequiredAttestationViolations(testutil.QualifiedImage, test.required, attestations, fetcher)
+			var reasons []string
+			for _, v := range vs {
+				reasons = append(reasons, string(v.Reason()))
+			}
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.reasons, reasons)
+		})
+	}
+}
+
+func Test_RegoRequirements(t *testing.T) {
+	const module = `package kritis
+
+deny[msg] {
+	v := input.vulnerabilities[_]
+	v.CVE == "CVE-2018-1"
+	msg := sprintf("%s is not allowed", [v.CVE])
+}
+
+deny[{"type": "MissingTeamLabel", "msg": "pods need a team label"}] {
+	not input.pod.metadata.labels.team
+}
+`
+	fetchConfigMap = func(namespace, name string) (*corev1.ConfigMap, error) {
+		if namespace != "foo" || name != "rules" {
+			return nil, fmt.Errorf("not found: %s/%s", namespace, name)
+		}
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, ResourceVersion: "1"},
+			Data:       map[string]string{"rules.rego": module, "README": "not a module"},
+		}, nil
+	}
+	defer func() { fetchConfigMap = kubernetesutil.ConfigMap }()
+	regoQueries.m = map[string]regoEntry{}
+
+	isp := v1beta1.ImageSecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: "foo"},
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{
+				MaximumSeverity: constants.AllowAll,
+			},
+			RegoRequirements: v1beta1.RegoRequirements{ConfigMap: "rules"},
+		},
+	}
+	var tests = []struct {
+		name       string
+		vulnz      []metadata.Vulnerability
+		labels     map[string]string
+		configMap  string
+		shouldErr  bool
+		violations []policy.Reason
+	}{
+		{
+			name:   "no violations",
+			vulnz:  []metadata.Vulnerability{{CVE: "CVE-2018-2", Severity: "LOW", HasFixAvailable: true}},
+			labels: map[string]string{"team": "a"},
+		},
+		{
+			name:   "message",
+			vulnz:  []metadata.Vulnerability{{CVE: "CVE-2018-1", Severity: "LOW", HasFixAvailable: true}},
+			labels: map[string]string{"team": "a"},
+			violations: []policy.Reason{
+				RegoReason(testutil.QualifiedImage, "", "CVE-2018-1 is not allowed"),
+			},
+		},
+		{
+			name: "custom type",
+			violations: []policy.Reason{
+				RegoReason(testutil.QualifiedImage, "MissingTeamLabel", "pods need a team label"),
+			},
+		},
+		{
+			name:      "missing ConfigMap",
+			configMap: "other",
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := isp
+			if test.configMap != "" {
+				p.Spec.RegoRequirements.ConfigMap = test.configMap
+			}
+			ctx := WithPod(context.Background(), &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Labels: test.labels}})
+			violations, err := ValidateImageSecurityPolicy(ctx, time.Now(), p, testutil.QualifiedImage, &testutil.MockMetadataClient{Vulnz: test.vulnz}, returnNilAttestorFetcher{})
+			var reasons []policy.Reason
+			for _, v := range violations {
+				testutil.DeepEqual(t, policy.RegoViolation, v.Type())
+				reasons = append(reasons, v.Reason())
+			}
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.violations, reasons)
+		})
+	}
+}
+
+func Test_CustomRuleAttestations(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	fetcher := staticAttestorFetcher{"projects/p/attestors/qa": &Attestor{
+		Name:       "projects/p/attestors/qa",
+		PublicKeys: []*AttestorPublicKey{{ID: "qa", PkixPem: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))}},
+	}}
+	host, err := container.NewAtomicContainerSig(testutil.QualifiedImage, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	payload, err := host.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	digest := sha256.Sum256([]byte(payload))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	attestations := []metadata.PGPAttestation{
+		{Signature: base64.StdEncoding.EncodeToString(sig), KeyID: "qa", Payload: payload},
+		// Not signed by the key of the attestor, or by an attestor of the policy.
+		{Signature: base64.StdEncoding.EncodeToString([]byte("forged")), KeyID: "qa", Payload: payload},
+		{Signature: base64.StdEncoding.EncodeToString(sig), KeyID: "unknown", Payload: payload},
+	}
+	rule := v1beta1.CustomRule{Name: "qa", Expression: `size(attestations) == 1 && attestations.all(a, a.attestor == "projects/p/attestors/qa")`}
+	tests := []struct {
+		name       string
+		required   []string
+		violations int
+	}{
+		{"verified attestations", []string{"projects/p/attestors/qa"}, 0},
+		{"no attestors", nil, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isp := v1beta1.ImageSecurityPolicy{Spec: v1beta1.ImageSecurityPolicySpec{
+				RequireAttestationsBy: test.required,
+				CustomRules:           []v1beta1.CustomRule{rule},
+			}}
+			vs, err := customRuleViolations(context.Background(), isp, testutil.QualifiedImage, nil, &testutil.MockMetadataClient{PGPAttestations: attestations}, fetcher)
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.violations, len(vs))
+		})
+	}
+}
+
+func Test_CustomRuleCostLimit(t *testing.T) {
+	vulnz := make([]metadata.Vulnerability, 200)
+	isp := v1beta1.ImageSecurityPolicy{Spec: v1beta1.ImageSecurityPolicySpec{
+		CustomRules: []v1beta1.CustomRule{{Name: "expensive", Expression: `vulnerabilities.all(a, vulnerabilities.all(b, vulnerabilities.all(c, a.cve == b.cve)))`}},
+	}}
+	_, err := customRuleViolations(context.Background(), isp, testutil.QualifiedImage, vulnz, &testutil.MockMetadataClient{}, returnNilAttestorFetcher{})
+	testutil.CheckError(t, true, err)
+}
+
+func Test_RegoSandbox(t *testing.T) {
+	modules := map[string]string{
+		"http": `package kritis
+
+deny[msg] {
+	resp := http.send({"method": "get", "url": "http://169.254.169.254/"})
+	msg := resp.body
+}
+`,
+		"runtime": `package kritis
+
+deny[msg] {
+	msg := opa.runtime().env.HOME
+}
+`,
+		"slow": `package kritis
+
+deny[msg] {
+	n := numbers.range(1, 100000)
+	x := n[_]
+	y := n[_]
+	x + y == 0
+	msg := "unreachable"
+}
+`,
+	}
+	fetches := 0
+	fetchConfigMap = func(namespace, name string) (*corev1.ConfigMap, error) {
+		fetches++
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, ResourceVersion: "1"},
+			Data:       map[string]string{"rules.rego": modules[name]},
+		}, nil
+	}
+	regoEvalTimeout = 100 * time.Millisecond
+	defer func() {
+		fetchConfigMap = kubernetesutil.ConfigMap
+		regoEvalTimeout = time.Second
+	}()
+	regoQueries.m = map[string]regoEntry{}
+
+	for name := range modules {
+		t.Run(name, func(t *testing.T) {
+			_, err := regoViolations(context.Background(), v1beta1.ImageSecurityPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: "foo"},
+				Spec:       v1beta1.ImageSecurityPolicySpec{RegoRequirements: v1beta1.RegoRequirements{ConfigMap: name}},
+			}, testutil.QualifiedImage, nil, nil, &testutil.MockMetadataClient{})
+			testutil.CheckError(t, true, err)
+		})
+	}
+
+	// The ConfigMaps are read once within regoConfigMapTTL.
+	fetches = 0
+	for i := 0; i < 2; i++ {
+		if _, err := regoQuery(context.Background(), "foo", v1beta1.RegoRequirements{ConfigMap: "slow"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testutil.DeepEqual(t, 0, fetches)
+	regoNow = func() time.Time { return time.Now().Add(regoConfigMapTTL) }
+	defer func() { regoNow = time.Now }()
+	if _, err := regoQuery(context.Background(), "foo", v1beta1.RegoRequirements{ConfigMap: "slow"}); err != nil {
+		t.Fatal(err)
+	}
+	testutil.DeepEqual(t, 1, fetches)
+
+	// The cache is bounded.
+	for i := 0; i < maxRegoQueries+10; i++ {
+		if _, err := regoQuery(context.Background(), "foo", v1beta1.RegoRequirements{ConfigMap: "slow", Query: fmt.Sprintf("data.kritis.deny%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testutil.DeepEqual(t, maxRegoQueries, len(regoQueries.m))
+}
+
+func Test_CustomRules(t *testing.T) {
+	isp := v1beta1.ImageSecurityPolicy{
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{
+				MaximumSeverity: constants.AllowAll,
+			},
+		},
+	}
+	vulnz := []metadata.Vulnerability{{CVE: "CVE-2018-1", Severity: "HIGH", Package: "openssl", HasFixAvailable: true}}
+	builds := []metadata.Build{{Provenance: &metadata.BuildProvenance{ProjectID: "my-project"}}}
+	var tests = []struct {
+		name       string
+		rules      []v1beta1.CustomRule
+		shouldErr  bool
+		violations []policy.Reason
+	}{
+		{
+			name: "rules hold",
+			rules: []v1beta1.CustomRule{
+				{Name: "no-critical", Expression: `vulnerabilities.all(v, v.severity != "CRITICAL")`},
+				{Name: "built-in-project", Expression: `builds.exists(b, b.projectID == "my-project")`},
+				{Name: "gcr", Expression: `image.startsWith("gcr.io/")`},
+			},
+		},
+		{
+			name: "rules violated",
+			rules: []v1beta1.CustomRule{
+				{Name: "no-openssl", Expression: `!vulnerabilities.exists(v, v.package == "openssl")`, Message: "openssl is not allowed"},
+				{Name: "attested", Expression: `size(attestations) > 0`},
+			},
+			violations: []policy.Reason{
+				CustomRuleReason(testutil.QualifiedImage, v1beta1.CustomRule{Name: "no-openssl", Message: "openssl is not allowed"}),
+				CustomRuleReason(testutil.QualifiedImage, v1beta1.CustomRule{Name: "attested", Expression: `size(attestations) > 0`}),
+			},
+		},
+		{
+			name:      "invalid expression",
+			rules:     []v1beta1.CustomRule{{Name: "invalid", Expression: `vulnerabilities.all(v,`}},
+			shouldErr: true,
+		},
+		{
+			name:      "not a bool",
+			rules:     []v1beta1.CustomRule{{Name: "size", Expression: `size(vulnerabilities)`}},
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := isp
+			p.Spec.CustomRules = test.rules
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), p, testutil.QualifiedImage, &testutil.MockMetadataClient{Vulnz: vulnz, Build: builds}, returnNilAttestorFetcher{})
+			var reasons []policy.Reason
+			for _, v := range violations {
+				testutil.DeepEqual(t, policy.CustomRuleViolation, v.Type())
+				reasons = append(reasons, v.Reason())
+			}
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.violations, reasons)
+		})
+	}
+}
+
+func Test_MaximumCounts(t *testing.T) {
+	vulnz := []metadata.Vulnerability{
+		{CVE: "CVE-1", Severity: "HIGH", HasFixAvailable: true},
+		{CVE: "CVE-2", Severity: "HIGH", HasFixAvailable: true},
+		{CVE: "CVE-3", Severity: "Important", HasFixAvailable: true},
+		{CVE: "CVE-4", Severity: "MEDIUM", HasFixAvailable: true},
+		{CVE: "CVE-5", Severity: "Unknown", HasFixAvailable: true},
+	}
+	var tests = []struct {
+		name       string
+		counts     map[string]int
+		unknownAs  string
+		whitelist  []string
+		shouldErr  bool
+		violations []policy.Reason
+	}{
+		{
+			name:   "within counts",
+			counts: map[string]int{"HIGH": 3, "MEDIUM": 1},
+		},
+		{
+			name:   "too many",
+			counts: map[string]int{"HIGH": 2, "MEDIUM": 0, "CRITICAL": 0},
+			violations: []policy.Reason{
+				SeverityCountReason(testutil.QualifiedImage, "HIGH", 3, 2),
+				SeverityCountReason(testutil.QualifiedImage, "MEDIUM", 1, 0),
+			},
+		},
+		{
+			name:      "whitelisted are not counted",
+			counts:    map[string]int{"HIGH": 2},
+			whitelist: []string{"CVE-1"},
+		},
+		{
+			name:      "unknown counted as",
+			counts:    map[string]int{"MEDIUM": 1},
+			unknownAs: "MEDIUM",
+			violations: []policy.Reason{
+				SeverityCountReason(testutil.QualifiedImage, "MEDIUM", 2, 1),
+			},
+		},
+		{
+			name:      "invalid severity",
+			counts:    map[string]int{"SEVERE": 1},
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			isp := v1beta1.ImageSecurityPolicy{
+				Spec: v1beta1.ImageSecurityPolicySpec{
+					PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{
+						MaximumSeverity:        constants.AllowAll,
+						MaximumCounts:          test.counts,
+						TreatUnknownSeverityAs: test.unknownAs,
+						WhitelistCVEs:          test.whitelist,
+					},
+				},
+			}
+			violations, err := ValidateImageSecurityPolicy(context.Background(), time.Now(), isp, testutil.QualifiedImage, &testutil.MockMetadataClient{Vulnz: vulnz}, returnNilAttestorFetcher{})
+			var reasons []policy.Reason
+			for _, v := range violations {
+				testutil.DeepEqual(t, policy.SeverityCountViolation, v.Type())
+				reasons = append(reasons, v.Reason())
+			}
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.violations, reasons)
+		})
+	}
+}
+
+func Test_MaximumFixAvailableDays(t *testing.T) {
+	current := time.Date(2018, 10, 31, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(d int) *time.Time {
+		t := current.AddDate(0, 0, -d)
+		return &t
+	}
+	isp := v1beta1.ImageSecurityPolicy{
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{
+				MaximumSeverity:         "MEDIUM",
+				MaximumFixAvailableDays: 7,
+			},
+		},
+	}
+	var tests = []struct {
+		name     string
+		vuln     metadata.Vulnerability
+		expected []policy.Reason
+	}{
+		{
+			name: "recent fix",
+			vuln: metadata.Vulnerability{CVE: "CVE-1", Severity: "HIGH", HasFixAvailable: true, FixAvailableSince: daysAgo(7)},
+		},
+		{
+			name:     "old fix",
+			vuln:     metadata.Vulnerability{CVE: "CVE-1", Severity: "HIGH", HasFixAvailable: true, FixAvailableSince: daysAgo(30)},
+			expected: []policy.Reason{FixAgeReason(testutil.QualifiedImage, metadata.Vulnerability{CVE: "CVE-1", Severity: "HIGH"}, 30, isp)},
+		},
+		{
+			name:     "unknown fix date",
+			vuln:     metadata.Vulnerability{CVE: "CVE-1", Severity: "HIGH", HasFixAvailable: true},
+			expected: []policy.Reason{SeverityReason(testutil.QualifiedImage, metadata.Vulnerability{CVE: "CVE-1", Severity: "HIGH"}, isp)},
+		},
+		{
+			name: "within max severity",
+			vuln: metadata.Vulnerability{CVE: "CVE-1", Severity: "LOW", HasFixAvailable: true, FixAvailableSince: daysAgo(30)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			violations, err := ValidateImageSecurityPolicy(context.Background(), current, isp, testutil.QualifiedImage, &testutil.MockMetadataClient{Vulnz: []metadata.Vulnerability{test.vuln}}, returnNilAttestorFetcher{})
+			var reasons []policy.Reason
+			for _, v := range violations {
+				reasons = append(reasons, v.Reason())
+			}
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, reasons)
+		})
+	}
+}
+
+func Test_SeverityReasonDetails(t *testing.T) {
+	isp := v1beta1.ImageSecurityPolicy{
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{MaximumSeverity: "MEDIUM"},
+		},
+	}
+	var tests = []struct {
+		name     string
+		vuln     metadata.Vulnerability
+		expected policy.Reason
+	}{
+		{
+			name:     "no details",
+			vuln:     metadata.Vulnerability{CVE: "CVE-1", Severity: "HIGH"},
+			expected: `found CVE "CVE-1" in "image", which has severity HIGH exceeding max severity MEDIUM`,
+		},
+		{
+			name: "all details",
+			vuln: metadata.Vulnerability{CVE: "CVE-1", Severity: "HIGH", Package: "openssl", Version: "1.1.1n-0",
+				FixedVersion: "1.1.1n-0+deb11u3", CVSSVector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"},
+			expected: `found CVE "CVE-1" in "image", which has severity HIGH exceeding max severity MEDIUM (package openssl 1.1.1n-0, fixed in 1.1.1n-0+deb11u3, CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H)`,
+		},
+		{
+			name:     "package without version",
+			vuln:     metadata.Vulnerability{CVE: "CVE-1", Severity: "HIGH", Package: "openssl"},
+			expected: `found CVE "CVE-1" in "image", which has severity HIGH exceeding max severity MEDIUM (package openssl)`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testutil.DeepEqual(t, test.expected, SeverityReason("image", test.vuln, isp))
+		})
+	}
+}
+
+func Test_Hash(t *testing.T) {
+	isp := v1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Name: "isp", ResourceVersion: "1"}}
+	isp.Spec.PackageVulnerabilityRequirements.MaximumSeverity = "HIGH"
+	hash := Hash(isp)
+
+	updated := isp
+	updated.ResourceVersion = "2"
+	updated.Status.ViolatingPods = 1
+	if Hash(updated) != hash {
+		t.Errorf("expected the hash to ignore metadata and status")
+	}
+	updated.Spec.PackageVulnerabilityRequirements.MaximumSeverity = "LOW"
+	if Hash(updated) == hash {
+		t.Errorf("expected the hash to change with the spec")
+	}
+}
+
+func Test_ImageSecurityPoliciesCached(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, key := range []string{"foo/b", "bar/a", "foo/a"} {
+		ns, name, _ := cache.SplitMetaNamespaceKey(key)
+		if err := indexer.Add(&v1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	informers.Set(&informers.Listers{ImageSecurityPolicies: listers.NewImageSecurityPolicyLister(indexer)})
+	defer informers.Set(nil)
+
+	names := func(isps []v1beta1.ImageSecurityPolicy) []string {
+		var keys []string
+		for _, isp := range isps {
+			keys = append(keys, isp.Namespace+"/"+isp.Name)
+		}
+		return keys
+	}
+	isps, err := ImageSecurityPolicies("")
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{"bar/a", "foo/a", "foo/b"}, names(isps))
+	isps, err = ImageSecurityPolicies("foo")
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{"foo/a", "foo/b"}, names(isps))
+
+	// Policies are copies of the cache
+	isps[0].Spec.RequireImageDigest = true
+	cached, err := ImageSecurityPolicies("foo")
+	testutil.CheckErrorAndDeepEqual(t, false, err, false, cached[0].Spec.RequireImageDigest)
+}
+
+func TestUpdateStatus(t *testing.T) {
+	isp := &v1beta1.ImageSecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: "foo"},
+		Spec:       v1beta1.ImageSecurityPolicySpec{ImageWhitelist: []string{"gcr.io/allowed"}},
+	}
+	client := fake.NewSimpleClientset(isp)
+	// The first write conflicts with a concurrent update of the ISP.
+	conflicts := 1
+	client.PrependReactor("update", "imagesecuritypolicies", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts == 0 {
+			return false, nil, nil
+		}
+		conflicts--
+		return true, nil, k8serrors.NewConflict(schema.GroupResource{Resource: "imagesecuritypolicies"}, "isp", fmt.Errorf("modified"))
+	})
+
+	// The ISP read by the cron job is stale.
+	stale := isp.DeepCopy()
+	stale.Spec.ImageWhitelist = nil
+	stale.Status = v1beta1.ImageSecurityPolicyStatus{ScannedImages: 2, ViolatingPods: 1}
+	if err := updateStatus(client, stale); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := client.KritisV1beta1().ImageSecurityPolicies("foo").Get("isp", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.DeepEqual(t, stale.Status, updated.Status)
+	testutil.DeepEqual(t, []string{"gcr.io/allowed"}, updated.Spec.ImageWhitelist)
+}
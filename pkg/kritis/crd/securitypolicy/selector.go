@@ -0,0 +1,50 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package securitypolicy
+
+import (
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// SelectImageSecurityPolicies returns the policies which apply to pods with the given labels.
+func SelectImageSecurityPolicies(isps []v1beta1.ImageSecurityPolicy, podLabels map[string]string) ([]v1beta1.ImageSecurityPolicy, error) {
+	var selected []v1beta1.ImageSecurityPolicy
+	for _, isp := range isps {
+		ok, err := selects(isp, podLabels)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			selected = append(selected, isp)
+		}
+	}
+	return selected, nil
+}
+
+func selects(isp v1beta1.ImageSecurityPolicy, podLabels map[string]string) (bool, error) {
+	if isp.Spec.PodSelector == nil {
+		return true, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(isp.Spec.PodSelector)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid pod selector in ImageSecurityPolicy %q", isp.Name)
+	}
+	return s.Matches(labels.Set(podLabels)), nil
+}
@@ -18,11 +18,13 @@ package securitypolicy
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
 	"github.com/grafeas/kritis/pkg/kritis/constants"
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
 	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/sbom"
 )
 
 // Violation represents a vulnerability that violates an ISP
@@ -63,24 +65,141 @@ func UnqualifiedImageReason(image string) policy.Reason {
 	return policy.Reason(fmt.Sprintf("%q is not a fully qualified image. You can run 'kubectl plugin resolve-tags' to qualify all images with a digest.", image))
 }
 
+// TagNotPinnedReason returns a detailed reason if the image is referenced by a tag while the policy requires a digest
+func TagNotPinnedReason(image string) policy.Reason {
+	return policy.Reason(fmt.Sprintf("%q is referenced by a mutable tag, but the policy requires images pinned by digest.", image))
+}
+
+// DockerHubReason returns a detailed reason if a Docker Hub image is not admissible
+func DockerHubReason(image string, isp v1beta1.ImageSecurityPolicy) policy.Reason {
+	if isp.Spec.DockerHubImages == constants.DockerHubOfficialOnly {
+		return policy.Reason(fmt.Sprintf("%q is hosted on Docker Hub, but the policy only allows official Docker Hub images.", image))
+	}
+	return policy.Reason(fmt.Sprintf("%q is hosted on Docker Hub, which the policy does not allow.", image))
+}
+
+// MissingSBOMReason returns a detailed reason if the image has no SBOM in an accepted format
+func MissingSBOMReason(image string, isp v1beta1.ImageSecurityPolicy) policy.Reason {
+	return policy.Reason(fmt.Sprintf("%q has no SBOM in an accepted format: [%s]", image, strings.Join(isp.Spec.RequireSBOM, ",")))
+}
+
+// DisallowedBaseImageReason returns a detailed reason if the image was not built from an allowed base image
+func DisallowedBaseImageReason(image string, bases [][]string, isp v1beta1.ImageSecurityPolicy) policy.Reason {
+	allowed := strings.Join(isp.Spec.AllowedBaseImages, ",")
+	if len(bases) == 0 {
+		return policy.Reason(fmt.Sprintf("%q has no known base image, the policy requires one of: [%s]", image, allowed))
+	}
+	var names []string
+	for _, refs := range bases {
+		names = append(names, refs[0])
+	}
+	return policy.Reason(fmt.Sprintf("%q was built from [%s], which the policy does not allow: [%s]", image, strings.Join(names, ","), allowed))
+}
+
+// DisallowedRepositoryReason returns a detailed reason if the image is not hosted in an allowed repository
+func DisallowedRepositoryReason(image string, isp v1beta1.ImageSecurityPolicy) policy.Reason {
+	return policy.Reason(fmt.Sprintf("%q is not hosted in an allowed repository: [%s]", image, strings.Join(isp.Spec.AllowedRepositories, ",")))
+}
+
+// ProvenanceReason returns a detailed reason if the image has no provenance meeting the policy requirements
+func ProvenanceReason(image string, mismatches []string) policy.Reason {
+	return policy.Reason(fmt.Sprintf("%q has no provenance meeting the policy requirements: %s", image, strings.Join(mismatches, "; ")))
+}
+
+// RegoReason returns the reason of a violation of the Rego rules of a policy, of a custom type if set
+func RegoReason(image, typ, msg string) policy.Reason {
+	if typ != "" {
+		return policy.Reason(fmt.Sprintf("%q violates Rego rule %s: %s", image, typ, msg))
+	}
+	return policy.Reason(fmt.Sprintf("%q violates Rego rules: %s", image, msg))
+}
+
+// CustomRuleReason returns the reason of a violation of a custom rule, its message if set
+func CustomRuleReason(image string, r v1beta1.CustomRule) policy.Reason {
+	if r.Message != "" {
+		return policy.Reason(fmt.Sprintf("%q violates custom rule %s: %s", image, r.Name, r.Message))
+	}
+	return policy.Reason(fmt.Sprintf("%q violates custom rule %s: %s", image, r.Name, r.Expression))
+}
+
+// FixAgeReason returns a detailed reason if a CVE exceeding the max severity has had a fix for longer than allowed
+func FixAgeReason(image string, v metadata.Vulnerability, age int, isp v1beta1.ImageSecurityPolicy) policy.Reason {
+	return policy.Reason(fmt.Sprintf("found CVE %q in %q, which has severity %s exceeding max severity %s and a fix available for %d days, longer than %d days%s",
+		v.CVE, image, v.Severity, isp.Spec.PackageVulnerabilityRequirements.MaximumSeverity, age, isp.Spec.PackageVulnerabilityRequirements.MaximumFixAvailableDays, vulnerabilityDetails(v)))
+}
+
+// SeverityCountReason returns a detailed reason if the image has more CVEs of a severity than allowed
+func SeverityCountReason(image, severity string, count, max int) policy.Reason {
+	return policy.Reason(fmt.Sprintf("found %d CVEs of severity %s in %q, exceeding the maximum count of %d", count, severity, image, max))
+}
+
+// LicenseReason returns a detailed reason if a package of the image has a license which is not permitted
+func LicenseReason(image string, p sbom.Package, err error) policy.Reason {
+	pkg := p.Name
+	if p.Version != "" {
+		pkg = fmt.Sprintf("%s %s", p.Name, p.Version)
+	}
+	if err != nil {
+		return policy.Reason(fmt.Sprintf("package %q of %q has a license which could not be checked: %s", pkg, image, err))
+	}
+	if p.License == "" {
+		return policy.Reason(fmt.Sprintf("package %q of %q has no known license, but the policy only allows listed licenses", pkg, image))
+	}
+	return policy.Reason(fmt.Sprintf("package %q of %q has license %q, which the policy does not allow", pkg, image, p.License))
+}
+
 // FixUnavailabileReason returns a detailed reason if an unfixable CVE exceeds max severity
 func FixUnavailableReason(image string, v metadata.Vulnerability, isp v1beta1.ImageSecurityPolicy) policy.Reason {
 	ms := isp.Spec.PackageVulnerabilityRequirements.MaximumFixUnavailableSeverity
 	if ms == constants.BlockAll {
-		return policy.Reason(fmt.Sprintf("found unfixable CVE %q in %q which isn't whitelisted, violating max severity %s",
-			v.CVE, image, ms))
+		return policy.Reason(fmt.Sprintf("found unfixable CVE %q in %q which isn't whitelisted, violating max severity %s%s",
+			v.CVE, image, ms, vulnerabilityDetails(v)))
 	}
-	return policy.Reason(fmt.Sprintf("found unfixable CVE %q in %q, which has severity %s exceeding max severity %s",
-		v.CVE, image, v.Severity, ms))
+	return policy.Reason(fmt.Sprintf("found unfixable CVE %q in %q, which has severity %s exceeding max severity %s%s",
+		v.CVE, image, v.Severity, ms, vulnerabilityDetails(v)))
 }
 
 // SeverityReason returns a detailed reason if a CVE exceeds max severity
 func SeverityReason(image string, v metadata.Vulnerability, isp v1beta1.ImageSecurityPolicy) policy.Reason {
 	ms := isp.Spec.PackageVulnerabilityRequirements.MaximumSeverity
 	if ms == constants.BlockAll {
-		return policy.Reason(fmt.Sprintf("found CVE %q in %q which isn't whitelisted, violating max severity %s",
-			v.CVE, image, ms))
+		return policy.Reason(fmt.Sprintf("found CVE %q in %q which isn't whitelisted, violating max severity %s%s",
+			v.CVE, image, ms, vulnerabilityDetails(v)))
 	}
-	return policy.Reason(fmt.Sprintf("found CVE %q in %q, which has severity %s exceeding max severity %s",
-		v.CVE, image, v.Severity, ms))
+	return policy.Reason(fmt.Sprintf("found CVE %q in %q, which has severity %s exceeding max severity %s%s",
+		v.CVE, image, v.Severity, ms, vulnerabilityDetails(v)))
+}
+
+// vulnerabilityDetails describes the affected package of a CVE and how to fix it, as far as known,
+// e.g. " (package openssl 1.1.1n-0, fixed in 1.1.1n-0+deb11u3, CVSS:3.1/AV:N/...)".
+func vulnerabilityDetails(v metadata.Vulnerability) string {
+	var details []string
+	if v.Package != "" {
+		details = append(details, strings.TrimSpace("package "+v.Package+" "+v.Version))
+	}
+	if v.FixedVersion != "" {
+		details = append(details, "fixed in "+v.FixedVersion)
+	}
+	if v.CVSSVector != "" {
+		details = append(details, v.CVSSVector)
+	}
+	if len(details) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", strings.Join(details, ", "))
+}
+
+// PlatformReason returns the reason of a violation found in the image of a platform of a multi-arch image
+func PlatformReason(reason policy.Reason, platform string) policy.Reason {
+	return policy.Reason(fmt.Sprintf("%s (platform %s)", reason, platform))
+}
+
+// NotScannedReason returns a detailed reason if an image has no vulnerability scan results
+func NotScannedReason(image string) policy.Reason {
+	return policy.Reason(fmt.Sprintf("%q has no vulnerability scan results, but the policy requires scanned images.", image))
+}
+
+// ScanInProgressReason returns a detailed reason if the vulnerability scan of an image didn't finish in time
+func ScanInProgressReason(image string) policy.Reason {
+	return policy.Reason(fmt.Sprintf("the vulnerability scan of %q is still in progress.", image))
 }
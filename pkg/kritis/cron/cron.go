@@ -18,69 +18,127 @@ package cron
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/golang/glog"
 	"github.com/grafeas/kritis/pkg/kritis/admission"
 	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/controller"
+	"github.com/grafeas/kritis/pkg/kritis/decisionlog"
+	"github.com/grafeas/kritis/pkg/kritis/exemption"
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
 	"github.com/grafeas/kritis/pkg/kritis/pods"
+	"github.com/grafeas/kritis/pkg/kritis/records"
+	"github.com/grafeas/kritis/pkg/kritis/report"
 	"github.com/grafeas/kritis/pkg/kritis/review"
 	"github.com/grafeas/kritis/pkg/kritis/secrets"
 
+	"github.com/grafeas/kritis/pkg/kritis/crd/attestationpolicy"
 	"github.com/grafeas/kritis/pkg/kritis/crd/authority"
 	"github.com/grafeas/kritis/pkg/kritis/crd/kritisconfig"
 	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
 	"github.com/grafeas/kritis/pkg/kritis/violation"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 )
 
 // For testing
 var (
-	podChecker = CheckPods
+	podChecker       = CheckPods
+	now              = time.Now
+	watchRetryPeriod = 10 * time.Second
 )
 
 // For testing.
 type podLister func(string) ([]corev1.Pod, error)
 
 type Config struct {
-	PodLister            podLister
-	Client               metadata.Fetcher
-	ReviewConfig         *review.Config
+	PodLister    podLister
+	Client       metadata.Fetcher
+	ReviewConfig *review.Config
+	// Reviewer reviews the pods, defaults to review.New(Client, ReviewConfig).
+	Reviewer             review.Interface
 	SecurityPolicyLister func(namespace string) ([]v1beta1.ImageSecurityPolicy, error)
+	// AttestationPolicyLister lists GenericAttestationPolicies, if set pods are also
+	// reviewed against those of their namespace.
+	AttestationPolicyLister func(namespace string) ([]v1beta1.GenericAttestationPolicy, error)
+	// StatusUpdater writes the compliance found back to each ImageSecurityPolicy, if set.
+	StatusUpdater func(isp *v1beta1.ImageSecurityPolicy) error
+	// SecurityPolicyWatcher watches ImageSecurityPolicies, if set the pods of their namespace
+	// are reviewed again as soon as their spec changes.
+	SecurityPolicyWatcher func() (watch.Interface, error)
+	// Exemptions select the pods which are not reviewed, if set.
+	Exemptions *exemption.Matcher
+	// Workers is the number of namespaces reviewed concurrently, defaults to DefaultWorkers.
+	Workers int
+	// Reporter is given the compliance of each namespace reviewed, and writes it
+	// periodically while the cron job runs, if set.
+	Reporter *report.Reporter
+	// Records keeps the violations found in each namespace reviewed, if set.
+	Records *records.Recorder
 }
 
 var (
-	defaultViolationStrategy = &violation.AnnotationStrategy{}
+	defaultViolationStrategy = &violation.RemediationStrategy{}
 )
 
-func NewCronConfig(cs *kubernetes.Clientset, client metadata.Fetcher) *Config {
-	attestorFetcher, err := securitypolicy.NewAttestorFetcher()
-	if err != nil {
-		glog.Fatalf("failed to create an attestorFetcher: %v", err)
-	}
+// DefaultWorkers is the number of namespaces reviewed concurrently if not configured.
+const DefaultWorkers = 2
 
+// NewCronConfig returns the Config of the cron job reviewing pods with the metadata of
+// client, and the attestors of attestorFetcher.
+func NewCronConfig(cs *kubernetes.Clientset, client metadata.Fetcher, attestorFetcher securitypolicy.AttestorFetcher) *Config {
 	cfg := Config{
 		PodLister: pods.Pods,
 		Client:    client,
 		ReviewConfig: &review.Config{
 			Secret:                          secrets.Fetch,
 			Auths:                           authority.Authority,
+			AuthLister:                      authority.Authorities,
+			DefaultAuth:                     authority.DefaultAuthority,
 			Strategy:                        defaultViolationStrategy,
 			IsWebhook:                       false,
 			Validate:                        securitypolicy.ValidateImageSecurityPolicy,
 			Attestors:                       attestorFetcher,
 			ClusterWhitelistedImagesRemover: kritisconfig.RemoveWhitelistedImages,
 		},
-		SecurityPolicyLister: securitypolicy.ImageSecurityPolicies,
+		SecurityPolicyLister:    securitypolicy.ImageSecurityPolicies,
+		AttestationPolicyLister: attestationpolicy.GenericAttestationPolicies,
+		StatusUpdater:           securitypolicy.UpdateStatus,
 	}
 	return &cfg
 }
 
 // Start starts the background processing of image security policies.
+// On every tick, each namespace with an ImageSecurityPolicy is queued and its
+// pods are reviewed by the cron controller, failed namespaces are retried.
+// With a SecurityPolicyWatcher, namespaces are also queued when their policies change.
 func Start(ctx context.Context, cfg Config, checkInterval time.Duration) {
+	ctrl := controller.New("cron", controller.ReconcilerFunc(func(namespace string) error {
+		return checkNamespace(cfg, namespace)
+	}))
+	stopped := make(chan struct{})
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	go func() {
+		ctrl.Run(ctx, workers)
+		close(stopped)
+	}()
+	if cfg.SecurityPolicyWatcher != nil {
+		go watchPolicies(ctx, cfg.SecurityPolicyWatcher, ctrl.Enqueue)
+	}
+	go cfg.Reporter.Run(ctx)
+
 	c := time.NewTicker(checkInterval)
+	defer c.Stop()
 	done := ctx.Done()
 
 	for {
@@ -92,33 +150,528 @@ func Start(ctx context.Context, cfg Config, checkInterval time.Duration) {
 				glog.Errorf("fetching image security policies: %s", err)
 				continue
 			}
-			if err := podChecker(cfg, isps); err != nil {
-				glog.Errorf("error checking pods: %s", err)
+			securitypolicy.RecordConflicts(securitypolicy.Conflicts(isps))
+			gaps, err := listAttestationPolicies(cfg, "")
+			if err != nil {
+				glog.Errorf("fetching generic attestation policies: %s", err)
+				continue
+			}
+			nss := namespaces(isps, gaps)
+			cfg.Reporter.Retain(nss)
+			for _, ns := range nss {
+				ctrl.Enqueue(ns)
 			}
 		case <-done:
+			<-stopped
 			return
 		}
 	}
 }
 
-// CheckPods checks all running pods against defined policies.
-func CheckPods(cfg Config, isps []v1beta1.ImageSecurityPolicy) error {
-	r := review.New(cfg.Client, cfg.ReviewConfig)
+// watchPolicies enqueues the namespace of every ImageSecurityPolicy created or whose
+// spec hash changed, until ctx is done. The watch is restarted when it ends.
+func watchPolicies(ctx context.Context, watcher func() (watch.Interface, error), enqueue func(string)) {
+	hashes := map[string]string{}
+	for {
+		w, err := watcher()
+		if err != nil {
+			glog.Errorf("failed to watch image security policies: %v", err)
+		} else {
+			policyChanges(ctx, w, hashes, enqueue)
+			w.Stop()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryPeriod):
+		}
+	}
+}
+
+// policyChanges processes the events of w until it ends or ctx is done. hashes holds
+// the spec hash of each policy seen, by namespace/name, so restarted watches only
+// enqueue namespaces whose policies changed meanwhile.
+func policyChanges(ctx context.Context, w watch.Interface, hashes map[string]string, enqueue func(string)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-w.ResultChan():
+			if !ok {
+				return
+			}
+			isp, ok := e.Object.(*v1beta1.ImageSecurityPolicy)
+			if !ok {
+				glog.Warningf("unexpected %s event watching image security policies: %v", e.Type, e.Object)
+				continue
+			}
+			key := policyKey(*isp)
+			switch e.Type {
+			case watch.Added, watch.Modified:
+				hash := securitypolicy.Hash(*isp)
+				if hashes[key] == hash {
+					continue
+				}
+				hashes[key] = hash
+				glog.Infof("ImageSecurityPolicy %s changed, reviewing pods of namespace %s", key, isp.Namespace)
+				enqueue(isp.Namespace)
+			case watch.Deleted:
+				delete(hashes, key)
+			}
+		}
+	}
+}
+
+// checkNamespace checks the pods of a namespace against its policies. The policies
+// are marked as not reconciled while it fails.
+func checkNamespace(cfg Config, namespace string) error {
+	isps, err := cfg.SecurityPolicyLister(namespace)
+	if err != nil {
+		return err
+	}
+	gaps, err := listAttestationPolicies(cfg, namespace)
+	if err != nil {
+		return err
+	}
+	if err := podChecker(cfg, isps, gaps); err != nil {
+		if cfg.StatusUpdater != nil {
+			for _, isp := range isps {
+				isp.Status.Conditions = reconciledConditions(isp.Status.Conditions, err)
+				if err := cfg.StatusUpdater(&isp); err != nil {
+					glog.Errorf("failed to update status of ImageSecurityPolicy %s: %v", policyKey(isp), err)
+				}
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// reconciledConditions returns conditions with the Reconciled condition set after a
+// review which failed with err, or succeeded if err is nil. The transition time is
+// kept while the status does not change.
+func reconciledConditions(conditions []v1beta1.ImageSecurityPolicyCondition, err error) []v1beta1.ImageSecurityPolicyCondition {
+	c := v1beta1.ImageSecurityPolicyCondition{
+		Type:               v1beta1.ImageSecurityPolicyReconciled,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(now()),
+		Reason:             "PodsReviewed",
+	}
+	if err != nil {
+		c.Status, c.Reason, c.Message = corev1.ConditionFalse, "ReviewFailed", err.Error()
+	}
+	result := []v1beta1.ImageSecurityPolicyCondition{}
+	for _, previous := range conditions {
+		if previous.Type != c.Type {
+			result = append(result, previous)
+		} else if previous.Status == c.Status {
+			c.LastTransitionTime = previous.LastTransitionTime
+		}
+	}
+	return append(result, c)
+}
+
+// listAttestationPolicies returns the GenericAttestationPolicies of namespace, or none
+// if the AttestationPolicyLister isn't set.
+func listAttestationPolicies(cfg Config, namespace string) ([]v1beta1.GenericAttestationPolicy, error) {
+	if cfg.AttestationPolicyLister == nil {
+		return nil, nil
+	}
+	return cfg.AttestationPolicyLister(namespace)
+}
+
+// namespaces returns the namespaces of the policies, in order of appearance.
+func namespaces(isps []v1beta1.ImageSecurityPolicy, gaps []v1beta1.GenericAttestationPolicy) []string {
+	seen := map[string]bool{}
+	nss := []string{}
+	add := func(ns string) {
+		if !seen[ns] {
+			seen[ns] = true
+			nss = append(nss, ns)
+		}
+	}
+	for _, isp := range isps {
+		add(isp.Namespace)
+	}
+	for _, gap := range gaps {
+		add(gap.Namespace)
+	}
+	return nss
+}
+
+// namespacePolicies returns the ImageSecurityPolicies of namespace.
+func namespacePolicies(isps []v1beta1.ImageSecurityPolicy, namespace string) []v1beta1.ImageSecurityPolicy {
+	l := []v1beta1.ImageSecurityPolicy{}
 	for _, isp := range isps {
-		ps, err := cfg.PodLister(isp.Namespace)
+		if isp.Namespace == namespace {
+			l = append(l, isp)
+		}
+	}
+	return l
+}
+
+// namespaceAttestationPolicies returns the GenericAttestationPolicies of namespace.
+func namespaceAttestationPolicies(gaps []v1beta1.GenericAttestationPolicy, namespace string) []v1beta1.GenericAttestationPolicy {
+	l := []v1beta1.GenericAttestationPolicy{}
+	for _, gap := range gaps {
+		if gap.Namespace == namespace {
+			l = append(l, gap)
+		}
+	}
+	return l
+}
+
+// clearingStrategies returns the strategies which may have labeled pods out of policy:
+// the strategy of the review and the violation strategies configured by policies.
+func clearingStrategies(rc *review.Config, isps []v1beta1.ImageSecurityPolicy) []violation.Strategy {
+	strategies := []violation.Strategy{rc.Strategy}
+	for _, isp := range isps {
+		if len(isp.Spec.ViolationStrategies) == 0 {
+			continue
+		}
+		// Invalid strategies were reported by the review
+		if c, err := violation.NewChain(isp.Spec.ViolationStrategies, rc.Notifier); err == nil {
+			strategies = append(strategies, c)
+		}
+	}
+	return strategies
+}
+
+// CheckPods checks all running pods against defined policies, ImageSecurityPolicies
+// and GenericAttestationPolicies, and updates the status of the ImageSecurityPolicies
+// of each namespace once all its pods are checked.
+func CheckPods(cfg Config, isps []v1beta1.ImageSecurityPolicy, gaps []v1beta1.GenericAttestationPolicy) error {
+	r := cfg.Reviewer
+	if r == nil {
+		r = review.New(cfg.Client, cfg.ReviewConfig)
+	}
+	for _, ns := range namespaces(isps, gaps) {
+		nsGAPs := namespaceAttestationPolicies(gaps, ns)
+		ps, err := cfg.PodLister(ns)
 		if err != nil {
 			return err
 		}
+		c := newCompliance()
 		for _, p := range ps {
+			if ok, err := cfg.Exemptions.ExemptPod(p); err != nil {
+				glog.Errorf("failed to check exemptions of pod %q, reviewing it: %v", p.Name, err)
+			} else if ok {
+				glog.Infof("pod %q is exempt from review", p.Name)
+				continue
+			}
 			glog.Infof("checking pod %q", p.Name)
-			if err := r.Review(admission.PodImages(p), isps, &p); err != nil {
+			images := admission.PodImages(p)
+			decision := &decisionlog.Decision{}
+			ctx := securitypolicy.WithoutWaiting(securitypolicy.WithEvents(decisionlog.NewContext(context.Background(), decision)))
+			err := r.ReviewContext(ctx, images, isps, &p)
+			violations := decision.Violations
+			if err != nil {
+				// The review stops at the first violation handled, so validate all the
+				// policies of the namespace for the compliance.
+				if all, verr := validateAll(r, cfg, ns, isps, p, images); verr != nil {
+					glog.Errorf("failed to validate pod %q for compliance: %v", p.Name, verr)
+				} else {
+					violations = all
+				}
+			}
+			c.add(ns, isps, p, images, decision, violations)
+			if err != nil {
 				glog.Error(err)
+				continue
+			}
+			if err := r.ReviewGAP(ctx, images, nsGAPs, &p); err != nil {
+				glog.Error(err)
+				continue
 			}
+			r.Promote(ctx, images, isps, &p)
+			// Clear the labels of pods which are back in policy
+			if _, ok := p.Labels[constants.InvalidImageSecPolicy]; ok {
+				glog.Infof("pod %q no longer violates its image security policy", p.Name)
+				for _, s := range clearingStrategies(cfg.ReviewConfig, isps) {
+					if err := s.HandleViolation("", &p, nil); err != nil {
+						glog.Error(err)
+					}
+				}
+			}
+		}
+		c.updateStatus(cfg, ns, isps)
+		if cfg.Reporter != nil {
+			cfg.Reporter.Update(c.report(cfg, ns, isps))
 		}
+		cfg.Records.Record(context.Background(), c.record(ns))
 	}
 	return nil
 }
 
+// compliance aggregates the reviews of the pods of a namespace per ImageSecurityPolicy,
+// by namespace/name.
+type compliance struct {
+	images     map[string]map[string]bool
+	pods       map[string]int
+	violations map[string]map[string]int
+	// reviewed counts the pods reviewed, violating holds the violations of each image
+	// and unattested the pods with an image without verified attestation, for reports.
+	reviewed   int
+	violating  map[string]*report.ViolatingImage
+	unattested []string
+	// counts holds the number of violations of each image, for records
+	counts map[string]int
+}
+
+func newCompliance() *compliance {
+	return &compliance{
+		images:     map[string]map[string]bool{},
+		pods:       map[string]int{},
+		violations: map[string]map[string]int{},
+		violating:  map[string]*report.ViolatingImage{},
+		counts:     map[string]int{},
+	}
+}
+
+// validateAll returns the violations of the images of pod p for each ImageSecurityPolicy
+// of namespace ns selecting it, without handling them.
+func validateAll(r review.Interface, cfg Config, ns string, isps []v1beta1.ImageSecurityPolicy, p corev1.Pod, images []string) ([]decisionlog.Violation, error) {
+	selected, err := securitypolicy.SelectImageSecurityPolicies(namespacePolicies(isps, ns), p.Labels)
+	if err != nil || len(selected) == 0 {
+		return nil, err
+	}
+	images, err = cfg.ReviewConfig.ClusterWhitelistedImagesRemover(images)
+	if err != nil {
+		return nil, err
+	}
+	ctx := securitypolicy.WithoutWaiting(securitypolicy.WithPod(context.Background(), &p))
+	var violations []decisionlog.Violation
+	for _, image := range images {
+		results, err := r.Validate(ctx, image, selected)
+		if err != nil {
+			return nil, err
+		}
+		for i, isp := range selected {
+			for _, v := range results[i] {
+				violations = append(violations, decisionlog.Violation{
+					Image:  image,
+					Policy: policyKey(isp),
+					Type:   v.Type().ToString(),
+					Reason: string(v.Reason()),
+				})
+			}
+		}
+	}
+	return violations, nil
+}
+
+// add records the review of pod p in namespace ns, with the violations of all the
+// policies selecting it.
+func (c *compliance) add(ns string, isps []v1beta1.ImageSecurityPolicy, p corev1.Pod, images []string, d *decisionlog.Decision, violations []decisionlog.Violation) {
+	selected, err := securitypolicy.SelectImageSecurityPolicies(isps, p.Labels)
+	if err != nil {
+		glog.Error(err)
+		return
+	}
+	for _, isp := range selected {
+		if isp.Namespace != ns {
+			continue
+		}
+		key := policyKey(isp)
+		if c.images[key] == nil {
+			c.images[key] = map[string]bool{}
+		}
+		for _, image := range images {
+			c.images[key][image] = true
+		}
+	}
+	violating := map[string]bool{}
+	for _, v := range violations {
+		if c.violations[v.Policy] == nil {
+			c.violations[v.Policy] = map[string]int{}
+		}
+		c.violations[v.Policy][v.Type]++
+		violating[v.Policy] = true
+
+		vi := c.violating[v.Image]
+		if vi == nil {
+			vi = &report.ViolatingImage{Image: v.Image}
+			c.violating[v.Image] = vi
+		}
+		vi.Pods = appendMissing(vi.Pods, p.Name)
+		vi.Policies = appendMissing(vi.Policies, v.Policy)
+		vi.Types = appendMissing(vi.Types, v.Type)
+		c.counts[v.Image]++
+	}
+	for key := range violating {
+		c.pods[key]++
+	}
+
+	c.reviewed++
+	attested := map[string]bool{}
+	for _, a := range d.Attestations {
+		attested[a.Image] = attested[a.Image] || a.Attested
+	}
+	for _, ok := range attested {
+		if !ok {
+			c.unattested = append(c.unattested, p.Name)
+			break
+		}
+	}
+}
+
+// report returns the compliance of namespace ns. The CVEs waived by its policies are
+// those whitelisted among the vulnerabilities of the images they reviewed, which are
+// only read once the report is written.
+func (c *compliance) report(cfg Config, ns string, isps []v1beta1.ImageSecurityPolicy) report.Namespace {
+	r := report.Namespace{
+		Name:           ns,
+		ReviewTime:     now(),
+		Pods:           c.reviewed,
+		UnattestedPods: c.unattested,
+	}
+	images := map[string]bool{}
+	var policies []v1beta1.ImageSecurityPolicy
+	reviewed := map[string][]string{}
+	for _, isp := range isps {
+		if isp.Namespace != ns {
+			continue
+		}
+		key := policyKey(isp)
+		for image := range c.images[key] {
+			images[image] = true
+			reviewed[key] = append(reviewed[key], image)
+		}
+		sort.Strings(reviewed[key])
+		policies = append(policies, isp)
+	}
+	if cfg.Client != nil && len(policies) > 0 {
+		client := cfg.Client
+		r.Waive = func(ctx context.Context) []report.WaivedCVE {
+			return waivedCVEs(ctx, client, policies, reviewed)
+		}
+	}
+	r.Images = len(images)
+	for _, vi := range c.violating {
+		r.ViolatingImages = append(r.ViolatingImages, *vi)
+	}
+	sort.Slice(r.ViolatingImages, func(i, j int) bool {
+		return r.ViolatingImages[i].Image < r.ViolatingImages[j].Image
+	})
+	return r
+}
+
+// waivedCVEs returns the CVEs whitelisted by each of isps among the vulnerabilities
+// of the images it reviewed, by policy key.
+func waivedCVEs(ctx context.Context, client metadata.Fetcher, isps []v1beta1.ImageSecurityPolicy, reviewed map[string][]string) []report.WaivedCVE {
+	var waivedCVEs []report.WaivedCVE
+	vulnz := map[string][]metadata.Vulnerability{}
+	for _, isp := range isps {
+		key := policyKey(isp)
+		waived := map[string][]string{}
+		var cves []string
+		for _, image := range reviewed[key] {
+			vs, ok := vulnz[image]
+			if !ok {
+				var err error
+				if vs, err = client.Vulnerabilities(ctx, image); err != nil {
+					glog.Errorf("failed to get vulnerabilities of %s for the compliance report: %v", image, err)
+					continue
+				}
+				vulnz[image] = vs
+			}
+			for _, cve := range securitypolicy.WaivedCVEs(isp, vs) {
+				if waived[cve] == nil {
+					cves = append(cves, cve)
+				}
+				waived[cve] = append(waived[cve], image)
+			}
+		}
+		sort.Strings(cves)
+		for _, cve := range cves {
+			waivedCVEs = append(waivedCVEs, report.WaivedCVE{CVE: cve, Policy: key, Images: waived[cve]})
+		}
+	}
+	return waivedCVEs
+}
+
+// record returns the ViolationRecord of namespace ns.
+func (c *compliance) record(ns string) *v1beta1.ViolationRecord {
+	reviewed := now()
+	r := &v1beta1.ViolationRecord{
+		ObjectMeta: metav1.ObjectMeta{Name: records.Name(reviewed), Namespace: ns},
+		Spec: v1beta1.ViolationRecordSpec{
+			ReviewTime: metav1.NewTime(reviewed),
+			Pods:       c.reviewed,
+		},
+	}
+	images := map[string]bool{}
+	for key, imgs := range c.images {
+		if strings.HasPrefix(key, ns+"/") {
+			for image := range imgs {
+				images[image] = true
+			}
+		}
+	}
+	r.Spec.Images = len(images)
+	for key, types := range c.violations {
+		if !strings.HasPrefix(key, ns+"/") {
+			continue
+		}
+		for t, n := range types {
+			if r.Spec.Violations == nil {
+				r.Spec.Violations = map[string]int{}
+			}
+			r.Spec.Violations[t] += n
+		}
+	}
+	pods := map[string]bool{}
+	for image, vi := range c.violating {
+		for _, p := range vi.Pods {
+			pods[p] = true
+		}
+		r.Spec.ViolatingImages = append(r.Spec.ViolatingImages, v1beta1.ViolatingImageRecord{Image: image, Violations: c.counts[image]})
+	}
+	r.Spec.ViolatingPods = len(pods)
+	sort.Slice(r.Spec.ViolatingImages, func(i, j int) bool {
+		return r.Spec.ViolatingImages[i].Image < r.Spec.ViolatingImages[j].Image
+	})
+	return r
+}
+
+// appendMissing appends s to list unless it is already in it.
+func appendMissing(list []string, s string) []string {
+	for _, l := range list {
+		if l == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// updateStatus writes the compliance of the policies of namespace ns, failures are logged.
+func (c *compliance) updateStatus(cfg Config, ns string, isps []v1beta1.ImageSecurityPolicy) {
+	if cfg.StatusUpdater == nil {
+		return
+	}
+	scanned := metav1.NewTime(now())
+	for _, isp := range isps {
+		if isp.Namespace != ns {
+			continue
+		}
+		key := policyKey(isp)
+		isp.Status = v1beta1.ImageSecurityPolicyStatus{
+			ScannedImages: len(c.images[key]),
+			ViolatingPods: c.pods[key],
+			LastScanTime:  &scanned,
+			Violations:    c.violations[key],
+			Conditions:    reconciledConditions(isp.Status.Conditions, nil),
+		}
+		if err := cfg.StatusUpdater(&isp); err != nil {
+			glog.Errorf("failed to update status of ImageSecurityPolicy %s: %v", key, err)
+		}
+	}
+}
+
+func policyKey(isp v1beta1.ImageSecurityPolicy) string {
+	return fmt.Sprintf("%s/%s", isp.Namespace, isp.Name)
+}
+
 // RunInForeground checks Pods in foreground.
 func RunInForeground(cfg Config) error {
 	isps, err := cfg.SecurityPolicyLister("")
@@ -126,5 +679,9 @@ func RunInForeground(cfg Config) error {
 		return err
 	}
 	glog.Infof("got ISPs: %v", isps)
-	return podChecker(cfg, isps)
+	gaps, err := listAttestationPolicies(cfg, "")
+	if err != nil {
+		return err
+	}
+	return podChecker(cfg, isps, gaps)
 }
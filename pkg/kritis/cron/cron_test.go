@@ -18,19 +18,26 @@ package cron
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
 	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/exemption"
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
 	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/records"
+	"github.com/grafeas/kritis/pkg/kritis/report"
 	"github.com/grafeas/kritis/pkg/kritis/review"
 	"github.com/grafeas/kritis/pkg/kritis/secrets"
 	"github.com/grafeas/kritis/pkg/kritis/testutil"
 	"github.com/grafeas/kritis/pkg/kritis/violation"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
 )
 
 func NoopClusterWhitelistedImagesRemover(images []string) ([]string, error) {
@@ -46,7 +53,7 @@ func TestStartCancels(t *testing.T) {
 
 	// Mock the check function and reset after the test.
 	originalChecker := podChecker
-	podChecker = func(cfg Config, isps []v1beta1.ImageSecurityPolicy) error {
+	podChecker = func(cfg Config, isps []v1beta1.ImageSecurityPolicy, gaps []v1beta1.GenericAttestationPolicy) error {
 		checked = true
 		return nil
 	}
@@ -62,7 +69,7 @@ func TestStartCancels(t *testing.T) {
 	// Make sure the checker is called and Start cancels correctly when the deadline expires.
 	Start(c, Config{
 		SecurityPolicyLister: func(namespace string) ([]v1beta1.ImageSecurityPolicy, error) {
-			return nil, nil
+			return isps, nil
 		},
 	}, checkInterval)
 
@@ -75,7 +82,7 @@ type imageViolations struct {
 	imageMap map[string]bool
 }
 
-func (iv *imageViolations) violationChecker(isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher securitypolicy.AttestorFetcher) ([]policy.Violation, error) {
+func (iv *imageViolations) violationChecker(ctx context.Context, now time.Time, isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher securitypolicy.AttestorFetcher) ([]policy.Violation, error) {
 	if ok := iv.imageMap[image]; ok {
 		v := securitypolicy.NewViolation(&metadata.Vulnerability{Severity: "foo"}, 0, "")
 		vs := []policy.Violation{}
@@ -216,7 +223,7 @@ func TestCheckPods(t *testing.T) {
 			ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
 		}
 		t.Run(tt.name, func(t *testing.T) {
-			if err := CheckPods(tt.args.cfg, tt.args.isps); err != nil {
+			if err := CheckPods(tt.args.cfg, tt.args.isps, nil); err != nil {
 				t.Fatalf("CheckPods() error = %v", err)
 			}
 		})
@@ -225,3 +232,385 @@ func TestCheckPods(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckPodsAttestationPolicies(t *testing.T) {
+	_, pub := testutil.CreateSecret(t, "sec")
+	th := violation.MemoryStrategy{
+		Violations:   map[string]bool{},
+		Attestations: map[string]bool{},
+	}
+	cfg := Config{
+		Client:    &testutil.MockMetadataClient{},
+		PodLister: testPods.list,
+		ReviewConfig: &review.Config{
+			Auths: func(string, name string) (*v1beta1.AttestationAuthority, error) {
+				return &v1beta1.AttestationAuthority{
+					ObjectMeta: metav1.ObjectMeta{Name: name},
+					Spec:       v1beta1.AttestationAuthoritySpec{PublicKeyData: base64.StdEncoding.EncodeToString([]byte(pub))},
+				}, nil
+			},
+			Strategy:                        &th,
+			ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+		},
+	}
+	// Namespaces with only GenericAttestationPolicies are reviewed too.
+	gaps := []v1beta1.GenericAttestationPolicy{{
+		ObjectMeta: metav1.ObjectMeta{Name: "gap", Namespace: "bar"},
+		Spec:       v1beta1.GenericAttestationPolicySpec{AttestationAuthorityNames: []string{"a"}},
+	}}
+	if err := CheckPods(cfg, nil, gaps); err != nil {
+		t.Fatalf("CheckPods() error = %v", err)
+	}
+	testutil.DeepEqual(t, map[string]bool{testutil.QualifiedImage: true}, th.Violations)
+}
+
+type clearingStrategy struct {
+	violation.MemoryStrategy
+	cleared []string
+}
+
+func (s *clearingStrategy) HandleViolation(image string, p *v1.Pod, v []policy.Violation) error {
+	if len(v) == 0 {
+		s.cleared = append(s.cleared, p.Name)
+		return nil
+	}
+	return s.MemoryStrategy.HandleViolation(image, p, v)
+}
+
+func TestCheckPodsClearsLabels(t *testing.T) {
+	labeled := testPods.pl[0]
+	labeled.Name = "labeled"
+	labeled.Labels = map[string]string{constants.InvalidImageSecPolicy: constants.InvalidImageSecPolicyLabelValue}
+	lister := testLister{pl: []v1.Pod{testPods.pl[0], labeled}}
+	for _, test := range []struct {
+		name     string
+		validate securitypolicy.ValidateFunc
+		cleared  []string
+	}{
+		{"back in policy", noVulnz.violationChecker, []string{"labeled"}},
+		{"still violating", someVulnz.violationChecker, nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := &clearingStrategy{MemoryStrategy: violation.MemoryStrategy{
+				Violations:   map[string]bool{},
+				Attestations: map[string]bool{},
+			}}
+			cfg := Config{
+				Client:    &testutil.MockMetadataClient{},
+				PodLister: lister.list,
+				ReviewConfig: &review.Config{
+					Validate: test.validate,
+					Auths: func(string, string) (*v1beta1.AttestationAuthority, error) {
+						return &v1beta1.AttestationAuthority{}, nil
+					},
+					Strategy:                        s,
+					ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+				},
+			}
+			if err := CheckPods(cfg, isps, nil); err != nil {
+				t.Fatalf("CheckPods() error = %v", err)
+			}
+			testutil.DeepEqual(t, test.cleared, s.cleared)
+		})
+	}
+}
+
+func TestCheckPodsUpdatesStatus(t *testing.T) {
+	scanned := time.Date(2018, 10, 2, 15, 4, 5, 0, time.UTC)
+	originalNow := now
+	now = func() time.Time { return scanned }
+	defer func() { now = originalNow }()
+
+	foo := v1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: "foo"}}
+	frontend := v1beta1.ImageSecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "foo"},
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "frontend"}},
+		},
+	}
+	lastScan := metav1.NewTime(scanned)
+	reconciled := []v1beta1.ImageSecurityPolicyCondition{{
+		Type:               v1beta1.ImageSecurityPolicyReconciled,
+		Status:             v1.ConditionTrue,
+		LastTransitionTime: lastScan,
+		Reason:             "PodsReviewed",
+	}}
+	for _, test := range []struct {
+		name     string
+		validate securitypolicy.ValidateFunc
+		expected map[string]v1beta1.ImageSecurityPolicyStatus
+	}{
+		{
+			name:     "compliant",
+			validate: noVulnz.violationChecker,
+			expected: map[string]v1beta1.ImageSecurityPolicyStatus{
+				"isp":      {ScannedImages: 1, LastScanTime: &lastScan, Conditions: reconciled},
+				"frontend": {LastScanTime: &lastScan, Conditions: reconciled},
+			},
+		},
+		{
+			name:     "violating",
+			validate: someVulnz.violationChecker,
+			expected: map[string]v1beta1.ImageSecurityPolicyStatus{
+				"isp":      {ScannedImages: 1, ViolatingPods: 1, LastScanTime: &lastScan, Violations: map[string]int{"UnqualifiedImageViolation": 1}, Conditions: reconciled},
+				"frontend": {LastScanTime: &lastScan, Conditions: reconciled},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			statuses := map[string]v1beta1.ImageSecurityPolicyStatus{}
+			cfg := Config{
+				Client:    &testutil.MockMetadataClient{},
+				PodLister: testPods.list,
+				ReviewConfig: &review.Config{
+					Validate: test.validate,
+					Auths: func(string, string) (*v1beta1.AttestationAuthority, error) {
+						return &v1beta1.AttestationAuthority{}, nil
+					},
+					Strategy: &violation.MemoryStrategy{
+						Violations:   map[string]bool{},
+						Attestations: map[string]bool{},
+					},
+					ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+				},
+				StatusUpdater: func(isp *v1beta1.ImageSecurityPolicy) error {
+					statuses[isp.Name] = isp.Status
+					return nil
+				},
+			}
+			if err := CheckPods(cfg, []v1beta1.ImageSecurityPolicy{foo, frontend}, nil); err != nil {
+				t.Fatalf("CheckPods() error = %v", err)
+			}
+			testutil.DeepEqual(t, test.expected, statuses)
+		})
+	}
+}
+
+func TestCheckPodsUpdatesStatusOfAllPolicies(t *testing.T) {
+	originalNow := now
+	now = func() time.Time { return time.Date(2018, 10, 2, 15, 4, 5, 0, time.UTC) }
+	defer func() { now = originalNow }()
+
+	// The review stops at the first violating policy, the second one must be counted too.
+	first := v1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Name: "first", Namespace: "foo"}}
+	second := v1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Name: "second", Namespace: "foo"}}
+	violatingPods := map[string]int{}
+	cfg := Config{
+		Client:    &testutil.MockMetadataClient{},
+		PodLister: testPods.list,
+		ReviewConfig: &review.Config{
+			Validate: someVulnz.violationChecker,
+			Auths: func(string, string) (*v1beta1.AttestationAuthority, error) {
+				return &v1beta1.AttestationAuthority{}, nil
+			},
+			Strategy:                        &violation.MemoryStrategy{Violations: map[string]bool{}, Attestations: map[string]bool{}},
+			ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+		},
+		StatusUpdater: func(isp *v1beta1.ImageSecurityPolicy) error {
+			violatingPods[isp.Name] = isp.Status.ViolatingPods
+			return nil
+		},
+	}
+	if err := CheckPods(cfg, []v1beta1.ImageSecurityPolicy{first, second}, nil); err != nil {
+		t.Fatalf("CheckPods() error = %v", err)
+	}
+	testutil.DeepEqual(t, map[string]int{"first": 1, "second": 1}, violatingPods)
+}
+
+func TestReconciledConditions(t *testing.T) {
+	start := time.Date(2018, 10, 2, 15, 4, 5, 0, time.UTC)
+	current := start
+	originalNow := now
+	now = func() time.Time { return current }
+	defer func() { now = originalNow }()
+
+	conditions := reconciledConditions(nil, fmt.Errorf("failed to list pods"))
+	testutil.DeepEqual(t, []v1beta1.ImageSecurityPolicyCondition{{
+		Type:               v1beta1.ImageSecurityPolicyReconciled,
+		Status:             v1.ConditionFalse,
+		LastTransitionTime: metav1.NewTime(start),
+		Reason:             "ReviewFailed",
+		Message:            "failed to list pods",
+	}}, conditions)
+	// The transition time only changes with the status
+	current = start.Add(time.Minute)
+	conditions = reconciledConditions(conditions, fmt.Errorf("failed to list pods"))
+	testutil.DeepEqual(t, metav1.NewTime(start), conditions[0].LastTransitionTime)
+	current = start.Add(2 * time.Minute)
+	conditions = reconciledConditions(conditions, nil)
+	testutil.DeepEqual(t, 1, len(conditions))
+	testutil.DeepEqual(t, v1.ConditionTrue, conditions[0].Status)
+	testutil.DeepEqual(t, metav1.NewTime(current), conditions[0].LastTransitionTime)
+}
+
+func TestCheckPodsReportsCompliance(t *testing.T) {
+	reviewed := time.Date(2018, 10, 2, 15, 4, 5, 0, time.UTC)
+	originalNow := now
+	now = func() time.Time { return reviewed }
+	defer func() { now = originalNow }()
+
+	isp := v1beta1.ImageSecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: "foo"},
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{WhitelistCVEs: []string{"CVE-W"}},
+		},
+	}
+	reporter := report.NewReporter(time.Hour)
+	client := &testutil.MockMetadataClient{
+		Vulnz: []metadata.Vulnerability{{CVE: "CVE-W"}, {CVE: "CVE-1"}},
+	}
+	cfg := Config{
+		Client:    client,
+		PodLister: testPods.list,
+		ReviewConfig: &review.Config{
+			Validate: someVulnz.violationChecker,
+			Auths: func(string, string) (*v1beta1.AttestationAuthority, error) {
+				return &v1beta1.AttestationAuthority{}, nil
+			},
+			Strategy: &violation.MemoryStrategy{
+				Violations:   map[string]bool{},
+				Attestations: map[string]bool{},
+			},
+			ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+		},
+		Reporter: reporter,
+	}
+	if err := CheckPods(cfg, []v1beta1.ImageSecurityPolicy{isp}, nil); err != nil {
+		t.Fatalf("CheckPods() error = %v", err)
+	}
+	// The vulnerabilities are only read for the waived CVEs once the report is written
+	reviewCalls := client.Calls("Vulnerabilities", "")
+	expected := []report.Namespace{{
+		Name:       "foo",
+		ReviewTime: reviewed,
+		Pods:       1,
+		Images:     1,
+		ViolatingImages: []report.ViolatingImage{{
+			Image:    testutil.QualifiedImage,
+			Pods:     []string{"foo"},
+			Policies: []string{"foo/isp"},
+			Types:    []string{"UnqualifiedImageViolation"},
+		}},
+		WaivedCVEs:     []report.WaivedCVE{{CVE: "CVE-W", Policy: "foo/isp", Images: []string{testutil.QualifiedImage}}},
+		UnattestedPods: []string{"foo"},
+	}}
+	testutil.DeepEqual(t, expected, reporter.Report(context.Background()).Namespaces)
+	testutil.DeepEqual(t, reviewCalls+1, client.Calls("Vulnerabilities", ""))
+}
+
+// recordStore keeps the records written in memory.
+type recordStore struct {
+	records []*v1beta1.ViolationRecord
+}
+
+func (s *recordStore) Write(ctx context.Context, r *v1beta1.ViolationRecord) error {
+	s.records = append(s.records, r)
+	return nil
+}
+
+func (s *recordStore) Prune(ctx context.Context, namespace string, t time.Time) error {
+	return nil
+}
+
+func TestCheckPodsRecordsViolations(t *testing.T) {
+	reviewed := time.Date(2018, 10, 2, 15, 4, 5, 0, time.UTC)
+	originalNow := now
+	now = func() time.Time { return reviewed }
+	defer func() { now = originalNow }()
+
+	isp := v1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: "foo"}}
+	store := &recordStore{}
+	cfg := Config{
+		Client:    &testutil.MockMetadataClient{},
+		PodLister: testPods.list,
+		ReviewConfig: &review.Config{
+			Validate: someVulnz.violationChecker,
+			Auths: func(string, string) (*v1beta1.AttestationAuthority, error) {
+				return &v1beta1.AttestationAuthority{}, nil
+			},
+			Strategy: &violation.MemoryStrategy{
+				Violations:   map[string]bool{},
+				Attestations: map[string]bool{},
+			},
+			ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+		},
+		Records: records.NewRecorder(store, time.Hour),
+	}
+	if err := CheckPods(cfg, []v1beta1.ImageSecurityPolicy{isp}, nil); err != nil {
+		t.Fatalf("CheckPods() error = %v", err)
+	}
+	expected := []*v1beta1.ViolationRecord{{
+		ObjectMeta: metav1.ObjectMeta{Name: "violations-20181002-150405", Namespace: "foo"},
+		Spec: v1beta1.ViolationRecordSpec{
+			ReviewTime:      metav1.NewTime(reviewed),
+			Pods:            1,
+			Images:          1,
+			ViolatingPods:   1,
+			Violations:      map[string]int{"UnqualifiedImageViolation": 1},
+			ViolatingImages: []v1beta1.ViolatingImageRecord{{Image: testutil.QualifiedImage, Violations: 1}},
+		},
+	}}
+	testutil.DeepEqual(t, expected, store.records)
+}
+
+func TestCheckPodsExemptions(t *testing.T) {
+	exemptions, err := exemption.New([]v1beta1.ExemptionSpec{{Namespaces: []string{"bar"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &violation.MemoryStrategy{
+		Violations:   map[string]bool{},
+		Attestations: map[string]bool{},
+	}
+	cfg := Config{
+		Client:    &testutil.MockMetadataClient{},
+		PodLister: testPods.list,
+		ReviewConfig: &review.Config{
+			Validate: someVulnz.violationChecker,
+			Auths: func(string, string) (*v1beta1.AttestationAuthority, error) {
+				return &v1beta1.AttestationAuthority{}, nil
+			},
+			Strategy:                        s,
+			ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+		},
+		Exemptions: exemptions,
+	}
+	if err := CheckPods(cfg, isps, nil); err != nil {
+		t.Fatalf("CheckPods() error = %v", err)
+	}
+	if len(s.Violations) != 0 {
+		t.Errorf("expected exempt pods not to be reviewed, got violations %v", s.Violations)
+	}
+}
+
+func TestPolicyChanges(t *testing.T) {
+	isp := func(ns, maxSev string) *v1beta1.ImageSecurityPolicy {
+		p := &v1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "isp"}}
+		p.Spec.PackageVulnerabilityRequirements.MaximumSeverity = maxSev
+		return p
+	}
+	w := watch.NewFake()
+	var enqueued []string
+	hashes := map[string]string{}
+	done := make(chan struct{})
+	go func() {
+		policyChanges(context.Background(), w, hashes, func(ns string) { enqueued = append(enqueued, ns) })
+		close(done)
+	}()
+	w.Add(isp("foo", "HIGH"))
+	w.Add(isp("bar", "HIGH"))
+	// Only the status changed
+	updated := isp("foo", "HIGH")
+	updated.Status.ViolatingPods = 2
+	w.Modify(updated)
+	w.Modify(isp("bar", "LOW"))
+	w.Delete(isp("foo", "HIGH"))
+	w.Add(isp("foo", "HIGH"))
+	w.Stop()
+	<-done
+	testutil.DeepEqual(t, []string{"foo", "bar", "bar", "foo"}, enqueued)
+	testutil.DeepEqual(t, map[string]string{
+		"foo/isp": securitypolicy.Hash(*isp("foo", "HIGH")),
+		"bar/isp": securitypolicy.Hash(*isp("bar", "LOW")),
+	}, hashes)
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package decisionlog writes a structured record of every admission decision
+// to stdout, files, Cloud Storage or BigQuery, so they can be audited later.
+package decisionlog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Decision records the review of an object by the admission webhook.
+type Decision struct {
+	mu sync.Mutex
+
+	Time      time.Time `json:"time"`
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name,omitempty"`
+	Operation string    `json:"operation"`
+	Allowed   bool      `json:"allowed"`
+	Message   string    `json:"message,omitempty"`
+	// Error is the failure of the webhook, answered with HTTP 500 so the API server
+	// applies the failurePolicy of the webhook
+	Error        string        `json:"error,omitempty"`
+	Breakglass   bool          `json:"breakglass,omitempty"`
+	FailedOpen   bool          `json:"failedOpen,omitempty"`
+	Images       []string      `json:"images,omitempty"`
+	Policies     []Policy      `json:"policies,omitempty"`
+	Violations   []Violation   `json:"violations,omitempty"`
+	Attestations []Attestation `json:"attestations,omitempty"`
+	Signatures   []Signature   `json:"signatures,omitempty"`
+	// Warnings are returned to the client of the API server with the decision
+	Warnings []string `json:"warnings,omitempty"`
+	// LatencyMillis is the time taken to review the object
+	LatencyMillis int64 `json:"latencyMillis"`
+}
+
+// Policy is a policy evaluated by a Decision.
+type Policy struct {
+	Kind string `json:"kind"`
+	// Name of the policy, as namespace/name
+	Name string `json:"name"`
+	// Version is the resourceVersion of the policy
+	Version string `json:"version,omitempty"`
+	// Hash of the spec of the policy, see securitypolicy.Hash
+	Hash string `json:"hash,omitempty"`
+}
+
+// Violation is a policy violation found by a Decision.
+type Violation struct {
+	Image  string `json:"image"`
+	Policy string `json:"policy"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// Attestation records whether an image had a verified attestation.
+type Attestation struct {
+	Image    string `json:"image"`
+	Attested bool   `json:"attested"`
+	// ID of the verified attestation occurrence, if any
+	ID string `json:"id,omitempty"`
+}
+
+// Signature records an attestation created by an authority for an image.
+type Signature struct {
+	Image     string `json:"image"`
+	Authority string `json:"authority"`
+	// Policy creating the attestation, as namespace/name
+	Policy string `json:"policy"`
+}
+
+// The Add methods may be called on a nil Decision, when decisions are not logged.
+
+// AddImages records the images reviewed.
+func (d *Decision) AddImages(images ...string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Images = append(d.Images, images...)
+}
+
+// AddPolicy records a policy evaluated at version with the spec hash, unless it was already recorded.
+func (d *Decision) AddPolicy(kind, namespace, name, version, hash string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p := Policy{Kind: kind, Name: fmt.Sprintf("%s/%s", namespace, name), Version: version, Hash: hash}
+	for _, q := range d.Policies {
+		if q == p {
+			return
+		}
+	}
+	d.Policies = append(d.Policies, p)
+}
+
+// AddViolation records a violation of the policy namespace/name.
+func (d *Decision) AddViolation(image, namespace, name, typ, reason string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Violations = append(d.Violations, Violation{
+		Image:  image,
+		Policy: fmt.Sprintf("%s/%s", namespace, name),
+		Type:   typ,
+		Reason: reason,
+	})
+}
+
+// AddAttestation records whether image had a verified attestation, and its ID.
+func (d *Decision) AddAttestation(image, id string, attested bool) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Attestations = append(d.Attestations, Attestation{Image: image, Attested: attested, ID: id})
+}
+
+// AddSignature records that authority attested image for the policy namespace/name.
+func (d *Decision) AddSignature(image, authority, namespace, name string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Signatures = append(d.Signatures, Signature{
+		Image:     image,
+		Authority: authority,
+		Policy:    fmt.Sprintf("%s/%s", namespace, name),
+	})
+}
+
+// AddWarning records a warning for the client, unless it was already recorded.
+func (d *Decision) AddWarning(format string, args ...interface{}) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w := fmt.Sprintf(format, args...)
+	for _, v := range d.Warnings {
+		if v == w {
+			return
+		}
+	}
+	d.Warnings = append(d.Warnings, w)
+}
+
+// SetBreakglass records that the object was admitted with the breakglass annotation.
+func (d *Decision) SetBreakglass() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Breakglass = true
+}
+
+// SetFailedOpen records that the object was admitted as the metadata backend failed.
+func (d *Decision) SetFailedOpen() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.FailedOpen = true
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying d, for the reviewers to record into.
+func NewContext(ctx context.Context, d *Decision) context.Context {
+	return context.WithValue(ctx, contextKey{}, d)
+}
+
+// FromContext returns the Decision of ctx, or nil if it has none.
+func FromContext(ctx context.Context) *Decision {
+	d, _ := ctx.Value(contextKey{}).(*Decision)
+	return d
+}
+
+// Sink writes batches of decisions to a destination.
+type Sink interface {
+	Write(ctx context.Context, ds []*Decision) error
+}
+
+// sinks fans out decisions to all of its sinks.
+type sinks []Sink
+
+func (s sinks) Write(ctx context.Context, ds []*Decision) error {
+	var errs []string
+	for _, sink := range s {
+		if err := sink.Write(ctx, ds); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to write %d decisions: %s", len(ds), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// queueSize bounds the decisions waiting to be written.
+const queueSize = 1000
+
+// For testing
+var (
+	// batchSize is the most decisions written at once.
+	batchSize = 100
+	// batchInterval is how long a decision waits for others to be written with.
+	batchInterval = 5 * time.Second
+)
+
+// Log writes decisions to its sink in the background, in batches, so slow sinks
+// don't delay admission. Decisions are dropped if the queue is full.
+type Log struct {
+	sink  Sink
+	queue chan *Decision
+	done  chan struct{}
+}
+
+// NewLog returns a Log writing to sink.
+func NewLog(sink Sink) *Log {
+	l := &Log{
+		sink:  sink,
+		queue: make(chan *Decision, queueSize),
+		done:  make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+func (l *Log) run() {
+	defer close(l.done)
+	for d := range l.queue {
+		batch := []*Decision{d}
+		timeout := time.After(batchInterval)
+	collect:
+		for len(batch) < batchSize {
+			select {
+			case d, ok := <-l.queue:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, d)
+			case <-timeout:
+				break collect
+			}
+		}
+		if err := l.sink.Write(context.Background(), batch); err != nil {
+			glog.Errorf("failed to log admission decisions: %v", err)
+		}
+	}
+}
+
+// Record queues d to be written. It may be called on a nil Log.
+func (l *Log) Record(d *Decision) {
+	if l == nil || d == nil {
+		return
+	}
+	select {
+	case l.queue <- d:
+	default:
+		glog.Warningf("admission decision log is full, dropping decision for %s %s/%s", d.Kind, d.Namespace, d.Name)
+	}
+}
+
+// Close writes the queued decisions and stops the Log.
+func (l *Log) Close() {
+	if l == nil {
+		return
+	}
+	close(l.queue)
+	<-l.done
+}
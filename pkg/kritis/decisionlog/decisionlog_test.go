@@ -0,0 +1,105 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decisionlog
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+type recordingSink struct {
+	mu      sync.Mutex
+	batches [][]*Decision
+}
+
+func (s *recordingSink) Write(ctx context.Context, ds []*Decision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, ds)
+	return nil
+}
+
+func TestDecision(t *testing.T) {
+	d := &Decision{}
+	ctx := NewContext(context.Background(), d)
+	FromContext(ctx).AddImages("image1", "image2")
+	FromContext(ctx).AddPolicy("ImageSecurityPolicy", "default", "isp", "12", "abc")
+	FromContext(ctx).AddPolicy("ImageSecurityPolicy", "default", "isp", "12", "abc")
+	FromContext(ctx).AddViolation("image1", "default", "isp", "SeverityViolation", "too severe")
+	FromContext(ctx).AddAttestation("image2", "occ-1", true)
+	FromContext(ctx).AddSignature("image2", "aa", "default", "isp")
+	FromContext(ctx).AddWarning("key of %s failed to parse", "aa")
+	FromContext(ctx).AddWarning("key of %s failed to parse", "aa")
+	FromContext(ctx).SetBreakglass()
+
+	expected := &Decision{
+		Images:       []string{"image1", "image2"},
+		Policies:     []Policy{{Kind: "ImageSecurityPolicy", Name: "default/isp", Version: "12", Hash: "abc"}},
+		Violations:   []Violation{{Image: "image1", Policy: "default/isp", Type: "SeverityViolation", Reason: "too severe"}},
+		Attestations: []Attestation{{Image: "image2", Attested: true, ID: "occ-1"}},
+		Signatures:   []Signature{{Image: "image2", Authority: "aa", Policy: "default/isp"}},
+		Warnings:     []string{"key of aa failed to parse"},
+		Breakglass:   true,
+	}
+	testutil.DeepEqual(t, expected, d)
+}
+
+func TestDecisionWithoutContext(t *testing.T) {
+	// Recording into a context without a Decision is a no-op.
+	d := FromContext(context.Background())
+	if d != nil {
+		t.Fatalf("expected no decision, got %v", d)
+	}
+	d.AddImages("image")
+	d.AddPolicy("ImageSecurityPolicy", "default", "isp", "12", "abc")
+	d.AddViolation("image", "default", "isp", "SeverityViolation", "too severe")
+	d.AddAttestation("image", "", false)
+	d.AddSignature("image", "aa", "default", "isp")
+	d.AddWarning("warning")
+	d.SetBreakglass()
+}
+
+func TestLog(t *testing.T) {
+	originalSize := batchSize
+	defer func() { batchSize = originalSize }()
+	batchSize = 2
+
+	sink := &recordingSink{}
+	l := NewLog(sink)
+	l.Record(&Decision{Name: "pod1"})
+	l.Record(nil)
+	l.Record(&Decision{Name: "pod2"})
+	l.Record(&Decision{Name: "pod3"})
+	l.Close()
+
+	var names [][]string
+	for _, batch := range sink.batches {
+		var batchNames []string
+		for _, d := range batch {
+			batchNames = append(batchNames, d.Name)
+		}
+		names = append(names, batchNames)
+	}
+	testutil.DeepEqual(t, [][]string{{"pod1", "pod2"}, {"pod3"}}, names)
+
+	var nilLog *Log
+	nilLog.Record(&Decision{})
+	nilLog.Close()
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decisionlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/pkg/errors"
+	bigquery "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/option"
+	storage "google.golang.org/api/storage/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/gcpauth"
+)
+
+// Sink types of DecisionLogSinkSpecs
+const (
+	Stdout   = "stdout"
+	File     = "file"
+	GCS      = "gcs"
+	BigQuery = "bigquery"
+)
+
+// For testing
+var clientOptions []option.ClientOption
+
+// writerSink writes decisions to w, one JSON object per line.
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *writerSink) Write(ctx context.Context, ds []*Decision) error {
+	b, err := jsonLines(ds)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(b)
+	return err
+}
+
+// jsonLines returns ds as JSON, one decision per line.
+func jsonLines(ds []*Decision) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, d := range ds {
+		if err := enc.Encode(d); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// gcsSink writes each batch of decisions to its own object in a Cloud Storage bucket.
+type gcsSink struct {
+	service *storage.Service
+	bucket  string
+	prefix  string
+}
+
+func newGCSSink(ctx context.Context, bucket, prefix string) (*gcsSink, error) {
+	opts, err := gcpauth.ClientOptions(ctx, gcpauth.Storage)
+	if err != nil {
+		return nil, err
+	}
+	service, err := storage.NewService(ctx, append(opts, clientOptions...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create Cloud Storage client")
+	}
+	return &gcsSink{service: service, bucket: bucket, prefix: prefix}, nil
+}
+
+// objectName returns the object of a batch starting with d, ordered by time and
+// unique per batch.
+func (s *gcsSink) objectName(d *Decision) string {
+	name := fmt.Sprintf("%s-%s-%s-%s.jsonl", d.Time.UTC().Format("2006/01/02/150405.000000000"), d.Kind, d.Namespace, d.Name)
+	return path.Join(s.prefix, name)
+}
+
+func (s *gcsSink) Write(ctx context.Context, ds []*Decision) error {
+	if len(ds) == 0 {
+		return nil
+	}
+	b, err := jsonLines(ds)
+	if err != nil {
+		return err
+	}
+	obj := &storage.Object{Name: s.objectName(ds[0]), ContentType: "application/x-ndjson"}
+	if _, err := s.service.Objects.Insert(s.bucket, obj).Media(bytes.NewReader(b)).Context(ctx).Do(); err != nil {
+		return errors.Wrapf(err, "failed to write decisions to gs://%s", s.bucket)
+	}
+	return nil
+}
+
+// bigQuerySink streams decisions into a BigQuery table, whose schema must match
+// the JSON of Decision, see artifacts/decisionlog-bigquery-schema.json.
+type bigQuerySink struct {
+	service *bigquery.Service
+	project string
+	dataset string
+	table   string
+}
+
+func newBigQuerySink(ctx context.Context, project, dataset, table string) (*bigQuerySink, error) {
+	opts, err := gcpauth.ClientOptions(ctx, gcpauth.BigQuery)
+	if err != nil {
+		return nil, err
+	}
+	service, err := bigquery.NewService(ctx, append(opts, clientOptions...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create BigQuery client")
+	}
+	return &bigQuerySink{service: service, project: project, dataset: dataset, table: table}, nil
+}
+
+func (s *bigQuerySink) Write(ctx context.Context, ds []*Decision) error {
+	if len(ds) == 0 {
+		return nil
+	}
+	req := &bigquery.TableDataInsertAllRequest{}
+	for _, d := range ds {
+		b, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		row := map[string]bigquery.JsonValue{}
+		if err := json.Unmarshal(b, &row); err != nil {
+			return err
+		}
+		req.Rows = append(req.Rows, &bigquery.TableDataInsertAllRequestRows{Json: row})
+	}
+	resp, err := s.service.Tabledata.InsertAll(s.project, s.dataset, s.table, req).Context(ctx).Do()
+	if err != nil {
+		return errors.Wrapf(err, "failed to insert decisions into %s.%s.%s", s.project, s.dataset, s.table)
+	}
+	if len(resp.InsertErrors) > 0 && len(resp.InsertErrors[0].Errors) > 0 {
+		return fmt.Errorf("failed to insert %d of %d decisions into %s.%s.%s: %s", len(resp.InsertErrors), len(ds), s.project, s.dataset, s.table, resp.InsertErrors[0].Errors[0].Message)
+	}
+	return nil
+}
+
+// New returns a Log writing decisions to the sinks of specs.
+// It returns nil if there are no specs.
+func New(specs []v1beta1.DecisionLogSinkSpec) (*Log, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	ctx := context.Background()
+	var s sinks
+	for _, spec := range specs {
+		switch spec.Type {
+		case Stdout:
+			s = append(s, &writerSink{w: os.Stdout})
+		case File:
+			if spec.Path == "" {
+				return nil, fmt.Errorf("file decision logs need a path")
+			}
+			f, err := os.OpenFile(spec.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to open decision log %s", spec.Path)
+			}
+			s = append(s, &writerSink{w: f})
+		case GCS:
+			if spec.Bucket == "" {
+				return nil, fmt.Errorf("gcs decision logs need a bucket")
+			}
+			sink, err := newGCSSink(ctx, spec.Bucket, spec.Prefix)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, sink)
+		case BigQuery:
+			if spec.Project == "" || spec.Dataset == "" || spec.Table == "" {
+				return nil, fmt.Errorf("bigquery decision logs need a project, dataset and table")
+			}
+			sink, err := newBigQuerySink(ctx, spec.Project, spec.Dataset, spec.Table)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, sink)
+		default:
+			return nil, fmt.Errorf("unknown decision log type %q", spec.Type)
+		}
+	}
+	return NewLog(s), nil
+}
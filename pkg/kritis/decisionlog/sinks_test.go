@@ -0,0 +1,212 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decisionlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+var testDecision = &Decision{
+	Time:          time.Date(2018, 10, 2, 15, 4, 5, 0, time.UTC),
+	Kind:          "Pod",
+	Namespace:     "default",
+	Name:          "pod",
+	Operation:     "CREATE",
+	Allowed:       false,
+	Images:        []string{"gcr.io/image@sha256:123"},
+	LatencyMillis: 42,
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	s := &writerSink{w: &buf}
+	if err := s.Write(context.Background(), []*Decision{testDecision, testDecision}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(context.Background(), []*Decision{testDecision}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", buf.String())
+	}
+	expected := `{"time":"2018-10-02T15:04:05Z","kind":"Pod","namespace":"default","name":"pod","operation":"CREATE","allowed":false,"images":["gcr.io/image@sha256:123"],"latencyMillis":42}`
+	testutil.DeepEqual(t, expected, lines[0])
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	for i := 0; i < 2; i++ {
+		l, err := New([]v1beta1.DecisionLogSinkSpec{{Type: File, Path: path}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Record(testDecision)
+		l.Close()
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Decisions are appended to the file.
+	if n := strings.Count(string(b), "\n"); n != 2 {
+		t.Fatalf("expected 2 decisions, got %q", b)
+	}
+}
+
+func TestGCSSink(t *testing.T) {
+	var body string
+	uploads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, "/b/kritis-decisions/o") {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		uploads++
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	clientOptions = []option.ClientOption{option.WithEndpoint(server.URL), option.WithoutAuthentication()}
+	defer func() { clientOptions = nil }()
+
+	s, err := newGCSSink(context.Background(), "kritis-decisions", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := &Decision{Time: testDecision.Time, Kind: "Pod", Namespace: "default", Name: "pod2"}
+	if err := s.Write(context.Background(), []*Decision{testDecision, second}); err != nil {
+		t.Fatal(err)
+	}
+	// The batch is a single object, uploaded with its metadata.
+	testutil.DeepEqual(t, 1, uploads)
+	if !strings.Contains(body, `"name":"prod/2018/10/02/150405.000000000-Pod-default-pod.jsonl"`) {
+		t.Errorf("expected the object name in the upload, got %q", body)
+	}
+	if !strings.Contains(body, `"name":"pod"`) || !strings.Contains(body, `"name":"pod2"`) {
+		t.Errorf("expected the decisions to be uploaded, got %q", body)
+	}
+}
+
+func TestBigQuerySink(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  string
+		shouldErr bool
+	}{
+		{
+			name:     "inserted",
+			response: `{}`,
+		},
+		{
+			name:      "insert errors",
+			response:  `{"insertErrors": [{"index": 0, "errors": [{"message": "no such field"}]}]}`,
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var req struct {
+				Rows []struct {
+					JSON map[string]interface{} `json:"json"`
+				} `json:"rows"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/projects/kritis/datasets/audit/tables/decisions/insertAll") {
+					t.Errorf("unexpected request %s", r.URL.Path)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Error(err)
+				}
+				w.Write([]byte(test.response))
+			}))
+			defer server.Close()
+			clientOptions = []option.ClientOption{option.WithEndpoint(server.URL), option.WithoutAuthentication()}
+			defer func() { clientOptions = nil }()
+
+			s, err := newBigQuerySink(context.Background(), "kritis", "audit", "decisions")
+			if err != nil {
+				t.Fatal(err)
+			}
+			second := &Decision{Time: testDecision.Time, Kind: "Pod", Namespace: "default", Name: "pod2"}
+			err = s.Write(context.Background(), []*Decision{testDecision, second})
+			testutil.CheckError(t, test.shouldErr, err)
+			if len(req.Rows) != 2 {
+				t.Fatalf("expected 2 rows, got %v", req.Rows)
+			}
+			testutil.DeepEqual(t, "pod", req.Rows[0].JSON["name"])
+			testutil.DeepEqual(t, "pod2", req.Rows[1].JSON["name"])
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		specs     []v1beta1.DecisionLogSinkSpec
+		shouldErr bool
+	}{
+		{
+			name:  "stdout",
+			specs: []v1beta1.DecisionLogSinkSpec{{Type: Stdout}},
+		},
+		{
+			name:      "file without path",
+			specs:     []v1beta1.DecisionLogSinkSpec{{Type: File}},
+			shouldErr: true,
+		},
+		{
+			name:      "gcs without bucket",
+			specs:     []v1beta1.DecisionLogSinkSpec{{Type: GCS}},
+			shouldErr: true,
+		},
+		{
+			name:      "bigquery without table",
+			specs:     []v1beta1.DecisionLogSinkSpec{{Type: BigQuery, Project: "kritis", Dataset: "audit"}},
+			shouldErr: true,
+		},
+		{
+			name:      "unknown type",
+			specs:     []v1beta1.DecisionLogSinkSpec{{Type: "syslog"}},
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := New(test.specs)
+			testutil.CheckError(t, test.shouldErr, err)
+			l.Close()
+		})
+	}
+
+	l, err := New(nil)
+	testutil.CheckErrorAndDeepEqual(t, false, err, (*Log)(nil), l)
+}
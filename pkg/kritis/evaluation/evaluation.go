@@ -0,0 +1,236 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package evaluation serves the policy simulations of the webhook over gRPC, so that
+// deployers running outside of Kubernetes can query the decisions Kritis would make.
+// Messages are google.protobuf.Struct values holding the JSON of the requests and
+// responses, see evaluation.proto.
+package evaluation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/admission"
+	"github.com/grafeas/kritis/pkg/kritis/authz"
+)
+
+// ServiceName is the full name of the gRPC service.
+const ServiceName = "kritis.evaluation.v1beta1.Evaluation"
+
+// ImageRequest is the request of EvaluateImage.
+type ImageRequest struct {
+	// Namespace whose ImageSecurityPolicies are evaluated.
+	Namespace string `json:"namespace,omitempty"`
+	// Images evaluated.
+	Images []string `json:"images"`
+	// Policies are YAML or JSON ImageSecurityPolicies evaluated instead of those of the namespace.
+	Policies string `json:"policies,omitempty"`
+}
+
+// PodRequest is the request of EvaluatePod.
+type PodRequest struct {
+	// Namespace whose ImageSecurityPolicies are evaluated, defaults to the namespace of Pod.
+	Namespace string `json:"namespace,omitempty"`
+	// Pod whose images are evaluated, and whose labels select the policies.
+	Pod *v1.Pod `json:"pod"`
+	// Policies are YAML or JSON ImageSecurityPolicies evaluated instead of those of the namespace.
+	Policies string `json:"policies,omitempty"`
+}
+
+// authorizationKey is the metadata key of the bearer token of requests.
+const authorizationKey = "authorization"
+
+// Token returns the bearer token of the request of a server call, sent in its
+// "authorization: Bearer <token>" metadata.
+func Token(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get(authorizationKey) {
+		if strings.HasPrefix(v, "Bearer ") {
+			return strings.TrimSpace(strings.TrimPrefix(v, "Bearer "))
+		}
+	}
+	return ""
+}
+
+// WithToken returns a copy of ctx whose client calls authenticate with the bearer token.
+func WithToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, authorizationKey, "Bearer "+token)
+}
+
+// Evaluator evaluates a simulation request, see admission.Simulate.
+type Evaluator func(ctx context.Context, req admission.SimulationRequest) (*admission.SimulationResponse, error)
+
+// Server implements the Evaluation service with an Evaluator.
+type Server struct {
+	evaluate Evaluator
+}
+
+// NewServer returns a Server evaluating requests with evaluate.
+func NewServer(evaluate Evaluator) *Server {
+	return &Server{evaluate: evaluate}
+}
+
+// Register registers s on g.
+func Register(g *grpc.Server, s *Server) {
+	g.RegisterService(&serviceDesc, s)
+}
+
+// EvaluateImage evaluates the images of an ImageRequest.
+func (s *Server) EvaluateImage(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req ImageRequest
+	if err := fromStruct(in, &req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid image request: %v", err)
+	}
+	if len(req.Images) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no images to evaluate")
+	}
+	return s.simulate(ctx, admission.SimulationRequest{Namespace: req.Namespace, Images: req.Images, Policies: req.Policies})
+}
+
+// EvaluatePod evaluates the pod of a PodRequest.
+func (s *Server) EvaluatePod(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req PodRequest
+	if err := fromStruct(in, &req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid pod request: %v", err)
+	}
+	if req.Pod == nil {
+		return nil, status.Error(codes.InvalidArgument, "no pod to evaluate")
+	}
+	return s.simulate(ctx, admission.SimulationRequest{Namespace: req.Namespace, Pod: req.Pod, Policies: req.Policies})
+}
+
+func (s *Server) simulate(ctx context.Context, req admission.SimulationRequest) (*structpb.Struct, error) {
+	resp, err := s.evaluate(ctx, req)
+	if admission.IsInvalidSimulation(err) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, ok := err.(*authz.Error); ok {
+		switch authz.StatusCode(err) {
+		case http.StatusUnauthorized:
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		case http.StatusForbidden:
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	out, err := toStruct(resp)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode response: %v", err)
+	}
+	return out, nil
+}
+
+// Client calls an Evaluation service.
+type Client struct {
+	conn *grpc.ClientConn
+}
+
+// NewClient returns a Client calling the service of conn.
+func NewClient(conn *grpc.ClientConn) *Client {
+	return &Client{conn: conn}
+}
+
+// EvaluateImage returns the violations the images of req would have.
+func (c *Client) EvaluateImage(ctx context.Context, req ImageRequest) (*admission.SimulationResponse, error) {
+	return c.call(ctx, "EvaluateImage", req)
+}
+
+// EvaluatePod returns the violations the pod of req would have.
+func (c *Client) EvaluatePod(ctx context.Context, req PodRequest) (*admission.SimulationResponse, error) {
+	return c.call(ctx, "EvaluatePod", req)
+}
+
+func (c *Client) call(ctx context.Context, method string, req interface{}) (*admission.SimulationResponse, error) {
+	in, err := toStruct(req)
+	if err != nil {
+		return nil, err
+	}
+	out := &structpb.Struct{}
+	if err := c.conn.Invoke(ctx, "/"+ServiceName+"/"+method, in, out); err != nil {
+		return nil, err
+	}
+	var resp admission.SimulationResponse
+	if err := fromStruct(out, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func toStruct(v interface{}) (*structpb.Struct, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := &structpb.Struct{}
+	if err := s.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func fromStruct(s *structpb.Struct, v interface{}) error {
+	b, err := s.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// evaluationServer is the interface of the handlers of serviceDesc.
+type evaluationServer interface {
+	EvaluateImage(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	EvaluatePod(context.Context, *structpb.Struct) (*structpb.Struct, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*evaluationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "EvaluateImage", Handler: handler("EvaluateImage", evaluationServer.EvaluateImage)},
+		{MethodName: "EvaluatePod", Handler: handler("EvaluatePod", evaluationServer.EvaluatePod)},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "evaluation.proto",
+}
+
+// handler returns the unary handler of a method, as generated by protoc-gen-go-grpc.
+func handler(method string, call func(evaluationServer, context.Context, *structpb.Struct) (*structpb.Struct, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := &structpb.Struct{}
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv.(evaluationServer), ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/" + method}
+		return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv.(evaluationServer), ctx, req.(*structpb.Struct))
+		})
+	}
+}
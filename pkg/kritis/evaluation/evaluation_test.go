@@ -0,0 +1,148 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evaluation
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/admission"
+	"github.com/grafeas/kritis/pkg/kritis/authz"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func newClient(t *testing.T, evaluate Evaluator) *Client {
+	listener := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	Register(g, NewServer(evaluate))
+	go g.Serve(listener)
+	t.Cleanup(g.Stop)
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewClient(conn)
+}
+
+func TestEvaluate(t *testing.T) {
+	var requests []admission.SimulationRequest
+	var tokens []string
+	evaluate := func(ctx context.Context, req admission.SimulationRequest) (*admission.SimulationResponse, error) {
+		requests = append(requests, req)
+		tokens = append(tokens, Token(ctx))
+		images := req.Images
+		if req.Pod != nil {
+			images = admission.PodImages(*req.Pod)
+		}
+		return &admission.SimulationResponse{
+			Allowed:  false,
+			Images:   images,
+			Policies: []string{req.Namespace + "/isp"},
+			Violations: []admission.SimulatedViolation{
+				{Image: images[0], Policy: req.Namespace + "/isp", Type: "SeverityViolation", Reason: "too severe"},
+			},
+		}, nil
+	}
+	c := newClient(t, evaluate)
+	expected := &admission.SimulationResponse{
+		Allowed:  false,
+		Images:   []string{testutil.QualifiedImage},
+		Policies: []string{"prod/isp"},
+		Violations: []admission.SimulatedViolation{
+			{Image: testutil.QualifiedImage, Policy: "prod/isp", Type: "SeverityViolation", Reason: "too severe"},
+		},
+	}
+
+	resp, err := c.EvaluateImage(context.Background(), ImageRequest{Namespace: "prod", Images: []string{testutil.QualifiedImage}})
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected, resp)
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "prod", Labels: map[string]string{"app": "web"}},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "web", Image: testutil.QualifiedImage}}},
+	}
+	resp, err = c.EvaluatePod(WithToken(context.Background(), "dev"), PodRequest{Namespace: "prod", Pod: pod})
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected, resp)
+	testutil.DeepEqual(t, 2, len(requests))
+	testutil.DeepEqual(t, pod.Labels, requests[1].Pod.Labels)
+	testutil.DeepEqual(t, []string{"", "dev"}, tokens)
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	evaluate := func(ctx context.Context, req admission.SimulationRequest) (*admission.SimulationResponse, error) {
+		switch req.Namespace {
+		case "":
+			return admission.Simulate(ctx, req, &admission.Config{}, func(string, string) error { return nil })
+		case "unauthenticated":
+			return nil, &authz.Error{Code: http.StatusUnauthorized, Message: "missing bearer token"}
+		case "forbidden":
+			return nil, &authz.Error{Code: http.StatusForbidden, Message: "user is not allowed"}
+		}
+		return nil, fmt.Errorf("metadata backend unavailable")
+	}
+	c := newClient(t, evaluate)
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{"no images", func() error {
+			_, err := c.EvaluateImage(context.Background(), ImageRequest{Namespace: "prod"})
+			return err
+		}, codes.InvalidArgument},
+		{"no pod", func() error {
+			_, err := c.EvaluatePod(context.Background(), PodRequest{Namespace: "prod"})
+			return err
+		}, codes.InvalidArgument},
+		{"invalid simulation", func() error {
+			_, err := c.EvaluateImage(context.Background(), ImageRequest{Images: []string{testutil.QualifiedImage}})
+			return err
+		}, codes.InvalidArgument},
+		{"failed simulation", func() error {
+			_, err := c.EvaluateImage(context.Background(), ImageRequest{Namespace: "prod", Images: []string{testutil.QualifiedImage}})
+			return err
+		}, codes.Internal},
+		{"unauthenticated", func() error {
+			_, err := c.EvaluateImage(context.Background(), ImageRequest{Namespace: "unauthenticated", Images: []string{testutil.QualifiedImage}})
+			return err
+		}, codes.Unauthenticated},
+		{"forbidden", func() error {
+			_, err := c.EvaluateImage(context.Background(), ImageRequest{Namespace: "forbidden", Images: []string{testutil.QualifiedImage}})
+			return err
+		}, codes.PermissionDenied},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testutil.DeepEqual(t, test.code, status.Code(test.call()))
+		})
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package exemption selects the objects exempt from review by Kritis, e.g. the
+// system workloads of kube-system, with the exemptions of the KritisConfig.
+package exemption
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/crd/informers"
+	"github.com/grafeas/kritis/pkg/kritis/util"
+)
+
+// For testing
+var fetchNamespace = informers.Namespace
+
+// exemption is an ExemptionSpec with its selectors parsed.
+type exemption struct {
+	namespaces        map[string]bool
+	namespaceSelector labels.Selector
+	images            []string
+	serviceAccounts   map[string]bool
+}
+
+// builtin exempts the objects running only the images of Kritis, its webhook and
+// its install hooks, which run before any policy can admit them.
+var builtin = exemption{images: constants.GlobalImageWhitelist}
+
+// Matcher tells whether objects are exempt from review.
+type Matcher struct {
+	exemptions []exemption
+}
+
+// New returns a Matcher of the exemptions of specs. It returns nil if there are no specs.
+func New(specs []v1beta1.ExemptionSpec) (*Matcher, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	m := &Matcher{}
+	for i, spec := range specs {
+		if len(spec.Namespaces) == 0 && spec.NamespaceSelector == nil && len(spec.Images) == 0 && len(spec.ServiceAccounts) == 0 {
+			return nil, fmt.Errorf("exemption %d selects no object", i)
+		}
+		e := exemption{}
+		if len(spec.Namespaces) > 0 {
+			e.namespaces = set(spec.Namespaces)
+		}
+		if len(spec.Images) > 0 {
+			e.images = spec.Images
+		}
+		if len(spec.ServiceAccounts) > 0 {
+			// Service accounts are namespaced, a bare name would match in every namespace.
+			for _, sa := range spec.ServiceAccounts {
+				if parts := strings.Split(sa, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+					return nil, fmt.Errorf("service account %q of exemption %d is not namespace/name", sa, i)
+				}
+			}
+			e.serviceAccounts = set(spec.ServiceAccounts)
+		}
+		var err error
+		if spec.NamespaceSelector != nil {
+			if e.namespaceSelector, err = metav1.LabelSelectorAsSelector(spec.NamespaceSelector); err != nil {
+				return nil, errors.Wrapf(err, "invalid namespace selector of exemption %d", i)
+			}
+		}
+		m.exemptions = append(m.exemptions, e)
+	}
+	return m, nil
+}
+
+func set(items []string) map[string]bool {
+	s := map[string]bool{}
+	for _, i := range items {
+		s[i] = true
+	}
+	return s
+}
+
+// Exempt returns true if pods in namespace with images, running as serviceAccount,
+// match an exemption. It may be called on a nil Matcher, which only exempts the
+// images of Kritis.
+func (m *Matcher) Exempt(namespace string, images []string, serviceAccount string) (bool, error) {
+	exemptions := []exemption{builtin}
+	if m != nil {
+		exemptions = append(exemptions, m.exemptions...)
+	}
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+	var ns *v1.Namespace
+	for _, e := range exemptions {
+		if e.namespaces != nil && !e.namespaces[namespace] {
+			continue
+		}
+		if e.serviceAccounts != nil && !e.serviceAccounts[namespace+"/"+serviceAccount] {
+			continue
+		}
+		if e.images != nil {
+			ok, err := allListed(e.images, images)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		if e.namespaceSelector != nil {
+			if ns == nil {
+				var err error
+				if ns, err = fetchNamespace(namespace); err != nil {
+					return false, errors.Wrapf(err, "failed to get namespace %s", namespace)
+				}
+			}
+			if !e.namespaceSelector.Matches(labels.Set(ns.Labels)) {
+				continue
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// ExemptPod returns true if pod matches an exemption.
+func (m *Matcher) ExemptPod(pod v1.Pod) (bool, error) {
+	return m.Exempt(pod.Namespace, util.PodSpecImages(pod.Spec), pod.Spec.ServiceAccountName)
+}
+
+// allListed returns true if there are images, and all of them are named in list.
+func allListed(list []string, images []string) (bool, error) {
+	if len(images) == 0 {
+		return false, nil
+	}
+	for _, image := range images {
+		ok, err := util.ImageInWhitelist(list, image)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to match image %s", image)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
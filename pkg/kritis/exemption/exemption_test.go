@@ -0,0 +1,101 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exemption
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func TestExempt(t *testing.T) {
+	original := fetchNamespace
+	defer func() { fetchNamespace = original }()
+	fetchNamespace = func(name string) (*v1.Namespace, error) {
+		switch name {
+		case "gke-system":
+			return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"system": "true"}}}, nil
+		case "missing":
+			return nil, fmt.Errorf("namespace %s not found", name)
+		}
+		return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}, nil
+	}
+	m, err := New([]v1beta1.ExemptionSpec{
+		{Namespaces: []string{"kube-system"}},
+		{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"system": "true"}}},
+		{Namespaces: []string{"monitoring"}, Images: []string{"gcr.io/my-project/node-exporter"}},
+		{ServiceAccounts: []string{"ci/deployer"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name           string
+		namespace      string
+		images         []string
+		serviceAccount string
+		exempt         bool
+		shouldErr      bool
+	}{
+		{"namespace", "kube-system", nil, "", true, false},
+		{"namespace selector", "gke-system", nil, "", true, false},
+		{"not exempt", "default", []string{"gcr.io/my-project/app:1"}, "", false, false},
+		{"namespace and images", "monitoring", []string{"gcr.io/my-project/node-exporter:v1"}, "", true, false},
+		{"namespace and other images", "monitoring", []string{"gcr.io/my-project/node-exporter:v1", "gcr.io/my-project/app:1"}, "", false, false},
+		{"images in another namespace", "default", []string{"gcr.io/my-project/node-exporter:v1"}, "", false, false},
+		{"namespaced service account", "ci", nil, "deployer", true, false},
+		{"service account of another namespace", "default", nil, "deployer", false, false},
+		{"images of Kritis", "default", []string{"gcr.io/kritis-project/kritis-server:tag", "gcr.io/kritis-project/preinstall@sha256:0000000000000000000000000000000000000000000000000000000000000000"}, "", true, false},
+		{"images of Kritis and others", "default", []string{"gcr.io/kritis-project/kritis-server:tag", "gcr.io/my-project/app:1"}, "", false, false},
+		{"namespace not found", "missing", nil, "", false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exempt, err := m.Exempt(test.namespace, test.images, test.serviceAccount)
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.exempt, exempt)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	m, err := New(nil)
+	testutil.CheckErrorAndDeepEqual(t, false, err, (*Matcher)(nil), m)
+	exempt, err := m.Exempt("kube-system", nil, "")
+	testutil.CheckErrorAndDeepEqual(t, false, err, false, exempt)
+	// The images of Kritis are exempt without exemptions.
+	exempt, err = m.Exempt("kritis", []string{"gcr.io/kritis-project/kritis-server:tag"}, "")
+	testutil.CheckErrorAndDeepEqual(t, false, err, true, exempt)
+
+	_, err = New([]v1beta1.ExemptionSpec{{}})
+	testutil.CheckError(t, true, err)
+
+	_, err = New([]v1beta1.ExemptionSpec{{NamespaceSelector: &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Near"}},
+	}}})
+	testutil.CheckError(t, true, err)
+
+	// Service accounts without namespace would match in every namespace.
+	for _, sa := range []string{"deployer", "/deployer", "ci/", "ci/deployer/x"} {
+		_, err = New([]v1beta1.ExemptionSpec{{ServiceAccounts: []string{sa}}})
+		testutil.CheckError(t, true, err)
+	}
+}
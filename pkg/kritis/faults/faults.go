@@ -0,0 +1,164 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package faults injects delays and errors into the calls Kritis makes to its
+// dependencies, so operators can check how the webhook behaves when they fail.
+// It must only be enabled in test clusters.
+package faults
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/grafeas/kritis/pkg/kritis/metrics"
+)
+
+// Dependencies faults can be injected into
+const (
+	Metadata = "metadata"
+	Binauthz = "binauthz"
+	KMS      = "kms"
+)
+
+// Rule describes the faults injected into calls to a dependency.
+type Rule struct {
+	// ErrorRate is the probability of a call failing, between 0 and 1.
+	ErrorRate float64 `json:"errorRate"`
+	// Delay is added to calls, as Duration e.g. "2s".
+	Delay string `json:"delay"`
+	// DelayRate is the probability of a call being delayed, 1 if not set.
+	DelayRate *float64 `json:"delayRate,omitempty"`
+
+	delay time.Duration
+}
+
+// Profile holds the rules of each dependency.
+type Profile struct {
+	Metadata Rule `json:"metadata"`
+	Binauthz Rule `json:"binauthz"`
+	KMS      Rule `json:"kms"`
+}
+
+// For testing
+var (
+	random = rand.Float64
+	sleep  = sleepContext
+)
+
+var (
+	mu      sync.RWMutex
+	profile *Profile
+)
+
+// Load reads a YAML or JSON profile.
+func Load(path string) (*Profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open fault profile %s", path)
+	}
+	defer f.Close()
+	p := &Profile{}
+	if err := yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(p); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode fault profile %s", path)
+	}
+	for name, r := range p.rules() {
+		if r.ErrorRate < 0 || r.ErrorRate > 1 {
+			return nil, fmt.Errorf("invalid errorRate %v of %s, expected a value between 0 and 1", r.ErrorRate, name)
+		}
+		if r.DelayRate != nil && (*r.DelayRate < 0 || *r.DelayRate > 1) {
+			return nil, fmt.Errorf("invalid delayRate %v of %s, expected a value between 0 and 1", *r.DelayRate, name)
+		}
+		if r.Delay == "" {
+			continue
+		}
+		if r.delay, err = time.ParseDuration(r.Delay); err != nil {
+			return nil, errors.Wrapf(err, "invalid delay of %s", name)
+		}
+	}
+	return p, nil
+}
+
+func (p *Profile) rules() map[string]*Rule {
+	return map[string]*Rule{
+		Metadata: &p.Metadata,
+		Binauthz: &p.Binauthz,
+		KMS:      &p.KMS,
+	}
+}
+
+// Enable injects the faults of the profile into all calls made from now on.
+// A nil profile disables fault injection.
+func Enable(p *Profile) {
+	mu.Lock()
+	defer mu.Unlock()
+	profile = p
+	if p != nil {
+		glog.Warningf("fault injection is enabled: %+v", *p)
+	}
+}
+
+// Enabled returns true if faults are injected.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return profile != nil
+}
+
+// Inject delays and fails a call to the dependency, as set by the enabled profile.
+// Delays end early, with the error of ctx, once ctx is done. It returns nil if fault
+// injection is disabled.
+func Inject(ctx context.Context, dependency string) error {
+	mu.RLock()
+	p := profile
+	mu.RUnlock()
+	if p == nil {
+		return nil
+	}
+	r, ok := p.rules()[dependency]
+	if !ok {
+		return nil
+	}
+	if r.delay > 0 && (r.DelayRate == nil || random() < *r.DelayRate) {
+		metrics.InjectedFaults.Add(dependency+"_delay", 1)
+		if err := sleep(ctx, r.delay); err != nil {
+			return err
+		}
+	}
+	if r.ErrorRate > 0 && random() < r.ErrorRate {
+		metrics.InjectedFaults.Add(dependency+"_error", 1)
+		return fmt.Errorf("injected fault: %s unavailable", dependency)
+	}
+	return nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
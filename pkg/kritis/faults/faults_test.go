@@ -0,0 +1,179 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faults
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func writeProfile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "faults")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "profile.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	var tests = []struct {
+		name        string
+		content     string
+		shouldErr   bool
+		expected    Rule
+		expectedKMS time.Duration
+	}{
+		{
+			name:        "yaml profile",
+			content:     "metadata:\n  errorRate: 0.5\n  delay: 2s\nkms:\n  delay: 100ms\n",
+			expected:    Rule{ErrorRate: 0.5, Delay: "2s", delay: 2 * time.Second},
+			expectedKMS: 100 * time.Millisecond,
+		},
+		{
+			name:     "json profile",
+			content:  `{"metadata": {"errorRate": 1}}`,
+			expected: Rule{ErrorRate: 1},
+		},
+		{
+			name:      "invalid error rate",
+			content:   "metadata:\n  errorRate: 2\n",
+			shouldErr: true,
+		},
+		{
+			name:      "invalid delay rate",
+			content:   "binauthz:\n  delay: 1s\n  delayRate: -1\n",
+			shouldErr: true,
+		},
+		{
+			name:      "invalid delay",
+			content:   "kms:\n  delay: soon\n",
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeProfile(t, test.content)
+			defer os.RemoveAll(filepath.Dir(path))
+			p, err := Load(path)
+			if test.shouldErr {
+				testutil.CheckError(t, true, err)
+				return
+			}
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, p.Metadata)
+			testutil.DeepEqual(t, test.expectedKMS, p.KMS.delay)
+		})
+	}
+}
+
+func TestInject(t *testing.T) {
+	half := 0.5
+	var tests = []struct {
+		name          string
+		rule          Rule
+		random        float64
+		shouldErr     bool
+		expectedSleep time.Duration
+	}{
+		{
+			name:      "error",
+			rule:      Rule{ErrorRate: 0.5},
+			random:    0.4,
+			shouldErr: true,
+		},
+		{
+			name:   "no error",
+			rule:   Rule{ErrorRate: 0.5},
+			random: 0.6,
+		},
+		{
+			name:          "delay",
+			rule:          Rule{delay: time.Second},
+			random:        0.9,
+			expectedSleep: time.Second,
+		},
+		{
+			name:          "delay with rate",
+			rule:          Rule{delay: time.Second, DelayRate: &half},
+			random:        0.4,
+			expectedSleep: time.Second,
+		},
+		{
+			name:   "no delay with rate",
+			rule:   Rule{delay: time.Second, DelayRate: &half},
+			random: 0.6,
+		},
+	}
+	originalRandom, originalSleep := random, sleep
+	defer func() {
+		random, sleep = originalRandom, originalSleep
+		Enable(nil)
+	}()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var slept time.Duration
+			random = func() float64 { return test.random }
+			sleep = func(ctx context.Context, d time.Duration) error {
+				slept += d
+				return nil
+			}
+			Enable(&Profile{Binauthz: test.rule})
+			testutil.CheckError(t, test.shouldErr, Inject(context.Background(), Binauthz))
+			testutil.DeepEqual(t, test.expectedSleep, slept)
+			// Other dependencies are not affected.
+			testutil.CheckError(t, false, Inject(context.Background(), Metadata))
+		})
+	}
+}
+
+func TestInjectDisabled(t *testing.T) {
+	Enable(nil)
+	if Enabled() {
+		t.Fatal("expected fault injection to be disabled")
+	}
+	testutil.CheckError(t, false, Inject(context.Background(), Metadata))
+}
+
+func TestInjectCanceled(t *testing.T) {
+	defer Enable(nil)
+	Enable(&Profile{Metadata: Rule{Delay: "1h", delay: time.Hour}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Inject(ctx, Metadata)
+	testutil.CheckErrorAndDeepEqual(t, true, err, context.Canceled, err)
+}
+
+func TestNewFetcher(t *testing.T) {
+	defer Enable(nil)
+	f := &testutil.MockMetadataClient{}
+	Enable(nil)
+	if _, ok := NewFetcher(f).(*testutil.MockMetadataClient); !ok {
+		t.Errorf("expected the fetcher to not be wrapped when disabled")
+	}
+	Enable(&Profile{Metadata: Rule{ErrorRate: 1}})
+	_, err := NewFetcher(f).Vulnerabilities(context.Background(), "gcr.io/foo/bar")
+	testutil.CheckError(t, true, err)
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faults
+
+import (
+	"context"
+
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+// faultyFetcher wraps a Fetcher and injects faults into all calls to the backend.
+type faultyFetcher struct {
+	metadata.Fetcher
+}
+
+// NewFetcher returns a Fetcher injecting the metadata faults of the enabled profile.
+// If fault injection is disabled, f is returned as is.
+func NewFetcher(f metadata.Fetcher) metadata.Fetcher {
+	if !Enabled() {
+		return f
+	}
+	return faultyFetcher{Fetcher: f}
+}
+
+// Vulnerabilities injects faults before getting vulnerabilities.
+func (f faultyFetcher) Vulnerabilities(ctx context.Context, containerImage string) ([]metadata.Vulnerability, error) {
+	if err := Inject(ctx, Metadata); err != nil {
+		return nil, err
+	}
+	return f.Fetcher.Vulnerabilities(ctx, containerImage)
+}
+
+// Attestations injects faults before getting attestations.
+func (f faultyFetcher) Attestations(ctx context.Context, containerImage string) ([]metadata.PGPAttestation, error) {
+	if err := Inject(ctx, Metadata); err != nil {
+		return nil, err
+	}
+	return f.Fetcher.Attestations(ctx, containerImage)
+}
+
+// OccurencesV1 injects faults before getting V1 occurrences.
+func (f faultyFetcher) OccurencesV1(ctx context.Context, containerImage string) ([]*metadata.OccurenceV1, error) {
+	if err := Inject(ctx, Metadata); err != nil {
+		return nil, err
+	}
+	return f.Fetcher.OccurencesV1(ctx, containerImage)
+}
+
+// Builds injects faults before getting builds.
+func (f faultyFetcher) Builds(ctx context.Context, containerImage string) ([]metadata.Build, error) {
+	if err := Inject(ctx, Metadata); err != nil {
+		return nil, err
+	}
+	return f.Fetcher.Builds(ctx, containerImage)
+}
+
+// AttestationNote injects faults before getting an attestation note.
+func (f faultyFetcher) AttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	if err := Inject(ctx, Metadata); err != nil {
+		return nil, err
+	}
+	return f.Fetcher.AttestationNote(ctx, aa)
+}
+
+// CreateAttestationNote injects faults before creating an attestation note.
+func (f faultyFetcher) CreateAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	if err := Inject(ctx, Metadata); err != nil {
+		return nil, err
+	}
+	return f.Fetcher.CreateAttestationNote(ctx, aa)
+}
+
+// CreateAttestationOccurence injects faults before creating an attestation.
+func (f faultyFetcher) CreateAttestationOccurence(ctx context.Context, note *grafeas.Note,
+	containerImage string,
+	pgpSigningKey *secrets.PGPSigningSecret) (*grafeas.Occurrence, error) {
+	if err := Inject(ctx, Metadata); err != nil {
+		return nil, err
+	}
+	return f.Fetcher.CreateAttestationOccurence(ctx, note, containerImage, pgpSigningKey)
+}
+
+// DiscoveryNote injects faults before getting a discovery note.
+func (f faultyFetcher) DiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	if err := Inject(ctx, Metadata); err != nil {
+		return nil, err
+	}
+	return f.Fetcher.DiscoveryNote(ctx, containerImage, noteID)
+}
+
+// CreateDiscoveryNote injects faults before creating a discovery note.
+func (f faultyFetcher) CreateDiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	if err := Inject(ctx, Metadata); err != nil {
+		return nil, err
+	}
+	return f.Fetcher.CreateDiscoveryNote(ctx, containerImage, noteID)
+}
+
+// ScanStatus injects faults before getting the status of the scan of an image.
+func (f faultyFetcher) ScanStatus(ctx context.Context, containerImage string) (metadata.ScanStatus, error) {
+	if err := Inject(ctx, Metadata); err != nil {
+		return metadata.ScanStatusUnknown, err
+	}
+	return f.Fetcher.ScanStatus(ctx, containerImage)
+}
+
+// CreateDiscoveryOccurrence injects faults before creating a discovery occurrence.
+func (f faultyFetcher) CreateDiscoveryOccurrence(ctx context.Context, note *grafeas.Note, containerImage string, message string) (*grafeas.Occurrence, error) {
+	if err := Inject(ctx, Metadata); err != nil {
+		return nil, err
+	}
+	return f.Fetcher.CreateDiscoveryOccurrence(ctx, note, containerImage, message)
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gate evaluates built images against ImageSecurityPolicies in CI, before they
+// are deployed, and reports the violations found for CI systems.
+package gate
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/review"
+)
+
+// Result lists the violations of an ImageSecurityPolicy found for an image.
+type Result struct {
+	Image      string
+	Policy     string
+	Violations []policy.Violation
+}
+
+// Evaluate validates each image against the rules of isps, e.g. their vulnerability,
+// Rego and allowlist rules, and returns one Result per image and policy. Unlike the
+// webhook, it doesn't pass images attested by the AttestationAuthorities of a policy,
+// nor evaluate GenericAttestationPolicies or the whitelist and exemptions of the
+// cluster, see NotChecked.
+func Evaluate(ctx context.Context, client metadata.Fetcher, attestors securitypolicy.AttestorFetcher, images []string, isps []v1beta1.ImageSecurityPolicy) ([]Result, error) {
+	r := review.New(client, &review.Config{
+		Validate:  securitypolicy.ValidateImageSecurityPolicy,
+		Attestors: attestors,
+	})
+	var results []Result
+	for _, image := range images {
+		vss, err := r.Validate(ctx, image, isps)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to evaluate %s", image)
+		}
+		for i, vs := range vss {
+			results = append(results, Result{Image: image, Policy: policyName(isps[i]), Violations: vs})
+		}
+	}
+	return results, nil
+}
+
+// NotChecked describes what the webhook checks besides Evaluate, for reports to tell that
+// images passing Evaluate may still be denied.
+const NotChecked = "AttestationAuthorities, GenericAttestationPolicies and the whitelist and exemptions of the cluster were not checked"
+
+func policyName(isp v1beta1.ImageSecurityPolicy) string {
+	if isp.Namespace == "" {
+		return isp.Name
+	}
+	return isp.Namespace + "/" + isp.Name
+}
+
+// Violations returns the number of violations of results.
+func Violations(results []Result) int {
+	n := 0
+	for _, r := range results {
+		n += len(r.Violations)
+	}
+	return n
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes results as a JUnit XML report, with a test suite per image and a
+// test case per policy, which fails with the violations of the policy.
+func WriteJUnit(w io.Writer, results []Result) error {
+	report := junitTestSuites{}
+	suites := map[string]int{}
+	for _, r := range results {
+		i, ok := suites[r.Image]
+		if !ok {
+			i = len(report.Suites)
+			suites[r.Image] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: r.Image})
+		}
+		s := &report.Suites[i]
+		c := junitTestCase{Name: r.Policy, ClassName: r.Image}
+		if len(r.Violations) > 0 {
+			var types, reasons []string
+			for _, v := range r.Violations {
+				types = append(types, v.Type().ToString())
+				reasons = append(reasons, string(v.Reason()))
+			}
+			c.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d violations of %s", len(r.Violations), r.Policy),
+				Type:    strings.Join(dedup(types), ","),
+				Text:    strings.Join(reasons, "\n"),
+			}
+			s.Failures++
+			report.Failures++
+		}
+		s.Cases = append(s.Cases, c)
+		s.Tests++
+		report.Tests++
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func dedup(s []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
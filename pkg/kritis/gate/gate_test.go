@@ -0,0 +1,91 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gate
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func TestEvaluate(t *testing.T) {
+	client := &testutil.MockMetadataClient{Vulnz: []metadata.Vulnerability{{CVE: "CVE-1", Severity: "CRITICAL", HasFixAvailable: true}}}
+	isps := []v1beta1.ImageSecurityPolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "strict", Namespace: "prod"},
+			Spec: v1beta1.ImageSecurityPolicySpec{
+				PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{MaximumSeverity: "MEDIUM"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "lax"},
+			Spec: v1beta1.ImageSecurityPolicySpec{
+				PackageVulnerabilityRequirements: v1beta1.PackageVulnerabilityRequirements{MaximumSeverity: "CRITICAL"},
+			},
+		},
+	}
+	results, err := Evaluate(context.Background(), client, nil, []string{testutil.QualifiedImage}, isps)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var summary [][]interface{}
+	for _, r := range results {
+		summary = append(summary, []interface{}{r.Image, r.Policy, len(r.Violations)})
+	}
+	testutil.DeepEqual(t, [][]interface{}{
+		{testutil.QualifiedImage, "prod/strict", 1},
+		{testutil.QualifiedImage, "lax", 0},
+	}, summary)
+	testutil.DeepEqual(t, 1, Violations(results))
+}
+
+func TestWriteJUnit(t *testing.T) {
+	results := []Result{
+		{Image: "gcr.io/p/a@sha256:1", Policy: "prod/isp", Violations: []policy.Violation{
+			securitypolicy.NewViolation(nil, policy.SeverityViolation, "CVE-1 is too severe"),
+			securitypolicy.NewViolation(nil, policy.SeverityViolation, "CVE-2 is too severe"),
+		}},
+		{Image: "gcr.io/p/a@sha256:1", Policy: "prod/other"},
+		{Image: "gcr.io/p/b@sha256:2", Policy: "prod/isp"},
+	}
+	var b bytes.Buffer
+	if err := WriteJUnit(&b, results); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="3" failures="1">
+  <testsuite name="gcr.io/p/a@sha256:1" tests="2" failures="1">
+    <testcase name="prod/isp" classname="gcr.io/p/a@sha256:1">
+      <failure message="2 violations of prod/isp" type="SeverityViolation">CVE-1 is too severe&#xA;CVE-2 is too severe</failure>
+    </testcase>
+    <testcase name="prod/other" classname="gcr.io/p/a@sha256:1"></testcase>
+  </testsuite>
+  <testsuite name="gcr.io/p/b@sha256:2" tests="1" failures="0">
+    <testcase name="prod/isp" classname="gcr.io/p/b@sha256:2"></testcase>
+  </testsuite>
+</testsuites>
+`
+	testutil.DeepEqual(t, expected, b.String())
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package gcbsigner
 
 import (
+	"context"
+
 	"github.com/golang/glog"
 	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
 	"github.com/grafeas/kritis/pkg/kritis/crd/authority"
@@ -57,7 +59,7 @@ var (
 // ValidateAndSign validates builtFrom against the build policies and creates
 // attestations for all authorities for the matching policies.
 // Returns an error if creating an attestation for any authority fails.
-func (s Signer) ValidateAndSign(prov BuildProvenance, bps []v1beta1.BuildPolicy) error {
+func (s Signer) ValidateAndSign(ctx context.Context, prov BuildProvenance, bps []v1beta1.BuildPolicy) error {
 	for _, bp := range bps {
 		glog.Infof("Validating %q against BuildPolicy %q", prov.ImageRef, bp.Name)
 		if result := s.config.Validate(bp, prov.BuiltFrom); result != nil {
@@ -65,29 +67,29 @@ func (s Signer) ValidateAndSign(prov BuildProvenance, bps []v1beta1.BuildPolicy)
 			continue
 		}
 		glog.Infof("Image %q matches BuildPolicy %s, creating attestations", prov.ImageRef, bp.Name)
-		if err := s.addAttestation(prov.ImageRef, bp.Namespace, bp.Spec.AttestationAuthorityName); err != nil {
+		if err := s.addAttestation(ctx, prov.ImageRef, bp.Namespace, bp.Spec.AttestationAuthorityName); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (s Signer) addAttestation(image string, ns string, authority string) error {
+func (s Signer) addAttestation(ctx context.Context, image string, ns string, authority string) error {
 	// Get AttestaionAuthority specified in the buildpolicy.
 	a, err := authFetcher(ns, authority)
 	if err != nil {
 		return err
 	}
-	n, err := util.GetOrCreateAttestationNote(s.client, a)
+	n, err := util.GetOrCreateAttestationNote(ctx, s.client, a)
 	if err != nil {
 		return err
 	}
 	// Get secret for this Authority
-	sec, err := s.config.Secret(ns, a.Spec.PrivateKeySecretName)
+	sec, err := secrets.FetchForAuthority(s.config.Secret, ns, *a)
 	if err != nil {
 		return err
 	}
 	// Create Attestation Signature
-	_, err = s.client.CreateAttestationOccurence(n, image, sec)
+	_, err = s.client.CreateAttestationOccurence(ctx, n, image, sec)
 	return err
 }
@@ -0,0 +1,162 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gcpauth builds the credentials of the Google Cloud clients of Kritis from
+// the KritisConfig, see GCPCredentialsSpec.
+package gcpauth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+// Clients whose credentials can be set separately
+const (
+	ContainerAnalysis   = "containerAnalysis"
+	BinaryAuthorization = "binaryAuthorization"
+	KMS                 = "kms"
+	SecretManager       = "secretManager"
+	Storage             = "storage"
+	BigQuery            = "bigQuery"
+)
+
+// KeySecretKey is the key of the service account key in KeySecret.
+const KeySecretKey = "key.json"
+
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// For testing
+var fetchSecret = secrets.FetchData
+
+var (
+	configMu sync.RWMutex
+	config   v1beta1.GCPCredentialsSpec
+)
+
+func init() {
+	// secrets can't import gcpauth, which reads service account keys with it.
+	secrets.SecretManagerClientOptions = func(ctx context.Context) ([]option.ClientOption, error) {
+		return ClientOptions(ctx, SecretManager)
+	}
+}
+
+// Configure sets the credentials of the clients created from now on.
+func Configure(spec v1beta1.GCPCredentialsSpec) error {
+	for client, c := range map[string]*v1beta1.GCPClientCredentialsSpec{
+		"":                  &spec.GCPClientCredentialsSpec,
+		ContainerAnalysis:   spec.ContainerAnalysis,
+		BinaryAuthorization: spec.BinaryAuthorization,
+		KMS:                 spec.KMS,
+		SecretManager:       spec.SecretManager,
+		Storage:             spec.Storage,
+		BigQuery:            spec.BigQuery,
+	} {
+		if c == nil || c.KeySecret == "" {
+			continue
+		}
+		if _, _, err := splitSecret(c.KeySecret); err != nil {
+			if client != "" {
+				return errors.Wrapf(err, "invalid %s credentials", client)
+			}
+			return err
+		}
+	}
+	configMu.Lock()
+	defer configMu.Unlock()
+	config = spec
+	return nil
+}
+
+func splitSecret(ref string) (string, string, error) {
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid key secret %q, expected namespace/name", ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+// clientSpec returns the credentials configured for client.
+func clientSpec(client string) v1beta1.GCPClientCredentialsSpec {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	var c *v1beta1.GCPClientCredentialsSpec
+	switch client {
+	case ContainerAnalysis:
+		c = config.ContainerAnalysis
+	case BinaryAuthorization:
+		c = config.BinaryAuthorization
+	case KMS:
+		c = config.KMS
+	case SecretManager:
+		c = config.SecretManager
+	case Storage:
+		c = config.Storage
+	case BigQuery:
+		c = config.BigQuery
+	}
+	if c != nil {
+		return *c
+	}
+	return config.GCPClientCredentialsSpec
+}
+
+// ClientOptions returns the options creating client with its configured credentials.
+// There are none if no credentials are configured, the clients then use Application
+// Default Credentials, e.g. from Workload Identity.
+func ClientOptions(ctx context.Context, client string) ([]option.ClientOption, error) {
+	spec := clientSpec(client)
+	var creds []option.ClientOption
+	if spec.KeySecret != "" {
+		namespace, name, err := splitSecret(spec.KeySecret)
+		if err != nil {
+			return nil, err
+		}
+		data, err := fetchSecret(namespace, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the service account key of %s", client)
+		}
+		key, ok := data[KeySecretKey]
+		if !ok {
+			return nil, fmt.Errorf("secret %s has no %s", spec.KeySecret, KeySecretKey)
+		}
+		creds = append(creds, option.WithCredentialsJSON(key))
+	}
+	opts := creds
+	if spec.ImpersonateServiceAccount != "" {
+		ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+			TargetPrincipal: spec.ImpersonateServiceAccount,
+			Delegates:       spec.Delegates,
+			Scopes:          []string{cloudPlatformScope},
+		}, creds...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to impersonate %s", spec.ImpersonateServiceAccount)
+		}
+		opts = []option.ClientOption{option.WithTokenSource(ts)}
+	}
+	if spec.QuotaProject != "" {
+		opts = append(opts, option.WithQuotaProject(spec.QuotaProject))
+	}
+	return opts, nil
+}
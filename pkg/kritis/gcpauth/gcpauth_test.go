@@ -0,0 +1,114 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcpauth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+const testKey = `{"type": "service_account", "client_email": "kritis@project.iam.gserviceaccount.com", "private_key": "", "token_uri": "https://oauth2.googleapis.com/token"}`
+
+func TestClientOptions(t *testing.T) {
+	fetchSecret = func(namespace, name string) (map[string][]byte, error) {
+		switch namespace + "/" + name {
+		case "kritis/gcp-key":
+			return map[string][]byte{KeySecretKey: []byte(testKey)}, nil
+		case "kritis/empty":
+			return map[string][]byte{}, nil
+		}
+		return nil, fmt.Errorf("secret %s/%s not found", namespace, name)
+	}
+	defer func() {
+		fetchSecret = secrets.FetchData
+		Configure(v1beta1.GCPCredentialsSpec{})
+	}()
+
+	tests := []struct {
+		name      string
+		spec      v1beta1.GCPCredentialsSpec
+		client    string
+		options   int
+		shouldErr bool
+	}{
+		{
+			name:   "application default credentials",
+			client: ContainerAnalysis,
+		},
+		{
+			name:    "key for all clients",
+			spec:    v1beta1.GCPCredentialsSpec{GCPClientCredentialsSpec: v1beta1.GCPClientCredentialsSpec{KeySecret: "kritis/gcp-key"}},
+			client:  KMS,
+			options: 1,
+		},
+		{
+			name: "override of the client",
+			spec: v1beta1.GCPCredentialsSpec{
+				GCPClientCredentialsSpec: v1beta1.GCPClientCredentialsSpec{KeySecret: "kritis/missing"},
+				BinaryAuthorization:      &v1beta1.GCPClientCredentialsSpec{QuotaProject: "billing"},
+			},
+			client:  BinaryAuthorization,
+			options: 1,
+		},
+		{
+			name: "impersonation with a key and quota project",
+			spec: v1beta1.GCPCredentialsSpec{
+				ContainerAnalysis: &v1beta1.GCPClientCredentialsSpec{
+					KeySecret:                 "kritis/gcp-key",
+					ImpersonateServiceAccount: "scanner@project.iam.gserviceaccount.com",
+					QuotaProject:              "billing",
+				},
+			},
+			client:  ContainerAnalysis,
+			options: 2,
+		},
+		{
+			name:      "missing secret",
+			spec:      v1beta1.GCPCredentialsSpec{GCPClientCredentialsSpec: v1beta1.GCPClientCredentialsSpec{KeySecret: "kritis/missing"}},
+			client:    ContainerAnalysis,
+			shouldErr: true,
+		},
+		{
+			name:      "secret without key",
+			spec:      v1beta1.GCPCredentialsSpec{KMS: &v1beta1.GCPClientCredentialsSpec{KeySecret: "kritis/empty"}},
+			client:    KMS,
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := Configure(test.spec); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			opts, err := ClientOptions(context.Background(), test.client)
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.options, len(opts))
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure(v1beta1.GCPCredentialsSpec{})
+	err := Configure(v1beta1.GCPCredentialsSpec{KMS: &v1beta1.GCPClientCredentialsSpec{KeySecret: "gcp-key"}})
+	testutil.CheckError(t, true, err)
+	err = Configure(v1beta1.GCPCredentialsSpec{GCPClientCredentialsSpec: v1beta1.GCPClientCredentialsSpec{KeySecret: "kritis/gcp-key"}})
+	testutil.CheckError(t, false, err)
+}
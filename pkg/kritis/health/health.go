@@ -0,0 +1,227 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package health serves the liveness and readiness endpoints of Kritis, backed by
+// probes of the services reviews depend on.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+// Names of the probes
+const (
+	Kubernetes          = "kubernetes"
+	ContainerAnalysis   = "containerAnalysis"
+	BinaryAuthorization = "binaryAuthorization"
+)
+
+const (
+	// DefaultTimeout of each probe
+	DefaultTimeout = 5 * time.Second
+	// CacheFor is how long the result of a probe is reused, so that frequent
+	// kubelet probes don't add load to the services.
+	CacheFor = 10 * time.Second
+)
+
+// For testing
+var now = time.Now
+
+// Probe checks that a service can be reached.
+type Probe struct {
+	Name  string
+	Check func(ctx context.Context) error
+	// Optional probes are reported without failing the check
+	Optional bool
+}
+
+// Checker runs probes and caches their results.
+type Checker struct {
+	probes  []Probe
+	timeout time.Duration
+
+	mu      sync.Mutex
+	results map[string]result
+}
+
+type result struct {
+	err error
+	at  time.Time
+}
+
+// Report is the outcome of a check, served as JSON.
+type Report struct {
+	// Status is "ok", or "failed" if a probe which isn't optional failed
+	Status string        `json:"status"`
+	Probes []ProbeReport `json:"probes"`
+}
+
+// ProbeReport is the outcome of a probe.
+type ProbeReport struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// New returns a Checker running probes, those named in spec.Optional are made optional.
+func New(spec v1beta1.HealthSpec, probes ...Probe) (*Checker, error) {
+	c := &Checker{
+		timeout: DefaultTimeout,
+		results: map[string]result{},
+	}
+	if spec.Timeout != "" {
+		d, err := time.ParseDuration(spec.Timeout)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid health timeout")
+		}
+		c.timeout = d
+	}
+	optional := map[string]bool{}
+	for _, name := range spec.Optional {
+		switch name {
+		case Kubernetes, ContainerAnalysis, BinaryAuthorization:
+			optional[name] = true
+		default:
+			return nil, fmt.Errorf("unknown health probe %q", name)
+		}
+	}
+	for _, p := range probes {
+		p.Optional = p.Optional || optional[p.Name]
+		c.probes = append(c.probes, p)
+	}
+	return c, nil
+}
+
+// Check runs the named probes concurrently, or all probes if none is named.
+// Results less than CacheFor old are reused.
+func (c *Checker) Check(ctx context.Context, names ...string) Report {
+	var probes []Probe
+	for _, p := range c.probes {
+		if len(names) == 0 || contains(names, p.Name) {
+			probes = append(probes, p)
+		}
+	}
+	report := Report{
+		Status: "ok",
+		Probes: make([]ProbeReport, len(probes)),
+	}
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func(i int, p Probe) {
+			defer wg.Done()
+			r := ProbeReport{Name: p.Name, Status: "ok", Optional: p.Optional}
+			if err := c.run(ctx, p); err != nil {
+				r.Status = "failed"
+				r.Error = err.Error()
+			}
+			report.Probes[i] = r
+		}(i, p)
+	}
+	wg.Wait()
+	for _, r := range report.Probes {
+		if r.Status != "ok" && !r.Optional {
+			report.Status = "failed"
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, p Probe) error {
+	c.mu.Lock()
+	r, ok := c.results[p.Name]
+	c.mu.Unlock()
+	if ok && now().Sub(r.at) < CacheFor {
+		return r.err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	// Probes which ignore ctx are abandoned when it times out.
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = errors.Wrapf(ctx.Err(), "%s probe", p.Name)
+	}
+	if err != nil {
+		glog.Warningf("%s health probe failed: %v", p.Name, err)
+	}
+	c.mu.Lock()
+	c.results[p.Name] = result{err: err, at: now()}
+	c.mu.Unlock()
+	return err
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler serves the Report of the named probes, or of all probes if none is
+// named, with status 200 if it is ok and 503 otherwise.
+func (c *Checker) Handler(names ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := c.Check(r.Context(), names...)
+		payload, err := json.Marshal(report)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write(payload)
+	}
+}
+
+// Alive serves the liveness of the process, which is ok as long as it serves requests.
+// It runs no probe, so that outages of the dependencies of Kritis don't restart it.
+func Alive(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Report{Status: "ok", Probes: []ProbeReport{}})
+}
+
+// KubernetesProbe checks that the API server returns its version.
+func KubernetesProbe(client kubernetes.Interface) Probe {
+	return Probe{
+		Name: Kubernetes,
+		Check: func(context.Context) error {
+			_, err := client.Discovery().ServerVersion()
+			return errors.Wrap(err, "failed to get the version of the Kubernetes API server")
+		},
+	}
+}
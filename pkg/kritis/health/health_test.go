@@ -0,0 +1,164 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func probe(name string, err error) Probe {
+	return Probe{
+		Name:  name,
+		Check: func(context.Context) error { return err },
+	}
+}
+
+func TestCheck(t *testing.T) {
+	slow := Probe{
+		Name: BinaryAuthorization,
+		Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		},
+	}
+	tests := []struct {
+		name     string
+		spec     v1beta1.HealthSpec
+		probes   []Probe
+		names    []string
+		expected Report
+	}{
+		{
+			name:   "all ok",
+			probes: []Probe{KubernetesProbe(fake.NewSimpleClientset()), probe(ContainerAnalysis, nil)},
+			expected: Report{Status: "ok", Probes: []ProbeReport{
+				{Name: Kubernetes, Status: "ok"},
+				{Name: ContainerAnalysis, Status: "ok"},
+			}},
+		},
+		{
+			name:   "failed probe",
+			probes: []Probe{probe(Kubernetes, nil), probe(ContainerAnalysis, fmt.Errorf("unavailable"))},
+			expected: Report{Status: "failed", Probes: []ProbeReport{
+				{Name: Kubernetes, Status: "ok"},
+				{Name: ContainerAnalysis, Status: "failed", Error: "unavailable"},
+			}},
+		},
+		{
+			name:   "failed optional probe",
+			spec:   v1beta1.HealthSpec{Optional: []string{ContainerAnalysis}},
+			probes: []Probe{probe(Kubernetes, nil), probe(ContainerAnalysis, fmt.Errorf("unavailable"))},
+			expected: Report{Status: "ok", Probes: []ProbeReport{
+				{Name: Kubernetes, Status: "ok"},
+				{Name: ContainerAnalysis, Status: "failed", Error: "unavailable", Optional: true},
+			}},
+		},
+		{
+			name:   "named probes",
+			probes: []Probe{probe(Kubernetes, nil), probe(ContainerAnalysis, fmt.Errorf("unavailable"))},
+			names:  []string{Kubernetes},
+			expected: Report{Status: "ok", Probes: []ProbeReport{
+				{Name: Kubernetes, Status: "ok"},
+			}},
+		},
+		{
+			name:   "timeout",
+			spec:   v1beta1.HealthSpec{Timeout: "10ms"},
+			probes: []Probe{slow},
+			expected: Report{Status: "failed", Probes: []ProbeReport{
+				{Name: BinaryAuthorization, Status: "failed", Error: "binaryAuthorization probe: context deadline exceeded"},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := New(test.spec, test.probes...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			testutil.DeepEqual(t, test.expected, c.Check(context.Background(), test.names...))
+		})
+	}
+}
+
+func TestCheckCache(t *testing.T) {
+	calls := 0
+	c, err := New(v1beta1.HealthSpec{}, Probe{
+		Name: Kubernetes,
+		Check: func(context.Context) error {
+			calls++
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start := time.Now()
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+	c.Check(context.Background())
+	c.Check(context.Background())
+	testutil.DeepEqual(t, 1, calls)
+	now = func() time.Time { return start.Add(CacheFor) }
+	c.Check(context.Background())
+	testutil.DeepEqual(t, 2, calls)
+}
+
+func TestHandler(t *testing.T) {
+	c, err := New(v1beta1.HealthSpec{}, probe(Kubernetes, nil), probe(ContainerAnalysis, fmt.Errorf("unavailable")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for path, expected := range map[string]int{
+		"/readyz/kubernetes": http.StatusOK,
+		"/readyz":            http.StatusServiceUnavailable,
+	} {
+		handler := c.Handler()
+		if path == "/readyz/kubernetes" {
+			handler = c.Handler(Kubernetes)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != expected {
+			t.Errorf("%s: expected status %d, got %d: %s", path, expected, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestAlive(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Alive(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	testutil.DeepEqual(t, http.StatusOK, rec.Code)
+	testutil.DeepEqual(t, "{\"status\":\"ok\",\"probes\":[]}\n", rec.Body.String())
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(v1beta1.HealthSpec{Optional: []string{"grafeas"}})
+	testutil.CheckError(t, true, err)
+	_, err = New(v1beta1.HealthSpec{Timeout: "soon"})
+	testutil.CheckError(t, true, err)
+}
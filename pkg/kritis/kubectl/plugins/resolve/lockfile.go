@@ -0,0 +1,97 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolve
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/golang/glog"
+	"gopkg.in/yaml.v2"
+
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+)
+
+// Lockfile maps the images referenced by tags in manifests to the digests they
+// resolved to, so that manifests can be checked to only deploy those digests.
+type Lockfile struct {
+	Images map[string]string `yaml:"images"`
+}
+
+// ReadLockfile reads the lockfile at path.
+func ReadLockfile(path string) (*Lockfile, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lock := &Lockfile{}
+	if err := yaml.Unmarshal(b, lock); err != nil {
+		return nil, fmt.Errorf("invalid lockfile %s: %v", path, err)
+	}
+	return lock, nil
+}
+
+// Write writes the lockfile as YAML, with its images sorted.
+func (l *Lockfile) Write(w io.Writer) error {
+	b, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// Verify returns a problem for each image of the files and rendered directories, see
+// Sources, which is not referenced by a digest of the lockfile, including images
+// referenced by tags.
+func Verify(files []string, lock *Lockfile) ([]string, error) {
+	digests := map[string]bool{}
+	for _, d := range lock.Images {
+		c, err := reference.Canonical(d)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lockfile digest %s: %v", d, err)
+		}
+		digests[c] = true
+	}
+	var problems []string
+	sources, err := Sources(files)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sources {
+		glog.Infof("Verifying %s ...", s.Path)
+		file := s.Path
+		for _, y := range strings.Split(s.Contents, yamlSeparator) {
+			m := yaml.MapSlice{}
+			if err := yaml.Unmarshal([]byte(y), &m); err != nil {
+				return nil, fmt.Errorf("invalid manifest %s: %v", file, err)
+			}
+			for _, image := range recursiveGetImages(m) {
+				if !FullyQualifiedImage(image) {
+					problems = append(problems, fmt.Sprintf("%s: %s is not pinned by digest", file, image))
+					continue
+				}
+				if c, err := reference.Canonical(image); err != nil || !digests[c] {
+					problems = append(problems, fmt.Sprintf("%s: %s is not in the lockfile", file, image))
+				}
+			}
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolve
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+const (
+	lockDigest  = "gcr.io/my-project/app@sha256:3e2e946cb834c4538b789312d566eb16f4a27734fc6b140a3b3f85baafce965f"
+	otherDigest = "gcr.io/my-project/app@sha256:547f82a1a5a194b22d1178f4c6aae3de006152757c0da267fd3a68b03e8b6d85"
+)
+
+var lockYaml = `apiVersion: v1
+kind: Pod
+metadata:
+  name: test
+spec:
+  containers:
+  - name: app
+    image: %s
+  - name: sidecar
+    image: %s
+`
+
+func writeManifest(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExecuteWithLockfile(t *testing.T) {
+	r := newFakeResolver()
+	r.tagMap["gcr.io/my-project/app:1.0"] = lockDigest
+	defer setResolver(r.resolve)()
+	dir, err := ioutil.TempDir("", "lockfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := writeManifest(t, dir, "pod.yaml", fmt.Sprintf(lockYaml, "gcr.io/my-project/app:1.0", otherDigest))
+
+	substitutes, lock, err := ExecuteWithLockfile([]string{file})
+	testutil.CheckErrorAndDeepEqual(t, false, err, map[string]string{"gcr.io/my-project/app:1.0": lockDigest}, lock.Images)
+	testutil.DeepEqual(t, fmt.Sprintf(lockYaml, lockDigest, otherDigest), substitutes[file])
+
+	var b bytes.Buffer
+	if err := lock.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	path := writeManifest(t, dir, "kritis.lock", b.String())
+	read, err := ReadLockfile(path)
+	testutil.CheckErrorAndDeepEqual(t, false, err, lock, read)
+}
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lockfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	lock := &Lockfile{Images: map[string]string{"gcr.io/my-project/app:1.0": lockDigest}}
+
+	pinned := writeManifest(t, dir, "pinned.yaml", fmt.Sprintf(lockYaml, lockDigest, "gcr.io/my-project/app:1.0@"+lockDigest[len("gcr.io/my-project/app@"):]))
+	unpinned := writeManifest(t, dir, "unpinned.yaml", fmt.Sprintf(lockYaml, otherDigest, "gcr.io/my-project/app:1.0"))
+	tests := []struct {
+		name     string
+		files    []string
+		expected []string
+	}{
+		{"all digests in lockfile", []string{pinned}, nil},
+		{"unknown digest and tag", []string{pinned, unpinned}, []string{
+			unpinned + ": " + otherDigest + " is not in the lockfile",
+			unpinned + ": gcr.io/my-project/app:1.0 is not pinned by digest",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems, err := Verify(test.files, lock)
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, problems)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolve
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// Source holds the manifests of a file, or the rendered output of a kustomization or
+// Helm chart directory.
+type Source struct {
+	Path     string
+	Contents string
+}
+
+// For testing
+var (
+	lookPath   = exec.LookPath
+	runCommand = func(name string, args ...string) ([]byte, error) {
+		cmd := exec.Command(name, args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v: %s", strings.Join(cmd.Args, " "), err, strings.TrimSpace(stderr.String()))
+		}
+		return out, nil
+	}
+)
+
+// Sources returns the manifests of the given files and directories.
+// Directories containing a kustomization are rendered with kustomize, directories
+// containing a Chart.yaml with helm template, and other directories are walked
+// recursively for .yaml and .yml files. Hidden directories, e.g. .git, are skipped.
+func Sources(paths []string) ([]Source, error) {
+	sources := []Source{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			s, err := readSource(path)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, s)
+			continue
+		}
+		err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if p != path && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				s, ok, err := renderDir(p)
+				if err != nil {
+					return err
+				}
+				if ok {
+					sources = append(sources, s)
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if ext := filepath.Ext(p); ext != ".yaml" && ext != ".yml" {
+				return nil
+			}
+			s, err := readSource(p)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, s)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sources, nil
+}
+
+func readSource(path string) (Source, error) {
+	glog.Infof("Reading %s ...", path)
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Source{}, err
+	}
+	return Source{Path: path, Contents: string(contents)}, nil
+}
+
+// renderDir renders dir if it is a kustomization or a Helm chart, and returns false
+// otherwise.
+func renderDir(dir string) (Source, bool, error) {
+	var out []byte
+	var err error
+	switch {
+	case isKustomization(dir):
+		glog.Infof("Rendering kustomization %s ...", dir)
+		out, err = kustomizeBuild(dir)
+	case exists(filepath.Join(dir, "Chart.yaml")):
+		glog.Infof("Rendering Helm chart %s ...", dir)
+		out, err = runCommand("helm", "template", filepath.Base(dir), dir)
+	default:
+		return Source{}, false, nil
+	}
+	if err != nil {
+		return Source{}, false, fmt.Errorf("unable to render %s: %v", dir, err)
+	}
+	return Source{Path: dir, Contents: string(out)}, true, nil
+}
+
+// kustomizeBuild uses the kustomize binary if it is installed, and kubectl kustomize
+// otherwise.
+func kustomizeBuild(dir string) ([]byte, error) {
+	if _, err := lookPath("kustomize"); err == nil {
+		return runCommand("kustomize", "build", dir)
+	}
+	return runCommand("kubectl", "kustomize", dir)
+}
+
+func isKustomization(dir string) bool {
+	for _, f := range kustomizationFiles {
+		if exists(filepath.Join(dir, f)) {
+			return true
+		}
+	}
+	return false
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
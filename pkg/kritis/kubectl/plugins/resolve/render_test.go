@@ -0,0 +1,114 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolve
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sources")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTree(t, dir, map[string]string{
+		"apps/pod.yaml":                   "kind: Pod\n",
+		"apps/README.md":                  "not a manifest",
+		"apps/nested/deploy.yml":          "kind: Deployment\n",
+		".git/config.yaml":                "not a manifest",
+		"overlay/kustomization.yaml":      "resources: [../apps]\n",
+		"overlay/patch.yaml":              "not walked",
+		"chart/Chart.yaml":                "name: chart\n",
+		"chart/templates/deployment.yaml": "not walked",
+	})
+
+	originalLookPath, originalRunCommand := lookPath, runCommand
+	defer func() { lookPath, runCommand = originalLookPath, originalRunCommand }()
+	lookPath = func(file string) (string, error) {
+		return "", fmt.Errorf("%s not found", file)
+	}
+	runCommand = func(name string, args ...string) ([]byte, error) {
+		return []byte(name + " " + strings.Join(args, " ")), nil
+	}
+
+	file := filepath.Join(dir, "apps", "pod.yaml")
+	sources, err := Sources([]string{dir, file})
+	expected := []Source{
+		{Path: filepath.Join(dir, "apps", "nested", "deploy.yml"), Contents: "kind: Deployment\n"},
+		{Path: file, Contents: "kind: Pod\n"},
+		{Path: filepath.Join(dir, "chart"), Contents: "helm template chart " + filepath.Join(dir, "chart")},
+		{Path: filepath.Join(dir, "overlay"), Contents: "kubectl kustomize " + filepath.Join(dir, "overlay")},
+		{Path: file, Contents: "kind: Pod\n"},
+	}
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected, sources)
+
+	lookPath = func(file string) (string, error) {
+		return "/usr/local/bin/" + file, nil
+	}
+	sources, err = Sources([]string{filepath.Join(dir, "overlay")})
+	expected = []Source{{Path: filepath.Join(dir, "overlay"), Contents: "kustomize build " + filepath.Join(dir, "overlay")}}
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected, sources)
+
+	runCommand = func(name string, args ...string) ([]byte, error) {
+		return nil, fmt.Errorf("exit status 1")
+	}
+	_, err = Sources([]string{dir})
+	testutil.CheckError(t, true, err)
+}
+
+func TestExecuteRenderedChart(t *testing.T) {
+	r := newFakeResolver()
+	r.tagMap["gcr.io/my-project/app:1.0"] = lockDigest
+	defer setResolver(r.resolve)()
+	dir, err := ioutil.TempDir("", "chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTree(t, dir, map[string]string{"Chart.yaml": "name: chart\n"})
+
+	originalRunCommand := runCommand
+	defer func() { runCommand = originalRunCommand }()
+	runCommand = func(name string, args ...string) ([]byte, error) {
+		return []byte("---\n" + fmt.Sprintf(lockYaml, "gcr.io/my-project/app:1.0", otherDigest)), nil
+	}
+
+	substitutes, lock, err := ExecuteWithLockfile([]string{dir})
+	testutil.CheckErrorAndDeepEqual(t, false, err, map[string]string{"gcr.io/my-project/app:1.0": lockDigest}, lock.Images)
+	testutil.DeepEqual(t, "---\n"+fmt.Sprintf(lockYaml, lockDigest, otherDigest), substitutes[dir])
+}
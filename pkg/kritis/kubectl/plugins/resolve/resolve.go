@@ -21,10 +21,10 @@ import (
 	"strings"
 
 	"github.com/golang/glog"
-	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
-
-	"io/ioutil"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+	"github.com/grafeas/kritis/pkg/kritis/registryauth"
 
 	"gopkg.in/yaml.v2"
 )
@@ -33,55 +33,90 @@ const (
 	yamlSeparator = "---\n"
 )
 
-// Execute replaces image:tag with image:digest in each file
-// It returns a map of [file name]:[new contents]
+// Execute replaces image:tag with image:digest in each file, and in the rendered
+// output of each directory, see Sources.
+// It returns a map of [file or directory name]:[new contents]
 func Execute(files []string) (map[string]string, error) {
+	substitutes, _, err := ExecuteWithLockfile(files)
+	return substitutes, err
+}
+
+// ExecuteWithLockfile replaces image:tag with image:digest in each file as Execute does,
+// and also returns the Lockfile of the tags it resolved.
+func ExecuteWithLockfile(files []string) (map[string]string, *Lockfile, error) {
 	substitutes := map[string]string{}
-	for _, file := range files {
-		glog.Infof("Reading %s ...", file)
-		contents, err := ioutil.ReadFile(file)
+	lock := &Lockfile{Images: map[string]string{}}
+	sources, err := Sources(files)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, s := range sources {
+		newContents, resolved, err := substitute(s.Contents)
 		if err != nil {
-			return nil, err
+			return nil, nil, fmt.Errorf("%s: %v", s.Path, err)
 		}
-		newContents, err := executeSubstitution(string(contents))
-		if err != nil {
-			return nil, err
+		substitutes[s.Path] = newContents
+		for tag, digest := range resolved {
+			lock.Images[tag] = digest
 		}
-		substitutes[file] = newContents
 	}
-	return substitutes, nil
+	return substitutes, lock, nil
 }
 
 func executeSubstitution(contents string) (string, error) {
+	newContents, _, err := substitute(contents)
+	return newContents, err
+}
+
+// substitute returns contents with tagged images replaced by their digests, and the
+// digests the tags resolved to.
+func substitute(contents string) (string, map[string]string, error) {
 	yamls := strings.Split(contents, yamlSeparator)
+	resolved := map[string]string{}
 	for i, y := range yamls {
 		m := yaml.MapSlice{}
 		if err := yaml.Unmarshal([]byte(y), &m); err != nil {
-			return "", err
+			return "", nil, err
+		}
+		// Keep empty documents, e.g. before a leading separator, as they are
+		if len(m) == 0 {
+			continue
 		}
 		taggedImages := recursiveGetTaggedImages(m)
 		resolvedImages, err := resolveTagsToDigests(taggedImages)
 		if err != nil {
-			return "", err
+			return "", nil, err
+		}
+		for tag, digest := range resolvedImages {
+			resolved[tag] = digest
 		}
 		replacedYaml := recursiveReplaceImage(m, resolvedImages)
 		updatedManifest, err := yaml.Marshal(replacedYaml)
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 		yamls[i] = string(updatedManifest)
 	}
-	return strings.Join(yamls, yamlSeparator), nil
+	return strings.Join(yamls, yamlSeparator), resolved, nil
 }
 
 // For testing
-var resolver = func(image string) (string, error) {
+var resolver = ResolveDigest
+
+// ResolveDigest resolves an image referenced by tag to image@sha256:digest
+func ResolveDigest(image string) (string, error) {
+	return ResolveDigestWithKeychain(image, registryauth.Keychain())
+}
+
+// ResolveDigestWithKeychain resolves an image referenced by tag to image@sha256:digest,
+// authenticating to its registry with kc.
+func ResolveDigestWithKeychain(image string, kc authn.Keychain) (string, error) {
 	glog.Infof("Resolving image %s ...", image)
-	tag, err := name.NewTag(image, name.WeakValidation)
+	tag, err := reference.ParseTag(image)
 	if err != nil {
-		return "", fmt.Errorf("NewTag(%s): %v", image, err)
+		return "", fmt.Errorf("ParseTag(%s): %v", image, err)
 	}
-	sourceImage, err := remote.Image(tag)
+	sourceImage, err := remote.Image(tag, remote.WithAuthFromKeychain(kc))
 	if err != nil {
 		return "", fmt.Errorf("remote.Image(%s): %v", tag, err)
 	}
@@ -97,24 +132,32 @@ var resolver = func(image string) (string, error) {
 // recursiveGetTaggedImages recursively gets all images referenced by tags
 // instead of digests
 func recursiveGetTaggedImages(m interface{}) []string {
+	images := []string{}
+	for _, image := range recursiveGetImages(m) {
+		if !FullyQualifiedImage(image) {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// recursiveGetImages recursively gets all images, by tag or by digest
+func recursiveGetImages(m interface{}) []string {
 	images := []string{}
 	switch t := m.(type) {
 	case yaml.MapSlice:
 		for _, v := range t {
-			images = append(images, recursiveGetTaggedImages(v)...)
+			images = append(images, recursiveGetImages(v)...)
 		}
 	case yaml.MapItem:
 		if t.Key.(string) == "image" {
-			image := t.Value.(string)
-			if !FullyQualifiedImage(image) {
-				images = append(images, image)
-			}
+			images = append(images, t.Value.(string))
 		} else {
-			images = append(images, recursiveGetTaggedImages(t.Value)...)
+			images = append(images, recursiveGetImages(t.Value)...)
 		}
 	case []interface{}:
 		for _, v := range t {
-			images = append(images, recursiveGetTaggedImages(v)...)
+			images = append(images, recursiveGetImages(v)...)
 		}
 	}
 	return images
@@ -122,8 +165,7 @@ func recursiveGetTaggedImages(m interface{}) []string {
 
 // FullyQualifiedImage returns true if the image is fully qualified
 func FullyQualifiedImage(image string) bool {
-	_, err := name.NewDigest(image, name.WeakValidation)
-	return err == nil
+	return reference.HasDigest(image)
 }
 
 // resolveTagsToDigests resolves all images specified by tag to digest
@@ -20,6 +20,8 @@ import (
 	"fmt"
 
 	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 
@@ -40,3 +42,30 @@ func GetClientset() (kubernetes.Interface, error) {
 	}
 	return client, nil
 }
+
+// Namespace returns the namespace with the given name
+func Namespace(name string) (*v1.Namespace, error) {
+	client, err := GetClientset()
+	if err != nil {
+		return nil, err
+	}
+	return client.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+}
+
+// ConfigMap returns the ConfigMap with the given namespace and name
+func ConfigMap(namespace, name string) (*v1.ConfigMap, error) {
+	client, err := GetClientset()
+	if err != nil {
+		return nil, err
+	}
+	return client.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+}
+
+// Pod returns the pod with the given namespace and name
+func Pod(namespace, name string) (*v1.Pod, error) {
+	client, err := GetClientset()
+	if err != nil {
+		return nil, err
+	}
+	return client.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+}
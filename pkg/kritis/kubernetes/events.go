@@ -0,0 +1,56 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EventSourceComponent is the source component of events created by Kritis
+const EventSourceComponent = "kritis"
+
+// NewEvent returns an event about the object, with a name generated by the API server.
+func NewEvent(object corev1.ObjectReference, eventType string, reason string, message string) *corev1.Event {
+	now := metav1.NewTime(time.Now())
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: object.Name + ".",
+			Namespace:    object.Namespace,
+		},
+		InvolvedObject: object,
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: EventSourceComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+}
+
+// CreateEvent creates the event in the namespace of its object.
+func CreateEvent(event *corev1.Event) error {
+	clientset, err := GetClientset()
+	if err != nil {
+		return err
+	}
+	_, err = clientset.CoreV1().Events(event.Namespace).Create(event)
+	return err
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package leader elects the Kritis replica running the cron job, so that replicas
+// don't review the same pods and create the same attestations.
+package leader
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+// DefaultLockName is the name of the lock ConfigMap.
+const DefaultLockName = "kritis-cron"
+
+// For testing
+var (
+	hostname      = os.Hostname
+	namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// Elector runs a function while the replica holds the lock, and campaigns again
+// whenever it loses it.
+type Elector struct {
+	config leaderelection.LeaderElectionConfig
+	lock   resourcelock.Interface
+	run    func(stop <-chan struct{})
+	// running is done when run returned
+	running sync.WaitGroup
+
+	mu      sync.RWMutex
+	leading bool
+	leader  string
+	since   time.Time
+}
+
+// Status is the leadership state reported by the Handler.
+type Status struct {
+	Identity string    `json:"identity"`
+	Leader   string    `json:"leader"`
+	IsLeader bool      `json:"isLeader"`
+	Since    time.Time `json:"since"`
+}
+
+// New returns an Elector campaigning for the lock configured by spec, which calls
+// run when it starts leading. stop is closed when it stops leading, run must then return.
+func New(client kubernetes.Interface, spec v1beta1.LeaderElectionSpec, run func(stop <-chan struct{})) (*Elector, error) {
+	identity, err := hostname()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the identity of the replica")
+	}
+	namespace := spec.Namespace
+	if namespace == "" {
+		b, err := ioutil.ReadFile(namespaceFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the namespace of Kritis, set leaderElection.namespace")
+		}
+		namespace = strings.TrimSpace(string(b))
+	}
+	name := spec.LockName
+	if name == "" {
+		name = DefaultLockName
+	}
+	e := &Elector{run: run}
+	e.config.LeaseDuration, err = duration(spec.LeaseDuration, 15*time.Second)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid leaseDuration")
+	}
+	e.config.RenewDeadline, err = duration(spec.RenewDeadline, 10*time.Second)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid renewDeadline")
+	}
+	e.config.RetryPeriod, err = duration(spec.RetryPeriod, 2*time.Second)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid retryPeriod")
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events(namespace)})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "kritis", Host: identity})
+	e.lock, err = resourcelock.New(resourcelock.ConfigMapsResourceLock, namespace, name, client.CoreV1(), resourcelock.ResourceLockConfig{
+		Identity:      identity,
+		EventRecorder: recorder,
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.config.Lock = e.lock
+	e.config.Callbacks = leaderelection.LeaderCallbacks{
+		OnStartedLeading: e.startedLeading,
+		OnStoppedLeading: e.stoppedLeading,
+		OnNewLeader:      e.newLeader,
+	}
+	// Validate the durations now rather than when campaigning.
+	if _, err := leaderelection.NewLeaderElector(e.config); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func duration(s string, d time.Duration) (time.Duration, error) {
+	if s == "" {
+		return d, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// Run campaigns for the lock and never returns. Leading stops when the lock can't
+// be renewed in time, the replica then campaigns again once run returned.
+func (e *Elector) Run() {
+	for {
+		le, err := leaderelection.NewLeaderElector(e.config)
+		if err != nil {
+			glog.Errorf("failed to elect the leader: %v", err)
+			return
+		}
+		e.running.Add(1)
+		le.Run()
+		e.running.Wait()
+	}
+}
+
+func (e *Elector) startedLeading(stop <-chan struct{}) {
+	glog.Infof("%s started leading", e.lock.Identity())
+	e.mu.Lock()
+	e.leading = true
+	e.leader = e.lock.Identity()
+	e.since = time.Now()
+	e.mu.Unlock()
+	defer e.running.Done()
+	e.run(stop)
+}
+
+func (e *Elector) stoppedLeading() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.leading {
+		glog.Infof("%s stopped leading", e.lock.Identity())
+	}
+	e.leading = false
+	e.since = time.Time{}
+}
+
+func (e *Elector) newLeader(identity string) {
+	glog.Infof("%s is the leader", identity)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.leader = identity
+}
+
+// IsLeader returns whether the replica holds the lock.
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leading
+}
+
+// Status returns the leadership state of the replica.
+func (e *Elector) Status() Status {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return Status{
+		Identity: e.lock.Identity(),
+		Leader:   e.leader,
+		IsLeader: e.leading,
+		Since:    e.since,
+	}
+}
+
+// Handler serves the Status as JSON, with status 200 while the replica leads
+// and 503 otherwise.
+func (e *Elector) Handler(w http.ResponseWriter, r *http.Request) {
+	status := e.Status()
+	payload, err := json.Marshal(status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !status.IsLeader {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(payload)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leader
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+var testSpec = v1beta1.LeaderElectionSpec{
+	Enabled:   true,
+	Namespace: "kritis",
+	// Lock records have a precision of one second, leases must be longer
+	LeaseDuration: "2s",
+	RenewDeadline: "1s",
+	RetryPeriod:   "50ms",
+}
+
+func newTestElector(t *testing.T, client *fake.Clientset, identity string, run func(stop <-chan struct{})) *Elector {
+	hostname = func() (string, error) { return identity, nil }
+	e, err := New(client, testSpec, run)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return e
+}
+
+func status(t *testing.T, e *Elector) (int, Status) {
+	rec := httptest.NewRecorder()
+	e.Handler(rec, httptest.NewRequest(http.MethodGet, "/readyz/leader", nil))
+	var s Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return rec.Code, s
+}
+
+func TestElector(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	started := make(chan string, 2)
+	a := newTestElector(t, client, "a", func(stop <-chan struct{}) {
+		started <- "a"
+		<-stop
+	})
+	b := newTestElector(t, client, "b", func(stop <-chan struct{}) {
+		started <- "b"
+		<-stop
+	})
+
+	if code, s := status(t, a); code != http.StatusServiceUnavailable || s.IsLeader {
+		t.Errorf("expected a not to lead before campaigning, got %d %+v", code, s)
+	}
+	go a.Run()
+	select {
+	case id := <-started:
+		if id != "a" {
+			t.Fatalf("expected a to lead, got %s", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a did not start leading")
+	}
+	go b.Run()
+	if err := wait.Poll(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return b.Status().Leader == "a", nil
+	}); err != nil {
+		t.Fatalf("b did not observe the leader: %v", err)
+	}
+
+	if code, s := status(t, a); code != http.StatusOK || !s.IsLeader || s.Identity != "a" || s.Leader != "a" || s.Since.IsZero() {
+		t.Errorf("unexpected status of a: %d %+v", code, s)
+	}
+	if code, s := status(t, b); code != http.StatusServiceUnavailable || s.IsLeader || s.Identity != "b" || s.Leader != "a" {
+		t.Errorf("unexpected status of b: %d %+v", code, s)
+	}
+	select {
+	case id := <-started:
+		t.Errorf("expected only a to run, %s started too", id)
+	case <-time.After(2 * testLeaseDuration(t)):
+	}
+	if _, err := client.CoreV1().ConfigMaps("kritis").Get(DefaultLockName, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the lock ConfigMap: %v", err)
+	}
+}
+
+func testLeaseDuration(t *testing.T) time.Duration {
+	d, err := time.ParseDuration(testSpec.LeaseDuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      v1beta1.LeaderElectionSpec
+		shouldErr bool
+	}{
+		{"defaults", v1beta1.LeaderElectionSpec{Enabled: true, Namespace: "kritis"}, false},
+		{"invalid duration", v1beta1.LeaderElectionSpec{Enabled: true, Namespace: "kritis", RetryPeriod: "soon"}, true},
+		{"short lease", v1beta1.LeaderElectionSpec{Enabled: true, Namespace: "kritis", LeaseDuration: "5s"}, true},
+		{"no namespace", v1beta1.LeaderElectionSpec{Enabled: true}, true},
+	}
+	namespaceFile = "testdata/missing"
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := New(fake.NewSimpleClientset(), test.spec, func(<-chan struct{}) {})
+			if (err != nil) != test.shouldErr {
+				t.Errorf("expected error %t, got %v", test.shouldErr, err)
+			}
+		})
+	}
+}
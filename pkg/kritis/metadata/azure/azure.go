@@ -0,0 +1,291 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package azure implements a metadata.Fetcher reading the container image
+// vulnerability assessments of Microsoft Defender for Cloud, for images
+// stored in Azure Container Registry, through Azure Resource Graph.
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/severity"
+)
+
+const (
+	armResource       = "https://management.azure.com/"
+	activeDirectory   = "https://login.microsoftonline.com/"
+	resourceGraphPath = "providers/Microsoft.ResourceGraph/resources?api-version=2021-03-01"
+	// assessmentKey identifies the Defender assessment of ACR container image vulnerabilities.
+	assessmentKey = "c0b7cfc6-3172-465a-b378-53c7ff2cc0d5"
+	fixAvailable  = "FixAvailable"
+)
+
+// acrSuffixes are the registry host suffixes of ACR in the Azure clouds.
+var acrSuffixes = []string{".azurecr.io", ".azurecr.cn", ".azurecr.us"}
+
+// For testing
+var resourceGraphURL = armResource + resourceGraphPath
+
+// tokenSource provides the bearer tokens of the Azure Resource Manager API.
+type tokenSource interface {
+	EnsureFresh() error
+	OAuthToken() string
+}
+
+// Client implements the Fetcher interface using Defender for Cloud assessments.
+// Defender stores no attestations, these are not supported.
+type Client struct {
+	subscriptions []string
+	token         tokenSource
+	client        *http.Client
+}
+
+// New returns a Client querying the assessments of the configured subscriptions.
+// It authenticates as the service principal set by AZURE_TENANT_ID, AZURE_CLIENT_ID
+// and AZURE_CLIENT_SECRET, or else as the managed identity of the node.
+func New(config kritisv1beta1.AzureConfigSpec) (*Client, error) {
+	if len(config.Subscriptions) == 0 {
+		return nil, fmt.Errorf("no Azure subscriptions configured")
+	}
+	token, err := NewToken()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to authenticate to Azure")
+	}
+	return &Client{
+		subscriptions: config.Subscriptions,
+		token:         token,
+		client:        http.DefaultClient,
+	}, nil
+}
+
+// NewToken returns an Azure Resource Manager token of the service principal set by
+// AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET, or else of the managed identity of the node.
+func NewToken() (*adal.ServicePrincipalToken, error) {
+	tenant, id, secret := os.Getenv("AZURE_TENANT_ID"), os.Getenv("AZURE_CLIENT_ID"), os.Getenv("AZURE_CLIENT_SECRET")
+	if tenant != "" && id != "" && secret != "" {
+		oauth, err := adal.NewOAuthConfig(activeDirectory, tenant)
+		if err != nil {
+			return nil, err
+		}
+		return adal.NewServicePrincipalToken(*oauth, id, secret, armResource)
+	}
+	endpoint, err := adal.GetMSIVMEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	if id != "" {
+		return adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(endpoint, armResource, id)
+	}
+	return adal.NewServicePrincipalTokenFromMSI(endpoint, armResource)
+}
+
+// Close closes connection
+func (c *Client) Close() {
+	// No Ops
+}
+
+type queryRequest struct {
+	Subscriptions []string     `json:"subscriptions"`
+	Query         string       `json:"query"`
+	Options       queryOptions `json:"options"`
+}
+
+type queryOptions struct {
+	ResultFormat string `json:"resultFormat"`
+	SkipToken    string `json:"$skipToken,omitempty"`
+}
+
+type queryResponse struct {
+	SkipToken string    `json:"$skipToken"`
+	Data      []finding `json:"data"`
+}
+
+// finding is a row of the assessments query.
+type finding struct {
+	CVE       string `json:"cve"`
+	Severity  string `json:"severity"`
+	FixStatus string `json:"fixStatus"`
+}
+
+// Vulnerabilities gets the vulnerabilities assessed by Defender for an ACR image.
+// Defender only reports unhealthy images, an image without findings has no vulnerabilities.
+func (c *Client) Vulnerabilities(ctx context.Context, containerImage string) ([]metadata.Vulnerability, error) {
+	query, err := assessmentsQuery(containerImage)
+	if err != nil {
+		return nil, err
+	}
+	vulnz := []metadata.Vulnerability{}
+	req := queryRequest{
+		Subscriptions: c.subscriptions,
+		Query:         query,
+		Options:       queryOptions{ResultFormat: "objectArray"},
+	}
+	for {
+		resp, err := c.query(ctx, req)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get assessments of %s", containerImage)
+		}
+		for _, f := range resp.Data {
+			vulnz = append(vulnz, metadata.Vulnerability{
+				CVE:             f.CVE,
+				Severity:        severity.Normalize(f.Severity),
+				HasFixAvailable: f.FixStatus == fixAvailable,
+			})
+		}
+		if resp.SkipToken == "" {
+			return vulnz, nil
+		}
+		req.Options.SkipToken = resp.SkipToken
+	}
+}
+
+func (c *Client) query(ctx context.Context, q queryRequest) (*queryResponse, error) {
+	if err := c.token.EnsureFresh(); err != nil {
+		return nil, errors.Wrap(err, "failed to refresh token")
+	}
+	body, err := json.Marshal(q)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, resourceGraphURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token.OAuthToken())
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("resource graph returned %s: %s", resp.Status, b)
+	}
+	qr := &queryResponse{}
+	if err := json.Unmarshal(b, qr); err != nil {
+		return nil, errors.Wrap(err, "failed to parse resource graph response")
+	}
+	return qr, nil
+}
+
+// assessmentsQuery returns the Resource Graph query listing the findings of an ACR image.
+func assessmentsQuery(containerImage string) (string, error) {
+	ref, err := reference.ParseReference(containerImage)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid image %s", containerImage)
+	}
+	registry := ref.Context().RegistryStr()
+	if !isACR(registry) {
+		return "", fmt.Errorf("%s is not an ACR image", containerImage)
+	}
+	var artifact string
+	switch r := ref.(type) {
+	case name.Digest:
+		artifact = fmt.Sprintf(`properties.additionalData.artifactDetails.digest == "%s"`, r.DigestStr())
+	case name.Tag:
+		artifact = fmt.Sprintf(`set_has_element(properties.additionalData.artifactDetails.tags, "%s")`, r.TagStr())
+	}
+	return fmt.Sprintf(`securityresources
+| where type == "microsoft.security/assessments/subassessments"
+| where id contains "/assessments/%s/"
+| where properties.additionalData.artifactDetails.registryHost == "%s"
+| where properties.additionalData.artifactDetails.repositoryName == "%s"
+| where %s
+| project cve = tostring(properties.id), severity = tostring(properties.status.severity), fixStatus = tostring(properties.additionalData.softwareDetails.fixStatus)`,
+		assessmentKey, registry, ref.Context().RepositoryStr(), artifact), nil
+}
+
+func isACR(registry string) bool {
+	for _, s := range acrSuffixes {
+		if strings.HasSuffix(registry, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Attestations returns no attestations, Defender does not store them.
+func (c *Client) Attestations(ctx context.Context, containerImage string) ([]metadata.PGPAttestation, error) {
+	return nil, nil
+}
+
+// OccurencesV1 returns no occurrences, Defender does not store them.
+func (c *Client) OccurencesV1(ctx context.Context, containerImage string) ([]*metadata.OccurenceV1, error) {
+	return nil, nil
+}
+
+// Builds returns no builds, Defender does not store them.
+func (c *Client) Builds(ctx context.Context, containerImage string) ([]metadata.Build, error) {
+	return nil, nil
+}
+
+// AttestationNote is not supported by Defender.
+func (c *Client) AttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	return nil, fmt.Errorf("attestations are not supported by the Azure backend")
+}
+
+// CreateAttestationNote is not supported by Defender.
+func (c *Client) CreateAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	return nil, fmt.Errorf("attestations are not supported by the Azure backend")
+}
+
+// CreateAttestationOccurence is not supported by Defender.
+func (c *Client) CreateAttestationOccurence(ctx context.Context, note *grafeas.Note,
+	containerImage string,
+	pgpSigningKey *secrets.PGPSigningSecret) (*grafeas.Occurrence, error) {
+	return nil, fmt.Errorf("attestations are not supported by the Azure backend")
+}
+
+// DiscoveryNote is not supported by Defender.
+func (c *Client) DiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	return nil, fmt.Errorf("discovery notes are not supported by the Azure backend")
+}
+
+// CreateDiscoveryNote is not supported by Defender.
+func (c *Client) CreateDiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	return nil, fmt.Errorf("discovery notes are not supported by the Azure backend")
+}
+
+// CreateDiscoveryOccurrence is not supported by Defender.
+func (c *Client) CreateDiscoveryOccurrence(ctx context.Context, note *grafeas.Note, containerImage string, message string) (*grafeas.Occurrence, error) {
+	return nil, fmt.Errorf("discovery occurrences are not supported by the Azure backend")
+}
+
+// ScanStatus returns an unknown status, the Azure backend doesn't report it.
+func (c *Client) ScanStatus(ctx context.Context, containerImage string) (metadata.ScanStatus, error) {
+	return metadata.ScanStatusUnknown, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+const image = "myregistry.azurecr.io/team/app@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+type staticToken string
+
+func (t staticToken) EnsureFresh() error { return nil }
+func (t staticToken) OAuthToken() string { return string(t) }
+
+func TestVulnerabilities(t *testing.T) {
+	pages := []queryResponse{
+		{
+			SkipToken: "next",
+			Data: []finding{
+				{CVE: "CVE-1", Severity: "High", FixStatus: "FixAvailable"},
+				{CVE: "CVE-2", Severity: "Critical", FixStatus: "NoFixAvailable"},
+			},
+		},
+		{
+			Data: []finding{{CVE: "CVE-3", Severity: "Unknown"}},
+		},
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		q := queryRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			t.Fatal(err)
+		}
+		testutil.DeepEqual(t, []string{"sub"}, q.Subscriptions)
+		page := pages[0]
+		if q.Options.SkipToken == "next" {
+			page = pages[1]
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer s.Close()
+	orig := resourceGraphURL
+	defer func() { resourceGraphURL = orig }()
+	resourceGraphURL = s.URL
+
+	c := &Client{subscriptions: []string{"sub"}, token: staticToken("token"), client: http.DefaultClient}
+	actual, err := c.Vulnerabilities(context.Background(), image)
+	expected := []metadata.Vulnerability{
+		{CVE: "CVE-1", Severity: "HIGH", HasFixAvailable: true},
+		{CVE: "CVE-2", Severity: "CRITICAL", HasFixAvailable: false},
+		{CVE: "CVE-3", Severity: "SEVERITY_UNSPECIFIED", HasFixAvailable: false},
+	}
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected, actual)
+
+	c.token = staticToken("expired")
+	_, err = c.Vulnerabilities(context.Background(), image)
+	testutil.CheckError(t, true, err)
+}
+
+func TestAssessmentsQuery(t *testing.T) {
+	tcs := []struct {
+		name      string
+		image     string
+		shouldErr bool
+		contains  []string
+	}{
+		{
+			name:  "digest",
+			image: image,
+			contains: []string{
+				`registryHost == "myregistry.azurecr.io"`,
+				`repositoryName == "team/app"`,
+				`digest == "sha256:0000000000000000000000000000000000000000000000000000000000000000"`,
+			},
+		},
+		{
+			name:     "tag",
+			image:    "myregistry.azurecr.cn/app:1.0",
+			contains: []string{`set_has_element(properties.additionalData.artifactDetails.tags, "1.0")`},
+		},
+		{
+			name:      "not an ACR image",
+			image:     testutil.QualifiedImage,
+			shouldErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := assessmentsQuery(tc.image)
+			testutil.CheckError(t, tc.shouldErr, err)
+			for _, c := range tc.contains {
+				if !strings.Contains(q, c) {
+					t.Errorf("expected query to contain %s, got:\n%s", c, q)
+				}
+			}
+		})
+	}
+}
@@ -17,6 +17,9 @@ limitations under the License.
 package containeranalysis
 
 import (
+	"context"
+	"sync"
+
 	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
 	"github.com/grafeas/kritis/pkg/kritis/secrets"
@@ -24,7 +27,9 @@ import (
 )
 
 // Cache struct defines Cache for container analysis client.
+// It is safe for concurrent use.
 type Cache struct {
+	mu     sync.Mutex
 	client metadata.Fetcher
 	vuln   map[string][]metadata.Vulnerability
 	att    map[string][]metadata.PGPAttestation
@@ -44,81 +49,127 @@ func NewCache() (*Cache, error) {
 		vuln:   map[string][]metadata.Vulnerability{},
 		att:    map[string][]metadata.PGPAttestation{},
 		occ:    map[string][]*metadata.OccurenceV1{},
+		build:  map[string][]metadata.Build{},
 		notes:  map[*kritisv1beta1.AttestationAuthority]*grafeas.Note{},
 	}, nil
 }
 
 // Close closes connection
-func (c Cache) Close() {
+func (c *Cache) Close() {
 	c.client.Close()
 }
 
 // Vulnerabilities gets Package Vulnerabilities Occurrences for a specified image.
-func (c Cache) Vulnerabilities(image string) ([]metadata.Vulnerability, error) {
-	if v, ok := c.vuln[image]; ok {
+func (c *Cache) Vulnerabilities(ctx context.Context, image string) ([]metadata.Vulnerability, error) {
+	c.mu.Lock()
+	v, ok := c.vuln[image]
+	c.mu.Unlock()
+	if ok && !metadata.Refreshing(ctx) {
 		return v, nil
 	}
-	v, err := c.client.Vulnerabilities(image)
-	if err != nil {
+	v, err := c.client.Vulnerabilities(ctx, image)
+	if err == nil {
+		c.mu.Lock()
 		c.vuln[image] = v
+		c.mu.Unlock()
 	}
 	return v, err
 }
 
 // Attestations gets AttesationAuthority Occurrences for a specified image from cache or from client.
-func (c Cache) Attestations(image string) ([]metadata.PGPAttestation, error) {
-	if a, ok := c.att[image]; ok {
+func (c *Cache) Attestations(ctx context.Context, image string) ([]metadata.PGPAttestation, error) {
+	c.mu.Lock()
+	a, ok := c.att[image]
+	c.mu.Unlock()
+	if ok && !metadata.Refreshing(ctx) {
 		return a, nil
 	}
-	a, err := c.client.Attestations(image)
-	if err != nil {
+	a, err := c.client.Attestations(ctx, image)
+	if err == nil {
+		c.mu.Lock()
 		c.att[image] = a
+		c.mu.Unlock()
 	}
 	return a, err
 }
 
 // OccurencesV1 gets V1 Occurrences for a specified image.
-func (c Cache) OccurencesV1(image string) ([]*metadata.OccurenceV1, error) {
-	if o, ok := c.occ[image]; ok {
+func (c *Cache) OccurencesV1(ctx context.Context, image string) ([]*metadata.OccurenceV1, error) {
+	c.mu.Lock()
+	o, ok := c.occ[image]
+	c.mu.Unlock()
+	if ok && !metadata.Refreshing(ctx) {
 		return o, nil
 	}
-	o, err := c.client.OccurencesV1(image)
-	if err != nil {
+	o, err := c.client.OccurencesV1(ctx, image)
+	if err == nil {
+		c.mu.Lock()
 		c.occ[image] = o
+		c.mu.Unlock()
 	}
 	return o, err
 }
 
+// ScanStatus gets the status of the vulnerability scan of an image, which is never cached.
+func (c *Cache) ScanStatus(ctx context.Context, image string) (metadata.ScanStatus, error) {
+	return c.client.ScanStatus(ctx, image)
+}
+
 // CreateAttestationNote creates an attestation note from AttestationAuthority
-func (c Cache) CreateAttestationNote(aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
-	return c.client.CreateAttestationNote(aa)
+func (c *Cache) CreateAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	return c.client.CreateAttestationNote(ctx, aa)
 }
 
 // AttestationNote returns a note if it exists for given AttestationAuthority
-func (c Cache) AttestationNote(aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
-	if n, ok := c.notes[aa]; ok {
+func (c *Cache) AttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	c.mu.Lock()
+	n, ok := c.notes[aa]
+	c.mu.Unlock()
+	if ok {
 		return n, nil
 	}
-	n, err := c.client.AttestationNote(aa)
-	if err != nil {
+	n, err := c.client.AttestationNote(ctx, aa)
+	if err == nil {
+		c.mu.Lock()
 		c.notes[aa] = n
+		c.mu.Unlock()
 	}
 	return n, err
 }
 
 // CreateAttestationOccurence creates an Attestation occurrence for a given image and secret.
-func (c Cache) CreateAttestationOccurence(n *grafeas.Note, image string, p *secrets.PGPSigningSecret) (*grafeas.Occurrence, error) {
-	return c.client.CreateAttestationOccurence(n, image, p)
+func (c *Cache) CreateAttestationOccurence(ctx context.Context, n *grafeas.Note, image string, p *secrets.PGPSigningSecret) (*grafeas.Occurrence, error) {
+	return c.client.CreateAttestationOccurence(ctx, n, image, p)
+}
+
+// DiscoveryNote returns the Discovery note in the project hosting the image.
+func (c *Cache) DiscoveryNote(ctx context.Context, image string, noteID string) (*grafeas.Note, error) {
+	return c.client.DiscoveryNote(ctx, image, noteID)
+}
+
+// CreateDiscoveryNote creates a Discovery note in the project hosting the image.
+func (c *Cache) CreateDiscoveryNote(ctx context.Context, image string, noteID string) (*grafeas.Note, error) {
+	return c.client.CreateDiscoveryNote(ctx, image, noteID)
+}
+
+// CreateDiscoveryOccurrence creates a Discovery occurrence with message for a given image.
+func (c *Cache) CreateDiscoveryOccurrence(ctx context.Context, n *grafeas.Note, image string, message string) (*grafeas.Occurrence, error) {
+	return c.client.CreateDiscoveryOccurrence(ctx, n, image, message)
 }
 
 // Builds gets Build Occurrences for a specified image.
-func (c Cache) Builds(image string) ([]metadata.Build, error) {
-	if v, ok := c.build[image]; ok {
+func (c *Cache) Builds(ctx context.Context, image string) ([]metadata.Build, error) {
+	c.mu.Lock()
+	v, ok := c.build[image]
+	c.mu.Unlock()
+	if ok {
 		return v, nil
 	}
-	v, err := c.client.Builds(image)
-	if err != nil {
+	v, err := c.client.Builds(ctx, image)
+	if err == nil {
+		c.mu.Lock()
 		c.build[image] = v
+		c.mu.Unlock()
 	}
 	return v, err
 }
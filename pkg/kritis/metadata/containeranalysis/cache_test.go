@@ -17,6 +17,8 @@ limitations under the License.
 package containeranalysis
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -46,7 +48,7 @@ func TestVCache(t *testing.T) {
 	}
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
-			actual, err := c.Vulnerabilities(tc.image)
+			actual, err := c.Vulnerabilities(context.Background(), tc.image)
 			if err != nil {
 				t.Errorf("unexpected error %v", err)
 			}
@@ -76,7 +78,7 @@ func TestACache(t *testing.T) {
 	}
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
-			actual, err := c.Attestations(tc.image)
+			actual, err := c.Attestations(context.Background(), tc.image)
 			if err != nil {
 				t.Errorf("unexpected error %v", err)
 			}
@@ -112,7 +114,7 @@ func TestNCache(t *testing.T) {
 	}
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
-			actual, err := c.AttestationNote(tc.aa)
+			actual, err := c.AttestationNote(context.Background(), tc.aa)
 			if err != nil {
 				t.Errorf("unexpected error %v", err)
 			}
@@ -122,3 +124,31 @@ func TestNCache(t *testing.T) {
 		})
 	}
 }
+
+func TestCacheCalls(t *testing.T) {
+	vulnz := []metadata.Vulnerability{{CVE: "CVE-1"}}
+	client := &testutil.MockMetadataClient{
+		Images: map[string]testutil.MockImage{"image": {Vulnz: vulnz}},
+		Errors: map[string][]error{"Vulnerabilities": {fmt.Errorf("unavailable")}},
+	}
+	c := Cache{
+		client: client,
+		vuln:   map[string][]metadata.Vulnerability{},
+	}
+	// Errors are not cached.
+	if _, err := c.Vulnerabilities(context.Background(), "image"); err == nil {
+		t.Fatalf("expected an error")
+	}
+	for i := 0; i < 2; i++ {
+		actual, err := c.Vulnerabilities(context.Background(), "image")
+		testutil.CheckErrorAndDeepEqual(t, false, err, vulnz, actual)
+	}
+	testutil.DeepEqual(t, 2, client.Calls("Vulnerabilities", "image"))
+	actual, err := c.Vulnerabilities(context.Background(), "other")
+	testutil.CheckErrorAndDeepEqual(t, false, err, []metadata.Vulnerability(nil), actual)
+	testutil.DeepEqual(t, 3, client.Calls("Vulnerabilities", ""))
+	// Refreshing reads skip the cache.
+	actual, err = c.Vulnerabilities(metadata.WithRefresh(context.Background()), "image")
+	testutil.CheckErrorAndDeepEqual(t, false, err, vulnz, actual)
+	testutil.DeepEqual(t, 3, client.Calls("Vulnerabilities", "image"))
+}
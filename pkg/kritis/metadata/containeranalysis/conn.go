@@ -0,0 +1,146 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containeranalysis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	ca "cloud.google.com/go/containeranalysis/apiv1beta1"
+	"github.com/pkg/errors"
+	cav1 "google.golang.org/api/containeranalysis/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/gcpauth"
+)
+
+const (
+	defaultKeepaliveTime    = 5 * time.Minute
+	defaultKeepaliveTimeout = 20 * time.Second
+)
+
+var (
+	connMu sync.Mutex
+	// conn is shared by the clients returned by New until Configure is called again
+	conn        *connection
+	connOptions = mustConnectionOptions(kritisv1beta1.GRPCConnectionSpec{})
+
+	// For testing
+	connCloseDelay = time.Minute
+)
+
+// connection holds the clients of the Container Analysis APIs, whose gRPC connections
+// are kept open and reused across reviews, rather than dialed for each admission request.
+type connection struct {
+	client   *ca.GrafeasV1Beta1Client
+	clientV1 *cav1.Service
+}
+
+// sharedConnection returns the shared connection, opening it on first use.
+func sharedConnection(ctx context.Context) (*connection, error) {
+	connMu.Lock()
+	defer connMu.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+	opts, err := gcpauth.ClientOptions(ctx, gcpauth.ContainerAnalysis)
+	if err != nil {
+		return nil, err
+	}
+	client, err := ca.NewGrafeasV1Beta1Client(ctx, append(opts, connOptions...)...)
+	if err != nil {
+		return nil, err
+	}
+	clientV1, err := cav1.NewService(ctx, opts...)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	conn = &connection{client: client, clientV1: clientV1}
+	return conn, nil
+}
+
+// configureConnection sets the options of the connection opened from now on. The
+// current connection is closed after connCloseDelay, leaving the clients using it
+// time to finish their reviews.
+func configureConnection(spec kritisv1beta1.GRPCConnectionSpec) error {
+	opts, err := connectionOptions(spec)
+	if err != nil {
+		return err
+	}
+	connMu.Lock()
+	defer connMu.Unlock()
+	connOptions = opts
+	if old := conn; old != nil {
+		time.AfterFunc(connCloseDelay, func() { old.client.Close() })
+	}
+	conn = nil
+	return nil
+}
+
+func mustConnectionOptions(spec kritisv1beta1.GRPCConnectionSpec) []option.ClientOption {
+	opts, err := connectionOptions(spec)
+	if err != nil {
+		panic(err)
+	}
+	return opts
+}
+
+func connectionOptions(spec kritisv1beta1.GRPCConnectionSpec) ([]option.ClientOption, error) {
+	if spec.PoolSize < 0 || spec.MaxRecvMsgSize < 0 || spec.MaxSendMsgSize < 0 {
+		return nil, fmt.Errorf("invalid containerAnalysis.connection %+v, expected positive values", spec)
+	}
+	params := keepalive.ClientParameters{
+		Time:    defaultKeepaliveTime,
+		Timeout: defaultKeepaliveTimeout,
+		// Ping idle connections too, so that they are not reset by load balancers.
+		PermitWithoutStream: true,
+	}
+	var err error
+	if spec.KeepaliveTime != "" {
+		if params.Time, err = time.ParseDuration(spec.KeepaliveTime); err != nil {
+			return nil, errors.Wrap(err, "invalid keepaliveTime")
+		}
+	}
+	if spec.KeepaliveTimeout != "" {
+		if params.Timeout, err = time.ParseDuration(spec.KeepaliveTimeout); err != nil {
+			return nil, errors.Wrap(err, "invalid keepaliveTimeout")
+		}
+	}
+	opts := []option.ClientOption{
+		option.WithGRPCDialOption(grpc.WithKeepaliveParams(params)),
+	}
+	if spec.PoolSize > 0 {
+		opts = append(opts, option.WithGRPCConnectionPool(spec.PoolSize))
+	}
+	var callOpts []grpc.CallOption
+	if spec.MaxRecvMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(spec.MaxRecvMsgSize))
+	}
+	if spec.MaxSendMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(spec.MaxSendMsgSize))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, option.WithGRPCDialOption(grpc.WithDefaultCallOptions(callOpts...)))
+	}
+	return opts, nil
+}
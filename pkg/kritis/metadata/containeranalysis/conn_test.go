@@ -0,0 +1,130 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containeranalysis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ca "cloud.google.com/go/containeranalysis/apiv1beta1"
+	"google.golang.org/api/option"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func TestConnectionOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      kritisv1beta1.GRPCConnectionSpec
+		options   int
+		shouldErr bool
+	}{
+		{"defaults", kritisv1beta1.GRPCConnectionSpec{}, 1, false},
+		{"pool and message sizes", kritisv1beta1.GRPCConnectionSpec{PoolSize: 4, MaxRecvMsgSize: 64 << 20, MaxSendMsgSize: 1 << 20}, 3, false},
+		{"keepalive", kritisv1beta1.GRPCConnectionSpec{KeepaliveTime: "1m", KeepaliveTimeout: "10s"}, 1, false},
+		{"invalid keepalive", kritisv1beta1.GRPCConnectionSpec{KeepaliveTime: "often"}, 0, true},
+		{"negative pool", kritisv1beta1.GRPCConnectionSpec{PoolSize: -1}, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts, err := connectionOptions(test.spec)
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.options, len(opts))
+		})
+	}
+}
+
+func TestSharedConnection(t *testing.T) {
+	s := testutil.NewFakeGrafeasServer()
+	defer s.Stop()
+	ctx := context.Background()
+	grpcConn, err := s.Conn(ctx)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	client, err := ca.NewGrafeasV1Beta1Client(ctx, option.WithGRPCConn(grpcConn))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	connMu.Lock()
+	conn = &connection{client: client}
+	connMu.Unlock()
+	defer Configure(kritisv1beta1.ContainerAnalysisConfigSpec{})
+
+	for i := 0; i < 2; i++ {
+		c, err := New()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if c.client != client {
+			t.Fatal("expected clients to share the connection")
+		}
+		// Closing a client keeps the shared connection open.
+		c.Close()
+		if err := c.Ping(ctx, "image"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := Configure(kritisv1beta1.ContainerAnalysisConfigSpec{Connection: kritisv1beta1.GRPCConnectionSpec{PoolSize: 2}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	connMu.Lock()
+	if conn != nil {
+		t.Error("expected Configure to drop the shared connection")
+	}
+	testutil.DeepEqual(t, 2, len(connOptions))
+	connMu.Unlock()
+}
+
+func TestConfigureClosesConnection(t *testing.T) {
+	s := testutil.NewFakeGrafeasServer()
+	defer s.Stop()
+	ctx := context.Background()
+	grpcConn, err := s.Conn(ctx)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	client, err := ca.NewGrafeasV1Beta1Client(ctx, option.WithGRPCConn(grpcConn))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	connMu.Lock()
+	conn = &connection{client: client}
+	connMu.Unlock()
+	original := connCloseDelay
+	connCloseDelay = 0
+	defer func() { connCloseDelay = original }()
+	defer Configure(kritisv1beta1.ContainerAnalysisConfigSpec{})
+
+	c, err := New()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := Configure(kritisv1beta1.ContainerAnalysisConfigSpec{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The previous connection is closed once its delay passed.
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Ping(ctx, "image") == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("expected Configure to close the previous connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
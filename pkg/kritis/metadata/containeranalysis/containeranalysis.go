@@ -19,14 +19,15 @@ package containeranalysis
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	ca "cloud.google.com/go/containeranalysis/apiv1beta1"
 	"github.com/golang/glog"
-	"github.com/google/go-containerregistry/pkg/name"
 	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
 	"github.com/grafeas/kritis/pkg/kritis/constants"
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
 	"github.com/grafeas/kritis/pkg/kritis/secrets"
 	"github.com/grafeas/kritis/pkg/kritis/util"
 	cav1 "google.golang.org/api/containeranalysis/v1"
@@ -39,6 +40,9 @@ import (
 const (
 	PkgVulnerability     = "PACKAGE_VULNERABILITY"
 	AttestationAuthority = "ATTESTATION_AUTHORITY"
+	Discovery            = "DISCOVERY"
+
+	defaultMaxOccurrences = 1000
 )
 
 // Client struct implements Fetcher Interface.
@@ -46,33 +50,35 @@ type Client struct {
 	client   *ca.GrafeasV1Beta1Client
 	clientV1 *cav1.Service
 	ctx      context.Context
+	// shared is set for clients using the shared connection, which stays open
+	shared bool
 }
 
+// New returns a Client using the connection shared by all clients, see containerAnalysis.connection.
 func New() (*Client, error) {
 	ctx := context.Background()
-	client, err := ca.NewGrafeasV1Beta1Client(ctx)
-	if err != nil {
-		return nil, err
-	}
-	clientV1, err := cav1.NewService(ctx)
+	conn, err := sharedConnection(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return &Client{
-		client:   client,
-		clientV1: clientV1,
+		client:   conn.client,
+		clientV1: conn.clientV1,
 		ctx:      ctx,
+		shared:   true,
 	}, nil
 }
 
-// Close closes connection
+// Close closes connection, unless it is shared
 func (c Client) Close() {
-	c.client.Close()
+	if !c.shared {
+		c.client.Close()
+	}
 }
 
 // Vulnerabilities gets Package Vulnerabilities Occurrences for a specified image.
-func (c Client) Vulnerabilities(containerImage string) ([]metadata.Vulnerability, error) {
-	occs, err := c.fetchOccurrence(containerImage, PkgVulnerability)
+func (c Client) Vulnerabilities(ctx context.Context, containerImage string) ([]metadata.Vulnerability, error) {
+	occs, err := c.fetchOccurrence(ctx, containerImage, PkgVulnerability)
 	if err != nil {
 		return nil, err
 	}
@@ -85,9 +91,18 @@ func (c Client) Vulnerabilities(containerImage string) ([]metadata.Vulnerability
 	return vulnz, nil
 }
 
+// ScanStatus gets the status of the vulnerability scan of an image from its Discovery Occurrences.
+func (c Client) ScanStatus(ctx context.Context, containerImage string) (metadata.ScanStatus, error) {
+	occs, err := c.fetchOccurrence(ctx, containerImage, Discovery)
+	if err != nil {
+		return metadata.ScanStatusUnknown, err
+	}
+	return util.GetScanStatusFromOccurrences(occs), nil
+}
+
 // Attestations gets AttesationAuthority Occurrences for a specified image.
-func (c Client) Attestations(containerImage string) ([]metadata.PGPAttestation, error) {
-	occs, err := c.fetchOccurrence(containerImage, AttestationAuthority)
+func (c Client) Attestations(ctx context.Context, containerImage string) ([]metadata.PGPAttestation, error) {
+	occs, err := c.fetchOccurrence(ctx, containerImage, AttestationAuthority)
 	if err != nil {
 		return nil, err
 	}
@@ -99,50 +114,91 @@ func (c Client) Attestations(containerImage string) ([]metadata.PGPAttestation,
 }
 
 // OccurencesV1 gets V1 Occurrences for a specified image.
-func (c Client) OccurencesV1(containerImage string) ([]*metadata.OccurenceV1, error) {
-	resp, err := c.clientV1.Projects.Occurrences.
-		List(fmt.Sprintf("projects/%s", getProjectFromContainerImage(containerImage))).
-		Filter(fmt.Sprintf("resource_url=%q", util.GetResourceURL(containerImage))).
-		PageSize(int64(constants.PageSize)).Do()
-
-	if err != nil {
+func (c Client) OccurencesV1(ctx context.Context, containerImage string) ([]*metadata.OccurenceV1, error) {
+	max := currentMaxOccurrences()
+	var occs []*metadata.OccurenceV1
+	skipped, err := currentRetrier().do(ctx, "OccurencesV1", skippableRead, func() error {
+		occs = nil
+		return c.clientV1.Projects.Occurrences.
+			List(fmt.Sprintf("projects/%s", getProjectFromContainerImage(containerImage))).
+			Filter(fmt.Sprintf("resource_url=%q", util.GetResourceURL(containerImage))).
+			PageSize(int64(constants.PageSize)).
+			Pages(ctx, func(resp *cav1.ListOccurrencesResponse) error {
+				occs = append(occs, resp.Occurrences...)
+				return checkTruncated(containerImage, len(occs), max)
+			})
+	})
+	if skipped || err != nil {
 		return nil, err
 	}
-	return resp.Occurrences, nil
+	return occs, nil
 }
 
-func (c Client) fetchOccurrence(containerImage string, kind string) ([]*grafeas.Occurrence, error) {
-	// Make sure container image valid and is a GCR image
-	if !isValidImageOnGCR(containerImage) {
-		return nil, fmt.Errorf("%q is not a valid image hosted in GCR", containerImage)
+// checkTruncated returns an error if more than max occurrences were listed, rather than
+// reviewing the image against part of its metadata.
+func checkTruncated(containerImage string, listed int, max int) error {
+	if listed > max {
+		return fmt.Errorf("%s has more than %d occurrences, raise containerAnalysis.maxOccurrences to review it", containerImage, max)
 	}
+	return nil
+}
+
+func (c Client) fetchOccurrence(ctx context.Context, containerImage string, kind string) ([]*grafeas.Occurrence, error) {
+	// Make sure container image is valid and its project known
+	if !isKnownImage(containerImage) {
+		return nil, fmt.Errorf("%q is not a valid image hosted in GCR or Artifact Registry, nor mapped to a project in containerAnalysis.projects", containerImage)
+	}
+	return c.listOccurrences(ctx, getProjectFromContainerImage(containerImage), containerImage, kind)
+}
+
+// listOccurrences lists the occurrences of kind for an image in project.
+func (c Client) listOccurrences(ctx context.Context, project string, containerImage string, kind string) ([]*grafeas.Occurrence, error) {
 	req := &grafeas.ListOccurrencesRequest{
 		Filter:   fmt.Sprintf("resource_url=%q AND kind=%q", util.GetResourceURL(containerImage), kind),
 		PageSize: constants.PageSize,
-		Parent:   fmt.Sprintf("projects/%s", getProjectFromContainerImage(containerImage)),
-	}
-	it := c.client.ListOccurrences(c.ctx, req)
-	occs := []*grafeas.Occurrence{}
-	for {
-		occ, err := it.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return nil, err
+		Parent:   fmt.Sprintf("projects/%s", project),
+	}
+	var occs []*grafeas.Occurrence
+	// Vulnerabilities and scan statuses can't be skipped, their absence would admit
+	// images regardless of the metadataFailurePolicy.
+	call := read
+	if kind == AttestationAuthority || kind == constants.BuildMetadataKind {
+		call = skippableRead
+	}
+	skipped, err := currentRetrier().do(ctx, "ListOccurrences", call, func() error {
+		// Pages are listed again on retries, so the whole listing is consistent.
+		occs = []*grafeas.Occurrence{}
+		it := c.client.ListOccurrences(ctx, req)
+		for {
+			occ, err := it.Next()
+			if err == iterator.Done {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			occs = append(occs, occ)
 		}
-		occs = append(occs, occ)
+	})
+	if skipped || err != nil {
+		return nil, err
 	}
 	return occs, nil
 }
 
-func isValidImageOnGCR(containerImage string) bool {
-	ref, err := name.ParseReference(containerImage, name.WeakValidation)
+func isValidImageOnGoogle(containerImage string) bool {
+	ref, err := reference.ParseReference(containerImage)
 	if err != nil {
 		glog.Warning(err)
 		return false
 	}
-	return isRegistryGCR(ref.Context().RegistryStr())
+	registry := ref.Context().RegistryStr()
+	return isRegistryGCR(registry) || reference.IsArtifactRegistry(registry)
+}
+
+// isKnownImage returns whether the project holding the metadata of containerImage is known.
+func isKnownImage(containerImage string) bool {
+	return isValidImageOnGoogle(containerImage) || getProjectFromContainerImage(containerImage) != ""
 }
 
 func isRegistryGCR(r string) bool {
@@ -165,7 +221,7 @@ func getProjectFromNoteReference(ref string) (string, error) {
 }
 
 // CreateAttestationNote creates an attestation note from AttestationAuthority
-func (c Client) CreateAttestationNote(aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+func (c Client) CreateAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
 	noteProject, err := getProjectFromNoteReference(aa.Spec.NoteReference)
 	if err != nil {
 		return nil, err
@@ -189,11 +245,11 @@ func (c Client) CreateAttestationNote(aa *kritisv1beta1.AttestationAuthority) (*
 		NoteId: aa.Name,
 		Parent: fmt.Sprintf("projects/%s", noteProject),
 	}
-	return c.client.CreateNote(c.ctx, req)
+	return c.client.CreateNote(ctx, req)
 }
 
 // AttestationNote returns a note if it exists for given AttestationAuthority
-func (c Client) AttestationNote(aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+func (c Client) AttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
 	noteProject, err := getProjectFromNoteReference(aa.Spec.NoteReference)
 	if err != nil {
 		return nil, err
@@ -201,37 +257,24 @@ func (c Client) AttestationNote(aa *kritisv1beta1.AttestationAuthority) (*grafea
 	req := &grafeas.GetNoteRequest{
 		Name: fmt.Sprintf("projects/%s/notes/%s", noteProject, aa.Name),
 	}
-	return c.client.GetNote(c.ctx, req)
+	return c.client.GetNote(ctx, req)
 }
 
 // CreateAttestationOccurence creates an Attestation occurrence for a given image and secret.
-func (c Client) CreateAttestationOccurence(note *grafeas.Note,
+func (c Client) CreateAttestationOccurence(ctx context.Context, note *grafeas.Note,
 	containerImage string,
 	pgpSigningKey *secrets.PGPSigningSecret) (*grafeas.Occurrence, error) {
-	if !isValidImageOnGCR(containerImage) {
-		return nil, fmt.Errorf("%q is not a valid image hosted in GCR", containerImage)
+	if !isKnownImage(containerImage) {
+		return nil, fmt.Errorf("%q is not a valid image hosted in GCR or Artifact Registry, nor mapped to a project in containerAnalysis.projects", containerImage)
 	}
-	fingerprint := util.GetAttestationKeyFingerprint(pgpSigningKey)
-
 	// Create Attestation Signature
-	sig, err := util.CreateAttestationSignature(containerImage, pgpSigningKey)
+	a, err := util.CreateAttestation(ctx, containerImage, pgpSigningKey)
 	if err != nil {
 		return nil, err
 	}
-	pgpSignedAttestation := &attestation.PgpSignedAttestation{
-		Signature: sig,
-		KeyId: &attestation.PgpSignedAttestation_PgpKeyId{
-			PgpKeyId: fingerprint,
-		},
-		ContentType: attestation.PgpSignedAttestation_SIMPLE_SIGNING_JSON,
-	}
-
 	attestationDetails := &grafeas.Occurrence_Attestation{
 		Attestation: &attestation.Details{
-			Attestation: &attestation.Attestation{
-				Signature: &attestation.Attestation_PgpSignedAttestation{
-					PgpSignedAttestation: pgpSignedAttestation,
-				}},
+			Attestation: a,
 		},
 	}
 	occ := &grafeas.Occurrence{
@@ -245,21 +288,92 @@ func (c Client) CreateAttestationOccurence(note *grafeas.Note,
 		Parent:     fmt.Sprintf("projects/%s", getProjectFromContainerImage(containerImage)),
 	}
 	// Call create Occurrence Api
-	return c.client.CreateOccurrence(c.ctx, req)
+	return c.createOccurrence(ctx, req)
+}
+
+// DiscoveryNote returns the Discovery note in the project hosting the image.
+func (c Client) DiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	req := &grafeas.GetNoteRequest{
+		Name: fmt.Sprintf("projects/%s/notes/%s", getProjectFromContainerImage(containerImage), noteID),
+	}
+	return c.client.GetNote(ctx, req)
+}
+
+// CreateDiscoveryNote creates a Discovery note in the project hosting the image.
+func (c Client) CreateDiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	project := getProjectFromContainerImage(containerImage)
+	req := &grafeas.CreateNoteRequest{
+		Note:   util.NewDiscoveryNote(project, noteID),
+		NoteId: noteID,
+		Parent: fmt.Sprintf("projects/%s", project),
+	}
+	return c.client.CreateNote(ctx, req)
+}
+
+// CreateDiscoveryOccurrence creates a Discovery occurrence with message for a given image.
+func (c Client) CreateDiscoveryOccurrence(ctx context.Context, note *grafeas.Note, containerImage string, message string) (*grafeas.Occurrence, error) {
+	if !isKnownImage(containerImage) {
+		return nil, fmt.Errorf("%q is not a valid image hosted in GCR or Artifact Registry, nor mapped to a project in containerAnalysis.projects", containerImage)
+	}
+	req := &grafeas.CreateOccurrenceRequest{
+		Occurrence: util.NewDiscoveryOccurrence(note, containerImage, message),
+		Parent:     fmt.Sprintf("projects/%s", getProjectFromContainerImage(containerImage)),
+	}
+	return c.createOccurrence(ctx, req)
 }
 
+func (c Client) createOccurrence(ctx context.Context, req *grafeas.CreateOccurrenceRequest) (occ *grafeas.Occurrence, err error) {
+	_, err = currentRetrier().do(ctx, "CreateOccurrence", write, func() (err error) {
+		occ, err = c.client.CreateOccurrence(ctx, req)
+		return err
+	})
+	return occ, err
+}
+
+// getProjectFromContainerImage returns the project holding the metadata of image: the
+// project of the longest prefix mapped in containerAnalysis.projects matching it, else
+// the project in the path of GCR and Artifact Registry images, else the default project.
+// It is empty if none applies.
 func getProjectFromContainerImage(image string) string {
-	tok := strings.Split(image, "/")
-	if len(tok) < 2 {
+	ref, err := reference.Parse(image)
+	if err != nil {
 		return ""
 	}
-	return tok[1]
+	configMu.RLock()
+	defer configMu.RUnlock()
+	name := ref.Name()
+	for _, m := range projects {
+		if name == m.Prefix || strings.HasPrefix(name, m.Prefix+"/") {
+			return m.Project
+		}
+	}
+	if p := ref.Project(); p != "" {
+		return p
+	}
+	return defaultProject
+}
+
+// projectMappings validates mappings and returns them sorted by decreasing prefix
+// length, so that the first matching prefix is the longest.
+func projectMappings(mappings []kritisv1beta1.ProjectMappingSpec) ([]kritisv1beta1.ProjectMappingSpec, error) {
+	sorted := make([]kritisv1beta1.ProjectMappingSpec, 0, len(mappings))
+	for _, m := range mappings {
+		if m.Prefix == "" || m.Project == "" {
+			return nil, fmt.Errorf("invalid containerAnalysis.projects entry %+v, prefix and project are required", m)
+		}
+		m.Prefix = strings.TrimSuffix(m.Prefix, "/")
+		sorted = append(sorted, m)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].Prefix) > len(sorted[j].Prefix)
+	})
+	return sorted, nil
 }
 
 // Builds gets Build Occurrences for a specified image.
-func (c Client) Builds(containerImage string) ([]metadata.Build, error) {
+func (c Client) Builds(ctx context.Context, containerImage string) ([]metadata.Build, error) {
 	glog.Infof("getttig build occurrences for %q", containerImage)
-	occs, err := c.fetchOccurrence(containerImage, "BUILD")
+	occs, err := c.fetchOccurrence(ctx, containerImage, "BUILD")
 	if err != nil {
 		glog.Warning(err)
 		return nil, err
@@ -277,7 +391,7 @@ func (c Client) Builds(containerImage string) ([]metadata.Build, error) {
 // The following methods are used for Testing
 
 // DeleteAttestationNote deletes a note for given AttestationAuthority
-func (c Client) DeleteAttestationNote(aa *kritisv1beta1.AttestationAuthority) error {
+func (c Client) DeleteAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) error {
 	noteProject, err := getProjectFromNoteReference(aa.Spec.NoteReference)
 	if err != nil {
 		return err
@@ -285,13 +399,25 @@ func (c Client) DeleteAttestationNote(aa *kritisv1beta1.AttestationAuthority) er
 	req := &grafeas.DeleteNoteRequest{
 		Name: fmt.Sprintf("projects/%s/notes/%s", noteProject, aa.Name),
 	}
-	return c.client.DeleteNote(c.ctx, req)
+	return c.client.DeleteNote(ctx, req)
 }
 
 // DeleteOccurrence deletes an occurrence with given ID
-func (c Client) DeleteOccurrence(ID string) error {
+func (c Client) DeleteOccurrence(ctx context.Context, ID string) error {
 	req := &grafeas.DeleteOccurrenceRequest{
 		Name: ID,
 	}
-	return c.client.DeleteOccurrence(c.ctx, req)
+	return c.client.DeleteOccurrence(ctx, req)
+}
+
+// Ping lists a note of project, to check that Container Analysis can be reached.
+func (c Client) Ping(ctx context.Context, project string) error {
+	req := &grafeas.ListNotesRequest{
+		Parent:   fmt.Sprintf("projects/%s", project),
+		PageSize: 1,
+	}
+	if _, err := c.client.ListNotes(ctx, req).Next(); err != nil && err != iterator.Done {
+		return fmt.Errorf("failed to list the notes of project %s: %v", project, err)
+	}
+	return nil
 }
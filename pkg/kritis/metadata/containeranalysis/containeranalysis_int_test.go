@@ -1,3 +1,4 @@
+//go:build integration
 // +build integration
 
 /*
@@ -19,6 +20,7 @@ limitations under the License.
 package containeranalysis
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -39,7 +41,7 @@ func TestGetVulnerabilities(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Could not initialize the client %s", err)
 	}
-	vuln, err := d.Vulnerabilities("gcr.io/kritis-int-test/java-with-vulnz@sha256:358687cfd3ec8e1dfeb2bf51b5110e4e16f6df71f64fba01986f720b2fcba68a")
+	vuln, err := d.Vulnerabilities(context.Background(), "gcr.io/kritis-int-test/java-with-vulnz@sha256:358687cfd3ec8e1dfeb2bf51b5110e4e16f6df71f64fba01986f720b2fcba68a")
 	if err != nil {
 		t.Fatalf("Found err %s", err)
 	}
@@ -61,12 +63,12 @@ func TestCreateAttestationNoteAndOccurrence(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Could not initialize the client %s", err)
 	}
-	_, err = d.CreateAttestationNote(aa)
+	_, err = d.CreateAttestationNote(context.Background(), aa)
 	if err != nil {
 		t.Fatalf("Unexpected error while creating Note %v", err)
 	}
-	defer d.DeleteAttestationNote(aa)
-	note, err := d.AttestationNote(aa)
+	defer d.DeleteAttestationNote(context.Background(), aa)
+	note, err := d.AttestationNote(context.Background(), aa)
 	expectedNoteName := fmt.Sprintf("projects/%s/notes/%s", IntProject, IntTestNoteName)
 	if note.Name != expectedNoteName {
 		t.Fatalf("Expected %s.\n Got %s", expectedNoteName, note.Name)
@@ -86,7 +88,7 @@ func TestCreateAttestationNoteAndOccurrence(t *testing.T) {
 		SecretName: "test",
 	}
 
-	occ, err := d.CreateAttestationOccurence(note, testutil.IntTestImage, secret)
+	occ, err := d.CreateAttestationOccurence(context.Background(), note, testutil.IntTestImage, secret)
 	if err != nil {
 		t.Fatalf("Unexpected error while creating Occurence %v", err)
 	}
@@ -95,8 +97,8 @@ func TestCreateAttestationNoteAndOccurrence(t *testing.T) {
 	if pgpKeyID != expectedPgpKeyID {
 		t.Errorf("Expected PGP key id: %q, got %q", expectedPgpKeyID, pgpKeyID)
 	}
-	defer d.DeleteOccurrence(occ.GetName())
-	occurrences, err := d.Attestations(testutil.IntTestImage)
+	defer d.DeleteOccurrence(context.Background(), occ.GetName())
+	occurrences, err := d.Attestations(context.Background(), testutil.IntTestImage)
 	if err != nil {
 		t.Fatalf("Unexpected error while listing Occ %v", err)
 	}
@@ -17,9 +17,27 @@ limitations under the License.
 package containeranalysis
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
+	ca "cloud.google.com/go/containeranalysis/apiv1beta1"
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
 	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	"github.com/grafeas/kritis/pkg/kritis/util"
+	cav1 "google.golang.org/api/containeranalysis/v1"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/vulnerability"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func Test_isRegistryGCR(t *testing.T) {
@@ -57,6 +75,26 @@ func Test_isRegistryGCR(t *testing.T) {
 	}
 }
 
+func Test_isValidImageOnGoogle(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected bool
+	}{
+		{"gcr.io/project/image", true},
+		{"eu.gcr.io/project/image:v1", true},
+		{"us-docker.pkg.dev/project/repo/image", true},
+		{"europe-west1-docker.pkg.dev/project/repo/image:v1", true},
+		{"docker.pkg.dev/project/repo/image", false},
+		{"index.docker.io/library/nginx", false},
+		{"not a valid image", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.image, func(t *testing.T) {
+			testutil.DeepEqual(t, tc.expected, isValidImageOnGoogle(tc.image))
+		})
+	}
+}
+
 func Test_getProjectFromContainerImage(t *testing.T) {
 	tests := []struct {
 		image   string
@@ -65,14 +103,58 @@ func Test_getProjectFromContainerImage(t *testing.T) {
 		{"gcr.io/project/1", "project"},
 		{"gcr.io/project", "project"},
 		{"gcr.io", ""},
+		{"us-docker.pkg.dev/project/repo/image", "project"},
+		{"europe-west1-docker.pkg.dev/project/repo/image@sha256:0000000000000000000000000000000000000000000000000000000000000000", "project"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.image, func(t *testing.T) {
+			testutil.DeepEqual(t, tc.project, getProjectFromContainerImage(tc.image))
+		})
+	}
+}
+
+func TestProjectMappings(t *testing.T) {
+	if err := Configure(kritisv1beta1.ContainerAnalysisConfigSpec{
+		Projects: []kritisv1beta1.ProjectMappingSpec{
+			{Prefix: "mirror.example.com", Project: "mirrors"},
+			{Prefix: "mirror.example.com/gcr/", Project: "gcr-mirror"},
+			{Prefix: "us-docker.pkg.dev/shared/remote", Project: "scanning"},
+		},
+		DefaultProject: "fallback",
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer Configure(kritisv1beta1.ContainerAnalysisConfigSpec{})
+	tests := []struct {
+		image   string
+		project string
+	}{
+		{"mirror.example.com/gcr/image@sha256:0000000000000000000000000000000000000000000000000000000000000000", "gcr-mirror"},
+		{"mirror.example.com/gcr", "gcr-mirror"},
+		{"mirror.example.com/gcrio/image", "mirrors"},
+		{"mirror.example.com/image:v1", "mirrors"},
+		{"us-docker.pkg.dev/shared/remote/library/nginx", "scanning"},
+		{"us-docker.pkg.dev/shared/repo/image", "shared"},
+		{"gcr.io/project/image", "project"},
+		{"quay.io/org/image", "fallback"},
+		{"not a valid image", ""},
 	}
 	for _, tc := range tests {
 		t.Run(tc.image, func(t *testing.T) {
 			testutil.DeepEqual(t, tc.project, getProjectFromContainerImage(tc.image))
+			testutil.DeepEqual(t, tc.project != "", isKnownImage(tc.image))
 		})
 	}
 }
 
+func TestConfigureInvalidProjects(t *testing.T) {
+	defer Configure(kritisv1beta1.ContainerAnalysisConfigSpec{})
+	err := Configure(kritisv1beta1.ContainerAnalysisConfigSpec{
+		Projects: []kritisv1beta1.ProjectMappingSpec{{Prefix: "mirror.example.com"}},
+	})
+	testutil.CheckError(t, true, err)
+}
+
 func TestGetProjectFromNoteRef(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -91,3 +173,220 @@ func TestGetProjectFromNoteRef(t *testing.T) {
 		})
 	}
 }
+
+func TestOccurencesV1Pages(t *testing.T) {
+	// The server lists 3 pages of 2 occurrences.
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+		resp := cav1.ListOccurrencesResponse{
+			Occurrences: []*cav1.Occurrence{{Name: fmt.Sprintf("occ-%d", 2*page)}, {Name: fmt.Sprintf("occ-%d", 2*page+1)}},
+		}
+		if page < 2 {
+			resp.NextPageToken = strconv.Itoa(page + 1)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer s.Close()
+	clientV1, err := cav1.NewService(context.Background(), option.WithEndpoint(s.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	c := Client{clientV1: clientV1}
+	defer Configure(kritisv1beta1.ContainerAnalysisConfigSpec{})
+
+	tests := []struct {
+		name      string
+		max       int
+		expected  int
+		shouldErr bool
+	}{
+		{"all pages", 0, 6, false},
+		{"max", 6, 6, false},
+		{"truncated", 5, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := Configure(kritisv1beta1.ContainerAnalysisConfigSpec{MaxOccurrences: test.max}); err != nil {
+				t.Fatalf("%v", err)
+			}
+			occs, err := c.OccurencesV1(context.Background(), "gcr.io/project/image")
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, len(occs))
+		})
+	}
+}
+
+func newFakeGrafeasClient(t *testing.T, s *testutil.FakeGrafeasServer) Client {
+	ctx := context.Background()
+	conn, err := s.Conn(ctx)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	client, err := ca.NewGrafeasV1Beta1Client(ctx, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	return Client{client: client, ctx: ctx}
+}
+
+func TestFakeGrafeasListing(t *testing.T) {
+	s := testutil.NewFakeGrafeasServer()
+	defer s.Stop()
+	s.MaxPageSize = 2
+	c := newFakeGrafeasClient(t, s)
+	defer c.Close()
+
+	vuln := func(image string, cve string) *grafeas.Occurrence {
+		return &grafeas.Occurrence{
+			Resource: util.GetResource(image),
+			NoteName: cve,
+			Details: &grafeas.Occurrence_Vulnerability{
+				Vulnerability: &vulnerability.Details{Severity: vulnerability.Severity_HIGH},
+			},
+		}
+	}
+	other := "gcr.io/image/other@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	occs := []*grafeas.Occurrence{
+		vuln(testutil.QualifiedImage, "projects/goog-vulnz/notes/CVE-1"),
+		vuln(other, "projects/goog-vulnz/notes/CVE-2"),
+		vuln(testutil.QualifiedImage, "projects/goog-vulnz/notes/CVE-3"),
+		{Resource: util.GetResource(testutil.QualifiedImage), Details: &grafeas.Occurrence_Attestation{}},
+		vuln(testutil.QualifiedImage, "projects/goog-vulnz/notes/CVE-4"),
+		vuln(testutil.QualifiedImage, "projects/goog-vulnz/notes/CVE-5"),
+		vuln(testutil.QualifiedImage, "projects/goog-vulnz/notes/CVE-6"),
+	}
+	if _, err := c.client.BatchCreateOccurrences(context.Background(), &grafeas.BatchCreateOccurrencesRequest{
+		Parent:      "projects/image",
+		Occurrences: occs,
+	}); err != nil {
+		t.Fatalf("%v", err)
+	}
+	// Occurrences of other projects are not listed.
+	if _, err := c.client.CreateOccurrence(context.Background(), &grafeas.CreateOccurrenceRequest{
+		Parent:     "projects/elsewhere",
+		Occurrence: vuln(testutil.QualifiedImage, "projects/goog-vulnz/notes/CVE-7"),
+	}); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	vulnz, err := c.Vulnerabilities(context.Background(), testutil.QualifiedImage)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var cves []string
+	for _, v := range vulnz {
+		cves = append(cves, v.CVE)
+	}
+	testutil.DeepEqual(t, []string{
+		"projects/goog-vulnz/notes/CVE-1",
+		"projects/goog-vulnz/notes/CVE-3",
+		"projects/goog-vulnz/notes/CVE-4",
+		"projects/goog-vulnz/notes/CVE-5",
+		"projects/goog-vulnz/notes/CVE-6",
+	}, cves)
+	// 5 vulnerabilities are listed in pages of 2.
+	testutil.DeepEqual(t, 3, s.ListCalls())
+
+	atts, err := c.Attestations(context.Background(), testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 1, len(atts))
+}
+
+func TestFakeGrafeasAttestations(t *testing.T) {
+	s := testutil.NewFakeGrafeasServer()
+	defer s.Stop()
+	c := newFakeGrafeasClient(t, s)
+	defer c.Close()
+	ctx := context.Background()
+
+	aa := &kritisv1beta1.AttestationAuthority{
+		ObjectMeta: metav1.ObjectMeta{Name: "qa", Namespace: "default"},
+		Spec:       kritisv1beta1.AttestationAuthoritySpec{NoteReference: "v1beta1/projects/image"},
+	}
+	if _, err := c.AttestationNote(ctx, aa); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected note to be missing, got %v", err)
+	}
+	note, err := c.CreateAttestationNote(ctx, aa)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := c.CreateAttestationNote(ctx, aa); status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("expected note to exist, got %v", err)
+	}
+	got, err := c.AttestationNote(ctx, aa)
+	testutil.CheckErrorAndDeepEqual(t, false, err, "projects/image/notes/qa", got.GetName())
+
+	pub, priv := testutil.CreateKeyPair(t, "test")
+	pgpKey, err := secrets.NewPgpKey(priv, "", pub)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	occ, err := c.CreateAttestationOccurence(ctx, note, testutil.QualifiedImage, &secrets.PGPSigningSecret{PgpKey: pgpKey, SecretName: "test"})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	atts, err := c.Attestations(ctx, testutil.QualifiedImage)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(atts) != 1 || atts[0].KeyID != pgpKey.Fingerprint() || atts[0].OccID != occ.GetName() || atts[0].CreateTime == nil {
+		t.Fatalf("unexpected attestations %+v", atts)
+	}
+
+	if err := c.DeleteOccurrence(ctx, occ.GetName()); err != nil {
+		t.Fatalf("%v", err)
+	}
+	atts, err = c.Attestations(ctx, testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 0, len(atts))
+	if err := c.DeleteAttestationNote(ctx, aa); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, err := c.AttestationNote(ctx, aa); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected note to be deleted, got %v", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	s := testutil.NewFakeGrafeasServer()
+	c := newFakeGrafeasClient(t, s)
+	defer c.Close()
+	if err := c.Ping(context.Background(), "image"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	s.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c.Ping(ctx, "image"); err == nil {
+		t.Error("expected an error once the server is stopped")
+	}
+}
+
+func TestFakeGrafeasMappedProject(t *testing.T) {
+	if err := Configure(kritisv1beta1.ContainerAnalysisConfigSpec{
+		Projects: []kritisv1beta1.ProjectMappingSpec{{Prefix: "mirror.example.com/gcr", Project: "scanning"}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer Configure(kritisv1beta1.ContainerAnalysisConfigSpec{})
+	s := testutil.NewFakeGrafeasServer()
+	defer s.Stop()
+	c := newFakeGrafeasClient(t, s)
+	defer c.Close()
+
+	image := "mirror.example.com/gcr/image@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	if _, err := c.client.CreateOccurrence(context.Background(), &grafeas.CreateOccurrenceRequest{
+		Parent: "projects/scanning",
+		Occurrence: &grafeas.Occurrence{
+			Resource: util.GetResource(image),
+			NoteName: "projects/goog-vulnz/notes/CVE-1",
+			Details: &grafeas.Occurrence_Vulnerability{
+				Vulnerability: &vulnerability.Details{Severity: vulnerability.Severity_HIGH},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("%v", err)
+	}
+	vulnz, err := c.Vulnerabilities(context.Background(), image)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 1, len(vulnz))
+
+	_, err = c.Vulnerabilities(context.Background(), "quay.io/org/image@sha256:0000000000000000000000000000000000000000000000000000000000000000")
+	testutil.CheckError(t, true, err)
+}
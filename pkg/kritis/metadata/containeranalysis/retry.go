@@ -0,0 +1,282 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containeranalysis
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metrics"
+)
+
+// Fallbacks of the calls made while the circuit breaker is open
+const (
+	// BreakerFail fails the call
+	BreakerFail = "fail"
+	// BreakerSkip returns no metadata from reads, writes still fail
+	BreakerSkip = "skip"
+)
+
+// callKind tells the retrier how a call may be retried and skipped.
+type callKind int
+
+const (
+	// write calls aren't idempotent, so they are made once.
+	write callKind = iota
+	// read calls are retried, and fail while the circuit breaker is open.
+	read
+	// skippableRead calls are retried, and return no metadata while the circuit
+	// breaker is open with the BreakerSkip fallback. Only reads whose missing
+	// metadata denies images, e.g. attestations, are skippable.
+	skippableRead
+)
+
+// errBreakerOpen is returned by calls made while the circuit breaker is open.
+var errBreakerOpen = errors.New("circuit breaker is open after repeated Container Analysis failures")
+
+// For testing
+var (
+	now    = time.Now
+	random = rand.Int63n
+	sleep  = sleepContext
+)
+
+var (
+	configMu       sync.RWMutex
+	retry          = mustRetrier(kritisv1beta1.RetrySpec{})
+	maxOccurrences = defaultMaxOccurrences
+	projects       []kritisv1beta1.ProjectMappingSpec
+	defaultProject string
+)
+
+// retrier retries the calls to Container Analysis failing with transient errors,
+// and stops making them for a while once they keep failing.
+type retrier struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	budget         time.Duration
+	threshold      int
+	cooldown       time.Duration
+	skip           bool
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// Configure sets how the calls made by all clients from now on are retried, how
+// many occurrences they list, the projects they look images up in and the options of
+// their connection. The state of the circuit breaker is reset.
+func Configure(spec kritisv1beta1.ContainerAnalysisConfigSpec) error {
+	r, err := newRetrier(spec.Retry)
+	if err != nil {
+		return err
+	}
+	if spec.MaxOccurrences < 0 {
+		return fmt.Errorf("invalid maxOccurrences %d, expected a positive value", spec.MaxOccurrences)
+	}
+	mappings, err := projectMappings(spec.Projects)
+	if err != nil {
+		return err
+	}
+	if err := configureConnection(spec.Connection); err != nil {
+		return err
+	}
+	configMu.Lock()
+	defer configMu.Unlock()
+	retry = r
+	maxOccurrences = defaultMaxOccurrences
+	if spec.MaxOccurrences != 0 {
+		maxOccurrences = spec.MaxOccurrences
+	}
+	projects = mappings
+	defaultProject = spec.DefaultProject
+	return nil
+}
+
+func currentRetrier() *retrier {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return retry
+}
+
+func currentMaxOccurrences() int {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return maxOccurrences
+}
+
+func mustRetrier(spec kritisv1beta1.RetrySpec) *retrier {
+	r, err := newRetrier(spec)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func newRetrier(spec kritisv1beta1.RetrySpec) (*retrier, error) {
+	r := &retrier{
+		maxAttempts: spec.MaxAttempts,
+		threshold:   spec.BreakerThreshold,
+	}
+	if r.maxAttempts == 0 {
+		r.maxAttempts = 3
+	}
+	if r.threshold == 0 {
+		r.threshold = 5
+	}
+	if r.maxAttempts < 0 || r.threshold < 0 {
+		return nil, fmt.Errorf("invalid retry maxAttempts %d or breakerThreshold %d, expected positive values", spec.MaxAttempts, spec.BreakerThreshold)
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		def   time.Duration
+		out   *time.Duration
+	}{
+		{"initialBackoff", spec.InitialBackoff, 200 * time.Millisecond, &r.initialBackoff},
+		{"maxBackoff", spec.MaxBackoff, 2 * time.Second, &r.maxBackoff},
+		{"budget", spec.Budget, 5 * time.Second, &r.budget},
+		{"breakerCooldown", spec.BreakerCooldown, 30 * time.Second, &r.cooldown},
+	} {
+		*d.out = d.def
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid retry %s", d.name)
+		}
+		*d.out = v
+	}
+	switch spec.BreakerFallback {
+	case "", BreakerFail:
+	case BreakerSkip:
+		r.skip = true
+	default:
+		return nil, fmt.Errorf("unsupported breakerFallback %q, expected %q or %q", spec.BreakerFallback, BreakerFail, BreakerSkip)
+	}
+	return r, nil
+}
+
+// do calls f until it succeeds, fails with an error which is not transient, or runs
+// out of attempts or budget. Writes are only called once. While the circuit breaker
+// is open f is not called: skippable reads are skipped with the BreakerSkip fallback,
+// other calls fail.
+func (r *retrier) do(ctx context.Context, method string, kind callKind, f func() error) (skipped bool, err error) {
+	if r.open() {
+		if kind == skippableRead && r.skip {
+			glog.Warningf("skipping %s: %v", method, errBreakerOpen)
+			return true, nil
+		}
+		return false, errors.Wrap(errBreakerOpen, method)
+	}
+	start := now()
+	backoff := r.initialBackoff
+	for attempt := 1; ; attempt++ {
+		if err = f(); err == nil || !transient(err) {
+			r.succeeded()
+			return false, err
+		}
+		if attempt >= r.maxAttempts || kind == write {
+			break
+		}
+		// Jitter the backoff between half and all of it, so clients do not retry in step.
+		d := backoff/2 + time.Duration(random(int64(backoff/2)+1))
+		if now().Sub(start)+d > r.budget {
+			break
+		}
+		glog.Warningf("retrying %s in %s: %v", method, d, err)
+		metrics.ContainerAnalysisRetries.Add(method, 1)
+		if sleep(ctx, d) != nil {
+			break
+		}
+		if backoff *= 2; backoff > r.maxBackoff {
+			backoff = r.maxBackoff
+		}
+	}
+	r.failed()
+	return false, err
+}
+
+func (r *retrier) open() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return now().Before(r.openUntil)
+}
+
+func (r *retrier) succeeded() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = 0
+}
+
+// failed counts a call which failed with a transient error and opens the circuit
+// breaker once threshold calls failed in a row. Once the cooldown is over calls are
+// made again; the breaker opens again on the first one failing.
+func (r *retrier) failed() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures++
+	if r.failures < r.threshold {
+		return
+	}
+	glog.Errorf("opening the Container Analysis circuit breaker for %s after %d failed calls", r.cooldown, r.failures)
+	metrics.ContainerAnalysisBreakerTrips.Add(1)
+	r.openUntil = now().Add(r.cooldown)
+}
+
+// transient returns true for errors of calls which may succeed when retried: the
+// API was unavailable or rate limited the call.
+func transient(err error) bool {
+	err = errors.Cause(err)
+	if e, ok := err.(*googleapi.Error); ok {
+		switch e.Code {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containeranalysis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+var errUnavailable = status.Error(codes.Unavailable, "unavailable")
+
+// fakeClock makes sleeps advance now instantly, and jitters backoffs to their maximum.
+func fakeClock(t *testing.T) *time.Time {
+	t.Helper()
+	originalNow, originalRandom, originalSleep := now, random, sleep
+	t.Cleanup(func() {
+		now, random, sleep = originalNow, originalRandom, originalSleep
+	})
+	clock := time.Unix(0, 0)
+	now = func() time.Time { return clock }
+	random = func(n int64) int64 { return n - 1 }
+	sleep = func(ctx context.Context, d time.Duration) error {
+		clock = clock.Add(d)
+		return nil
+	}
+	return &clock
+}
+
+func TestRetrierDo(t *testing.T) {
+	tests := []struct {
+		name          string
+		spec          kritisv1beta1.RetrySpec
+		errs          []error
+		expectedCalls int
+		expectedSleep time.Duration
+		shouldErr     bool
+	}{
+		{"success", kritisv1beta1.RetrySpec{}, nil, 1, 0, false},
+		{"transient error", kritisv1beta1.RetrySpec{}, []error{errUnavailable}, 2, 200 * time.Millisecond, false},
+		{"out of attempts", kritisv1beta1.RetrySpec{}, []error{errUnavailable, errUnavailable, errUnavailable}, 3, 600 * time.Millisecond, true},
+		{"max backoff", kritisv1beta1.RetrySpec{MaxAttempts: 4, MaxBackoff: "300ms"}, []error{errUnavailable, errUnavailable, errUnavailable}, 4, 800 * time.Millisecond, false},
+		{"out of budget", kritisv1beta1.RetrySpec{Budget: "500ms"}, []error{errUnavailable, errUnavailable}, 2, 200 * time.Millisecond, true},
+		{"retries disabled", kritisv1beta1.RetrySpec{MaxAttempts: 1}, []error{errUnavailable}, 1, 0, true},
+		{"not transient", kritisv1beta1.RetrySpec{}, []error{status.Error(codes.NotFound, "not found")}, 1, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := fakeClock(t)
+			r, err := newRetrier(test.spec)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			calls := 0
+			_, err = r.do(context.Background(), "ListOccurrences", skippableRead, func() error {
+				calls++
+				if calls <= len(test.errs) {
+					return test.errs[calls-1]
+				}
+				return nil
+			})
+			testutil.CheckError(t, test.shouldErr, err)
+			testutil.DeepEqual(t, test.expectedCalls, calls)
+			testutil.DeepEqual(t, test.expectedSleep, clock.Sub(time.Unix(0, 0)))
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	for _, fallback := range []string{BreakerFail, BreakerSkip} {
+		t.Run(fallback, func(t *testing.T) {
+			clock := fakeClock(t)
+			r, err := newRetrier(kritisv1beta1.RetrySpec{MaxAttempts: 1, BreakerThreshold: 2, BreakerCooldown: "30s", BreakerFallback: fallback})
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			calls := 0
+			fail := func() error {
+				calls++
+				return errUnavailable
+			}
+			for i := 0; i < 2; i++ {
+				if _, err := r.do(context.Background(), "ListOccurrences", skippableRead, fail); err != errUnavailable {
+					t.Fatalf("expected %v, got %v", errUnavailable, err)
+				}
+			}
+			// The breaker is open: skippable reads fail or are skipped, other calls fail.
+			skipped, err := r.do(context.Background(), "ListOccurrences", skippableRead, fail)
+			testutil.DeepEqual(t, fallback == BreakerSkip, skipped)
+			testutil.CheckError(t, fallback == BreakerFail, err)
+			skipped, err = r.do(context.Background(), "ListOccurrences", read, fail)
+			testutil.DeepEqual(t, false, skipped)
+			if errors.Cause(err) != errBreakerOpen {
+				t.Errorf("expected %v, got %v", errBreakerOpen, err)
+			}
+			_, err = r.do(context.Background(), "CreateOccurrence", write, fail)
+			if errors.Cause(err) != errBreakerOpen {
+				t.Errorf("expected %v, got %v", errBreakerOpen, err)
+			}
+			testutil.DeepEqual(t, 2, calls)
+			// Calls are made again after the cooldown, and close the breaker once they succeed.
+			*clock = clock.Add(30 * time.Second)
+			if _, err := r.do(context.Background(), "ListOccurrences", skippableRead, func() error { return nil }); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := r.do(context.Background(), "ListOccurrences", skippableRead, fail); err != errUnavailable {
+				t.Errorf("expected %v, got %v", errUnavailable, err)
+			}
+		})
+	}
+}
+
+func TestRetrierDoWrite(t *testing.T) {
+	fakeClock(t)
+	r, err := newRetrier(kritisv1beta1.RetrySpec{})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	calls := 0
+	_, err = r.do(context.Background(), "CreateOccurrence", write, func() error {
+		calls++
+		return errUnavailable
+	})
+	if err != errUnavailable {
+		t.Errorf("expected %v, got %v", errUnavailable, err)
+	}
+	testutil.DeepEqual(t, 1, calls)
+}
+
+func TestNewRetrierInvalid(t *testing.T) {
+	for _, spec := range []kritisv1beta1.RetrySpec{
+		{MaxAttempts: -1},
+		{Budget: "soon"},
+		{BreakerFallback: "open"},
+	} {
+		if _, err := newRetrier(spec); err == nil {
+			t.Errorf("expected error for %+v", spec)
+		}
+	}
+}
+
+func Test_transient(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"unavailable", errUnavailable, true},
+		{"rate limited", status.Error(codes.ResourceExhausted, "quota"), true},
+		{"wrapped", errors.Wrap(errUnavailable, "failed to list"), true},
+		{"not found", status.Error(codes.NotFound, "not found"), false},
+		{"REST rate limited", &googleapi.Error{Code: 429}, true},
+		{"REST service unavailable", &googleapi.Error{Code: 503}, true},
+		{"REST forbidden", &googleapi.Error{Code: 403}, false},
+		{"other", fmt.Errorf("invalid image"), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testutil.DeepEqual(t, test.expected, transient(test.err))
+		})
+	}
+}
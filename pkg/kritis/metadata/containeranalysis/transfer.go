@@ -0,0 +1,96 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containeranalysis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+	"github.com/grafeas/kritis/pkg/kritis/util"
+)
+
+// CopyAttestations copies the attestation occurrences of image in project src to project dst,
+// as attestations of target, e.g. once the image is promoted to the registry of another project.
+// target must have the digest of image. Projects default to those holding the metadata of the
+// images. Attestations already in dst, of the same note and with the same signature, are not
+// copied again. It returns the occurrences created.
+func (c Client) CopyAttestations(ctx context.Context, image, src, target, dst string) ([]*grafeas.Occurrence, error) {
+	from, err := reference.Parse(image)
+	if err != nil {
+		return nil, err
+	}
+	to, err := reference.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if !from.HasDigest() || from.Digest != to.Digest {
+		return nil, fmt.Errorf("attestations can only be copied between images of the same digest, got %s and %s", image, target)
+	}
+	if src == "" {
+		src = getProjectFromContainerImage(image)
+	}
+	if dst == "" {
+		dst = getProjectFromContainerImage(target)
+	}
+	if src == "" || dst == "" {
+		return nil, fmt.Errorf("projects of %s and %s are unknown, set them explicitly", image, target)
+	}
+	occs, err := c.listOccurrences(ctx, src, image, AttestationAuthority)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list attestations of %s in project %s", image, src)
+	}
+	existing, err := c.listOccurrences(ctx, dst, target, AttestationAuthority)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list attestations of %s in project %s", target, dst)
+	}
+	var created []*grafeas.Occurrence
+	for _, occ := range occs {
+		if containsAttestation(existing, occ) {
+			continue
+		}
+		req := &grafeas.CreateOccurrenceRequest{
+			Occurrence: &grafeas.Occurrence{
+				Resource: util.GetResource(target),
+				NoteName: occ.GetNoteName(),
+				Details:  occ.GetDetails(),
+			},
+			Parent: fmt.Sprintf("projects/%s", dst),
+		}
+		copied, err := c.createOccurrence(ctx, req)
+		if err != nil {
+			return created, errors.Wrapf(err, "failed to copy attestation %s", occ.GetName())
+		}
+		created = append(created, copied)
+		existing = append(existing, copied)
+	}
+	return created, nil
+}
+
+// containsAttestation returns true if occs has an attestation of the note of occ with the same signature.
+func containsAttestation(occs []*grafeas.Occurrence, occ *grafeas.Occurrence) bool {
+	for _, o := range occs {
+		if o.GetNoteName() == occ.GetNoteName() && proto.Equal(o.GetAttestation(), occ.GetAttestation()) {
+			return true
+		}
+	}
+	return false
+}
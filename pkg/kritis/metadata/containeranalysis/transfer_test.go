@@ -0,0 +1,86 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package containeranalysis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/attestation"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	"github.com/grafeas/kritis/pkg/kritis/util"
+)
+
+func TestCopyAttestations(t *testing.T) {
+	s := testutil.NewFakeGrafeasServer()
+	defer s.Stop()
+	c := newFakeGrafeasClient(t, s)
+	defer c.Close()
+	ctx := context.Background()
+
+	att := func(image, note, signature string) *grafeas.Occurrence {
+		return &grafeas.Occurrence{
+			Resource: util.GetResource(image),
+			NoteName: note,
+			Details: &grafeas.Occurrence_Attestation{
+				Attestation: &attestation.Details{Attestation: &attestation.Attestation{
+					Signature: &attestation.Attestation_PgpSignedAttestation{
+						PgpSignedAttestation: &attestation.PgpSignedAttestation{Signature: signature},
+					},
+				}},
+			},
+		}
+	}
+	digest := testutil.QualifiedImage[strings.Index(testutil.QualifiedImage, "@"):]
+	target := "gcr.io/prod/app" + digest
+	for _, occ := range []*grafeas.Occurrence{
+		att(testutil.QualifiedImage, "projects/attestors/notes/qa", "qa"),
+		att(testutil.QualifiedImage, "projects/attestors/notes/release", "release"),
+	} {
+		if _, err := c.client.CreateOccurrence(ctx, &grafeas.CreateOccurrenceRequest{Parent: "projects/image", Occurrence: occ}); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	// The qa attestation was already copied
+	if _, err := c.client.CreateOccurrence(ctx, &grafeas.CreateOccurrenceRequest{
+		Parent:     "projects/prod",
+		Occurrence: att(target, "projects/attestors/notes/qa", "qa"),
+	}); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	created, err := c.CopyAttestations(ctx, testutil.QualifiedImage, "", target, "")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(created) != 1 || created[0].GetNoteName() != "projects/attestors/notes/release" {
+		t.Fatalf("expected the release attestation to be copied, got %v", created)
+	}
+	atts, err := c.listOccurrences(ctx, "prod", target, AttestationAuthority)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 2, len(atts))
+
+	// Copies are idempotent
+	created, err = c.CopyAttestations(ctx, testutil.QualifiedImage, "image", target, "prod")
+	testutil.CheckErrorAndDeepEqual(t, false, err, 0, len(created))
+
+	if _, err := c.CopyAttestations(ctx, testutil.QualifiedImage, "", "gcr.io/prod/app:latest", ""); err == nil {
+		t.Error("expected an error copying attestations to an image of another digest")
+	}
+}
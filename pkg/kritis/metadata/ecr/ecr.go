@@ -0,0 +1,272 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ecr implements a metadata.Fetcher reading the image scan findings
+// of AWS Elastic Container Registry, for both basic and enhanced scanning.
+package ecr
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/severity"
+)
+
+// noRemediation is the remediation text of enhanced findings without a fix.
+const noRemediation = "None Provided"
+
+// registryRegexp matches ECR registry hosts, capturing the account and region.
+var registryRegexp = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+
+// For testing
+var newAPI = func(sess *session.Session, region string) ecriface.ECRAPI {
+	return ecr.New(sess, aws.NewConfig().WithRegion(region))
+}
+
+// Client implements the Fetcher interface using ECR image scan findings.
+// ECR stores no attestations, these are not supported.
+type Client struct {
+	sess *session.Session
+	mu   sync.Mutex
+	apis map[string]ecriface.ECRAPI
+}
+
+// New returns a Client using the default AWS credential chain, which includes
+// IAM roles for service accounts on EKS.
+func New() (*Client, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create AWS session")
+	}
+	return &Client{
+		sess: sess,
+		apis: map[string]ecriface.ECRAPI{},
+	}, nil
+}
+
+// Close closes connection
+func (c *Client) Close() {
+	// No Ops
+}
+
+// api returns the ECR API of the region, images are looked up in the region of their registry.
+func (c *Client) api(region string) ecriface.ECRAPI {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if a, ok := c.apis[region]; ok {
+		return a
+	}
+	a := newAPI(c.sess, region)
+	c.apis[region] = a
+	return a
+}
+
+// Vulnerabilities gets the scan findings of an ECR image.
+// An error is returned if the image has not been scanned yet.
+func (c *Client) Vulnerabilities(ctx context.Context, containerImage string) ([]metadata.Vulnerability, error) {
+	region, input, err := scanFindingsInput(containerImage)
+	if err != nil {
+		return nil, err
+	}
+	vulnz := []metadata.Vulnerability{}
+	var scanErr error
+	err = c.api(region).DescribeImageScanFindingsPagesWithContext(ctx, input, func(out *ecr.DescribeImageScanFindingsOutput, last bool) bool {
+		if status := out.ImageScanStatus; status != nil {
+			s := aws.StringValue(status.Status)
+			if s != ecr.ScanStatusComplete && s != ecr.ScanStatusActive {
+				scanErr = fmt.Errorf("scan of %s is %s: %s", containerImage, s, aws.StringValue(status.Description))
+				return false
+			}
+		}
+		if out.ImageScanFindings == nil {
+			return true
+		}
+		for _, f := range out.ImageScanFindings.Findings {
+			v := metadata.Vulnerability{
+				CVE:      aws.StringValue(f.Name),
+				Severity: severity.Normalize(aws.StringValue(f.Severity)),
+				// Basic scanning does not report fixes.
+				HasFixAvailable: true,
+			}
+			for _, a := range f.Attributes {
+				switch aws.StringValue(a.Key) {
+				case "package_name":
+					v.Package = aws.StringValue(a.Value)
+				case "package_version":
+					v.Version = aws.StringValue(a.Value)
+				}
+			}
+			vulnz = append(vulnz, v)
+		}
+		for _, f := range out.ImageScanFindings.EnhancedFindings {
+			if f.PackageVulnerabilityDetails == nil {
+				continue
+			}
+			v := metadata.Vulnerability{
+				CVE:             aws.StringValue(f.PackageVulnerabilityDetails.VulnerabilityId),
+				Severity:        severity.Normalize(aws.StringValue(f.Severity)),
+				HasFixAvailable: hasFix(f.Remediation),
+			}
+			if pkgs := f.PackageVulnerabilityDetails.VulnerablePackages; len(pkgs) > 0 {
+				v.Package = aws.StringValue(pkgs[0].Name)
+				v.Version = aws.StringValue(pkgs[0].Version)
+			}
+			if cvss := f.PackageVulnerabilityDetails.Cvss; len(cvss) > 0 {
+				v.CVSSVector = aws.StringValue(cvss[0].ScoringVector)
+				v.CVSSScore = aws.Float64Value(cvss[0].BaseScore)
+			}
+			vulnz = append(vulnz, v)
+		}
+		return true
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ecr.ErrCodeScanNotFoundException {
+		return nil, fmt.Errorf("%s has not been scanned", containerImage)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get scan findings of %s", containerImage)
+	}
+	if scanErr != nil {
+		return nil, scanErr
+	}
+	return vulnz, nil
+}
+
+// ScanStatus gets the status of the scan of an ECR image. Images whose scan hasn't started
+// yet have an unknown status.
+func (c *Client) ScanStatus(ctx context.Context, containerImage string) (metadata.ScanStatus, error) {
+	region, input, err := scanFindingsInput(containerImage)
+	if err != nil {
+		return metadata.ScanStatusUnknown, err
+	}
+	input.MaxResults = aws.Int64(1)
+	out, err := c.api(region).DescribeImageScanFindingsWithContext(ctx, input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ecr.ErrCodeScanNotFoundException {
+		return metadata.ScanStatusUnknown, nil
+	}
+	if err != nil {
+		return metadata.ScanStatusUnknown, errors.Wrapf(err, "failed to get scan status of %s", containerImage)
+	}
+	if out.ImageScanStatus == nil {
+		return metadata.ScanStatusUnknown, nil
+	}
+	switch aws.StringValue(out.ImageScanStatus.Status) {
+	case ecr.ScanStatusPending:
+		return metadata.ScanStatusPending, nil
+	case ecr.ScanStatusInProgress:
+		return metadata.ScanStatusScanning, nil
+	case ecr.ScanStatusComplete, ecr.ScanStatusActive:
+		return metadata.ScanStatusFinished, nil
+	case ecr.ScanStatusUnsupportedImage:
+		return metadata.ScanStatusUnsupported, nil
+	default:
+		return metadata.ScanStatusFailed, nil
+	}
+}
+
+// scanFindingsInput returns the region and the scan findings request of an ECR image.
+func scanFindingsInput(containerImage string) (string, *ecr.DescribeImageScanFindingsInput, error) {
+	ref, err := reference.ParseReference(containerImage)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "invalid image %s", containerImage)
+	}
+	repo := ref.Context()
+	m := registryRegexp.FindStringSubmatch(repo.RegistryStr())
+	if m == nil {
+		return "", nil, fmt.Errorf("%s is not an ECR image", containerImage)
+	}
+	id := &ecr.ImageIdentifier{}
+	switch r := ref.(type) {
+	case name.Digest:
+		id.ImageDigest = aws.String(r.DigestStr())
+	case name.Tag:
+		id.ImageTag = aws.String(r.TagStr())
+	}
+	return m[2], &ecr.DescribeImageScanFindingsInput{
+		RegistryId:     aws.String(m[1]),
+		RepositoryName: aws.String(repo.RepositoryStr()),
+		ImageId:        id,
+	}, nil
+}
+
+func hasFix(r *ecr.Remediation) bool {
+	if r == nil || r.Recommendation == nil {
+		return false
+	}
+	text := aws.StringValue(r.Recommendation.Text)
+	return text != "" && text != noRemediation
+}
+
+// Attestations returns no attestations, ECR does not store them.
+func (c *Client) Attestations(ctx context.Context, containerImage string) ([]metadata.PGPAttestation, error) {
+	return nil, nil
+}
+
+// OccurencesV1 returns no occurrences, ECR does not store them.
+func (c *Client) OccurencesV1(ctx context.Context, containerImage string) ([]*metadata.OccurenceV1, error) {
+	return nil, nil
+}
+
+// Builds returns no builds, ECR does not store them.
+func (c *Client) Builds(ctx context.Context, containerImage string) ([]metadata.Build, error) {
+	return nil, nil
+}
+
+// AttestationNote is not supported by ECR.
+func (c *Client) AttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	return nil, fmt.Errorf("attestations are not supported by the ECR backend")
+}
+
+// CreateAttestationNote is not supported by ECR.
+func (c *Client) CreateAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	return nil, fmt.Errorf("attestations are not supported by the ECR backend")
+}
+
+// CreateAttestationOccurence is not supported by ECR.
+func (c *Client) CreateAttestationOccurence(ctx context.Context, note *grafeas.Note,
+	containerImage string,
+	pgpSigningKey *secrets.PGPSigningSecret) (*grafeas.Occurrence, error) {
+	return nil, fmt.Errorf("attestations are not supported by the ECR backend")
+}
+
+// DiscoveryNote is not supported by ECR.
+func (c *Client) DiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	return nil, fmt.Errorf("discovery notes are not supported by the ECR backend")
+}
+
+// CreateDiscoveryNote is not supported by ECR.
+func (c *Client) CreateDiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	return nil, fmt.Errorf("discovery notes are not supported by the ECR backend")
+}
+
+// CreateDiscoveryOccurrence is not supported by ECR.
+func (c *Client) CreateDiscoveryOccurrence(ctx context.Context, note *grafeas.Note, containerImage string, message string) (*grafeas.Occurrence, error) {
+	return nil, fmt.Errorf("discovery occurrences are not supported by the ECR backend")
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecr
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+const (
+	image  = "123456789012.dkr.ecr.us-west-2.amazonaws.com/team/app@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+)
+
+type mockECR struct {
+	ecriface.ECRAPI
+	input *ecr.DescribeImageScanFindingsInput
+	pages []*ecr.DescribeImageScanFindingsOutput
+	err   error
+}
+
+func (m *mockECR) DescribeImageScanFindingsPagesWithContext(ctx aws.Context, input *ecr.DescribeImageScanFindingsInput, fn func(*ecr.DescribeImageScanFindingsOutput, bool) bool, opts ...request.Option) error {
+	m.input = input
+	if m.err != nil {
+		return m.err
+	}
+	for i, p := range m.pages {
+		if !fn(p, i == len(m.pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *mockECR) DescribeImageScanFindingsWithContext(ctx aws.Context, input *ecr.DescribeImageScanFindingsInput, opts ...request.Option) (*ecr.DescribeImageScanFindingsOutput, error) {
+	m.input = input
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.pages[0], nil
+}
+
+func complete() *ecr.ImageScanStatus {
+	return &ecr.ImageScanStatus{Status: aws.String(ecr.ScanStatusComplete)}
+}
+
+func TestVulnerabilities(t *testing.T) {
+	tcs := []struct {
+		name      string
+		image     string
+		mock      *mockECR
+		shouldErr bool
+		expected  []metadata.Vulnerability
+	}{
+		{
+			name:  "basic and enhanced findings",
+			image: image,
+			mock: &mockECR{pages: []*ecr.DescribeImageScanFindingsOutput{
+				{
+					ImageScanStatus: complete(),
+					ImageScanFindings: &ecr.ImageScanFindings{
+						Findings: []*ecr.ImageScanFinding{
+							{Name: aws.String("CVE-1"), Severity: aws.String("HIGH")},
+							{Name: aws.String("CVE-2"), Severity: aws.String("INFORMATIONAL")},
+						},
+					},
+				},
+				{
+					ImageScanStatus: complete(),
+					ImageScanFindings: &ecr.ImageScanFindings{
+						EnhancedFindings: []*ecr.EnhancedImageScanFinding{
+							{
+								Severity: aws.String("CRITICAL"),
+								PackageVulnerabilityDetails: &ecr.PackageVulnerabilityDetails{
+									VulnerabilityId: aws.String("CVE-3"),
+									Cvss:            []*ecr.CvssScore{{ScoringVector: aws.String("CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H")}},
+								},
+								Remediation: &ecr.Remediation{Recommendation: &ecr.Recommendation{Text: aws.String("Upgrade openssl")}},
+							},
+							{
+								Severity:                    aws.String("UNTRIAGED"),
+								PackageVulnerabilityDetails: &ecr.PackageVulnerabilityDetails{VulnerabilityId: aws.String("CVE-4")},
+								Remediation:                 &ecr.Remediation{Recommendation: &ecr.Recommendation{Text: aws.String("None Provided")}},
+							},
+						},
+					},
+				},
+			}},
+			expected: []metadata.Vulnerability{
+				{CVE: "CVE-1", Severity: "HIGH", HasFixAvailable: true},
+				{CVE: "CVE-2", Severity: "MINIMAL", HasFixAvailable: true},
+				{CVE: "CVE-3", Severity: "CRITICAL", HasFixAvailable: true, CVSSVector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"},
+				{CVE: "CVE-4", Severity: "SEVERITY_UNSPECIFIED", HasFixAvailable: false},
+			},
+		},
+		{
+			name:  "scan in progress",
+			image: image,
+			mock: &mockECR{pages: []*ecr.DescribeImageScanFindingsOutput{
+				{ImageScanStatus: &ecr.ImageScanStatus{Status: aws.String(ecr.ScanStatusInProgress)}},
+			}},
+			shouldErr: true,
+		},
+		{
+			name:      "image not scanned",
+			image:     image,
+			mock:      &mockECR{err: awserr.New(ecr.ErrCodeScanNotFoundException, "not found", nil)},
+			shouldErr: true,
+		},
+		{
+			name:      "not an ECR image",
+			image:     testutil.QualifiedImage,
+			mock:      &mockECR{},
+			shouldErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Client{apis: map[string]ecriface.ECRAPI{"us-west-2": tc.mock}}
+			actual, err := c.Vulnerabilities(context.Background(), tc.image)
+			testutil.CheckErrorAndDeepEqual(t, tc.shouldErr, err, tc.expected, actual)
+		})
+	}
+}
+
+func TestScanStatus(t *testing.T) {
+	status := func(s string) *mockECR {
+		return &mockECR{pages: []*ecr.DescribeImageScanFindingsOutput{{ImageScanStatus: &ecr.ImageScanStatus{Status: aws.String(s)}}}}
+	}
+	tcs := []struct {
+		name      string
+		mock      *mockECR
+		shouldErr bool
+		expected  metadata.ScanStatus
+	}{
+		{"in progress", status(ecr.ScanStatusInProgress), false, metadata.ScanStatusScanning},
+		{"pending", status(ecr.ScanStatusPending), false, metadata.ScanStatusPending},
+		{"complete", status(ecr.ScanStatusComplete), false, metadata.ScanStatusFinished},
+		{"continuous scanning", status(ecr.ScanStatusActive), false, metadata.ScanStatusFinished},
+		{"unsupported", status(ecr.ScanStatusUnsupportedImage), false, metadata.ScanStatusUnsupported},
+		{"failed", status(ecr.ScanStatusFindingsUnavailable), false, metadata.ScanStatusFailed},
+		{"not scanned", &mockECR{err: awserr.New(ecr.ErrCodeScanNotFoundException, "not found", nil)}, false, metadata.ScanStatusUnknown},
+		{"error", &mockECR{err: awserr.New(ecr.ErrCodeServerException, "unavailable", nil)}, true, metadata.ScanStatusUnknown},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Client{apis: map[string]ecriface.ECRAPI{"us-west-2": tc.mock}}
+			actual, err := c.ScanStatus(context.Background(), image)
+			testutil.CheckErrorAndDeepEqual(t, tc.shouldErr, err, tc.expected, actual)
+		})
+	}
+}
+
+func TestScanFindingsInput(t *testing.T) {
+	tcs := []struct {
+		name      string
+		image     string
+		shouldErr bool
+		region    string
+		expected  *ecr.DescribeImageScanFindingsInput
+	}{
+		{
+			name:   "digest",
+			image:  image,
+			region: "us-west-2",
+			expected: &ecr.DescribeImageScanFindingsInput{
+				RegistryId:     aws.String("123456789012"),
+				RepositoryName: aws.String("team/app"),
+				ImageId:        &ecr.ImageIdentifier{ImageDigest: aws.String(digest)},
+			},
+		},
+		{
+			name:   "tag in china region",
+			image:  "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn/app:1.0",
+			region: "cn-north-1",
+			expected: &ecr.DescribeImageScanFindingsInput{
+				RegistryId:     aws.String("123456789012"),
+				RepositoryName: aws.String("app"),
+				ImageId:        &ecr.ImageIdentifier{ImageTag: aws.String("1.0")},
+			},
+		},
+		{
+			name:      "other registry",
+			image:     "docker.io/library/nginx:latest",
+			shouldErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			region, input, err := scanFindingsInput(tc.image)
+			testutil.CheckErrorAndDeepEqual(t, tc.shouldErr, err, tc.region, region)
+			testutil.DeepEqual(t, tc.expected, input)
+		})
+	}
+}
+
+func TestClientPerRegion(t *testing.T) {
+	orig := newAPI
+	defer func() { newAPI = orig }()
+	created := []string{}
+	newAPI = func(sess *session.Session, region string) ecriface.ECRAPI {
+		created = append(created, region)
+		return &mockECR{}
+	}
+	c := &Client{apis: map[string]ecriface.ECRAPI{}}
+	c.api("us-west-2")
+	c.api("eu-west-1")
+	c.api("us-west-2")
+	testutil.DeepEqual(t, []string{"us-west-2", "eu-west-1"}, created)
+}
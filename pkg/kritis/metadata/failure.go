@@ -0,0 +1,189 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+// Failure policies of the calls to the backend which fail
+const (
+	// FailClosed denies admission of the images
+	FailClosed = "failClosed"
+	// FailOpen admits the images without reviewing them
+	FailOpen = "failOpen"
+)
+
+// Error is returned by the Fetcher of NewErrorFetcher when a call to the backend fails.
+type Error struct {
+	Backend string // Backend is the name of the metadata backend
+	Method  string // Method is the Fetcher method which failed
+	Timeout bool   // Timeout is true if the call timed out
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the backend.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// errorFetcher wraps a Fetcher and returns its errors as *Error.
+type errorFetcher struct {
+	Fetcher
+	backend string
+}
+
+// NewErrorFetcher returns a Fetcher whose errors are *Error of the given backend,
+// so that reviews may tell a failing backend apart from a denied image.
+func NewErrorFetcher(f Fetcher, backend string) Fetcher {
+	return errorFetcher{
+		Fetcher: f,
+		backend: backend,
+	}
+}
+
+func (e errorFetcher) wrap(method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var inner *Error
+	if errors.As(err, &inner) {
+		inner.Backend = e.backend
+		return err
+	}
+	return &Error{
+		Backend: e.backend,
+		Method:  method,
+		Timeout: errors.Is(err, context.DeadlineExceeded) || status.Code(errors.Cause(err)) == codes.DeadlineExceeded,
+		Err:     err,
+	}
+}
+
+// ValidateFailurePolicy returns an error if the policy sets an unknown failure policy.
+func ValidateFailurePolicy(policy kritisv1beta1.MetadataFailurePolicySpec) error {
+	for _, p := range []string{policy.OnError, policy.OnTimeout} {
+		switch p {
+		case "", FailClosed, FailOpen:
+		default:
+			return fmt.Errorf("unsupported metadata failure policy %q, expected %q or %q", p, FailClosed, FailOpen)
+		}
+	}
+	return nil
+}
+
+// FailsOpen returns true if err is a failure of the backend which the policy admits.
+func FailsOpen(policy kritisv1beta1.MetadataFailurePolicySpec, err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	p := policy.OnError
+	if e.Timeout && policy.OnTimeout != "" {
+		p = policy.OnTimeout
+	}
+	if p != FailOpen {
+		return false
+	}
+	if len(policy.Backends) == 0 {
+		return true
+	}
+	for _, b := range policy.Backends {
+		if b == e.Backend {
+			return true
+		}
+	}
+	return false
+}
+
+// Vulnerabilities gets vulnerabilities from the backend.
+func (e errorFetcher) Vulnerabilities(ctx context.Context, containerImage string) ([]Vulnerability, error) {
+	v, err := e.Fetcher.Vulnerabilities(ctx, containerImage)
+	return v, e.wrap("Vulnerabilities", err)
+}
+
+// Attestations gets attestations from the backend.
+func (e errorFetcher) Attestations(ctx context.Context, containerImage string) ([]PGPAttestation, error) {
+	a, err := e.Fetcher.Attestations(ctx, containerImage)
+	return a, e.wrap("Attestations", err)
+}
+
+// OccurencesV1 gets V1 occurrences from the backend.
+func (e errorFetcher) OccurencesV1(ctx context.Context, containerImage string) ([]*OccurenceV1, error) {
+	o, err := e.Fetcher.OccurencesV1(ctx, containerImage)
+	return o, e.wrap("OccurencesV1", err)
+}
+
+// Builds gets builds from the backend.
+func (e errorFetcher) Builds(ctx context.Context, containerImage string) ([]Build, error) {
+	b, err := e.Fetcher.Builds(ctx, containerImage)
+	return b, e.wrap("Builds", err)
+}
+
+// AttestationNote gets an attestation note from the backend.
+func (e errorFetcher) AttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	n, err := e.Fetcher.AttestationNote(ctx, aa)
+	return n, e.wrap("AttestationNote", err)
+}
+
+// CreateAttestationNote creates an attestation note in the backend.
+func (e errorFetcher) CreateAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	n, err := e.Fetcher.CreateAttestationNote(ctx, aa)
+	return n, e.wrap("CreateAttestationNote", err)
+}
+
+// CreateAttestationOccurence creates an attestation occurrence in the backend.
+func (e errorFetcher) CreateAttestationOccurence(ctx context.Context, note *grafeas.Note, containerImage string, pgpSigningKey *secrets.PGPSigningSecret) (*grafeas.Occurrence, error) {
+	o, err := e.Fetcher.CreateAttestationOccurence(ctx, note, containerImage, pgpSigningKey)
+	return o, e.wrap("CreateAttestationOccurence", err)
+}
+
+// DiscoveryNote gets a discovery note from the backend.
+func (e errorFetcher) DiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	n, err := e.Fetcher.DiscoveryNote(ctx, containerImage, noteID)
+	return n, e.wrap("DiscoveryNote", err)
+}
+
+// CreateDiscoveryNote creates a discovery note in the backend.
+func (e errorFetcher) CreateDiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	n, err := e.Fetcher.CreateDiscoveryNote(ctx, containerImage, noteID)
+	return n, e.wrap("CreateDiscoveryNote", err)
+}
+
+// ScanStatus gets the status of the scan of an image from the backend.
+func (e errorFetcher) ScanStatus(ctx context.Context, containerImage string) (ScanStatus, error) {
+	s, err := e.Fetcher.ScanStatus(ctx, containerImage)
+	return s, e.wrap("ScanStatus", err)
+}
+
+// CreateDiscoveryOccurrence creates a discovery occurrence in the backend.
+func (e errorFetcher) CreateDiscoveryOccurrence(ctx context.Context, note *grafeas.Note, containerImage string, message string) (*grafeas.Occurrence, error) {
+	o, err := e.Fetcher.CreateDiscoveryOccurrence(ctx, note, containerImage, message)
+	return o, e.wrap("CreateDiscoveryOccurrence", err)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+// failingFetcher fails all vulnerability reads with err.
+type failingFetcher struct {
+	Fetcher
+	err error
+}
+
+func (f failingFetcher) Vulnerabilities(ctx context.Context, containerImage string) ([]Vulnerability, error) {
+	return nil, f.err
+}
+
+func TestErrorFetcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		fetcher  Fetcher
+		expected *Error
+	}{
+		{"error", failingFetcher{err: fmt.Errorf("unavailable")}, &Error{Backend: "grafeas", Method: "Vulnerabilities"}},
+		{"deadline", failingFetcher{err: context.DeadlineExceeded}, &Error{Backend: "grafeas", Method: "Vulnerabilities", Timeout: true}},
+		{"timeout", NewTimeoutFetcher(slowFetcher{}, time.Millisecond, TimeoutFail), &Error{Backend: "grafeas", Method: "Vulnerabilities", Timeout: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewErrorFetcher(test.fetcher, "grafeas").Vulnerabilities(context.Background(), "gcr.io/foo/bar")
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("expected *Error, got %v", err)
+			}
+			if e.Backend != test.expected.Backend || e.Method != test.expected.Method || e.Timeout != test.expected.Timeout {
+				t.Errorf("expected %+v, got %+v", test.expected, e)
+			}
+		})
+	}
+}
+
+func TestFailsOpen(t *testing.T) {
+	failure := errors.Wrap(&Error{Backend: "harbor", Err: fmt.Errorf("unavailable")}, "failed validating image security policy")
+	timeout := &Error{Backend: "harbor", Timeout: true, Err: context.DeadlineExceeded}
+	tests := []struct {
+		name     string
+		policy   kritisv1beta1.MetadataFailurePolicySpec
+		err      error
+		expected bool
+	}{
+		{"fails closed by default", kritisv1beta1.MetadataFailurePolicySpec{}, failure, false},
+		{"fails open on error", kritisv1beta1.MetadataFailurePolicySpec{OnError: FailOpen}, failure, true},
+		{"timeout defaults to error", kritisv1beta1.MetadataFailurePolicySpec{OnError: FailOpen}, timeout, true},
+		{"fails closed on timeout", kritisv1beta1.MetadataFailurePolicySpec{OnError: FailOpen, OnTimeout: FailClosed}, timeout, false},
+		{"fails open on timeout only", kritisv1beta1.MetadataFailurePolicySpec{OnTimeout: FailOpen}, failure, false},
+		{"fails open for backend", kritisv1beta1.MetadataFailurePolicySpec{OnError: FailOpen, Backends: []string{"harbor"}}, failure, true},
+		{"fails closed for other backends", kritisv1beta1.MetadataFailurePolicySpec{OnError: FailOpen, Backends: []string{"grafeas"}}, failure, false},
+		{"other errors fail closed", kritisv1beta1.MetadataFailurePolicySpec{OnError: FailOpen}, fmt.Errorf("found violations"), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := FailsOpen(test.policy, test.err); actual != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidateFailurePolicy(t *testing.T) {
+	if err := ValidateFailurePolicy(kritisv1beta1.MetadataFailurePolicySpec{OnError: FailOpen, OnTimeout: FailClosed}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateFailurePolicy(kritisv1beta1.MetadataFailurePolicySpec{OnTimeout: "open"}); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metrics"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+	"github.com/grafeas/kritis/pkg/kritis/registryauth"
+)
+
+const (
+	// BundleManifestFile is the tarball entry holding the BundleManifest.
+	BundleManifestFile = "manifest.json"
+	// BundleFixturesFile is the tarball entry holding the fixtures, in the format read by New.
+	BundleFixturesFile = "fixtures.json"
+
+	bundleSignatureSuffix = ".sig"
+
+	// DefaultBundleRefreshInterval is the interval at which bundles are loaded again.
+	DefaultBundleRefreshInterval = time.Hour
+)
+
+// BundleManifest describes a vulnerability bundle.
+type BundleManifest struct {
+	Version    string    `json:"version"`
+	CreateTime time.Time `json:"createTime"`
+}
+
+var (
+	// For testing
+	now        = time.Now
+	pullBundle = pullBundleImage
+	loadBundle = LoadBundle
+)
+
+// LoadBundle reads the vulnerability bundle described by spec, from a mounted
+// volume or an OCI artifact, and returns a Client serving it once its signature
+// and freshness are verified.
+func LoadBundle(spec kritisv1beta1.VulnerabilityBundleSpec) (*Client, error) {
+	if spec.PublicKeyPath == "" {
+		return nil, fmt.Errorf("no public key configured to verify the vulnerability bundle")
+	}
+	keys, err := ioutil.ReadFile(spec.PublicKeyPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read bundle public key %s", spec.PublicKeyPath)
+	}
+	var maxAge time.Duration
+	if spec.MaxAge != "" {
+		if maxAge, err = time.ParseDuration(spec.MaxAge); err != nil {
+			return nil, errors.Wrapf(err, "invalid bundle maxAge %q", spec.MaxAge)
+		}
+	}
+	var tarball, sig []byte
+	switch {
+	case spec.Image != "":
+		if tarball, sig, err = pullBundle(spec.Image); err != nil {
+			return nil, errors.Wrapf(err, "failed to pull bundle %s", spec.Image)
+		}
+	case spec.Path != "":
+		sigPath := spec.SignaturePath
+		if sigPath == "" {
+			sigPath = spec.Path + bundleSignatureSuffix
+		}
+		if tarball, err = ioutil.ReadFile(spec.Path); err != nil {
+			return nil, errors.Wrapf(err, "failed to read bundle %s", spec.Path)
+		}
+		if isSignedBundle(tarball) {
+			return NewFromSignedBundle(tarball, keys, maxAge)
+		}
+		if sig, err = ioutil.ReadFile(sigPath); err != nil {
+			return nil, errors.Wrapf(err, "failed to read bundle signature %s", sigPath)
+		}
+	default:
+		return nil, fmt.Errorf("no vulnerability bundle path or image configured")
+	}
+	return NewFromBundle(tarball, sig, keys, maxAge)
+}
+
+// Bundle serves the vulnerability bundle described by its spec. The bundle is
+// loaded once, and reloaded by Refresh so that a new bundle is picked up without
+// restarting Kritis.
+type Bundle struct {
+	spec     kritisv1beta1.VulnerabilityBundleSpec
+	interval time.Duration
+
+	mu     sync.RWMutex
+	client *Client
+}
+
+// NewBundle loads the vulnerability bundle described by spec, see LoadBundle.
+func NewBundle(spec kritisv1beta1.VulnerabilityBundleSpec) (*Bundle, error) {
+	interval := DefaultBundleRefreshInterval
+	if spec.RefreshInterval != "" {
+		var err error
+		if interval, err = time.ParseDuration(spec.RefreshInterval); err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid bundle refreshInterval %q", spec.RefreshInterval)
+		}
+	}
+	c, err := loadBundle(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &Bundle{spec: spec, interval: interval, client: c}, nil
+}
+
+// Client returns the Client serving the last bundle loaded.
+func (b *Bundle) Client() *Client {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.client
+}
+
+// Refresh loads the bundle again every refresh interval until stopCh is closed.
+// The previous bundle is kept, until it expires, if the new one can't be loaded.
+func (b *Bundle) Refresh(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			b.reload()
+		}
+	}
+}
+
+func (b *Bundle) reload() {
+	c, err := loadBundle(b.spec)
+	if err != nil {
+		glog.Errorf("failed to reload vulnerability bundle, serving the previous one: %v", err)
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.client = c
+}
+
+// NewFromBundle verifies the detached signature of the bundle tarball against
+// the armored PGP public keys, and returns a Client serving its fixtures.
+// The client refuses to serve vulnerabilities once the bundle is older than
+// maxAge, a maxAge of 0 disables the freshness check.
+func NewFromBundle(tarball, sig, keys []byte, maxAge time.Duration) (*Client, error) {
+	if err := checkSignature(tarball, sig, keys); err != nil {
+		return nil, err
+	}
+	manifest, fixtures, err := readBundle(tarball)
+	if err != nil {
+		return nil, err
+	}
+	return newFromManifest(manifest, fixtures, maxAge)
+}
+
+// checkSignature verifies the detached signature, armored or binary, of content
+// against the armored PGP public keys.
+func checkSignature(content, sig, keys []byte) error {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(keys))
+	if err != nil {
+		return errors.Wrap(err, "failed to read bundle public key")
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(sig), []byte("-----BEGIN")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(content), bytes.NewReader(sig))
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(content), bytes.NewReader(sig))
+	}
+	return errors.Wrap(err, "invalid bundle signature")
+}
+
+func newFromManifest(manifest *BundleManifest, fixtures []Fixture, maxAge time.Duration) (*Client, error) {
+	if manifest.CreateTime.IsZero() {
+		return nil, fmt.Errorf("bundle %q has no createTime", manifest.Version)
+	}
+	c := NewFromFixtures(fixtures)
+	if maxAge > 0 {
+		c.expires = manifest.CreateTime.Add(maxAge)
+		if err := c.checkFresh(); err != nil {
+			return nil, err
+		}
+	}
+	metrics.SetVulnerabilityBundle(manifest.Version, manifest.CreateTime)
+	glog.Infof("loaded vulnerability bundle %q created at %s", manifest.Version, manifest.CreateTime)
+	return c, nil
+}
+
+// checkFresh returns an error if the bundle served by c is expired.
+func (c *Client) checkFresh() error {
+	if !c.expires.IsZero() && now().After(c.expires) {
+		return fmt.Errorf("vulnerability bundle expired at %s", c.expires.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func readBundle(tarball []byte) (*BundleManifest, []Fixture, error) {
+	var manifest *BundleManifest
+	var fixtures []Fixture
+	tr := tar.NewReader(bytes.NewReader(tarball))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read bundle")
+		}
+		switch hdr.Name {
+		case BundleManifestFile:
+			manifest = &BundleManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, nil, errors.Wrapf(err, "failed to parse %s", BundleManifestFile)
+			}
+		case BundleFixturesFile:
+			if err := json.NewDecoder(tr).Decode(&fixtures); err != nil {
+				return nil, nil, errors.Wrapf(err, "failed to parse %s", BundleFixturesFile)
+			}
+		}
+	}
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("bundle has no %s", BundleManifestFile)
+	}
+	return manifest, fixtures, nil
+}
+
+// pullBundleImage pulls an OCI artifact whose first layer is the bundle tarball
+// and second layer its detached signature.
+func pullBundleImage(image string) ([]byte, []byte, error) {
+	ref, err := reference.ParseReference(image)
+	if err != nil {
+		return nil, nil, err
+	}
+	img, err := remote.Image(ref, remote.WithAuthFromKeychain(registryauth.Keychain()))
+	if err != nil {
+		return nil, nil, err
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(layers) != 2 {
+		return nil, nil, fmt.Errorf("expected 2 layers, got %d", len(layers))
+	}
+	blobs := [][]byte{}
+	for _, l := range layers {
+		// Artifact layers are stored as is, Compressed returns the raw blob.
+		rc, err := l.Compressed()
+		if err != nil {
+			return nil, nil, err
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		blobs = append(blobs, b)
+	}
+	return blobs[0], blobs[1], nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/metrics"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func newBundle(t *testing.T, manifest BundleManifest, fixtures []Fixture) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, v := range map[string]interface{}{BundleManifestFile: manifest, BundleFixturesFile: fixtures} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(b))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newSigner(t *testing.T) (*openpgp.Entity, []byte) {
+	e, err := openpgp.NewEntity("bundle", "", "bundle@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return e, buf.Bytes()
+}
+
+func sign(t *testing.T, e *openpgp.Entity, b []byte) []byte {
+	var sig bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&sig, e, bytes.NewReader(b), nil); err != nil {
+		t.Fatal(err)
+	}
+	return sig.Bytes()
+}
+
+func TestNewFromBundle(t *testing.T) {
+	created := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	origNow := now
+	defer func() { now = origNow }()
+	now = func() time.Time { return created.Add(24 * time.Hour) }
+
+	vulnz := []metadata.Vulnerability{{CVE: "CVE-1", Severity: "HIGH"}}
+	bundle := newBundle(t, BundleManifest{Version: "v1", CreateTime: created},
+		[]Fixture{{Image: testutil.QualifiedImage, Vulnerabilities: vulnz}})
+	signer, keys := newSigner(t)
+	other, _ := newSigner(t)
+
+	tcs := []struct {
+		name      string
+		sig       []byte
+		maxAge    time.Duration
+		shouldErr bool
+	}{
+		{"valid bundle", sign(t, signer, bundle), 48 * time.Hour, false},
+		{"no freshness check", sign(t, signer, bundle), 0, false},
+		{"stale bundle", sign(t, signer, bundle), time.Hour, true},
+		{"untrusted signer", sign(t, other, bundle), 0, true},
+		{"signature over other content", sign(t, signer, []byte("other")), 0, true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewFromBundle(bundle, tc.sig, keys, tc.maxAge)
+			testutil.CheckError(t, tc.shouldErr, err)
+			if err != nil {
+				return
+			}
+			actual, err := c.Vulnerabilities(context.Background(), testutil.QualifiedImage)
+			testutil.CheckErrorAndDeepEqual(t, false, err, vulnz, actual)
+		})
+	}
+
+	if metrics.VulnerabilityBundleVersion.Value() != "v1" {
+		t.Errorf("bundle version metric not set: %q", metrics.VulnerabilityBundleVersion.Value())
+	}
+}
+
+func TestBundleExpiry(t *testing.T) {
+	created := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	origNow := now
+	defer func() { now = origNow }()
+	now = func() time.Time { return created }
+
+	bundle := newBundle(t, BundleManifest{Version: "v1", CreateTime: created}, nil)
+	signer, keys := newSigner(t)
+	c, err := NewFromBundle(bundle, sign(t, signer, bundle), keys, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	now = func() time.Time { return created.Add(2 * time.Hour) }
+	_, err = c.Vulnerabilities(context.Background(), testutil.QualifiedImage)
+	testutil.CheckError(t, true, err)
+}
+
+func TestBundleReload(t *testing.T) {
+	origLoad := loadBundle
+	defer func() { loadBundle = origLoad }()
+	var loaded []*Client
+	var loadErr error
+	loadBundle = func(kritisv1beta1.VulnerabilityBundleSpec) (*Client, error) {
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		c := NewFromFixtures(nil)
+		loaded = append(loaded, c)
+		return c, nil
+	}
+
+	_, err := NewBundle(kritisv1beta1.VulnerabilityBundleSpec{RefreshInterval: "soon"})
+	testutil.CheckError(t, true, err)
+	b, err := NewBundle(kritisv1beta1.VulnerabilityBundleSpec{})
+	testutil.CheckError(t, false, err)
+	testutil.DeepEqual(t, DefaultBundleRefreshInterval, b.interval)
+	if b.Client() != loaded[0] {
+		t.Errorf("expected the bundle loaded first")
+	}
+	b.reload()
+	if b.Client() != loaded[1] {
+		t.Errorf("expected the reloaded bundle")
+	}
+	// The previous bundle is kept if the new one is invalid
+	loadErr = fmt.Errorf("invalid bundle signature")
+	b.reload()
+	if b.Client() != loaded[1] {
+		t.Errorf("expected the previous bundle")
+	}
+}
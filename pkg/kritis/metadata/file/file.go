@@ -0,0 +1,251 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package file implements a metadata.Fetcher backed by fixture files, see
+// Fixture for the format. It is used for offline review and tests.
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/attestation"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/util"
+)
+
+// Fixture holds all metadata known about a single image.
+type Fixture struct {
+	Image           string                    `json:"image"`
+	Vulnerabilities []metadata.Vulnerability  `json:"vulnerabilities,omitempty"`
+	Attestations    []metadata.PGPAttestation `json:"attestations,omitempty"`
+	Builds          []metadata.Build          `json:"builds,omitempty"`
+	OccurrencesV1   []*metadata.OccurenceV1   `json:"occurrencesV1,omitempty"`
+}
+
+// Client implements the Fetcher interface using fixtures.
+// Notes and occurrences created through the client are only kept in memory.
+type Client struct {
+	mu       sync.Mutex
+	fixtures map[string]*Fixture
+	notes    map[string]*grafeas.Note
+	expires  time.Time // expires is set for clients serving a vulnerability bundle
+}
+
+// New reads the list of fixtures at path and returns a Client serving them.
+func New(path string) (*Client, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read fixtures %s", path)
+	}
+	var fixtures []Fixture
+	if err := json.Unmarshal(b, &fixtures); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse fixtures %s", path)
+	}
+	return NewFromFixtures(fixtures), nil
+}
+
+// NewFromFixtures returns a Client serving the given fixtures.
+func NewFromFixtures(fixtures []Fixture) *Client {
+	c := &Client{
+		fixtures: map[string]*Fixture{},
+		notes:    map[string]*grafeas.Note{},
+	}
+	for i := range fixtures {
+		c.fixtures[fixtures[i].Image] = &fixtures[i]
+	}
+	return c
+}
+
+// Close closes connection
+func (c *Client) Close() {
+	// No Ops
+}
+
+func (c *Client) fixture(image string) *Fixture {
+	if f, ok := c.fixtures[image]; ok {
+		return f
+	}
+	return &Fixture{}
+}
+
+// Vulnerabilities gets Package Vulnerabilities for a specified image.
+func (c *Client) Vulnerabilities(ctx context.Context, containerImage string) ([]metadata.Vulnerability, error) {
+	if err := c.checkFresh(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fixture(containerImage).Vulnerabilities, nil
+}
+
+// Attestations gets Attestations for a specified image.
+func (c *Client) Attestations(ctx context.Context, containerImage string) ([]metadata.PGPAttestation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fixture(containerImage).Attestations, nil
+}
+
+// OccurencesV1 gets V1 Occurrences for a specified image.
+func (c *Client) OccurencesV1(ctx context.Context, containerImage string) ([]*metadata.OccurenceV1, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fixture(containerImage).OccurrencesV1, nil
+}
+
+// Builds gets Builds for a specified image.
+func (c *Client) Builds(ctx context.Context, containerImage string) ([]metadata.Build, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fixture(containerImage).Builds, nil
+}
+
+// AttestationNote returns a note if it was created for given AttestationAuthority
+func (c *Client) AttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n, ok := c.notes[aa.Name]; ok {
+		return n, nil
+	}
+	return nil, fmt.Errorf("note for %q not found", aa.Name)
+}
+
+// CreateAttestationNote creates an attestation note from AttestationAuthority
+func (c *Client) CreateAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := &grafeas.Note{
+		Name:             aa.Spec.NoteReference,
+		ShortDescription: "Image Policy Security Attestor",
+	}
+	c.notes[aa.Name] = n
+	return n, nil
+}
+
+// CreateAttestationOccurence signs the image and records the attestation in memory.
+func (c *Client) CreateAttestationOccurence(ctx context.Context, note *grafeas.Note,
+	containerImage string,
+	pgpSigningKey *secrets.PGPSigningSecret) (*grafeas.Occurrence, error) {
+	a, err := util.CreateAttestation(ctx, containerImage, pgpSigningKey)
+	if err != nil {
+		return nil, err
+	}
+	occ := &grafeas.Occurrence{
+		Resource: util.GetResource(containerImage),
+		NoteName: note.GetName(),
+		Details: &grafeas.Occurrence_Attestation{
+			Attestation: &attestation.Details{Attestation: a},
+		},
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, ok := c.fixtures[containerImage]
+	if !ok {
+		f = &Fixture{Image: containerImage}
+		c.fixtures[containerImage] = f
+	}
+	f.Attestations = append(f.Attestations, util.GetPgpAttestationFromOccurrence(occ))
+	return occ, nil
+}
+
+// DiscoveryNote returns a discovery note if it was created.
+func (c *Client) DiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n, ok := c.notes[noteID]; ok {
+		return n, nil
+	}
+	return nil, fmt.Errorf("note %q not found", noteID)
+}
+
+// CreateDiscoveryNote creates a discovery note.
+func (c *Client) CreateDiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := util.NewDiscoveryNote("file", noteID)
+	c.notes[noteID] = n
+	return n, nil
+}
+
+// CreateDiscoveryOccurrence returns the discovery occurrence without recording it,
+// since fixtures carry no discovery metadata.
+func (c *Client) CreateDiscoveryOccurrence(ctx context.Context, note *grafeas.Note, containerImage string, message string) (*grafeas.Occurrence, error) {
+	return util.NewDiscoveryOccurrence(note, containerImage, message), nil
+}
+
+// ScanStatus returns an unknown status, since fixtures carry no scan status.
+func (c *Client) ScanStatus(ctx context.Context, containerImage string) (metadata.ScanStatus, error) {
+	return metadata.ScanStatusUnknown, nil
+}
+
+// Export fetches all metadata for the image from f and returns it as a sanitized Fixture.
+// Occurrence names and timestamps are dropped, since they identify the source project
+// and are not used when reviewing images.
+func Export(ctx context.Context, f metadata.Fetcher, image string) (*Fixture, error) {
+	vulnz, err := f.Vulnerabilities(ctx, image)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get vulnerabilities")
+	}
+	atts, err := f.Attestations(ctx, image)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get attestations")
+	}
+	builds, err := f.Builds(ctx, image)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get builds")
+	}
+	occs, err := f.OccurencesV1(ctx, image)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get V1 occurrences")
+	}
+	fixture := &Fixture{
+		Image:           image,
+		Vulnerabilities: vulnz,
+		Builds:          builds,
+	}
+	for _, a := range atts {
+		a.OccID = ""
+		fixture.Attestations = append(fixture.Attestations, a)
+	}
+	for _, occ := range occs {
+		o := *occ
+		o.Name = ""
+		o.CreateTime = ""
+		o.UpdateTime = ""
+		fixture.OccurrencesV1 = append(fixture.OccurrencesV1, &o)
+	}
+	return fixture, nil
+}
+
+// Write writes fixtures in the format read by New.
+func Write(w io.Writer, fixtures []Fixture) error {
+	b, err := json.MarshalIndent(fixtures, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	cav1 "google.golang.org/api/containeranalysis/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type v1MockClient struct {
+	testutil.MockMetadataClient
+	occs []*metadata.OccurenceV1
+}
+
+func (m *v1MockClient) OccurencesV1(ctx context.Context, containerImage string) ([]*metadata.OccurenceV1, error) {
+	return m.occs, nil
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	mc := &v1MockClient{
+		MockMetadataClient: testutil.MockMetadataClient{
+			Vulnz: []metadata.Vulnerability{{CVE: "c", Severity: "CRITICAL", HasFixAvailable: true}},
+			PGPAttestations: []metadata.PGPAttestation{
+				{Signature: "sig", KeyID: "key", OccID: "projects/secret/occurrences/1"},
+			},
+			Build: []metadata.Build{{Provenance: &metadata.BuildProvenance{ProjectID: "p", Creator: "c"}}},
+		},
+		occs: []*metadata.OccurenceV1{
+			{Name: "projects/secret/occurrences/2", NoteName: "projects/p/notes/n", CreateTime: "2018-01-01T00:00:00Z",
+				Attestation: &cav1.AttestationOccurrence{Jwts: []*cav1.Jwt{{CompactJwt: "jwt"}}}},
+		},
+	}
+	f, err := Export(context.Background(), mc, testutil.QualifiedImage)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if f.Attestations[0].OccID != "" || f.OccurrencesV1[0].Name != "" || f.OccurrencesV1[0].CreateTime != "" {
+		t.Errorf("fixture was not sanitized: %+v", f)
+	}
+	if mc.occs[0].Name == "" {
+		t.Errorf("export should not modify fetched occurrences")
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, []Fixture{*f}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	tmp, err := ioutil.TempFile("", "fixtures")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	tmp.Close()
+
+	c, err := New(tmp.Name())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	vulnz, err := c.Vulnerabilities(context.Background(), testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, mc.Vulnz, vulnz)
+	builds, err := c.Builds(context.Background(), testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, mc.Build, builds)
+	occs, err := c.OccurencesV1(context.Background(), testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, "jwt", occs[0].Attestation.Jwts[0].CompactJwt)
+	vulnz, err = c.Vulnerabilities(context.Background(), "gcr.io/unknown/image")
+	testutil.CheckErrorAndDeepEqual(t, false, err, []metadata.Vulnerability(nil), vulnz)
+}
+
+func TestCreateAttestationOccurence(t *testing.T) {
+	sec, _ := testutil.CreateSecret(t, "sec")
+	c := NewFromFixtures(nil)
+	n, err := c.CreateAttestationNote(context.Background(), testAuthority())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := c.CreateAttestationOccurence(context.Background(), n, testutil.QualifiedImage, sec); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	atts, err := c.Attestations(context.Background(), testutil.QualifiedImage)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(atts) != 1 || atts[0].KeyID != sec.PgpKey.Fingerprint() {
+		t.Errorf("unexpected attestations %v", atts)
+	}
+	if _, err := c.AttestationNote(context.Background(), testAuthority()); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func testAuthority() *kritisv1beta1.AttestationAuthority {
+	return &kritisv1beta1.AttestationAuthority{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec:       kritisv1beta1.AttestationAuthoritySpec{NoteReference: "projects/p/notes/test"},
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"
+
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+// SignedBundle is a vulnerability bundle as a single JSON document, holding a
+// BundlePayload and its armored detached PGP signature. The payload is kept
+// base64 encoded, so that the signed bytes are verified as they were written.
+type SignedBundle struct {
+	Payload   []byte `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// BundlePayload is the signed content of a SignedBundle.
+type BundlePayload struct {
+	BundleManifest
+	Fixtures []Fixture `json:"fixtures"`
+}
+
+// WriteSignedBundle signs the manifest and fixtures with the PGP private key, and
+// writes them as a SignedBundle.
+func WriteSignedBundle(w io.Writer, manifest BundleManifest, fixtures []Fixture, key *secrets.PgpKey) error {
+	if key.PrivateKey() == nil {
+		return errors.New("a PGP private key is required to sign the bundle")
+	}
+	payload, err := json.Marshal(BundlePayload{BundleManifest: manifest, Fixtures: fixtures})
+	if err != nil {
+		return err
+	}
+	var sig bytes.Buffer
+	signer := &openpgp.Entity{PrimaryKey: key.PublicKey(), PrivateKey: key.PrivateKey()}
+	if err := openpgp.ArmoredDetachSign(&sig, signer, bytes.NewReader(payload), nil); err != nil {
+		return errors.Wrap(err, "failed to sign bundle")
+	}
+	b, err := json.MarshalIndent(SignedBundle{Payload: payload, Signature: sig.String()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ReadSignedBundle verifies the signature of the SignedBundle b against the armored
+// PGP public keys, and returns its payload.
+func ReadSignedBundle(b, keys []byte) (*BundlePayload, error) {
+	var bundle SignedBundle
+	if err := json.Unmarshal(b, &bundle); err != nil {
+		return nil, errors.Wrap(err, "failed to parse signed bundle")
+	}
+	if err := checkSignature(bundle.Payload, []byte(bundle.Signature), keys); err != nil {
+		return nil, err
+	}
+	payload := &BundlePayload{}
+	if err := json.Unmarshal(bundle.Payload, payload); err != nil {
+		return nil, errors.Wrap(err, "failed to parse bundle payload")
+	}
+	return payload, nil
+}
+
+// NewFromSignedBundle verifies the SignedBundle b against the armored PGP public
+// keys, and returns a Client serving its fixtures, see NewFromBundle.
+func NewFromSignedBundle(b, keys []byte, maxAge time.Duration) (*Client, error) {
+	payload, err := ReadSignedBundle(b, keys)
+	if err != nil {
+		return nil, err
+	}
+	return newFromManifest(&payload.BundleManifest, payload.Fixtures, maxAge)
+}
+
+// isSignedBundle returns true if b is a JSON document rather than a tarball.
+func isSignedBundle(b []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(b), []byte("{"))
+}
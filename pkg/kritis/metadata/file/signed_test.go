@@ -0,0 +1,143 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func newPgpKey(t *testing.T) (*secrets.PgpKey, []byte) {
+	t.Helper()
+	priv, pub, err := secrets.GeneratePgpKey("bundle", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := secrets.NewPgpKey(priv, "", pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, []byte(pub)
+}
+
+func TestSignedBundle(t *testing.T) {
+	created := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	origNow := now
+	defer func() { now = origNow }()
+	now = func() time.Time { return created.Add(24 * time.Hour) }
+
+	vulnz := []metadata.Vulnerability{{CVE: "CVE-1", Severity: "HIGH"}}
+	builds := []metadata.Build{{Provenance: &metadata.BuildProvenance{Creator: "builder@example.com"}}}
+	fixtures := []Fixture{{Image: testutil.QualifiedImage, Vulnerabilities: vulnz, Builds: builds}}
+	key, keys := newPgpKey(t)
+	_, otherKeys := newPgpKey(t)
+
+	var b bytes.Buffer
+	if err := WriteSignedBundle(&b, BundleManifest{Version: "v1", CreateTime: created}, fixtures, key); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	bundle := b.Bytes()
+	tampered := bytes.Replace(bundle, []byte(`"payload": "`), []byte(`"payload": "AA`), 1)
+
+	tcs := []struct {
+		name      string
+		bundle    []byte
+		keys      []byte
+		maxAge    time.Duration
+		shouldErr bool
+	}{
+		{"valid bundle", bundle, keys, 48 * time.Hour, false},
+		{"stale bundle", bundle, keys, time.Hour, true},
+		{"untrusted signer", bundle, otherKeys, 0, true},
+		{"tampered payload", tampered, keys, 0, true},
+		{"not a bundle", []byte("{"), keys, 0, true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewFromSignedBundle(tc.bundle, tc.keys, tc.maxAge)
+			testutil.CheckError(t, tc.shouldErr, err)
+			if err != nil {
+				return
+			}
+			actual, err := c.Vulnerabilities(context.Background(), testutil.QualifiedImage)
+			testutil.CheckErrorAndDeepEqual(t, false, err, vulnz, actual)
+			actualBuilds, err := c.Builds(context.Background(), testutil.QualifiedImage)
+			testutil.CheckErrorAndDeepEqual(t, false, err, builds, actualBuilds)
+		})
+	}
+}
+
+func TestLoadSignedBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	key, keys := newPgpKey(t)
+	var b bytes.Buffer
+	vulnz := []metadata.Vulnerability{{CVE: "CVE-1", Severity: "HIGH"}}
+	fixtures := []Fixture{{Image: testutil.QualifiedImage, Vulnerabilities: vulnz}}
+	if err := WriteSignedBundle(&b, BundleManifest{Version: "v1", CreateTime: now()}, fixtures, key); err != nil {
+		t.Fatal(err)
+	}
+	spec := kritisv1beta1.VulnerabilityBundleSpec{
+		Path:          filepath.Join(dir, "bundle.json"),
+		PublicKeyPath: filepath.Join(dir, "pub.asc"),
+	}
+	if err := ioutil.WriteFile(spec.Path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(spec.PublicKeyPath, keys, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// No detached signature is read for signed bundles
+	c, err := LoadBundle(spec)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	actual, err := c.Vulnerabilities(context.Background(), testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, vulnz, actual)
+}
+
+func TestWriteSignedBundleNoPrivateKey(t *testing.T) {
+	_, pub, err := secrets.GeneratePgpKey("bundle", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := secrets.NewPgpKey("", "", pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WriteSignedBundle(&bytes.Buffer{}, BundleManifest{Version: "v1"}, nil, key)
+	testutil.CheckError(t, true, err)
+}
+
+func TestIsSignedBundle(t *testing.T) {
+	testutil.DeepEqual(t, true, isSignedBundle([]byte("\n  {\"payload\": \"\"}")))
+	testutil.DeepEqual(t, false, isSignedBundle([]byte(strings.Repeat("\x00", 512))))
+}
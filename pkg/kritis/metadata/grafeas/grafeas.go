@@ -44,6 +44,7 @@ import (
 const (
 	PkgVulnerability     = "PACKAGE_VULNERABILITY"
 	AttestationAuthority = "ATTESTATION_AUTHORITY"
+	Discovery            = "DISCOVERY"
 	DefaultProject       = "kritis" // DefaultProject is the default project name, only single project is supported
 )
 
@@ -140,8 +141,8 @@ func New(config kritisv1beta1.GrafeasConfigSpec) (*Client, error) {
 }
 
 // Vulnerabilities gets Package Vulnerabilities Occurrences for a specified image.
-func (c Client) Vulnerabilities(containerImage string) ([]metadata.Vulnerability, error) {
-	occs, err := c.fetchOccurrence(containerImage, PkgVulnerability)
+func (c Client) Vulnerabilities(ctx context.Context, containerImage string) ([]metadata.Vulnerability, error) {
+	occs, err := c.fetchOccurrence(ctx, containerImage, PkgVulnerability)
 	if err != nil {
 		return nil, err
 	}
@@ -154,9 +155,18 @@ func (c Client) Vulnerabilities(containerImage string) ([]metadata.Vulnerability
 	return vulnz, nil
 }
 
+// ScanStatus gets the status of the vulnerability scan of an image from its Discovery Occurrences.
+func (c Client) ScanStatus(ctx context.Context, containerImage string) (metadata.ScanStatus, error) {
+	occs, err := c.fetchOccurrence(ctx, containerImage, Discovery)
+	if err != nil {
+		return metadata.ScanStatusUnknown, err
+	}
+	return util.GetScanStatusFromOccurrences(occs), nil
+}
+
 // Attestations gets AttesationAuthority Occurrences for a specified image.
-func (c Client) Attestations(containerImage string) ([]metadata.PGPAttestation, error) {
-	occs, err := c.fetchOccurrence(containerImage, AttestationAuthority)
+func (c Client) Attestations(ctx context.Context, containerImage string) ([]metadata.PGPAttestation, error) {
+	occs, err := c.fetchOccurrence(ctx, containerImage, AttestationAuthority)
 	if err != nil {
 		return nil, err
 	}
@@ -168,12 +178,12 @@ func (c Client) Attestations(containerImage string) ([]metadata.PGPAttestation,
 }
 
 // OccurencesV1 gets V1 Occurrences for a specified image.
-func (c Client) OccurencesV1(containerImage string) ([]*metadata.OccurenceV1, error) {
+func (c Client) OccurencesV1(ctx context.Context, containerImage string) ([]*metadata.OccurenceV1, error) {
 	return nil, errors.New("not implemented")
 }
 
 // CreateAttestationNote creates an attestation note from AttestationAuthority
-func (c Client) CreateAttestationNote(aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+func (c Client) CreateAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
 	aaNote := &attestation.Authority{
 		Hint: &attestation.Authority_Hint{
 			HumanReadableName: aa.Name,
@@ -193,42 +203,29 @@ func (c Client) CreateAttestationNote(aa *kritisv1beta1.AttestationAuthority) (*
 		NoteId: aa.Name,
 		Parent: fmt.Sprintf("projects/%s", DefaultProject),
 	}
-	return c.client.CreateNote(c.ctx, req)
+	return c.client.CreateNote(ctx, req)
 }
 
 // AttestationNote returns a note if it exists for given AttestationAuthority
-func (c Client) AttestationNote(aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+func (c Client) AttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
 	req := &grafeas.GetNoteRequest{
 		Name: fmt.Sprintf("projects/%s/notes/%s", DefaultProject, aa.Name),
 	}
-	return c.client.GetNote(c.ctx, req)
+	return c.client.GetNote(ctx, req)
 }
 
 // CreateAttestationOccurence creates an Attestation occurrence for a given image and secret.
-func (c Client) CreateAttestationOccurence(note *grafeas.Note,
+func (c Client) CreateAttestationOccurence(ctx context.Context, note *grafeas.Note,
 	containerImage string,
 	pgpSigningKey *secrets.PGPSigningSecret) (*grafeas.Occurrence, error) {
-	fingerprint := util.GetAttestationKeyFingerprint(pgpSigningKey)
-
 	// Create Attestation Signature
-	sig, err := util.CreateAttestationSignature(containerImage, pgpSigningKey)
+	a, err := util.CreateAttestation(ctx, containerImage, pgpSigningKey)
 	if err != nil {
 		return nil, err
 	}
-	pgpSignedAttestation := &attestation.PgpSignedAttestation{
-		Signature: sig,
-		KeyId: &attestation.PgpSignedAttestation_PgpKeyId{
-			PgpKeyId: fingerprint,
-		},
-		ContentType: attestation.PgpSignedAttestation_SIMPLE_SIGNING_JSON,
-	}
-
 	attestationDetails := &grafeas.Occurrence_Attestation{
 		Attestation: &attestation.Details{
-			Attestation: &attestation.Attestation{
-				Signature: &attestation.Attestation_PgpSignedAttestation{
-					PgpSignedAttestation: pgpSignedAttestation,
-				}},
+			Attestation: a,
 		},
 	}
 	occ := &grafeas.Occurrence{
@@ -241,13 +238,40 @@ func (c Client) CreateAttestationOccurence(note *grafeas.Note,
 		Occurrence: occ,
 		Parent:     fmt.Sprintf("projects/%s", DefaultProject),
 	}
-	return c.client.CreateOccurrence(c.ctx, req)
+	return c.client.CreateOccurrence(ctx, req)
+}
+
+// DiscoveryNote returns the Discovery note if it exists.
+func (c Client) DiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	req := &grafeas.GetNoteRequest{
+		Name: fmt.Sprintf("projects/%s/notes/%s", DefaultProject, noteID),
+	}
+	return c.client.GetNote(ctx, req)
+}
+
+// CreateDiscoveryNote creates a Discovery note.
+func (c Client) CreateDiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	req := &grafeas.CreateNoteRequest{
+		Note:   util.NewDiscoveryNote(DefaultProject, noteID),
+		NoteId: noteID,
+		Parent: fmt.Sprintf("projects/%s", DefaultProject),
+	}
+	return c.client.CreateNote(ctx, req)
+}
+
+// CreateDiscoveryOccurrence creates a Discovery occurrence with message for a given image.
+func (c Client) CreateDiscoveryOccurrence(ctx context.Context, note *grafeas.Note, containerImage string, message string) (*grafeas.Occurrence, error) {
+	req := &grafeas.CreateOccurrenceRequest{
+		Occurrence: util.NewDiscoveryOccurrence(note, containerImage, message),
+		Parent:     fmt.Sprintf("projects/%s", DefaultProject),
+	}
+	return c.client.CreateOccurrence(ctx, req)
 }
 
 // Builds gets Build Occurrences for a specified image.
-func (c Client) Builds(containerImage string) ([]metadata.Build, error) {
+func (c Client) Builds(ctx context.Context, containerImage string) ([]metadata.Build, error) {
 	glog.Infof("getttig build occurrences for %s", containerImage)
-	occs, err := c.fetchOccurrence(containerImage, "BUILD")
+	occs, err := c.fetchOccurrence(ctx, containerImage, "BUILD")
 	if err != nil {
 		return nil, err
 	}
@@ -261,7 +285,7 @@ func (c Client) Builds(containerImage string) ([]metadata.Build, error) {
 	return builds, nil
 }
 
-func (c Client) fetchOccurrence(containerImage string, kind string) ([]*grafeas.Occurrence, error) {
+func (c Client) fetchOccurrence(ctx context.Context, containerImage string, kind string) ([]*grafeas.Occurrence, error) {
 	req := &grafeas.ListOccurrencesRequest{
 		Filter:   fmt.Sprintf("resource_url=%q AND kind=%q", util.GetResourceURL(containerImage), kind),
 		PageSize: constants.PageSize,
@@ -271,7 +295,7 @@ func (c Client) fetchOccurrence(containerImage string, kind string) ([]*grafeas.
 	var nextPageToken string
 	for {
 		req.PageToken = nextPageToken
-		resp, err := c.client.ListOccurrences(c.ctx, req)
+		resp, err := c.client.ListOccurrences(ctx, req)
 		if err != nil {
 			return nil, err
 		}
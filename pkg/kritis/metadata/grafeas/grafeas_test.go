@@ -155,10 +155,10 @@ func TestCreateAttestationNoteAndOccurrence(t *testing.T) {
 			Name: "note1",
 		},
 	}
-	if _, err := client.CreateAttestationNote(aa); err != nil {
+	if _, err := client.CreateAttestationNote(context.Background(), aa); err != nil {
 		t.Fatalf("Unexpected error while creating Note %v", err)
 	}
-	note, err := client.AttestationNote(aa)
+	note, err := client.AttestationNote(context.Background(), aa)
 	if err != nil {
 		t.Fatalf("Unexpected no error while getting attestation note %v", err)
 	}
@@ -180,7 +180,7 @@ func TestCreateAttestationNoteAndOccurrence(t *testing.T) {
 		PgpKey:     pgpKey,
 		SecretName: "test",
 	}
-	occ, err := client.CreateAttestationOccurence(note, testutil.IntTestImage, secret)
+	occ, err := client.CreateAttestationOccurence(context.Background(), note, testutil.IntTestImage, secret)
 	if err != nil {
 		t.Fatalf("Unexpected error while creating Occurence %v", err)
 	}
@@ -193,7 +193,7 @@ func TestCreateAttestationNoteAndOccurrence(t *testing.T) {
 	if pgpKeyID != expectedPgpKeyID {
 		t.Errorf("Expected PGP key id: %q, got %q", expectedPgpKeyID, pgpKeyID)
 	}
-	occurrences, err := client.Attestations(testutil.IntTestImage)
+	occurrences, err := client.Attestations(context.Background(), testutil.IntTestImage)
 	if err != nil {
 		t.Fatalf("Unexpected error while listing Occ %v", err)
 	}
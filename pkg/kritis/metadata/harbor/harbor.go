@@ -0,0 +1,361 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package harbor implements a metadata.Fetcher reading the scan reports and
+// the cosign signatures of images stored in Harbor registries.
+package harbor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/severity"
+)
+
+const (
+	// reportMimeType is the mime type of the vulnerability reports read from Harbor.
+	reportMimeType = "application/vnd.security.vulnerability.report; version=1.1"
+	// cosignAccessory is the accessory type of cosign signatures.
+	cosignAccessory = "signature.cosign"
+	// signatureAnnotation holds the base64 encoded signature of a cosign signature layer.
+	signatureAnnotation = "dev.cosignproject.cosign/signature"
+	// FingerprintAnnotation may hold the fingerprint of the PGP key which signed a cosign signature layer.
+	FingerprintAnnotation = "dev.kritis.grafeas.io/key-fingerprint"
+)
+
+// For testing
+var (
+	fetchCredentials = secrets.FetchBasicAuth
+	signatureImage   = func(ref name.Reference, auth authn.Authenticator) (v1.Image, error) {
+		return remote.Image(ref, remote.WithAuth(auth))
+	}
+)
+
+// registry is a Harbor registry with the robot account used to query it.
+type registry struct {
+	url  string
+	auth *secrets.BasicAuth
+}
+
+// Client implements the Fetcher interface using Harbor scan reports and cosign signatures.
+// Harbor only stores attestations pushed by signing tools, creating them is not supported.
+type Client struct {
+	client     *http.Client
+	registries map[string]registry
+}
+
+// New returns a Client for the configured registries, reading their robot account credentials.
+func New(specs []kritisv1beta1.HarborRegistrySpec) (*Client, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no Harbor registries configured")
+	}
+	c := &Client{
+		client:     http.DefaultClient,
+		registries: map[string]registry{},
+	}
+	for _, spec := range specs {
+		parts := strings.SplitN(spec.CredentialsSecret, "/", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid credentials secret %q of Harbor registry %s, expected namespace/name", spec.CredentialsSecret, spec.Host)
+		}
+		auth, err := fetchCredentials(parts[0], parts[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get credentials of Harbor registry %s", spec.Host)
+		}
+		u := spec.URL
+		if u == "" {
+			u = "https://" + spec.Host
+		}
+		c.registries[spec.Host] = registry{
+			url:  strings.TrimSuffix(u, "/"),
+			auth: auth,
+		}
+	}
+	return c, nil
+}
+
+// Close closes connection
+func (c *Client) Close() {
+	// No Ops
+}
+
+// artifact is an image in a Harbor registry.
+type artifact struct {
+	registry registry
+	ref      name.Reference
+	project  string
+	repo     string
+	version  string
+}
+
+func (c *Client) artifact(containerImage string) (*artifact, error) {
+	ref, err := reference.ParseReference(containerImage)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid image %s", containerImage)
+	}
+	r, ok := c.registries[ref.Context().RegistryStr()]
+	if !ok {
+		return nil, fmt.Errorf("%s is not stored in a configured Harbor registry", containerImage)
+	}
+	parts := strings.SplitN(ref.Context().RepositoryStr(), "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%s has no Harbor project", containerImage)
+	}
+	return &artifact{
+		registry: r,
+		ref:      ref,
+		project:  parts[0],
+		repo:     parts[1],
+		version:  ref.Identifier(),
+	}, nil
+}
+
+// path returns the API path of the artifact, repositories are encoded twice as required by Harbor.
+func (a *artifact) path(suffix string) string {
+	return fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts/%s%s",
+		a.registry.url, url.PathEscape(a.project), url.PathEscape(url.PathEscape(a.repo)), url.PathEscape(a.version), suffix)
+}
+
+func (c *Client) get(ctx context.Context, a *artifact, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(a.registry.auth.Username, a.registry.auth.Password)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+type report struct {
+	Vulnerabilities []struct {
+		ID         string `json:"id"`
+		Package    string `json:"package"`
+		Version    string `json:"version"`
+		Severity   string `json:"severity"`
+		FixVersion string `json:"fix_version"`
+		CVSS       struct {
+			ScoreV3  float64 `json:"score_v3"`
+			VectorV3 string  `json:"vector_v3"`
+			VectorV2 string  `json:"vector_v2"`
+		} `json:"preferred_cvss"`
+	} `json:"vulnerabilities"`
+}
+
+// Vulnerabilities gets the vulnerabilities of the scan report of a Harbor image.
+// An error is returned if the image has not been scanned yet.
+func (c *Client) Vulnerabilities(ctx context.Context, containerImage string) ([]metadata.Vulnerability, error) {
+	a, err := c.artifact(containerImage)
+	if err != nil {
+		return nil, err
+	}
+	reports := map[string]report{}
+	if err := c.get(ctx, a, a.path("/additions/vulnerabilities"), &reports); err != nil {
+		return nil, errors.Wrapf(err, "failed to get scan report of %s", containerImage)
+	}
+	r, ok := reports[reportMimeType]
+	if !ok {
+		return nil, fmt.Errorf("%s has not been scanned", containerImage)
+	}
+	vulnz := []metadata.Vulnerability{}
+	for _, v := range r.Vulnerabilities {
+		vulnz = append(vulnz, metadata.Vulnerability{
+			CVE:             v.ID,
+			Package:         v.Package,
+			Version:         v.Version,
+			Severity:        severity.Normalize(v.Severity),
+			HasFixAvailable: v.FixVersion != "",
+			FixedVersion:    v.FixVersion,
+			CVSSVector:      cvssVector(v.CVSS.VectorV3, v.CVSS.VectorV2),
+			CVSSScore:       v.CVSS.ScoreV3,
+		})
+	}
+	return vulnz, nil
+}
+
+// cvssVector returns the CVSS v3 vector, or the v2 vector of older scanners.
+func cvssVector(v3, v2 string) string {
+	if v3 != "" {
+		return v3
+	}
+	return v2
+}
+
+// Attestations gets the PGP signatures stored as cosign signatures of the image.
+// Signatures made with other keys can not be verified by Kritis and are skipped.
+func (c *Client) Attestations(ctx context.Context, containerImage string) ([]metadata.PGPAttestation, error) {
+	a, err := c.artifact(containerImage)
+	if err != nil {
+		return nil, err
+	}
+	var art struct {
+		Accessories []struct {
+			Type   string `json:"type"`
+			Digest string `json:"digest"`
+		} `json:"accessories"`
+	}
+	if err := c.get(ctx, a, a.path("?with_accessory=true"), &art); err != nil {
+		return nil, errors.Wrapf(err, "failed to get signatures of %s", containerImage)
+	}
+	auth := &authn.Basic{Username: a.registry.auth.Username, Password: a.registry.auth.Password}
+	atts := []metadata.PGPAttestation{}
+	for _, acc := range art.Accessories {
+		if acc.Type != cosignAccessory {
+			continue
+		}
+		ref, err := reference.ParseDigest(a.ref.Context().Name() + "@" + acc.Digest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid signature of %s", containerImage)
+		}
+		img, err := signatureImage(ref, auth)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get signature %s", ref)
+		}
+		m, err := img.Manifest()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get signature %s", ref)
+		}
+		for _, l := range m.Layers {
+			att, ok := pgpAttestation(l.Annotations)
+			if !ok {
+				glog.V(2).Infof("skipping signature of %s which is not PGP signed: %s", containerImage, l.Digest)
+				continue
+			}
+			att.OccID = acc.Digest
+			atts = append(atts, att)
+		}
+	}
+	return atts, nil
+}
+
+// pgpAttestation returns the attestation of a signature layer if it is an armored PGP message.
+// The signer is identified by the fingerprint annotation, or else by the issuer key ID.
+func pgpAttestation(annotations map[string]string) (metadata.PGPAttestation, bool) {
+	sig, err := base64.StdEncoding.DecodeString(annotations[signatureAnnotation])
+	if err != nil {
+		return metadata.PGPAttestation{}, false
+	}
+	block, err := armor.Decode(bytes.NewReader(sig))
+	if err != nil {
+		return metadata.PGPAttestation{}, false
+	}
+	keyID := annotations[FingerprintAnnotation]
+	if keyID == "" {
+		keyID = issuerKeyID(block.Body)
+	}
+	return metadata.PGPAttestation{
+		Signature: string(sig),
+		KeyID:     strings.ToUpper(keyID),
+	}, true
+}
+
+// issuerKeyID returns the ID of the key which signed a PGP message, e.g. "0123456789ABCDEF".
+func issuerKeyID(r io.Reader) string {
+	packets := packet.NewReader(r)
+	for {
+		p, err := packets.Next()
+		if err != nil {
+			return ""
+		}
+		switch p := p.(type) {
+		case *packet.OnePassSignature:
+			return fmt.Sprintf("%016X", p.KeyId)
+		case *packet.Signature:
+			if p.IssuerKeyId != nil {
+				return fmt.Sprintf("%016X", *p.IssuerKeyId)
+			}
+		case *packet.Compressed:
+			packets = packet.NewReader(p.Body)
+		}
+	}
+}
+
+// OccurencesV1 returns no occurrences, Harbor does not store them.
+func (c *Client) OccurencesV1(ctx context.Context, containerImage string) ([]*metadata.OccurenceV1, error) {
+	return nil, nil
+}
+
+// Builds returns no builds, Harbor does not store them.
+func (c *Client) Builds(ctx context.Context, containerImage string) ([]metadata.Build, error) {
+	return nil, nil
+}
+
+// AttestationNote is not supported by Harbor.
+func (c *Client) AttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	return nil, fmt.Errorf("attestation notes are not supported by the Harbor backend")
+}
+
+// CreateAttestationNote is not supported by Harbor.
+func (c *Client) CreateAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	return nil, fmt.Errorf("attestation notes are not supported by the Harbor backend")
+}
+
+// CreateAttestationOccurence is not supported by Harbor.
+func (c *Client) CreateAttestationOccurence(ctx context.Context, note *grafeas.Note,
+	containerImage string,
+	pgpSigningKey *secrets.PGPSigningSecret) (*grafeas.Occurrence, error) {
+	return nil, fmt.Errorf("creating attestations is not supported by the Harbor backend")
+}
+
+// DiscoveryNote is not supported by Harbor.
+func (c *Client) DiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	return nil, fmt.Errorf("discovery notes are not supported by the Harbor backend")
+}
+
+// CreateDiscoveryNote is not supported by Harbor.
+func (c *Client) CreateDiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	return nil, fmt.Errorf("discovery notes are not supported by the Harbor backend")
+}
+
+// CreateDiscoveryOccurrence is not supported by Harbor.
+func (c *Client) CreateDiscoveryOccurrence(ctx context.Context, note *grafeas.Note, containerImage string, message string) (*grafeas.Occurrence, error) {
+	return nil, fmt.Errorf("discovery occurrences are not supported by the Harbor backend")
+}
+
+// ScanStatus returns an unknown status, the Harbor backend doesn't report it.
+func (c *Client) ScanStatus(ctx context.Context, containerImage string) (metadata.ScanStatus, error) {
+	return metadata.ScanStatusUnknown, nil
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harbor
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	"github.com/grafeas/kritis/pkg/kritis/util"
+)
+
+const (
+	digest    = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	sigDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+)
+
+// signature is a cosign signature image, only its manifest is read.
+type signature struct {
+	v1.Image
+	manifest *v1.Manifest
+}
+
+func (s signature) Manifest() (*v1.Manifest, error) {
+	return s.manifest, nil
+}
+
+func newTestClient(handler http.HandlerFunc) (*Client, string, func()) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != "robot$kritis" || p != "token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}))
+	host := strings.TrimPrefix(s.URL, "http://")
+	c := &Client{
+		client: http.DefaultClient,
+		registries: map[string]registry{
+			host: {url: s.URL, auth: &secrets.BasicAuth{Username: "robot$kritis", Password: "token"}},
+		},
+	}
+	return c, host + "/proj/team/app@" + digest, s.Close
+}
+
+func TestVulnerabilities(t *testing.T) {
+	var tests = []struct {
+		name      string
+		response  string
+		shouldErr bool
+		expected  []metadata.Vulnerability
+	}{
+		{
+			name: "scanned",
+			response: `{"application/vnd.security.vulnerability.report; version=1.1": {"vulnerabilities": [
+				{"id": "CVE-1", "severity": "High", "fix_version": "1.2", "preferred_cvss": {"vector_v3": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}},
+				{"id": "CVE-2", "severity": "Negligible", "preferred_cvss": {"vector_v2": "AV:N/AC:L/Au:N/C:P/I:N/A:N"}},
+				{"id": "CVE-3", "severity": "Unknown"}]}}`,
+			expected: []metadata.Vulnerability{
+				{CVE: "CVE-1", Severity: "HIGH", HasFixAvailable: true, FixedVersion: "1.2", CVSSVector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"},
+				{CVE: "CVE-2", Severity: "MINIMAL", HasFixAvailable: false, CVSSVector: "AV:N/AC:L/Au:N/C:P/I:N/A:N"},
+				{CVE: "CVE-3", Severity: "SEVERITY_UNSPECIFIED", HasFixAvailable: false},
+			},
+		},
+		{
+			name:      "not scanned",
+			response:  `{}`,
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, image, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				expected := "/api/v2.0/projects/proj/repositories/team%252Fapp/artifacts/" + digest + "/additions/vulnerabilities"
+				if r.URL.EscapedPath() != expected {
+					http.NotFound(w, r)
+					return
+				}
+				fmt.Fprint(w, test.response)
+			})
+			defer done()
+			actual, err := c.Vulnerabilities(context.Background(), image)
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, actual)
+		})
+	}
+}
+
+func TestVulnerabilitiesUnknownRegistry(t *testing.T) {
+	c, _, done := newTestClient(http.NotFound)
+	defer done()
+	_, err := c.Vulnerabilities(context.Background(), "gcr.io/foo/bar@"+digest)
+	testutil.CheckError(t, true, err)
+}
+
+func TestAttestations(t *testing.T) {
+	sec, _ := testutil.CreateSecret(t, "sec")
+	c, image, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("with_accessory") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"accessories": [{"type": "signature.cosign", "digest": %q}, {"type": "sbom", "digest": %q}]}`, sigDigest, digest)
+	})
+	defer done()
+	sig, err := util.CreateAttestationSignature(image, sec)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	orig := signatureImage
+	defer func() { signatureImage = orig }()
+	signatureImage = func(ref name.Reference, auth authn.Authenticator) (v1.Image, error) {
+		if ref.Identifier() != sigDigest {
+			return nil, fmt.Errorf("unexpected signature %s", ref)
+		}
+		return signature{manifest: &v1.Manifest{
+			Layers: []v1.Descriptor{
+				{Annotations: map[string]string{signatureAnnotation: base64.StdEncoding.EncodeToString([]byte(sig))}},
+				{Annotations: map[string]string{signatureAnnotation: base64.StdEncoding.EncodeToString([]byte("ecdsa"))}},
+			},
+		}}, nil
+	}
+	fpr := sec.PgpKey.Fingerprint()
+	actual, err := c.Attestations(context.Background(), image)
+	expected := []metadata.PGPAttestation{{Signature: sig, KeyID: fpr[len(fpr)-16:], OccID: sigDigest}}
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected, actual)
+}
+
+func TestNew(t *testing.T) {
+	orig := fetchCredentials
+	defer func() { fetchCredentials = orig }()
+	fetchCredentials = func(namespace, name string) (*secrets.BasicAuth, error) {
+		if namespace != "kritis" || name != "robot" {
+			return nil, fmt.Errorf("secret %s/%s not found", namespace, name)
+		}
+		return &secrets.BasicAuth{Username: "robot$kritis", Password: "token"}, nil
+	}
+	var tests = []struct {
+		name      string
+		specs     []kritisv1beta1.HarborRegistrySpec
+		shouldErr bool
+		expected  map[string]registry
+	}{
+		{
+			name:  "default url",
+			specs: []kritisv1beta1.HarborRegistrySpec{{Host: "harbor.example.com", CredentialsSecret: "kritis/robot"}},
+			expected: map[string]registry{
+				"harbor.example.com": {url: "https://harbor.example.com", auth: &secrets.BasicAuth{Username: "robot$kritis", Password: "token"}},
+			},
+		},
+		{
+			name:  "custom url",
+			specs: []kritisv1beta1.HarborRegistrySpec{{Host: "harbor.example.com", URL: "http://harbor.local/", CredentialsSecret: "kritis/robot"}},
+			expected: map[string]registry{
+				"harbor.example.com": {url: "http://harbor.local", auth: &secrets.BasicAuth{Username: "robot$kritis", Password: "token"}},
+			},
+		},
+		{"no registries", nil, true, nil},
+		{"invalid secret", []kritisv1beta1.HarborRegistrySpec{{Host: "harbor.example.com", CredentialsSecret: "robot"}}, true, nil},
+		{"missing secret", []kritisv1beta1.HarborRegistrySpec{{Host: "harbor.example.com", CredentialsSecret: "kritis/missing"}}, true, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := New(test.specs)
+			testutil.CheckError(t, test.shouldErr, err)
+			if err == nil {
+				testutil.DeepEqual(t, test.expected, c.registries)
+			}
+		})
+	}
+}
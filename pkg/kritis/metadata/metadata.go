@@ -17,39 +17,91 @@ limitations under the License.
 package metadata
 
 import (
+	"context"
+	"time"
+
 	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
 	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/severity"
 	cav1 "google.golang.org/api/containeranalysis/v1"
 	grafeasv1beta1 "google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
 )
 
 type Fetcher interface {
 	// Vulnerabilities returns package vulnerabilities for a given image.
-	Vulnerabilities(containerImage string) ([]Vulnerability, error)
+	Vulnerabilities(ctx context.Context, containerImage string) ([]Vulnerability, error)
 	// Create Attesatation Occurrence for an image.
-	CreateAttestationOccurence(note *grafeasv1beta1.Note,
+	CreateAttestationOccurence(ctx context.Context, note *grafeasv1beta1.Note,
 		containerImage string,
 		pgpSigningKey *secrets.PGPSigningSecret) (*grafeasv1beta1.Occurrence, error)
 	//AttestationNote getches a Attestation note for an Attestation Authority.
-	AttestationNote(aa *kritisv1beta1.AttestationAuthority) (*grafeasv1beta1.Note, error)
+	AttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeasv1beta1.Note, error)
 	// Create Attestation Note for an Attestation Authority.
-	CreateAttestationNote(aa *kritisv1beta1.AttestationAuthority) (*grafeasv1beta1.Note, error)
+	CreateAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeasv1beta1.Note, error)
 	//Attestations get Attestation Occurrences for given image.
-	Attestations(containerImage string) ([]PGPAttestation, error)
+	Attestations(ctx context.Context, containerImage string) ([]PGPAttestation, error)
 	// OccurencesV1 gets V1 Occurrences for a specified image.
-	OccurencesV1(containerImage string) ([]*OccurenceV1, error)
+	OccurencesV1(ctx context.Context, containerImage string) ([]*OccurenceV1, error)
 
 	// Builds get Build Occurrences for given image.
-	Builds(containerImage string) ([]Build, error)
+	Builds(ctx context.Context, containerImage string) ([]Build, error)
+
+	// DiscoveryNote fetches the Discovery note used to record audit results for an image.
+	DiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeasv1beta1.Note, error)
+	// CreateDiscoveryNote creates the Discovery note used to record audit results for an image.
+	CreateDiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeasv1beta1.Note, error)
+	// CreateDiscoveryOccurrence records an audit message for an image as a Discovery occurrence.
+	CreateDiscoveryOccurrence(ctx context.Context, note *grafeasv1beta1.Note, containerImage string, message string) (*grafeasv1beta1.Occurrence, error)
+
+	// ScanStatus returns the status of the vulnerability scan of an image, or ScanStatusUnknown
+	// if the backend doesn't report it.
+	ScanStatus(ctx context.Context, containerImage string) (ScanStatus, error)
 
 	// Close client connection
 	Close()
 }
 
+// ScanStatus is the status of the vulnerability scan of an image.
+type ScanStatus string
+
+// Statuses of vulnerability scans, as reported by Container Analysis discovery occurrences.
+const (
+	ScanStatusUnknown     ScanStatus = ""
+	ScanStatusPending     ScanStatus = "PENDING"
+	ScanStatusScanning    ScanStatus = "SCANNING"
+	ScanStatusFinished    ScanStatus = "FINISHED_SUCCESS"
+	ScanStatusFailed      ScanStatus = "FINISHED_FAILED"
+	ScanStatusUnsupported ScanStatus = "FINISHED_UNSUPPORTED"
+)
+
+// InProgress returns true if the scan has not finished yet.
+func (s ScanStatus) InProgress() bool {
+	return s == ScanStatusPending || s == ScanStatusScanning
+}
+
 type Vulnerability struct {
 	Severity        string
 	HasFixAvailable bool
 	CVE             string
+	// Package, Version and CPEURI identify the affected package, if known.
+	Package string `json:",omitempty"`
+	Version string `json:",omitempty"`
+	CPEURI  string `json:",omitempty"`
+	// FixedVersion is the first version of the package fixing the vulnerability, if known.
+	FixedVersion string `json:",omitempty"`
+	// CVSSVector is the CVSS vector of the vulnerability, e.g. CVSS:3.1/AV:N/AC:L/..., if known.
+	CVSSVector string `json:",omitempty"`
+	// FixAvailableSince is when the fix of the vulnerability was published, if known.
+	// Times a finding was last updated, e.g. by a rescan, must not be used.
+	FixAvailableSince *time.Time `json:",omitempty"`
+	// CVSSScore is the CVSS base score of the vulnerability, if known.
+	CVSSScore float64 `json:",omitempty"`
+}
+
+// CanonicalSeverity returns the severity of v on the Grafeas scale, derived from its
+// CVSS score or vector if the scanner reported no known severity.
+func (v Vulnerability) CanonicalSeverity() string {
+	return severity.Canonical(v.Severity, v.CVSSScore, v.CVSSVector)
 }
 
 // PGPAttestation represents the Signature and the Signer Key Id from the
@@ -59,6 +111,11 @@ type PGPAttestation struct {
 	KeyID     string
 	// OccID is the occurrence ID for containeranalysis Occurrence_Attestation instance
 	OccID string
+	// Payload is the signed payload of generic signed attestations, whose Signature is
+	// then the base64 encoded signature over it.
+	Payload string `json:",omitempty"`
+	// CreateTime is when the attestation was created, if known.
+	CreateTime *time.Time `json:",omitempty"`
 }
 
 type Build struct {
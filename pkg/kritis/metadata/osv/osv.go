@@ -0,0 +1,365 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package osv enriches the vulnerabilities returned by a metadata.Fetcher with
+// the fixed versions and affected ecosystems known to OSV.dev.
+package osv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/util"
+)
+
+const (
+	// DefaultURL is the URL of the OSV API.
+	DefaultURL = "https://api.osv.dev"
+	cacheTTL   = time.Hour
+	// errorTTL is how long failed lookups are cached before being retried.
+	errorTTL = time.Minute
+	// maxCacheEntries bounds the number of lookups cached.
+	maxCacheEntries = 10000
+	// maxLookups bounds the number of concurrent requests to OSV.
+	maxLookups = 4
+)
+
+// ecosystems maps CPE vendors to the OSV ecosystems of their distributions.
+var ecosystems = map[string]string{
+	"alpine":    "Alpine",
+	"almalinux": "AlmaLinux",
+	"canonical": "Ubuntu",
+	"debian":    "Debian",
+	"redhat":    "Red Hat",
+	"rocky":     "Rocky Linux",
+}
+
+// idPrefixes are the prefixes of the OSV IDs of distribution advisories for a CVE.
+var idPrefixes = map[string]string{
+	"Debian": "DEBIAN-",
+	"Ubuntu": "UBUNTU-",
+}
+
+// For testing
+var (
+	now = time.Now
+	// entries caches OSV entries by ID across fetchers, as new fetchers are created for each review.
+	entries = newCache()
+	// background runs the lookups of the entries missing from the cache, off the review path.
+	background = func(f func()) { go f() }
+	lookups    = make(chan struct{}, maxLookups)
+)
+
+// Entry is the part of an OSV entry used for enrichment.
+type Entry struct {
+	ID       string     `json:"id"`
+	Affected []Affected `json:"affected"`
+}
+
+// Affected is a package affected by an OSV entry.
+type Affected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string `json:"type"`
+		Events []struct {
+			Introduced string `json:"introduced,omitempty"`
+			Fixed      string `json:"fixed,omitempty"`
+		} `json:"events"`
+	} `json:"ranges"`
+}
+
+// cacheEntry is the result of a lookup: an entry, nil if OSV has none, or an error.
+type cacheEntry struct {
+	entry   *Entry
+	err     error
+	expires time.Time
+}
+
+type cache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	// pending are the IDs being looked up.
+	pending map[string]bool
+}
+
+func newCache() *cache {
+	return &cache{entries: map[string]cacheEntry{}, pending: map[string]bool{}}
+}
+
+// get returns the unexpired result of the lookup of id.
+func (c *cache) get(id string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[id]
+	if !ok || !now().Before(e.expires) {
+		return cacheEntry{}, false
+	}
+	return e, true
+}
+
+// claim marks the ids as pending, and returns those which weren't already.
+func (c *cache) claim(ids []string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var claimed []string
+	for _, id := range ids {
+		if !c.pending[id] {
+			c.pending[id] = true
+			claimed = append(claimed, id)
+		}
+	}
+	return claimed
+}
+
+// put caches the result of the lookup of id. Once the cache is full, expired
+// results are dropped, then the results expiring first.
+func (c *cache) put(id string, entry *Entry, err error) {
+	ttl := cacheTTL
+	if err != nil {
+		ttl = errorTTL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, id)
+	if _, ok := c.entries[id]; !ok && len(c.entries) >= maxCacheEntries {
+		t := now()
+		oldest := ""
+		for k, e := range c.entries {
+			if !t.Before(e.expires) {
+				delete(c.entries, k)
+			} else if oldest == "" || e.expires.Before(c.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		if len(c.entries) >= maxCacheEntries {
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[id] = cacheEntry{entry: entry, err: err, expires: now().Add(ttl)}
+}
+
+// enrichingFetcher wraps a Fetcher and enriches its vulnerabilities with OSV data.
+type enrichingFetcher struct {
+	metadata.Fetcher
+	url    string
+	client *http.Client
+}
+
+// NewEnrichingFetcher returns a Fetcher which looks up the vulnerabilities of f in OSV.
+// Vulnerabilities without a fix are marked as fixed if OSV knows a version of the
+// affected package fixing its installed version. Entries are only read from a cache,
+// which is filled in the background, so reviews never wait for OSV: vulnerabilities
+// missing from the cache, or whose lookup failed, are left as is.
+func NewEnrichingFetcher(f metadata.Fetcher, config kritisv1beta1.OSVConfigSpec) metadata.Fetcher {
+	u := config.URL
+	if u == "" {
+		u = DefaultURL
+	}
+	return enrichingFetcher{
+		Fetcher: f,
+		url:     strings.TrimSuffix(u, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Vulnerabilities returns the vulnerabilities of the wrapped Fetcher, enriched with OSV data.
+func (e enrichingFetcher) Vulnerabilities(ctx context.Context, containerImage string) ([]metadata.Vulnerability, error) {
+	found, err := e.Fetcher.Vulnerabilities(ctx, containerImage)
+	if err != nil {
+		return nil, err
+	}
+	// Copy the vulnerabilities, the wrapped Fetcher may cache them.
+	vulnz := make([]metadata.Vulnerability, len(found))
+	copy(vulnz, found)
+	var missing []string
+	seen := map[string]bool{}
+	for _, v := range vulnz {
+		if v.HasFixAvailable {
+			continue
+		}
+		for _, id := range entryIDs(v) {
+			if _, ok := entries.get(id); !ok && !seen[id] {
+				seen[id] = true
+				missing = append(missing, id)
+			}
+		}
+	}
+	e.lookup(missing)
+	for i := range vulnz {
+		if vulnz[i].HasFixAvailable {
+			continue
+		}
+		enrich(&vulnz[i])
+	}
+	return vulnz, nil
+}
+
+// entryIDs returns the IDs of the OSV entries of a vulnerability, none if the
+// ecosystem or the version of its package is unknown.
+func entryIDs(v metadata.Vulnerability) []string {
+	ecosystem, _ := ecosystem(v.CPEURI)
+	if ecosystem == "" || v.Version == "" {
+		return nil
+	}
+	id := cveID(v.CVE)
+	ids := []string{id}
+	if prefix, ok := idPrefixes[ecosystem]; ok {
+		ids = append(ids, prefix+id)
+	}
+	return ids
+}
+
+// enrich sets the fixed version of v from the cached OSV entries.
+func enrich(v *metadata.Vulnerability) {
+	ecosystem, release := ecosystem(v.CPEURI)
+	for _, id := range entryIDs(*v) {
+		c, ok := entries.get(id)
+		if !ok || c.entry == nil {
+			continue
+		}
+		if fixed := fixedVersion(c.entry, v.Package, v.Version, ecosystem, release); fixed != "" {
+			v.HasFixAvailable = true
+			v.FixedVersion = fixed
+			return
+		}
+	}
+}
+
+// lookup fetches the entries with the given IDs in the background, at most
+// maxLookups at a time. IDs already being fetched are skipped.
+func (e enrichingFetcher) lookup(ids []string) {
+	ids = entries.claim(ids)
+	if len(ids) == 0 {
+		return
+	}
+	background(func() {
+		var wg sync.WaitGroup
+		for _, id := range ids {
+			lookups <- struct{}{}
+			wg.Add(1)
+			go func(id string) {
+				defer func() {
+					<-lookups
+					wg.Done()
+				}()
+				entry, err := e.fetch(id)
+				if err != nil {
+					glog.Warningf("failed to look up %s in OSV: %v", id, err)
+				}
+				entries.put(id, entry, err)
+			}(id)
+		}
+		wg.Wait()
+	})
+}
+
+// fetch returns the OSV entry with the given ID, or nil if there is none.
+func (e enrichingFetcher) fetch(id string) (*Entry, error) {
+	resp, err := e.client.Get(fmt.Sprintf("%s/v1/vulns/%s", e.url, url.PathEscape(id)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		entry := &Entry{}
+		if err := json.NewDecoder(resp.Body).Decode(entry); err != nil {
+			return nil, err
+		}
+		return entry, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+}
+
+// fixedVersion returns the version fixing the installed version of the package
+// in the entry, if any. Affected packages must be of the ecosystem of the image,
+// and of its release if both are known.
+func fixedVersion(entry *Entry, pkg, installed, ecosystem, release string) string {
+	for _, a := range entry.Affected {
+		// Ecosystems may have a release suffix, e.g. "Debian:11" or "Alpine:v3.9".
+		parts := strings.SplitN(a.Package.Ecosystem, ":", 2)
+		if parts[0] != ecosystem {
+			continue
+		}
+		if len(parts) == 2 && release != "" && !sameRelease(parts[1], release) {
+			continue
+		}
+		if pkg != "" && a.Package.Name != pkg {
+			continue
+		}
+		for _, r := range a.Ranges {
+			if r.Type != "ECOSYSTEM" && r.Type != "SEMVER" {
+				continue
+			}
+			affected := false
+			for _, ev := range r.Events {
+				if ev.Introduced != "" && (ev.Introduced == "0" || util.CompareVersions(installed, ev.Introduced) >= 0) {
+					affected = true
+				}
+				if ev.Fixed != "" {
+					if affected && util.CompareVersions(installed, ev.Fixed) < 0 {
+						return ev.Fixed
+					}
+					affected = false
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// sameRelease returns true if an OSV release, e.g. "v3.9" or "18.04:LTS", is the
+// release of a CPE URI, e.g. "3.9" or "18.04".
+func sameRelease(osvRelease, release string) bool {
+	osvRelease = strings.SplitN(osvRelease, ":", 2)[0]
+	return strings.TrimPrefix(osvRelease, "v") == strings.TrimPrefix(release, "v")
+}
+
+// cveID returns the ID of a vulnerability note, e.g. "CVE-2019-1234" for
+// "projects/goog-vulnz/notes/CVE-2019-1234".
+func cveID(cve string) string {
+	return cve[strings.LastIndex(cve, "/")+1:]
+}
+
+// ecosystem returns the OSV ecosystem and the release of a CPE URI, e.g. "Debian"
+// and "9" for "cpe:/o:debian:debian_linux:9", or "" if it is unknown.
+func ecosystem(cpeURI string) (string, string) {
+	parts := strings.Split(cpeURI, ":")
+	if len(parts) < 3 {
+		return "", ""
+	}
+	release := ""
+	if len(parts) > 4 {
+		release = parts[4]
+	}
+	return ecosystems[parts[2]], release
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+var osvEntries = map[string]string{
+	"CVE-2019-1": `{"id": "CVE-2019-1", "affected": [
+		{"package": {"ecosystem": "Alpine:v3.9", "name": "openssl"}, "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.1.1b-r1"}]}]}]}`,
+	"DEBIAN-CVE-2019-1": `{"id": "DEBIAN-CVE-2019-1", "affected": [
+		{"package": {"ecosystem": "Debian:9", "name": "openssl"}, "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.1.0j-1"}]}]}]}`,
+	"CVE-2019-2": `{"id": "CVE-2019-2", "affected": [
+		{"package": {}, "ranges": [{"type": "GIT", "events": [{"introduced": "0"}, {"fixed": "abcdef"}]}]}]}`,
+}
+
+func TestVulnerabilities(t *testing.T) {
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/v1/vulns/CVE-2019-3" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		for id, e := range osvEntries {
+			if r.URL.Path == "/v1/vulns/"+id {
+				fmt.Fprint(w, e)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	defer s.Close()
+	entries = newCache()
+	// Look entries up synchronously, so the first review is enriched.
+	background = func(f func()) { f() }
+	clock := time.Now()
+	now = func() time.Time { return clock }
+	defer func() {
+		background = func(f func()) { go f() }
+		now = time.Now
+	}()
+
+	debian := "cpe:/o:debian:debian_linux:9"
+	alpine := "cpe:/o:alpine:alpine_linux:3.9"
+	mc := &testutil.MockMetadataClient{
+		Vulnz: []metadata.Vulnerability{
+			{CVE: "projects/goog-vulnz/notes/CVE-2019-1", Package: "openssl", Version: "1.1.0f-3", CPEURI: debian},
+			{CVE: "projects/goog-vulnz/notes/CVE-2019-1", Package: "openssl", Version: "1.1.1a-r0", CPEURI: alpine},
+			{CVE: "projects/goog-vulnz/notes/CVE-2019-1", Package: "openssl", Version: "1.1.1b-r1", CPEURI: alpine},
+			{CVE: "projects/goog-vulnz/notes/CVE-2019-1", Package: "openssl", CPEURI: alpine},
+			{CVE: "projects/goog-vulnz/notes/CVE-2019-1", Package: "openssl", Version: "1.1.1a-r0", CPEURI: "cpe:/o:alpine:alpine_linux:3.10"},
+			{CVE: "projects/goog-vulnz/notes/CVE-2019-1", Package: "curl", Version: "7.52.1-5", CPEURI: debian},
+			{CVE: "projects/goog-vulnz/notes/CVE-2019-1", Package: "openssl", Version: "1.1.0f-3"},
+			{CVE: "projects/goog-vulnz/notes/CVE-2019-2", Package: "openssl", Version: "1.1.0f-3", CPEURI: debian},
+			{CVE: "projects/goog-vulnz/notes/CVE-2019-3", Package: "openssl", Version: "1.1.0f-3", CPEURI: debian},
+			{CVE: "projects/goog-vulnz/notes/CVE-2019-4", HasFixAvailable: true},
+		},
+	}
+	f := NewEnrichingFetcher(mc, kritisv1beta1.OSVConfigSpec{Enabled: true, URL: s.URL})
+	expected := make([]metadata.Vulnerability, len(mc.Vulnz))
+	copy(expected, mc.Vulnz)
+	expected[0].HasFixAvailable, expected[0].FixedVersion = true, "1.1.0j-1"
+	expected[1].HasFixAvailable, expected[1].FixedVersion = true, "1.1.1b-r1"
+	actual, err := f.Vulnerabilities(context.Background(), testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected, actual)
+
+	// Entries, missing entries and errors are cached, errors only briefly
+	before := requests
+	actual, err = f.Vulnerabilities(context.Background(), testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, expected, actual)
+	testutil.DeepEqual(t, 0, requests-before)
+	clock = clock.Add(2 * errorTTL)
+	_, err = f.Vulnerabilities(context.Background(), testutil.QualifiedImage)
+	testutil.CheckError(t, false, err)
+	testutil.DeepEqual(t, 1, requests-before)
+	if mc.Vulnz[0].HasFixAvailable {
+		t.Error("expected vulnerabilities of the wrapped fetcher not to be modified")
+	}
+}
+
+func TestCacheBounded(t *testing.T) {
+	c := newCache()
+	for i := 0; i < maxCacheEntries+10; i++ {
+		c.put(fmt.Sprintf("CVE-%d", i), nil, nil)
+	}
+	testutil.DeepEqual(t, maxCacheEntries, len(c.entries))
+}
+
+func TestEcosystem(t *testing.T) {
+	var tests = []struct {
+		cpe       string
+		ecosystem string
+		release   string
+	}{
+		{"cpe:/o:debian:debian_linux:9", "Debian", "9"},
+		{"cpe:/o:canonical:ubuntu_linux:18.04", "Ubuntu", "18.04"},
+		{"cpe:/o:centos:centos:7", "", "7"},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.cpe, func(t *testing.T) {
+			ecosystem, release := ecosystem(test.cpe)
+			testutil.DeepEqual(t, test.ecosystem, ecosystem)
+			testutil.DeepEqual(t, test.release, release)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"sync"
+)
+
+// maxPrefetches bounds the calls Prefetch makes at once.
+const maxPrefetches = 8
+
+type refreshContextKey struct{}
+
+// WithRefresh returns a copy of ctx whose reads skip the results prefetched or cached
+// by Fetchers, e.g. to read again the vulnerabilities of an image being scanned.
+func WithRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshContextKey{}, true)
+}
+
+// Refreshing returns true if the reads made with ctx skip prefetched and cached results.
+func Refreshing(ctx context.Context) bool {
+	refresh, _ := ctx.Value(refreshContextKey{}).(bool)
+	return refresh
+}
+
+// prefetched holds the results of the reads of an image made by Prefetch.
+type prefetched struct {
+	vulnz    []Vulnerability
+	vulnzErr error
+	atts     []PGPAttestation
+	attsErr  error
+}
+
+// prefetchingFetcher answers the reads of the images it prefetched from their results.
+type prefetchingFetcher struct {
+	Fetcher
+	images map[string]*prefetched
+}
+
+// Prefetch gets the vulnerabilities and attestations of images from f concurrently,
+// once per distinct image, and returns a Fetcher answering these reads from their
+// results, errors included. Other calls are made to f.
+// Reviewing pods with many containers then takes about as long as their slowest image.
+func Prefetch(ctx context.Context, f Fetcher, images []string) Fetcher {
+	p := prefetchingFetcher{
+		Fetcher: f,
+		images:  map[string]*prefetched{},
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxPrefetches)
+	run := func(call func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			call()
+		}()
+	}
+	for _, image := range images {
+		if _, ok := p.images[image]; ok {
+			continue
+		}
+		image, r := image, &prefetched{}
+		p.images[image] = r
+		run(func() { r.vulnz, r.vulnzErr = f.Vulnerabilities(ctx, image) })
+		run(func() { r.atts, r.attsErr = f.Attestations(ctx, image) })
+	}
+	wg.Wait()
+	return p
+}
+
+// Vulnerabilities returns the prefetched vulnerabilities of the image.
+func (p prefetchingFetcher) Vulnerabilities(ctx context.Context, containerImage string) ([]Vulnerability, error) {
+	if r, ok := p.images[containerImage]; ok && !Refreshing(ctx) {
+		return r.vulnz, r.vulnzErr
+	}
+	return p.Fetcher.Vulnerabilities(ctx, containerImage)
+}
+
+// Attestations returns the prefetched attestations of the image.
+func (p prefetchingFetcher) Attestations(ctx context.Context, containerImage string) ([]PGPAttestation, error) {
+	if r, ok := p.images[containerImage]; ok && !Refreshing(ctx) {
+		return r.atts, r.attsErr
+	}
+	return p.Fetcher.Attestations(ctx, containerImage)
+}
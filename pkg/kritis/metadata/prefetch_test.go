@@ -0,0 +1,100 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingFetcher counts the calls per image, and the most calls made at once.
+type countingFetcher struct {
+	Fetcher
+	mu       sync.Mutex
+	calls    map[string]int
+	inFlight int
+	maxCalls int
+}
+
+func (c *countingFetcher) call(image string) {
+	c.mu.Lock()
+	c.calls[image]++
+	c.inFlight++
+	if c.inFlight > c.maxCalls {
+		c.maxCalls = c.inFlight
+	}
+	c.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+}
+
+func (c *countingFetcher) Vulnerabilities(ctx context.Context, containerImage string) ([]Vulnerability, error) {
+	c.call(containerImage)
+	if containerImage == "bad" {
+		return nil, fmt.Errorf("unavailable")
+	}
+	return []Vulnerability{{CVE: containerImage}}, nil
+}
+
+func (c *countingFetcher) Attestations(ctx context.Context, containerImage string) ([]PGPAttestation, error) {
+	c.call(containerImage)
+	return []PGPAttestation{{KeyID: containerImage}}, nil
+}
+
+func TestPrefetch(t *testing.T) {
+	c := &countingFetcher{calls: map[string]int{}}
+	f := Prefetch(context.Background(), c, []string{"a", "b", "a", "bad"})
+	if c.maxCalls < 2 {
+		t.Errorf("expected concurrent calls, got at most %d", c.maxCalls)
+	}
+	// Reads of prefetched images make no calls.
+	v, err := f.Vulnerabilities(context.Background(), "a")
+	if err != nil || !reflect.DeepEqual(v, []Vulnerability{{CVE: "a"}}) {
+		t.Errorf("unexpected vulnerabilities %v, %v", v, err)
+	}
+	if _, err := f.Vulnerabilities(context.Background(), "bad"); err == nil {
+		t.Error("expected the error of the prefetched call")
+	}
+	a, err := f.Attestations(context.Background(), "b")
+	if err != nil || !reflect.DeepEqual(a, []PGPAttestation{{KeyID: "b"}}) {
+		t.Errorf("unexpected attestations %v, %v", a, err)
+	}
+	expected := map[string]int{"a": 2, "b": 2, "bad": 2}
+	if !reflect.DeepEqual(c.calls, expected) {
+		t.Errorf("expected calls %v, got %v", expected, c.calls)
+	}
+	// Other images are read from the fetcher.
+	if _, err := f.Attestations(context.Background(), "c"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if c.calls["c"] != 1 {
+		t.Errorf("expected a call for c, got %d", c.calls["c"])
+	}
+	// Refreshing reads skip the prefetched results.
+	if _, err := f.Vulnerabilities(WithRefresh(context.Background()), "a"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if c.calls["a"] != 3 {
+		t.Errorf("expected a call for a, got %d", c.calls["a"]-2)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metrics"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+// NewRateLimiter returns a token bucket limiter allowing spec.QPS calls per second,
+// with bursts of spec.Burst calls. It returns nil if spec.QPS is not set.
+func NewRateLimiter(spec kritisv1beta1.RateLimitSpec) (*rate.Limiter, error) {
+	if spec.QPS == 0 {
+		return nil, nil
+	}
+	if spec.QPS < 0 || spec.Burst < 0 {
+		return nil, fmt.Errorf("invalid rate limit qps %v or burst %d, expected positive values", spec.QPS, spec.Burst)
+	}
+	burst := spec.Burst
+	if burst == 0 {
+		// Allow a second worth of calls at once.
+		burst = int(spec.QPS + 0.999)
+	}
+	return rate.NewLimiter(rate.Limit(spec.QPS), burst), nil
+}
+
+// rateLimitedFetcher wraps a Fetcher and waits for the limiter before each call.
+type rateLimitedFetcher struct {
+	Fetcher
+	limiter *rate.Limiter
+}
+
+// NewRateLimitedFetcher returns a Fetcher whose calls to f wait for a token of the limiter,
+// or until their context is done. Sharing the limiter between Fetchers bounds their calls
+// together. If limiter is nil, f is returned as is.
+func NewRateLimitedFetcher(f Fetcher, limiter *rate.Limiter) Fetcher {
+	if limiter == nil {
+		return f
+	}
+	return rateLimitedFetcher{
+		Fetcher: f,
+		limiter: limiter,
+	}
+}
+
+func (r rateLimitedFetcher) wait(ctx context.Context, method string) error {
+	start := time.Now()
+	if err := r.limiter.Wait(ctx); err != nil {
+		return errors.Wrapf(err, "%s was rate limited", method)
+	}
+	if waited := time.Since(start); waited > time.Millisecond {
+		metrics.MetadataRateLimitWait.AddFloat(method, waited.Seconds())
+	}
+	return nil
+}
+
+// Vulnerabilities gets vulnerabilities within the rate limit.
+func (r rateLimitedFetcher) Vulnerabilities(ctx context.Context, containerImage string) ([]Vulnerability, error) {
+	if err := r.wait(ctx, "Vulnerabilities"); err != nil {
+		return nil, err
+	}
+	return r.Fetcher.Vulnerabilities(ctx, containerImage)
+}
+
+// Attestations gets attestations within the rate limit.
+func (r rateLimitedFetcher) Attestations(ctx context.Context, containerImage string) ([]PGPAttestation, error) {
+	if err := r.wait(ctx, "Attestations"); err != nil {
+		return nil, err
+	}
+	return r.Fetcher.Attestations(ctx, containerImage)
+}
+
+// OccurencesV1 gets V1 occurrences within the rate limit.
+func (r rateLimitedFetcher) OccurencesV1(ctx context.Context, containerImage string) ([]*OccurenceV1, error) {
+	if err := r.wait(ctx, "OccurencesV1"); err != nil {
+		return nil, err
+	}
+	return r.Fetcher.OccurencesV1(ctx, containerImage)
+}
+
+// Builds gets builds within the rate limit.
+func (r rateLimitedFetcher) Builds(ctx context.Context, containerImage string) ([]Build, error) {
+	if err := r.wait(ctx, "Builds"); err != nil {
+		return nil, err
+	}
+	return r.Fetcher.Builds(ctx, containerImage)
+}
+
+// AttestationNote gets an attestation note within the rate limit.
+func (r rateLimitedFetcher) AttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	if err := r.wait(ctx, "AttestationNote"); err != nil {
+		return nil, err
+	}
+	return r.Fetcher.AttestationNote(ctx, aa)
+}
+
+// CreateAttestationNote creates an attestation note within the rate limit.
+func (r rateLimitedFetcher) CreateAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	if err := r.wait(ctx, "CreateAttestationNote"); err != nil {
+		return nil, err
+	}
+	return r.Fetcher.CreateAttestationNote(ctx, aa)
+}
+
+// CreateAttestationOccurence creates an attestation occurrence within the rate limit.
+func (r rateLimitedFetcher) CreateAttestationOccurence(ctx context.Context, note *grafeas.Note, containerImage string, pgpSigningKey *secrets.PGPSigningSecret) (*grafeas.Occurrence, error) {
+	if err := r.wait(ctx, "CreateAttestationOccurence"); err != nil {
+		return nil, err
+	}
+	return r.Fetcher.CreateAttestationOccurence(ctx, note, containerImage, pgpSigningKey)
+}
+
+// DiscoveryNote gets a discovery note within the rate limit.
+func (r rateLimitedFetcher) DiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	if err := r.wait(ctx, "DiscoveryNote"); err != nil {
+		return nil, err
+	}
+	return r.Fetcher.DiscoveryNote(ctx, containerImage, noteID)
+}
+
+// CreateDiscoveryNote creates a discovery note within the rate limit.
+func (r rateLimitedFetcher) CreateDiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	if err := r.wait(ctx, "CreateDiscoveryNote"); err != nil {
+		return nil, err
+	}
+	return r.Fetcher.CreateDiscoveryNote(ctx, containerImage, noteID)
+}
+
+// ScanStatus gets the status of the scan of an image within the rate limit.
+func (r rateLimitedFetcher) ScanStatus(ctx context.Context, containerImage string) (ScanStatus, error) {
+	if err := r.wait(ctx, "ScanStatus"); err != nil {
+		return ScanStatusUnknown, err
+	}
+	return r.Fetcher.ScanStatus(ctx, containerImage)
+}
+
+// CreateDiscoveryOccurrence creates a discovery occurrence within the rate limit.
+func (r rateLimitedFetcher) CreateDiscoveryOccurrence(ctx context.Context, note *grafeas.Note, containerImage string, message string) (*grafeas.Occurrence, error) {
+	if err := r.wait(ctx, "CreateDiscoveryOccurrence"); err != nil {
+		return nil, err
+	}
+	return r.Fetcher.CreateDiscoveryOccurrence(ctx, note, containerImage, message)
+}
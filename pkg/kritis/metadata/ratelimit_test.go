@@ -0,0 +1,74 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	tests := []struct {
+		name          string
+		spec          kritisv1beta1.RateLimitSpec
+		expectedBurst int
+		shouldErr     bool
+	}{
+		{"disabled", kritisv1beta1.RateLimitSpec{}, 0, false},
+		{"burst", kritisv1beta1.RateLimitSpec{QPS: 10, Burst: 20}, 20, false},
+		{"default burst", kritisv1beta1.RateLimitSpec{QPS: 2.5}, 3, false},
+		{"negative", kritisv1beta1.RateLimitSpec{QPS: -1}, 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := NewRateLimiter(test.spec)
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("expected error %t, got %v", test.shouldErr, err)
+			}
+			if l == nil {
+				if test.expectedBurst != 0 {
+					t.Errorf("expected a limiter with burst %d", test.expectedBurst)
+				}
+				return
+			}
+			if l.Burst() != test.expectedBurst {
+				t.Errorf("expected burst %d, got %d", test.expectedBurst, l.Burst())
+			}
+		})
+	}
+}
+
+func TestRateLimitedFetcher(t *testing.T) {
+	l, err := NewRateLimiter(kritisv1beta1.RateLimitSpec{QPS: 0.1, Burst: 1})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	// Fetchers sharing the limiter share its burst.
+	webhook := NewRateLimitedFetcher(slowFetcher{fast: true}, l)
+	cron := NewRateLimitedFetcher(slowFetcher{fast: true}, l)
+	if _, err := webhook.Vulnerabilities(context.Background(), "gcr.io/foo/bar"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := cron.Vulnerabilities(ctx, "gcr.io/foo/bar"); err == nil {
+		t.Error("expected the call to be rate limited")
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+)
+
+// skippingFetcher wraps a Fetcher and returns empty results for skipped
+// metadata kinds without calling the backend.
+type skippingFetcher struct {
+	Fetcher
+	skip map[string]bool
+}
+
+// ValidateSkipMetadataKinds returns an error naming the first of kinds which is not a known metadata kind.
+func ValidateSkipMetadataKinds(kinds []string) error {
+	for _, k := range kinds {
+		switch k {
+		case constants.VulnerabilityMetadataKind, constants.BuildMetadataKind, constants.OccurrenceV1MetadataKind:
+		default:
+			return fmt.Errorf("unsupported skipMetadataKinds %q, expected %q, %q or %q", k,
+				constants.VulnerabilityMetadataKind, constants.BuildMetadataKind, constants.OccurrenceV1MetadataKind)
+		}
+	}
+	return nil
+}
+
+// NewSkippingFetcher returns a Fetcher which never queries the wrapped Fetcher
+// for the given metadata kinds, or an error if one of them is unknown.
+// If kinds is empty, f is returned as is.
+func NewSkippingFetcher(f Fetcher, kinds []string) (Fetcher, error) {
+	if len(kinds) == 0 {
+		return f, nil
+	}
+	if err := ValidateSkipMetadataKinds(kinds); err != nil {
+		return nil, err
+	}
+	skip := map[string]bool{}
+	for _, k := range kinds {
+		skip[k] = true
+	}
+	return skippingFetcher{
+		Fetcher: f,
+		skip:    skip,
+	}, nil
+}
+
+// Vulnerabilities returns no vulnerabilities if they are skipped.
+func (s skippingFetcher) Vulnerabilities(ctx context.Context, containerImage string) ([]Vulnerability, error) {
+	if s.skip[constants.VulnerabilityMetadataKind] {
+		return nil, nil
+	}
+	return s.Fetcher.Vulnerabilities(ctx, containerImage)
+}
+
+// ScanStatus returns an unknown status if vulnerabilities are skipped.
+func (s skippingFetcher) ScanStatus(ctx context.Context, containerImage string) (ScanStatus, error) {
+	if s.skip[constants.VulnerabilityMetadataKind] {
+		return ScanStatusUnknown, nil
+	}
+	return s.Fetcher.ScanStatus(ctx, containerImage)
+}
+
+// OccurencesV1 returns no occurrences if they are skipped.
+func (s skippingFetcher) OccurencesV1(ctx context.Context, containerImage string) ([]*OccurenceV1, error) {
+	if s.skip[constants.OccurrenceV1MetadataKind] {
+		return nil, nil
+	}
+	return s.Fetcher.OccurencesV1(ctx, containerImage)
+}
+
+// Builds returns no builds if they are skipped.
+func (s skippingFetcher) Builds(ctx context.Context, containerImage string) ([]Build, error) {
+	if s.skip[constants.BuildMetadataKind] {
+		return nil, nil
+	}
+	return s.Fetcher.Builds(ctx, containerImage)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+)
+
+func TestSkippingFetcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		kinds     []string
+		expected  []Vulnerability
+		shouldErr bool
+	}{
+		{"no kinds", nil, []Vulnerability{{CVE: "CVE-1"}}, false},
+		{"vulnerabilities skipped", []string{constants.VulnerabilityMetadataKind}, nil, false},
+		{"other kinds skipped", []string{constants.BuildMetadataKind, constants.OccurrenceV1MetadataKind}, []Vulnerability{{CVE: "CVE-1"}}, false},
+		{"unknown kind", []string{constants.BuildMetadataKind, "vulnerabilty"}, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := NewSkippingFetcher(slowFetcher{fast: true}, test.kinds)
+			if test.shouldErr {
+				if err == nil || !strings.Contains(err.Error(), `"vulnerabilty"`) {
+					t.Fatalf("expected an error naming the unknown kind, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			vulnz, err := f.Vulnerabilities(context.Background(), "image")
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, vulnz) {
+				t.Errorf("expected %v, got %v", test.expected, vulnz)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metrics"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+// Fallbacks of the calls to the backend which time out
+const (
+	// TimeoutFail fails the call, and with it the review
+	TimeoutFail = "fail"
+	// TimeoutSkip returns no metadata, as for skipped metadata kinds
+	TimeoutSkip = "skip"
+)
+
+// timeoutFetcher wraps a Fetcher and bounds the duration of each call to the backend.
+type timeoutFetcher struct {
+	Fetcher
+	timeout time.Duration
+	skip    bool
+}
+
+// NewTimeoutFetcher returns a Fetcher whose calls time out after timeout, or at the
+// deadline of their context if it is earlier. With the TimeoutSkip fallback, reads
+// which time out return no metadata; otherwise, and for writes, the call fails.
+// If timeout is 0, f is returned as is.
+func NewTimeoutFetcher(f Fetcher, timeout time.Duration, fallback string) Fetcher {
+	if timeout == 0 {
+		return f
+	}
+	return timeoutFetcher{
+		Fetcher: f,
+		timeout: timeout,
+		skip:    fallback == TimeoutSkip,
+	}
+}
+
+// ValidateTimeoutFallback returns an error if fallback is not a known fallback.
+func ValidateTimeoutFallback(fallback string) error {
+	switch fallback {
+	case "", TimeoutFail, TimeoutSkip:
+		return nil
+	}
+	return fmt.Errorf("unsupported metadata timeout fallback %q, expected %q or %q", fallback, TimeoutFail, TimeoutSkip)
+}
+
+// timedOut returns true if the call to method failed because ctx expired.
+func (t timeoutFetcher) timedOut(ctx context.Context, method string, err error) bool {
+	if err == nil || ctx.Err() != context.DeadlineExceeded {
+		return false
+	}
+	metrics.MetadataTimeouts.Add(method, 1)
+	return true
+}
+
+// read returns the outcome of a read which failed with err.
+func (t timeoutFetcher) read(ctx context.Context, method string, image string, err error) (bool, error) {
+	if !t.timedOut(ctx, method, err) {
+		return false, err
+	}
+	if t.skip {
+		glog.Warningf("%s of %s timed out, skipping: %v", method, image, err)
+		return true, nil
+	}
+	return false, &Error{Method: method, Timeout: true, Err: errors.Wrapf(err, "%s of %s timed out after %s", method, image, t.timeout)}
+}
+
+// write returns the error of a write which failed with err.
+func (t timeoutFetcher) write(ctx context.Context, method string, err error) error {
+	if !t.timedOut(ctx, method, err) {
+		return err
+	}
+	return &Error{Method: method, Timeout: true, Err: errors.Wrapf(err, "%s timed out after %s", method, t.timeout)}
+}
+
+// Vulnerabilities gets vulnerabilities within the timeout.
+func (t timeoutFetcher) Vulnerabilities(ctx context.Context, containerImage string) ([]Vulnerability, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	v, err := t.Fetcher.Vulnerabilities(ctx, containerImage)
+	if skipped, err := t.read(ctx, "Vulnerabilities", containerImage, err); skipped || err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Attestations gets attestations within the timeout.
+func (t timeoutFetcher) Attestations(ctx context.Context, containerImage string) ([]PGPAttestation, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	a, err := t.Fetcher.Attestations(ctx, containerImage)
+	if skipped, err := t.read(ctx, "Attestations", containerImage, err); skipped || err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// OccurencesV1 gets V1 occurrences within the timeout.
+func (t timeoutFetcher) OccurencesV1(ctx context.Context, containerImage string) ([]*OccurenceV1, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	o, err := t.Fetcher.OccurencesV1(ctx, containerImage)
+	if skipped, err := t.read(ctx, "OccurencesV1", containerImage, err); skipped || err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Builds gets builds within the timeout.
+func (t timeoutFetcher) Builds(ctx context.Context, containerImage string) ([]Build, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	b, err := t.Fetcher.Builds(ctx, containerImage)
+	if skipped, err := t.read(ctx, "Builds", containerImage, err); skipped || err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// AttestationNote gets an attestation note within the timeout.
+func (t timeoutFetcher) AttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	n, err := t.Fetcher.AttestationNote(ctx, aa)
+	return n, t.write(ctx, "AttestationNote", err)
+}
+
+// CreateAttestationNote creates an attestation note within the timeout.
+func (t timeoutFetcher) CreateAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	n, err := t.Fetcher.CreateAttestationNote(ctx, aa)
+	return n, t.write(ctx, "CreateAttestationNote", err)
+}
+
+// CreateAttestationOccurence creates an attestation occurrence within the timeout.
+func (t timeoutFetcher) CreateAttestationOccurence(ctx context.Context, note *grafeas.Note, containerImage string, pgpSigningKey *secrets.PGPSigningSecret) (*grafeas.Occurrence, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	o, err := t.Fetcher.CreateAttestationOccurence(ctx, note, containerImage, pgpSigningKey)
+	return o, t.write(ctx, "CreateAttestationOccurence", err)
+}
+
+// DiscoveryNote gets a discovery note within the timeout.
+func (t timeoutFetcher) DiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	n, err := t.Fetcher.DiscoveryNote(ctx, containerImage, noteID)
+	return n, t.write(ctx, "DiscoveryNote", err)
+}
+
+// CreateDiscoveryNote creates a discovery note within the timeout.
+func (t timeoutFetcher) CreateDiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	n, err := t.Fetcher.CreateDiscoveryNote(ctx, containerImage, noteID)
+	return n, t.write(ctx, "CreateDiscoveryNote", err)
+}
+
+// ScanStatus gets the status of the scan of an image within the timeout.
+func (t timeoutFetcher) ScanStatus(ctx context.Context, containerImage string) (ScanStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	s, err := t.Fetcher.ScanStatus(ctx, containerImage)
+	if skipped, err := t.read(ctx, "ScanStatus", containerImage, err); skipped || err != nil {
+		return ScanStatusUnknown, err
+	}
+	return s, nil
+}
+
+// CreateDiscoveryOccurrence creates a discovery occurrence within the timeout.
+func (t timeoutFetcher) CreateDiscoveryOccurrence(ctx context.Context, note *grafeas.Note, containerImage string, message string) (*grafeas.Occurrence, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	o, err := t.Fetcher.CreateDiscoveryOccurrence(ctx, note, containerImage, message)
+	return o, t.write(ctx, "CreateDiscoveryOccurrence", err)
+}
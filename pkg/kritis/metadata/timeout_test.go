@@ -0,0 +1,94 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+)
+
+// slowFetcher returns its results once ctx is done, or immediately if fast.
+type slowFetcher struct {
+	Fetcher
+	fast bool
+}
+
+func (s slowFetcher) wait(ctx context.Context) error {
+	if s.fast {
+		return nil
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s slowFetcher) Vulnerabilities(ctx context.Context, containerImage string) ([]Vulnerability, error) {
+	return []Vulnerability{{CVE: "CVE-1"}}, s.wait(ctx)
+}
+
+func (s slowFetcher) CreateDiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	return &grafeas.Note{Name: noteID}, s.wait(ctx)
+}
+
+func TestTimeoutFetcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		fast      bool
+		fallback  string
+		expected  []Vulnerability
+		shouldErr bool
+	}{
+		{"in time", true, TimeoutFail, []Vulnerability{{CVE: "CVE-1"}}, false},
+		{"timeout fails", false, TimeoutFail, nil, true},
+		{"timeout fails by default", false, "", nil, true},
+		{"timeout skips", false, TimeoutSkip, nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := NewTimeoutFetcher(slowFetcher{fast: test.fast}, time.Millisecond, test.fallback)
+			v, err := f.Vulnerabilities(context.Background(), "gcr.io/foo/bar")
+			if (err != nil) != test.shouldErr {
+				t.Fatalf("expected error %t, got %v", test.shouldErr, err)
+			}
+			if !reflect.DeepEqual(v, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, v)
+			}
+			// Writes always fail on timeout.
+			if _, err := f.CreateDiscoveryNote(context.Background(), "gcr.io/foo/bar", "note"); (err != nil) != !test.fast {
+				t.Errorf("expected error %t creating a note, got %v", !test.fast, err)
+			}
+		})
+	}
+}
+
+func TestTimeoutFetcherDisabled(t *testing.T) {
+	f := slowFetcher{fast: true}
+	if _, ok := NewTimeoutFetcher(f, 0, TimeoutSkip).(slowFetcher); !ok {
+		t.Errorf("expected the fetcher to not be wrapped without timeout")
+	}
+}
+
+func TestValidateTimeoutFallback(t *testing.T) {
+	for fallback, shouldErr := range map[string]bool{"": false, TimeoutFail: false, TimeoutSkip: false, "allow": true} {
+		if err := ValidateTimeoutFallback(fallback); (err != nil) != shouldErr {
+			t.Errorf("%q: expected error %t, got %v", fallback, shouldErr, err)
+		}
+	}
+}
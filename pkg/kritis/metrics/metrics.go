@@ -0,0 +1,92 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics holds the counters exported by Kritis. They are published
+// with expvar and served at /debug/vars on the default mux.
+package metrics
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+var (
+	// BreakglassAdmissions counts objects admitted with a breakglass annotation, per namespace.
+	BreakglassAdmissions = expvar.NewMap("kritis_breakglass_admissions")
+
+	// ControllerReconciles counts reconciled keys, per controller.
+	ControllerReconciles = expvar.NewMap("kritis_controller_reconciles")
+	// ControllerErrors counts failed reconciliations, per controller.
+	ControllerErrors = expvar.NewMap("kritis_controller_errors")
+	// ControllerQueueDepth is the number of keys waiting in the work queue, per controller.
+	ControllerQueueDepth = expvar.NewMap("kritis_controller_queue_depth")
+
+	// NewViolations counts pods found out of policy which were compliant on their last review, per namespace.
+	NewViolations = expvar.NewMap("kritis_new_violations")
+	// ViolationStrategyErrors counts the failures of the stages of violation strategy chains, per stage.
+	ViolationStrategyErrors = expvar.NewMap("kritis_violation_strategy_errors")
+
+	// MetadataTimeouts counts the calls to the metadata backend which timed out, per method.
+	MetadataTimeouts = expvar.NewMap("kritis_metadata_timeouts")
+	// MetadataRateLimitWait is the time calls to the metadata backend waited for the rate limiter, in seconds, per method.
+	MetadataRateLimitWait = expvar.NewMap("kritis_metadata_rate_limit_wait_seconds")
+	// FailOpenAdmissions counts objects admitted because the metadata backend failed, per namespace.
+	FailOpenAdmissions = expvar.NewMap("kritis_fail_open_admissions")
+
+	// ContainerAnalysisRetries counts the calls to Container Analysis which were retried, per method.
+	ContainerAnalysisRetries = expvar.NewMap("kritis_containeranalysis_retries")
+	// ContainerAnalysisBreakerTrips counts the times the Container Analysis circuit breaker opened.
+	ContainerAnalysisBreakerTrips = expvar.NewInt("kritis_containeranalysis_breaker_trips")
+
+	// InjectedFaults counts the delays and errors injected into calls, per dependency.
+	InjectedFaults = expvar.NewMap("kritis_injected_faults")
+)
+
+var (
+	// VulnerabilityBundleVersion is the version of the loaded vulnerability bundle.
+	VulnerabilityBundleVersion = expvar.NewString("kritis_vulnerability_bundle_version")
+
+	bundleMu      sync.Mutex
+	bundleCreated time.Time
+	// For testing
+	now = time.Now
+)
+
+func init() {
+	expvar.Publish("kritis_vulnerability_bundle_age_seconds", expvar.Func(func() interface{} {
+		return VulnerabilityBundleAge().Seconds()
+	}))
+}
+
+// SetVulnerabilityBundle records the version and creation time of the loaded vulnerability bundle.
+func SetVulnerabilityBundle(version string, created time.Time) {
+	bundleMu.Lock()
+	defer bundleMu.Unlock()
+	VulnerabilityBundleVersion.Set(version)
+	bundleCreated = created
+}
+
+// VulnerabilityBundleAge returns the age of the loaded vulnerability bundle,
+// or 0 if no bundle is loaded.
+func VulnerabilityBundleAge() time.Duration {
+	bundleMu.Lock()
+	defer bundleMu.Unlock()
+	if bundleCreated.IsZero() {
+		return 0
+	}
+	return now().Sub(bundleCreated)
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notify sends summaries of policy violations to Slack, PagerDuty and
+// generic webhooks.
+package notify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+// Receiver types of NotificationSpecs
+const (
+	Slack     = "slack"
+	Webhook   = "webhook"
+	PagerDuty = "pagerduty"
+)
+
+// Keys of the secrets of NotificationSpecs
+const (
+	// URLKey holds the URL notifications are posted to, overriding the spec URL.
+	URLKey = "url"
+	// TokenKey holds the bearer token of webhooks, or the routing key of PagerDuty.
+	TokenKey = "token"
+)
+
+// For testing
+var fetchSecret = secrets.FetchData
+
+// Notification summarizes the violations found in an image.
+type Notification struct {
+	Image     string `json:"image"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod,omitempty"`
+	// Policy is the ImageSecurityPolicy violated, if known.
+	Policy     string      `json:"policy,omitempty"`
+	Violations []Violation `json:"violations"`
+}
+
+// Violation is a policy violation of a Notification.
+type Violation struct {
+	Type     string `json:"type"`
+	Reason   string `json:"reason"`
+	CVE      string `json:"cve,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	// Link is the advisory of the CVE
+	Link string `json:"link,omitempty"`
+}
+
+// NewNotification summarizes the violations of an image.
+func NewNotification(image, namespace, pod string, violations []policy.Violation) Notification {
+	n := Notification{
+		Image:     image,
+		Namespace: namespace,
+		Pod:       pod,
+	}
+	for _, v := range violations {
+		nv := Violation{
+			Type:   v.Type().ToString(),
+			Reason: string(v.Reason()),
+		}
+		if vuln, ok := v.Details().(metadata.Vulnerability); ok && vuln.CVE != "" {
+			nv.CVE = cveID(vuln.CVE)
+			nv.Severity = vuln.Severity
+			nv.Link = advisoryLink(nv.CVE)
+		}
+		n.Violations = append(n.Violations, nv)
+	}
+	return n
+}
+
+// Title returns a one line summary of the notification.
+func (n Notification) Title() string {
+	where := fmt.Sprintf("namespace %s", n.Namespace)
+	if n.Pod != "" {
+		where = fmt.Sprintf("pod %s/%s", n.Namespace, n.Pod)
+	}
+	return fmt.Sprintf("%d policy violations found in %s, used by %s", len(n.Violations), n.Image, where)
+}
+
+// cveID returns the ID of a CVE, e.g. "CVE-2017-1000082" for "providers/goog-vulnz/notes/CVE-2017-1000082".
+func cveID(cve string) string {
+	return cve[strings.LastIndex(cve, "/")+1:]
+}
+
+// advisoryLink returns the URL of the advisory of a vulnerability ID.
+func advisoryLink(id string) string {
+	switch {
+	case strings.HasPrefix(id, "CVE-"):
+		return "https://nvd.nist.gov/vuln/detail/" + id
+	case strings.HasPrefix(id, "GHSA-"):
+		return "https://github.com/advisories/" + id
+	default:
+		return "https://osv.dev/vulnerability/" + id
+	}
+}
+
+// Sender sends notifications to a receiver.
+type Sender interface {
+	Send(n Notification) error
+}
+
+// senders fans out notifications to all of its receivers.
+type senders []Sender
+
+func (s senders) Send(n Notification) error {
+	var errs []string
+	for _, sender := range s {
+		if err := sender.Send(n); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to send notifications: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// New returns a Sender fanning out notifications to the receivers of the specs,
+// reading their secrets. It returns nil if there are no specs.
+func New(specs []v1beta1.NotificationSpec) (Sender, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	var s senders
+	for _, spec := range specs {
+		url, token := spec.URL, ""
+		if spec.Secret != "" {
+			parts := strings.SplitN(spec.Secret, "/", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid secret %q of %s notifications, expected namespace/name", spec.Secret, spec.Type)
+			}
+			data, err := fetchSecret(parts[0], parts[1])
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get secret of %s notifications", spec.Type)
+			}
+			if u, ok := data[URLKey]; ok {
+				url = string(u)
+			}
+			token = string(data[TokenKey])
+		}
+		switch spec.Type {
+		case Slack:
+			if url == "" {
+				return nil, fmt.Errorf("slack notifications need the URL of an incoming webhook")
+			}
+			s = append(s, &slackSender{url: url})
+		case Webhook:
+			if url == "" {
+				return nil, fmt.Errorf("webhook notifications need a URL")
+			}
+			s = append(s, &webhookSender{url: url, token: token})
+		case PagerDuty:
+			if token == "" {
+				return nil, fmt.Errorf("pagerduty notifications need a routing key in the %q key of their secret", TokenKey)
+			}
+			if url == "" {
+				url = pagerDutyEventsURL
+			}
+			s = append(s, &pagerDutySender{url: url, routingKey: token})
+		default:
+			return nil, fmt.Errorf("unknown notification type %q", spec.Type)
+		}
+	}
+	return s, nil
+}
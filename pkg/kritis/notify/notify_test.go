@@ -0,0 +1,204 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+var violations = []policy.Violation{
+	securitypolicy.NewViolation(&metadata.Vulnerability{CVE: "providers/goog-vulnz/notes/CVE-2017-1000082", Severity: "HIGH"}, policy.SeverityViolation, "found CVE"),
+	securitypolicy.NewViolation(&metadata.Vulnerability{CVE: "GHSA-jfh8-c2jp-5v3q", Severity: "CRITICAL"}, policy.FixUnavailableViolation, "found unfixable CVE"),
+	securitypolicy.NewViolation(nil, policy.DockerHubViolation, "hosted on Docker Hub"),
+}
+
+func TestNewNotification(t *testing.T) {
+	expected := Notification{
+		Image:     testutil.QualifiedImage,
+		Namespace: "ns",
+		Pod:       "pod",
+		Violations: []Violation{
+			{Type: "SeverityViolation", Reason: "found CVE", CVE: "CVE-2017-1000082", Severity: "HIGH", Link: "https://nvd.nist.gov/vuln/detail/CVE-2017-1000082"},
+			{Type: "FixUnavailableViolation", Reason: "found unfixable CVE", CVE: "GHSA-jfh8-c2jp-5v3q", Severity: "CRITICAL", Link: "https://github.com/advisories/GHSA-jfh8-c2jp-5v3q"},
+			{Type: "DockerHubViolation", Reason: "hosted on Docker Hub"},
+		},
+	}
+	testutil.DeepEqual(t, expected, NewNotification(testutil.QualifiedImage, "ns", "pod", violations))
+}
+
+type request struct {
+	auth string
+	body map[string]interface{}
+}
+
+func receiver(t *testing.T, status int) (*httptest.Server, *[]request) {
+	var requests []request
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		req := request{auth: r.Header.Get("Authorization")}
+		if err := json.Unmarshal(b, &req.body); err != nil {
+			t.Errorf("invalid body %s: %v", b, err)
+		}
+		requests = append(requests, req)
+		w.WriteHeader(status)
+	}))
+	return s, &requests
+}
+
+func TestSend(t *testing.T) {
+	s, requests := receiver(t, http.StatusOK)
+	defer s.Close()
+	original := fetchSecret
+	defer func() { fetchSecret = original }()
+	fetchSecret = func(namespace, name string) (map[string][]byte, error) {
+		switch name {
+		case "slack":
+			return map[string][]byte{URLKey: []byte(s.URL + "/slack")}, nil
+		case "pagerduty":
+			return map[string][]byte{TokenKey: []byte("routing-key")}, nil
+		}
+		return map[string][]byte{TokenKey: []byte("bearer-token")}, nil
+	}
+	sender, err := New([]v1beta1.NotificationSpec{
+		{Type: Slack, Secret: "kritis/slack"},
+		{Type: Webhook, URL: s.URL, Secret: "kritis/webhook"},
+		{Type: PagerDuty, URL: s.URL, Secret: "kritis/pagerduty"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := NewNotification(testutil.QualifiedImage, "ns", "pod", violations)
+	testutil.CheckError(t, false, sender.Send(n))
+	if len(*requests) != 3 {
+		t.Fatalf("expected 3 requests, got %v", *requests)
+	}
+	slack, webhook, pagerduty := (*requests)[0], (*requests)[1], (*requests)[2]
+
+	text := slack.body["text"].(string)
+	if !strings.Contains(text, "3 policy violations found in "+testutil.QualifiedImage+", used by pod ns/pod") ||
+		!strings.Contains(text, "<https://nvd.nist.gov/vuln/detail/CVE-2017-1000082|CVE-2017-1000082>") {
+		t.Errorf("unexpected slack message: %s", text)
+	}
+	testutil.DeepEqual(t, "Bearer bearer-token", webhook.auth)
+	testutil.DeepEqual(t, testutil.QualifiedImage, webhook.body["image"])
+	testutil.DeepEqual(t, "routing-key", pagerduty.body["routing_key"])
+	testutil.DeepEqual(t, "ns/"+testutil.QualifiedImage, pagerduty.body["dedup_key"])
+}
+
+func TestSendFailure(t *testing.T) {
+	s, _ := receiver(t, http.StatusForbidden)
+	defer s.Close()
+	sender, err := New([]v1beta1.NotificationSpec{{Type: Webhook, URL: s.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.CheckError(t, true, sender.Send(NewNotification(testutil.QualifiedImage, "ns", "", violations)))
+}
+
+func TestNew(t *testing.T) {
+	original := fetchSecret
+	defer func() { fetchSecret = original }()
+	fetchSecret = func(namespace, name string) (map[string][]byte, error) {
+		if name == "missing" {
+			return nil, errors.New("not found")
+		}
+		return map[string][]byte{}, nil
+	}
+	var tests = []struct {
+		name      string
+		specs     []v1beta1.NotificationSpec
+		shouldErr bool
+	}{
+		{"no receivers", nil, false},
+		{"slack without url", []v1beta1.NotificationSpec{{Type: Slack}}, true},
+		{"webhook without url", []v1beta1.NotificationSpec{{Type: Webhook}}, true},
+		{"pagerduty without routing key", []v1beta1.NotificationSpec{{Type: PagerDuty, Secret: "kritis/pagerduty"}}, true},
+		{"invalid secret", []v1beta1.NotificationSpec{{Type: Webhook, URL: "https://example.com", Secret: "webhook"}}, true},
+		{"missing secret", []v1beta1.NotificationSpec{{Type: Webhook, URL: "https://example.com", Secret: "kritis/missing"}}, true},
+		{"unknown type", []v1beta1.NotificationSpec{{Type: "email", URL: "mailto:security@example.com"}}, true},
+		{"webhook", []v1beta1.NotificationSpec{{Type: Webhook, URL: "https://example.com"}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := New(test.specs)
+			testutil.CheckError(t, test.shouldErr, err)
+		})
+	}
+}
+
+type recordingSender struct {
+	sent chan Notification
+	err  error
+}
+
+func (r *recordingSender) Send(n Notification) error {
+	r.sent <- n
+	return r.err
+}
+
+func TestQueue(t *testing.T) {
+	r := &recordingSender{sent: make(chan Notification, 10)}
+	q := NewQueue(r, 10)
+	n := NewNotification(testutil.QualifiedImage, "ns", "pod", violations)
+	n.Policy = "isp"
+	testutil.CheckError(t, false, q.Send(n))
+	testutil.DeepEqual(t, n, <-r.sent)
+
+	// The same violations of another pod are not notified again
+	other := n
+	other.Pod = "other"
+	testutil.CheckError(t, false, q.Send(other))
+	// Other violations are
+	fewer := NewNotification(testutil.QualifiedImage, "ns", "pod", violations[:1])
+	fewer.Policy = "isp"
+	testutil.CheckError(t, false, q.Send(fewer))
+	testutil.DeepEqual(t, fewer, <-r.sent)
+
+	// Notifications are sent again after the dedup window
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Now().Add(DedupWindow) }
+	testutil.CheckError(t, false, q.Send(other))
+	testutil.DeepEqual(t, other, <-r.sent)
+	if nilQueue := NewQueue(nil, 1); nilQueue != nil {
+		t.Errorf("expected no queue without a sender, got %v", nilQueue)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	// Without a worker sending them, notifications wait in the queue
+	q := &queue{items: make(chan Notification, 1), sent: map[string]time.Time{}}
+	testutil.CheckError(t, false, q.Send(NewNotification("gcr.io/foo/a@sha256:0", "ns", "", violations)))
+	dropped := NewNotification("gcr.io/foo/b@sha256:0", "ns", "", violations)
+	testutil.CheckError(t, true, q.Send(dropped))
+	// Dropped notifications are not deduplicated
+	if _, ok := q.sent[dropped.key()]; ok {
+		t.Error("expected the dropped notification to be sent again")
+	}
+}
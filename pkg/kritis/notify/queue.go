@@ -0,0 +1,130 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// DefaultQueueSize is the number of notifications waiting to be sent.
+	DefaultQueueSize = 100
+	// DedupWindow is how long the same violations of an image aren't notified again.
+	DedupWindow = time.Hour
+	// maxSent bounds the number of notifications remembered for deduplication.
+	maxSent = 10000
+)
+
+// For testing
+var now = time.Now
+
+// queue sends notifications in the background, so reviews never wait for receivers.
+type queue struct {
+	sender Sender
+	items  chan Notification
+	mu     sync.Mutex
+	// sent holds when notifications were queued, by key.
+	sent map[string]time.Time
+}
+
+// NewQueue returns a Sender queueing notifications to s, which sends them in the
+// background. Notifications of the same violations of an image for the same policy
+// are only sent once per DedupWindow, and are dropped while size notifications wait.
+// It returns nil if s is nil.
+func NewQueue(s Sender, size int) Sender {
+	if s == nil {
+		return nil
+	}
+	q := &queue{
+		sender: s,
+		items:  make(chan Notification, size),
+		sent:   map[string]time.Time{},
+	}
+	go q.run()
+	return q
+}
+
+// Send queues the notification, it only returns an error if the queue is full.
+func (q *queue) Send(n Notification) error {
+	key := n.key()
+	q.mu.Lock()
+	if t, ok := q.sent[key]; ok && now().Sub(t) < DedupWindow {
+		q.mu.Unlock()
+		return nil
+	}
+	q.evict()
+	q.sent[key] = now()
+	q.mu.Unlock()
+	select {
+	case q.items <- n:
+		return nil
+	default:
+		q.forget(key)
+		return fmt.Errorf("notification queue is full, dropping the notification of %s", n.Image)
+	}
+}
+
+func (q *queue) run() {
+	for n := range q.items {
+		if err := q.sender.Send(n); err != nil {
+			glog.Errorf("failed to notify violations of %q: %v", n.Image, err)
+			// Send the notification again on the next review.
+			q.forget(n.key())
+		}
+	}
+}
+
+func (q *queue) forget(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.sent, key)
+}
+
+// evict drops the notifications sent before DedupWindow, then the oldest ones if
+// there are too many. It is called with mu held.
+func (q *queue) evict() {
+	if len(q.sent) < maxSent {
+		return
+	}
+	oldest := ""
+	for k, t := range q.sent {
+		if now().Sub(t) >= DedupWindow {
+			delete(q.sent, k)
+		} else if oldest == "" || t.Before(q.sent[oldest]) {
+			oldest = k
+		}
+	}
+	if len(q.sent) >= maxSent {
+		delete(q.sent, oldest)
+	}
+}
+
+// key identifies the image, policy and violations of a notification.
+func (n Notification) key() string {
+	var vs []string
+	for _, v := range n.Violations {
+		vs = append(vs, strings.Join([]string{v.Type, v.CVE, v.Reason}, "\x00"))
+	}
+	sort.Strings(vs)
+	return strings.Join(append([]string{n.Image, n.Namespace, n.Policy}, vs...), "\x01")
+}
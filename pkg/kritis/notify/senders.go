@@ -0,0 +1,127 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// pagerDutyEventsURL is the PagerDuty Events API v2 endpoint.
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// For testing
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// slackSender posts notifications to a Slack incoming webhook.
+type slackSender struct {
+	url string
+}
+
+func (s *slackSender) Send(n Notification) error {
+	lines := []string{fmt.Sprintf("*%s*", n.Title())}
+	for _, v := range n.Violations {
+		line := fmt.Sprintf("• %s: %s", v.Type, v.Reason)
+		if v.Link != "" {
+			line += fmt.Sprintf(" <%s|%s>", v.Link, v.CVE)
+		}
+		lines = append(lines, line)
+	}
+	return post(s.url, "", map[string]string{"text": strings.Join(lines, "\n")})
+}
+
+// webhookSender posts notifications as JSON to a URL.
+type webhookSender struct {
+	url   string
+	token string
+}
+
+func (s *webhookSender) Send(n Notification) error {
+	return post(s.url, s.token, n)
+}
+
+// pagerDutySender triggers PagerDuty incidents for notifications.
+type pagerDutySender struct {
+	url        string
+	routingKey string
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key"`
+	Payload     pagerDutyPayload `json:"payload"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string       `json:"summary"`
+	Source        string       `json:"source"`
+	Severity      string       `json:"severity"`
+	CustomDetails Notification `json:"custom_details"`
+}
+
+func (s *pagerDutySender) Send(n Notification) error {
+	summary := n.Title()
+	// PagerDuty truncates longer summaries
+	if len(summary) > 1024 {
+		summary = summary[:1024]
+	}
+	return post(s.url, "", pagerDutyEvent{
+		RoutingKey:  s.routingKey,
+		EventAction: "trigger",
+		// Violations of the same image in the same namespace are grouped in one incident
+		DedupKey: n.Namespace + "/" + n.Image,
+		Payload: pagerDutyPayload{
+			Summary:       summary,
+			Source:        n.Image,
+			Severity:      "error",
+			CustomDetails: n,
+		},
+	})
+}
+
+// post posts the body as JSON to the URL, with a bearer token if not empty.
+func post(url, token string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
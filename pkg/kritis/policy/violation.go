@@ -31,16 +31,42 @@ const (
 	BuildProjectIDViolation
 	RequiredAttestationViolation
 	ArkCISignatureViolation
+	ArkCIClaimViolation
+	TagNotPinnedViolation
+	DockerHubViolation
+	MissingSBOMViolation
+	LicenseViolation
+	DisallowedBaseImageViolation
+	ProvenanceViolation
+	DisallowedRepositoryViolation
+	UnattestedImageViolation
+	RegoViolation
+	CustomRuleViolation
+	SeverityCountViolation
+	NotScannedViolation
 )
 
 func (v ViolationType) ToString() string {
 	str := map[ViolationType]string{
-		UnqualifiedImageViolation:    "UnqualifiedImageViolation",
-		FixUnavailableViolation:      "FixUnavailableViolation",
-		SeverityViolation:            "SeverityViolation",
-		BuildProjectIDViolation:      "BuildProjectIDViolation",
-		RequiredAttestationViolation: "RequiredAttestationViolation",
-		ArkCISignatureViolation:      "ArkCISignatureViolation",
+		UnqualifiedImageViolation:     "UnqualifiedImageViolation",
+		FixUnavailableViolation:       "FixUnavailableViolation",
+		SeverityViolation:             "SeverityViolation",
+		BuildProjectIDViolation:       "BuildProjectIDViolation",
+		RequiredAttestationViolation:  "RequiredAttestationViolation",
+		ArkCISignatureViolation:       "ArkCISignatureViolation",
+		ArkCIClaimViolation:           "ArkCIClaimViolation",
+		TagNotPinnedViolation:         "TagNotPinnedViolation",
+		DockerHubViolation:            "DockerHubViolation",
+		MissingSBOMViolation:          "MissingSBOMViolation",
+		LicenseViolation:              "LicenseViolation",
+		DisallowedBaseImageViolation:  "DisallowedBaseImageViolation",
+		ProvenanceViolation:           "ProvenanceViolation",
+		DisallowedRepositoryViolation: "DisallowedRepositoryViolation",
+		UnattestedImageViolation:      "UnattestedImageViolation",
+		RegoViolation:                 "RegoViolation",
+		CustomRuleViolation:           "CustomRuleViolation",
+		SeverityCountViolation:        "SeverityCountViolation",
+		NotScannedViolation:           "NotScannedViolation",
 	}
 
 	return str[v]
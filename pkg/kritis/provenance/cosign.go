@@ -0,0 +1,135 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+	"github.com/grafeas/kritis/pkg/kritis/registryauth"
+)
+
+// dsseMediaType is the media type of the layers of cosign attestations.
+const dsseMediaType = "application/vnd.dsse.envelope.v1+json"
+
+// For testing
+var cosignImage = func(ref name.Reference) (v1.Image, error) {
+	return remote.Image(ref, remote.WithAuthFromKeychain(registryauth.Keychain()), remote.WithTransport(registryTransport))
+}
+
+// FromCosign returns the provenance attached to the image by cosign attest, following
+// the cosign convention of a "sha256-<digest>.att" tag in the image repository, or
+// stored as OCI 1.1 referrers of the image. Attestations which are not signed by one
+// of the keys are ignored.
+func FromCosign(image string, keys []Key) ([]Provenance, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	digest, imgs, err := cosignArtifacts(image, "att", cosignAttestationArtifactType, dsseMediaType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get attestations of %s", image)
+	}
+	var provs []Provenance
+	for _, img := range imgs {
+		m, err := img.Manifest()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get attestations manifest of %s", image)
+		}
+		for _, l := range m.Layers {
+			if string(l.MediaType) != dsseMediaType {
+				continue
+			}
+			b, err := layerBlob(img, l.Digest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read attestation of %s", image)
+			}
+			var e Envelope
+			if err := json.Unmarshal(b, &e); err != nil {
+				glog.Warningf("skipping attestation %s of %s: %v", l.Digest, image, err)
+				continue
+			}
+			if p := e.provenance(digest.DigestStr(), keys); p != nil {
+				provs = append(provs, *p)
+			}
+		}
+	}
+	return provs, nil
+}
+
+// cosignArtifacts returns the image digest, the image stored by cosign under the
+// "sha256-<digest>.<suffix>" tag if there is one, and the referrers of the image
+// with one of the artifact types.
+func cosignArtifacts(image, suffix string, artifactTypes ...string) (name.Digest, []v1.Image, error) {
+	digest, err := reference.ParseDigest(image)
+	if err != nil {
+		return digest, nil, errors.Wrapf(err, "%q is not referenced by digest", image)
+	}
+	tag, err := name.NewTag(fmt.Sprintf("%s:%s.%s", digest.Context().Name(), strings.Replace(digest.DigestStr(), ":", "-", 1), suffix), name.WeakValidation)
+	if err != nil {
+		return digest, nil, err
+	}
+	var imgs []v1.Image
+	img, err := cosignImage(tag)
+	switch {
+	case err == nil:
+		imgs = append(imgs, img)
+	case !notFound(err):
+		return digest, nil, err
+	}
+	refs, err := referrerImages(digest, artifactTypes...)
+	if err != nil {
+		return digest, nil, err
+	}
+	return digest, append(imgs, refs...), nil
+}
+
+// layerBlob returns the content of an artifact layer, which is stored as is.
+func layerBlob(img v1.Image, h v1.Hash) ([]byte, error) {
+	layer, err := img.LayerByDigest(h)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+func notFound(err error) bool {
+	terr, ok := err.(*transport.Error)
+	if !ok {
+		return false
+	}
+	for _, d := range terr.Errors {
+		if d.Code == transport.ManifestUnknownErrorCode || d.Code == transport.NameUnknownErrorCode {
+			return true
+		}
+	}
+	return false
+}
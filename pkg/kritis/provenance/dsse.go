@@ -0,0 +1,109 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+)
+
+// Key is a public key verifying attestation signatures.
+type Key struct {
+	crypto.PublicKey
+}
+
+// ParseKeys parses PEM encoded PKIX public keys, as written by cosign generate-key-pair.
+func ParseKeys(pems []string) ([]Key, error) {
+	var keys []Key
+	for i, p := range pems {
+		block, _ := pem.Decode([]byte(p))
+		if block == nil {
+			return nil, fmt.Errorf("public key %d is not PEM encoded", i)
+		}
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key %d: %v", i, err)
+		}
+		keys = append(keys, Key{k})
+	}
+	return keys, nil
+}
+
+// Envelope is a DSSE envelope, see https://github.com/secure-systems-lab/dsse.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a signature of a DSSE envelope.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// provenance returns the provenance signed in the envelope by one of the keys,
+// or nil if the envelope is not signed or not SLSA provenance about the image digest.
+func (e *Envelope) provenance(digest string, keys []Key) *Provenance {
+	payload, err := decodePayload(e.Payload)
+	if err != nil {
+		return nil
+	}
+	if !e.verify(payload, keys) {
+		return nil
+	}
+	return parseStatement(payload, digest)
+}
+
+// verify returns true if one of the signatures of the envelope is valid for one of the keys.
+func (e *Envelope) verify(payload []byte, keys []Key) bool {
+	pae := []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(e.PayloadType), e.PayloadType, len(payload), payload))
+	for _, s := range e.Signatures {
+		sig, err := decodePayload(s.Sig)
+		if err != nil {
+			continue
+		}
+		for _, k := range keys {
+			if verifySignature(k.PublicKey, pae, sig) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verifySignature returns true if sig is a valid signature of message by the public key,
+// using SHA-256 for ECDSA and RSA keys.
+func verifySignature(key crypto.PublicKey, message, sig []byte) bool {
+	digest := sha256.Sum256(message)
+	switch pub := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(pub, digest[:], sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil ||
+			rsa.VerifyPSS(pub, crypto.SHA256, digest[:], sig, nil) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, message, sig)
+	}
+	return false
+}
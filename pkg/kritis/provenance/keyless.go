@@ -0,0 +1,332 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+// Media type and annotations of the layers of cosign signatures.
+const (
+	simpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	signatureAnnotation    = "dev.cosignproject.cosign/signature"
+	certificateAnnotation  = "dev.sigstore.cosign/certificate"
+	chainAnnotation        = "dev.sigstore.cosign/chain"
+	bundleAnnotation       = "dev.sigstore.cosign/bundle"
+)
+
+// OIDC issuer extensions of Fulcio certificates, the first one is deprecated.
+var (
+	issuerV1OID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	issuerV2OID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Identity is the identity of the Fulcio certificate of a keyless signature.
+type Identity struct {
+	// Issuer is the OIDC issuer which authenticated the signer.
+	Issuer string
+	// Subject is the email or URI of the signer.
+	Subject string
+}
+
+// trustRoot holds the Sigstore trust roots verifying keyless signatures.
+type trustRoot struct {
+	roots         *x509.CertPool
+	intermediates []*x509.Certificate
+	rekor         []Key
+}
+
+var (
+	sigstoreMu sync.RWMutex
+	sigstore   *trustRoot
+)
+
+// ConfigureSigstore sets the trust roots verifying keyless signatures.
+// Keyless signatures are not trusted until both Fulcio roots and Rekor keys are configured.
+func ConfigureSigstore(spec v1beta1.SigstoreSpec) error {
+	root, err := newTrustRoot(spec)
+	if err != nil {
+		return err
+	}
+	sigstoreMu.Lock()
+	defer sigstoreMu.Unlock()
+	sigstore = root
+	return nil
+}
+
+func newTrustRoot(spec v1beta1.SigstoreSpec) (*trustRoot, error) {
+	if len(spec.FulcioRoots) == 0 && len(spec.RekorPublicKeys) == 0 {
+		return nil, nil
+	}
+	if len(spec.FulcioRoots) == 0 || len(spec.RekorPublicKeys) == 0 {
+		return nil, fmt.Errorf("both fulcioRoots and rekorPublicKeys must be set")
+	}
+	root := &trustRoot{roots: x509.NewCertPool()}
+	for i, p := range spec.FulcioRoots {
+		certs, err := parseCertificates([]byte(p))
+		if err != nil || len(certs) == 0 {
+			return nil, fmt.Errorf("invalid Fulcio root %d: %v", i, err)
+		}
+		for _, c := range certs {
+			if bytes.Equal(c.RawIssuer, c.RawSubject) && c.CheckSignatureFrom(c) == nil {
+				root.roots.AddCert(c)
+			} else {
+				root.intermediates = append(root.intermediates, c)
+			}
+		}
+	}
+	keys, err := ParseKeys(spec.RekorPublicKeys)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid Rekor public keys")
+	}
+	root.rekor = keys
+	return root, nil
+}
+
+// CosignIdentities returns the identities of the keyless cosign signatures of the image,
+// following the cosign convention of a "sha256-<digest>.sig" tag in the image repository,
+// or stored as OCI 1.1 referrers of the image.
+// Signatures which can not be verified with the configured trust roots are ignored.
+func CosignIdentities(image string) ([]Identity, error) {
+	sigstoreMu.RLock()
+	root := sigstore
+	sigstoreMu.RUnlock()
+	if root == nil {
+		return nil, errors.New("no Sigstore trust roots are configured")
+	}
+	digest, imgs, err := cosignArtifacts(image, "sig", cosignSignatureArtifactType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get signatures of %s", image)
+	}
+	var ids []Identity
+	for _, img := range imgs {
+		m, err := img.Manifest()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get signatures manifest of %s", image)
+		}
+		for _, l := range m.Layers {
+			if string(l.MediaType) != simpleSigningMediaType || l.Annotations[certificateAnnotation] == "" {
+				continue
+			}
+			payload, err := layerBlob(img, l.Digest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read signature of %s", image)
+			}
+			id, err := root.verify(digest.DigestStr(), payload, l.Annotations)
+			if err != nil {
+				glog.Warningf("skipping signature %s of %s: %v", l.Digest, image, err)
+				continue
+			}
+			ids = append(ids, *id)
+		}
+	}
+	return ids, nil
+}
+
+// verify verifies a keyless signature of the image digest and returns the identity of its certificate.
+func (r *trustRoot) verify(digest string, payload []byte, annotations map[string]string) (*Identity, error) {
+	sig, err := base64.StdEncoding.DecodeString(annotations[signatureAnnotation])
+	if err != nil || len(sig) == 0 {
+		return nil, fmt.Errorf("invalid signature: %v", err)
+	}
+	certs, err := parseCertificates([]byte(annotations[certificateAnnotation]))
+	if err != nil || len(certs) != 1 {
+		return nil, fmt.Errorf("invalid certificate: %v", err)
+	}
+	cert := certs[0]
+	// Fulcio certificates expire minutes after signing, they are verified at the time
+	// the signature was recorded in the transparency log.
+	signedAt, err := r.verifyBundle(annotations[bundleAnnotation], payload, sig, cert)
+	if err != nil {
+		return nil, err
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range r.intermediates {
+		intermediates.AddCert(c)
+	}
+	chain, err := parseCertificates([]byte(annotations[chainAnnotation]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate chain: %v", err)
+	}
+	for _, c := range chain {
+		intermediates.AddCert(c)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         r.roots,
+		Intermediates: intermediates,
+		CurrentTime:   signedAt,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return nil, errors.Wrap(err, "certificate is not issued by Fulcio")
+	}
+	if !verifySignature(cert.PublicKey, payload, sig) {
+		return nil, errors.New("signature is not valid")
+	}
+	var p simpleSigning
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, errors.Wrap(err, "invalid payload")
+	}
+	if p.Critical.Image.DockerManifestDigest != digest {
+		return nil, fmt.Errorf("payload signs %q", p.Critical.Image.DockerManifestDigest)
+	}
+	return certificateIdentity(cert)
+}
+
+// simpleSigning is the payload of cosign signatures.
+type simpleSigning struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// rekorBundle is the proof of inclusion of a signature in the Rekor transparency log.
+type rekorBundle struct {
+	SignedEntryTimestamp []byte       `json:"SignedEntryTimestamp"`
+	Payload              rekorPayload `json:"Payload"`
+}
+
+// rekorPayload is the Rekor log entry signed by the signed entry timestamp.
+// Its fields are sorted to marshal to canonical JSON.
+type rekorPayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// hashedRekord is the body of the Rekor entries of signatures.
+type hashedRekord struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   []byte `json:"content"`
+			PublicKey struct {
+				Content []byte `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// verifyBundle verifies the signature was recorded in the Rekor log and returns when.
+func (r *trustRoot) verifyBundle(bundle string, payload, sig []byte, cert *x509.Certificate) (time.Time, error) {
+	if bundle == "" {
+		return time.Time{}, errors.New("no Rekor bundle")
+	}
+	var b rekorBundle
+	if err := json.Unmarshal([]byte(bundle), &b); err != nil {
+		return time.Time{}, errors.Wrap(err, "invalid Rekor bundle")
+	}
+	canonical, err := json.Marshal(b.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	verified := false
+	for _, k := range r.rekor {
+		if verifySignature(k.PublicKey, canonical, b.SignedEntryTimestamp) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return time.Time{}, errors.New("Rekor bundle is not signed by a trusted log")
+	}
+	body, err := base64.StdEncoding.DecodeString(b.Payload.Body)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "invalid Rekor entry")
+	}
+	var entry hashedRekord
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return time.Time{}, errors.Wrap(err, "invalid Rekor entry")
+	}
+	h := sha256.Sum256(payload)
+	if entry.Kind != "hashedrekord" || entry.Spec.Data.Hash.Algorithm != "sha256" || entry.Spec.Data.Hash.Value != hex.EncodeToString(h[:]) {
+		return time.Time{}, errors.New("Rekor entry does not match the payload")
+	}
+	if !bytes.Equal(entry.Spec.Signature.Content, sig) {
+		return time.Time{}, errors.New("Rekor entry does not match the signature")
+	}
+	certs, err := parseCertificates(entry.Spec.Signature.PublicKey.Content)
+	if err != nil || len(certs) != 1 || !certs[0].Equal(cert) {
+		return time.Time{}, errors.New("Rekor entry does not match the certificate")
+	}
+	return time.Unix(b.Payload.IntegratedTime, 0), nil
+}
+
+// certificateIdentity returns the OIDC issuer and subject of a Fulcio certificate.
+func certificateIdentity(cert *x509.Certificate) (*Identity, error) {
+	id := &Identity{}
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(issuerV2OID):
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &id.Issuer, "utf8"); err != nil {
+				return nil, errors.Wrap(err, "invalid issuer extension")
+			}
+		case ext.Id.Equal(issuerV1OID) && id.Issuer == "":
+			id.Issuer = string(ext.Value)
+		}
+	}
+	switch {
+	case len(cert.EmailAddresses) > 0:
+		id.Subject = cert.EmailAddresses[0]
+	case len(cert.URIs) > 0:
+		id.Subject = cert.URIs[0].String()
+	}
+	if id.Issuer == "" || id.Subject == "" {
+		return nil, errors.New("certificate has no identity")
+	}
+	return id, nil
+}
+
+// parseCertificates parses a list of PEM encoded certificates.
+func parseCertificates(b []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			return certs, nil
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+const publisher = "https://github.com/my-vendor/app/.github/workflows/release.yml@refs/tags/v1.0.0"
+
+// signedAt is within the validity of the test certificates, which expired since.
+var signedAt = time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+type fulcio struct {
+	priv *ecdsa.PrivateKey
+	cert *x509.Certificate
+	pem  string
+}
+
+func newFulcio(t *testing.T) fulcio {
+	priv, _ := newKey(t)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "sigstore"},
+		NotBefore:             signedAt.Add(-time.Hour),
+		NotAfter:              signedAt.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return fulcio{priv: priv, cert: cert, pem: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))}
+}
+
+// issue returns a key and a PEM certificate for the identity.
+func (f fulcio) issue(t *testing.T, issuer, subject string) (*ecdsa.PrivateKey, string) {
+	priv, _ := newKey(t)
+	uri, _ := url.Parse(subject)
+	ext, _ := asn1.MarshalWithParams(issuer, "utf8")
+	tmpl := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       signedAt.Add(-5 * time.Minute),
+		NotAfter:        signedAt.Add(5 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		URIs:            []*url.URL{uri},
+		ExtraExtensions: []pkix.Extension{{Id: issuerV2OID, Value: ext}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, f.cert, &priv.PublicKey, f.priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// signature returns the payload and annotations of a keyless signature of the digest.
+func signature(t *testing.T, priv *ecdsa.PrivateKey, cert string, rekor *ecdsa.PrivateKey, imageDigest string) (string, map[string]string) {
+	payload := fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"gcr.io/image/digest"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, imageDigest)
+	h := sha256.Sum256([]byte(payload))
+	sig, err := ecdsa.SignASN1(rand.Reader, priv, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry hashedRekord
+	entry.Kind = "hashedrekord"
+	entry.Spec.Data.Hash.Algorithm = "sha256"
+	entry.Spec.Data.Hash.Value = hex.EncodeToString(h[:])
+	entry.Spec.Signature.Content = sig
+	entry.Spec.Signature.PublicKey.Content = []byte(cert)
+	body, _ := json.Marshal(entry)
+	b := rekorBundle{Payload: rekorPayload{
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: signedAt.Unix(),
+		LogID:          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+		LogIndex:       1,
+	}}
+	canonical, _ := json.Marshal(b.Payload)
+	ch := sha256.Sum256(canonical)
+	if b.SignedEntryTimestamp, err = ecdsa.SignASN1(rand.Reader, rekor, ch[:]); err != nil {
+		t.Fatal(err)
+	}
+	bundle, _ := json.Marshal(b)
+	return payload, map[string]string{
+		signatureAnnotation:   base64.StdEncoding.EncodeToString(sig),
+		certificateAnnotation: cert,
+		bundleAnnotation:      string(bundle),
+	}
+}
+
+type fakeSignatures struct {
+	v1.Image
+	payloads    []string
+	annotations []map[string]string
+}
+
+func (f fakeSignatures) Manifest() (*v1.Manifest, error) {
+	m := &v1.Manifest{}
+	for i := range f.payloads {
+		m.Layers = append(m.Layers, v1.Descriptor{
+			MediaType:   types.MediaType(simpleSigningMediaType),
+			Digest:      v1.Hash{Algorithm: "sha256", Hex: fmt.Sprintf("%064d", i)},
+			Annotations: f.annotations[i],
+		})
+	}
+	return m, nil
+}
+
+func (f fakeSignatures) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	var i int
+	fmt.Sscanf(h.Hex, "%d", &i)
+	return fakeLayer{content: f.payloads[i]}, nil
+}
+
+func TestConfigureSigstore(t *testing.T) {
+	_, rekorPub := newKey(t)
+	root := newFulcio(t).pem
+	tests := []struct {
+		name       string
+		spec       v1beta1.SigstoreSpec
+		shouldErr  bool
+		configured bool
+	}{
+		{name: "not configured", spec: v1beta1.SigstoreSpec{}},
+		{name: "configured", spec: v1beta1.SigstoreSpec{FulcioRoots: []string{root}, RekorPublicKeys: []string{rekorPub}}, configured: true},
+		{name: "no rekor keys", spec: v1beta1.SigstoreSpec{FulcioRoots: []string{root}}, shouldErr: true},
+		{name: "invalid root", spec: v1beta1.SigstoreSpec{FulcioRoots: []string{"root"}, RekorPublicKeys: []string{rekorPub}}, shouldErr: true},
+		{name: "invalid rekor key", spec: v1beta1.SigstoreSpec{FulcioRoots: []string{root}, RekorPublicKeys: []string{"key"}}, shouldErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := newTrustRoot(test.spec)
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.configured, r != nil)
+		})
+	}
+}
+
+func TestCosignIdentities(t *testing.T) {
+	f := newFulcio(t)
+	rekor, rekorPub := newKey(t)
+	other, _ := newKey(t)
+	issuer := "https://token.actions.githubusercontent.com"
+	priv, cert := f.issue(t, issuer, publisher)
+
+	// Only the first signature is valid, the others sign another image, are recorded
+	// in an untrusted log or are signed with a certificate not issued by Fulcio.
+	valid, validAnnotations := signature(t, priv, cert, rekor, digest)
+	otherImage, otherImageAnnotations := signature(t, priv, cert, rekor, "sha256:1111111111111111111111111111111111111111111111111111111111111111")
+	unlogged, unloggedAnnotations := signature(t, priv, cert, other, digest)
+	selfSigned, selfSignedAnnotations := signature(t, priv, newFulcio(t).pem, rekor, digest)
+
+	original, originalReferrers := cosignImage, referrersIndex
+	defer func() { cosignImage, referrersIndex = original, originalReferrers }()
+	referrersIndex = func(digest name.Digest) ([]byte, error) {
+		return nil, nil
+	}
+	defer ConfigureSigstore(v1beta1.SigstoreSpec{})
+
+	var requested string
+	cosignImage = func(ref name.Reference) (v1.Image, error) {
+		requested = ref.Name()
+		return fakeSignatures{
+			payloads:    []string{valid, otherImage, unlogged, selfSigned},
+			annotations: []map[string]string{validAnnotations, otherImageAnnotations, unloggedAnnotations, selfSignedAnnotations},
+		}, nil
+	}
+	// Signatures can not be verified without trust roots
+	_, err := CosignIdentities(testutil.QualifiedImage)
+	testutil.CheckError(t, true, err)
+
+	if err := ConfigureSigstore(v1beta1.SigstoreSpec{FulcioRoots: []string{f.pem}, RekorPublicKeys: []string{rekorPub}}); err != nil {
+		t.Fatal(err)
+	}
+	ids, err := CosignIdentities(testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, []Identity{{Issuer: issuer, Subject: publisher}}, ids)
+	testutil.DeepEqual(t, "gcr.io/image/digest:sha256-0000000000000000000000000000000000000000000000000000000000000000.sig", requested)
+
+	// Certificates issued by another authority are not trusted
+	if err := ConfigureSigstore(v1beta1.SigstoreSpec{FulcioRoots: []string{newFulcio(t).pem}, RekorPublicKeys: []string{rekorPub}}); err != nil {
+		t.Fatal(err)
+	}
+	ids, err = CosignIdentities(testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 0, len(ids))
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+// Artifact type, envelope media type and payload content type of Notary Project signatures.
+const (
+	notationSignatureArtifactType = "application/vnd.cncf.notary.signature"
+	notationJWSMediaType          = "application/jose+json"
+	notationPayloadContentType    = "application/vnd.cncf.notary.payload.v1+json"
+	notationX509SigningScheme     = "notary.x509"
+)
+
+// For testing
+var notationNow = time.Now
+
+// notationTrustStore holds the certificate authorities verifying Notary Project signatures.
+type notationTrustStore struct {
+	roots         *x509.CertPool
+	intermediates []*x509.Certificate
+}
+
+var (
+	notationMu sync.RWMutex
+	notation   *notationTrustStore
+)
+
+// ConfigureNotation sets the trust store verifying Notary Project signatures.
+// Notation signatures are not trusted until trusted certificates are configured.
+func ConfigureNotation(spec v1beta1.NotationSpec) error {
+	store, err := newNotationTrustStore(spec)
+	if err != nil {
+		return err
+	}
+	notationMu.Lock()
+	defer notationMu.Unlock()
+	notation = store
+	return nil
+}
+
+func newNotationTrustStore(spec v1beta1.NotationSpec) (*notationTrustStore, error) {
+	if len(spec.TrustedCertificates) == 0 {
+		return nil, nil
+	}
+	store := &notationTrustStore{roots: x509.NewCertPool()}
+	for i, p := range spec.TrustedCertificates {
+		certs, err := parseCertificates([]byte(p))
+		if err != nil || len(certs) == 0 {
+			return nil, fmt.Errorf("invalid notation trusted certificate %d: %v", i, err)
+		}
+		for _, c := range certs {
+			if bytes.Equal(c.RawIssuer, c.RawSubject) && c.CheckSignatureFrom(c) == nil {
+				store.roots.AddCert(c)
+			} else {
+				store.intermediates = append(store.intermediates, c)
+			}
+		}
+	}
+	return store, nil
+}
+
+// NotationIdentities returns the identities of the Notary Project signatures stored as
+// OCI 1.1 referrers of the image, as written by notation sign. The issuer and subject of
+// an identity are the distinguished names of the issuer and subject of the signing
+// certificate. Signatures which can not be verified with the trust store, or with a COSE
+// envelope, are ignored.
+func NotationIdentities(image string) ([]Identity, error) {
+	notationMu.RLock()
+	store := notation
+	notationMu.RUnlock()
+	if store == nil {
+		return nil, errors.New("no notation trusted certificates are configured")
+	}
+	digest, err := name.NewDigest(image, name.WeakValidation)
+	if err != nil {
+		return nil, err
+	}
+	imgs, err := referrerImages(digest, notationSignatureArtifactType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get notation signatures of %s", image)
+	}
+	var ids []Identity
+	for _, img := range imgs {
+		m, err := img.Manifest()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get notation signature manifest of %s", image)
+		}
+		for _, l := range m.Layers {
+			if string(l.MediaType) != notationJWSMediaType {
+				continue
+			}
+			envelope, err := layerBlob(img, l.Digest)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read notation signature of %s", image)
+			}
+			id, err := store.verify(digest.DigestStr(), envelope)
+			if err != nil {
+				glog.Warningf("skipping notation signature %s of %s: %v", l.Digest, image, err)
+				continue
+			}
+			ids = append(ids, *id)
+		}
+	}
+	return ids, nil
+}
+
+// jwsEnvelope is a JWS JSON serialized Notary Project signature envelope.
+type jwsEnvelope struct {
+	Payload   string `json:"payload"`
+	Protected string `json:"protected"`
+	Header    struct {
+		// X5C is the certificate chain of the signing key, leaf first
+		X5C [][]byte `json:"x5c"`
+	} `json:"header"`
+	Signature string `json:"signature"`
+}
+
+// jwsProtectedHeader holds the protected headers of a Notary Project signature.
+type jwsProtectedHeader struct {
+	Algorithm     string   `json:"alg"`
+	ContentType   string   `json:"cty"`
+	Critical      []string `json:"crit"`
+	SigningScheme string   `json:"io.cncf.notary.signingScheme"`
+	Expiry        string   `json:"io.cncf.notary.expiry"`
+}
+
+// notationPayload is the payload of a Notary Project signature.
+type notationPayload struct {
+	TargetArtifact struct {
+		Digest string `json:"digest"`
+	} `json:"targetArtifact"`
+}
+
+// verify verifies a JWS Notary Project signature of the image digest and returns the
+// identity of its signing certificate.
+func (s *notationTrustStore) verify(digest string, b []byte) (*Identity, error) {
+	var env jwsEnvelope
+	if err := json.Unmarshal(b, &env); err != nil {
+		return nil, errors.Wrap(err, "invalid envelope")
+	}
+	protected, err := base64.RawURLEncoding.DecodeString(env.Protected)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid protected header")
+	}
+	var header jwsProtectedHeader
+	if err := json.Unmarshal(protected, &header); err != nil {
+		return nil, errors.Wrap(err, "invalid protected header")
+	}
+	if header.ContentType != notationPayloadContentType {
+		return nil, fmt.Errorf("unsupported payload content type %q", header.ContentType)
+	}
+	if header.SigningScheme != notationX509SigningScheme {
+		return nil, fmt.Errorf("unsupported signing scheme %q", header.SigningScheme)
+	}
+	for _, c := range header.Critical {
+		switch c {
+		case "io.cncf.notary.signingScheme", "io.cncf.notary.expiry":
+		default:
+			return nil, fmt.Errorf("unsupported critical header %q", c)
+		}
+	}
+	now := notationNow()
+	if header.Expiry != "" {
+		expiry, err := time.Parse(time.RFC3339, header.Expiry)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid expiry")
+		}
+		if now.After(expiry) {
+			return nil, fmt.Errorf("signature expired at %s", header.Expiry)
+		}
+	}
+	if len(env.Header.X5C) == 0 {
+		return nil, errors.New("no certificate chain")
+	}
+	var chain []*x509.Certificate
+	for _, der := range env.Header.X5C {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid certificate chain")
+		}
+		chain = append(chain, c)
+	}
+	cert := chain[0]
+	intermediates := x509.NewCertPool()
+	for _, c := range append(s.intermediates, chain[1:]...) {
+		intermediates.AddCert(c)
+	}
+	// Without a timestamp countersignature, the chain must be valid when verified
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         s.roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return nil, errors.Wrap(err, "certificate is not issued by a trusted authority")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(env.Signature)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid signature")
+	}
+	if err := verifyJWS(header.Algorithm, cert.PublicKey, []byte(env.Protected+"."+env.Payload), sig); err != nil {
+		return nil, err
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid payload")
+	}
+	var p notationPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, errors.Wrap(err, "invalid payload")
+	}
+	if p.TargetArtifact.Digest != digest {
+		return nil, fmt.Errorf("payload signs %q", p.TargetArtifact.Digest)
+	}
+	return &Identity{Issuer: cert.Issuer.String(), Subject: cert.Subject.String()}, nil
+}
+
+// verifyJWS verifies a JWS signature of message with one of the algorithms allowed
+// by the Notary Project: RSASSA-PSS or ECDSA with SHA-256, SHA-384 or SHA-512.
+func verifyJWS(alg string, key crypto.PublicKey, message, sig []byte) error {
+	var h crypto.Hash
+	switch alg {
+	case "PS256", "ES256":
+		h = crypto.SHA256
+	case "PS384", "ES384":
+		h = crypto.SHA384
+	case "PS512", "ES512":
+		h = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", alg)
+	}
+	hasher := h.New()
+	hasher.Write(message)
+	digest := hasher.Sum(nil)
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if alg[0] == 'P' && rsa.VerifyPSS(pub, h, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		// JWS ECDSA signatures are the concatenated r and s values
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if alg[0] == 'E' && len(sig) == 2*size {
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			if ecdsa.Verify(pub, digest, r, s) {
+				return nil
+			}
+		}
+	}
+	return errors.New("signature is not valid")
+}
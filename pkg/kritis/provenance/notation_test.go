@@ -0,0 +1,178 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+// notationCA issues the signing certificates of notation signatures.
+type notationCA struct {
+	priv *ecdsa.PrivateKey
+	cert *x509.Certificate
+	pem  string
+}
+
+func newNotationCA(t *testing.T) notationCA {
+	priv, _ := newKey(t)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Vendor CA", Organization: []string{"Vendor"}},
+		NotBefore:             signedAt.Add(-time.Hour),
+		NotAfter:              signedAt.Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return notationCA{priv: priv, cert: cert, pem: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))}
+}
+
+// sign returns a JWS envelope signing the digest with a certificate issued for subject.
+func (ca notationCA) sign(t *testing.T, subject, imageDigest string) string {
+	priv, _ := newKey(t)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: subject, Organization: []string{"Vendor"}},
+		NotBefore:    signedAt.Add(-time.Hour),
+		NotAfter:     signedAt.Add(30 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &priv.PublicKey, ca.priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	protected := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","cty":"application/vnd.cncf.notary.payload.v1+json","crit":["io.cncf.notary.signingScheme"],"io.cncf.notary.signingScheme":"notary.x509","io.cncf.notary.signingTime":"2022-10-01T12:00:00Z"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"targetArtifact":{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":%q,"size":1024}}`, imageDigest)))
+	h := sha256.Sum256([]byte(protected + "." + payload))
+	r, s, err := ecdsa.Sign(rand.Reader, priv, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	env := map[string]interface{}{
+		"payload":   payload,
+		"protected": protected,
+		"header":    map[string]interface{}{"x5c": [][]byte{der}},
+		"signature": base64.RawURLEncoding.EncodeToString(sig),
+	}
+	b, _ := json.Marshal(env)
+	return string(b)
+}
+
+// fakeNotationSignature is a notation signature manifest with a JWS envelope layer.
+type fakeNotationSignature struct {
+	v1.Image
+	envelope string
+}
+
+func (f fakeNotationSignature) Manifest() (*v1.Manifest, error) {
+	return &v1.Manifest{Layers: []v1.Descriptor{{
+		MediaType: types.MediaType(notationJWSMediaType),
+		Digest:    v1.Hash{Algorithm: "sha256", Hex: fmt.Sprintf("%064d", 0)},
+	}}}, nil
+}
+
+func (f fakeNotationSignature) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	return fakeLayer{content: f.envelope}, nil
+}
+
+func TestConfigureNotation(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       v1beta1.NotationSpec
+		shouldErr  bool
+		configured bool
+	}{
+		{name: "not configured", spec: v1beta1.NotationSpec{}},
+		{name: "configured", spec: v1beta1.NotationSpec{TrustedCertificates: []string{newNotationCA(t).pem}}, configured: true},
+		{name: "invalid certificate", spec: v1beta1.NotationSpec{TrustedCertificates: []string{"root"}}, shouldErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := newNotationTrustStore(test.spec)
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.configured, s != nil)
+		})
+	}
+}
+
+func TestNotationIdentities(t *testing.T) {
+	ca := newNotationCA(t)
+	signatures := map[string]string{
+		"sha256:1111111111111111111111111111111111111111111111111111111111111111": ca.sign(t, "release", digest),
+		"sha256:2222222222222222222222222222222222222222222222222222222222222222": ca.sign(t, "other-image", "sha256:3333333333333333333333333333333333333333333333333333333333333333"),
+		"sha256:4444444444444444444444444444444444444444444444444444444444444444": newNotationCA(t).sign(t, "untrusted", digest),
+	}
+	index := `{"manifests": [
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:1111111111111111111111111111111111111111111111111111111111111111", "artifactType": "application/vnd.cncf.notary.signature"},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:2222222222222222222222222222222222222222222222222222222222222222", "artifactType": "application/vnd.cncf.notary.signature"},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:4444444444444444444444444444444444444444444444444444444444444444", "artifactType": "application/vnd.cncf.notary.signature"}
+  ]}`
+
+	original, originalReferrers, originalNow := cosignImage, referrersIndex, notationNow
+	defer func() { cosignImage, referrersIndex, notationNow = original, originalReferrers, originalNow }()
+	referrersIndex = func(digest name.Digest) ([]byte, error) {
+		return []byte(index), nil
+	}
+	cosignImage = func(ref name.Reference) (v1.Image, error) {
+		return fakeNotationSignature{envelope: signatures[ref.(name.Digest).DigestStr()]}, nil
+	}
+	notationNow = func() time.Time { return signedAt }
+	defer ConfigureNotation(v1beta1.NotationSpec{})
+
+	// Signatures can not be verified without a trust store
+	_, err := NotationIdentities(testutil.QualifiedImage)
+	testutil.CheckError(t, true, err)
+
+	// Only the first signature is valid, the others sign another image or are
+	// signed with a certificate of an untrusted authority.
+	if err := ConfigureNotation(v1beta1.NotationSpec{TrustedCertificates: []string{ca.pem}}); err != nil {
+		t.Fatal(err)
+	}
+	ids, err := NotationIdentities(testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, []Identity{{Issuer: "CN=Vendor CA,O=Vendor", Subject: "CN=release,O=Vendor"}}, ids)
+
+	// Expired certificates are not trusted
+	notationNow = func() time.Time { return signedAt.Add(60 * 24 * time.Hour) }
+	ids, err = NotationIdentities(testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 0, len(ids))
+}
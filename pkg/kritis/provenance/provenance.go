@@ -0,0 +1,247 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package provenance reads the SLSA build provenance of images, from Container
+// Analysis occurrences and from in-toto attestations signed with cosign.
+package provenance
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	cav1 "google.golang.org/api/containeranalysis/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+)
+
+// predicateTypePrefix is the prefix of the predicate types of all SLSA provenance versions.
+const predicateTypePrefix = "https://slsa.dev/provenance/"
+
+// Provenance describes how an image was built.
+type Provenance struct {
+	// BuilderID identifies the builder which generated the provenance.
+	BuilderID string
+	// SourceURIs are the URIs of the build config source and the build materials.
+	SourceURIs []string
+}
+
+// statement is the part of an in-toto statement read by Kritis.
+type statement struct {
+	PredicateType string `json:"predicateType"`
+	Subject       []struct {
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+	Predicate json.RawMessage `json:"predicate"`
+}
+
+// predicate is the part of SLSA provenance predicates read by Kritis, in any version.
+type predicate struct {
+	// SLSA v0.1 and v0.2
+	Builder struct {
+		ID string `json:"id"`
+	} `json:"builder"`
+	Invocation struct {
+		ConfigSource struct {
+			URI string `json:"uri"`
+		} `json:"configSource"`
+	} `json:"invocation"`
+	Materials []struct {
+		URI string `json:"uri"`
+	} `json:"materials"`
+	// SLSA v1
+	RunDetails struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+	} `json:"runDetails"`
+	BuildDefinition struct {
+		ResolvedDependencies []struct {
+			URI string `json:"uri"`
+		} `json:"resolvedDependencies"`
+	} `json:"buildDefinition"`
+}
+
+// FromOccurrences returns the provenance of DSSE attestation occurrences whose envelope
+// is signed by one of the keys, and of Build occurrences about the image digest.
+// If keys are given, Build occurrences are only used if their envelope is signed by
+// one of them, as anyone who can write occurrences could otherwise forge provenance.
+func FromOccurrences(occs []*metadata.OccurenceV1, digest string, keys []Key) []Provenance {
+	var provs []Provenance
+	for _, occ := range occs {
+		if occ == nil {
+			continue
+		}
+		if occ.Build != nil {
+			var p *Provenance
+			if len(keys) > 0 {
+				if occ.Envelope != nil {
+					p = newEnvelope(occ.Envelope).provenance(digest, keys)
+				}
+			} else if buildAbout(occ.Build, digest) {
+				p = buildProvenance(occ.Build)
+			}
+			if p != nil {
+				provs = append(provs, *p)
+			}
+		}
+		if occ.DsseAttestation == nil {
+			continue
+		}
+		env := occ.DsseAttestation.Envelope
+		if env == nil {
+			env = occ.Envelope
+		}
+		if env == nil {
+			continue
+		}
+		if p := newEnvelope(env).provenance(digest, keys); p != nil {
+			provs = append(provs, *p)
+		}
+	}
+	return provs
+}
+
+func newEnvelope(env *cav1.Envelope) *Envelope {
+	e := &Envelope{PayloadType: env.PayloadType, Payload: env.Payload}
+	for _, s := range env.Signatures {
+		e.Signatures = append(e.Signatures, Signature{KeyID: s.Keyid, Sig: s.Sig})
+	}
+	return e
+}
+
+// buildAbout returns true unless the in-toto statement of a Build occurrence has
+// subjects, none of which is the image digest.
+func buildAbout(b *cav1.BuildOccurrence, digest string) bool {
+	if b.IntotoStatement == nil || len(b.IntotoStatement.Subject) == 0 {
+		return true
+	}
+	algorithm, hex := splitDigest(digest)
+	for _, s := range b.IntotoStatement.Subject {
+		if s != nil && hex != "" && s.Digest[algorithm] == hex {
+			return true
+		}
+	}
+	return false
+}
+
+// buildProvenance reads the provenance of a Build occurrence, in any of its representations.
+func buildProvenance(b *cav1.BuildOccurrence) *Provenance {
+	if s := b.IntotoStatement; s != nil {
+		switch {
+		case s.SlsaProvenanceZeroTwo != nil:
+			p := &Provenance{}
+			if s.SlsaProvenanceZeroTwo.Builder != nil {
+				p.BuilderID = s.SlsaProvenanceZeroTwo.Builder.Id
+			}
+			if inv := s.SlsaProvenanceZeroTwo.Invocation; inv != nil && inv.ConfigSource != nil {
+				p.addSource(inv.ConfigSource.Uri)
+			}
+			for _, m := range s.SlsaProvenanceZeroTwo.Materials {
+				p.addSource(m.Uri)
+			}
+			return p
+		case s.SlsaProvenance != nil:
+			p := &Provenance{}
+			if s.SlsaProvenance.Builder != nil {
+				p.BuilderID = s.SlsaProvenance.Builder.Id
+			}
+			for _, m := range s.SlsaProvenance.Materials {
+				p.addSource(m.Uri)
+			}
+			return p
+		case s.Provenance != nil:
+			return intotoProvenance(s.Provenance)
+		}
+	}
+	if b.IntotoProvenance != nil {
+		return intotoProvenance(b.IntotoProvenance)
+	}
+	return nil
+}
+
+func intotoProvenance(ip *cav1.InTotoProvenance) *Provenance {
+	p := &Provenance{}
+	if ip.BuilderConfig != nil {
+		p.BuilderID = ip.BuilderConfig.Id
+	}
+	for _, m := range ip.Materials {
+		p.addSource(m)
+	}
+	return p
+}
+
+func (p *Provenance) addSource(uri string) {
+	if uri != "" {
+		p.SourceURIs = append(p.SourceURIs, uri)
+	}
+}
+
+// parseStatement reads the provenance of an in-toto statement about the image digest.
+// It returns nil if the statement is not SLSA provenance or is about another image.
+func parseStatement(b []byte, digest string) *Provenance {
+	var s statement
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil
+	}
+	if !strings.HasPrefix(s.PredicateType, predicateTypePrefix) {
+		return nil
+	}
+	algorithm, hex := splitDigest(digest)
+	about := false
+	for _, subject := range s.Subject {
+		if subject.Digest[algorithm] == hex {
+			about = true
+			break
+		}
+	}
+	if !about {
+		return nil
+	}
+	var pred predicate
+	if err := json.Unmarshal(s.Predicate, &pred); err != nil {
+		return nil
+	}
+	p := &Provenance{BuilderID: pred.Builder.ID}
+	if p.BuilderID == "" {
+		p.BuilderID = pred.RunDetails.Builder.ID
+	}
+	p.addSource(pred.Invocation.ConfigSource.URI)
+	for _, m := range pred.Materials {
+		p.addSource(m.URI)
+	}
+	for _, d := range pred.BuildDefinition.ResolvedDependencies {
+		p.addSource(d.URI)
+	}
+	return p
+}
+
+func splitDigest(digest string) (string, string) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// decodePayload decodes the base64 payload of an envelope, which may be URL encoded.
+func decodePayload(payload string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return base64.URLEncoding.DecodeString(payload)
+	}
+	return b, nil
+}
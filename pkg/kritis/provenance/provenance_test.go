@@ -0,0 +1,345 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	cav1 "google.golang.org/api/containeranalysis/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+const (
+	digest     = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	intotoType = "application/vnd.in-toto+json"
+)
+
+func statementJSON(imageDigest string) string {
+	return fmt.Sprintf(`{
+  "_type": "https://in-toto.io/Statement/v0.1",
+  "predicateType": "https://slsa.dev/provenance/v0.2",
+  "subject": [{"name": "gcr.io/image/digest", "digest": {"sha256": %q}}],
+  "predicate": {
+    "builder": {"id": "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_container_slsa3.yml@refs/tags/v1.4.0"},
+    "invocation": {"configSource": {"uri": "git+https://github.com/my-org/app@refs/heads/main"}}
+  }
+}`, strings.TrimPrefix(imageDigest, "sha256:"))
+}
+
+func newKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func sign(t *testing.T, priv *ecdsa.PrivateKey, payload string) Envelope {
+	pae := fmt.Sprintf("DSSEv1 %d %s %d %s", len(intotoType), intotoType, len(payload), payload)
+	h := sha256.Sum256([]byte(pae))
+	sig, err := ecdsa.SignASN1(rand.Reader, priv, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Envelope{
+		PayloadType: intotoType,
+		Payload:     base64.StdEncoding.EncodeToString([]byte(payload)),
+		Signatures:  []Signature{{Sig: base64.StdEncoding.EncodeToString(sig)}},
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	_, pub := newKey(t)
+	keys, err := ParseKeys([]string{pub})
+	testutil.CheckErrorAndDeepEqual(t, false, err, 1, len(keys))
+	_, err = ParseKeys([]string{"not a key"})
+	testutil.CheckError(t, true, err)
+}
+
+func TestFromOccurrences(t *testing.T) {
+	priv, pub := newKey(t)
+	keys, err := ParseKeys([]string{pub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := newKey(t)
+	signed := sign(t, priv, statementJSON(digest))
+	forged := sign(t, other, statementJSON(digest))
+	otherImage := sign(t, priv, statementJSON("sha256:1111111111111111111111111111111111111111111111111111111111111111"))
+	dsse := func(e Envelope) *metadata.OccurenceV1 {
+		return &metadata.OccurenceV1{DsseAttestation: &cav1.DSSEAttestationOccurrence{Envelope: &cav1.Envelope{
+			PayloadType: e.PayloadType,
+			Payload:     e.Payload,
+			Signatures:  []*cav1.EnvelopeSignature{{Sig: e.Signatures[0].Sig}},
+		}}}
+	}
+	envelope := func(e Envelope) *cav1.Envelope {
+		return &cav1.Envelope{
+			PayloadType: e.PayloadType,
+			Payload:     e.Payload,
+			Signatures:  []*cav1.EnvelopeSignature{{Sig: e.Signatures[0].Sig}},
+		}
+	}
+	unsigned := []*metadata.OccurenceV1{
+		{Build: &cav1.BuildOccurrence{IntotoStatement: &cav1.InTotoStatement{
+			Subject: []*cav1.Subject{{Digest: map[string]string{"sha256": strings.TrimPrefix(digest, "sha256:")}}},
+			SlsaProvenanceZeroTwo: &cav1.SlsaProvenanceZeroTwo{
+				Builder: &cav1.GrafeasV1SlsaProvenanceZeroTwoSlsaBuilder{Id: "https://cloudbuild.googleapis.com/GoogleHostedWorker"},
+				Invocation: &cav1.GrafeasV1SlsaProvenanceZeroTwoSlsaInvocation{
+					ConfigSource: &cav1.GrafeasV1SlsaProvenanceZeroTwoSlsaConfigSource{Uri: "https://github.com/my-org/app"},
+				},
+			},
+		}}},
+		{Build: &cav1.BuildOccurrence{IntotoProvenance: &cav1.InTotoProvenance{
+			BuilderConfig: &cav1.BuilderConfig{Id: "https://cloudbuild.googleapis.com/GoogleHostedWorker@v0.2"},
+			Materials:     []string{"https://github.com/my-org/lib"},
+		}}},
+		{Build: &cav1.BuildOccurrence{IntotoStatement: &cav1.InTotoStatement{
+			Subject:        []*cav1.Subject{{Digest: map[string]string{"sha256": "1111111111111111111111111111111111111111111111111111111111111111"}}},
+			SlsaProvenance: &cav1.SlsaProvenance{Builder: &cav1.SlsaBuilder{Id: "https://example.com/other-image"}},
+		}}},
+	}
+	occs := append([]*metadata.OccurenceV1{
+		{Build: &cav1.BuildOccurrence{}, Envelope: envelope(signed)},
+		{Build: &cav1.BuildOccurrence{}, Envelope: envelope(forged)},
+		dsse(signed),
+		dsse(forged),
+		dsse(otherImage),
+		{Kind: "VULNERABILITY"},
+	}, unsigned...)
+	verified := Provenance{
+		BuilderID:  "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/generator_container_slsa3.yml@refs/tags/v1.4.0",
+		SourceURIs: []string{"git+https://github.com/my-org/app@refs/heads/main"},
+	}
+	// With keys, only signed provenance is used
+	testutil.DeepEqual(t, []Provenance{verified, verified}, FromOccurrences(occs, digest, keys))
+
+	// Without keys, Build occurrences about the image are used
+	expected := []Provenance{
+		{BuilderID: "https://cloudbuild.googleapis.com/GoogleHostedWorker", SourceURIs: []string{"https://github.com/my-org/app"}},
+		{BuilderID: "https://cloudbuild.googleapis.com/GoogleHostedWorker@v0.2", SourceURIs: []string{"https://github.com/my-org/lib"}},
+	}
+	testutil.DeepEqual(t, expected, FromOccurrences(unsigned, digest, nil))
+}
+
+type fakeImage struct {
+	v1.Image
+	blobs []string
+}
+
+func (f fakeImage) Manifest() (*v1.Manifest, error) {
+	m := &v1.Manifest{}
+	for i := range f.blobs {
+		m.Layers = append(m.Layers, v1.Descriptor{
+			MediaType: types.MediaType(dsseMediaType),
+			Digest:    v1.Hash{Algorithm: "sha256", Hex: fmt.Sprintf("%064d", i)},
+		})
+	}
+	return m, nil
+}
+
+func (f fakeImage) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	var i int
+	fmt.Sscanf(h.Hex, "%d", &i)
+	return fakeLayer{content: f.blobs[i]}, nil
+}
+
+type fakeLayer struct {
+	v1.Layer
+	content string
+}
+
+func (f fakeLayer) Compressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(f.content)), nil
+}
+
+func TestFromCosign(t *testing.T) {
+	priv, pub := newKey(t)
+	keys, err := ParseKeys([]string{pub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := newKey(t)
+	var blobs []string
+	for _, e := range []Envelope{sign(t, priv, statementJSON(digest)), sign(t, other, statementJSON(digest))} {
+		b, _ := json.Marshal(e)
+		blobs = append(blobs, string(b))
+	}
+	original, originalReferrers := cosignImage, referrersIndex
+	defer func() { cosignImage, referrersIndex = original, originalReferrers }()
+	referrersIndex = func(digest name.Digest) ([]byte, error) {
+		return nil, nil
+	}
+
+	var requested string
+	cosignImage = func(ref name.Reference) (v1.Image, error) {
+		requested = ref.Name()
+		return fakeImage{blobs: append(blobs, "not json")}, nil
+	}
+	provs, err := FromCosign(testutil.QualifiedImage, keys)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 1, len(provs))
+	testutil.DeepEqual(t, "gcr.io/image/digest:sha256-0000000000000000000000000000000000000000000000000000000000000000.att", requested)
+
+	// Attestations are also read from the referrers of the image
+	referrersIndex = func(digest name.Digest) ([]byte, error) {
+		return []byte(`{"manifests": [{"digest": "sha256:1111111111111111111111111111111111111111111111111111111111111111", "artifactType": "application/vnd.dev.cosign.artifact.att.v1+json"}]}`), nil
+	}
+	var referrers []string
+	cosignImage = func(ref name.Reference) (v1.Image, error) {
+		if _, ok := ref.(name.Tag); ok {
+			return nil, &transport.Error{Errors: []transport.Diagnostic{{Code: transport.ManifestUnknownErrorCode}}}
+		}
+		referrers = append(referrers, ref.Name())
+		return fakeImage{blobs: blobs}, nil
+	}
+	provs, err = FromCosign(testutil.QualifiedImage, keys)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 1, len(provs))
+	testutil.DeepEqual(t, []string{"gcr.io/image/digest@sha256:1111111111111111111111111111111111111111111111111111111111111111"}, referrers)
+	referrersIndex = func(digest name.Digest) ([]byte, error) {
+		return nil, nil
+	}
+
+	// Attestations can not be verified without keys
+	provs, err = FromCosign(testutil.QualifiedImage, nil)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 0, len(provs))
+
+	cosignImage = func(ref name.Reference) (v1.Image, error) {
+		return nil, &transport.Error{Errors: []transport.Diagnostic{{Code: transport.ManifestUnknownErrorCode}}}
+	}
+	provs, err = FromCosign(testutil.QualifiedImage, keys)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 0, len(provs))
+
+	cosignImage = func(ref name.Reference) (v1.Image, error) {
+		return nil, &transport.Error{Errors: []transport.Diagnostic{{Code: transport.DeniedErrorCode}}}
+	}
+	_, err = FromCosign(testutil.QualifiedImage, keys)
+	testutil.CheckError(t, true, err)
+}
+
+func TestReferrers(t *testing.T) {
+	original := referrersIndex
+	defer func() { referrersIndex = original }()
+
+	index := `{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.oci.image.index.v1+json",
+  "manifests": [
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:1111111111111111111111111111111111111111111111111111111111111111", "artifactType": "application/vnd.dev.cosign.artifact.sig.v1+json"},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:2222222222222222222222222222222222222222222222222222222222222222", "artifactType": "application/spdx+json"}
+  ]
+}`
+	var requested string
+	referrersIndex = func(digest name.Digest) ([]byte, error) {
+		requested = digest.String()
+		return []byte(index), nil
+	}
+	refs, err := Referrers(testutil.QualifiedImage, cosignSignatureArtifactType)
+	testutil.CheckErrorAndDeepEqual(t, false, err, []Referrer{{
+		MediaType:    "application/vnd.oci.image.manifest.v1+json",
+		Digest:       "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		ArtifactType: cosignSignatureArtifactType,
+	}}, refs)
+	testutil.DeepEqual(t, testutil.QualifiedImage, requested)
+
+	refs, err = Referrers(testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 2, len(refs))
+
+	// Images without referrers
+	referrersIndex = func(digest name.Digest) ([]byte, error) {
+		return nil, nil
+	}
+	refs, err = Referrers(testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 0, len(refs))
+
+	_, err = Referrers("gcr.io/image/tag:latest")
+	testutil.CheckError(t, true, err)
+}
+
+func TestFetchReferrersIndex(t *testing.T) {
+	index := `{"manifests": []}`
+	referrersAPI := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+		case r.URL.Path == "/v2/failing/referrers/"+digest:
+			w.WriteHeader(http.StatusInternalServerError)
+		case r.URL.Path == "/v2/image/referrers/"+digest && referrersAPI:
+			w.Header().Set("Content-Type", string(types.OCIImageIndex))
+			w.Write([]byte(index))
+		case r.URL.Path == "/v2/image/manifests/"+strings.Replace(digest, ":", "-", 1) && !referrersAPI:
+			w.Header().Set("Content-Type", string(types.OCIImageIndex))
+			w.Write([]byte(index))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": [{"code": "MANIFEST_UNKNOWN"}]}`))
+		}
+	}))
+	defer server.Close()
+	ref, err := name.NewDigest(strings.TrimPrefix(server.URL, "http://")+"/image@"+digest, name.WeakValidation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := fetchReferrersIndex(ref)
+	testutil.CheckErrorAndDeepEqual(t, false, err, index, string(b))
+
+	// Registries without the referrers API
+	referrersAPI = false
+	b, err = fetchReferrersIndex(ref)
+	testutil.CheckErrorAndDeepEqual(t, false, err, index, string(b))
+
+	other, _ := name.NewDigest(strings.TrimPrefix(server.URL, "http://")+"/other@"+digest, name.WeakValidation)
+	b, err = fetchReferrersIndex(other)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 0, len(b))
+
+	// Only registries answering 404 lack the referrers API, other failures are errors
+	failing, _ := name.NewDigest(strings.TrimPrefix(server.URL, "http://")+"/failing@"+digest, name.WeakValidation)
+	_, err = fetchReferrersIndex(failing)
+	testutil.CheckError(t, true, err)
+
+	// Oversized indexes are rejected, with or without the referrers API
+	index = `{"manifests": [], "padding": "` + strings.Repeat("x", maxReferrersIndexBytes) + `"}`
+	_, err = fetchReferrersIndex(ref)
+	testutil.CheckError(t, true, err)
+	referrersAPI = true
+	_, err = fetchReferrersIndex(ref)
+	testutil.CheckError(t, true, err)
+}
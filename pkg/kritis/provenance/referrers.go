@@ -0,0 +1,197 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+	"github.com/grafeas/kritis/pkg/kritis/registryauth"
+)
+
+// Artifact types of the signatures and attestations stored by cosign as OCI 1.1 referrers.
+const (
+	cosignSignatureArtifactType   = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	cosignAttestationArtifactType = "application/vnd.dev.cosign.artifact.att.v1+json"
+)
+
+// maxReferrersIndexBytes bounds the referrers index read from a registry.
+const maxReferrersIndexBytes = 4 << 20
+
+// registryTimeout bounds the requests made to registries to read signatures and attestations.
+const registryTimeout = 30 * time.Second
+
+// registryTransport is the transport of the requests made to registries, as their
+// clients have no timeout of their own.
+var registryTransport http.RoundTripper = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ResponseHeaderTimeout: registryTimeout,
+	IdleConnTimeout:       90 * time.Second,
+	MaxIdleConnsPerHost:   10,
+}
+
+// For testing
+var referrersIndex = fetchReferrersIndex
+
+// Referrer is a manifest whose subject is an image, as listed by the OCI referrers API.
+type Referrer struct {
+	MediaType    string            `json:"mediaType"`
+	Digest       string            `json:"digest"`
+	ArtifactType string            `json:"artifactType"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+// Referrers returns the manifests referring to the image with one of the artifact types,
+// or all of them if none is given. Referrers are listed with the OCI 1.1 referrers API,
+// or read from the "sha256-<digest>" tag in registries which don't implement it.
+func Referrers(image string, artifactTypes ...string) ([]Referrer, error) {
+	digest, err := reference.ParseDigest(image)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%q is not referenced by digest", image)
+	}
+	b, err := referrersIndex(digest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list referrers of %s", image)
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var index struct {
+		Manifests []Referrer `json:"manifests"`
+	}
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, errors.Wrapf(err, "invalid referrers of %s", image)
+	}
+	if len(artifactTypes) == 0 {
+		return index.Manifests, nil
+	}
+	var refs []Referrer
+	for _, r := range index.Manifests {
+		for _, t := range artifactTypes {
+			if r.ArtifactType == t {
+				refs = append(refs, r)
+				break
+			}
+		}
+	}
+	return refs, nil
+}
+
+// referrerImages returns the manifests referring to the image with one of the artifact types.
+func referrerImages(digest name.Digest, artifactTypes ...string) ([]v1.Image, error) {
+	refs, err := Referrers(digest.String(), artifactTypes...)
+	if err != nil {
+		return nil, err
+	}
+	var imgs []v1.Image
+	for _, r := range refs {
+		ref, err := name.NewDigest(fmt.Sprintf("%s@%s", digest.Context().Name(), r.Digest), name.WeakValidation)
+		if err != nil {
+			glog.Warningf("skipping referrer %s of %s: %v", r.Digest, digest, err)
+			continue
+		}
+		img, err := cosignImage(ref)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get referrer %s", r.Digest)
+		}
+		imgs = append(imgs, img)
+	}
+	return imgs, nil
+}
+
+// fetchReferrersIndex returns the image index listing the referrers of the digest,
+// or nil if there are none. The fallback tag is only read if the registry doesn't
+// implement the referrers API, i.e. answers 404. Both reads are bounded by
+// registryTimeout and maxReferrersIndexBytes.
+func fetchReferrersIndex(digest name.Digest) ([]byte, error) {
+	reg := digest.Context().Registry
+	auth, err := registryauth.Keychain().Resolve(reg)
+	if err != nil {
+		return nil, err
+	}
+	tr, err := transport.New(reg, auth, registryTransport, []string{digest.Scope(transport.PullScope)})
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: tr, Timeout: registryTimeout}
+	repo := digest.Context().RepositoryStr()
+	b, found, err := getIndex(client, reg, fmt.Sprintf("/v2/%s/referrers/%s", repo, digest.DigestStr()), string(types.OCIImageIndex))
+	if err != nil {
+		return nil, errors.Wrap(err, "referrers API")
+	}
+	if found {
+		return b, nil
+	}
+	// Registries without the referrers API list referrers in an index tagged with the digest
+	tag := strings.Replace(digest.DigestStr(), ":", "-", 1)
+	b, _, err = getIndex(client, reg, fmt.Sprintf("/v2/%s/manifests/%s", repo, tag), string(types.OCIImageIndex)+","+string(types.DockerManifestList))
+	if err != nil {
+		return nil, errors.Wrapf(err, "referrers tag %s", tag)
+	}
+	return b, nil
+}
+
+// getIndex reads the index at path of the registry, of at most maxReferrersIndexBytes,
+// and returns whether it was found.
+func getIndex(client *http.Client, reg name.Registry, path, accept string) ([]byte, bool, error) {
+	u := url.URL{Scheme: reg.Scheme(), Host: reg.RegistryStr(), Path: path}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", accept)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxReferrersIndexBytes+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(b) > maxReferrersIndexBytes {
+		return nil, false, fmt.Errorf("referrers index exceeds %d bytes", maxReferrersIndexBytes)
+	}
+	return b, true, nil
+}
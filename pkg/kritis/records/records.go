@@ -0,0 +1,99 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package records keeps the violations found by the cron job in each namespace, as
+// ViolationRecords or in Cloud Storage, and deletes them once older than their retention.
+package records
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	clientset "github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned"
+)
+
+// DefaultRetention of the records if not configured.
+const DefaultRetention = 30 * 24 * time.Hour
+
+// Stores of ViolationRecordsSpecs
+const (
+	CRD = "crd"
+	GCS = "gcs"
+)
+
+// For testing
+var now = time.Now
+
+// Store is where records are kept.
+type Store interface {
+	Write(ctx context.Context, r *v1beta1.ViolationRecord) error
+	// Prune deletes the records of namespace reviewed before t.
+	Prune(ctx context.Context, namespace string, t time.Time) error
+}
+
+// Recorder writes the records of the namespaces reviewed to its store.
+// The methods of a nil Recorder do nothing, when no store is configured.
+type Recorder struct {
+	store     Store
+	retention time.Duration
+}
+
+// NewRecorder returns a Recorder keeping records in store for retention.
+func NewRecorder(store Store, retention time.Duration) *Recorder {
+	return &Recorder{store: store, retention: retention}
+}
+
+// New returns a Recorder configured by spec, using client for ViolationRecords.
+// It returns nil if spec has no store.
+func New(spec v1beta1.ViolationRecordsSpec, client clientset.Interface) (*Recorder, error) {
+	if spec.Store == "" {
+		return nil, nil
+	}
+	retention := DefaultRetention
+	if spec.Retention != "" {
+		var err error
+		if retention, err = time.ParseDuration(spec.Retention); err != nil {
+			return nil, errors.Wrap(err, "invalid retention")
+		}
+		if retention <= 0 {
+			return nil, fmt.Errorf("retention must be positive, got %s", spec.Retention)
+		}
+	}
+	store, err := newStore(context.Background(), spec, client)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecorder(store, retention), nil
+}
+
+// Record writes r, then deletes the records of its namespace older than the retention.
+// Failures are logged, since records don't change the reviews.
+func (r *Recorder) Record(ctx context.Context, record *v1beta1.ViolationRecord) {
+	if r == nil {
+		return
+	}
+	if err := r.store.Write(ctx, record); err != nil {
+		glog.Errorf("failed to record violations of namespace %s: %v", record.Namespace, err)
+	}
+	if err := r.store.Prune(ctx, record.Namespace, now().Add(-r.retention)); err != nil {
+		glog.Errorf("failed to delete expired violation records of namespace %s: %v", record.Namespace, err)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package records
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+	storage "google.golang.org/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned/fake"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func record(namespace string, reviewed time.Time) *v1beta1.ViolationRecord {
+	return &v1beta1.ViolationRecord{
+		ObjectMeta: metav1.ObjectMeta{Name: Name(reviewed), Namespace: namespace},
+		Spec: v1beta1.ViolationRecordSpec{
+			ReviewTime: metav1.NewTime(reviewed),
+			Violations: map[string]int{"SeverityViolation": 3},
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	var tests = []struct {
+		name        string
+		spec        v1beta1.ViolationRecordsSpec
+		shouldErr   bool
+		expectedNil bool
+	}{
+		{name: "no store", spec: v1beta1.ViolationRecordsSpec{}, expectedNil: true},
+		{name: "crd", spec: v1beta1.ViolationRecordsSpec{Store: CRD, Retention: "48h"}},
+		{name: "gcs without bucket", spec: v1beta1.ViolationRecordsSpec{Store: GCS}, shouldErr: true},
+		{name: "unknown store", spec: v1beta1.ViolationRecordsSpec{Store: "s3"}, shouldErr: true},
+		{name: "invalid retention", spec: v1beta1.ViolationRecordsSpec{Store: CRD, Retention: "a month"}, shouldErr: true},
+		{name: "negative retention", spec: v1beta1.ViolationRecordsSpec{Store: CRD, Retention: "-1h"}, shouldErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := New(test.spec, fake.NewSimpleClientset())
+			testutil.CheckError(t, test.shouldErr, err)
+			if !test.shouldErr && (r == nil) != test.expectedNil {
+				t.Errorf("expected nil Recorder: %t, got %v", test.expectedNil, r)
+			}
+		})
+	}
+}
+
+func TestCRDStore(t *testing.T) {
+	reviewed := time.Date(2018, 10, 2, 15, 4, 5, 0, time.UTC)
+	originalNow := now
+	now = func() time.Time { return reviewed }
+	defer func() { now = originalNow }()
+
+	client := fake.NewSimpleClientset(record("foo", reviewed.Add(-72*time.Hour)), record("foo", reviewed.Add(-time.Hour)), record("bar", reviewed.Add(-72*time.Hour)))
+	r, err := New(v1beta1.ViolationRecordsSpec{Store: CRD, Retention: "48h"}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Record(context.Background(), record("foo", reviewed))
+
+	// Only the records of the namespace reviewed are pruned
+	for ns, expected := range map[string][]string{
+		"foo": {"violations-20181002-140405", "violations-20181002-150405"},
+		"bar": {"violations-20180929-150405"},
+	} {
+		list, err := client.KritisV1beta1().ViolationRecords(ns).List(metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, r := range list.Items {
+			names = append(names, r.Name)
+		}
+		sort.Strings(names)
+		testutil.DeepEqual(t, expected, names)
+	}
+}
+
+func TestGCSStore(t *testing.T) {
+	reviewed := time.Date(2018, 10, 2, 15, 4, 5, 0, time.UTC)
+	originalNow := now
+	now = func() time.Time { return reviewed }
+	defer func() { now = originalNow }()
+
+	var uploaded string
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.Contains(r.URL.Path, "/b/kritis-records/o"):
+			b, _ := ioutil.ReadAll(r.Body)
+			uploaded = string(b)
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/b/kritis-records/o"):
+			testutil.DeepEqual(t, "prod/foo/", r.URL.Query().Get("prefix"))
+			json.NewEncoder(w).Encode(&storage.Objects{Items: []*storage.Object{
+				{Name: "prod/foo/2018/09/29/150405.json"},
+				{Name: "prod/foo/2018/10/02/140405.json"},
+				{Name: "prod/foo/README"},
+			}})
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/b/kritis-records/o/"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	clientOptions = []option.ClientOption{option.WithEndpoint(server.URL), option.WithoutAuthentication()}
+	defer func() { clientOptions = nil }()
+
+	r, err := New(v1beta1.ViolationRecordsSpec{Store: GCS, Bucket: "kritis-records", Prefix: "prod", Retention: "48h"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Record(context.Background(), record("foo", reviewed))
+
+	if !strings.Contains(uploaded, `"name":"prod/foo/2018/10/02/150405.json"`) {
+		t.Errorf("expected the object name in the upload, got %q", uploaded)
+	}
+	if !strings.Contains(uploaded, `"SeverityViolation":3`) {
+		t.Errorf("expected the record to be uploaded, got %q", uploaded)
+	}
+	testutil.DeepEqual(t, []string{"prod/foo/2018/09/29/150405.json"}, deleted)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package records
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/option"
+	storage "google.golang.org/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	clientset "github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned"
+	"github.com/grafeas/kritis/pkg/kritis/gcpauth"
+)
+
+// Layout of the review time in the names of records
+const (
+	nameLayout   = "20060102-150405"
+	objectLayout = "2006/01/02/150405"
+)
+
+// For testing
+var clientOptions []option.ClientOption
+
+func newStore(ctx context.Context, spec v1beta1.ViolationRecordsSpec, client clientset.Interface) (Store, error) {
+	switch spec.Store {
+	case CRD:
+		return &crdStore{client: client}, nil
+	case GCS:
+		if spec.Bucket == "" {
+			return nil, fmt.Errorf("gcs violation records need a bucket")
+		}
+		opts, err := gcpauth.ClientOptions(ctx, gcpauth.Storage)
+		if err != nil {
+			return nil, err
+		}
+		service, err := storage.NewService(ctx, append(opts, clientOptions...)...)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create Cloud Storage client")
+		}
+		return &gcsStore{service: service, bucket: spec.Bucket, prefix: spec.Prefix}, nil
+	}
+	return nil, fmt.Errorf("unknown violation records store %q", spec.Store)
+}
+
+// Name returns the name of the record of a review at t.
+func Name(t time.Time) string {
+	return "violations-" + t.UTC().Format(nameLayout)
+}
+
+// crdStore keeps records as ViolationRecords in the namespace they were found in.
+type crdStore struct {
+	client clientset.Interface
+}
+
+func (s *crdStore) Write(ctx context.Context, r *v1beta1.ViolationRecord) error {
+	if _, err := s.client.KritisV1beta1().ViolationRecords(r.Namespace).Create(r); err != nil {
+		return errors.Wrapf(err, "failed to create violation record %s", r.Name)
+	}
+	return nil
+}
+
+func (s *crdStore) Prune(ctx context.Context, namespace string, t time.Time) error {
+	records := s.client.KritisV1beta1().ViolationRecords(namespace)
+	list, err := records.List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list violation records")
+	}
+	for _, r := range list.Items {
+		if !r.Spec.ReviewTime.Time.Before(t) {
+			continue
+		}
+		if err := records.Delete(r.Name, &metav1.DeleteOptions{}); err != nil {
+			return errors.Wrapf(err, "failed to delete violation record %s", r.Name)
+		}
+	}
+	return nil
+}
+
+// gcsStore writes each record to its own object in a Cloud Storage bucket, named
+// <prefix>/<namespace>/2006/01/02/150405.json after the review time.
+type gcsStore struct {
+	service *storage.Service
+	bucket  string
+	prefix  string
+}
+
+func (s *gcsStore) Write(ctx context.Context, r *v1beta1.ViolationRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	name := path.Join(s.prefix, r.Namespace, r.Spec.ReviewTime.UTC().Format(objectLayout)+".json")
+	obj := &storage.Object{Name: name, ContentType: "application/json"}
+	if _, err := s.service.Objects.Insert(s.bucket, obj).Media(bytes.NewReader(b)).Context(ctx).Do(); err != nil {
+		return errors.Wrapf(err, "failed to write violation record to gs://%s", s.bucket)
+	}
+	return nil
+}
+
+func (s *gcsStore) Prune(ctx context.Context, namespace string, t time.Time) error {
+	dir := path.Join(s.prefix, namespace) + "/"
+	var expired []string
+	err := s.service.Objects.List(s.bucket).Prefix(dir).Pages(ctx, func(objs *storage.Objects) error {
+		for _, obj := range objs.Items {
+			// Objects not named after a review time are not records, and are kept
+			reviewed, err := time.Parse(objectLayout, strings.TrimSuffix(strings.TrimPrefix(obj.Name, dir), ".json"))
+			if err == nil && reviewed.Before(t) {
+				expired = append(expired, obj.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list violation records in gs://%s", s.bucket)
+	}
+	for _, name := range expired {
+		if err := s.service.Objects.Delete(s.bucket, name).Context(ctx).Do(); err != nil {
+			return errors.Wrapf(err, "failed to delete violation record gs://%s/%s", s.bucket, name)
+		}
+	}
+	return nil
+}
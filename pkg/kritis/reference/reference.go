@@ -0,0 +1,232 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reference canonicalizes container image references, so images
+// written differently, e.g. "nginx" and "docker.io/library/nginx:latest",
+// are compared and looked up as the same image by all packages.
+package reference
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+const (
+	// DockerHubRegistry is the canonical registry of Docker Hub images.
+	DockerHubRegistry = "docker.io"
+	// DockerHubOfficialNamespace is the namespace of Docker Hub official images.
+	DockerHubOfficialNamespace = "library"
+	defaultTag                 = "latest"
+)
+
+// dockerHubRegistries are the hosts accepted for Docker Hub, which is also
+// the registry of references without an explicit one.
+var dockerHubRegistries = map[string]bool{
+	DockerHubRegistry:      true,
+	name.DefaultRegistry:   true,
+	"registry-1.docker.io": true,
+}
+
+// Reference is a parsed image reference.
+type Reference struct {
+	// Registry is the registry host, with its port if any.
+	Registry string
+	// Repository is the repository path, "library/" is prepended to Docker Hub official images.
+	Repository string
+	// Tag is empty for references by digest, and "latest" if the reference has neither.
+	Tag string
+	// Digest is the image digest, e.g. "sha256:<hex>".
+	Digest string
+}
+
+// Parse parses an image reference. Docker Hub shorthands such as "nginx" and
+// "library/nginx" are expanded, and registries may have ports, e.g.
+// "localhost:5000/app". If a reference has both a tag and a digest, the
+// digest identifies the image and the tag is dropped.
+func Parse(image string) (*Reference, error) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		repo := image[:i]
+		// The tag is after the last colon which isn't part of the registry.
+		if j := strings.LastIndex(repo, ":"); j > strings.LastIndex(repo, "/") {
+			repo = repo[:j]
+		}
+		d, err := name.NewDigest(repo+image[i:], name.WeakValidation)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image reference %q: %v", image, err)
+		}
+		return newReference(d.Context(), "", d.DigestStr()), nil
+	}
+	t, err := name.NewTag(image, name.WeakValidation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image reference %q: %v", image, err)
+	}
+	return newReference(t.Context(), t.TagStr(), ""), nil
+}
+
+func newReference(repo name.Repository, tag, digest string) *Reference {
+	r := &Reference{
+		Registry:   repo.RegistryStr(),
+		Repository: repo.RepositoryStr(),
+		Tag:        tag,
+		Digest:     digest,
+	}
+	if dockerHubRegistries[r.Registry] {
+		r.Registry = DockerHubRegistry
+		if !strings.Contains(r.Repository, "/") {
+			r.Repository = DockerHubOfficialNamespace + "/" + r.Repository
+		}
+	}
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = defaultTag
+	}
+	return r
+}
+
+// Name returns the canonical repository name, e.g. "docker.io/library/nginx".
+func (r *Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the canonical reference, e.g. "docker.io/library/nginx:latest".
+func (r *Reference) String() string {
+	if r.Digest != "" {
+		return r.Name() + "@" + r.Digest
+	}
+	return r.Name() + ":" + r.Tag
+}
+
+// HasDigest returns true if the image is referenced by digest.
+func (r *Reference) HasDigest() bool {
+	return r.Digest != ""
+}
+
+// IsDockerHub returns true if the image is hosted on Docker Hub.
+func (r *Reference) IsDockerHub() bool {
+	return r.Registry == DockerHubRegistry
+}
+
+// IsDockerHubOfficial returns true if the image is a Docker Hub official image.
+func (r *Reference) IsDockerHubOfficial() bool {
+	return r.IsDockerHub() && strings.HasPrefix(r.Repository, DockerHubOfficialNamespace+"/") &&
+		strings.Count(r.Repository, "/") == 1
+}
+
+// Project returns the project of images hosted in GCR or Artifact Registry,
+// e.g. "my-project" for "gcr.io/my-project/app", and "" for other registries.
+func (r *Reference) Project() string {
+	host := r.Registry
+	if i := strings.Index(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	if host != "gcr.io" && !strings.HasSuffix(host, ".gcr.io") && !IsArtifactRegistry(host) {
+		return ""
+	}
+	return strings.SplitN(r.Repository, "/", 2)[0]
+}
+
+// IsArtifactRegistry returns true for Artifact Registry hosts, e.g. "us-docker.pkg.dev".
+// Images are stored there as <host>/<project>/<repository>/<image>.
+func IsArtifactRegistry(registry string) bool {
+	if i := strings.Index(registry, ":"); i >= 0 {
+		registry = registry[:i]
+	}
+	return strings.HasSuffix(registry, "-docker.pkg.dev")
+}
+
+// Canonical returns the canonical form of an image reference.
+func Canonical(image string) (string, error) {
+	r, err := Parse(image)
+	if err != nil {
+		return "", err
+	}
+	return r.String(), nil
+}
+
+// HasDigest returns true if the image is a valid reference by digest.
+func HasDigest(image string) bool {
+	r, err := Parse(image)
+	return err == nil && r.HasDigest()
+}
+
+// Equal returns true if both references identify the same image.
+func Equal(a, b string) bool {
+	ra, err := Parse(a)
+	if err != nil {
+		return false
+	}
+	rb, err := Parse(b)
+	if err != nil {
+		return false
+	}
+	return ra.String() == rb.String()
+}
+
+// SameRepository returns true if both references are in the same repository.
+func SameRepository(a, b string) bool {
+	ra, err := Parse(a)
+	if err != nil {
+		return false
+	}
+	rb, err := Parse(b)
+	if err != nil {
+		return false
+	}
+	return ra.Name() == rb.Name()
+}
+
+// Ref returns the go-containerregistry reference of the canonical image,
+// a name.Digest for references by digest and a name.Tag otherwise.
+func (r *Reference) Ref() (name.Reference, error) {
+	if r.HasDigest() {
+		return name.NewDigest(r.String(), name.WeakValidation)
+	}
+	return name.NewTag(r.String(), name.WeakValidation)
+}
+
+// ParseReference parses an image into a go-containerregistry reference.
+func ParseReference(image string) (name.Reference, error) {
+	r, err := Parse(image)
+	if err != nil {
+		return nil, err
+	}
+	return r.Ref()
+}
+
+// ParseDigest parses an image referenced by digest.
+func ParseDigest(image string) (name.Digest, error) {
+	r, err := Parse(image)
+	if err != nil {
+		return name.Digest{}, err
+	}
+	if !r.HasDigest() {
+		return name.Digest{}, fmt.Errorf("image %q is not referenced by digest", image)
+	}
+	return name.NewDigest(r.String(), name.WeakValidation)
+}
+
+// ParseTag parses an image referenced by tag, "latest" if it has none.
+func ParseTag(image string) (name.Tag, error) {
+	r, err := Parse(image)
+	if err != nil {
+		return name.Tag{}, err
+	}
+	if r.HasDigest() {
+		return name.Tag{}, fmt.Errorf("image %q is referenced by digest", image)
+	}
+	return name.NewTag(r.String(), name.WeakValidation)
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reference
+
+import (
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+func TestCanonical(t *testing.T) {
+	var tests = []struct {
+		image     string
+		shouldErr bool
+		expected  string
+	}{
+		{"nginx", false, "docker.io/library/nginx:latest"},
+		{"library/nginx", false, "docker.io/library/nginx:latest"},
+		{"docker.io/nginx:1.15", false, "docker.io/library/nginx:1.15"},
+		{"index.docker.io/library/nginx", false, "docker.io/library/nginx:latest"},
+		{"bitnami/nginx", false, "docker.io/bitnami/nginx:latest"},
+		{"localhost:5000/foo:1", false, "localhost:5000/foo:1"},
+		{"gcr.io/my-project/app@" + digest, false, "gcr.io/my-project/app@" + digest},
+		{"gcr.io/my-project/app:v1@" + digest, false, "gcr.io/my-project/app@" + digest},
+		{"localhost:5000/foo:1@" + digest, false, "localhost:5000/foo@" + digest},
+		{"gcr.io/my-project/app@sha256:abc", true, ""},
+		{"Invalid Image", true, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			actual, err := Canonical(test.image)
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, actual)
+		})
+	}
+}
+
+func TestProject(t *testing.T) {
+	var tests = []struct {
+		image    string
+		expected string
+	}{
+		{"gcr.io/my-project/app:v1", "my-project"},
+		{"us.gcr.io/my-project/app", "my-project"},
+		{"us-central1-docker.pkg.dev/my-project/repo/app", "my-project"},
+		{"nginx", ""},
+		{"localhost:5000/my-project/app", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			r, err := Parse(test.image)
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, r.Project())
+		})
+	}
+}
+
+func TestIsArtifactRegistry(t *testing.T) {
+	var tests = []struct {
+		registry string
+		expected bool
+	}{
+		{"us-docker.pkg.dev", true},
+		{"us-central1-docker.pkg.dev", true},
+		{"europe-west1-docker.pkg.dev:443", true},
+		{"docker.pkg.dev", false},
+		{"gcr.io", false},
+		{"registry.example.com", false},
+	}
+	for _, test := range tests {
+		t.Run(test.registry, func(t *testing.T) {
+			testutil.DeepEqual(t, test.expected, IsArtifactRegistry(test.registry))
+		})
+	}
+}
+
+func TestDockerHub(t *testing.T) {
+	var tests = []struct {
+		image    string
+		hub      bool
+		official bool
+	}{
+		{"nginx", true, true},
+		{"docker.io/library/nginx@" + digest, true, true},
+		{"bitnami/nginx", true, false},
+		{"docker.io/library/foo/bar", true, false},
+		{"gcr.io/library/nginx", false, false},
+		{"localhost:5000/nginx", false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			r, err := Parse(test.image)
+			testutil.CheckError(t, false, err)
+			if r.IsDockerHub() != test.hub {
+				t.Errorf("IsDockerHub() = %t, expected %t", r.IsDockerHub(), test.hub)
+			}
+			if r.IsDockerHubOfficial() != test.official {
+				t.Errorf("IsDockerHubOfficial() = %t, expected %t", r.IsDockerHubOfficial(), test.official)
+			}
+		})
+	}
+}
+
+func TestEqual(t *testing.T) {
+	var tests = []struct {
+		name           string
+		a              string
+		b              string
+		equal          bool
+		sameRepository bool
+	}{
+		{"shorthand", "nginx", "docker.io/library/nginx:latest", true, true},
+		{"different tags", "nginx:1.15", "nginx:1.16", false, true},
+		{"tag and digest", "nginx:1.15@" + digest, "nginx@" + digest, true, true},
+		{"different registries", "gcr.io/foo/bar", "us.gcr.io/foo/bar", false, false},
+		{"invalid", "nginx", "Invalid Image", false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if Equal(test.a, test.b) != test.equal {
+				t.Errorf("Equal(%q, %q) = %t, expected %t", test.a, test.b, !test.equal, test.equal)
+			}
+			if SameRepository(test.a, test.b) != test.sameRepository {
+				t.Errorf("SameRepository(%q, %q) = %t, expected %t", test.a, test.b, !test.sameRepository, test.sameRepository)
+			}
+		})
+	}
+}
+
+func TestHasDigest(t *testing.T) {
+	if !HasDigest("gcr.io/foo/bar@" + digest) {
+		t.Error("expected image with digest to have a digest")
+	}
+	if HasDigest("gcr.io/foo/bar:latest") {
+		t.Error("expected image with tag not to have a digest")
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	var tests = []struct {
+		image     string
+		shouldErr bool
+		expected  string
+	}{
+		{"nginx", false, "index.docker.io/library/nginx:latest"},
+		{"gcr.io/my-project/app:v1", false, "gcr.io/my-project/app:v1"},
+		{"gcr.io/my-project/app:v1@" + digest, false, "gcr.io/my-project/app@" + digest},
+		{"Invalid Image", true, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			ref, err := ParseReference(test.image)
+			actual := ""
+			if err == nil {
+				actual = ref.Name()
+			}
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, actual)
+		})
+	}
+}
+
+func TestParseDigestAndTag(t *testing.T) {
+	d, err := ParseDigest("gcr.io/my-project/app:v1@" + digest)
+	testutil.CheckErrorAndDeepEqual(t, false, err, digest, d.DigestStr())
+	_, err = ParseDigest("gcr.io/my-project/app:v1")
+	testutil.CheckError(t, true, err)
+
+	tag, err := ParseTag("library/nginx")
+	testutil.CheckErrorAndDeepEqual(t, false, err, "latest", tag.TagStr())
+	_, err = ParseTag("gcr.io/my-project/app@" + digest)
+	testutil.CheckError(t, true, err)
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registryauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
+	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/google"
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/metadata/azure"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+)
+
+// acrUsername is the username of ACR refresh tokens.
+const acrUsername = "00000000-0000-0000-0000-000000000000"
+
+// acrTokenLifetime is how long ACR refresh tokens are used, they expire after 3 hours.
+const acrTokenLifetime = time.Hour
+
+var (
+	ecrRegistryRegexp = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+	acrRegistryRegexp = regexp.MustCompile(`^[a-z0-9]+\.azurecr\.(?:io|cn|us)$`)
+	gcrRegistryRegexp = regexp.MustCompile(`^(?:[a-z]+\.)?gcr\.io$`)
+)
+
+// For testing
+var (
+	newECRAPI = func(region string) (ecriface.ECRAPI, error) {
+		sess, err := session.NewSession()
+		if err != nil {
+			return nil, err
+		}
+		return ecr.New(sess, aws.NewConfig().WithRegion(region)), nil
+	}
+	azureToken = func() (string, error) {
+		token, err := azure.NewToken()
+		if err != nil {
+			return "", err
+		}
+		if err := token.EnsureFresh(); err != nil {
+			return "", err
+		}
+		return token.OAuthToken(), nil
+	}
+	googleAuthenticator = google.NewEnvAuthenticator
+	acrClient           = &http.Client{Timeout: 10 * time.Second}
+	now                 = time.Now
+)
+
+// helpers resolves the credentials of Google, ECR and ACR registries from the
+// environment of Kritis, e.g. Workload Identity or IAM roles for service accounts.
+// Other registries, and registries whose credentials can not be obtained, are anonymous.
+var helpers = &helperKeychain{tokens: map[string]cachedAuth{}}
+
+type cachedAuth struct {
+	auth    authn.Authenticator
+	expires time.Time
+}
+
+type helperKeychain struct {
+	mu     sync.Mutex
+	tokens map[string]cachedAuth
+}
+
+func (k *helperKeychain) Resolve(reg name.Registry) (authn.Authenticator, error) {
+	host := reg.RegistryStr()
+	var auth authn.Authenticator
+	var expires time.Time
+	var err error
+	switch {
+	case gcrRegistryRegexp.MatchString(host) || reference.IsArtifactRegistry(host):
+		// Google tokens are refreshed by the authenticator
+		if auth, err = googleAuthenticator(); err != nil {
+			glog.Warningf("failed to get Google credentials for %s: %v", host, err)
+			return authn.Anonymous, nil
+		}
+		return auth, nil
+	case ecrRegistryRegexp.MatchString(host):
+		if cached := k.cached(host); cached != nil {
+			return cached, nil
+		}
+		auth, expires, err = ecrAuth(host)
+	case acrRegistryRegexp.MatchString(host):
+		if cached := k.cached(host); cached != nil {
+			return cached, nil
+		}
+		auth, expires, err = acrAuth("https://"+host, host)
+	default:
+		return authn.Anonymous, nil
+	}
+	if err != nil {
+		glog.Warningf("failed to get credentials for %s: %v", host, err)
+		return authn.Anonymous, nil
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.tokens[host] = cachedAuth{auth: auth, expires: expires}
+	return auth, nil
+}
+
+func (k *helperKeychain) cached(host string) authn.Authenticator {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if c, ok := k.tokens[host]; ok && now().Before(c.expires) {
+		return c.auth
+	}
+	return nil
+}
+
+// ecrAuth returns the credentials of an ECR registry, from an authorization token.
+func ecrAuth(host string) (authn.Authenticator, time.Time, error) {
+	m := ecrRegistryRegexp.FindStringSubmatch(host)
+	api, err := newECRAPI(m[2])
+	if err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "failed to create AWS session")
+	}
+	resp, err := api.GetAuthorizationToken(&ecr.GetAuthorizationTokenInput{RegistryIds: []*string{aws.String(m[1])}})
+	if err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "failed to get ECR authorization token")
+	}
+	if len(resp.AuthorizationData) == 0 {
+		return nil, time.Time{}, errors.New("no ECR authorization token")
+	}
+	data := resp.AuthorizationData[0]
+	b, err := base64.StdEncoding.DecodeString(aws.StringValue(data.AuthorizationToken))
+	if err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "invalid ECR authorization token")
+	}
+	parts := strings.SplitN(string(b), ":", 2)
+	if len(parts) != 2 {
+		return nil, time.Time{}, errors.New("invalid ECR authorization token")
+	}
+	// Tokens are renewed before they expire
+	expires := aws.TimeValue(data.ExpiresAt).Add(-5 * time.Minute)
+	return &authn.Basic{Username: parts[0], Password: parts[1]}, expires, nil
+}
+
+// acrAuth returns the credentials of an ACR registry, exchanging an Azure token for a refresh token.
+func acrAuth(endpoint, host string) (authn.Authenticator, time.Time, error) {
+	token, err := azureToken()
+	if err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "failed to authenticate to Azure")
+	}
+	form := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {host},
+		"access_token": {token},
+	}
+	resp, err := acrClient.PostForm(endpoint+"/oauth2/exchange", form)
+	if err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "failed to get ACR refresh token")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, errors.Errorf("failed to get ACR refresh token: %s", resp.Status)
+	}
+	var exchange struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&exchange); err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "invalid ACR refresh token")
+	}
+	return &authn.Basic{Username: acrUsername, Password: exchange.RefreshToken}, now().Add(acrTokenLifetime), nil
+}
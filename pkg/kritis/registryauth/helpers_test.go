@@ -0,0 +1,128 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registryauth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+type fakeECR struct {
+	ecriface.ECRAPI
+	calls    int
+	registry string
+}
+
+func (f *fakeECR) GetAuthorizationToken(in *ecr.GetAuthorizationTokenInput) (*ecr.GetAuthorizationTokenOutput, error) {
+	f.calls++
+	f.registry = aws.StringValue(in.RegistryIds[0])
+	return &ecr.GetAuthorizationTokenOutput{AuthorizationData: []*ecr.AuthorizationData{{
+		AuthorizationToken: aws.String(basicAuth("AWS", "token")),
+		ExpiresAt:          aws.Time(time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)),
+	}}}, nil
+}
+
+func resolve(t *testing.T, k authn.Keychain, registry string) authn.Authenticator {
+	reg, err := name.NewRegistry(registry, name.WeakValidation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := k.Resolve(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth
+}
+
+func TestHelpers(t *testing.T) {
+	originalECR, originalAzure, originalGoogle, originalNow := newECRAPI, azureToken, googleAuthenticator, now
+	defer func() {
+		newECRAPI, azureToken, googleAuthenticator, now = originalECR, originalAzure, originalGoogle, originalNow
+	}()
+	api := &fakeECR{}
+	var region string
+	newECRAPI = func(r string) (ecriface.ECRAPI, error) {
+		region = r
+		return api, nil
+	}
+	azureToken = func() (string, error) {
+		return "", errors.New("no managed identity")
+	}
+	googleAuth := &authn.Bearer{Token: "google"}
+	googleAuthenticator = func() (authn.Authenticator, error) {
+		return googleAuth, nil
+	}
+	now = func() time.Time {
+		return time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	k := &helperKeychain{tokens: map[string]cachedAuth{}}
+
+	testutil.DeepEqual(t, googleAuth, resolve(t, k, "gcr.io"))
+	testutil.DeepEqual(t, googleAuth, resolve(t, k, "eu.gcr.io"))
+	testutil.DeepEqual(t, googleAuth, resolve(t, k, "europe-docker.pkg.dev"))
+	testutil.DeepEqual(t, authn.Anonymous, resolve(t, k, "registry.example.com"))
+
+	ecrAuth := &authn.Basic{Username: "AWS", Password: "token"}
+	testutil.DeepEqual(t, ecrAuth, resolve(t, k, "123456789012.dkr.ecr.eu-west-1.amazonaws.com"))
+	testutil.DeepEqual(t, "eu-west-1", region)
+	testutil.DeepEqual(t, "123456789012", api.registry)
+	// Tokens are cached until they expire
+	testutil.DeepEqual(t, ecrAuth, resolve(t, k, "123456789012.dkr.ecr.eu-west-1.amazonaws.com"))
+	testutil.DeepEqual(t, 1, api.calls)
+	now = func() time.Time {
+		return time.Date(2022, 1, 1, 11, 58, 0, 0, time.UTC)
+	}
+	resolve(t, k, "123456789012.dkr.ecr.eu-west-1.amazonaws.com")
+	testutil.DeepEqual(t, 2, api.calls)
+
+	// Registries whose credentials can not be obtained are anonymous
+	testutil.DeepEqual(t, authn.Anonymous, resolve(t, k, "myregistry.azurecr.io"))
+}
+
+func TestACRAuth(t *testing.T) {
+	originalAzure := azureToken
+	defer func() { azureToken = originalAzure }()
+	azureToken = func() (string, error) {
+		return "aad-token", nil
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth2/exchange" || r.FormValue("grant_type") != "access_token" ||
+			r.FormValue("service") != "myregistry.azurecr.io" || r.FormValue("access_token") != "aad-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"refresh_token": "refresh-token"}`))
+	}))
+	defer server.Close()
+
+	auth, _, err := acrAuth(server.URL, "myregistry.azurecr.io")
+	testutil.CheckErrorAndDeepEqual(t, false, err, &authn.Basic{Username: acrUsername, Password: "refresh-token"}, auth)
+
+	_, _, err = acrAuth(server.URL, "other.azurecr.io")
+	testutil.CheckError(t, true, err)
+}
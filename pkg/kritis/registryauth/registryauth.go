@@ -0,0 +1,295 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package registryauth resolves the credentials used to access container registries,
+// from image pull secrets and the credential helpers of Google, AWS and Azure registries.
+package registryauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	kubernetesutil "github.com/grafeas/kritis/pkg/kritis/kubernetes"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+// Keys of the docker config in kubernetes.io/dockerconfigjson and kubernetes.io/dockercfg Secrets.
+const (
+	DockerConfigJSONKey = ".dockerconfigjson"
+	DockerConfigKey     = ".dockercfg"
+)
+
+// Pull secrets are read again once cached for secretTTL, so rotated credentials are
+// used, and at most maxCachedSecrets are cached.
+const (
+	secretTTL        = time.Minute
+	maxCachedSecrets = 1000
+)
+
+// For testing
+var (
+	fetchSecret         = secrets.FetchData
+	fetchServiceAccount = func(namespace, name string) (*v1.ServiceAccount, error) {
+		c, err := kubeClient()
+		if err != nil {
+			return nil, err
+		}
+		return c.CoreV1().ServiceAccounts(namespace).Get(name, metav1.GetOptions{})
+	}
+)
+
+// serviceAccountClient is the clientset reading service accounts, built once.
+var serviceAccountClient = struct {
+	once      sync.Once
+	clientset kubernetes.Interface
+	err       error
+}{}
+
+func kubeClient() (kubernetes.Interface, error) {
+	serviceAccountClient.once.Do(func() {
+		serviceAccountClient.clientset, serviceAccountClient.err = kubernetesutil.GetClientset()
+	})
+	return serviceAccountClient.clientset, serviceAccountClient.err
+}
+
+var (
+	configMu    sync.RWMutex
+	pullSecrets []string
+)
+
+// Configure sets the pull secrets used for all images, as namespace/name.
+func Configure(spec v1beta1.RegistryCredentialsSpec) error {
+	for _, s := range spec.PullSecrets {
+		if _, _, err := splitSecret(s); err != nil {
+			return err
+		}
+	}
+	configMu.Lock()
+	defer configMu.Unlock()
+	pullSecrets = spec.PullSecrets
+	return nil
+}
+
+func splitSecret(ref string) (string, string, error) {
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid pull secret %q, expected namespace/name", ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+// Keychain returns the credentials of registries from the configured pull secrets,
+// then the credential helpers and the docker config of Kritis.
+func Keychain() authn.Keychain {
+	configMu.RLock()
+	refs := pullSecrets
+	configMu.RUnlock()
+	return authn.NewMultiKeychain(&secretKeychain{refs: refs}, helpers, authn.DefaultKeychain)
+}
+
+// ForPod returns the credentials of registries used for the images of a pod, from its
+// image pull secrets and those of its service account, before those of Keychain.
+// Secrets are read when credentials are first resolved.
+func ForPod(namespace, serviceAccount string, secrets []v1.LocalObjectReference) authn.Keychain {
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+	return &podKeychain{namespace: namespace, serviceAccount: serviceAccount, secrets: secrets}
+}
+
+type podKeychain struct {
+	namespace      string
+	serviceAccount string
+	secrets        []v1.LocalObjectReference
+
+	once     sync.Once
+	keychain authn.Keychain
+}
+
+func (k *podKeychain) Resolve(reg name.Registry) (authn.Authenticator, error) {
+	k.once.Do(func() {
+		var refs []string
+		for _, s := range k.secrets {
+			refs = append(refs, k.namespace+"/"+s.Name)
+		}
+		if sa, err := fetchServiceAccount(k.namespace, k.serviceAccount); err != nil {
+			glog.Warningf("failed to get the image pull secrets of service account %s/%s: %v", k.namespace, k.serviceAccount, err)
+		} else {
+			for _, s := range sa.ImagePullSecrets {
+				refs = append(refs, k.namespace+"/"+s.Name)
+			}
+		}
+		k.keychain = authn.NewMultiKeychain(&secretKeychain{refs: refs}, Keychain())
+	})
+	return k.keychain.Resolve(reg)
+}
+
+// dockerConfig is the docker config stored in pull secrets.
+type dockerConfig struct {
+	Auths map[string]dockerConfigEntry `json:"auths"`
+}
+
+type dockerConfigEntry struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+}
+
+// secretKeychain resolves credentials from docker config Secrets, in order.
+// Secrets which can not be read are skipped, as done by the kubelet.
+type secretKeychain struct {
+	refs []string
+}
+
+func (k *secretKeychain) Resolve(reg name.Registry) (authn.Authenticator, error) {
+	for _, ref := range k.refs {
+		auths, err := secretAuths(ref)
+		if err != nil {
+			glog.Warningf("skipping pull secret %s: %v", ref, err)
+			continue
+		}
+		for host, entry := range auths {
+			if !registryMatches(host, reg.RegistryStr()) {
+				continue
+			}
+			username, password, err := entry.credentials()
+			if err != nil {
+				glog.Warningf("skipping %s credentials of pull secret %s: %v", host, ref, err)
+				continue
+			}
+			return &authn.Basic{Username: username, Password: password}, nil
+		}
+	}
+	return authn.Anonymous, nil
+}
+
+// cachedSecret holds the registry credentials read from a pull secret, or the error reading it.
+type cachedSecret struct {
+	auths   map[string]dockerConfigEntry
+	err     error
+	expires time.Time
+}
+
+var (
+	secretsMu     sync.Mutex
+	cachedSecrets = map[string]cachedSecret{}
+)
+
+// secretAuths returns the registry credentials of a pull secret, as namespace/name,
+// cached for secretTTL.
+func secretAuths(ref string) (map[string]dockerConfigEntry, error) {
+	secretsMu.Lock()
+	c, ok := cachedSecrets[ref]
+	secretsMu.Unlock()
+	if ok && now().Before(c.expires) {
+		return c.auths, c.err
+	}
+	auths, err := readSecretAuths(ref)
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	if _, ok := cachedSecrets[ref]; !ok && len(cachedSecrets) >= maxCachedSecrets {
+		for k := range cachedSecrets {
+			delete(cachedSecrets, k)
+			break
+		}
+	}
+	cachedSecrets[ref] = cachedSecret{auths: auths, err: err, expires: now().Add(secretTTL)}
+	return auths, err
+}
+
+// readSecretAuths reads the registry credentials of a pull secret, as namespace/name.
+func readSecretAuths(ref string) (map[string]dockerConfigEntry, error) {
+	namespace, name, err := splitSecret(ref)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fetchSecret(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := data[DockerConfigJSONKey]; ok {
+		var cfg dockerConfig
+		if err := json.Unmarshal(b, &cfg); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", DockerConfigJSONKey)
+		}
+		return cfg.Auths, nil
+	}
+	if b, ok := data[DockerConfigKey]; ok {
+		var auths map[string]dockerConfigEntry
+		if err := json.Unmarshal(b, &auths); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", DockerConfigKey)
+		}
+		return auths, nil
+	}
+	return nil, fmt.Errorf("no %s or %s key", DockerConfigJSONKey, DockerConfigKey)
+}
+
+func (e dockerConfigEntry) credentials() (string, string, error) {
+	if e.Auth == "" {
+		return e.Username, e.Password, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(e.Auth)
+	if err != nil {
+		return "", "", errors.Wrap(err, "invalid auth")
+	}
+	parts := strings.SplitN(string(b), ":", 2)
+	if len(parts) != 2 {
+		return "", "", errors.New("invalid auth, expected username:password")
+	}
+	return parts[0], parts[1], nil
+}
+
+// dockerHubHosts are the hosts of Docker Hub found in docker configs.
+var dockerHubHosts = map[string]bool{
+	name.DefaultRegistry:   true,
+	"docker.io":            true,
+	"registry-1.docker.io": true,
+}
+
+// registryMatches returns true if the docker config key, e.g. https://index.docker.io/v1/,
+// is the registry. Keys may match subdomains with a wildcard, e.g. *.example.com.
+func registryMatches(key, registry string) bool {
+	host := key
+	if u, err := url.Parse(key); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	host = strings.SplitN(host, "/", 2)[0]
+	if host == registry {
+		return true
+	}
+	if dockerHubHosts[host] && dockerHubHosts[registry] {
+		return true
+	}
+	if strings.HasPrefix(host, "*.") {
+		suffix := host[1:]
+		return strings.HasSuffix(registry, suffix) && !strings.Contains(strings.TrimSuffix(registry, suffix), ".")
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registryauth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func basicAuth(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure(v1beta1.RegistryCredentialsSpec{})
+	testutil.CheckError(t, false, Configure(v1beta1.RegistryCredentialsSpec{PullSecrets: []string{"kritis/registry"}}))
+	testutil.CheckError(t, true, Configure(v1beta1.RegistryCredentialsSpec{PullSecrets: []string{"registry"}}))
+}
+
+func TestRegistryMatches(t *testing.T) {
+	tests := []struct {
+		key      string
+		registry string
+		expected bool
+	}{
+		{"registry.example.com", "registry.example.com", true},
+		{"registry.example.com:5000", "registry.example.com:5000", true},
+		{"https://registry.example.com/v2/", "registry.example.com", true},
+		{"https://index.docker.io/v1/", "index.docker.io", true},
+		{"docker.io", "index.docker.io", true},
+		{"*.example.com", "registry.example.com", true},
+		{"*.example.com", "a.registry.example.com", false},
+		{"registry.example.com", "registry.example.com:5000", false},
+		{"example.com", "registry.example.com", false},
+	}
+	for _, test := range tests {
+		t.Run(test.key+" "+test.registry, func(t *testing.T) {
+			testutil.DeepEqual(t, test.expected, registryMatches(test.key, test.registry))
+		})
+	}
+}
+
+func TestForPod(t *testing.T) {
+	secrets := map[string]map[string][]byte{
+		"team/pod-secret": {
+			DockerConfigJSONKey: []byte(fmt.Sprintf(`{"auths": {"registry.example.com": {"auth": %q}}}`, basicAuth("pod", "secret"))),
+		},
+		"team/sa-secret": {
+			DockerConfigKey: []byte(`{"https://registry.example.com": {"username": "sa", "password": "secret"}, "other.example.com": {"username": "other", "password": "secret"}}`),
+		},
+		"kritis/global": {
+			DockerConfigJSONKey: []byte(`{"auths": {"*.example.com": {"username": "global", "password": "secret"}}}`),
+		},
+		"team/invalid": {
+			"token": []byte("secret"),
+		},
+	}
+	originalSecret, originalSA := fetchSecret, fetchServiceAccount
+	defer func() {
+		fetchSecret, fetchServiceAccount = originalSecret, originalSA
+		cachedSecrets = map[string]cachedSecret{}
+		Configure(v1beta1.RegistryCredentialsSpec{})
+	}()
+	cachedSecrets = map[string]cachedSecret{}
+	fetchSecret = func(namespace, name string) (map[string][]byte, error) {
+		if data, ok := secrets[namespace+"/"+name]; ok {
+			return data, nil
+		}
+		return nil, fmt.Errorf("secret %s/%s not found", namespace, name)
+	}
+	var serviceAccount string
+	fetchServiceAccount = func(namespace, name string) (*v1.ServiceAccount, error) {
+		serviceAccount = namespace + "/" + name
+		return &v1.ServiceAccount{ImagePullSecrets: []v1.LocalObjectReference{{Name: "sa-secret"}}}, nil
+	}
+	if err := Configure(v1beta1.RegistryCredentialsSpec{PullSecrets: []string{"kritis/global"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	kc := ForPod("team", "", []v1.LocalObjectReference{{Name: "missing"}, {Name: "invalid"}, {Name: "pod-secret"}})
+	tests := []struct {
+		registry string
+		expected authn.Authenticator
+	}{
+		{"registry.example.com", &authn.Basic{Username: "pod", Password: "secret"}},
+		{"other.example.com", &authn.Basic{Username: "other", Password: "secret"}},
+		{"third.example.com", &authn.Basic{Username: "global", Password: "secret"}},
+		{"registry.internal", authn.Anonymous},
+	}
+	for _, test := range tests {
+		t.Run(test.registry, func(t *testing.T) {
+			reg, err := name.NewRegistry(test.registry, name.WeakValidation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			auth, err := kc.Resolve(reg)
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, auth)
+		})
+	}
+	testutil.DeepEqual(t, "team/default", serviceAccount)
+}
+
+func TestSecretAuthsCache(t *testing.T) {
+	originalSecret, originalNow := fetchSecret, now
+	defer func() {
+		fetchSecret, now = originalSecret, originalNow
+		cachedSecrets = map[string]cachedSecret{}
+	}()
+	cachedSecrets = map[string]cachedSecret{}
+	current := time.Date(2018, 10, 2, 15, 4, 5, 0, time.UTC)
+	now = func() time.Time { return current }
+	reads := map[string]int{}
+	fetchSecret = func(namespace, name string) (map[string][]byte, error) {
+		reads[namespace+"/"+name]++
+		if name == "missing" {
+			return nil, fmt.Errorf("secret %s/%s not found", namespace, name)
+		}
+		return map[string][]byte{DockerConfigJSONKey: []byte(`{"auths": {"registry.example.com": {"username": "pod", "password": "secret"}}}`)}, nil
+	}
+
+	// Secrets, and failures to read them, are cached until they expire
+	for i := 0; i < 2; i++ {
+		auths, err := secretAuths("team/registry")
+		testutil.CheckErrorAndDeepEqual(t, false, err, 1, len(auths))
+		_, err = secretAuths("team/missing")
+		testutil.CheckError(t, true, err)
+	}
+	testutil.DeepEqual(t, map[string]int{"team/registry": 1, "team/missing": 1}, reads)
+	current = current.Add(secretTTL)
+	secretAuths("team/registry")
+	testutil.DeepEqual(t, 2, reads["team/registry"])
+}
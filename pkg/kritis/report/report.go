@@ -0,0 +1,233 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package report aggregates the compliance of the namespaces reviewed by the cron job
+// into periodic reports, written to a ConfigMap, Cloud Storage or sent by email.
+package report
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+// DefaultInterval between reports if not configured.
+const DefaultInterval = 24 * time.Hour
+
+// For testing
+var now = time.Now
+
+// Report is the compliance of every namespace with an ImageSecurityPolicy, as of their last review.
+type Report struct {
+	Time       time.Time   `json:"time"`
+	Namespaces []Namespace `json:"namespaces"`
+}
+
+// Namespace is the compliance of the pods of a namespace.
+type Namespace struct {
+	Name string `json:"name"`
+	// ReviewTime is when the pods of the namespace were last reviewed
+	ReviewTime time.Time `json:"reviewTime"`
+	Pods       int       `json:"pods"`
+	Images     int       `json:"images"`
+	// ViolatingImages are the images violating an ImageSecurityPolicy
+	ViolatingImages []ViolatingImage `json:"violatingImages,omitempty"`
+	// WaivedCVEs are the CVEs found in the images but whitelisted by a policy
+	WaivedCVEs []WaivedCVE `json:"waivedCVEs,omitempty"`
+	// UnattestedPods are the pods with images without a verified attestation
+	UnattestedPods []string `json:"unattestedPods,omitempty"`
+	// Waive computes WaivedCVEs if set, only once a report is due as it reads the
+	// vulnerabilities of every image reviewed.
+	Waive func(ctx context.Context) []WaivedCVE `json:"-"`
+}
+
+// ViolatingImage is an image violating the policies of a namespace.
+type ViolatingImage struct {
+	Image    string   `json:"image"`
+	Pods     []string `json:"pods"`
+	Policies []string `json:"policies"`
+	// Types of the violations, e.g. SeverityViolation
+	Types []string `json:"types"`
+}
+
+// WaivedCVE is a CVE whitelisted by a policy, with the images it was found in.
+type WaivedCVE struct {
+	CVE    string   `json:"cve"`
+	Policy string   `json:"policy"`
+	Images []string `json:"images"`
+}
+
+// Compliant returns true if no image of the namespace violates a policy and all of
+// its pods are attested.
+func (n Namespace) Compliant() bool {
+	return len(n.ViolatingImages) == 0 && len(n.UnattestedPods) == 0
+}
+
+// Summary returns a line of text summarizing the report.
+func (r *Report) Summary() string {
+	compliant := 0
+	for _, ns := range r.Namespaces {
+		if ns.Compliant() {
+			compliant++
+		}
+	}
+	return fmt.Sprintf("%d of %d namespaces compliant", compliant, len(r.Namespaces))
+}
+
+// Sink is a destination of reports.
+type Sink interface {
+	Write(ctx context.Context, r *Report) error
+}
+
+// Reporter keeps the compliance of each namespace and writes it to its sinks every interval.
+// The methods of a nil Reporter do nothing, when no report is configured.
+type Reporter struct {
+	mu         sync.Mutex
+	namespaces map[string]Namespace
+	interval   time.Duration
+	sinks      []Sink
+}
+
+// NewReporter returns a Reporter writing to sinks every interval.
+func NewReporter(interval time.Duration, sinks ...Sink) *Reporter {
+	return &Reporter{namespaces: map[string]Namespace{}, interval: interval, sinks: sinks}
+}
+
+// New returns a Reporter configured by spec, using client for ConfigMaps and secrets.
+// It returns nil if spec has no sinks.
+func New(spec v1beta1.ComplianceReportSpec, client kubernetes.Interface) (*Reporter, error) {
+	if len(spec.Sinks) == 0 {
+		return nil, nil
+	}
+	interval := DefaultInterval
+	if spec.Interval != "" {
+		var err error
+		if interval, err = time.ParseDuration(spec.Interval); err != nil {
+			return nil, errors.Wrap(err, "invalid interval")
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("interval must be positive, got %s", spec.Interval)
+		}
+	}
+	var sinks []Sink
+	for _, s := range spec.Sinks {
+		sink, err := newSink(context.Background(), s, client)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return NewReporter(interval, sinks...), nil
+}
+
+// Update records the compliance of a namespace, replacing that of its previous review.
+func (r *Reporter) Update(ns Namespace) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.namespaces[ns.Name] = ns
+}
+
+// Retain drops the namespaces not in names, e.g. once their policies are deleted.
+func (r *Reporter) Retain(names []string) {
+	if r == nil {
+		return
+	}
+	keep := map[string]bool{}
+	for _, name := range names {
+		keep[name] = true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name := range r.namespaces {
+		if !keep[name] {
+			delete(r.namespaces, name)
+		}
+	}
+}
+
+// Report returns the current report, with namespaces sorted by name and their waived
+// CVEs computed.
+func (r *Reporter) Report(ctx context.Context) *Report {
+	report := &Report{Time: now(), Namespaces: []Namespace{}}
+	if r == nil {
+		return report
+	}
+	r.mu.Lock()
+	for _, ns := range r.namespaces {
+		report.Namespaces = append(report.Namespaces, ns)
+	}
+	r.mu.Unlock()
+	// The waived CVEs are computed without the lock, not to hold the reviews updating it
+	for i, ns := range report.Namespaces {
+		if ns.Waive != nil {
+			report.Namespaces[i].WaivedCVEs = ns.Waive(ctx)
+			report.Namespaces[i].Waive = nil
+		}
+	}
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		return report.Namespaces[i].Name < report.Namespaces[j].Name
+	})
+	return report
+}
+
+// Write writes the current report to all sinks.
+func (r *Reporter) Write(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	report := r.Report(ctx)
+	var errs []string
+	for _, s := range r.sinks {
+		if err := s.Write(ctx, report); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to write compliance report: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Run writes the report every interval until ctx is done.
+func (r *Reporter) Run(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := r.Write(ctx); err != nil {
+				glog.Error(err)
+			}
+		}
+	}
+}
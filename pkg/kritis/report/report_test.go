@@ -0,0 +1,155 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+var reportTime = time.Date(2018, 10, 2, 15, 4, 5, 0, time.UTC)
+
+type memorySink struct {
+	reports []*Report
+	err     error
+}
+
+func (s *memorySink) Write(ctx context.Context, r *Report) error {
+	s.reports = append(s.reports, r)
+	return s.err
+}
+
+func TestReporter(t *testing.T) {
+	originalNow := now
+	now = func() time.Time { return reportTime }
+	defer func() { now = originalNow }()
+
+	sink := &memorySink{}
+	failing := &memorySink{err: fmt.Errorf("unavailable")}
+	r := NewReporter(time.Hour, sink, failing)
+	r.Update(Namespace{Name: "qa", Pods: 1})
+	r.Update(Namespace{Name: "prod", Pods: 2, UnattestedPods: []string{"app"}})
+	r.Update(Namespace{Name: "dev", Pods: 3})
+	r.Update(Namespace{Name: "qa", Pods: 4})
+	r.Retain([]string{"prod", "qa"})
+
+	expected := &Report{
+		Time: reportTime,
+		Namespaces: []Namespace{
+			{Name: "prod", Pods: 2, UnattestedPods: []string{"app"}},
+			{Name: "qa", Pods: 4},
+		},
+	}
+	testutil.DeepEqual(t, expected, r.Report(context.Background()))
+	testutil.DeepEqual(t, "1 of 2 namespaces compliant", r.Report(context.Background()).Summary())
+
+	// All sinks are written, even if one fails
+	testutil.CheckError(t, true, r.Write(context.Background()))
+	testutil.DeepEqual(t, []*Report{expected}, sink.reports)
+	testutil.DeepEqual(t, []*Report{expected}, failing.reports)
+}
+
+func TestNilReporter(t *testing.T) {
+	var r *Reporter
+	r.Update(Namespace{Name: "qa"})
+	r.Retain(nil)
+	r.Run(context.Background())
+	testutil.CheckError(t, false, r.Write(context.Background()))
+	testutil.DeepEqual(t, []Namespace{}, r.Report(context.Background()).Namespaces)
+}
+
+func TestRun(t *testing.T) {
+	sink := &memorySink{}
+	r := NewReporter(10*time.Millisecond, sink)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	r.Run(ctx)
+	if len(sink.reports) == 0 {
+		t.Errorf("expected reports to be written")
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      v1beta1.ComplianceReportSpec
+		interval  time.Duration
+		shouldErr bool
+	}{
+		{
+			name: "no sinks",
+		},
+		{
+			name:     "default interval",
+			spec:     v1beta1.ComplianceReportSpec{Sinks: []v1beta1.ComplianceReportSinkSpec{{Type: ConfigMap, Namespace: "kritis"}}},
+			interval: DefaultInterval,
+		},
+		{
+			name:     "interval",
+			spec:     v1beta1.ComplianceReportSpec{Interval: "168h", Sinks: []v1beta1.ComplianceReportSinkSpec{{Type: ConfigMap, Namespace: "kritis"}}},
+			interval: 168 * time.Hour,
+		},
+		{
+			name:      "invalid interval",
+			spec:      v1beta1.ComplianceReportSpec{Interval: "daily", Sinks: []v1beta1.ComplianceReportSinkSpec{{Type: ConfigMap, Namespace: "kritis"}}},
+			shouldErr: true,
+		},
+		{
+			name:      "negative interval",
+			spec:      v1beta1.ComplianceReportSpec{Interval: "-1h", Sinks: []v1beta1.ComplianceReportSinkSpec{{Type: ConfigMap, Namespace: "kritis"}}},
+			shouldErr: true,
+		},
+		{
+			name:      "unknown type",
+			spec:      v1beta1.ComplianceReportSpec{Sinks: []v1beta1.ComplianceReportSinkSpec{{Type: "s3"}}},
+			shouldErr: true,
+		},
+		{
+			name:      "gcs without bucket",
+			spec:      v1beta1.ComplianceReportSpec{Sinks: []v1beta1.ComplianceReportSinkSpec{{Type: GCS}}},
+			shouldErr: true,
+		},
+		{
+			name:      "smtp without recipients",
+			spec:      v1beta1.ComplianceReportSpec{Sinks: []v1beta1.ComplianceReportSinkSpec{{Type: SMTP, Address: "smtp.example.com:587", From: "kritis@example.com"}}},
+			shouldErr: true,
+		},
+		{
+			name:      "smtp without port",
+			spec:      v1beta1.ComplianceReportSpec{Sinks: []v1beta1.ComplianceReportSinkSpec{{Type: SMTP, Address: "smtp.example.com", From: "kritis@example.com", To: []string{"security@example.com"}}}},
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := New(test.spec, nil)
+			testutil.CheckError(t, test.shouldErr, err)
+			if test.interval == 0 {
+				if r != nil {
+					t.Errorf("expected no reporter, got %v", r)
+				}
+				return
+			}
+			testutil.DeepEqual(t, test.interval, r.interval)
+		})
+	}
+}
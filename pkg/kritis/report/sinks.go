@@ -0,0 +1,257 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/smtp"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/option"
+	storage "google.golang.org/api/storage/v1"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/gcpauth"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+// Sink types of ComplianceReportSinkSpecs
+const (
+	ConfigMap = "configmap"
+	GCS       = "gcs"
+	SMTP      = "smtp"
+)
+
+// DefaultConfigMapName is the ConfigMap holding the latest report if not configured.
+const DefaultConfigMapName = "kritis-compliance-report"
+
+// Key of the report in the ConfigMaps, and keys of the SMTP secret
+const (
+	ReportKey   = "report.json"
+	UsernameKey = "username"
+	PasswordKey = "password"
+)
+
+// ReportLabel labels the ConfigMaps holding the namespaces of a report with its name.
+const ReportLabel = "kritis.grafeas.io/compliance-report"
+
+// For testing
+var (
+	clientOptions []option.ClientOption
+	fetchSecret   = secrets.FetchData
+	sendMail      = smtp.SendMail
+	namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+func newSink(ctx context.Context, spec v1beta1.ComplianceReportSinkSpec, client kubernetes.Interface) (Sink, error) {
+	switch spec.Type {
+	case ConfigMap:
+		namespace := spec.Namespace
+		if namespace == "" {
+			b, err := ioutil.ReadFile(namespaceFile)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read the namespace of Kritis, set the namespace of the configmap")
+			}
+			namespace = strings.TrimSpace(string(b))
+		}
+		name := spec.Name
+		if name == "" {
+			name = DefaultConfigMapName
+		}
+		return &configMapSink{client: client, namespace: namespace, name: name}, nil
+	case GCS:
+		if spec.Bucket == "" {
+			return nil, fmt.Errorf("gcs compliance reports need a bucket")
+		}
+		return newGCSSink(ctx, spec.Bucket, spec.Prefix)
+	case SMTP:
+		if spec.Address == "" || spec.From == "" || len(spec.To) == 0 {
+			return nil, fmt.Errorf("smtp compliance reports need an address, a sender and recipients")
+		}
+		host, _, err := net.SplitHostPort(spec.Address)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid smtp address %q", spec.Address)
+		}
+		s := &smtpSink{address: spec.Address, from: spec.From, to: spec.To}
+		if spec.Secret != "" {
+			parts := strings.SplitN(spec.Secret, "/", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid secret %q of smtp compliance reports, expected namespace/name", spec.Secret)
+			}
+			data, err := fetchSecret(parts[0], parts[1])
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to get secret of smtp compliance reports")
+			}
+			s.auth = smtp.PlainAuth("", string(data[UsernameKey]), string(data[PasswordKey]), host)
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown compliance report type %q", spec.Type)
+}
+
+// configMapSink keeps the latest report in ConfigMaps. As a ConfigMap holds at most
+// 1MiB, the compliance of each namespace is kept in its own ConfigMap, named after
+// the report and the namespace, and the report ConfigMap only lists them.
+type configMapSink struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// configMapIndex is the content of the report ConfigMap.
+type configMapIndex struct {
+	Time       time.Time             `json:"time"`
+	Summary    string                `json:"summary"`
+	Namespaces []configMapIndexEntry `json:"namespaces"`
+}
+
+type configMapIndexEntry struct {
+	Name      string `json:"name"`
+	Compliant bool   `json:"compliant"`
+	// ConfigMap holding the compliance of the namespace
+	ConfigMap string `json:"configMap"`
+}
+
+func (s *configMapSink) Write(ctx context.Context, r *Report) error {
+	index := configMapIndex{Time: r.Time, Summary: r.Summary(), Namespaces: []configMapIndexEntry{}}
+	written := map[string]bool{}
+	for _, ns := range r.Namespaces {
+		name := s.name + "-" + ns.Name
+		if err := s.write(name, ns); err != nil {
+			return err
+		}
+		written[name] = true
+		index.Namespaces = append(index.Namespaces, configMapIndexEntry{Name: ns.Name, Compliant: ns.Compliant(), ConfigMap: name})
+	}
+	if err := s.write(s.name, index); err != nil {
+		return err
+	}
+	// Drop the namespaces which are no longer reported
+	cms, err := s.client.CoreV1().ConfigMaps(s.namespace).List(metav1.ListOptions{LabelSelector: ReportLabel + "=" + s.name})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list the configmaps of report %s/%s", s.namespace, s.name)
+	}
+	for _, cm := range cms.Items {
+		if written[cm.Name] {
+			continue
+		}
+		if err := s.client.CoreV1().ConfigMaps(s.namespace).Delete(cm.Name, &metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete configmap %s/%s", s.namespace, cm.Name)
+		}
+	}
+	return nil
+}
+
+// write sets the report key of ConfigMap name to the JSON of v. The ConfigMaps of
+// namespaces are labeled with the name of the report.
+func (s *configMapSink) write(name string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	var labels map[string]string
+	if name != s.name {
+		labels = map[string]string{ReportLabel: s.name}
+	}
+	cms := s.client.CoreV1().ConfigMaps(s.namespace)
+	cm, err := cms.Get(name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		cm = &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: s.namespace, Labels: labels}}
+		cm.Data = map[string]string{ReportKey: string(b)}
+		_, err = cms.Create(cm)
+	} else if err == nil {
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[ReportKey] = string(b)
+		_, err = cms.Update(cm)
+	}
+	return errors.Wrapf(err, "failed to write report to configmap %s/%s", s.namespace, name)
+}
+
+// gcsSink writes each report to its own object in a Cloud Storage bucket.
+type gcsSink struct {
+	service *storage.Service
+	bucket  string
+	prefix  string
+}
+
+func newGCSSink(ctx context.Context, bucket, prefix string) (*gcsSink, error) {
+	opts, err := gcpauth.ClientOptions(ctx, gcpauth.Storage)
+	if err != nil {
+		return nil, err
+	}
+	service, err := storage.NewService(ctx, append(opts, clientOptions...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create Cloud Storage client")
+	}
+	return &gcsSink{service: service, bucket: bucket, prefix: prefix}, nil
+}
+
+func (s *gcsSink) Write(ctx context.Context, r *Report) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	name := path.Join(s.prefix, r.Time.UTC().Format("2006/01/02/150405")+".json")
+	obj := &storage.Object{Name: name, ContentType: "application/json"}
+	if _, err := s.service.Objects.Insert(s.bucket, obj).Media(bytes.NewReader(b)).Context(ctx).Do(); err != nil {
+		return errors.Wrapf(err, "failed to write report to gs://%s", s.bucket)
+	}
+	return nil
+}
+
+// smtpSink emails reports, as a plain text summary followed by the JSON report.
+type smtpSink struct {
+	address string
+	auth    smtp.Auth
+	from    string
+	to      []string
+}
+
+func (s *smtpSink) Write(ctx context.Context, r *Report) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&msg, "Subject: Kritis compliance report: %s\r\n", r.Summary())
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	for _, ns := range r.Namespaces {
+		fmt.Fprintf(&msg, "%s: %d violating images, %d waived CVEs, %d unattested pods\r\n",
+			ns.Name, len(ns.ViolatingImages), len(ns.WaivedCVEs), len(ns.UnattestedPods))
+	}
+	fmt.Fprintf(&msg, "\r\n%s\r\n", strings.Replace(string(b), "\n", "\r\n", -1))
+	if err := sendMail(s.address, s.auth, s.from, s.to, msg.Bytes()); err != nil {
+		return errors.Wrapf(err, "failed to email report through %s", s.address)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/option"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+var testReport = &Report{
+	Time: reportTime,
+	Namespaces: []Namespace{{
+		Name:            "prod",
+		ReviewTime:      reportTime,
+		Pods:            2,
+		Images:          1,
+		ViolatingImages: []ViolatingImage{{Image: "gcr.io/my-project/app@sha256:...", Pods: []string{"app"}, Policies: []string{"prod/isp"}, Types: []string{"SeverityViolation"}}},
+	}},
+}
+
+func TestConfigMapSink(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	s, err := newSink(context.Background(), v1beta1.ComplianceReportSinkSpec{Type: ConfigMap, Namespace: "kritis"}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The ConfigMaps are created, then updated with the latest report
+	for _, r := range []*Report{{Time: reportTime, Namespaces: []Namespace{{Name: "qa"}}}, testReport} {
+		if err := s.Write(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cm, err := client.CoreV1().ConfigMaps("kritis").Get(DefaultConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(cm.Data[ReportKey], `"configMap": "kritis-compliance-report-prod"`) {
+		t.Errorf("expected the latest report to list the prod namespace, got %q", cm.Data[ReportKey])
+	}
+	cm, err = client.CoreV1().ConfigMaps("kritis").Get(DefaultConfigMapName+"-prod", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.DeepEqual(t, DefaultConfigMapName, cm.Labels[ReportLabel])
+	if !strings.Contains(cm.Data[ReportKey], `"image": "gcr.io/my-project/app@sha256:..."`) {
+		t.Errorf("expected the compliance of the prod namespace, got %q", cm.Data[ReportKey])
+	}
+	// The namespaces no longer reported are deleted
+	cms, err := client.CoreV1().ConfigMaps("kritis").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.DeepEqual(t, 2, len(cms.Items))
+}
+
+func TestGCSSink(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, "/b/kritis-reports/o") {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	clientOptions = []option.ClientOption{option.WithEndpoint(server.URL), option.WithoutAuthentication()}
+	defer func() { clientOptions = nil }()
+
+	s, err := newSink(context.Background(), v1beta1.ComplianceReportSinkSpec{Type: GCS, Bucket: "kritis-reports", Prefix: "compliance"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(context.Background(), testReport); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body, `"name":"compliance/2018/10/02/150405.json"`) {
+		t.Errorf("expected the object name in the upload, got %q", body)
+	}
+	if !strings.Contains(body, `"name":"prod"`) {
+		t.Errorf("expected the report to be uploaded, got %q", body)
+	}
+}
+
+func TestSMTPSink(t *testing.T) {
+	originalFetch, originalSend := fetchSecret, sendMail
+	defer func() { fetchSecret, sendMail = originalFetch, originalSend }()
+	fetchSecret = func(namespace, name string) (map[string][]byte, error) {
+		testutil.DeepEqual(t, "kritis/smtp", namespace+"/"+name)
+		return map[string][]byte{UsernameKey: []byte("kritis"), PasswordKey: []byte("secret")}, nil
+	}
+	var addr, from, msg string
+	var to []string
+	var auth smtp.Auth
+	sendMail = func(a string, au smtp.Auth, f string, t []string, m []byte) error {
+		addr, auth, from, to, msg = a, au, f, t, string(m)
+		return nil
+	}
+
+	s, err := newSink(context.Background(), v1beta1.ComplianceReportSinkSpec{
+		Type:    SMTP,
+		Address: "smtp.example.com:587",
+		From:    "kritis@example.com",
+		To:      []string{"security@example.com", "sre@example.com"},
+		Secret:  "kritis/smtp",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(context.Background(), testReport); err != nil {
+		t.Fatal(err)
+	}
+	testutil.DeepEqual(t, "smtp.example.com:587", addr)
+	testutil.DeepEqual(t, "kritis@example.com", from)
+	testutil.DeepEqual(t, []string{"security@example.com", "sre@example.com"}, to)
+	if auth == nil {
+		t.Errorf("expected the credentials of the secret to be used")
+	}
+	for _, expected := range []string{
+		"To: security@example.com, sre@example.com\r\n",
+		"Subject: Kritis compliance report: 0 of 1 namespaces compliant\r\n",
+		"prod: 1 violating images, 0 waived CVEs, 0 unattested pods\r\n",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("expected message to contain %q, got %q", expected, msg)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package review
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// auditTTL is how long the same decision on an image isn't recorded again.
+	auditTTL = 24 * time.Hour
+	// maxAudits bounds the number of decisions remembered.
+	maxAudits = 10000
+)
+
+var audited = newAuditTracker()
+
+// auditTracker remembers the audit occurrences recorded, so each review of an
+// image, e.g. by every pod using it or every cron run, doesn't record another
+// occurrence of the same decision. State is only kept in memory.
+type auditTracker struct {
+	mu       sync.Mutex
+	recorded map[string]time.Time
+	now      func() time.Time
+}
+
+func newAuditTracker() *auditTracker {
+	return &auditTracker{recorded: map[string]time.Time{}, now: time.Now}
+}
+
+// seen returns true if the decision identified by key was recorded within auditTTL.
+func (t *auditTracker) seen(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.recorded[key]
+	return ok && t.now().Sub(r) < auditTTL
+}
+
+// record remembers that the decision identified by key was recorded. Once
+// maxAudits decisions are remembered, expired ones are dropped, then the oldest.
+func (t *auditTracker) record(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	if _, ok := t.recorded[key]; !ok && len(t.recorded) >= maxAudits {
+		oldest := ""
+		for k, r := range t.recorded {
+			if now.Sub(r) >= auditTTL {
+				delete(t.recorded, k)
+			} else if oldest == "" || r.Before(t.recorded[oldest]) {
+				oldest = k
+			}
+		}
+		if len(t.recorded) >= maxAudits {
+			delete(t.recorded, oldest)
+		}
+	}
+	t.recorded[key] = now
+}
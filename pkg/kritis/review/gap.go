@@ -0,0 +1,164 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package review
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/crd/attestationpolicy"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/decisionlog"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/tracing"
+)
+
+// ReviewGAP reviews a set of images against GenericAttestationPolicies, independently of
+// ImageSecurityPolicies. Images need a valid attestation of one of the AttestationAuthorities
+// of each policy, and attestations of all the Binary Authorization attestors it requires.
+// Returns error if violations are found and handles them as per violation strategy
+func (r Reviewer) ReviewGAP(ctx context.Context, images []string, gaps []v1beta1.GenericAttestationPolicy, pod *v1.Pod) (err error) {
+	ctx, span := tracing.Start(ctx, "ReviewGAP", trace.WithAttributes(
+		attribute.StringSlice("kritis.images", images),
+		attribute.Int("kritis.policies", len(gaps)),
+	))
+	defer func() { tracing.End(span, err) }()
+	if len(gaps) == 0 {
+		return nil
+	}
+	if pod != nil {
+		if gaps, err = attestationpolicy.SelectGenericAttestationPolicies(gaps, pod.Labels); err != nil {
+			return err
+		}
+	}
+	if len(images) == 0 || len(gaps) == 0 {
+		return nil
+	}
+	if images, err = r.removeClusterWhitelisted(images); err != nil {
+		return err
+	}
+
+	for _, gap := range gaps {
+		glog.Infof("validating against GenericAttestationPolicy: %s", gap.Name)
+		auths, err := r.getAttestationAuthorities(gap.Namespace, gap.Name, gap.Spec.AttestationAuthorityNames, gap.Spec.AttestationAuthoritySelector)
+		if err != nil {
+			return err
+		}
+		for _, image := range images {
+			if attestationpolicy.Whitelisted(gap, image) {
+				glog.Infof("%q is whitelisted in GenericAttestationPolicy %q", image, gap.Name)
+				continue
+			}
+			violations, err := r.validateGAP(ctx, gap, auths, image)
+			if err != nil {
+				return errors.Wrap(err, "failed validating generic attestation policy")
+			}
+			r.recordReview("GenericAttestationPolicy", gap.Namespace, gap.Name, image, pod, len(violations) == 0, violations)
+			if len(violations) != 0 {
+				if err := r.handleGAPViolations(ctx, gap, image, pod, violations); err != nil {
+					return err
+				}
+				continue
+			}
+			glog.Infof("found no violations for %q within GAP %q", image, gap.Name)
+		}
+	}
+	return nil
+}
+
+// ValidateGAP returns the violations of image for each of gaps, in order, without
+// handling them. Images whitelisted by the cluster pass all policies, as in ReviewGAP.
+// The attestations verified are added to the decision of ctx.
+func (r Reviewer) ValidateGAP(ctx context.Context, image string, gaps []v1beta1.GenericAttestationPolicy) ([][]policy.Violation, error) {
+	violations := make([][]policy.Violation, len(gaps))
+	whitelisted, err := r.clusterWhitelisted(image)
+	if err != nil {
+		return nil, err
+	}
+	if whitelisted {
+		return violations, nil
+	}
+	for i, gap := range gaps {
+		if attestationpolicy.Whitelisted(gap, image) {
+			continue
+		}
+		auths, err := r.getAttestationAuthorities(gap.Namespace, gap.Name, gap.Spec.AttestationAuthorityNames, gap.Spec.AttestationAuthoritySelector)
+		if err != nil {
+			return nil, err
+		}
+		vs, err := r.validateGAP(ctx, gap, auths, image)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed validating generic attestation policy %s", gap.Name)
+		}
+		violations[i] = vs
+	}
+	return violations, nil
+}
+
+func (r Reviewer) validateGAP(ctx context.Context, gap v1beta1.GenericAttestationPolicy, auths []v1beta1.AttestationAuthority, image string) ([]policy.Violation, error) {
+	attestations, err := r.client.Attestations(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	var violations []policy.Violation
+	if len(auths) > 0 {
+		id, ok := r.verifiedAttestation(image, attestations, auths)
+		decisionlog.FromContext(ctx).AddAttestation(image, id, ok)
+		if !ok {
+			names := make([]string, len(auths))
+			for i, a := range auths {
+				names[i] = a.Name
+			}
+			violations = append(violations, securitypolicy.NewViolation(nil, policy.UnattestedImageViolation,
+				policy.Reason(fmt.Sprintf("%q is not attested by any attestation authority: [%s]", image, strings.Join(names, ",")))))
+		}
+	}
+	if len(gap.Spec.RequireAttestationsBy) > 0 {
+		vs, err := securitypolicy.RequiredAttestationViolations(image, gap.Spec.RequireAttestationsBy, attestations, r.config.Attestors)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, vs...)
+	}
+	return violations, nil
+}
+
+func (r Reviewer) handleGAPViolations(ctx context.Context, gap v1beta1.GenericAttestationPolicy, image string, pod *v1.Pod, violations []policy.Violation) error {
+	var violationSummaries []string
+	decision := decisionlog.FromContext(ctx)
+	for _, v := range violations {
+		violationSummaries = append(violationSummaries, fmt.Sprintf("%s: %s", v.Type().ToString(), v.Reason()))
+		decision.AddViolation(image, gap.Namespace, gap.Name, v.Type().ToString(), string(v.Reason()))
+	}
+	errMsg := fmt.Sprintf("found violations in %q (\n%s\n)", image, strings.Join(violationSummaries, ",\n"))
+	if err := r.config.Strategy.HandleViolation(image, pod, violations); err != nil {
+		return errors.Wrapf(err, "failed to handle violation: %s", errMsg)
+	}
+	if r.admits(r.config.Strategy) {
+		glog.Warningf("admitting %q, since the violation strategies don't deny: %s", image, errMsg)
+		return nil
+	}
+	return fmt.Errorf(errMsg)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package review
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/decisionlog"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	"github.com/grafeas/kritis/pkg/kritis/util"
+	"github.com/grafeas/kritis/pkg/kritis/violation"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReviewGAP(t *testing.T) {
+	sec, pub := testutil.CreateSecret(t, "sec")
+	sig, err := util.CreateAttestationSignature(testutil.QualifiedImage, sec)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	attested := []metadata.PGPAttestation{{Signature: sig, KeyID: sec.PgpKey.Fingerprint()}}
+	authMock := func(ns string, name string) (*v1beta1.AttestationAuthority, error) {
+		if name != "test" {
+			return nil, fmt.Errorf("no authority %s", name)
+		}
+		return &v1beta1.AttestationAuthority{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: v1beta1.AttestationAuthoritySpec{
+				PrivateKeySecretName: "sec",
+				PublicKeyData:        base64.StdEncoding.EncodeToString([]byte(pub)),
+			},
+		}, nil
+	}
+	gap := v1beta1.GenericAttestationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "gap", Namespace: "foo"},
+		Spec: v1beta1.GenericAttestationPolicySpec{
+			AttestationAuthorityNames: []string{"test"},
+		},
+	}
+	whitelisted := gap
+	whitelisted.Spec.ImageWhitelist = []string{testutil.QualifiedImage}
+	frontend := gap
+	frontend.Spec.PodSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "frontend"}}
+	tests := []struct {
+		name         string
+		gaps         []v1beta1.GenericAttestationPolicy
+		attestations []metadata.PGPAttestation
+		labels       map[string]string
+		shdErr       bool
+		violation    bool
+	}{
+		{"attested image passes", []v1beta1.GenericAttestationPolicy{gap}, attested, nil, false, false},
+		{"unattested image is denied", []v1beta1.GenericAttestationPolicy{gap}, nil, nil, true, true},
+		{"whitelisted image passes", []v1beta1.GenericAttestationPolicy{whitelisted}, nil, nil, false, false},
+		{"pods not selected pass", []v1beta1.GenericAttestationPolicy{frontend}, nil, map[string]string{"tier": "batch"}, false, false},
+		{"selected pods are reviewed", []v1beta1.GenericAttestationPolicy{frontend}, nil, map[string]string{"tier": "frontend"}, true, true},
+		{"no policies", nil, nil, nil, false, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			strategy := &violation.MemoryStrategy{
+				Violations:   map[string]bool{},
+				Attestations: map[string]bool{},
+			}
+			r := New(&testutil.MockMetadataClient{PGPAttestations: tc.attestations}, &Config{
+				Auths:                           authMock,
+				Strategy:                        strategy,
+				IsWebhook:                       true,
+				ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+			})
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Labels: tc.labels}}
+			decision := &decisionlog.Decision{}
+			ctx := decisionlog.NewContext(context.Background(), decision)
+			err := r.ReviewGAP(ctx, []string{testutil.QualifiedImage}, tc.gaps, pod)
+			testutil.CheckError(t, tc.shdErr, err)
+			if strategy.Violations[testutil.QualifiedImage] != tc.violation {
+				t.Errorf("expected violation %t, got %t", tc.violation, strategy.Violations[testutil.QualifiedImage])
+			}
+			if logged := len(decision.Violations) > 0; logged != tc.violation {
+				t.Errorf("expected violation in the decision log %t, got %v", tc.violation, decision.Violations)
+			}
+			for _, v := range decision.Violations {
+				testutil.DeepEqual(t, "foo/gap", v.Policy)
+			}
+		})
+	}
+
+	// ValidateGAP reports the violations of each policy without handling them.
+	for _, attestations := range [][]metadata.PGPAttestation{attested, nil} {
+		strategy := &violation.MemoryStrategy{Violations: map[string]bool{}, Attestations: map[string]bool{}}
+		r := New(&testutil.MockMetadataClient{PGPAttestations: attestations}, &Config{
+			Auths:                           authMock,
+			Strategy:                        strategy,
+			IsWebhook:                       true,
+			ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+		})
+		decision := &decisionlog.Decision{}
+		vss, err := r.ValidateGAP(decisionlog.NewContext(context.Background(), decision), testutil.QualifiedImage, []v1beta1.GenericAttestationPolicy{gap, whitelisted})
+		testutil.CheckError(t, false, err)
+		testutil.DeepEqual(t, 2, len(vss))
+		testutil.DeepEqual(t, attestations == nil, len(vss[0]) > 0)
+		testutil.DeepEqual(t, 0, len(vss[1]))
+		testutil.DeepEqual(t, 0, len(strategy.Violations))
+		testutil.DeepEqual(t, 0, len(decision.Violations))
+		testutil.DeepEqual(t, 1, len(decision.Attestations))
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package review
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/decisionlog"
+)
+
+// ValidatePromotions checks that the authorities of promotions are given as namespace/name.
+func ValidatePromotions(promotions []v1beta1.PromotionSpec) error {
+	for _, p := range promotions {
+		if _, _, err := promotionAuthority(p); err != nil {
+			return err
+		}
+		if len(p.Namespaces) == 0 {
+			return fmt.Errorf("promotion by %s has no namespaces", p.Authority)
+		}
+	}
+	return nil
+}
+
+func promotionAuthority(p v1beta1.PromotionSpec) (namespace, name string, err error) {
+	parts := strings.SplitN(p.Authority, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid promotion authority %q, expected namespace/name", p.Authority)
+	}
+	return parts[0], parts[1], nil
+}
+
+// Promote attests the images of pod, which passed all the policies of their namespace, on
+// behalf of the authorities promoting its images. It is called once both ReviewContext and
+// ReviewGAP returned nil for the images, with the same ctx: images are only promoted if ctx
+// carries the decision of their reviews without violations, i.e. none were admitted despite
+// their violations. As in ReviewContext, images whitelisted by the cluster are not promoted.
+func (r Reviewer) Promote(ctx context.Context, images []string, isps []v1beta1.ImageSecurityPolicy, pod *v1.Pod) {
+	if len(r.config.Promotions) == 0 || len(isps) == 0 {
+		return
+	}
+	if d := decisionlog.FromContext(ctx); d == nil || len(d.Violations) > 0 {
+		return
+	}
+	if pod != nil {
+		selected, err := securitypolicy.SelectImageSecurityPolicies(isps, pod.Labels)
+		if err != nil {
+			glog.Errorf("not promoting images of pod %q: %v", pod.Name, err)
+			return
+		}
+		if len(selected) == 0 {
+			return
+		}
+		isps = selected
+	}
+	promoted, err := r.removeClusterWhitelisted(images)
+	if err != nil {
+		glog.Errorf("not promoting %s: %v", images, err)
+		return
+	}
+	r.promote(ctx, isps[0].Namespace, promoted)
+}
+
+// promote attests images of namespace on behalf of the authorities promoting its images.
+// Images are only attested by an authority once, the first time they pass. Failures are
+// logged, since they don't change the decision.
+func (r Reviewer) promote(ctx context.Context, namespace string, images []string) {
+	for _, p := range r.config.Promotions {
+		if !contains(p.Namespaces, namespace) {
+			continue
+		}
+		authNamespace, authName, err := promotionAuthority(p)
+		if err != nil {
+			glog.Error(err)
+			continue
+		}
+		auth, err := r.config.Auths(authNamespace, authName)
+		if err != nil {
+			glog.Errorf("failed to get promotion authority %s: %v", p.Authority, err)
+			continue
+		}
+		_, keyID, err := authorityKey(*auth)
+		if err != nil {
+			glog.Errorf("error parsing key for %q: %v", auth.Name, err)
+			decisionlog.FromContext(ctx).AddWarning("kritis: not promoting images for AttestationAuthority %q: its public key failed to parse: %v", auth.Name, err)
+			continue
+		}
+		for _, image := range images {
+			atts, err := r.client.Attestations(ctx, image)
+			if err != nil {
+				glog.Errorf("failed to get attestations of %s, not promoting it: %v", image, err)
+				continue
+			}
+			if len(getUnAttested([]v1beta1.AttestationAuthority{*auth}, map[string]string{auth.Name: keyID}, atts)) == 0 {
+				continue
+			}
+			glog.Infof("promoting %s of namespace %s with an attestation by %s", image, namespace, p.Authority)
+			if _, errMsgs := r.attest(ctx, image, *auth, authNamespace, keyID, namespace, ""); len(errMsgs) > 0 {
+				glog.Errorf("failed to promote %s: %s", image, errMsgs)
+			}
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package review
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/decisionlog"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	"github.com/grafeas/kritis/pkg/kritis/transparency"
+	"github.com/grafeas/kritis/pkg/kritis/util"
+	"github.com/grafeas/kritis/pkg/kritis/violation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPromote(t *testing.T) {
+	sec, pub := testutil.CreateSecret(t, "promoter")
+	sig, err := util.CreateAttestationSignature(testutil.IntTestImage, sec)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	promotions := []v1beta1.PromotionSpec{{Namespaces: []string{"staging"}, Authority: "prod/promoter"}}
+	auths := func(ns string, name string) (*v1beta1.AttestationAuthority, error) {
+		if ns+"/"+name != "prod/promoter" && ns+"/"+name != "staging/qa" {
+			t.Errorf("unexpected AttestationAuthority %s/%s", ns, name)
+		}
+		return &v1beta1.AttestationAuthority{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+			Spec: v1beta1.AttestationAuthoritySpec{
+				NoteReference:        "v1beta1/projects/prod",
+				PrivateKeySecretName: "promoter-key",
+				PublicKeyData:        base64.StdEncoding.EncodeToString([]byte(pub)),
+			}}, nil
+	}
+	tests := []struct {
+		name          string
+		namespace     string
+		image         string
+		attestations  []metadata.PGPAttestation
+		gaps          []v1beta1.GenericAttestationPolicy
+		shdPromote    bool
+		shdViolations bool
+	}{
+		{name: "image passing the policies of staging", namespace: "staging", image: testutil.IntTestImage, shdPromote: true},
+		{name: "image already promoted", namespace: "staging", image: testutil.IntTestImage,
+			attestations: []metadata.PGPAttestation{{Signature: sig, KeyID: sec.PgpKey.Fingerprint()}}},
+		{name: "violating image", namespace: "staging", image: testutil.QualifiedImage, shdViolations: true},
+		{name: "image denied by a GenericAttestationPolicy", namespace: "staging", image: testutil.IntTestImage,
+			gaps: []v1beta1.GenericAttestationPolicy{{
+				ObjectMeta: metav1.ObjectMeta{Name: "gap", Namespace: "staging"},
+				Spec:       v1beta1.GenericAttestationPolicySpec{AttestationAuthorityNames: []string{"qa"}},
+			}},
+			shdViolations: true},
+		{name: "image of another namespace", namespace: "dev", image: testutil.IntTestImage},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cMock := &testutil.MockMetadataClient{PGPAttestations: tc.attestations}
+			var records []transparency.Record
+			r := New(cMock, &Config{
+				Validate: func(ctx context.Context, now time.Time, isp v1beta1.ImageSecurityPolicy, image string, f metadata.Fetcher, a securitypolicy.AttestorFetcher) ([]policy.Violation, error) {
+					if image == testutil.QualifiedImage {
+						return []policy.Violation{securitypolicy.NewViolation(nil, policy.UnqualifiedImageViolation, "violation")}, nil
+					}
+					return nil, nil
+				},
+				Secret: func(namespace string, name string) (*secrets.PGPSigningSecret, error) {
+					testutil.DeepEqual(t, "prod/promoter-key", namespace+"/"+name)
+					return sec, nil
+				},
+				Auths: auths,
+				Strategy: &violation.MemoryStrategy{
+					Violations:   map[string]bool{},
+					Attestations: map[string]bool{},
+				},
+				ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+				RecordAttestation: func(r transparency.Record) error {
+					records = append(records, r)
+					return nil
+				},
+				Promotions: promotions,
+			})
+			isps := []v1beta1.ImageSecurityPolicy{{ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: tc.namespace}}}
+			// As the webhook, promote images only once they passed all the policies
+			ctx := decisionlog.NewContext(context.Background(), &decisionlog.Decision{})
+			images := []string{tc.image}
+			err := r.ReviewContext(ctx, images, isps, nil)
+			if err == nil {
+				err = r.ReviewGAP(ctx, images, tc.gaps, nil)
+			}
+			if err == nil {
+				r.Promote(ctx, images, isps, nil)
+			}
+			testutil.CheckError(t, tc.shdViolations, err)
+			testutil.DeepEqual(t, tc.shdPromote, cMock.Calls("CreateAttestationOccurence", tc.image) == 1)
+			if tc.shdPromote {
+				testutil.DeepEqual(t, []transparency.Record{{
+					Image:     tc.image,
+					Note:      "v1beta1/projects/prod",
+					Authority: "promoter",
+					KeyID:     sec.PgpKey.Fingerprint(),
+					Namespace: "staging",
+				}}, records)
+			}
+		})
+	}
+}
+
+func TestValidatePromotions(t *testing.T) {
+	var tests = []struct {
+		name       string
+		promotions []v1beta1.PromotionSpec
+		shouldErr  bool
+	}{
+		{"valid", []v1beta1.PromotionSpec{{Namespaces: []string{"staging"}, Authority: "prod/promoter"}}, false},
+		{"authority without namespace", []v1beta1.PromotionSpec{{Namespaces: []string{"staging"}, Authority: "promoter"}}, true},
+		{"no namespaces", []v1beta1.PromotionSpec{{Authority: "prod/promoter"}}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testutil.CheckError(t, test.shouldErr, ValidatePromotions(test.promotions))
+		})
+	}
+}
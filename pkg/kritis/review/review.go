@@ -17,40 +17,87 @@ limitations under the License.
 package review
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
 	"github.com/grafeas/kritis/pkg/kritis/container"
 	"github.com/grafeas/kritis/pkg/kritis/crd/authority"
+	"github.com/grafeas/kritis/pkg/kritis/crd/imagereview"
 	"github.com/grafeas/kritis/pkg/kritis/crd/kritisconfig"
 	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/decisionlog"
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/notify"
 	"github.com/grafeas/kritis/pkg/kritis/policy"
 	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/signer"
+	"github.com/grafeas/kritis/pkg/kritis/tracing"
+	"github.com/grafeas/kritis/pkg/kritis/transparency"
 	"github.com/grafeas/kritis/pkg/kritis/util"
 	"github.com/grafeas/kritis/pkg/kritis/violation"
 )
 
+// Interface reviews images against policies. It is implemented by Reviewer for the
+// webhook, the cron job and the CLI, and faked by their tests.
+type Interface interface {
+	// ReviewContext reviews images against ImageSecurityPolicies, see Reviewer.ReviewContext.
+	ReviewContext(ctx context.Context, images []string, isps []v1beta1.ImageSecurityPolicy, pod *v1.Pod) error
+	// ReviewGAP reviews images against GenericAttestationPolicies, see Reviewer.ReviewGAP.
+	ReviewGAP(ctx context.Context, images []string, gaps []v1beta1.GenericAttestationPolicy, pod *v1.Pod) error
+	// Validate returns the violations of image for each of isps, see Reviewer.Validate.
+	Validate(ctx context.Context, image string, isps []v1beta1.ImageSecurityPolicy) ([][]policy.Violation, error)
+	// ValidateGAP returns the violations of image for each of gaps, see Reviewer.ValidateGAP.
+	ValidateGAP(ctx context.Context, image string, gaps []v1beta1.GenericAttestationPolicy) ([][]policy.Violation, error)
+	// Promote attests the images which passed all the policies of their namespace, see Reviewer.Promote.
+	Promote(ctx context.Context, images []string, isps []v1beta1.ImageSecurityPolicy, pod *v1.Pod)
+}
+
+var _ Interface = Reviewer{}
+
+// Reviewer reviews images with the metadata of its client. All other external calls
+// are made through its Config, so it can be given fakes.
 type Reviewer struct {
 	config *Config
 	client metadata.Fetcher
 }
 
 type Config struct {
-	Validate                        securitypolicy.ValidateFunc
-	Secret                          secrets.Fetcher
-	Auths                           authority.Fetcher
+	Validate securitypolicy.ValidateFunc
+	Secret   secrets.Fetcher
+	Auths    authority.Fetcher
+	// AuthLister lists the authorities of a namespace, to resolve the authority selectors of policies
+	AuthLister                      authority.Lister
+	DefaultAuth                     authority.DefaultFetcher
 	Attestors                       securitypolicy.AttestorFetcher
 	Strategy                        violation.Strategy
 	ClusterWhitelistedImagesRemover kritisconfig.ClusterWhitelistedImagesRemover
 	IsWebhook                       bool
+	// RecordAttestation is called for each attestation created, if set
+	RecordAttestation func(transparency.Record) error
+	// AuditDecisions records the violations found as Discovery occurrences on the images
+	AuditDecisions bool
+	// RecordReview is called with the outcome of each image and policy reviewed, if set
+	RecordReview func(*v1beta1.ImageReview) error
+	// Promotions attest the images passing all the policies of their namespaces, see Promote
+	Promotions []v1beta1.PromotionSpec
+	// Notifier is sent the violations of policies configuring their own violation strategies, if set
+	Notifier notify.Sender
+	// Now is the clock of the reviews, defaults to time.Now
+	Now func() time.Time
 }
 
 func New(client metadata.Fetcher, c *Config) Reviewer {
@@ -63,28 +110,115 @@ func New(client metadata.Fetcher, c *Config) Reviewer {
 // Review reviews a set of images against a set of policies
 // Returns error if violations are found and handles them as per violation strategy
 func (r Reviewer) Review(images []string, isps []v1beta1.ImageSecurityPolicy, pod *v1.Pod) error {
+	return r.ReviewContext(context.Background(), images, isps, pod)
+}
+
+// ReviewContext is Review, making the calls to the metadata backend with ctx and
+// recording the review as a span of ctx.
+func (r Reviewer) ReviewContext(ctx context.Context, images []string, isps []v1beta1.ImageSecurityPolicy, pod *v1.Pod) (err error) {
+	ctx, span := tracing.Start(ctx, "Review", trace.WithAttributes(
+		attribute.StringSlice("kritis.images", images),
+		attribute.Int("kritis.policies", len(isps)),
+	))
+	if pod != nil {
+		span.SetAttributes(attribute.String("kritis.pod", pod.Namespace+"/"+pod.Name))
+	}
+	defer func() { tracing.End(span, err) }()
+	return r.review(ctx, images, isps, pod)
+}
+
+// Validate returns the violations of image for each of isps, in order, without
+// handling them or attesting the image, e.g. for the check command to report them.
+// As in ReviewContext, images whitelisted by the cluster pass all policies and, for the
+// webhook, images with a verified attestation of the authorities of a policy pass it.
+// Their attestation is then added to the decision of ctx.
+func (r Reviewer) Validate(ctx context.Context, image string, isps []v1beta1.ImageSecurityPolicy) ([][]policy.Violation, error) {
+	violations := make([][]policy.Violation, len(isps))
+	whitelisted, err := r.clusterWhitelisted(image)
+	if err != nil {
+		return nil, err
+	}
+	if whitelisted {
+		return violations, nil
+	}
+	for i, isp := range isps {
+		if r.config.IsWebhook {
+			auths, err := r.getAttestationAuthoritiesForISP(isp)
+			if err != nil {
+				return nil, err
+			}
+			if attested, _, _ := r.verifyAttestations(ctx, image, auths, isp.Spec.MaximumAttestationAgeDays); attested {
+				continue
+			}
+		}
+		vs, err := r.config.Validate(ctx, r.now(), isp, image, r.client, r.config.Attestors)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed validating image security policy %s", isp.Name)
+		}
+		violations[i] = vs
+	}
+	return violations, nil
+}
+
+// clusterWhitelisted returns true if image is whitelisted by the cluster.
+func (r Reviewer) clusterWhitelisted(image string) (bool, error) {
+	images, err := r.removeClusterWhitelisted([]string{image})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to remove cluster whitelisted images")
+	}
+	return len(images) == 0, nil
+}
+
+// removeClusterWhitelisted returns the images which are not whitelisted by the cluster,
+// all of them without a ClusterWhitelistedImagesRemover, e.g. for the check command.
+func (r Reviewer) removeClusterWhitelisted(images []string) ([]string, error) {
+	if r.config.ClusterWhitelistedImagesRemover == nil {
+		return images, nil
+	}
+	return r.config.ClusterWhitelistedImagesRemover(images)
+}
+
+// now returns the current time of the reviewer clock.
+func (r Reviewer) now() time.Time {
+	if r.config.Now != nil {
+		return r.config.Now()
+	}
+	return time.Now()
+}
+
+func (r Reviewer) review(ctx context.Context, images []string, isps []v1beta1.ImageSecurityPolicy, pod *v1.Pod) error {
 	if len(isps) == 0 {
 		return nil
 	}
+	if pod != nil {
+		selected, err := securitypolicy.SelectImageSecurityPolicies(isps, pod.Labels)
+		if err != nil {
+			return err
+		}
+		if len(selected) == 0 {
+			glog.Infof("no ImageSecurityPolicy selects pod %q, skip reviewing", pod.Name)
+			return nil
+		}
+		isps = selected
+	}
 
 	orgImages := make([]string, len(images))
 	copy(orgImages, images)
 
-	images = util.RemoveGloballyWhitelistedImages(images)
-	if len(images) == 0 {
-		glog.Infof("images are all globally whitelisted, returning successful status: %s", orgImages)
-		return nil
-	}
-
-	images, err := r.config.ClusterWhitelistedImagesRemover(images)
+	images, err := r.removeClusterWhitelisted(images)
 	if err != nil {
 		glog.Errorf("failed to remove cluster whitelisted images: %v", err)
 		return err
 	}
 	if len(images) == 0 {
-		glog.Infof("images are all globally or cluster whitelisted, returning successful status: %s", orgImages)
+		glog.Infof("images are all cluster whitelisted, returning successful status: %s", orgImages)
 		return nil
 	}
+	if pod != nil {
+		ctx = securitypolicy.WithPod(ctx, pod)
+	}
+	// Fetch the metadata of all images at once, rather than one image after the other.
+	r.client = metadata.Prefetch(ctx, r.client, unwhitelistedImages(images, isps))
 
 	for _, isp := range isps {
 		glog.Infof("validating against ImageSecurityPolicy: %s", isp.Name)
@@ -95,23 +229,30 @@ func (r Reviewer) Review(images []string, isps []v1beta1.ImageSecurityPolicy, po
 		}
 		for _, image := range images {
 			glog.Infof("checking if the image already has valid Kritis attestations: %s", image)
-			isAttested, attestations := r.fetchAndVerifyAttestations(image, auths, pod)
+			isAttested, attestations, expired := r.fetchAndVerifyAttestations(ctx, image, auths, pod, isp.Spec.MaximumAttestationAgeDays)
 			// Skip check for Webhook if attestations found.
 			if isAttested && r.config.IsWebhook {
 				glog.Infof("skip validating policy since the image already has valid Kritis attestations: %s", image)
+				r.recordReview("ImageSecurityPolicy", isp.Namespace, isp.Name, image, pod, true, nil)
 				continue
 			}
 
 			glog.Infof("validating policy: %s", image)
-			violations, err := r.config.Validate(isp, image, r.client, r.config.Attestors)
+			violations, err := r.config.Validate(ctx, r.now(), isp, image, r.client, r.config.Attestors)
 			if err != nil {
 				return errors.Wrap(err, "failed validating image security policy")
 			}
+			r.recordReview("ImageSecurityPolicy", isp.Namespace, isp.Name, image, pod, isAttested, violations)
 			if len(violations) != 0 {
-				return r.handleViolations(image, pod, violations)
+				if err := r.handleViolations(ctx, isp, image, pod, violations); err != nil {
+					return err
+				}
+				// Admitted despite its violations, so not attested
+				continue
 			}
-			if r.config.IsWebhook {
-				if err := r.addAttestations(image, attestations, isp); err != nil {
+			// The cron job attests images again when their attestations expired.
+			if r.config.IsWebhook || expired {
+				if _, err := r.addAttestations(ctx, image, attestations, isp); err != nil {
 					glog.Errorf("failed to add attestations: %v", err)
 				}
 			}
@@ -121,21 +262,77 @@ func (r Reviewer) Review(images []string, isps []v1beta1.ImageSecurityPolicy, po
 	return nil
 }
 
-func (r Reviewer) fetchAndVerifyAttestations(image string, auths []v1beta1.AttestationAuthority, pod *v1.Pod) (bool, []metadata.PGPAttestation) {
-	attestations, err := r.client.Attestations(image)
+// unwhitelistedImages returns the images which are not whitelisted by all isps.
+func unwhitelistedImages(images []string, isps []v1beta1.ImageSecurityPolicy) []string {
+	l := []string{}
+	for _, image := range images {
+		for _, isp := range isps {
+			if !securitypolicy.ImageWhitelisted(isp, image) {
+				l = append(l, image)
+				break
+			}
+		}
+	}
+	return l
+}
+
+// fetchAndVerifyAttestations returns whether the image has a valid attestation and its
+// attestations, without those older than maxAgeDays if set. expired is true if some were.
+func (r Reviewer) fetchAndVerifyAttestations(ctx context.Context, image string, auths []v1beta1.AttestationAuthority, pod *v1.Pod, maxAgeDays int) (attested bool, atts []metadata.PGPAttestation, expired bool) {
+	attested, atts, expired = r.verifyAttestations(ctx, image, auths, maxAgeDays)
+	if err := r.config.Strategy.HandleAttestation(image, pod, attested); err != nil {
+		glog.Errorf("error handling attestations: %v", err)
+	}
+	return attested, atts, expired
+}
+
+// verifyAttestations is fetchAndVerifyAttestations, without handling the outcome with
+// the violation strategy. The attestation verified is added to the decision of ctx.
+func (r Reviewer) verifyAttestations(ctx context.Context, image string, auths []v1beta1.AttestationAuthority, maxAgeDays int) (attested bool, atts []metadata.PGPAttestation, expired bool) {
+	attestations, err := r.client.Attestations(ctx, image)
 	if err != nil {
 		glog.Errorf("error while fetching attestations: %v", err)
-		return false, attestations
+		return false, attestations, false
 	}
-	isAttested := r.hasValidImageAttestations(image, attestations, auths)
-	if err := r.config.Strategy.HandleAttestation(image, pod, isAttested); err != nil {
-		glog.Errorf("error handling attestations: %v", err)
+	unexpired, undated, expired := unexpiredAttestations(image, attestations, maxAgeDays, r.now())
+	id, isAttested := r.verifiedAttestation(image, unexpired, auths)
+	decisionlog.FromContext(ctx).AddAttestation(image, id, isAttested)
+	// Attestations of unknown age are not verified, but kept so as not to create them again.
+	return isAttested, append(unexpired, undated...), expired
+}
+
+// unexpiredAttestations drops the attestations created more than maxAgeDays ago, if set.
+// Attestations of unknown age, as on backends not reporting their creation time, are
+// returned in undated and count as expired.
+func unexpiredAttestations(image string, atts []metadata.PGPAttestation, maxAgeDays int, now time.Time) (unexpired, undated []metadata.PGPAttestation, expired bool) {
+	if maxAgeDays <= 0 {
+		return atts, nil, false
 	}
-	return isAttested, attestations
+	cutoff := now.AddDate(0, 0, -maxAgeDays)
+	unexpired = []metadata.PGPAttestation{}
+	for _, a := range atts {
+		if a.CreateTime == nil {
+			glog.Infof("ignoring attestation %s of %s of unknown age", a.OccID, image)
+			undated = append(undated, a)
+			continue
+		}
+		if a.CreateTime.Before(cutoff) {
+			glog.Infof("ignoring attestation %s of %s created more than %d days ago", a.OccID, image, maxAgeDays)
+			continue
+		}
+		unexpired = append(unexpired, a)
+	}
+	return unexpired, undated, len(unexpired) < len(atts)
 }
 
 // hasValidImageAttestations return true if any one image attestation is verified.
 func (r Reviewer) hasValidImageAttestations(image string, attestations []metadata.PGPAttestation, auths []v1beta1.AttestationAuthority) bool {
+	_, ok := r.verifiedAttestation(image, attestations, auths)
+	return ok
+}
+
+// verifiedAttestation returns the occurrence ID of the first verified image attestation.
+func (r Reviewer) verifiedAttestation(image string, attestations []metadata.PGPAttestation, auths []v1beta1.AttestationAuthority) (string, bool) {
 	if len(attestations) == 0 {
 		glog.Infof(`No attestations found for image %s.
 This normally happens when you deploy a pod before kritis or no attestation authority is deployed.
@@ -144,109 +341,308 @@ Please see instructions `, image)
 	host, err := container.NewAtomicContainerSig(image, map[string]string{})
 	if err != nil {
 		glog.Error(err)
-		return false
+		return "", false
 	}
 	keys := map[string]string{}
 	for _, auth := range auths {
-		key, fingerprint, err := fingerprint(auth.Spec.PublicKeyData)
+		key, fingerprint, err := authorityKey(auth)
 		if err != nil {
 			glog.Errorf("error parsing key for %q: %v", auth.Name, err)
 			continue
 		}
 		keys[fingerprint] = key
+		for id, key := range previousKeys(auth, r.now()) {
+			keys[id] = key
+		}
 	}
 	for _, a := range attestations {
-		if err = host.VerifyAttestationSignature(keys[a.KeyID], a.Signature); err != nil {
-			glog.Errorf("could not verify attestation for attestation authority: %s", a.KeyID)
+		key := keyFor(keys, a.KeyID)
+		if a.Payload != "" {
+			err = verifyGenericAttestation(host, key, a)
+		} else {
+			err = host.VerifyAttestationSignature(key, a.Signature)
+		}
+		if err != nil {
+			glog.Errorf("could not verify attestation for attestation authority: %s: %v", a.KeyID, err)
 		} else {
 			glog.Infof("image has valid attestation: %s, %s", image, a.OccID)
-			return true
+			return a.OccID, true
 		}
 	}
-	return false
+	return "", false
 }
 
-func (r Reviewer) handleViolations(image string, pod *v1.Pod, violations []policy.Violation) error {
-	var violationSummaries []string
-
+func (r Reviewer) handleViolations(ctx context.Context, isp v1beta1.ImageSecurityPolicy, image string, pod *v1.Pod, violations []policy.Violation) error {
+	decision := decisionlog.FromContext(ctx)
 	for _, v := range violations {
-		violationSummaries = append(violationSummaries, fmt.Sprintf("%s: %s", v.Type().ToString(), v.Reason()))
+		decision.AddViolation(image, isp.Namespace, isp.Name, v.Type().ToString(), string(v.Reason()))
+	}
+	// Images with many vulnerabilities would yield messages too long to be shown in full,
+	// so only the message summarizes them. The audit records all of them.
+	r.auditViolations(ctx, isp, image, pod, violationLines(violations))
+	violationSummaries := summarizeViolations(violations)
+	if summarized(violations) {
+		violationSummaries = append(violationSummaries, r.fullListHint(isp, image))
 	}
 
 	joinedSummaries := fmt.Sprintf("\n%s\n", strings.Join(violationSummaries, ",\n"))
 	errMsg := fmt.Sprintf("found violations in %q (%v)", image, joinedSummaries)
 
-	if err := r.config.Strategy.HandleViolation(image, pod, violations); err != nil {
+	strategy := r.config.Strategy
+	if len(isp.Spec.ViolationStrategies) > 0 {
+		chain, err := violation.NewChain(isp.Spec.ViolationStrategies, r.config.Notifier)
+		if err != nil {
+			return errors.Wrapf(err, "invalid violationStrategies of ImageSecurityPolicy %s/%s: %s", isp.Namespace, isp.Name, errMsg)
+		}
+		strategy = chain
+	}
+
+	var err error
+	if s, ok := strategy.(violation.PolicyStrategy); ok {
+		err = s.HandlePolicyViolation(isp, image, pod, violations)
+	} else {
+		err = strategy.HandleViolation(image, pod, violations)
+	}
+	if err != nil {
 		return errors.Wrapf(err, "failed to handle violation: %s", errMsg)
 	}
+	if r.admits(strategy) {
+		glog.Warningf("admitting %q, since the violation strategies of ImageSecurityPolicy %s/%s don't deny: %s", image, isp.Namespace, isp.Name, errMsg)
+		return nil
+	}
 
 	return fmt.Errorf(errMsg)
 }
 
-func (r Reviewer) addAttestations(image string, atts []metadata.PGPAttestation, isp v1beta1.ImageSecurityPolicy) error {
+// admits returns true if the webhook admits images with violations handled by
+// strategy, i.e. strategy is a Chain without a deny stage. Violations are always
+// reported by other reviews.
+func (r Reviewer) admits(strategy violation.Strategy) bool {
+	chain, ok := strategy.(*violation.Chain)
+	return ok && r.config.IsWebhook && !chain.Denies()
+}
+
+// auditViolations records the violations of an image as a Discovery occurrence, if enabled.
+// Pods already labeled out of policy were recorded by an earlier review, as were the
+// same violations of the image found for the policy within auditTTL, e.g. in another
+// pod. Failures are logged, since they don't change the decision.
+func (r Reviewer) auditViolations(ctx context.Context, isp v1beta1.ImageSecurityPolicy, image string, pod *v1.Pod, lines []string) {
+	if !r.config.AuditDecisions {
+		return
+	}
+	decision := "violates"
+	if r.config.IsWebhook {
+		decision = "rejected by"
+	}
+	message := fmt.Sprintf("%s %s ImageSecurityPolicy %s/%s", image, decision, isp.Namespace, isp.Name)
+	if pod != nil {
+		if _, ok := pod.Labels[constants.InvalidImageSecPolicy]; ok {
+			return
+		}
+		message += fmt.Sprintf(" in pod %s/%s", pod.Namespace, pod.Name)
+	}
+	violations := strings.Join(lines, ", ")
+	message += ": " + violations
+	key := strings.Join([]string{decision, isp.Namespace, isp.Name, image, violations}, "\x00")
+	if audited.seen(key) {
+		return
+	}
+	n, err := util.GetOrCreateDiscoveryNote(ctx, r.client, image, constants.AuditNoteID)
+	if err != nil {
+		glog.Errorf("error getting audit note for %s: %v", image, err)
+		return
+	}
+	if _, err := r.client.CreateDiscoveryOccurrence(ctx, n, image, message); err != nil {
+		glog.Errorf("error recording audit occurrence for %s: %v", image, err)
+		return
+	}
+	audited.record(key)
+}
+
+// recordReview passes the outcome of reviewing image against a policy to RecordReview, if set.
+// Failures are logged, since they don't change the decision.
+func (r Reviewer) recordReview(kind, namespace, name, image string, pod *v1.Pod, attested bool, violations []policy.Violation) {
+	if r.config.RecordReview == nil {
+		return
+	}
+	source, podName := "cron", ""
+	if r.config.IsWebhook {
+		source = "webhook"
+	}
+	if pod != nil {
+		podName = pod.Name
+	}
+	ir := imagereview.New(kind, namespace, name, image, podName, source, attested, violations)
+	ir.Status.ReviewTime = metav1.NewTime(r.now())
+	if err := r.config.RecordReview(ir); err != nil {
+		glog.Errorf("error recording review of %s: %v", image, err)
+	}
+}
+
+// addAttestations attests image by the authorities of isp which have not attested it yet, and
+// returns the names of the authorities it was signed for. Authorities whose public key fails to
+// parse are skipped with a warning, since their existing attestations can't be recognized.
+func (r Reviewer) addAttestations(ctx context.Context, image string, atts []metadata.PGPAttestation, isp v1beta1.ImageSecurityPolicy) ([]string, error) {
 	// Get all AttestationAuthorities in this policy.
 	auths, err := r.getAttestationAuthoritiesForISP(isp)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if len(auths) == 0 {
-		return fmt.Errorf("no attestation authorities configured for security policy %q", isp.Name)
+		return nil, fmt.Errorf("no attestation authorities configured for security policy %q", isp.Name)
 	}
 	keys := map[string]string{}
+	parsed := []v1beta1.AttestationAuthority{}
 	for _, auth := range auths {
-		_, fingerprint, err := fingerprint(auth.Spec.PublicKeyData)
+		_, fingerprint, err := authorityKey(auth)
 		if err != nil {
-			glog.Errorf("error parsing key for %q: %v", auth.Name, err)
+			glog.Errorf("not attesting %s for %q: error parsing key: %v", image, auth.Name, err)
+			decisionlog.FromContext(ctx).AddWarning("kritis: not attesting images for AttestationAuthority %q: its public key failed to parse: %v", auth.Name, err)
 			continue
 		}
 		keys[auth.Name] = fingerprint
+		parsed = append(parsed, auth)
 	}
 	// Get all AttestationAuthorities which have not attested the image.
 	errMsgs := []string{}
-	u := getUnAttested(auths, keys, atts)
+	signed := []string{}
+	u := getUnAttested(parsed, keys, atts)
 	if len(u) == 0 {
 		glog.Info("attestation exists for all authorities")
-		return nil
+		return signed, nil
 	}
 	for _, a := range u {
-		// Get or Create Note for this this Authority
-		n, err := util.GetOrCreateAttestationNote(r.client, &a)
-		if err != nil {
-			errMsgs = append(errMsgs, err.Error())
-		}
-		// Get secret for this Authority
-		s, err := r.config.Secret(isp.Namespace, a.Spec.PrivateKeySecretName)
-		if err != nil {
-			errMsgs = append(errMsgs, err.Error())
+		ok, msgs := r.attest(ctx, image, a, isp.Namespace, keys[a.Name], isp.Namespace, isp.Name)
+		if ok {
+			signed = append(signed, a.Name)
 		}
-		// Create Attestation Signature
-		if _, err := r.client.CreateAttestationOccurence(n, image, s); err != nil {
-			errMsgs = append(errMsgs, err.Error())
-		}
-
+		errMsgs = append(errMsgs, msgs...)
 	}
+	glog.Infof("attested %s for authorities %v of security policy %s/%s", image, signed, isp.Namespace, isp.Name)
 	if len(errMsgs) == 0 {
-		return nil
+		return signed, nil
 	}
-	return fmt.Errorf("one or more errors adding attestations: %s", errMsgs)
+	return signed, fmt.Errorf("one or more errors adding attestations: %s", errMsgs)
 }
 
-func getUnAttested(auths []v1beta1.AttestationAuthority, keys map[string]string, atts []metadata.PGPAttestation) []v1beta1.AttestationAuthority {
-	l := []v1beta1.AttestationAuthority{}
-	m := map[string]bool{}
-	for _, a := range atts {
-		m[a.KeyID] = true
+// attest creates the attestation of image by authority a, whose secret is in secretNamespace,
+// and records it as created for the policy namespace/name. It returns whether the attestation
+// was created, and the errors met.
+func (r Reviewer) attest(ctx context.Context, image string, a v1beta1.AttestationAuthority, secretNamespace, keyID, namespace, name string) (bool, []string) {
+	errMsgs := []string{}
+	// Get or Create Note for this this Authority
+	n, err := util.GetOrCreateAttestationNote(ctx, r.client, &a)
+	if err != nil {
+		errMsgs = append(errMsgs, err.Error())
+	}
+	// Get secret for this Authority
+	s, err := secrets.FetchForAuthority(r.config.Secret, secretNamespace, a)
+	if err != nil {
+		errMsgs = append(errMsgs, err.Error())
+	}
+	// Create Attestation Signature
+	if _, err := r.client.CreateAttestationOccurence(ctx, n, image, s); err != nil {
+		return false, append(errMsgs, err.Error())
 	}
+	decisionlog.FromContext(ctx).AddSignature(image, a.Name, namespace, name)
+	if r.config.RecordAttestation == nil {
+		return true, errMsgs
+	}
+	if err := r.config.RecordAttestation(transparency.Record{
+		Image:     image,
+		Note:      n.GetName(),
+		Authority: a.Name,
+		KeyID:     keyID,
+		Namespace: namespace,
+		Policy:    name,
+	}); err != nil {
+		errMsgs = append(errMsgs, err.Error())
+	}
+	return true, errMsgs
+}
 
+// getUnAttested returns the authorities without an attestation in atts, given the key IDs
+// of the authorities by name.
+func getUnAttested(auths []v1beta1.AttestationAuthority, keys map[string]string, atts []metadata.PGPAttestation) []v1beta1.AttestationAuthority {
+	l := []v1beta1.AttestationAuthority{}
 	for _, a := range auths {
-		_, ok := m[keys[a.Name]]
-		if !ok {
+		attested := false
+		for _, att := range atts {
+			if util.SameKeyID(keys[a.Name], att.KeyID) {
+				attested = true
+				break
+			}
+		}
+		if !attested {
 			l = append(l, a)
 		}
 	}
 	return l
 }
 
+// keyFor returns the key of keys, by key ID, matching id.
+func keyFor(keys map[string]string, id string) string {
+	if key, ok := keys[id]; ok {
+		return key
+	}
+	for k, key := range keys {
+		if util.SameKeyID(k, id) {
+			return key
+		}
+	}
+	return ""
+}
+
+// authorityKey returns the public key of the authority and the ID of its signatures. Authorities
+// signing with a KMS have a PEM public key, identified by the name of their key unless
+// PublicKeyID is set.
+func authorityKey(auth v1beta1.AttestationAuthority) (key, id string, err error) {
+	if !isPKIX(auth) {
+		return fingerprint(auth.Spec.PublicKeyData)
+	}
+	publicData, err := base64.StdEncoding.DecodeString(auth.Spec.PublicKeyData)
+	if err != nil {
+		return key, id, err
+	}
+	if auth.Spec.PublicKeyID != "" {
+		return string(publicData), auth.Spec.PublicKeyID, nil
+	}
+	return string(publicData), signer.KeyID(auth.Spec.KMSKeyName), nil
+}
+
+// previousKeys returns the previous PGP keys of the authority not retired at now, by fingerprint.
+func previousKeys(auth v1beta1.AttestationAuthority, now time.Time) map[string]string {
+	keys := map[string]string{}
+	if isPKIX(auth) {
+		return keys
+	}
+	for _, p := range auth.Spec.PreviousKeys {
+		if !now.Before(p.RetireTime.Time) {
+			continue
+		}
+		key, id, err := fingerprint(p.PublicKeyData)
+		if err != nil {
+			glog.Errorf("error parsing previous key of %q: %v", auth.Name, err)
+			continue
+		}
+		keys[id] = key
+	}
+	return keys
+}
+
+// isPKIX returns true if the authority has a PKIX instead of a PGP public key.
+func isPKIX(auth v1beta1.AttestationAuthority) bool {
+	return auth.Spec.KMSKeyName != "" || auth.Spec.PublicKeyID != ""
+}
+
+func verifyGenericAttestation(host *container.AtomicContainerSig, key string, a metadata.PGPAttestation) error {
+	sig, err := base64.StdEncoding.DecodeString(a.Signature)
+	if err != nil {
+		return err
+	}
+	return host.VerifyGenericAttestationSignature(key, []byte(a.Payload), sig)
+}
+
 // fingerprint returns the fingerprint and key from the base64 encoded public key data
 func fingerprint(publicKeyData string) (key, fingerprint string, err error) {
 	publicData, err := base64.StdEncoding.DecodeString(publicKeyData)
@@ -261,13 +657,62 @@ func fingerprint(publicKeyData string) (key, fingerprint string, err error) {
 }
 
 func (r Reviewer) getAttestationAuthoritiesForISP(isp v1beta1.ImageSecurityPolicy) ([]v1beta1.AttestationAuthority, error) {
-	auths := make([]v1beta1.AttestationAuthority, len(isp.Spec.AttestationAuthorityNames))
-	for i, aName := range isp.Spec.AttestationAuthorityNames {
-		a, err := r.config.Auths(isp.Namespace, aName)
+	return r.getAttestationAuthorities(isp.Namespace, isp.Name, isp.Spec.AttestationAuthorityNames, isp.Spec.AttestationAuthoritySelector)
+}
+
+// getAttestationAuthorities returns the authorities listed by name, followed by those
+// of the namespace matching selector. Without Auths, e.g. for the check command which
+// reads no resource of the cluster, policies have no authorities.
+func (r Reviewer) getAttestationAuthorities(namespace string, policyName string, names []string, selector *metav1.LabelSelector) ([]v1beta1.AttestationAuthority, error) {
+	if r.config.Auths == nil {
+		return nil, nil
+	}
+	if len(names) == 0 && selector == nil && r.config.DefaultAuth != nil {
+		// Policies listing no authority use the default of their namespace, if any
+		a, err := r.config.DefaultAuth(namespace)
+		if err != nil {
+			glog.Errorf("failed to get default attestation authority for %q: %v", policyName, err)
+		}
+		if a != nil {
+			return []v1beta1.AttestationAuthority{*a}, nil
+		}
+	}
+	auths := make([]v1beta1.AttestationAuthority, len(names))
+	for i, aName := range names {
+		a, err := r.config.Auths(namespace, aName)
 		if err != nil {
 			return nil, errors.Wrap(err, "faild to get attestation authorities")
 		}
 		auths[i] = *a
 	}
+	if selector == nil {
+		return auths, nil
+	}
+	sel, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid attestation authority selector of %q", policyName)
+	}
+	if r.config.AuthLister == nil {
+		return nil, fmt.Errorf("cannot resolve the attestation authority selector of %q", policyName)
+	}
+	all, err := r.config.AuthLister(namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list attestation authorities")
+	}
+	for _, a := range all {
+		if !sel.Matches(labels.Set(a.Labels)) || hasAuthority(auths, a.Name) {
+			continue
+		}
+		auths = append(auths, a)
+	}
 	return auths, nil
 }
+
+func hasAuthority(auths []v1beta1.AttestationAuthority, name string) bool {
+	for _, a := range auths {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -17,19 +17,34 @@ limitations under the License.
 package review
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/pem"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/container"
 	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/decisionlog"
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
 	"github.com/grafeas/kritis/pkg/kritis/policy"
 	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/signer"
 	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	"github.com/grafeas/kritis/pkg/kritis/transparency"
 	"github.com/grafeas/kritis/pkg/kritis/util"
 	"github.com/grafeas/kritis/pkg/kritis/violation"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -116,6 +131,110 @@ func TestHasValidAttestations(t *testing.T) {
 	}
 }
 
+func TestHasValidGenericAttestations(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	keyName := "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1"
+	publicKeyData := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	auths := []v1beta1.AttestationAuthority{{
+		Spec: v1beta1.AttestationAuthoritySpec{
+			KMSKeyName:    keyName,
+			PublicKeyData: publicKeyData,
+		},
+	}}
+	pkixAuths := []v1beta1.AttestationAuthority{{
+		Spec: v1beta1.AttestationAuthoritySpec{
+			PublicKeyID:   "attestor-key",
+			PublicKeyData: publicKeyData,
+		},
+	}}
+	sign := func(image string) metadata.PGPAttestation {
+		host, err := container.NewAtomicContainerSig(image, map[string]string{})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		payload, err := host.JSON()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		digest := sha256.Sum256([]byte(payload))
+		sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		return metadata.PGPAttestation{
+			Signature: base64.StdEncoding.EncodeToString(sig),
+			KeyID:     signer.KeyID(keyName),
+			Payload:   payload,
+		}
+	}
+	otherKey := sign(testutil.QualifiedImage)
+	otherKey.KeyID = signer.KeyID("projects/p/locations/global/keyRings/r/cryptoKeys/other/cryptoKeyVersions/1")
+	attestorKey := sign(testutil.QualifiedImage)
+	attestorKey.KeyID = "attestor-key"
+	tcs := []struct {
+		name         string
+		expected     bool
+		attestations []metadata.PGPAttestation
+		auths        []v1beta1.AttestationAuthority
+	}{
+		{"valid signature", true, []metadata.PGPAttestation{sign(testutil.QualifiedImage)}, auths},
+		{"signature of another image", false, []metadata.PGPAttestation{sign(testutil.IntTestImage)}, auths},
+		{"signature of another key", false, []metadata.PGPAttestation{otherKey}, auths},
+		{"signature of public key ID", true, []metadata.PGPAttestation{attestorKey}, pkixAuths},
+		{"KMS signature for public key ID", false, []metadata.PGPAttestation{sign(testutil.QualifiedImage)}, pkixAuths},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := New(&testutil.MockMetadataClient{}, &Config{
+				ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+			})
+			actual := r.hasValidImageAttestations(testutil.QualifiedImage, tc.attestations, tc.auths)
+			if actual != tc.expected {
+				t.Fatalf("Expected %v, Got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestHasValidAttestationsOfPreviousKeys(t *testing.T) {
+	old, oldPub := testutil.CreateSecret(t, "old")
+	_, pub := testutil.CreateSecret(t, "new")
+	sig, err := util.CreateAttestationSignature(testutil.QualifiedImage, old)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	atts := []metadata.PGPAttestation{{Signature: sig, KeyID: old.PgpKey.Fingerprint()}}
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	auth := func(retire time.Time) []v1beta1.AttestationAuthority {
+		return []v1beta1.AttestationAuthority{{
+			Spec: v1beta1.AttestationAuthoritySpec{
+				PublicKeyData: base64.StdEncoding.EncodeToString([]byte(pub)),
+				PreviousKeys: []v1beta1.PreviousKey{{
+					PublicKeyData: base64.StdEncoding.EncodeToString([]byte(oldPub)),
+					RetireTime:    metav1.NewTime(retire),
+				}},
+			},
+		}}
+	}
+	r := New(&testutil.MockMetadataClient{}, &Config{
+		ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+		Now:                             func() time.Time { return now },
+	})
+	if !r.hasValidImageAttestations(testutil.QualifiedImage, atts, auth(now.Add(time.Hour))) {
+		t.Errorf("expected the attestation of the previous key to be valid before its retirement")
+	}
+	if r.hasValidImageAttestations(testutil.QualifiedImage, atts, auth(now)) {
+		t.Errorf("expected the attestation of the retired key to be invalid")
+	}
+}
+
 func TestReview(t *testing.T) {
 	sec, pub := testutil.CreateSecret(t, "sec")
 	secFpr := sec.PgpKey.Fingerprint()
@@ -154,7 +273,7 @@ func TestReview(t *testing.T) {
 				PublicKeyData:        base64.StdEncoding.EncodeToString([]byte(pub)),
 			}}, nil
 	}
-	mockValidate := func(isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher securitypolicy.AttestorFetcher) ([]policy.Violation, error) {
+	mockValidate := func(ctx context.Context, now time.Time, isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher securitypolicy.AttestorFetcher) ([]policy.Violation, error) {
 		if image == vulnImage {
 			v := securitypolicy.NewViolation(&metadata.Vulnerability{Severity: "foo"}, 1, "")
 			vs := []policy.Violation{}
@@ -268,16 +387,6 @@ func TestReview(t *testing.T) {
 			shdAttestImage:    false,
 			shdErr:            true,
 		},
-		{
-			name:              "review image in global whitelist",
-			image:             "gcr.io/kritis-project/preinstall",
-			isWebhook:         true,
-			attestations:      []metadata.PGPAttestation{},
-			handledViolations: 0,
-			isAttested:        false,
-			shdAttestImage:    false,
-			shdErr:            false,
-		},
 	}
 	for _, tc := range tests {
 		th := violation.MemoryStrategy{
@@ -288,6 +397,7 @@ func TestReview(t *testing.T) {
 			cMock := &testutil.MockMetadataClient{
 				PGPAttestations: tc.attestations,
 			}
+			var records []transparency.Record
 			r := New(cMock, &Config{
 				Validate:                        mockValidate,
 				Secret:                          sMock,
@@ -295,6 +405,10 @@ func TestReview(t *testing.T) {
 				IsWebhook:                       tc.isWebhook,
 				Strategy:                        &th,
 				ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+				RecordAttestation: func(r transparency.Record) error {
+					records = append(records, r)
+					return nil
+				},
 			})
 			if err := r.Review([]string{tc.image}, isps, nil); (err != nil) != tc.shdErr {
 				t.Errorf("expected review to return error %t, actual error %s", tc.shdErr, err)
@@ -309,6 +423,125 @@ func TestReview(t *testing.T) {
 			if (len(cMock.Occ) != 0) != tc.shdAttestImage {
 				t.Errorf("expected an image to be attested, but found none")
 			}
+			if (len(records) != 0) != tc.shdAttestImage {
+				t.Errorf("expected attestations to be recorded: %t. Got %v", tc.shdAttestImage, records)
+			}
+			for _, rec := range records {
+				if rec.Image != tc.image || rec.Authority != "test" || rec.KeyID != secFpr || rec.Namespace != "foo" {
+					t.Errorf("unexpected attestation record %+v", rec)
+				}
+			}
+		})
+	}
+}
+
+func TestReviewPodSelector(t *testing.T) {
+	isps := []v1beta1.ImageSecurityPolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "foo"},
+			Spec: v1beta1.ImageSecurityPolicySpec{
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "frontend"}},
+			},
+		},
+	}
+	var validated []string
+	mockValidate := func(ctx context.Context, now time.Time, isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher securitypolicy.AttestorFetcher) ([]policy.Violation, error) {
+		validated = append(validated, isp.Name)
+		return []policy.Violation{securitypolicy.NewViolation(nil, policy.SeverityViolation, "")}, nil
+	}
+	tests := []struct {
+		name      string
+		labels    map[string]string
+		validated []string
+		shdErr    bool
+	}{
+		{"matching pod is reviewed", map[string]string{"tier": "frontend"}, []string{"frontend"}, true},
+		{"non matching pod is skipped", map[string]string{"tier": "batch"}, nil, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			validated = nil
+			r := New(&testutil.MockMetadataClient{}, &Config{
+				Validate: mockValidate,
+				Auths: func(ns string, name string) (*v1beta1.AttestationAuthority, error) {
+					return nil, fmt.Errorf("no authority %s", name)
+				},
+				Strategy: &violation.MemoryStrategy{
+					Violations:   map[string]bool{},
+					Attestations: map[string]bool{},
+				},
+				ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+			})
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Labels: tc.labels}}
+			err := r.Review([]string{testutil.QualifiedImage}, isps, pod)
+			testutil.CheckErrorAndDeepEqual(t, tc.shdErr, err, tc.validated, validated)
+		})
+	}
+}
+
+func TestReviewExpiredAttestations(t *testing.T) {
+	sec, pub := testutil.CreateSecret(t, "sec")
+	sig, err := util.CreateAttestationSignature(testutil.IntTestImage, sec)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	now := time.Date(2018, 10, 31, 0, 0, 0, 0, time.UTC)
+	recent, old := now.AddDate(0, 0, -10), now.AddDate(0, 0, -60)
+	isps := []v1beta1.ImageSecurityPolicy{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo"},
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			AttestationAuthorityNames: []string{"test"},
+			MaximumAttestationAgeDays: 30,
+		},
+	}}
+	authMock := func(ns string, name string) (*v1beta1.AttestationAuthority, error) {
+		return &v1beta1.AttestationAuthority{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1beta1.AttestationAuthoritySpec{
+				NoteReference:        "provider/test",
+				PrivateKeySecretName: "test",
+				PublicKeyData:        base64.StdEncoding.EncodeToString([]byte(pub)),
+			}}, nil
+	}
+	tests := []struct {
+		name           string
+		isWebhook      bool
+		created        *time.Time
+		isAttested     bool
+		shdAttestImage bool
+	}{
+		{"recent attestation for cron", false, &recent, true, false},
+		{"expired attestation for cron shd attest again", false, &old, false, true},
+		{"attestation of unknown age for cron is not created again", false, nil, false, false},
+		{"recent attestation for webhook", true, &recent, true, false},
+		{"expired attestation for webhook shd attest again", true, &old, false, true},
+		{"attestation of unknown age for webhook is not created again", true, nil, false, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			th := violation.MemoryStrategy{
+				Violations:   map[string]bool{},
+				Attestations: map[string]bool{},
+			}
+			cMock := &testutil.MockMetadataClient{
+				PGPAttestations: []metadata.PGPAttestation{{Signature: sig, KeyID: sec.PgpKey.Fingerprint(), CreateTime: tc.created}},
+			}
+			r := New(cMock, &Config{
+				Validate: func(ctx context.Context, now time.Time, isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher securitypolicy.AttestorFetcher) ([]policy.Violation, error) {
+					return nil, nil
+				},
+				Secret:                          func(string, string) (*secrets.PGPSigningSecret, error) { return sec, nil },
+				Auths:                           authMock,
+				IsWebhook:                       tc.isWebhook,
+				Strategy:                        &th,
+				ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+				Now:                             func() time.Time { return now },
+			})
+			if err := r.Review([]string{testutil.IntTestImage}, isps, nil); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			testutil.DeepEqual(t, tc.isAttested, th.Attestations[testutil.IntTestImage])
+			testutil.DeepEqual(t, tc.shdAttestImage, cMock.Calls("CreateAttestationOccurence", testutil.IntTestImage) == 1)
 		})
 	}
 }
@@ -335,6 +568,34 @@ func TestGetUnAttested(t *testing.T) {
 			[]string{"a-fpr", "b-fpr"},
 			[]string{},
 		},
+		{
+			"long key ID",
+			[]string{"a"},
+			map[string]string{"a": "0123456789ABCDEF0123456789ABCDEF01234567"},
+			[]string{"89abcdef01234567"},
+			[]string{},
+		},
+		{
+			"formatted fingerprint",
+			[]string{"a"},
+			map[string]string{"a": "0123456789ABCDEF0123456789ABCDEF01234567"},
+			[]string{"0x0123 4567 89AB CDEF 0123 4567 89AB CDEF 0123 4567"},
+			[]string{},
+		},
+		{
+			"short key ID",
+			[]string{"a"},
+			map[string]string{"a": "0123456789ABCDEF0123456789ABCDEF01234567"},
+			[]string{"01234567"},
+			[]string{"a"},
+		},
+		{
+			"no key ID",
+			[]string{"a"},
+			map[string]string{},
+			[]string{""},
+			[]string{"a"},
+		},
 	}
 
 	for _, tc := range tcs {
@@ -351,6 +612,58 @@ func TestGetUnAttested(t *testing.T) {
 	}
 }
 
+func TestAddAttestations(t *testing.T) {
+	sec, pub := testutil.CreateSecret(t, "sec")
+	fpr := sec.PgpKey.Fingerprint()
+	isp := v1beta1.ImageSecurityPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "isp"},
+		Spec: v1beta1.ImageSecurityPolicySpec{
+			AttestationAuthorityNames: []string{"valid", "invalid"},
+		},
+	}
+	authMock := func(ns string, name string) (*v1beta1.AttestationAuthority, error) {
+		key := base64.StdEncoding.EncodeToString([]byte(pub))
+		if name == "invalid" {
+			key = "not a key"
+		}
+		return &v1beta1.AttestationAuthority{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1beta1.AttestationAuthoritySpec{
+				NoteReference:        "provider/" + name,
+				PrivateKeySecretName: name,
+				PublicKeyData:        key,
+			}}, nil
+	}
+	tcs := []struct {
+		name     string
+		atts     []metadata.PGPAttestation
+		expected []string
+	}{
+		{"unattested", nil, []string{"valid"}},
+		{"attested by long key ID", []metadata.PGPAttestation{{KeyID: fpr[len(fpr)-16:]}}, []string{}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &decisionlog.Decision{}
+			ctx := decisionlog.NewContext(context.Background(), d)
+			r := New(&testutil.MockMetadataClient{}, &Config{
+				Secret: func(string, string) (*secrets.PGPSigningSecret, error) { return sec, nil },
+				Auths:  authMock,
+			})
+			signed, err := r.addAttestations(ctx, testutil.IntTestImage, tc.atts, isp)
+			testutil.CheckErrorAndDeepEqual(t, false, err, tc.expected, signed)
+			if len(d.Warnings) != 1 || !strings.Contains(d.Warnings[0], `"invalid"`) {
+				t.Errorf("expected a warning for the invalid authority, got %v", d.Warnings)
+			}
+			var expected []decisionlog.Signature
+			for _, a := range tc.expected {
+				expected = append(expected, decisionlog.Signature{Image: testutil.IntTestImage, Authority: a, Policy: "foo/isp"})
+			}
+			testutil.DeepEqual(t, expected, d.Signatures)
+		})
+	}
+}
+
 func makeAuth(ids []string) []v1beta1.AttestationAuthority {
 	l := make([]v1beta1.AttestationAuthority, len(ids))
 	for i, s := range ids {
@@ -443,3 +756,411 @@ func TestGetAttestationAuthoritiesForISP(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultAttestationAuthority(t *testing.T) {
+	defaultAuth := func(ns string) (*v1beta1.AttestationAuthority, error) {
+		if ns != "foo" {
+			return nil, nil
+		}
+		return &v1beta1.AttestationAuthority{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: ns}}, nil
+	}
+	authMock := func(ns string, name string) (*v1beta1.AttestationAuthority, error) {
+		return &v1beta1.AttestationAuthority{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns}}, nil
+	}
+	r := New(nil, &Config{
+		Auths:       authMock,
+		DefaultAuth: defaultAuth,
+	})
+	tcs := []struct {
+		name      string
+		namespace string
+		aList     []string
+		expected  []string
+	}{
+		{"policy without authorities uses the default", "foo", nil, []string{"default"}},
+		{"listed authorities override the default", "foo", []string{"a1"}, []string{"a1"}},
+		{"namespace without default", "bar", nil, []string{}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			isp := v1beta1.ImageSecurityPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: tc.namespace},
+				Spec: v1beta1.ImageSecurityPolicySpec{
+					AttestationAuthorityNames: tc.aList,
+				},
+			}
+			auths, err := r.getAttestationAuthoritiesForISP(isp)
+			names := []string{}
+			for _, a := range auths {
+				names = append(names, a.Name)
+			}
+			testutil.CheckErrorAndDeepEqual(t, false, err, tc.expected, names)
+		})
+	}
+}
+
+func TestAttestationAuthoritySelector(t *testing.T) {
+	all := []v1beta1.AttestationAuthority{
+		{ObjectMeta: metav1.ObjectMeta{Name: "a1", Labels: map[string]string{"team": "qa"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "a2", Labels: map[string]string{"team": "qa"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "a3", Labels: map[string]string{"team": "sec"}}},
+	}
+	authMock := func(ns string, name string) (*v1beta1.AttestationAuthority, error) {
+		return &v1beta1.AttestationAuthority{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns}}, nil
+	}
+	lister := func(ns string) ([]v1beta1.AttestationAuthority, error) {
+		if ns != "foo" {
+			return nil, nil
+		}
+		return all, nil
+	}
+	defaultAuth := func(ns string) (*v1beta1.AttestationAuthority, error) {
+		return &v1beta1.AttestationAuthority{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: ns}}, nil
+	}
+	r := New(nil, &Config{
+		Auths:       authMock,
+		AuthLister:  lister,
+		DefaultAuth: defaultAuth,
+	})
+	tcs := []struct {
+		name      string
+		namespace string
+		aList     []string
+		selector  *metav1.LabelSelector
+		shdErr    bool
+		expected  []string
+	}{
+		{
+			name:      "selector matches labels",
+			namespace: "foo",
+			selector:  &metav1.LabelSelector{MatchLabels: map[string]string{"team": "qa"}},
+			expected:  []string{"a1", "a2"},
+		},
+		{
+			name:      "named authorities come first and are not repeated",
+			namespace: "foo",
+			aList:     []string{"a2", "other"},
+			selector:  &metav1.LabelSelector{MatchLabels: map[string]string{"team": "qa"}},
+			expected:  []string{"a2", "other", "a1"},
+		},
+		{
+			name:      "empty selector matches all authorities",
+			namespace: "foo",
+			selector:  &metav1.LabelSelector{},
+			expected:  []string{"a1", "a2", "a3"},
+		},
+		{
+			name:      "selector without matches does not use the default",
+			namespace: "bar",
+			selector:  &metav1.LabelSelector{MatchLabels: map[string]string{"team": "qa"}},
+			expected:  []string{},
+		},
+		{
+			name:      "invalid selector",
+			namespace: "foo",
+			selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "team", Operator: "Bogus"},
+			}},
+			shdErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			isp := v1beta1.ImageSecurityPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: tc.namespace},
+				Spec: v1beta1.ImageSecurityPolicySpec{
+					AttestationAuthorityNames:    tc.aList,
+					AttestationAuthoritySelector: tc.selector,
+				},
+			}
+			auths, err := r.getAttestationAuthoritiesForISP(isp)
+			var names []string
+			if !tc.shdErr {
+				names = []string{}
+			}
+			for _, a := range auths {
+				names = append(names, a.Name)
+			}
+			testutil.CheckErrorAndDeepEqual(t, tc.shdErr, err, tc.expected, names)
+		})
+	}
+}
+
+func TestAuditDecisions(t *testing.T) {
+	isps := []v1beta1.ImageSecurityPolicy{
+		{ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: "foo"}},
+	}
+	mockValidate := func(ctx context.Context, now time.Time, isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher securitypolicy.AttestorFetcher) ([]policy.Violation, error) {
+		return []policy.Violation{securitypolicy.NewViolation(nil, policy.UnqualifiedImageViolation, "bad image")}, nil
+	}
+	key := fmt.Sprintf("%s-%s", testutil.QualifiedImage, constants.AuditNoteID)
+	tests := []struct {
+		name      string
+		enabled   bool
+		isWebhook bool
+		labels    map[string]string
+		expected  map[string]string
+	}{
+		{"disabled", false, true, nil, nil},
+		{"webhook", true, true, nil, map[string]string{
+			key: testutil.QualifiedImage + " rejected by ImageSecurityPolicy foo/isp in pod foo/pod: UnqualifiedImageViolation: bad image",
+		}},
+		{"cron", true, false, nil, map[string]string{
+			key: testutil.QualifiedImage + " violates ImageSecurityPolicy foo/isp in pod foo/pod: UnqualifiedImageViolation: bad image",
+		}},
+		{"pod already out of policy", true, false, map[string]string{constants.InvalidImageSecPolicy: constants.InvalidImageSecPolicyLabelValue}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			audited = newAuditTracker()
+			client := &testutil.MockMetadataClient{}
+			r := New(client, &Config{
+				Validate:  mockValidate,
+				IsWebhook: tc.isWebhook,
+				Strategy: &violation.MemoryStrategy{
+					Violations:   map[string]bool{},
+					Attestations: map[string]bool{},
+				},
+				ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+				AuditDecisions:                  tc.enabled,
+			})
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "foo", Labels: tc.labels}}
+			err := r.Review([]string{testutil.QualifiedImage}, isps, pod)
+			testutil.CheckErrorAndDeepEqual(t, true, err, tc.expected, client.Discovery)
+		})
+	}
+}
+
+func TestAuditDecisionsListAllViolations(t *testing.T) {
+	audited = newAuditTracker()
+	defer func() { audited = newAuditTracker() }()
+	isps := []v1beta1.ImageSecurityPolicy{
+		{ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: "foo"}},
+	}
+	var violations []policy.Violation
+	var lines []string
+	for i := 0; i <= maxViolationSummaries; i++ {
+		cve := fmt.Sprintf("CVE-2022-%d", i)
+		violations = append(violations, securitypolicy.NewViolation(&metadata.Vulnerability{CVE: cve, Severity: "HIGH"}, policy.SeverityViolation, policy.Reason("found "+cve)))
+		lines = append(lines, "SeverityViolation: found "+cve)
+	}
+	mockValidate := func(ctx context.Context, now time.Time, isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher securitypolicy.AttestorFetcher) ([]policy.Violation, error) {
+		return violations, nil
+	}
+	client := &testutil.MockMetadataClient{}
+	r := New(client, &Config{
+		Validate:                        mockValidate,
+		IsWebhook:                       true,
+		Strategy:                        &violation.LoggingStrategy{},
+		ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+		AuditDecisions:                  true,
+	})
+	err := r.Review([]string{testutil.QualifiedImage}, isps, nil)
+	// The message summarizes the violations, the audit lists them all
+	if err == nil || strings.Contains(err.Error(), "found CVE-2022-0") {
+		t.Errorf("expected the violations to be summarized, got %v", err)
+	}
+	key := fmt.Sprintf("%s-%s", testutil.QualifiedImage, constants.AuditNoteID)
+	testutil.DeepEqual(t, testutil.QualifiedImage+" rejected by ImageSecurityPolicy foo/isp: "+strings.Join(lines, ", "), client.Discovery[key])
+}
+
+func TestAuditDecisionsDeduplicated(t *testing.T) {
+	audited = newAuditTracker()
+	defer func() { audited = newAuditTracker() }()
+	isps := []v1beta1.ImageSecurityPolicy{
+		{ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: "foo"}},
+	}
+	mockValidate := func(ctx context.Context, now time.Time, isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher securitypolicy.AttestorFetcher) ([]policy.Violation, error) {
+		return []policy.Violation{securitypolicy.NewViolation(nil, policy.UnqualifiedImageViolation, "bad image")}, nil
+	}
+	review := func(pod string) map[string]string {
+		client := &testutil.MockMetadataClient{}
+		r := New(client, &Config{
+			Validate:  mockValidate,
+			IsWebhook: true,
+			Strategy: &violation.MemoryStrategy{
+				Violations:   map[string]bool{},
+				Attestations: map[string]bool{},
+			},
+			ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+			AuditDecisions:                  true,
+		})
+		testutil.CheckError(t, true, r.Review([]string{testutil.QualifiedImage}, isps, &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: pod, Namespace: "foo"}}))
+		return client.Discovery
+	}
+	if d := review("first"); len(d) != 1 {
+		t.Fatalf("expected the first review to be audited, got %v", d)
+	}
+	if d := review("second"); len(d) != 0 {
+		t.Errorf("expected the same decision on another pod not to be audited again, got %v", d)
+	}
+	now := time.Now()
+	audited.now = func() time.Time { return now.Add(auditTTL) }
+	if d := review("third"); len(d) != 1 {
+		t.Errorf("expected the decision to be audited again after %s, got %v", auditTTL, d)
+	}
+}
+
+func TestRecordReview(t *testing.T) {
+	isps := []v1beta1.ImageSecurityPolicy{
+		{ObjectMeta: metav1.ObjectMeta{Name: "good", Namespace: "foo"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "bad", Namespace: "foo"}},
+	}
+	mockValidate := func(ctx context.Context, now time.Time, isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher securitypolicy.AttestorFetcher) ([]policy.Violation, error) {
+		if isp.Name == "bad" {
+			return []policy.Violation{securitypolicy.NewViolation(nil, policy.UnqualifiedImageViolation, "bad image")}, nil
+		}
+		return nil, nil
+	}
+	var reviews []*v1beta1.ImageReview
+	r := New(&testutil.MockMetadataClient{}, &Config{
+		Validate: mockValidate,
+		Strategy: &violation.MemoryStrategy{
+			Violations:   map[string]bool{},
+			Attestations: map[string]bool{},
+		},
+		ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+		RecordReview: func(ir *v1beta1.ImageReview) error {
+			reviews = append(reviews, ir)
+			return nil
+		},
+	})
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "foo"}}
+	if err := r.Review([]string{testutil.QualifiedImage}, isps, pod); err == nil {
+		t.Fatal("expected violations")
+	}
+	var specs []v1beta1.ImageReviewSpec
+	var violations [][]v1beta1.ImageReviewViolation
+	for _, ir := range reviews {
+		specs = append(specs, ir.Spec)
+		violations = append(violations, ir.Status.Violations)
+	}
+	testutil.DeepEqual(t, []v1beta1.ImageReviewSpec{
+		{Image: testutil.QualifiedImage, PolicyKind: "ImageSecurityPolicy", Policy: "good", Pod: "pod", Source: "cron"},
+		{Image: testutil.QualifiedImage, PolicyKind: "ImageSecurityPolicy", Policy: "bad", Pod: "pod", Source: "cron"},
+	}, specs)
+	testutil.DeepEqual(t, [][]v1beta1.ImageReviewViolation{
+		nil,
+		{{Type: "UnqualifiedImageViolation", Reason: "bad image"}},
+	}, violations)
+}
+
+func TestValidate(t *testing.T) {
+	isps := []v1beta1.ImageSecurityPolicy{
+		{ObjectMeta: metav1.ObjectMeta{Name: "good", Namespace: "foo"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "bad", Namespace: "foo"}},
+	}
+	reviewTime := time.Date(2018, 10, 31, 0, 0, 0, 0, time.UTC)
+	mockValidate := func(ctx context.Context, now time.Time, isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher securitypolicy.AttestorFetcher) ([]policy.Violation, error) {
+		// Policies are validated at the time of the reviewer clock.
+		if !now.Equal(reviewTime) {
+			return nil, fmt.Errorf("validated at %s, expected %s", now, reviewTime)
+		}
+		if isp.Name == "bad" {
+			return []policy.Violation{securitypolicy.NewViolation(nil, policy.UnqualifiedImageViolation, "bad image")}, nil
+		}
+		return nil, nil
+	}
+	var reviews []*v1beta1.ImageReview
+	r := New(&testutil.MockMetadataClient{}, &Config{
+		Validate:                        mockValidate,
+		Strategy:                        &violation.MemoryStrategy{Violations: map[string]bool{}, Attestations: map[string]bool{}},
+		ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+		RecordReview: func(ir *v1beta1.ImageReview) error {
+			reviews = append(reviews, ir)
+			return nil
+		},
+		Now: func() time.Time { return reviewTime },
+	})
+
+	// Validate reports the violations of every policy, without handling them.
+	vss, err := r.Validate(context.Background(), testutil.QualifiedImage, isps)
+	testutil.CheckError(t, false, err)
+	testutil.DeepEqual(t, 2, len(vss))
+	testutil.DeepEqual(t, 0, len(vss[0]))
+	testutil.DeepEqual(t, 1, len(vss[1]))
+	testutil.DeepEqual(t, 0, len(reviews))
+
+	// Images whitelisted by the cluster pass all policies.
+	whitelisted := New(&testutil.MockMetadataClient{}, &Config{
+		Validate:                        mockValidate,
+		ClusterWhitelistedImagesRemover: func([]string) ([]string, error) { return nil, nil },
+	})
+	vss, err = whitelisted.Validate(context.Background(), testutil.QualifiedImage, isps)
+	testutil.CheckErrorAndDeepEqual(t, false, err, make([][]policy.Violation, 2), vss)
+
+	// Reviews are recorded with the time of the reviewer clock.
+	if err := r.ReviewContext(context.Background(), []string{testutil.QualifiedImage}, isps, nil); err == nil {
+		t.Fatal("expected violations")
+	}
+	for _, ir := range reviews {
+		testutil.DeepEqual(t, reviewTime, ir.Status.ReviewTime.Time.UTC())
+	}
+}
+
+func TestViolationStrategies(t *testing.T) {
+	mockValidate := func(ctx context.Context, now time.Time, isp v1beta1.ImageSecurityPolicy, image string, metadataFetcher metadata.Fetcher, attestorFetcher securitypolicy.AttestorFetcher) ([]policy.Violation, error) {
+		if isp.Name == "audited" {
+			return []policy.Violation{securitypolicy.NewViolation(nil, policy.UnqualifiedImageViolation, "bad image")}, nil
+		}
+		return nil, nil
+	}
+	tests := []struct {
+		name       string
+		strategies []string
+		isWebhook  bool
+		shdErr     bool
+		violations map[string]bool
+	}{
+		{"webhook admits without deny", []string{"log"}, true, false, map[string]bool{}},
+		{"webhook denies", []string{"log", "deny"}, true, true, map[string]bool{}},
+		{"cron reports without deny", []string{"log"}, false, true, map[string]bool{}},
+		{"unknown strategy", []string{"panic"}, true, true, map[string]bool{}},
+		{"global strategy", nil, true, true, map[string]bool{testutil.QualifiedImage: true}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			isps := []v1beta1.ImageSecurityPolicy{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "audited", Namespace: "foo"},
+					Spec:       v1beta1.ImageSecurityPolicySpec{ViolationStrategies: tc.strategies},
+				},
+				{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "foo"}},
+			}
+			strategy := &violation.MemoryStrategy{
+				Violations:   map[string]bool{},
+				Attestations: map[string]bool{},
+			}
+			var reviewed []string
+			r := New(&testutil.MockMetadataClient{}, &Config{
+				Validate: mockValidate,
+				Auths: func(ns string, name string) (*v1beta1.AttestationAuthority, error) {
+					return nil, fmt.Errorf("no authority %s", name)
+				},
+				Strategy:                        strategy,
+				IsWebhook:                       tc.isWebhook,
+				ClusterWhitelistedImagesRemover: NoopClusterWhitelistedImagesRemover,
+				RecordReview: func(ir *v1beta1.ImageReview) error {
+					reviewed = append(reviewed, ir.Spec.Policy)
+					return nil
+				},
+			})
+			err := r.Review([]string{testutil.QualifiedImage}, isps, nil)
+			testutil.CheckErrorAndDeepEqual(t, tc.shdErr, err, tc.violations, strategy.Violations)
+			if !tc.shdErr {
+				// The policies following the admitted violations are still reviewed
+				testutil.DeepEqual(t, []string{"audited", "other"}, reviewed)
+			}
+		})
+	}
+}
+
+func TestUnwhitelistedImages(t *testing.T) {
+	isps := []v1beta1.ImageSecurityPolicy{
+		{Spec: v1beta1.ImageSecurityPolicySpec{ImageWhitelist: []string{"gcr.io/a", "gcr.io/b"}}},
+		{Spec: v1beta1.ImageSecurityPolicySpec{ImageWhitelist: []string{"gcr.io/a"}}},
+	}
+	// Only images whitelisted by every policy are not prefetched.
+	actual := unwhitelistedImages([]string{"gcr.io/a", "gcr.io/b", "gcr.io/c"}, isps)
+	testutil.DeepEqual(t, []string{"gcr.io/b", "gcr.io/c"}, actual)
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package review
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+)
+
+// Violations are summarized one per line up to maxViolationSummaries. Beyond, the
+// vulnerabilities of a type and severity are grouped, naming their most frequent
+// packages and a sample of their CVEs.
+var (
+	maxViolationSummaries = 20
+	summarySampleSize     = 3
+)
+
+// vulnerabilityGroup holds the vulnerabilities of a violation type and severity. The
+// same CVE found in a package, e.g. in the images of several platforms, counts once.
+type vulnerabilityGroup struct {
+	vType    policy.ViolationType
+	severity string
+	cves     []string
+	packages map[string]int
+	seen     map[string]bool
+}
+
+// violationLines returns one line per distinct violation.
+func violationLines(violations []policy.Violation) []string {
+	var lines []string
+	seen := map[string]bool{}
+	for _, v := range violations {
+		l := fmt.Sprintf("%s: %s", v.Type().ToString(), v.Reason())
+		if !seen[l] {
+			seen[l] = true
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// summarized returns true if violations are too many to list in review messages.
+func summarized(violations []policy.Violation) bool {
+	return len(violationLines(violations)) > maxViolationSummaries
+}
+
+// summarizeViolations returns the lines of the violations in review messages, grouping
+// vulnerabilities if there are too many to list.
+func summarizeViolations(violations []policy.Violation) []string {
+	if !summarized(violations) {
+		return violationLines(violations)
+	}
+	var others []policy.Violation
+	groups := map[string]*vulnerabilityGroup{}
+	var keys []string
+	for _, v := range violations {
+		vuln, ok := v.Details().(metadata.Vulnerability)
+		if !ok || vuln.CVE == "" {
+			others = append(others, v)
+			continue
+		}
+		severity := vuln.CanonicalSeverity()
+		key := v.Type().ToString() + "/" + severity
+		g, ok := groups[key]
+		if !ok {
+			g = &vulnerabilityGroup{vType: v.Type(), severity: severity, packages: map[string]int{}, seen: map[string]bool{}}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		g.add(vuln)
+	}
+	summaries := violationLines(others)
+	for _, k := range keys {
+		summaries = append(summaries, groups[k].summary())
+	}
+	return summaries
+}
+
+func (g *vulnerabilityGroup) add(vuln metadata.Vulnerability) {
+	if !g.seen[vuln.CVE] {
+		g.seen[vuln.CVE] = true
+		g.cves = append(g.cves, vuln.CVE)
+	}
+	if key := vuln.CVE + "\x00" + vuln.Package; vuln.Package != "" && !g.seen[key] {
+		g.seen[key] = true
+		g.packages[vuln.Package]++
+	}
+}
+
+// summary describes the group, e.g. "SeverityViolation: 120 CVEs of severity HIGH, most in
+// openssl (40), glibc (12), curl (9), e.g. CVE-2022-0001, CVE-2022-0002, CVE-2022-0003".
+func (g *vulnerabilityGroup) summary() string {
+	s := fmt.Sprintf("%s: %d CVEs of severity %s", g.vType.ToString(), len(g.cves), g.severity)
+	if len(g.packages) > 0 {
+		packages := make([]string, 0, len(g.packages))
+		for p := range g.packages {
+			packages = append(packages, p)
+		}
+		sort.Slice(packages, func(i, j int) bool {
+			if g.packages[packages[i]] != g.packages[packages[j]] {
+				return g.packages[packages[i]] > g.packages[packages[j]]
+			}
+			return packages[i] < packages[j]
+		})
+		if len(packages) > summarySampleSize {
+			packages = packages[:summarySampleSize]
+		}
+		for i, p := range packages {
+			packages[i] = fmt.Sprintf("%s (%d)", p, g.packages[p])
+		}
+		s += fmt.Sprintf(", most in %s", strings.Join(packages, ", "))
+	}
+	cves := g.cves
+	if len(cves) > summarySampleSize {
+		cves = cves[:summarySampleSize]
+	}
+	return s + ", e.g. " + strings.Join(cves, ", ")
+}
+
+// fullListHint returns how to list all violations of the image, when they were summarized.
+func (r Reviewer) fullListHint(isp v1beta1.ImageSecurityPolicy, image string) string {
+	if r.config.RecordReview != nil {
+		return fmt.Sprintf("list all violations with: kubectl get imagereviews -n %s -l %s=%s -o yaml",
+			isp.Namespace, constants.ImageReviewPolicy, isp.Name)
+	}
+	return fmt.Sprintf("list all violations with: kubectl get imagesecuritypolicy -n %s %s -o yaml > isp.yaml && kritis check --image %s --policy isp.yaml",
+		isp.Namespace, isp.Name, image)
+}
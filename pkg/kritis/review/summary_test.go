@@ -0,0 +1,105 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package review
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSummarizeViolations(t *testing.T) {
+	defer func(m int) { maxViolationSummaries = m }(maxViolationSummaries)
+	maxViolationSummaries = 3
+
+	vuln := func(cve, severity, pkg string) policy.Violation {
+		v := metadata.Vulnerability{CVE: cve, Severity: severity, Package: pkg}
+		return securitypolicy.NewViolation(&v, policy.SeverityViolation, policy.Reason("found "+cve))
+	}
+	platformVuln := func(cve, severity, pkg, platform string) policy.Violation {
+		v := metadata.Vulnerability{CVE: cve, Severity: severity, Package: pkg}
+		return securitypolicy.NewViolation(&v, policy.SeverityViolation, securitypolicy.PlatformReason(policy.Reason("found "+cve), platform))
+	}
+	unqualified := securitypolicy.NewViolation(nil, policy.UnqualifiedImageViolation, "not qualified")
+
+	var tests = []struct {
+		name       string
+		violations []policy.Violation
+		expected   []string
+	}{
+		{
+			name:       "few violations are listed",
+			violations: []policy.Violation{vuln("CVE-1", "HIGH", "openssl"), unqualified},
+			expected:   []string{"SeverityViolation: found CVE-1", "UnqualifiedImageViolation: not qualified"},
+		},
+		{
+			name: "many violations are grouped",
+			violations: []policy.Violation{
+				vuln("CVE-1", "HIGH", "openssl"),
+				vuln("CVE-2", "CRITICAL", "glibc"),
+				vuln("CVE-3", "HIGH", "curl"),
+				unqualified,
+				vuln("CVE-4", "HIGH", "openssl"),
+				vuln("CVE-5", "HIGH", "zlib"),
+				vuln("CVE-6", "HIGH", "glibc"),
+			},
+			expected: []string{
+				"UnqualifiedImageViolation: not qualified",
+				"SeverityViolation: 5 CVEs of severity HIGH, most in openssl (2), curl (1), glibc (1), e.g. CVE-1, CVE-3, CVE-4",
+				"SeverityViolation: 1 CVEs of severity CRITICAL, most in glibc (1), e.g. CVE-2",
+			},
+		},
+		{
+			name:       "identical violations are listed once",
+			violations: []policy.Violation{vuln("CVE-1", "HIGH", "openssl"), unqualified, vuln("CVE-1", "HIGH", "openssl"), unqualified},
+			expected:   []string{"SeverityViolation: found CVE-1", "UnqualifiedImageViolation: not qualified"},
+		},
+		{
+			name: "identical CVEs are counted once",
+			violations: []policy.Violation{
+				platformVuln("CVE-1", "HIGH", "openssl", "linux/amd64"),
+				platformVuln("CVE-1", "HIGH", "openssl", "linux/arm64"),
+				platformVuln("CVE-2", "HIGH", "glibc", "linux/amd64"),
+				platformVuln("CVE-2", "HIGH", "glibc", "linux/arm64"),
+			},
+			expected: []string{"SeverityViolation: 2 CVEs of severity HIGH, most in glibc (1), openssl (1), e.g. CVE-1, CVE-2"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testutil.DeepEqual(t, test.expected, summarizeViolations(test.violations))
+		})
+	}
+}
+
+func TestFullListHint(t *testing.T) {
+	isp := v1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar"}}
+	image := "gcr.io/image@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+	r := New(nil, &Config{})
+	expected := fmt.Sprintf("list all violations with: kubectl get imagesecuritypolicy -n bar foo -o yaml > isp.yaml && kritis check --image %s --policy isp.yaml", image)
+	testutil.DeepEqual(t, expected, r.fullListHint(isp, image))
+
+	r = New(nil, &Config{RecordReview: func(*v1beta1.ImageReview) error { return nil }})
+	testutil.DeepEqual(t, "list all violations with: kubectl get imagereviews -n bar -l kritis.grafeas.io/policy=foo -o yaml", r.fullListHint(isp, image))
+}
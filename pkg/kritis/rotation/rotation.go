@@ -0,0 +1,267 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rotation rotates the PGP keys of AttestationAuthorities signing with the key of a
+// Kubernetes Secret. The previous public key of a rotated authority stays in its previousKeys,
+// so its attestations are verified during a grace period while images are attested again
+// with the new key.
+package rotation
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	clientset "github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/util"
+)
+
+// DefaultGracePeriod is how long the attestations of a rotated key are still verified.
+const DefaultGracePeriod = 7 * 24 * time.Hour
+
+// Result describes a key rotation.
+type Result struct {
+	// Authority is the updated AttestationAuthority
+	Authority *v1beta1.AttestationAuthority
+	// Secret signs with the new key
+	Secret         *secrets.PGPSigningSecret
+	OldFingerprint string
+	NewFingerprint string
+	RetireTime     time.Time
+}
+
+// Rotate generates a new key pair for the AttestationAuthority namespace/name. The key pair is
+// written to its Secret first, so that the new private key is never lost, then the authority
+// is updated with the new publicKeyData and its previous key retiring at now+grace. The
+// Secret is restored if the authority can't be updated.
+func Rotate(kritis clientset.Interface, kube kubernetes.Interface, namespace, name string, grace time.Duration, now time.Time) (*Result, error) {
+	auth, err := kritis.KritisV1beta1().AttestationAuthorities(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get AttestationAuthority %s/%s", namespace, name)
+	}
+	switch {
+	case auth.Spec.KMSKeyName != "" || auth.Spec.PublicKeyID != "":
+		return nil, fmt.Errorf("AttestationAuthority %s/%s has a PKIX key, rotate it with its KMS and update publicKeyData", namespace, name)
+	case auth.Spec.VaultKey != nil:
+		return nil, fmt.Errorf("AttestationAuthority %s/%s signs with a Vault key, rotate it in Vault", namespace, name)
+	case auth.Spec.SecretManagerKey != nil:
+		return nil, fmt.Errorf("AttestationAuthority %s/%s signs with a Secret Manager key, add a secret version and update publicKeyData", namespace, name)
+	}
+	secret, err := kube.CoreV1().Secrets(namespace).Get(auth.Spec.PrivateKeySecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get secret %s/%s of AttestationAuthority %s", namespace, auth.Spec.PrivateKeySecretName, name)
+	}
+	oldPub, err := base64.StdEncoding.DecodeString(auth.Spec.PublicKeyData)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid publicKeyData of AttestationAuthority %s/%s", namespace, name)
+	}
+	oldKey, err := secrets.NewPgpKey("", "", string(oldPub))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid publicKeyData of AttestationAuthority %s/%s", namespace, name)
+	}
+	priv, pub, err := secrets.GeneratePgpKey(name, fmt.Sprintf("kritis AttestationAuthority %s/%s", namespace, name))
+	if err != nil {
+		return nil, err
+	}
+	newKey, err := secrets.NewPgpKey(priv, "", pub)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := map[string][]byte{}
+	for k, v := range secret.Data {
+		previous[k] = v
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[secrets.PrivateKey] = []byte(priv)
+	secret.Data[secrets.PublicKey] = []byte(pub)
+	// The new key is not encrypted.
+	delete(secret.Data, secrets.Passphrase)
+	secret, err = kube.CoreV1().Secrets(namespace).Update(secret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update secret %s/%s of AttestationAuthority %s", namespace, auth.Spec.PrivateKeySecretName, name)
+	}
+
+	retire := now.Add(grace)
+	updated := auth.DeepCopy()
+	updated.Spec.PreviousKeys = append(updated.Spec.PreviousKeys, v1beta1.PreviousKey{
+		PublicKeyData: auth.Spec.PublicKeyData,
+		RetireTime:    metav1.NewTime(retire),
+	})
+	updated.Spec.PublicKeyData = base64.StdEncoding.EncodeToString([]byte(pub))
+	auth, err = kritis.KritisV1beta1().AttestationAuthorities(namespace).Update(updated)
+	if err != nil {
+		secret.Data = previous
+		if _, rerr := kube.CoreV1().Secrets(namespace).Update(secret); rerr != nil {
+			return nil, fmt.Errorf("failed to update AttestationAuthority %s/%s: %v, and to restore its secret %s, which holds the new key of fingerprint %s: %v. Set the publicKeyData of the authority to the base64 encoded %q key of the secret, and add the previous one to its previousKeys",
+				namespace, name, err, secret.Name, newKey.Fingerprint(), rerr, secrets.PublicKey)
+		}
+		return nil, errors.Wrapf(err, "failed to update AttestationAuthority %s/%s, its secret %s was restored", namespace, name, secret.Name)
+	}
+	return &Result{
+		Authority:      auth,
+		Secret:         &secrets.PGPSigningSecret{PgpKey: newKey, SecretName: secret.Name},
+		OldFingerprint: oldKey.Fingerprint(),
+		NewFingerprint: newKey.Fingerprint(),
+		RetireTime:     retire,
+	}, nil
+}
+
+// Current returns the last rotation of the AttestationAuthority namespace/name: its newest
+// previous key, and the key pair of its Secret, which must be its publicKeyData. It is used to
+// attest images again after Rotate, e.g. when Reattest failed, without rotating the key again.
+func Current(kritis clientset.Interface, kube kubernetes.Interface, namespace, name string) (*Result, error) {
+	auth, err := kritis.KritisV1beta1().AttestationAuthorities(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get AttestationAuthority %s/%s", namespace, name)
+	}
+	if len(auth.Spec.PreviousKeys) == 0 {
+		return nil, fmt.Errorf("AttestationAuthority %s/%s has no previousKeys, its key was not rotated", namespace, name)
+	}
+	// Rotate appends the previous key, so the last one is the newest.
+	previous := auth.Spec.PreviousKeys[len(auth.Spec.PreviousKeys)-1]
+	oldPub, err := base64.StdEncoding.DecodeString(previous.PublicKeyData)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid previousKeys of AttestationAuthority %s/%s", namespace, name)
+	}
+	oldKey, err := secrets.NewPgpKey("", "", string(oldPub))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid previousKeys of AttestationAuthority %s/%s", namespace, name)
+	}
+	currentPub, err := base64.StdEncoding.DecodeString(auth.Spec.PublicKeyData)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid publicKeyData of AttestationAuthority %s/%s", namespace, name)
+	}
+	currentKey, err := secrets.NewPgpKey("", "", string(currentPub))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid publicKeyData of AttestationAuthority %s/%s", namespace, name)
+	}
+	secret, err := kube.CoreV1().Secrets(namespace).Get(auth.Spec.PrivateKeySecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get secret %s/%s of AttestationAuthority %s", namespace, auth.Spec.PrivateKeySecretName, name)
+	}
+	// Rotate stores the new key unencrypted.
+	priv := string(secret.Data[secrets.PrivateKey])
+	if priv == "" {
+		return nil, fmt.Errorf("secret %s/%s of AttestationAuthority %s has no %q key", namespace, secret.Name, name, secrets.PrivateKey)
+	}
+	newKey, err := secrets.NewPgpKey(priv, "", string(secret.Data[secrets.PublicKey]))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid key in secret %s/%s of AttestationAuthority %s", namespace, secret.Name, name)
+	}
+	if newKey.Fingerprint() != currentKey.Fingerprint() {
+		return nil, fmt.Errorf("secret %s/%s holds the key of fingerprint %s, not the publicKeyData of AttestationAuthority %s of fingerprint %s",
+			namespace, secret.Name, newKey.Fingerprint(), name, currentKey.Fingerprint())
+	}
+	return &Result{
+		Authority:      auth,
+		Secret:         &secrets.PGPSigningSecret{PgpKey: newKey, SecretName: secret.Name},
+		OldFingerprint: oldKey.Fingerprint(),
+		NewFingerprint: newKey.Fingerprint(),
+		RetireTime:     previous.RetireTime.Time,
+	}, nil
+}
+
+// RecentImages returns the images of the ImageReviews of namespace reviewed since then.
+func RecentImages(kritis clientset.Interface, namespace string, since time.Time) ([]string, error) {
+	list, err := kritis.KritisV1beta1().ImageReviews(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the ImageReviews of %s", namespace)
+	}
+	seen := map[string]bool{}
+	images := []string{}
+	for _, ir := range list.Items {
+		if ir.Status.ReviewTime.Time.Before(since) || seen[ir.Spec.Image] {
+			continue
+		}
+		seen[ir.Spec.Image] = true
+		images = append(images, ir.Spec.Image)
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// Reattest attests the images which have an attestation signed with the key oldFingerprint
+// again, with secret on behalf of auth, unless they already have one signed with newFingerprint.
+// It returns the images attested.
+func Reattest(ctx context.Context, client metadata.Fetcher, auth *v1beta1.AttestationAuthority, secret *secrets.PGPSigningSecret, oldFingerprint, newFingerprint string, images []string) ([]string, error) {
+	attested := []string{}
+	if len(images) == 0 {
+		return attested, nil
+	}
+	n, err := util.GetOrCreateAttestationNote(ctx, client, auth)
+	if err != nil {
+		return attested, errors.Wrapf(err, "failed to get note of %s", auth.Name)
+	}
+	for _, image := range images {
+		atts, err := client.Attestations(ctx, image)
+		if err != nil {
+			return attested, errors.Wrapf(err, "failed to get attestations of %s", image)
+		}
+		if !signedWith(atts, oldFingerprint) || signedWith(atts, newFingerprint) {
+			continue
+		}
+		if _, err := client.CreateAttestationOccurence(ctx, n, image, secret); err != nil {
+			return attested, errors.Wrapf(err, "failed to attest %s", image)
+		}
+		attested = append(attested, image)
+	}
+	return attested, nil
+}
+
+func signedWith(atts []metadata.PGPAttestation, fingerprint string) bool {
+	for _, a := range atts {
+		if util.SameKeyID(a.KeyID, fingerprint) {
+			return true
+		}
+	}
+	return false
+}
+
+// Retire removes the previous keys of the AttestationAuthority namespace/name retired at now,
+// or all of them if all is set, and returns the number of keys removed.
+func Retire(kritis clientset.Interface, namespace, name string, now time.Time, all bool) (int, error) {
+	auth, err := kritis.KritisV1beta1().AttestationAuthorities(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get AttestationAuthority %s/%s", namespace, name)
+	}
+	keys := []v1beta1.PreviousKey{}
+	for _, k := range auth.Spec.PreviousKeys {
+		if !all && now.Before(k.RetireTime.Time) {
+			keys = append(keys, k)
+		}
+	}
+	removed := len(auth.Spec.PreviousKeys) - len(keys)
+	if removed == 0 {
+		return 0, nil
+	}
+	auth.Spec.PreviousKeys = keys
+	if _, err := kritis.KritisV1beta1().AttestationAuthorities(namespace).Update(auth); err != nil {
+		return 0, errors.Wrapf(err, "failed to update AttestationAuthority %s/%s", namespace, name)
+	}
+	return removed, nil
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotation
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/client/clientset/versioned/fake"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+var now = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestRotate(t *testing.T) {
+	old, pub := testutil.CreateSecret(t, "qa")
+	auth := &v1beta1.AttestationAuthority{
+		ObjectMeta: metav1.ObjectMeta{Name: "qa-attestator", Namespace: "qa"},
+		Spec: v1beta1.AttestationAuthoritySpec{
+			NoteReference:        "v1beta1/projects/qa",
+			PrivateKeySecretName: "qa-key",
+			PublicKeyData:        base64.StdEncoding.EncodeToString([]byte(pub)),
+		},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "qa-key", Namespace: "qa"},
+		Data:       map[string][]byte{secrets.PublicKey: []byte(pub), secrets.PrivateKey: []byte("old"), secrets.Passphrase: []byte("cGFzcw==")},
+	}
+	kritis := fake.NewSimpleClientset(auth)
+	kube := kubefake.NewSimpleClientset(secret)
+
+	r, err := Rotate(kritis, kube, "qa", "qa-attestator", time.Hour, now)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	testutil.DeepEqual(t, old.PgpKey.Fingerprint(), r.OldFingerprint)
+	testutil.DeepEqual(t, r.Secret.PgpKey.Fingerprint(), r.NewFingerprint)
+	updated, _ := kritis.KritisV1beta1().AttestationAuthorities("qa").Get("qa-attestator", metav1.GetOptions{})
+	testutil.DeepEqual(t, []v1beta1.PreviousKey{{PublicKeyData: auth.Spec.PublicKeyData, RetireTime: metav1.NewTime(now.Add(time.Hour))}}, updated.Spec.PreviousKeys)
+	updatedSecret, _ := kube.CoreV1().Secrets("qa").Get("qa-key", metav1.GetOptions{})
+	if _, ok := updatedSecret.Data[secrets.Passphrase]; ok {
+		t.Errorf("expected the passphrase of the old key to be removed")
+	}
+	newSecret, err := secrets.NewPgpKey(string(updatedSecret.Data[secrets.PrivateKey]), "", string(updatedSecret.Data[secrets.PublicKey]))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	testutil.DeepEqual(t, r.NewFingerprint, newSecret.Fingerprint())
+	newPub, _ := base64.StdEncoding.DecodeString(updated.Spec.PublicKeyData)
+	testutil.DeepEqual(t, string(updatedSecret.Data[secrets.PublicKey]), string(newPub))
+
+	// Keys outside of Kubernetes Secrets are rotated in their backend.
+	kms := auth.DeepCopy()
+	kms.Name = "kms"
+	kms.Spec.KMSKeyName = "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1"
+	if _, err := Rotate(fake.NewSimpleClientset(kms), kube, "qa", "kms", time.Hour, now); err == nil {
+		t.Errorf("expected error rotating a KMS key")
+	}
+}
+
+func TestRotateFailure(t *testing.T) {
+	_, pub := testutil.CreateSecret(t, "qa")
+	auth := &v1beta1.AttestationAuthority{
+		ObjectMeta: metav1.ObjectMeta{Name: "qa-attestator", Namespace: "qa"},
+		Spec: v1beta1.AttestationAuthoritySpec{
+			NoteReference:        "v1beta1/projects/qa",
+			PrivateKeySecretName: "qa-key",
+			PublicKeyData:        base64.StdEncoding.EncodeToString([]byte(pub)),
+		},
+	}
+	data := map[string][]byte{secrets.PublicKey: []byte(pub), secrets.PrivateKey: []byte("old")}
+	failUpdate := func(calls *int, after int) k8stesting.ReactionFunc {
+		return func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if *calls++; *calls > after {
+				return true, nil, fmt.Errorf("conflict")
+			}
+			return false, nil, nil
+		}
+	}
+	for _, test := range []struct {
+		name          string
+		secretUpdates int
+		restored      bool
+	}{
+		{"secret is restored", 2, true},
+		{"secret can't be restored", 1, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			kritis := fake.NewSimpleClientset(auth)
+			var authUpdates, secretUpdates int
+			kritis.PrependReactor("update", "attestationauthorities", failUpdate(&authUpdates, 0))
+			kube := kubefake.NewSimpleClientset(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "qa-key", Namespace: "qa"}, Data: data})
+			kube.PrependReactor("update", "secrets", failUpdate(&secretUpdates, test.secretUpdates))
+
+			_, err := Rotate(kritis, kube, "qa", "qa-attestator", time.Hour, now)
+			testutil.CheckError(t, true, err)
+			unchanged, _ := kritis.KritisV1beta1().AttestationAuthorities("qa").Get("qa-attestator", metav1.GetOptions{})
+			testutil.DeepEqual(t, auth.Spec, unchanged.Spec)
+			secret, _ := kube.CoreV1().Secrets("qa").Get("qa-key", metav1.GetOptions{})
+			testutil.DeepEqual(t, test.restored, string(secret.Data[secrets.PrivateKey]) == "old")
+			// The new key is kept in the secret otherwise
+			if !test.restored && !strings.Contains(err.Error(), "holds the new key") {
+				t.Errorf("expected the error to point to the new key, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	_, pub := testutil.CreateSecret(t, "qa")
+	auth := &v1beta1.AttestationAuthority{
+		ObjectMeta: metav1.ObjectMeta{Name: "qa-attestator", Namespace: "qa"},
+		Spec: v1beta1.AttestationAuthoritySpec{
+			NoteReference:        "v1beta1/projects/qa",
+			PrivateKeySecretName: "qa-key",
+			PublicKeyData:        base64.StdEncoding.EncodeToString([]byte(pub)),
+		},
+	}
+	kritis := fake.NewSimpleClientset(auth)
+	kube := kubefake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "qa-key", Namespace: "qa"},
+		Data:       map[string][]byte{secrets.PublicKey: []byte(pub), secrets.PrivateKey: []byte("old")},
+	})
+	if _, err := Current(kritis, kube, "qa", "qa-attestator"); err == nil {
+		t.Errorf("expected error for an authority which was not rotated")
+	}
+
+	rotated, err := Rotate(kritis, kube, "qa", "qa-attestator", time.Hour, now)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	r, err := Current(kritis, kube, "qa", "qa-attestator")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	testutil.DeepEqual(t, rotated.OldFingerprint, r.OldFingerprint)
+	testutil.DeepEqual(t, rotated.NewFingerprint, r.NewFingerprint)
+	testutil.DeepEqual(t, rotated.NewFingerprint, r.Secret.PgpKey.Fingerprint())
+	testutil.DeepEqual(t, now.Add(time.Hour), r.RetireTime)
+}
+
+func TestRecentImages(t *testing.T) {
+	review := func(name, image string, reviewed time.Time) *v1beta1.ImageReview {
+		return &v1beta1.ImageReview{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "qa"},
+			Spec:       v1beta1.ImageReviewSpec{Image: image},
+			Status:     v1beta1.ImageReviewStatus{ReviewTime: metav1.NewTime(reviewed)},
+		}
+	}
+	kritis := fake.NewSimpleClientset(
+		review("a", "gcr.io/p/b@sha256:1", now),
+		review("b", "gcr.io/p/a@sha256:1", now.Add(-time.Hour)),
+		review("c", "gcr.io/p/b@sha256:1", now.Add(-time.Hour)),
+		review("d", "gcr.io/p/old@sha256:1", now.Add(-48*time.Hour)),
+	)
+	images, err := RecentImages(kritis, "qa", now.Add(-24*time.Hour))
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{"gcr.io/p/a@sha256:1", "gcr.io/p/b@sha256:1"}, images)
+}
+
+func TestReattest(t *testing.T) {
+	auth := &v1beta1.AttestationAuthority{
+		ObjectMeta: metav1.ObjectMeta{Name: "qa-attestator"},
+		Spec:       v1beta1.AttestationAuthoritySpec{NoteReference: "v1beta1/projects/qa"},
+	}
+	oldFpr, newFpr := "0123456789ABCDEF0123456789ABCDEF01234567", "89ABCDEF0123456789ABCDEF0123456789ABCDEF"
+	client := &testutil.MockMetadataClient{
+		Images: map[string]testutil.MockImage{
+			"old":   {PGPAttestations: []metadata.PGPAttestation{{KeyID: oldFpr[24:]}}},
+			"both":  {PGPAttestations: []metadata.PGPAttestation{{KeyID: oldFpr}, {KeyID: newFpr}}},
+			"other": {PGPAttestations: []metadata.PGPAttestation{{KeyID: "FFFF"}}},
+		},
+	}
+	secret := &secrets.PGPSigningSecret{SecretName: "qa-key"}
+	attested, err := Reattest(context.Background(), client, auth, secret, oldFpr, newFpr, []string{"old", "both", "other"})
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{"old"}, attested)
+	testutil.DeepEqual(t, map[string]string{"old-v1beta1/projects/qa": "qa-key"}, client.Occ)
+}
+
+func TestRetire(t *testing.T) {
+	auth := &v1beta1.AttestationAuthority{
+		ObjectMeta: metav1.ObjectMeta{Name: "qa-attestator", Namespace: "qa"},
+		Spec: v1beta1.AttestationAuthoritySpec{
+			PreviousKeys: []v1beta1.PreviousKey{
+				{PublicKeyData: "retired", RetireTime: metav1.NewTime(now)},
+				{PublicKeyData: "retiring", RetireTime: metav1.NewTime(now.Add(time.Hour))},
+			},
+		},
+	}
+	kritis := fake.NewSimpleClientset(auth)
+	removed, err := Retire(kritis, "qa", "qa-attestator", now, false)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 1, removed)
+	updated, _ := kritis.KritisV1beta1().AttestationAuthorities("qa").Get("qa-attestator", metav1.GetOptions{})
+	testutil.DeepEqual(t, []v1beta1.PreviousKey{auth.Spec.PreviousKeys[1]}, updated.Spec.PreviousKeys)
+
+	removed, err = Retire(kritis, "qa", "qa-attestator", now, true)
+	testutil.CheckErrorAndDeepEqual(t, false, err, 1, removed)
+}
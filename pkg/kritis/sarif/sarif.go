@@ -0,0 +1,238 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sarif writes policy violations as SARIF 2.1.0 logs, e.g. for GitHub code scanning.
+package sarif
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+)
+
+const (
+	version = "2.1.0"
+	schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolURI = "https://github.com/grafeas/kritis"
+
+	// fingerprintKey identifies the fingerprint of results across runs.
+	fingerprintKey = "kritisViolation/v1"
+)
+
+// descriptions are the short descriptions of the rules of each violation type.
+var descriptions = map[policy.ViolationType]string{
+	policy.UnqualifiedImageViolation:     "Image is not fully qualified with a digest",
+	policy.FixUnavailableViolation:       "Vulnerability has no fix available",
+	policy.SeverityViolation:             "Vulnerability exceeds the maximum severity",
+	policy.BuildProjectIDViolation:       "Image was not built by an allowed project",
+	policy.RequiredAttestationViolation:  "Image lacks a required attestation",
+	policy.ArkCISignatureViolation:       "Image lacks a valid ArkCI signature",
+	policy.ArkCIClaimViolation:           "Image lacks a required ArkCI claim",
+	policy.TagNotPinnedViolation:         "Image is referenced by a mutable tag",
+	policy.DockerHubViolation:            "Docker Hub image is not allowed",
+	policy.MissingSBOMViolation:          "Image has no SBOM",
+	policy.LicenseViolation:              "Image contains a package with a disallowed license",
+	policy.DisallowedBaseImageViolation:  "Image is built from a disallowed base image",
+	policy.ProvenanceViolation:           "Image provenance does not satisfy the policy",
+	policy.DisallowedRepositoryViolation: "Image is hosted in a disallowed repository",
+	policy.UnattestedImageViolation:      "Image is not attested by an AttestationAuthority",
+	policy.RegoViolation:                 "Image violates a Rego rule",
+	policy.CustomRuleViolation:           "Image violates a custom rule",
+	policy.SeverityCountViolation:        "Image has too many vulnerabilities of a severity",
+	policy.NotScannedViolation:           "Image has not been scanned",
+}
+
+// Log is a SARIF log with a single run.
+type Log struct {
+	Version string `json:"version"`
+	Schema  string `json:"$schema"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run is the run of a SARIF log.
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+// Tool describes Kritis and the rules of the results.
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver is the tool component producing the results.
+type Driver struct {
+	Name           string `json:"name"`
+	Version        string `json:"version,omitempty"`
+	InformationURI string `json:"informationUri"`
+	Rules          []Rule `json:"rules"`
+}
+
+// Rule describes a violation type.
+type Rule struct {
+	ID               string                 `json:"id"`
+	Name             string                 `json:"name"`
+	ShortDescription Message                `json:"shortDescription"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+// Message is a SARIF message.
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Result is a violation.
+type Result struct {
+	RuleID              string                 `json:"ruleId"`
+	RuleIndex           int                    `json:"ruleIndex"`
+	Level               string                 `json:"level"`
+	Message             Message                `json:"message"`
+	Locations           []Location             `json:"locations,omitempty"`
+	PartialFingerprints map[string]string      `json:"partialFingerprints"`
+	Properties          map[string]interface{} `json:"properties"`
+}
+
+// Location is the location of a result.
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+// PhysicalLocation locates a result in an artifact.
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+}
+
+// ArtifactLocation is the URI of an artifact.
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// Report accumulates violations into a SARIF log.
+type Report struct {
+	// ArtifactURI locates the results, e.g. the policy file or the Dockerfile of the images.
+	// Code scanning requires results to have a location, results have none if empty.
+	ArtifactURI string
+
+	log   Log
+	rules map[policy.ViolationType]int
+}
+
+// NewReport returns an empty Report of Kritis at toolVersion.
+func NewReport(toolVersion string) *Report {
+	return &Report{
+		log: Log{
+			Version: version,
+			Schema:  schema,
+			Runs: []Run{{
+				Tool: Tool{Driver: Driver{
+					Name:           "kritis",
+					Version:        toolVersion,
+					InformationURI: toolURI,
+					Rules:          []Rule{},
+				}},
+				Results: []Result{},
+			}},
+		},
+		rules: map[policy.ViolationType]int{},
+	}
+}
+
+// Add adds the violations of a policy found for an image. Vulnerabilities of
+// the violations are recorded as properties of their results.
+func (r *Report) Add(image, policyName string, violations []policy.Violation) {
+	run := &r.log.Runs[0]
+	for _, v := range violations {
+		res := Result{
+			RuleID:    v.Type().ToString(),
+			RuleIndex: r.rule(v.Type()),
+			Level:     "error",
+			Message:   Message{Text: string(v.Reason())},
+			Properties: map[string]interface{}{
+				"image":  image,
+				"policy": policyName,
+			},
+		}
+		key := image + "\x00" + policyName + "\x00" + res.RuleID
+		if vuln, ok := v.Details().(metadata.Vulnerability); ok && vuln.CVE != "" {
+			addVulnerability(res.Properties, vuln)
+			key += "\x00" + vuln.CVE + "\x00" + vuln.Package
+		} else {
+			key += "\x00" + res.Message.Text
+		}
+		sum := sha256.Sum256([]byte(key))
+		res.PartialFingerprints = map[string]string{fingerprintKey: hex.EncodeToString(sum[:])}
+		if r.ArtifactURI != "" {
+			res.Locations = []Location{{PhysicalLocation: PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: r.ArtifactURI}}}}
+		}
+		run.Results = append(run.Results, res)
+	}
+}
+
+// rule returns the index of the rule of t, adding it if needed.
+func (r *Report) rule(t policy.ViolationType) int {
+	if i, ok := r.rules[t]; ok {
+		return i
+	}
+	driver := &r.log.Runs[0].Tool.Driver
+	description, ok := descriptions[t]
+	if !ok {
+		description = t.ToString()
+	}
+	driver.Rules = append(driver.Rules, Rule{
+		ID:               t.ToString(),
+		Name:             t.ToString(),
+		ShortDescription: Message{Text: description},
+		Properties:       map[string]interface{}{"tags": []string{"security", "container"}},
+	})
+	r.rules[t] = len(driver.Rules) - 1
+	return r.rules[t]
+}
+
+func addVulnerability(props map[string]interface{}, vuln metadata.Vulnerability) {
+	props["cve"] = vuln.CVE
+	props["severity"] = vuln.Severity
+	props["fixAvailable"] = vuln.HasFixAvailable
+	optional := map[string]string{
+		"package":      vuln.Package,
+		"version":      vuln.Version,
+		"fixedVersion": vuln.FixedVersion,
+		"cvssVector":   vuln.CVSSVector,
+	}
+	for k, v := range optional {
+		if v != "" {
+			props[k] = v
+		}
+	}
+	if vuln.CVSSScore > 0 {
+		props["cvssScore"] = vuln.CVSSScore
+	}
+}
+
+// Log returns the SARIF log of the violations added.
+func (r *Report) Log() Log {
+	return r.log
+}
+
+// Write writes the SARIF log of the violations added.
+func (r *Report) Write(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(r.log)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/crd/securitypolicy"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func TestReport(t *testing.T) {
+	vuln := metadata.Vulnerability{CVE: "CVE-2019-1", Severity: "CRITICAL", HasFixAvailable: true, Package: "openssl", CVSSScore: 9.8}
+	r := NewReport("v0.1.0")
+	r.ArtifactURI = "deploy/isp.yaml"
+	r.Add("gcr.io/p/a@sha256:1", "prod/isp", []policy.Violation{
+		securitypolicy.NewViolation(&vuln, policy.SeverityViolation, "CVE-2019-1 is too severe"),
+		securitypolicy.NewViolation(nil, policy.UnqualifiedImageViolation, "not qualified"),
+	})
+	r.Add("gcr.io/p/b@sha256:2", "prod/isp", []policy.Violation{
+		securitypolicy.NewViolation(&vuln, policy.SeverityViolation, "CVE-2019-1 is too severe"),
+	})
+	log := r.Log()
+
+	testutil.DeepEqual(t, "2.1.0", log.Version)
+	driver := log.Runs[0].Tool.Driver
+	testutil.DeepEqual(t, "v0.1.0", driver.Version)
+	var rules []string
+	for _, rule := range driver.Rules {
+		rules = append(rules, rule.ID)
+	}
+	testutil.DeepEqual(t, []string{"SeverityViolation", "UnqualifiedImageViolation"}, rules)
+
+	results := log.Runs[0].Results
+	var summary [][]interface{}
+	for _, res := range results {
+		summary = append(summary, []interface{}{res.RuleID, res.RuleIndex, res.Properties["image"], res.Properties["cve"]})
+	}
+	testutil.DeepEqual(t, [][]interface{}{
+		{"SeverityViolation", 0, "gcr.io/p/a@sha256:1", "CVE-2019-1"},
+		{"UnqualifiedImageViolation", 1, "gcr.io/p/a@sha256:1", nil},
+		{"SeverityViolation", 0, "gcr.io/p/b@sha256:2", "CVE-2019-1"},
+	}, summary)
+	testutil.DeepEqual(t, map[string]interface{}{
+		"image":        "gcr.io/p/a@sha256:1",
+		"policy":       "prod/isp",
+		"cve":          "CVE-2019-1",
+		"severity":     "CRITICAL",
+		"fixAvailable": true,
+		"package":      "openssl",
+		"cvssScore":    9.8,
+	}, results[0].Properties)
+	testutil.DeepEqual(t, "deploy/isp.yaml", results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	if results[0].PartialFingerprints[fingerprintKey] == results[2].PartialFingerprints[fingerprintKey] {
+		t.Errorf("expected results of different images to have different fingerprints")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	r := NewReport("")
+	var b bytes.Buffer
+	if err := r.Write(&b); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	runs := decoded["runs"].([]interface{})
+	testutil.DeepEqual(t, []interface{}{}, runs[0].(map[string]interface{})["results"])
+	testutil.DeepEqual(t, schema, decoded["$schema"])
+}
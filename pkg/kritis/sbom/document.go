@@ -0,0 +1,188 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+)
+
+// Package is a package listed in an SBOM.
+type Package struct {
+	Name    string
+	Version string
+	// License is the SPDX license expression of the package, empty if unknown.
+	License string
+}
+
+// Document holds the packages listed in an SBOM.
+type Document struct {
+	Format   string
+	Packages []Package
+}
+
+// spdxDocument is the part of an SPDX JSON document read by Kritis.
+type spdxDocument struct {
+	Packages []struct {
+		Name             string `json:"name"`
+		VersionInfo      string `json:"versionInfo"`
+		LicenseConcluded string `json:"licenseConcluded"`
+		LicenseDeclared  string `json:"licenseDeclared"`
+	} `json:"packages"`
+}
+
+// cycloneDXComponent is the part of a CycloneDX JSON component read by Kritis.
+type cycloneDXComponent struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Licenses []struct {
+		License struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"license"`
+		Expression string `json:"expression"`
+	} `json:"licenses"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+// Documents returns the SBOMs of the image, read from attestation occurrences and
+// from the SBOM attached to the image in its registry. Only JSON documents are read,
+// SBOMs in other encodings are skipped.
+func Documents(image string, occs []*metadata.OccurenceV1) ([]Document, error) {
+	var docs []Document
+	for _, occ := range occs {
+		if occ == nil || occ.DsseAttestation == nil {
+			continue
+		}
+		s := envelopeStatement(occ)
+		if s == nil {
+			continue
+		}
+		format := predicateFormat(s.PredicateType)
+		if format == "" {
+			continue
+		}
+		doc, err := parse(format, s.Predicate)
+		if err != nil {
+			glog.Warningf("skipping SBOM attestation %s: %v", occ.Name, err)
+			continue
+		}
+		docs = append(docs, *doc)
+	}
+
+	img, m, err := attached(image)
+	if err != nil || img == nil {
+		return docs, err
+	}
+	for _, l := range m.Layers {
+		format := mediaTypeFormat(l.MediaType)
+		if format == "" {
+			continue
+		}
+		layer, err := img.LayerByDigest(l.Digest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get SBOM layer of %s", image)
+		}
+		// Artifact layers are stored as is, Compressed returns the raw blob.
+		rc, err := layer.Compressed()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read SBOM layer of %s", image)
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read SBOM layer of %s", image)
+		}
+		doc, err := parse(format, b)
+		if err != nil {
+			glog.Warningf("skipping SBOM %s of %s: %v", l.Digest, image, err)
+			continue
+		}
+		docs = append(docs, *doc)
+	}
+	return docs, nil
+}
+
+// parse reads the packages of a JSON SBOM document.
+func parse(format string, b []byte) (*Document, error) {
+	doc := &Document{Format: format}
+	switch format {
+	case constants.SBOMFormatSPDX:
+		var d spdxDocument
+		if err := json.Unmarshal(b, &d); err != nil {
+			return nil, errors.Wrap(err, "invalid SPDX JSON document")
+		}
+		for _, p := range d.Packages {
+			license := spdxLicense(p.LicenseConcluded)
+			if license == "" {
+				license = spdxLicense(p.LicenseDeclared)
+			}
+			doc.Packages = append(doc.Packages, Package{Name: p.Name, Version: p.VersionInfo, License: license})
+		}
+	case constants.SBOMFormatCycloneDX:
+		var d cycloneDXComponent
+		if err := json.Unmarshal(b, &d); err != nil {
+			return nil, errors.Wrap(err, "invalid CycloneDX JSON document")
+		}
+		doc.Packages = cycloneDXPackages(d.Components, nil)
+	default:
+		return nil, fmt.Errorf("unsupported SBOM format %s", format)
+	}
+	return doc, nil
+}
+
+// spdxLicense returns an empty string for the SPDX values meaning no license is known.
+func spdxLicense(l string) string {
+	if l == "NOASSERTION" || l == "NONE" {
+		return ""
+	}
+	return l
+}
+
+// cycloneDXPackages flattens nested components. Licenses listed separately must all be respected.
+func cycloneDXPackages(components []cycloneDXComponent, pkgs []Package) []Package {
+	for _, c := range components {
+		license := ""
+		for _, l := range c.Licenses {
+			expr := l.Expression
+			if expr == "" {
+				expr = l.License.ID
+			}
+			if expr == "" {
+				expr = l.License.Name
+			}
+			if expr == "" {
+				continue
+			}
+			if license == "" {
+				license = expr
+			} else {
+				license = fmt.Sprintf("(%s) AND (%s)", license, expr)
+			}
+		}
+		pkgs = append(pkgs, Package{Name: c.Name, Version: c.Version, License: license})
+		pkgs = cycloneDXPackages(c.Components, pkgs)
+	}
+	return pkgs
+}
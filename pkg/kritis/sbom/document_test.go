@@ -0,0 +1,124 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	cav1 "google.golang.org/api/containeranalysis/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+const spdxJSON = `{
+  "spdxVersion": "SPDX-2.3",
+  "packages": [
+    {"name": "openssl", "versionInfo": "3.0.2", "licenseConcluded": "Apache-2.0"},
+    {"name": "bash", "versionInfo": "5.1", "licenseConcluded": "NOASSERTION", "licenseDeclared": "GPL-3.0-or-later"},
+    {"name": "busybox", "licenseConcluded": "NOASSERTION", "licenseDeclared": "NONE"}
+  ]
+}`
+
+const cycloneDXJSON = `{
+  "bomFormat": "CycloneDX",
+  "components": [
+    {"name": "left-pad", "version": "1.3.0", "licenses": [{"license": {"id": "WTFPL"}}]},
+    {"name": "app", "version": "2.0", "licenses": [{"expression": "MIT OR Apache-2.0"}, {"license": {"name": "Custom License"}}],
+     "components": [{"name": "vendored", "version": "0.1"}]}
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		name      string
+		format    string
+		content   string
+		shouldErr bool
+		expected  []Package
+	}{
+		{
+			name:    "spdx",
+			format:  constants.SBOMFormatSPDX,
+			content: spdxJSON,
+			expected: []Package{
+				{Name: "openssl", Version: "3.0.2", License: "Apache-2.0"},
+				{Name: "bash", Version: "5.1", License: "GPL-3.0-or-later"},
+				{Name: "busybox"},
+			},
+		},
+		{
+			name:    "cyclonedx",
+			format:  constants.SBOMFormatCycloneDX,
+			content: cycloneDXJSON,
+			expected: []Package{
+				{Name: "left-pad", Version: "1.3.0", License: "WTFPL"},
+				{Name: "app", Version: "2.0", License: "(MIT OR Apache-2.0) AND (Custom License)"},
+				{Name: "vendored", Version: "0.1"},
+			},
+		},
+		{
+			name:      "xml",
+			format:    constants.SBOMFormatCycloneDX,
+			content:   `<bom xmlns="http://cyclonedx.org/schema/bom/1.4"></bom>`,
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := parse(test.format, []byte(test.content))
+			if test.shouldErr {
+				testutil.CheckError(t, true, err)
+				return
+			}
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, doc.Packages)
+		})
+	}
+}
+
+func TestDocuments(t *testing.T) {
+	original := attachedImage
+	defer func() { attachedImage = original }()
+	attachedImage = func(ref name.Reference) (v1.Image, error) {
+		return fakeImage{
+			layers: []string{"application/vnd.cyclonedx+xml", "application/vnd.cyclonedx+json"},
+			blobs: map[string]string{
+				"0000000000000000000000000000000000000000000000000000000000000000": "<bom/>",
+				"0000000000000000000000000000000000000000000000000000000000000001": cycloneDXJSON,
+			},
+		}, nil
+	}
+	payload := base64.StdEncoding.EncodeToString([]byte(`{"predicateType": "https://spdx.dev/Document", "predicate": ` + spdxJSON + `}`))
+	occs := []*metadata.OccurenceV1{
+		{DsseAttestation: &cav1.DSSEAttestationOccurrence{Envelope: &cav1.Envelope{Payload: payload}}},
+		{DsseAttestation: &cav1.DSSEAttestationOccurrence{
+			Statement: &cav1.InTotoStatement{PredicateType: "https://spdx.dev/Document"},
+		}},
+	}
+	docs, err := Documents(testutil.QualifiedImage, occs)
+	testutil.CheckError(t, false, err)
+	var formats []string
+	for _, d := range docs {
+		formats = append(formats, d.Format)
+	}
+	testutil.DeepEqual(t, []string{constants.SBOMFormatSPDX, constants.SBOMFormatCycloneDX}, formats)
+	testutil.DeepEqual(t, 3, len(docs[1].Packages))
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Satisfies evaluates an SPDX license expression, e.g. "MIT OR (GPL-2.0-only AND BSD-3-Clause)",
+// returning true if permitted returns true for one license of each OR and all licenses of each AND.
+// A license with an exception, e.g. "GPL-2.0-only WITH Classpath-exception-2.0", is passed as a whole.
+func Satisfies(expression string, permitted func(license string) bool) (bool, error) {
+	p := &licenseParser{tokens: tokenize(expression), permitted: permitted}
+	ok, err := p.or()
+	if err != nil {
+		return false, err
+	}
+	if p.pos != len(p.tokens) {
+		return false, fmt.Errorf("invalid license expression %q: unexpected %q", expression, p.tokens[p.pos])
+	}
+	return ok, nil
+}
+
+// tokenize splits an expression into parentheses, AND and OR operators and licenses.
+// Consecutive words are kept together, so license names with spaces are read as one license.
+func tokenize(expression string) []string {
+	words := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression))
+	var tokens []string
+	license := []string{}
+	flush := func() {
+		if len(license) > 0 {
+			tokens = append(tokens, strings.Join(license, " "))
+			license = []string{}
+		}
+	}
+	for _, w := range words {
+		switch strings.ToUpper(w) {
+		case "(", ")", "AND", "OR":
+			flush()
+			tokens = append(tokens, strings.ToUpper(w))
+		default:
+			license = append(license, w)
+		}
+	}
+	flush()
+	return tokens
+}
+
+type licenseParser struct {
+	tokens    []string
+	pos       int
+	permitted func(string) bool
+}
+
+func (p *licenseParser) next() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// or parses: and {OR and}
+func (p *licenseParser) or() (bool, error) {
+	ok, err := p.and()
+	if err != nil {
+		return false, err
+	}
+	for p.next() == "OR" {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return false, err
+		}
+		ok = ok || right
+	}
+	return ok, nil
+}
+
+// and parses: license {AND license}
+func (p *licenseParser) and() (bool, error) {
+	ok, err := p.license()
+	if err != nil {
+		return false, err
+	}
+	for p.next() == "AND" {
+		p.pos++
+		right, err := p.license()
+		if err != nil {
+			return false, err
+		}
+		ok = ok && right
+	}
+	return ok, nil
+}
+
+// license parses: "(" or ")" | license
+func (p *licenseParser) license() (bool, error) {
+	t := p.next()
+	switch t {
+	case "":
+		return false, fmt.Errorf("invalid license expression: missing license")
+	case "(":
+		p.pos++
+		ok, err := p.or()
+		if err != nil {
+			return false, err
+		}
+		if p.next() != ")" {
+			return false, fmt.Errorf("invalid license expression: missing )")
+		}
+		p.pos++
+		return ok, nil
+	case ")", "AND", "OR":
+		return false, fmt.Errorf("invalid license expression: unexpected %q", t)
+	}
+	p.pos++
+	return p.permitted(t), nil
+}
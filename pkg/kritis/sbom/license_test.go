@@ -0,0 +1,60 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func TestSatisfies(t *testing.T) {
+	denied := map[string]bool{
+		"GPL-3.0-only": true,
+		"AGPL-3.0":     true,
+	}
+	permitted := func(l string) bool { return !denied[l] }
+	var tests = []struct {
+		expression string
+		shouldErr  bool
+		expected   bool
+	}{
+		{"MIT", false, true},
+		{"GPL-3.0-only", false, false},
+		{"MIT OR GPL-3.0-only", false, true},
+		{"MIT AND GPL-3.0-only", false, false},
+		{"MIT and (GPL-3.0-only or Apache-2.0)", false, true},
+		{"(MIT OR BSD-3-Clause) AND (AGPL-3.0 OR GPL-3.0-only)", false, false},
+		{"GPL-3.0-only WITH GCC-exception-3.1", false, true},
+		{"Apache License 2.0", false, true},
+		{"MIT AND", true, false},
+		{"(MIT OR Apache-2.0", true, false},
+		{"MIT) OR Apache-2.0", true, false},
+		{"", true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			ok, err := Satisfies(test.expression, permitted)
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, ok)
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	expected := []string{"(", "Apache License 2.0", "OR", "GPL-2.0-only WITH Classpath-exception-2.0", ")", "AND", "MIT"}
+	testutil.DeepEqual(t, expected, tokenize("(Apache License 2.0 or GPL-2.0-only WITH Classpath-exception-2.0) AND MIT"))
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sbom finds the software bill of materials (SBOM) of images, either
+// recorded as in-toto attestations or attached to the image in its registry.
+package sbom
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+	"github.com/grafeas/kritis/pkg/kritis/registryauth"
+)
+
+// predicateTypes maps the in-toto predicate types of SBOM attestations to their format.
+var predicateTypes = map[string]string{
+	"https://spdx.dev/Document": constants.SBOMFormatSPDX,
+	"https://cyclonedx.org/bom": constants.SBOMFormatCycloneDX,
+}
+
+// mediaTypes maps the media types of attached SBOM layers to their format.
+var mediaTypes = map[string]string{
+	"text/spdx":                      constants.SBOMFormatSPDX,
+	"text/spdx+json":                 constants.SBOMFormatSPDX,
+	"text/spdx+xml":                  constants.SBOMFormatSPDX,
+	"application/spdx+json":          constants.SBOMFormatSPDX,
+	"application/vnd.cyclonedx":      constants.SBOMFormatCycloneDX,
+	"application/vnd.cyclonedx+json": constants.SBOMFormatCycloneDX,
+	"application/vnd.cyclonedx+xml":  constants.SBOMFormatCycloneDX,
+}
+
+// For testing
+var attachedImage = func(ref name.Reference) (v1.Image, error) {
+	return remote.Image(ref, remote.WithAuthFromKeychain(registryauth.Keychain()))
+}
+
+// Format returns the canonical name of a SBOM format, matched case insensitively.
+// It returns an empty string for unknown formats.
+func Format(format string) string {
+	for _, f := range []string{constants.SBOMFormatSPDX, constants.SBOMFormatCycloneDX} {
+		if strings.EqualFold(f, format) {
+			return f
+		}
+	}
+	return ""
+}
+
+// Find returns the format of an SBOM of the image in one of the accepted formats,
+// or an empty string if there is none. Occurrences are checked before the registry.
+func Find(image string, occs []*metadata.OccurenceV1, accepted []string) (string, error) {
+	ok := map[string]bool{}
+	for _, a := range accepted {
+		ok[Format(a)] = true
+	}
+	for _, f := range FromOccurrences(occs) {
+		if ok[f] {
+			return f, nil
+		}
+	}
+	formats, err := FromRegistry(image)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range formats {
+		if ok[f] {
+			return f, nil
+		}
+	}
+	return "", nil
+}
+
+// FromOccurrences returns the formats of the SBOM attestations among occs.
+func FromOccurrences(occs []*metadata.OccurenceV1) []string {
+	var formats []string
+	for _, occ := range occs {
+		if f := predicateFormat(predicateType(occ)); f != "" {
+			formats = append(formats, f)
+		}
+	}
+	return formats
+}
+
+// predicateType returns the in-toto predicate type of a DSSE attestation occurrence,
+// reading it from the signed envelope if the statement is not set.
+func predicateType(occ *metadata.OccurenceV1) string {
+	if occ == nil || occ.DsseAttestation == nil {
+		return ""
+	}
+	if s := occ.DsseAttestation.Statement; s != nil && s.PredicateType != "" {
+		return s.PredicateType
+	}
+	if s := envelopeStatement(occ); s != nil {
+		return s.PredicateType
+	}
+	return ""
+}
+
+// statement is the part of an in-toto statement read by Kritis.
+type statement struct {
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+// envelopeStatement decodes the in-toto statement signed in the envelope of a
+// DSSE attestation occurrence, or returns nil if there is none.
+func envelopeStatement(occ *metadata.OccurenceV1) *statement {
+	env := occ.DsseAttestation.Envelope
+	if env == nil {
+		env = occ.Envelope
+	}
+	if env == nil {
+		return nil
+	}
+	b, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil
+	}
+	s := &statement{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil
+	}
+	return s
+}
+
+// predicateFormat matches predicate types by prefix, as they may carry a version.
+func predicateFormat(pt string) string {
+	for prefix, f := range predicateTypes {
+		if strings.HasPrefix(pt, prefix) {
+			return f
+		}
+	}
+	return ""
+}
+
+// FromRegistry returns the formats of the SBOM attached to the image, following
+// the cosign convention of a "sha256-<digest>.sbom" tag in the image repository.
+func FromRegistry(image string) ([]string, error) {
+	_, m, err := attached(image)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	var formats []string
+	for _, l := range m.Layers {
+		if f := mediaTypeFormat(l.MediaType); f != "" {
+			formats = append(formats, f)
+		}
+	}
+	return formats, nil
+}
+
+// attached returns the SBOM image attached to the image and its manifest,
+// or nil if the image has none.
+func attached(image string) (v1.Image, *v1.Manifest, error) {
+	digest, err := reference.ParseDigest(image)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "%q is not referenced by digest", image)
+	}
+	tag, err := name.NewTag(fmt.Sprintf("%s:%s.sbom", digest.Context().Name(), strings.Replace(digest.DigestStr(), ":", "-", 1)), name.WeakValidation)
+	if err != nil {
+		return nil, nil, err
+	}
+	img, err := attachedImage(tag)
+	if err != nil {
+		if notFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, errors.Wrapf(err, "failed to get SBOM of %s", image)
+	}
+	m, err := img.Manifest()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to get SBOM manifest of %s", image)
+	}
+	return img, m, nil
+}
+
+// mediaTypeFormat returns the format of a layer media type, ignoring its parameters.
+func mediaTypeFormat(mt types.MediaType) string {
+	return mediaTypes[strings.TrimSpace(strings.SplitN(string(mt), ";", 2)[0])]
+}
+
+func notFound(err error) bool {
+	terr, ok := err.(*transport.Error)
+	if !ok {
+		return false
+	}
+	for _, d := range terr.Errors {
+		if d.Code == transport.ManifestUnknownErrorCode || d.Code == transport.NameUnknownErrorCode {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	cav1 "google.golang.org/api/containeranalysis/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+type fakeImage struct {
+	v1.Image
+	layers []string
+	blobs  map[string]string
+}
+
+func (f fakeImage) Manifest() (*v1.Manifest, error) {
+	m := &v1.Manifest{}
+	for i, mt := range f.layers {
+		m.Layers = append(m.Layers, v1.Descriptor{
+			MediaType: types.MediaType(mt),
+			Digest:    v1.Hash{Algorithm: "sha256", Hex: fmt.Sprintf("%064d", i)},
+		})
+	}
+	return m, nil
+}
+
+func (f fakeImage) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	return fakeLayer{content: f.blobs[h.Hex]}, nil
+}
+
+type fakeLayer struct {
+	v1.Layer
+	content string
+}
+
+func (f fakeLayer) Compressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(f.content)), nil
+}
+
+func TestFormat(t *testing.T) {
+	testutil.DeepEqual(t, constants.SBOMFormatSPDX, Format("spdx"))
+	testutil.DeepEqual(t, constants.SBOMFormatCycloneDX, Format("CycloneDX"))
+	testutil.DeepEqual(t, "", Format("SWID"))
+}
+
+func TestFromOccurrences(t *testing.T) {
+	payload := base64.StdEncoding.EncodeToString([]byte(`{"predicateType": "https://cyclonedx.org/bom/v1.4"}`))
+	occs := []*metadata.OccurenceV1{
+		{DsseAttestation: &cav1.DSSEAttestationOccurrence{
+			Statement: &cav1.InTotoStatement{PredicateType: "https://spdx.dev/Document"},
+		}},
+		{DsseAttestation: &cav1.DSSEAttestationOccurrence{
+			Envelope: &cav1.Envelope{Payload: payload},
+		}},
+		{DsseAttestation: &cav1.DSSEAttestationOccurrence{
+			Statement: &cav1.InTotoStatement{PredicateType: "https://slsa.dev/provenance/v0.2"},
+		}},
+		{Kind: "VULNERABILITY"},
+	}
+	expected := []string{constants.SBOMFormatSPDX, constants.SBOMFormatCycloneDX}
+	testutil.DeepEqual(t, expected, FromOccurrences(occs))
+}
+
+func TestFromRegistry(t *testing.T) {
+	original := attachedImage
+	defer func() { attachedImage = original }()
+
+	var requested string
+	attachedImage = func(ref name.Reference) (v1.Image, error) {
+		requested = ref.Name()
+		return fakeImage{layers: []string{"text/spdx+json", "application/octet-stream"}}, nil
+	}
+	formats, err := FromRegistry(testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{constants.SBOMFormatSPDX}, formats)
+	testutil.DeepEqual(t, "gcr.io/image/digest:sha256-0000000000000000000000000000000000000000000000000000000000000000.sbom", requested)
+
+	attachedImage = func(ref name.Reference) (v1.Image, error) {
+		return nil, &transport.Error{Errors: []transport.Diagnostic{{Code: transport.ManifestUnknownErrorCode}}}
+	}
+	formats, err = FromRegistry(testutil.QualifiedImage)
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string(nil), formats)
+
+	attachedImage = func(ref name.Reference) (v1.Image, error) {
+		return nil, &transport.Error{Errors: []transport.Diagnostic{{Code: transport.UnauthorizedErrorCode}}}
+	}
+	_, err = FromRegistry(testutil.QualifiedImage)
+	testutil.CheckError(t, true, err)
+
+	_, err = FromRegistry("gcr.io/foo/bar:latest")
+	testutil.CheckError(t, true, err)
+}
+
+func TestFind(t *testing.T) {
+	original := attachedImage
+	defer func() { attachedImage = original }()
+	attachedImage = func(ref name.Reference) (v1.Image, error) {
+		return fakeImage{layers: []string{"application/vnd.cyclonedx+json"}}, nil
+	}
+	occs := []*metadata.OccurenceV1{
+		{DsseAttestation: &cav1.DSSEAttestationOccurrence{
+			Statement: &cav1.InTotoStatement{PredicateType: "https://spdx.dev/Document"},
+		}},
+	}
+	var tests = []struct {
+		name     string
+		accepted []string
+		expected string
+	}{
+		{"attestation", []string{"SPDX"}, constants.SBOMFormatSPDX},
+		{"attached", []string{"cyclonedx"}, constants.SBOMFormatCycloneDX},
+		{"attestation first", []string{constants.SBOMFormatCycloneDX, constants.SBOMFormatSPDX}, constants.SBOMFormatSPDX},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, err := Find(testutil.QualifiedImage, occs, test.accepted)
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, format)
+		})
+	}
+
+	attachedImage = func(ref name.Reference) (v1.Image, error) {
+		return fakeImage{}, nil
+	}
+	format, err := Find(testutil.QualifiedImage, nil, []string{constants.SBOMFormatSPDX})
+	testutil.CheckErrorAndDeepEqual(t, false, err, "", format)
+}
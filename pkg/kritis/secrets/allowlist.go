@@ -0,0 +1,50 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+// validateAllowlist returns an error if an entry of the allowlist of field has no namespace.
+func validateAllowlist(field string, allowlist []v1beta1.KeyAllowlistSpec) error {
+	for _, a := range allowlist {
+		if a.Namespace == "" {
+			return fmt.Errorf("%s require a namespace", field)
+		}
+	}
+	return nil
+}
+
+// keyAllowed returns true if the allowlist lets the AttestationAuthorities of namespace
+// use the key. Namespaces which are not listed can't use any key.
+func keyAllowed(allowlist []v1beta1.KeyAllowlistSpec, namespace string, key string) bool {
+	for _, a := range allowlist {
+		if a.Namespace != namespace {
+			continue
+		}
+		for _, k := range a.Keys {
+			if k == key || (strings.HasSuffix(k, "*") && strings.HasPrefix(key, strings.TrimSuffix(k, "*"))) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+func TestKeyAllowed(t *testing.T) {
+	allowlist := []v1beta1.KeyAllowlistSpec{
+		{Namespace: "qa", Keys: []string{"transit/qa", "secret/qa/*"}},
+	}
+	tests := []struct {
+		namespace string
+		key       string
+		expected  bool
+	}{
+		{"qa", "transit/qa", true},
+		{"qa", "transit/qa-prod", false},
+		{"qa", "secret/qa/signer", true},
+		{"qa", "secret/prod/signer", false},
+		{"prod", "transit/qa", false},
+	}
+	for _, test := range tests {
+		if actual := keyAllowed(allowlist, test.namespace, test.key); actual != test.expected {
+			t.Errorf("keyAllowed(%s, %s): expected %t, got %t", test.namespace, test.key, test.expected, actual)
+		}
+	}
+}
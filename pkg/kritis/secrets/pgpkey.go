@@ -17,8 +17,13 @@ limitations under the License.
 package secrets
 
 import (
+	"bytes"
+	"crypto"
 	"fmt"
+	"io"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/openpgp"
@@ -29,6 +34,7 @@ import (
 // PgpKey struct converts the base64 encoded PEM keys into openpgp private and
 // public keys
 type PgpKey struct {
+	mu         sync.Mutex // mu guards the decryption of privateKey
 	privateKey *packet.PrivateKey
 	publicKey  *packet.PublicKey
 }
@@ -56,6 +62,16 @@ func NewPgpKey(privateKeyStr string, passphrase string, publicKeyStr string) (*P
 	}, nil
 }
 
+// NewPgpKeyFromSigner returns a PgpKey signing with signer, whose private key may
+// be held outside of kritis. creationTime is part of the fingerprint of the key.
+func NewPgpKeyFromSigner(creationTime time.Time, signer crypto.Signer) *PgpKey {
+	privateKey := packet.NewSignerPrivateKey(creationTime, signer)
+	return &PgpKey{
+		privateKey: privateKey,
+		publicKey:  &privateKey.PublicKey,
+	}
+}
+
 func (key *PgpKey) PublicKey() *packet.PublicKey {
 	return key.publicKey
 }
@@ -64,10 +80,72 @@ func (key *PgpKey) PrivateKey() *packet.PrivateKey {
 	return key.privateKey
 }
 
+// Encrypted returns true if the private key is still encrypted with a passphrase.
+func (key *PgpKey) Encrypted() bool {
+	key.mu.Lock()
+	defer key.mu.Unlock()
+	return key.privateKey != nil && key.privateKey.Encrypted
+}
+
+// Decrypt decrypts the private key with passphrase. It is a no-op if the key is not encrypted.
+func (key *PgpKey) Decrypt(passphrase string) error {
+	key.mu.Lock()
+	defer key.mu.Unlock()
+	if key.privateKey == nil {
+		return nil
+	}
+	return decrypt(key.privateKey, passphrase)
+}
+
 func (key *PgpKey) Fingerprint() string {
 	return fmt.Sprintf("%X", key.publicKey.Fingerprint)
 }
 
+// ArmoredPublicKey returns the armored public key, self-signed with the private key, as
+// expected in the publicKeyData of AttestationAuthorities once base64 encoded.
+func (key *PgpKey) ArmoredPublicKey() (string, error) {
+	if key.publicKey == nil || key.privateKey == nil {
+		return "", fmt.Errorf("a key pair is required to self-sign the public key")
+	}
+	uid := packet.NewUserId("kritis", "", "")
+	isPrimaryID := true
+	e := &openpgp.Entity{
+		PrimaryKey: key.publicKey,
+		Identities: map[string]*openpgp.Identity{
+			uid.Id: {
+				Name:   uid.Id,
+				UserId: uid,
+				SelfSignature: &packet.Signature{
+					CreationTime: time.Now(),
+					SigType:      packet.SigTypePositiveCert,
+					PubKeyAlgo:   key.publicKey.PubKeyAlgo,
+					Hash:         crypto.SHA256,
+					IsPrimaryId:  &isPrimaryID,
+					FlagsValid:   true,
+					FlagSign:     true,
+					FlagCertify:  true,
+					IssuerKeyId:  &key.publicKey.KeyId,
+				},
+			},
+		},
+	}
+	if err := e.Identities[uid.Id].SelfSignature.SignUserId(uid.Id, key.publicKey, key.privateKey, nil); err != nil {
+		return "", errors.Wrap(err, "failed to self-sign public key")
+	}
+	b := new(bytes.Buffer)
+	w, err := armor.Encode(b, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := e.Serialize(w); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
 func parsePublicKey(publicKey string) (*packet.PublicKey, error) {
 	pkt, err := parseKey(publicKey, openpgp.PublicKeyType)
 	if err != nil {
@@ -90,16 +168,24 @@ func parsePrivateKey(privateKey string, passphrase string) (*packet.PrivateKey,
 		return nil, fmt.Errorf("not a private key")
 	}
 	if passphrase != "" {
-		// Decrypt the private key with the passphrase
-		pb := []byte(passphrase)
-		err := key.Decrypt(pb)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to decrypt private key with passphrase")
+		if err := decrypt(key, passphrase); err != nil {
+			return nil, err
 		}
 	}
 	return key, nil
 }
 
+// decrypt decrypts key with passphrase, if it is encrypted.
+func decrypt(key *packet.PrivateKey, passphrase string) error {
+	if !key.Encrypted {
+		return nil
+	}
+	if err := key.Decrypt([]byte(passphrase)); err != nil {
+		return errors.Wrapf(err, "failed to decrypt private key %X with passphrase, check that it is the passphrase of the key, without a trailing newline", key.Fingerprint)
+	}
+	return nil
+}
+
 func parseKey(key string, keytype string) (packet.Packet, error) {
 	r := strings.NewReader(key)
 	block, err := armor.Decode(r)
@@ -112,3 +198,33 @@ func parseKey(key string, keytype string) (packet.Packet, error) {
 	reader := packet.NewReader(block.Body)
 	return reader.Next()
 }
+
+// GeneratePgpKey generates an unencrypted PGP key pair for name, and returns the armored
+// private and public keys, as the "private" and "public" fields of signing Secrets.
+func GeneratePgpKey(name string, comment string) (private string, public string, err error) {
+	e, err := openpgp.NewEntity(name, comment, "", nil)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to generate PGP key")
+	}
+	var priv, pub bytes.Buffer
+	for _, k := range []struct {
+		b         *bytes.Buffer
+		blockType string
+		serialize func(w io.Writer) error
+	}{
+		{&priv, openpgp.PrivateKeyType, func(w io.Writer) error { return e.SerializePrivate(w, nil) }},
+		{&pub, openpgp.PublicKeyType, e.Serialize},
+	} {
+		w, err := armor.Encode(k.b, k.blockType, nil)
+		if err != nil {
+			return "", "", err
+		}
+		if err := k.serialize(w); err != nil {
+			return "", "", err
+		}
+		if err := w.Close(); err != nil {
+			return "", "", err
+		}
+	}
+	return priv.String(), pub.String(), nil
+}
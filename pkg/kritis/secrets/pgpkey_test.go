@@ -17,6 +17,7 @@ limitations under the License.
 package secrets
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -188,3 +189,43 @@ func TestPgpKey(t *testing.T) {
 		})
 	}
 }
+
+func TestPgpKeyDecrypt(t *testing.T) {
+	if _, err := NewPgpKey(privPassphrase, "wrong passphrase", public); err == nil {
+		t.Errorf("expected error decrypting with the wrong passphrase")
+	}
+	key, err := NewPgpKey(privPassphrase, "", public)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !key.Encrypted() {
+		t.Fatalf("expected the private key to be encrypted")
+	}
+	if err := key.Decrypt("wrong passphrase"); err == nil {
+		t.Errorf("expected error decrypting with the wrong passphrase")
+	}
+	if err := key.Decrypt("test passphrase"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if key.Encrypted() {
+		t.Errorf("expected the private key to be decrypted")
+	}
+	// Decrypting a decrypted key is a no-op.
+	if err := key.Decrypt("wrong passphrase"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestGeneratePgpKey(t *testing.T) {
+	priv, pub, err := GeneratePgpKey("qa-attestator", "kritis")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	key, err := NewPgpKey(priv, "", pub)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if key.Encrypted() || key.Fingerprint() != fmt.Sprintf("%X", key.PrivateKey().PublicKey.Fingerprint) {
+		t.Errorf("expected an unencrypted key pair, got fingerprints %s and %X", key.Fingerprint(), key.PrivateKey().PublicKey.Fingerprint)
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/option"
+	secretmanager "google.golang.org/api/secretmanager/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+// SecretManagerCacheTTL is how long the keys of unpinned secret versions, such as
+// "latest", are cached. Pinned versions are immutable and cached until restart.
+const SecretManagerCacheTTL = 5 * time.Minute
+
+var (
+	// SecretManagerClientOptions returns the options of the Secret Manager client, set by
+	// gcpauth to use the configured credentials. Application Default Credentials are used otherwise.
+	SecretManagerClientOptions = func(ctx context.Context) ([]option.ClientOption, error) {
+		return nil, nil
+	}
+	defaultSecretManager = &secretManager{cache: map[string]secretManagerEntry{}}
+	// secretManagerKeys lists the secrets the authorities of each namespace may read
+	secretManagerKeys []v1beta1.KeyAllowlistSpec
+
+	// For testing
+	fetchSecretManagerFunc = defaultSecretManager.Fetch
+	secretManagerNow       = time.Now
+)
+
+// FetchSecretManager is a Fetcher reading signing keys from Google Secret Manager. name is the
+// resource name of the secret, projects/p/secrets/s, whose latest version is read, or of a
+// version, projects/p/secrets/s/versions/3. The authorities of namespace must be allowed
+// to read the secret, see ConfigureSecretManager.
+func FetchSecretManager(namespace string, name string) (*PGPSigningSecret, error) {
+	secret := strings.TrimSuffix(name, "/")
+	if i := strings.Index(secret, "/versions/"); i >= 0 {
+		secret = secret[:i]
+	}
+	if !keyAllowed(secretManagerKeys, namespace, secret) {
+		return nil, fmt.Errorf("secret %s is not allowed for namespace %s, see secretManagerKeys in the KritisConfig", secret, namespace)
+	}
+	return fetchSecretManagerFunc(namespace, name)
+}
+
+// ConfigureSecretManager sets the Secret Manager secrets the AttestationAuthorities of each
+// namespace may read their key from. The client is created again, with the credentials
+// configured meanwhile.
+func ConfigureSecretManager(allowlist []v1beta1.KeyAllowlistSpec) error {
+	if err := validateAllowlist("secretManagerKeys", allowlist); err != nil {
+		return err
+	}
+	secretManagerKeys = allowlist
+	defaultSecretManager.reset()
+	return nil
+}
+
+// SecretManagerVersion returns the resource name of the secret version described by spec.
+func SecretManagerVersion(spec v1beta1.SecretManagerKeySpec) (string, error) {
+	parts := strings.Split(spec.Secret, "/")
+	if len(parts) != 4 || parts[0] != "projects" || parts[2] != "secrets" || parts[1] == "" || parts[3] == "" {
+		return "", fmt.Errorf("invalid secret %q, expected projects/<project>/secrets/<secret>", spec.Secret)
+	}
+	return fmt.Sprintf("%s/versions/%s", spec.Secret, orDefault(spec.Version, "latest")), nil
+}
+
+type secretManagerEntry struct {
+	secret  *PGPSigningSecret
+	expires time.Time // zero for pinned versions
+}
+
+// secretManager fetches and caches the signing secrets of Secret Manager versions, with a
+// client created on first use.
+type secretManager struct {
+	mu      sync.Mutex
+	cache   map[string]secretManagerEntry
+	service *secretmanager.Service
+}
+
+// client returns the Secret Manager client, created with SecretManagerClientOptions.
+func (s *secretManager) client(ctx context.Context) (*secretmanager.Service, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.service != nil {
+		return s.service, nil
+	}
+	opts, err := SecretManagerClientOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	service, err := secretmanager.NewService(context.Background(), opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create Secret Manager client")
+	}
+	s.service = service
+	return service, nil
+}
+
+// reset drops the client, so that the next fetch creates it again.
+func (s *secretManager) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.service = nil
+}
+
+func (s *secretManager) Fetch(namespace string, name string) (*PGPSigningSecret, error) {
+	if !strings.Contains(name, "/versions/") {
+		name = strings.TrimSuffix(name, "/") + "/versions/latest"
+	}
+	s.mu.Lock()
+	e, ok := s.cache[name]
+	s.mu.Unlock()
+	if ok && (e.expires.IsZero() || secretManagerNow().Before(e.expires)) {
+		return e.secret, nil
+	}
+	secret, err := s.access(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	e = secretManagerEntry{secret: secret}
+	if !pinned(name) {
+		e.expires = secretManagerNow().Add(SecretManagerCacheTTL)
+	}
+	s.mu.Lock()
+	s.cache[name] = e
+	s.mu.Unlock()
+	return secret, nil
+}
+
+// pinned returns true if the version name ends with a version number, not an alias such as latest.
+func pinned(name string) bool {
+	v := name[strings.LastIndex(name, "/")+1:]
+	if v == "" {
+		return false
+	}
+	for _, c := range v {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *secretManager) access(ctx context.Context, name string) (*PGPSigningSecret, error) {
+	service, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := service.Projects.Secrets.Versions.Access(name).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to access secret version %s", name)
+	}
+	if resp.Payload == nil {
+		return nil, fmt.Errorf("secret version %s has no payload", name)
+	}
+	payload, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid payload of secret version %s", name)
+	}
+	data := map[string]string{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, errors.Wrapf(err, "payload of secret version %s is not a JSON object", name)
+	}
+	for _, k := range []string{PublicKey, PrivateKey} {
+		if _, ok := data[k]; !ok {
+			return nil, fmt.Errorf("invalid secret version %s. could not find key %s", name, k)
+		}
+	}
+	pgpKey, err := NewPgpKey(data[PrivateKey], data[Passphrase], data[PublicKey])
+	if err != nil {
+		return nil, err
+	}
+	return &PGPSigningSecret{
+		PgpKey:     pgpKey,
+		SecretName: name,
+	}, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+	secretmanager "google.golang.org/api/secretmanager/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+func TestFetchSecretManager(t *testing.T) {
+	accesses := map[string]int{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/"), ":access")
+		accesses[name]++
+		payload := map[string]string{PrivateKey: priv, PublicKey: pub}
+		switch name {
+		case "projects/p/secrets/kritis/versions/latest", "projects/p/secrets/kritis/versions/3":
+		case "projects/p/secrets/public-only/versions/latest":
+			delete(payload, PrivateKey)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		b, _ := json.Marshal(payload)
+		json.NewEncoder(w).Encode(secretmanager.AccessSecretVersionResponse{
+			Name:    name,
+			Payload: &secretmanager.SecretPayload{Data: base64.StdEncoding.EncodeToString(b)},
+		})
+	}))
+	defer s.Close()
+	originalOptions, originalNow := SecretManagerClientOptions, secretManagerNow
+	defer func() {
+		SecretManagerClientOptions, secretManagerNow = originalOptions, originalNow
+	}()
+	SecretManagerClientOptions = func(ctx context.Context) ([]option.ClientOption, error) {
+		return []option.ClientOption{option.WithEndpoint(s.URL), option.WithoutAuthentication()}, nil
+	}
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	secretManagerNow = func() time.Time { return now }
+	m := &secretManager{cache: map[string]secretManagerEntry{}}
+
+	for _, name := range []string{"projects/p/secrets/kritis", "projects/p/secrets/kritis/versions/3"} {
+		sec, err := m.Fetch("", name)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if sec.PgpKey.Fingerprint() != pgpKey.Fingerprint() {
+			t.Errorf("expected fingerprint %s, got %s", pgpKey.Fingerprint(), sec.PgpKey.Fingerprint())
+		}
+	}
+	// The client is created once.
+	service := m.service
+	if service == nil {
+		t.Fatal("expected the client to be kept")
+	}
+	// Cached versions are not accessed again, until latest expires.
+	now = now.Add(time.Minute)
+	m.Fetch("", "projects/p/secrets/kritis/versions/latest")
+	m.Fetch("", "projects/p/secrets/kritis/versions/3")
+	now = now.Add(SecretManagerCacheTTL)
+	m.Fetch("", "projects/p/secrets/kritis")
+	m.Fetch("", "projects/p/secrets/kritis/versions/3")
+	expected := map[string]int{
+		"projects/p/secrets/kritis/versions/latest": 2,
+		"projects/p/secrets/kritis/versions/3":      1,
+	}
+	if !reflect.DeepEqual(expected, accesses) {
+		t.Errorf("expected accesses %v, got %v", expected, accesses)
+	}
+	if m.service != service {
+		t.Error("expected the client to be reused")
+	}
+
+	for _, name := range []string{"projects/p/secrets/public-only", "projects/p/secrets/missing"} {
+		if _, err := m.Fetch("", name); err == nil {
+			t.Errorf("expected error fetching %s", name)
+		}
+	}
+}
+
+func TestSecretManagerVersion(t *testing.T) {
+	tests := []struct {
+		spec     v1beta1.SecretManagerKeySpec
+		expected string
+		shdErr   bool
+	}{
+		{v1beta1.SecretManagerKeySpec{Secret: "projects/p/secrets/s"}, "projects/p/secrets/s/versions/latest", false},
+		{v1beta1.SecretManagerKeySpec{Secret: "projects/p/secrets/s", Version: "3"}, "projects/p/secrets/s/versions/3", false},
+		{v1beta1.SecretManagerKeySpec{Secret: "s"}, "", true},
+		{v1beta1.SecretManagerKeySpec{Secret: "projects/p/secrets/s/versions/3"}, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.spec.Secret, func(t *testing.T) {
+			name, err := SecretManagerVersion(test.spec)
+			if (err != nil) != test.shdErr {
+				t.Fatalf("expected error %t, got %v", test.shdErr, err)
+			}
+			if name != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, name)
+			}
+		})
+	}
+}
@@ -34,6 +34,10 @@ const (
 	PublicKey = "public"
 	// Passphrase constant for Attestation Secrets.
 	Passphrase = "passphrase"
+	// Username constant for registry credential Secrets.
+	Username = "username"
+	// Password constant for registry credential Secrets.
+	Password = "password"
 )
 
 var (
@@ -50,6 +54,8 @@ var (
 type PGPSigningSecret struct {
 	PgpKey     *PgpKey
 	SecretName string
+	// KMSKeyName is set instead of PgpKey for authorities signing with Cloud KMS.
+	KMSKeyName string
 }
 
 // Fetcher is the function used to fetch kubernetes secret.
@@ -91,6 +97,44 @@ func Fetch(namespace string, name string) (*PGPSigningSecret, error) {
 	}, nil
 }
 
+// BasicAuth represents registry credentials stored in your kubernetes cluster,
+// in the "username" and "password" keys of a secret e.g.
+// kubectl create secret generic my-robot --from-literal=username='robot$kritis' \
+// --from-literal=password=<token>
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// FetchBasicAuth fetches registry credentials from a kubernetes secret
+func FetchBasicAuth(namespace string, name string) (*BasicAuth, error) {
+	secret, err := getSecretFunc(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	username, ok := secret.Data[Username]
+	if !ok {
+		return nil, fmt.Errorf("invalid secret %s. could not find key %s", name, Username)
+	}
+	password, ok := secret.Data[Password]
+	if !ok {
+		return nil, fmt.Errorf("invalid secret %s. could not find key %s", name, Password)
+	}
+	return &BasicAuth{
+		Username: string(username),
+		Password: string(password),
+	}, nil
+}
+
+// FetchData fetches the data of a kubernetes secret
+func FetchData(namespace string, name string) (map[string][]byte, error) {
+	secret, err := getSecretFunc(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return secret.Data, nil
+}
+
 func getSecret(namespace string, name string) (*v1.Secret, error) {
 	c, err := kubernetesutil.GetClientset()
 	if err != nil {
@@ -5,7 +5,7 @@ Licensed under the Apache License, Version 2.0 (the "License");
 you may not use this file except in compliance with the License.
 You may obtain a copy of the License at
 
-    http://www.apache.org/licenses/LICENSE-2.0
+	http://www.apache.org/licenses/LICENSE-2.0
 
 Unless required by applicable law or agreed to in writing, software
 distributed under the License is distributed on an "AS IS" BASIS,
@@ -61,7 +61,39 @@ func TestSecrets(t *testing.T) {
 	}
 }
 
+func TestFetchBasicAuth(t *testing.T) {
+	getSecretFunc = getTestSecret
+	var tests = []struct {
+		name       string
+		secretName string
+		shdErr     bool
+		expected   *BasicAuth
+	}{
+		{"good", "robot-sec", false, &BasicAuth{Username: "robot$kritis", Password: "token"}},
+		{"no username", "bad1-sec", true, nil},
+		{"notfound", "not-present", true, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := FetchBasicAuth("test", tc.secretName)
+			if (err != nil) != tc.shdErr {
+				t.Fatalf("expected error: %v but found %v", tc.shdErr, err)
+			}
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Fatalf("expected: %v but found %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
 var testSecrets = []v1.Secret{
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "robot-sec"},
+		Data: map[string][]byte{
+			"username": []byte("robot$kritis"),
+			"password": []byte("token"),
+		},
+	},
 	{
 		ObjectMeta: metav1.ObjectMeta{Name: "good-sec"},
 		Data: map[string][]byte{
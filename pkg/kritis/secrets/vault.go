@@ -0,0 +1,419 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+const (
+	// VaultKV reads signing keys from a Vault KV version 2 secret.
+	VaultKV = "kv"
+	// VaultTransit signs images with a key of the Vault transit engine.
+	VaultTransit = "transit"
+)
+
+// DefaultVaultTimeout bounds the requests to Vault if the KritisConfig sets no timeout.
+const DefaultVaultTimeout = 10 * time.Second
+
+var (
+	defaultVault = &Vault{client: &http.Client{Timeout: DefaultVaultTimeout}}
+	// kmsKeys lists the KMS keys the authorities of each namespace may sign with
+	kmsKeys []v1beta1.KeyAllowlistSpec
+
+	// For testing
+	fetchDataFunc  = FetchData
+	vaultTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	fetchVaultFunc = FetchVault
+	vaultNow       = time.Now
+)
+
+// FetchForAuthority fetches the signing secret of the AttestationAuthority, from Vault or
+// Secret Manager if it sets a vaultKey or secretManagerKey and with fetcher otherwise.
+// Authorities signing with a KMS have no secret, only the name of their key, which must be
+// allowed for namespace, see ConfigureKMS.
+// Encrypted private keys are decrypted with the passphraseSecretName Secret, if set.
+func FetchForAuthority(fetcher Fetcher, namespace string, a v1beta1.AttestationAuthority) (*PGPSigningSecret, error) {
+	if a.Spec.KMSKeyName != "" {
+		if !keyAllowed(kmsKeys, namespace, a.Spec.KMSKeyName) {
+			return nil, fmt.Errorf("KMS key %s is not allowed for namespace %s, see kmsKeys in the KritisConfig", a.Spec.KMSKeyName, namespace)
+		}
+		return &PGPSigningSecret{SecretName: a.Spec.KMSKeyName, KMSKeyName: a.Spec.KMSKeyName}, nil
+	}
+	var sec *PGPSigningSecret
+	var err error
+	switch {
+	case a.Spec.VaultKey != nil:
+		sec, err = fetchVaultFunc(namespace, *a.Spec.VaultKey)
+	case a.Spec.SecretManagerKey != nil:
+		var name string
+		if name, err = SecretManagerVersion(*a.Spec.SecretManagerKey); err == nil {
+			sec, err = FetchSecretManager(namespace, name)
+		}
+	default:
+		sec, err = fetcher(namespace, a.Spec.PrivateKeySecretName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := decryptForAuthority(sec, namespace, a); err != nil {
+		return nil, err
+	}
+	return sec, nil
+}
+
+// decryptForAuthority decrypts the private key of sec with the passphrase Secret of the
+// authority, and fails if it is left encrypted, since it could not sign.
+func decryptForAuthority(sec *PGPSigningSecret, namespace string, a v1beta1.AttestationAuthority) error {
+	if sec.PgpKey == nil || !sec.PgpKey.Encrypted() {
+		return nil
+	}
+	if a.Spec.PassphraseSecretName == "" {
+		return fmt.Errorf("the private key of %s is encrypted, set its passphrase in the %q field of the secret or in the Secret named by passphraseSecretName of AttestationAuthority %s", sec.SecretName, Passphrase, a.Name)
+	}
+	data, err := fetchDataFunc(namespace, a.Spec.PassphraseSecretName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get passphrase secret %s/%s of AttestationAuthority %s", namespace, a.Spec.PassphraseSecretName, a.Name)
+	}
+	key := orDefault(a.Spec.PassphraseSecretKey, Passphrase)
+	phrase, ok := data[key]
+	if !ok {
+		return fmt.Errorf("passphrase secret %s/%s of AttestationAuthority %s has no key %s", namespace, a.Spec.PassphraseSecretName, a.Name, key)
+	}
+	if err := sec.PgpKey.Decrypt(string(phrase)); err != nil {
+		return errors.Wrapf(err, "the passphrase in secret %s/%s does not decrypt the private key of %s", namespace, a.Spec.PassphraseSecretName, sec.SecretName)
+	}
+	return nil
+}
+
+// ConfigureVault sets the Vault server AttestationAuthorities read their vaultKey from, and
+// the keys the authorities of each namespace may use.
+func ConfigureVault(spec v1beta1.VaultConfigSpec) error {
+	v, err := NewVault(spec)
+	if err != nil {
+		return err
+	}
+	defaultVault = v
+	return nil
+}
+
+// Configure sets the Vault server and the keys AttestationAuthorities may sign with from
+// the spec of the KritisConfig.
+func Configure(spec v1beta1.KritisConfigSpec) error {
+	if err := ConfigureVault(spec.Vault); err != nil {
+		return err
+	}
+	if err := ConfigureSecretManager(spec.SecretManagerKeys); err != nil {
+		return err
+	}
+	return ConfigureKMS(spec.KMSKeys)
+}
+
+// ConfigureKMS sets the KMS keys the AttestationAuthorities of each namespace may sign with.
+func ConfigureKMS(allowlist []v1beta1.KeyAllowlistSpec) error {
+	if err := validateAllowlist("kmsKeys", allowlist); err != nil {
+		return err
+	}
+	kmsKeys = allowlist
+	return nil
+}
+
+// FetchVault fetches the signing secret described by spec from the Vault server of the
+// KritisConfig, if the authorities of namespace are allowed to use its key.
+func FetchVault(namespace string, spec v1beta1.VaultKeySpec) (*PGPSigningSecret, error) {
+	return defaultVault.Fetch(namespace, spec)
+}
+
+// Vault reads signing keys from a Vault server. It authenticates with the VAULT_TOKEN
+// environment variable if set, and by logging in to the Kubernetes auth method with the
+// service account token otherwise. The token it logs in with is kept until its lease expires.
+type Vault struct {
+	config v1beta1.VaultConfigSpec
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time // zero if the token does not expire
+}
+
+// NewVault returns a Vault reading keys from the server of spec.
+func NewVault(spec v1beta1.VaultConfigSpec) (*Vault, error) {
+	timeout := DefaultVaultTimeout
+	if spec.Timeout != "" {
+		d, err := time.ParseDuration(spec.Timeout)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid vault timeout")
+		}
+		timeout = d
+	}
+	if err := validateAllowlist("vault allowedKeys", spec.AllowedKeys); err != nil {
+		return nil, err
+	}
+	return &Vault{config: spec, client: &http.Client{Timeout: timeout}}, nil
+}
+
+// Fetch fetches the signing secret described by spec, if the authorities of namespace are
+// allowed to use its key.
+func (v *Vault) Fetch(namespace string, spec v1beta1.VaultKeySpec) (*PGPSigningSecret, error) {
+	key := fmt.Sprintf("%s/%s", vaultMount(spec), spec.Path)
+	if !keyAllowed(v.config.AllowedKeys, namespace, key) {
+		return nil, fmt.Errorf("vault key %s is not allowed for namespace %s, see vault.allowedKeys in the KritisConfig", key, namespace)
+	}
+	return v.Key(spec)
+}
+
+// Key fetches the signing secret described by spec, whatever namespace uses it.
+func (v *Vault) Key(spec v1beta1.VaultKeySpec) (*PGPSigningSecret, error) {
+	if v.config.Address == "" {
+		return nil, fmt.Errorf("vault address is required, see vault.address in the KritisConfig")
+	}
+	switch spec.Engine {
+	case VaultKV:
+		return v.kvSecret(spec)
+	case VaultTransit:
+		return v.transitSecret(spec)
+	default:
+		return nil, fmt.Errorf("unknown vault engine %q, must be %s or %s", spec.Engine, VaultKV, VaultTransit)
+	}
+}
+
+// vaultMount returns the path of the secrets engine of spec.
+func vaultMount(spec v1beta1.VaultKeySpec) string {
+	if spec.Engine == VaultTransit {
+		return orDefault(spec.Mount, "transit")
+	}
+	return orDefault(spec.Mount, "secret")
+}
+
+// currentToken returns the VAULT_TOKEN, or the token logged in with, logging in again
+// once its lease expired.
+func (v *Vault) currentToken() (string, error) {
+	if t := os.Getenv("VAULT_TOKEN"); t != "" {
+		return t, nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.token != "" && (v.expires.IsZero() || vaultNow().Before(v.expires)) {
+		return v.token, nil
+	}
+	if v.config.Role == "" {
+		return "", fmt.Errorf("vault role is required without VAULT_TOKEN")
+	}
+	jwt, err := ioutil.ReadFile(vaultTokenPath)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read service account token")
+	}
+	var login struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	req := map[string]string{"role": v.config.Role, "jwt": string(jwt)}
+	if err := v.request(http.MethodPost, fmt.Sprintf("auth/%s/login", orDefault(v.config.AuthMount, "kubernetes")), "", req, &login); err != nil {
+		return "", errors.Wrap(err, "failed to log in to vault")
+	}
+	v.token, v.expires = login.Auth.ClientToken, time.Time{}
+	if lease := time.Duration(login.Auth.LeaseDuration) * time.Second; lease > 0 {
+		// Log in again a little before the lease expires, rather than fail a request.
+		v.expires = vaultNow().Add(lease * 9 / 10)
+	}
+	return v.token, nil
+}
+
+// do sends a request to the Vault API with the current token.
+func (v *Vault) do(method string, path string, body interface{}, out interface{}) error {
+	token, err := v.currentToken()
+	if err != nil {
+		return err
+	}
+	return v.request(method, path, token, body, out)
+}
+
+// request sends a request with the JSON encoded body to the Vault API, and decodes the
+// response into out.
+func (v *Vault) request(method string, path string, token string, body interface{}, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(v.config.Address, "/"), path), r)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("vault %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(b)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (v *Vault) kvSecret(spec v1beta1.VaultKeySpec) (*PGPSigningSecret, error) {
+	var secret struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := v.do(http.MethodGet, fmt.Sprintf("%s/data/%s", vaultMount(spec), spec.Path), nil, &secret); err != nil {
+		return nil, err
+	}
+	data := secret.Data.Data
+	for _, k := range []string{PublicKey, PrivateKey} {
+		if _, ok := data[k]; !ok {
+			return nil, fmt.Errorf("invalid vault secret %s. could not find key %s", spec.Path, k)
+		}
+	}
+	pgpKey, err := NewPgpKey(data[PrivateKey], data[Passphrase], data[PublicKey])
+	if err != nil {
+		return nil, err
+	}
+	return &PGPSigningSecret{
+		PgpKey:     pgpKey,
+		SecretName: spec.Path,
+	}, nil
+}
+
+type transitKey struct {
+	Type          string `json:"type"`
+	LatestVersion int    `json:"latest_version"`
+	Keys          map[string]struct {
+		PublicKey    string    `json:"public_key"`
+		CreationTime time.Time `json:"creation_time"`
+	} `json:"keys"`
+}
+
+func (v *Vault) transitSecret(spec v1beta1.VaultKeySpec) (*PGPSigningSecret, error) {
+	mount := vaultMount(spec)
+	var key struct {
+		Data transitKey `json:"data"`
+	}
+	if err := v.do(http.MethodGet, fmt.Sprintf("%s/keys/%s", mount, spec.Path), nil, &key); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(key.Data.Type, "rsa-") {
+		return nil, fmt.Errorf("vault transit key %s has type %s, only rsa keys can sign attestations", spec.Path, key.Data.Type)
+	}
+	version, ok := key.Data.Keys[strconv.Itoa(key.Data.LatestVersion)]
+	if !ok {
+		return nil, fmt.Errorf("vault transit key %s has no version %d", spec.Path, key.Data.LatestVersion)
+	}
+	block, _ := pem.Decode([]byte(version.PublicKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key of vault transit key %s", spec.Path)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid public key of vault transit key %s", spec.Path)
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("vault transit key %s is not an rsa key", spec.Path)
+	}
+	// The creation time of the key version is part of the PGP fingerprint, so it stays stable across fetches.
+	return &PGPSigningSecret{
+		PgpKey: NewPgpKeyFromSigner(version.CreationTime, &transitSigner{
+			vault:   v,
+			path:    fmt.Sprintf("%s/sign/%s", mount, spec.Path),
+			version: key.Data.LatestVersion,
+			public:  rsaPub,
+		}),
+		SecretName: spec.Path,
+	}, nil
+}
+
+// transitSigner implements crypto.Signer by signing digests with the Vault transit engine.
+type transitSigner struct {
+	vault   *Vault
+	path    string
+	version int
+	public  *rsa.PublicKey
+}
+
+func (s *transitSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+func (s *transitSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	algorithms := map[crypto.Hash]string{
+		crypto.SHA224: "sha2-224",
+		crypto.SHA256: "sha2-256",
+		crypto.SHA384: "sha2-384",
+		crypto.SHA512: "sha2-512",
+	}
+	algorithm, ok := algorithms[opts.HashFunc()]
+	if !ok {
+		return nil, fmt.Errorf("hash %v is not supported by vault transit", opts.HashFunc())
+	}
+	req := map[string]interface{}{
+		"input":               base64.StdEncoding.EncodeToString(digest),
+		"prehashed":           true,
+		"signature_algorithm": "pkcs1v15",
+		"key_version":         s.version,
+	}
+	var resp struct {
+		Data struct {
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}
+	if err := s.vault.do(http.MethodPost, fmt.Sprintf("%s/%s", s.path, algorithm), req, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to sign with vault")
+	}
+	// Signatures are returned as vault:v<version>:<base64 signature>
+	parts := strings.Split(resp.Data.Signature, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid vault signature %q", resp.Data.Signature)
+	}
+	return base64.StdEncoding.DecodeString(parts[2])
+}
+
+func orDefault(s string, d string) string {
+	if s == "" {
+		return d
+	}
+	return s
+}
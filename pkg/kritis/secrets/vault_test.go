@@ -0,0 +1,387 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+// fakeVault serves the subset of the Vault API used by FetchVault.
+// Logins to the Kubernetes auth method are counted in logins.
+func fakeVault(t *testing.T, key *rsa.PrivateKey, logins *int) *httptest.Server {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path == "/v1/auth/kubernetes/login" {
+			if body["role"] != "kritis" || body["jwt"] != "sa-token" {
+				http.Error(w, "permission denied", http.StatusForbidden)
+				return
+			}
+			*logins++
+			fmt.Fprint(w, `{"auth": {"client_token": "vault-token", "lease_duration": 3600}}`)
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/kritis/qa":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"data": map[string]string{PrivateKey: priv, PublicKey: pub},
+				},
+			})
+		case "/v1/transit/keys/kritis":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"type":           "rsa-2048",
+					"latest_version": 1,
+					"keys": map[string]interface{}{
+						"1": map[string]interface{}{"public_key": pemKey, "creation_time": "2019-01-01T00:00:00Z"},
+					},
+				},
+			})
+		case "/v1/transit/sign/kritis/sha2-256":
+			digest, _ := base64.StdEncoding.DecodeString(body["input"].(string))
+			sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			fmt.Fprintf(w, `{"data": {"signature": "vault:v1:%s"}}`, base64.StdEncoding.EncodeToString(sig))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestFetchVault(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	logins := 0
+	s := fakeVault(t, key, &logins)
+	defer s.Close()
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(tokenPath, []byte("sa-token"), 0600); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	originalPath, originalNow := vaultTokenPath, vaultNow
+	defer func() {
+		vaultTokenPath, vaultNow = originalPath, originalNow
+	}()
+	vaultTokenPath = tokenPath
+	clock := time.Unix(0, 0)
+	vaultNow = func() time.Time { return clock }
+	allowed := []v1beta1.KeyAllowlistSpec{{Namespace: "qa", Keys: []string{"secret/kritis/*", "transit/kritis"}}}
+	newVault := func(spec v1beta1.VaultConfigSpec) *Vault {
+		t.Helper()
+		spec.AllowedKeys = allowed
+		v, err := NewVault(spec)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		return v
+	}
+	v := newVault(v1beta1.VaultConfigSpec{Address: s.URL, Role: "kritis"})
+
+	t.Run("kv", func(t *testing.T) {
+		sec, err := v.Fetch("qa", v1beta1.VaultKeySpec{Engine: VaultKV, Path: "kritis/qa"})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if sec.PgpKey.Fingerprint() != pgpKey.Fingerprint() {
+			t.Errorf("expected fingerprint %s, got %s", pgpKey.Fingerprint(), sec.PgpKey.Fingerprint())
+		}
+	})
+	t.Run("transit", func(t *testing.T) {
+		spec := v1beta1.VaultKeySpec{Engine: VaultTransit, Path: "kritis"}
+		sec, err := v.Fetch("qa", spec)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		again, err := v.Fetch("qa", spec)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if sec.PgpKey.Fingerprint() != again.PgpKey.Fingerprint() {
+			t.Errorf("expected stable fingerprint, got %s and %s", sec.PgpKey.Fingerprint(), again.PgpKey.Fingerprint())
+		}
+		armored, err := sec.PgpKey.ArmoredPublicKey()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		signer := &openpgp.Entity{PrimaryKey: sec.PgpKey.PublicKey(), PrivateKey: sec.PgpKey.PrivateKey()}
+		var sig bytes.Buffer
+		if err := openpgp.DetachSign(&sig, signer, strings.NewReader("image"), nil); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if _, err := openpgp.CheckDetachedSignature(keyring, strings.NewReader("image"), &sig); err != nil {
+			t.Errorf("signature by vault does not verify: %v", err)
+		}
+	})
+	t.Run("token lease", func(t *testing.T) {
+		// The token logged in with is reused until its lease expires.
+		before := logins
+		if _, err := v.Fetch("qa", v1beta1.VaultKeySpec{Engine: VaultKV, Path: "kritis/qa"}); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if logins != before {
+			t.Errorf("expected no login, got %d", logins-before)
+		}
+		clock = clock.Add(time.Hour)
+		if _, err := v.Fetch("qa", v1beta1.VaultKeySpec{Engine: VaultKV, Path: "kritis/qa"}); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if logins != before+1 {
+			t.Errorf("expected a login once the lease expired, got %d", logins-before)
+		}
+	})
+	t.Run("vault token", func(t *testing.T) {
+		os.Setenv("VAULT_TOKEN", "vault-token")
+		defer os.Unsetenv("VAULT_TOKEN")
+		if _, err := newVault(v1beta1.VaultConfigSpec{Address: s.URL}).Fetch("qa", v1beta1.VaultKeySpec{Engine: VaultKV, Path: "kritis/qa"}); err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+	errTests := []struct {
+		name      string
+		vault     *Vault
+		namespace string
+		spec      v1beta1.VaultKeySpec
+	}{
+		{"unknown role", newVault(v1beta1.VaultConfigSpec{Address: s.URL, Role: "other"}), "qa", v1beta1.VaultKeySpec{Engine: VaultKV, Path: "kritis/qa"}},
+		{"missing secret", v, "qa", v1beta1.VaultKeySpec{Engine: VaultKV, Path: "kritis/prod"}},
+		{"unknown engine", v, "qa", v1beta1.VaultKeySpec{Engine: "pki", Mount: "secret", Path: "kritis/qa"}},
+		{"no address", newVault(v1beta1.VaultConfigSpec{Role: "kritis"}), "qa", v1beta1.VaultKeySpec{Engine: VaultKV, Path: "kritis/qa"}},
+		{"namespace not allowed", v, "prod", v1beta1.VaultKeySpec{Engine: VaultKV, Path: "kritis/qa"}},
+		{"key not allowed", v, "qa", v1beta1.VaultKeySpec{Engine: VaultTransit, Path: "kritis-prod"}},
+	}
+	for _, test := range errTests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.vault.Fetch(test.namespace, test.spec); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestNewVaultInvalid(t *testing.T) {
+	for _, spec := range []v1beta1.VaultConfigSpec{
+		{Timeout: "soon"},
+		{AllowedKeys: []v1beta1.KeyAllowlistSpec{{Keys: []string{"*"}}}},
+	} {
+		if _, err := NewVault(spec); err == nil {
+			t.Errorf("expected error for %+v", spec)
+		}
+	}
+}
+
+func TestFetchForAuthority(t *testing.T) {
+	original := fetchVaultFunc
+	defer func() {
+		fetchVaultFunc = original
+	}()
+	fetchVaultFunc = func(namespace string, spec v1beta1.VaultKeySpec) (*PGPSigningSecret, error) {
+		return &PGPSigningSecret{SecretName: "vault:" + spec.Path}, nil
+	}
+	originalSecretManager := fetchSecretManagerFunc
+	defer func() {
+		fetchSecretManagerFunc = originalSecretManager
+	}()
+	fetchSecretManagerFunc = func(namespace string, name string) (*PGPSigningSecret, error) {
+		return &PGPSigningSecret{SecretName: "secretmanager:" + name}, nil
+	}
+	defer ConfigureSecretManager(nil)
+	if err := ConfigureSecretManager([]v1beta1.KeyAllowlistSpec{{Namespace: "qa", Keys: []string{"projects/p/secrets/kritis"}}}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	fetcher := func(namespace string, name string) (*PGPSigningSecret, error) {
+		return &PGPSigningSecret{SecretName: name}, nil
+	}
+	tests := []struct {
+		name     string
+		spec     v1beta1.AttestationAuthoritySpec
+		expected string
+	}{
+		{"kubernetes secret", v1beta1.AttestationAuthoritySpec{PrivateKeySecretName: "sec"}, "sec"},
+		{"vault", v1beta1.AttestationAuthoritySpec{PrivateKeySecretName: "sec", VaultKey: &v1beta1.VaultKeySpec{Path: "kritis"}}, "vault:kritis"},
+		{"secret manager", v1beta1.AttestationAuthoritySpec{PrivateKeySecretName: "sec", SecretManagerKey: &v1beta1.SecretManagerKeySpec{Secret: "projects/p/secrets/kritis"}}, "secretmanager:projects/p/secrets/kritis/versions/latest"},
+		{"pinned secret manager version", v1beta1.AttestationAuthoritySpec{SecretManagerKey: &v1beta1.SecretManagerKeySpec{Secret: "projects/p/secrets/kritis", Version: "3"}}, "secretmanager:projects/p/secrets/kritis/versions/3"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sec, err := FetchForAuthority(fetcher, "qa", v1beta1.AttestationAuthority{Spec: test.spec})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if sec.SecretName != test.expected {
+				t.Errorf("expected secret %s, got %s", test.expected, sec.SecretName)
+			}
+		})
+	}
+	// Authorities can only read the secrets allowed for their namespace.
+	for _, test := range []struct {
+		namespace string
+		secret    string
+	}{
+		{"prod", "projects/p/secrets/kritis"},
+		{"qa", "projects/p/secrets/kritis-prod"},
+	} {
+		a := v1beta1.AttestationAuthority{Spec: v1beta1.AttestationAuthoritySpec{SecretManagerKey: &v1beta1.SecretManagerKeySpec{Secret: test.secret}}}
+		if _, err := FetchForAuthority(fetcher, test.namespace, a); err == nil {
+			t.Errorf("%s in %s: expected error", test.secret, test.namespace)
+		}
+	}
+	if err := ConfigureSecretManager([]v1beta1.KeyAllowlistSpec{{Keys: []string{"projects/p/secrets/kritis"}}}); err == nil {
+		t.Errorf("expected error for an allowlist without namespace")
+	}
+}
+
+func TestFetchForAuthorityKMS(t *testing.T) {
+	original := kmsKeys
+	defer func() {
+		kmsKeys = original
+	}()
+	key := "projects/p/locations/global/keyRings/qa/cryptoKeys/signer/cryptoKeyVersions/1"
+	if err := ConfigureKMS([]v1beta1.KeyAllowlistSpec{{Namespace: "qa", Keys: []string{"projects/p/locations/global/keyRings/qa/*"}}}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	a := v1beta1.AttestationAuthority{Spec: v1beta1.AttestationAuthoritySpec{KMSKeyName: key}}
+	sec, err := FetchForAuthority(nil, "qa", a)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if sec.KMSKeyName != key {
+		t.Errorf("expected KMS key %s, got %s", key, sec.KMSKeyName)
+	}
+	// Authorities of other namespaces can't sign with the key.
+	if _, err := FetchForAuthority(nil, "prod", a); err == nil {
+		t.Errorf("expected error")
+	}
+	// AWS KMS and Azure Key Vault keys are allowed by their name, prefix included.
+	if err := ConfigureKMS([]v1beta1.KeyAllowlistSpec{
+		{Namespace: "qa", Keys: []string{"awskms://arn:aws:kms:us-east-1:111122223333:key/qa"}},
+		{Namespace: "prod", Keys: []string{"azurekv://kritis.vault.azure.net/keys/prod/*"}},
+	}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, test := range []struct {
+		namespace string
+		key       string
+		allowed   bool
+	}{
+		{"qa", "awskms://arn:aws:kms:us-east-1:111122223333:key/qa", true},
+		{"qa", "azurekv://kritis.vault.azure.net/keys/prod/1", false},
+		{"prod", "azurekv://kritis.vault.azure.net/keys/prod/1", true},
+		{"prod", "arn:aws:kms:us-east-1:111122223333:key/qa", false},
+	} {
+		a := v1beta1.AttestationAuthority{Spec: v1beta1.AttestationAuthoritySpec{KMSKeyName: test.key}}
+		_, err := FetchForAuthority(nil, test.namespace, a)
+		if (err == nil) != test.allowed {
+			t.Errorf("%s in %s: expected allowed %t, got error %v", test.key, test.namespace, test.allowed, err)
+		}
+	}
+	if err := ConfigureKMS([]v1beta1.KeyAllowlistSpec{{Keys: []string{key}}}); err == nil {
+		t.Errorf("expected error for an allowlist without namespace")
+	}
+}
+
+func TestFetchForAuthorityPassphrase(t *testing.T) {
+	original := fetchDataFunc
+	defer func() {
+		fetchDataFunc = original
+	}()
+	fetchDataFunc = func(namespace string, name string) (map[string][]byte, error) {
+		if namespace != "qa" || name != "passphrases" {
+			return nil, fmt.Errorf("secret %s/%s not found", namespace, name)
+		}
+		return map[string][]byte{Passphrase: []byte("test passphrase"), "other": []byte("wrong passphrase")}, nil
+	}
+	fetcher := func(namespace string, name string) (*PGPSigningSecret, error) {
+		key, err := NewPgpKey(privPassphrase, "", public)
+		return &PGPSigningSecret{SecretName: name, PgpKey: key}, err
+	}
+	tests := []struct {
+		name   string
+		spec   v1beta1.AttestationAuthoritySpec
+		shdErr bool
+	}{
+		{"passphrase secret", v1beta1.AttestationAuthoritySpec{PrivateKeySecretName: "sec", PassphraseSecretName: "passphrases"}, false},
+		{"no passphrase", v1beta1.AttestationAuthoritySpec{PrivateKeySecretName: "sec"}, true},
+		{"wrong passphrase", v1beta1.AttestationAuthoritySpec{PrivateKeySecretName: "sec", PassphraseSecretName: "passphrases", PassphraseSecretKey: "other"}, true},
+		{"missing key", v1beta1.AttestationAuthoritySpec{PrivateKeySecretName: "sec", PassphraseSecretName: "passphrases", PassphraseSecretKey: "missing"}, true},
+		{"missing secret", v1beta1.AttestationAuthoritySpec{PrivateKeySecretName: "sec", PassphraseSecretName: "missing"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sec, err := FetchForAuthority(fetcher, "qa", v1beta1.AttestationAuthority{Spec: test.spec})
+			if (err != nil) != test.shdErr {
+				t.Fatalf("expected error %t, got %v", test.shdErr, err)
+			}
+			if err == nil && sec.PgpKey.Encrypted() {
+				t.Errorf("expected the private key to be decrypted")
+			}
+		})
+	}
+}
+
+func TestNewPgpKeyFromSignerFingerprint(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	created := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	a, b := NewPgpKeyFromSigner(created, key), NewPgpKeyFromSigner(created, key)
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Errorf("expected equal fingerprints, got %s and %s", a.Fingerprint(), b.Fingerprint())
+	}
+	if c := NewPgpKeyFromSigner(created.Add(time.Hour), key); c.Fingerprint() == a.Fingerprint() {
+		t.Errorf("expected fingerprint to depend on the creation time")
+	}
+}
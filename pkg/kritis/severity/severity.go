@@ -0,0 +1,168 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package severity maps the severities and CVSS scores reported by scanners to the
+// Grafeas severity scale ImageSecurityPolicies are evaluated on, so that thresholds
+// behave the same whichever backend reports the vulnerabilities.
+package severity
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/vulnerability"
+)
+
+// Severities of the Grafeas scale, lowest first
+const (
+	Unspecified = "SEVERITY_UNSPECIFIED"
+	Minimal     = "MINIMAL"
+	Low         = "LOW"
+	Medium      = "MEDIUM"
+	High        = "HIGH"
+	Critical    = "CRITICAL"
+)
+
+// vendorSeverities maps scanner specific severities to the Grafeas severity scale.
+var vendorSeverities = map[string]string{
+	"NONE":          Minimal,
+	"NEGLIGIBLE":    Minimal,
+	"INFORMATIONAL": Minimal,
+	"INFO":          Minimal,
+	"MODERATE":      Medium,
+	"IMPORTANT":     High,
+}
+
+// Normalize maps a scanner severity to the Grafeas severity scale used by policies,
+// ignoring case, e.g. "Negligible" is MINIMAL and "Important" is HIGH. Severities with
+// no equivalent, e.g. "Unknown" or "Untriaged", are SEVERITY_UNSPECIFIED.
+func Normalize(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if sev, ok := vendorSeverities[s]; ok {
+		return sev
+	}
+	if _, ok := vulnerability.Severity_value[s]; ok {
+		return s
+	}
+	return Unspecified
+}
+
+// FromScore returns the severity of a CVSS score, following the CVSS v3 qualitative
+// rating scale, with None as MINIMAL. Scores out of the 0-10 range are SEVERITY_UNSPECIFIED.
+func FromScore(score float64) string {
+	switch {
+	case score < 0 || score > 10:
+		return Unspecified
+	case score == 0:
+		return Minimal
+	case score < 4:
+		return Low
+	case score < 7:
+		return Medium
+	case score < 9:
+		return High
+	}
+	return Critical
+}
+
+// Canonical returns the severity of a vulnerability reported with severity s. Scanners
+// which report no known severity have it derived from the CVSS score, if positive, or
+// else from the CVSS v3 vector.
+func Canonical(s string, score float64, vector string) string {
+	if sev := Normalize(s); sev != Unspecified {
+		return sev
+	}
+	if score > 0 {
+		return FromScore(score)
+	}
+	if vector != "" {
+		if score, err := Score(vector); err == nil {
+			return FromScore(score)
+		}
+	}
+	return Unspecified
+}
+
+// Weights of the base metrics of CVSS v3
+var cvssWeights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"PR": {"N": 0.85, "L": 0.62, "H": 0.27},
+	"UI": {"N": 0.85, "R": 0.62},
+	"S":  {"U": 0, "C": 0},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// Score returns the base score of a CVSS v3.0 or v3.1 vector, e.g.
+// CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H is 9.8.
+func Score(vector string) (float64, error) {
+	parts := strings.Split(vector, "/")
+	if parts[0] != "CVSS:3.0" && parts[0] != "CVSS:3.1" {
+		return 0, fmt.Errorf("unsupported CVSS vector %q, expected CVSS:3.0 or CVSS:3.1", vector)
+	}
+	metrics := map[string]string{}
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(p, ":", 2)
+		if len(kv) != 2 {
+			return 0, fmt.Errorf("invalid metric %q of CVSS vector %q", p, vector)
+		}
+		metrics[kv[0]] = kv[1]
+	}
+	w := map[string]float64{}
+	for m, values := range cvssWeights {
+		v, ok := values[metrics[m]]
+		if !ok {
+			return 0, fmt.Errorf("invalid or missing base metric %s of CVSS vector %q", m, vector)
+		}
+		w[m] = v
+	}
+	changed := metrics["S"] == "C"
+	if changed {
+		// Privileges matter more when the scope changes.
+		switch metrics["PR"] {
+		case "L":
+			w["PR"] = 0.68
+		case "H":
+			w["PR"] = 0.5
+		}
+	}
+	iss := 1 - (1-w["C"])*(1-w["I"])*(1-w["A"])
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, nil
+	}
+	exploitability := 8.22 * w["AV"] * w["AC"] * w["PR"] * w["UI"]
+	if changed {
+		return roundUp(math.Min(1.08*(impact+exploitability), 10)), nil
+	}
+	return roundUp(math.Min(impact+exploitability, 10)), nil
+}
+
+// roundUp returns the smallest number with one decimal no lower than x, as
+// defined by CVSS v3.1 to avoid floating point errors.
+func roundUp(x float64) float64 {
+	i := int64(math.Round(x * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return float64(i/10000+1) / 10
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package severity
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	var tests = []struct {
+		severity string
+		expected string
+	}{
+		{"HIGH", "HIGH"},
+		{"critical", "CRITICAL"},
+		{" Medium ", "MEDIUM"},
+		{"Negligible", "MINIMAL"},
+		{"INFORMATIONAL", "MINIMAL"},
+		{"Moderate", "MEDIUM"},
+		{"Important", "HIGH"},
+		{"Unknown", "SEVERITY_UNSPECIFIED"},
+		{"", "SEVERITY_UNSPECIFIED"},
+	}
+	for _, test := range tests {
+		t.Run(test.severity, func(t *testing.T) {
+			if actual := Normalize(test.severity); actual != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFromScore(t *testing.T) {
+	var tests = []struct {
+		score    float64
+		expected string
+	}{
+		{0, "MINIMAL"},
+		{0.1, "LOW"},
+		{3.9, "LOW"},
+		{4, "MEDIUM"},
+		{6.9, "MEDIUM"},
+		{7, "HIGH"},
+		{8.9, "HIGH"},
+		{9, "CRITICAL"},
+		{10, "CRITICAL"},
+		{-1, "SEVERITY_UNSPECIFIED"},
+		{10.1, "SEVERITY_UNSPECIFIED"},
+	}
+	for _, test := range tests {
+		if actual := FromScore(test.score); actual != test.expected {
+			t.Errorf("score %v: expected %s, got %s", test.score, test.expected, actual)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	var tests = []struct {
+		vector    string
+		expected  float64
+		shouldErr bool
+	}{
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", 9.8, false},
+		{"CVSS:3.0/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", 10, false},
+		{"CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:N/A:N", 5.5, false},
+		{"CVSS:3.1/AV:N/AC:H/PR:N/UI:R/S:U/C:L/I:N/A:N", 3.1, false},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N", 6.1, false},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", 0, false},
+		{"AV:N/AC:L/Au:N/C:P/I:P/A:P", 0, true},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H", 0, true},
+		{"CVSS:3.1/AV:X/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", 0, true},
+		{"CVSS:3.1/AV", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.vector, func(t *testing.T) {
+			actual, err := Score(test.vector)
+			if test.shouldErr != (err != nil) {
+				t.Fatalf("expected error %t, got %v", test.shouldErr, err)
+			}
+			if actual != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	var tests = []struct {
+		name     string
+		severity string
+		score    float64
+		vector   string
+		expected string
+	}{
+		{"known severity", "Moderate", 9.8, "", "MEDIUM"},
+		{"score", "Unknown", 7.5, "", "HIGH"},
+		{"vector", "", 0, "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", "CRITICAL"},
+		{"score before vector", "", 5, "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", "MEDIUM"},
+		{"invalid vector", "", 0, "AV:N/AC:L/Au:N/C:P/I:P/A:P", "SEVERITY_UNSPECIFIED"},
+		{"nothing known", "", 0, "", "SEVERITY_UNSPECIFIED"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := Canonical(test.severity, test.score, test.vector); actual != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, actual)
+			}
+		})
+	}
+}
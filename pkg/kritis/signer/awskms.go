@@ -0,0 +1,97 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/pkg/errors"
+)
+
+// awsKMSAlgorithms are the signing algorithms and digests used for each key spec.
+var awsKMSAlgorithms = map[string]struct {
+	algorithm string
+	hash      crypto.Hash
+}{
+	kms.KeySpecEccNistP256: {kms.SigningAlgorithmSpecEcdsaSha256, crypto.SHA256},
+	kms.KeySpecEccNistP384: {kms.SigningAlgorithmSpecEcdsaSha384, crypto.SHA384},
+	kms.KeySpecEccNistP521: {kms.SigningAlgorithmSpecEcdsaSha512, crypto.SHA512},
+	kms.KeySpecRsa2048:     {kms.SigningAlgorithmSpecRsassaPkcs1V15Sha256, crypto.SHA256},
+	kms.KeySpecRsa3072:     {kms.SigningAlgorithmSpecRsassaPkcs1V15Sha256, crypto.SHA256},
+	kms.KeySpecRsa4096:     {kms.SigningAlgorithmSpecRsassaPkcs1V15Sha256, crypto.SHA256},
+}
+
+// For testing
+var newAWSKMSAPI = func(region string) (kmsiface.KMSAPI, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return kms.New(sess, aws.NewConfig().WithRegion(region)), nil
+}
+
+// awsKMSSigner signs with an asymmetric signing key of AWS KMS, identified by its ARN,
+// using the default AWS credential chain.
+type awsKMSSigner struct {
+	arn string
+	api kmsiface.KMSAPI
+}
+
+func newAWSKMSSigner(arn string) (KeySigner, error) {
+	// arn:aws:kms:<region>:<account>:key/<id>
+	parts := strings.Split(arn, ":")
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "kms" {
+		return nil, fmt.Errorf("invalid AWS KMS key ARN %q", arn)
+	}
+	api, err := newAWSKMSAPI(parts[3])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create AWS KMS client")
+	}
+	return &awsKMSSigner{arn: arn, api: api}, nil
+}
+
+func (s *awsKMSSigner) KeyID() string {
+	return s.arn
+}
+
+func (s *awsKMSSigner) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	pub, err := s.api.GetPublicKeyWithContext(ctx, &kms.GetPublicKeyInput{KeyId: aws.String(s.arn)})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get public key of %s", s.arn)
+	}
+	alg, ok := awsKMSAlgorithms[aws.StringValue(pub.KeySpec)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported AWS KMS key spec %s of %s", aws.StringValue(pub.KeySpec), s.arn)
+	}
+	resp, err := s.api.SignWithContext(ctx, &kms.SignInput{
+		KeyId:            aws.String(s.arn),
+		Message:          hash(alg.hash, payload),
+		MessageType:      aws.String(kms.MessageTypeDigest),
+		SigningAlgorithm: aws.String(alg.algorithm),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to sign with %s", s.arn)
+	}
+	return resp.Signature, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/grafeas/kritis/pkg/kritis/attestation"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+type fakeAWSKMS struct {
+	kmsiface.KMSAPI
+	key     *ecdsa.PrivateKey
+	keySpec string
+}
+
+func (f *fakeAWSKMS) GetPublicKeyWithContext(ctx aws.Context, in *kms.GetPublicKeyInput, _ ...request.Option) (*kms.GetPublicKeyOutput, error) {
+	return &kms.GetPublicKeyOutput{KeyId: in.KeyId, KeySpec: aws.String(f.keySpec)}, nil
+}
+
+func (f *fakeAWSKMS) SignWithContext(ctx aws.Context, in *kms.SignInput, _ ...request.Option) (*kms.SignOutput, error) {
+	sig, err := ecdsa.SignASN1(rand.Reader, f.key, in.Message)
+	if err != nil {
+		return nil, err
+	}
+	return &kms.SignOutput{KeyId: in.KeyId, Signature: sig}, nil
+}
+
+func TestNewAWSKMSSigner(t *testing.T) {
+	original := newAWSKMSAPI
+	defer func() { newAWSKMSAPI = original }()
+	var region string
+	newAWSKMSAPI = func(r string) (kmsiface.KMSAPI, error) {
+		region = r
+		return &fakeAWSKMS{}, nil
+	}
+	_, err := newAWSKMSSigner("arn:aws:kms:eu-west-1:111122223333:key/k")
+	testutil.CheckErrorAndDeepEqual(t, false, err, "eu-west-1", region)
+	_, err = newAWSKMSSigner("alias/k")
+	testutil.CheckError(t, true, err)
+}
+
+func TestAWSKMSSign(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	payload := []byte("payload")
+	arn := "arn:aws:kms:us-east-1:111122223333:key/k"
+	s := &awsKMSSigner{arn: arn, api: &fakeAWSKMS{key: key, keySpec: kms.KeySpecEccNistP256}}
+	sig, err := s.Sign(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := attestation.VerifyPKIXSignature(pemPublicKey(t, &key.PublicKey), payload, sig); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+	s.api = &fakeAWSKMS{key: key, keySpec: kms.KeySpecSymmetricDefault}
+	_, err = s.Sign(context.Background(), payload)
+	testutil.CheckError(t, true, err)
+}
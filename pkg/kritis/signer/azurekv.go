@@ -0,0 +1,175 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/pkg/errors"
+)
+
+const (
+	azureKeyVaultResource   = "https://vault.azure.net"
+	azureKeyVaultAPIVersion = "7.4"
+	azureActiveDirectory    = "https://login.microsoftonline.com/"
+)
+
+// azureKeyVaultAlgorithms are the signing algorithms and digests used for each EC curve, RSA keys use RS256.
+var azureKeyVaultAlgorithms = map[string]struct {
+	algorithm string
+	hash      crypto.Hash
+}{
+	"P-256": {"ES256", crypto.SHA256},
+	"P-384": {"ES384", crypto.SHA384},
+	"P-521": {"ES512", crypto.SHA512},
+	"":      {"RS256", crypto.SHA256},
+}
+
+var (
+	// For testing
+	azureKeyVaultClient = http.DefaultClient
+	azureKeyVaultToken  = func() (string, error) {
+		token, err := newAzureToken()
+		if err != nil {
+			return "", err
+		}
+		if err := token.EnsureFresh(); err != nil {
+			return "", err
+		}
+		return token.OAuthToken(), nil
+	}
+)
+
+// newAzureToken authenticates with the service principal of the AZURE_TENANT_ID, AZURE_CLIENT_ID
+// and AZURE_CLIENT_SECRET environment variables if set, and with a managed identity otherwise.
+func newAzureToken() (*adal.ServicePrincipalToken, error) {
+	tenant, id, secret := os.Getenv("AZURE_TENANT_ID"), os.Getenv("AZURE_CLIENT_ID"), os.Getenv("AZURE_CLIENT_SECRET")
+	if tenant != "" && id != "" && secret != "" {
+		oauth, err := adal.NewOAuthConfig(azureActiveDirectory, tenant)
+		if err != nil {
+			return nil, err
+		}
+		return adal.NewServicePrincipalToken(*oauth, id, secret, azureKeyVaultResource)
+	}
+	endpoint, err := adal.GetMSIVMEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	if id != "" {
+		return adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(endpoint, azureKeyVaultResource, id)
+	}
+	return adal.NewServicePrincipalTokenFromMSI(endpoint, azureKeyVaultResource)
+}
+
+// azureKeyVaultKeyID returns the URL of the key <vault>/keys/<name>/<version>.
+func azureKeyVaultKeyID(key string) string {
+	return "https://" + strings.TrimPrefix(key, "https://")
+}
+
+// azureKeyVaultSigner signs with a key of Azure Key Vault, or of a Managed HSM.
+type azureKeyVaultSigner struct {
+	url string
+}
+
+func newAzureKeyVaultSigner(key string) (KeySigner, error) {
+	if len(strings.Split(strings.TrimPrefix(key, "https://"), "/")) != 4 {
+		return nil, fmt.Errorf("invalid Azure Key Vault key %q, expected <vault>/keys/<name>/<version>", key)
+	}
+	return &azureKeyVaultSigner{url: azureKeyVaultKeyID(key)}, nil
+}
+
+func (s *azureKeyVaultSigner) KeyID() string {
+	return s.url
+}
+
+func (s *azureKeyVaultSigner) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	token, err := azureKeyVaultToken()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to authenticate to Azure")
+	}
+	var key struct {
+		Key struct {
+			Kty string `json:"kty"`
+			Crv string `json:"crv"`
+		} `json:"key"`
+	}
+	if err := s.do(ctx, token, http.MethodGet, s.url, nil, &key); err != nil {
+		return nil, errors.Wrapf(err, "failed to get key %s", s.url)
+	}
+	alg, ok := azureKeyVaultAlgorithms[key.Key.Crv]
+	if !ok || (key.Key.Crv == "" && !strings.HasPrefix(key.Key.Kty, "RSA")) {
+		return nil, fmt.Errorf("unsupported Azure Key Vault key type %s %s of %s", key.Key.Kty, key.Key.Crv, s.url)
+	}
+	req := map[string]string{
+		"alg":   alg.algorithm,
+		"value": base64.RawURLEncoding.EncodeToString(hash(alg.hash, payload)),
+	}
+	var resp struct {
+		Value string `json:"value"`
+	}
+	if err := s.do(ctx, token, http.MethodPost, s.url+"/sign", req, &resp); err != nil {
+		return nil, errors.Wrapf(err, "failed to sign with %s", s.url)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(resp.Value)
+	if err != nil {
+		return nil, err
+	}
+	if key.Key.Crv == "" {
+		return sig, nil
+	}
+	// Key Vault returns the concatenated R and S of ECDSA signatures, which are ASN.1 encoded in attestations.
+	r, sv := new(big.Int).SetBytes(sig[:len(sig)/2]), new(big.Int).SetBytes(sig[len(sig)/2:])
+	return asn1.Marshal(struct{ R, S *big.Int }{r, sv})
+}
+
+func (s *azureKeyVaultSigner) do(ctx context.Context, token string, method string, url string, body interface{}, out interface{}) error {
+	var b []byte
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, url+"?api-version="+azureKeyVaultAPIVersion, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := azureKeyVaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
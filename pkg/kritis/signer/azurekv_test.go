@@ -0,0 +1,90 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/attestation"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func TestAzureKeyVaultSign(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	payload := []byte("payload")
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /keys/k/1":
+			w.Write([]byte(`{"key": {"kty": "EC-HSM", "crv": "P-256"}}`))
+		case "POST /keys/k/1/sign":
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			digest, _ := base64.RawURLEncoding.DecodeString(req["value"])
+			if req["alg"] != "ES256" || string(digest) != string(hash(crypto.SHA256, payload)) {
+				http.Error(w, "unexpected request", http.StatusBadRequest)
+				return
+			}
+			r, s, _ := ecdsa.Sign(rand.Reader, key, digest)
+			sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+			json.NewEncoder(w).Encode(map[string]string{"value": base64.RawURLEncoding.EncodeToString(sig)})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+	originalClient, originalToken := azureKeyVaultClient, azureKeyVaultToken
+	defer func() { azureKeyVaultClient, azureKeyVaultToken = originalClient, originalToken }()
+	azureKeyVaultClient = s.Client()
+	azureKeyVaultToken = func() (string, error) { return "token", nil }
+	vault := strings.TrimPrefix(s.URL, "https://")
+
+	signer, err := newAzureKeyVaultSigner(vault + "/keys/k/1")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	sig, err := signer.Sign(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := attestation.VerifyPKIXSignature(pemPublicKey(t, &key.PublicKey), payload, sig); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+
+	signer, err = newAzureKeyVaultSigner(vault + "/keys/missing/1")
+	testutil.CheckError(t, false, err)
+	_, err = signer.Sign(context.Background(), payload)
+	testutil.CheckError(t, true, err)
+	_, err = newAzureKeyVaultSigner(vault + "/keys/k")
+	testutil.CheckError(t, true, err)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/gcpauth"
+)
+
+var (
+	// For testing
+	gcpKMSVersions = func(ctx context.Context) (*cloudkms.ProjectsLocationsKeyRingsCryptoKeysCryptoKeyVersionsService, error) {
+		opts, err := gcpauth.ClientOptions(ctx, gcpauth.KMS)
+		if err != nil {
+			return nil, err
+		}
+		s, err := cloudkms.NewService(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return s.Projects.Locations.KeyRings.CryptoKeys.CryptoKeyVersions, nil
+	}
+)
+
+// maxGCPKMSSigners bounds the signers cached by newGCPKMSSigner.
+const maxGCPKMSSigners = 100
+
+var (
+	gcpKMSMu sync.Mutex
+	// gcpKMSSigners caches the signer of each key, so that its client and credentials
+	// are created once rather than for every signature.
+	gcpKMSSigners = map[string]*gcpKMSSigner{}
+)
+
+// gcpKMSKeyID prefixes the CryptoKeyVersion name, as in Binary Authorization.
+func gcpKMSKeyID(keyName string) string {
+	return "//cloudkms.googleapis.com/v1/" + keyName
+}
+
+// gcpKMSSigner signs with an asymmetric signing CryptoKeyVersion of Google Cloud KMS, e.g.
+// projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1, using Application
+// Default Credentials unless gcpCredentials are configured.
+type gcpKMSSigner struct {
+	keyName  string
+	versions *cloudkms.ProjectsLocationsKeyRingsCryptoKeysCryptoKeyVersionsService
+}
+
+// newGCPKMSSigner returns the signer of keyName, creating its client on first use.
+func newGCPKMSSigner(keyName string) (KeySigner, error) {
+	gcpKMSMu.Lock()
+	defer gcpKMSMu.Unlock()
+	if s, ok := gcpKMSSigners[keyName]; ok {
+		return s, nil
+	}
+	// The client outlives the request it is created for.
+	versions, err := gcpKMSVersions(context.Background())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create KMS client")
+	}
+	if len(gcpKMSSigners) >= maxGCPKMSSigners {
+		for k := range gcpKMSSigners {
+			delete(gcpKMSSigners, k)
+			break
+		}
+	}
+	s := &gcpKMSSigner{keyName: keyName, versions: versions}
+	gcpKMSSigners[keyName] = s
+	return s, nil
+}
+
+func (s *gcpKMSSigner) KeyID() string {
+	return gcpKMSKeyID(s.keyName)
+}
+
+func (s *gcpKMSSigner) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	pub, err := s.versions.GetPublicKey(s.keyName).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get public key of %s", s.keyName)
+	}
+	digest := &cloudkms.Digest{}
+	switch {
+	case strings.HasSuffix(pub.Algorithm, "SHA512"):
+		digest.Sha512 = base64.StdEncoding.EncodeToString(hash(crypto.SHA512, payload))
+	case strings.HasSuffix(pub.Algorithm, "SHA384"):
+		digest.Sha384 = base64.StdEncoding.EncodeToString(hash(crypto.SHA384, payload))
+	case strings.HasSuffix(pub.Algorithm, "SHA256"):
+		digest.Sha256 = base64.StdEncoding.EncodeToString(hash(crypto.SHA256, payload))
+	default:
+		return nil, fmt.Errorf("unsupported KMS signing algorithm %s of %s", pub.Algorithm, s.keyName)
+	}
+	resp, err := s.versions.AsymmetricSign(s.keyName, &cloudkms.AsymmetricSignRequest{Digest: digest}).Context(ctx).Do()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to sign with %s", s.keyName)
+	}
+	return base64.StdEncoding.DecodeString(resp.Signature)
+}
+
+func hash(h crypto.Hash, payload []byte) []byte {
+	d := h.New()
+	d.Write(payload)
+	return d.Sum(nil)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/attestation"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+)
+
+func TestGCPKMSSign(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	payload := []byte("payload")
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/" + testGCPKey + "/publicKey":
+			json.NewEncoder(w).Encode(cloudkms.PublicKey{Algorithm: "EC_SIGN_P256_SHA256", Pem: pemPublicKey(t, &key.PublicKey)})
+		case "/v1/" + testGCPKey + ":asymmetricSign":
+			var req cloudkms.AsymmetricSignRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			digest, _ := base64.StdEncoding.DecodeString(req.Digest.Sha256)
+			if string(digest) != string(hash(crypto.SHA256, payload)) {
+				http.Error(w, "unexpected digest", http.StatusBadRequest)
+				return
+			}
+			sig, _ := ecdsa.SignASN1(rand.Reader, key, digest)
+			json.NewEncoder(w).Encode(cloudkms.AsymmetricSignResponse{Signature: base64.StdEncoding.EncodeToString(sig)})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+	original := gcpKMSVersions
+	defer func() {
+		gcpKMSVersions = original
+		gcpKMSSigners = map[string]*gcpKMSSigner{}
+	}()
+	gcpKMSSigners = map[string]*gcpKMSSigner{}
+	clients := 0
+	gcpKMSVersions = func(ctx context.Context) (*cloudkms.ProjectsLocationsKeyRingsCryptoKeysCryptoKeyVersionsService, error) {
+		clients++
+		svc, err := cloudkms.NewService(ctx, option.WithEndpoint(s.URL), option.WithoutAuthentication())
+		if err != nil {
+			return nil, err
+		}
+		return svc.Projects.Locations.KeyRings.CryptoKeys.CryptoKeyVersions, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		signer, err := newGCPKMSSigner(testGCPKey)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		sig, err := signer.Sign(context.Background(), payload)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if err := attestation.VerifyPKIXSignature(pemPublicKey(t, &key.PublicKey), payload, sig); err != nil {
+			t.Errorf("signature does not verify: %v", err)
+		}
+	}
+	// The client of a key is created once.
+	if clients != 1 {
+		t.Errorf("expected 1 KMS client, got %d", clients)
+	}
+	missing, err := newGCPKMSSigner("projects/p/missing")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := missing.Sign(context.Background(), payload); err == nil {
+		t.Errorf("expected error for missing key")
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"context"
+
+	attestationpb "google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/attestation"
+
+	"github.com/grafeas/kritis/pkg/kritis/attestation"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+// NewPGP returns a Signer creating PGP signed attestations with the key.
+func NewPGP(key *secrets.PgpKey) Signer {
+	return pgpSigner{key: key}
+}
+
+type pgpSigner struct {
+	key *secrets.PgpKey
+}
+
+func (s pgpSigner) KeyID() string {
+	return s.key.Fingerprint()
+}
+
+func (s pgpSigner) Attest(ctx context.Context, payload []byte) (*attestationpb.Attestation, error) {
+	sig, err := attestation.CreateMessageAttestation(s.key, string(payload))
+	if err != nil {
+		return nil, err
+	}
+	return &attestationpb.Attestation{
+		Signature: &attestationpb.Attestation_PgpSignedAttestation{
+			PgpSignedAttestation: &attestationpb.PgpSignedAttestation{
+				Signature: sig,
+				KeyId: &attestationpb.PgpSignedAttestation_PgpKeyId{
+					PgpKeyId: s.KeyID(),
+				},
+				ContentType: attestationpb.PgpSignedAttestation_SIMPLE_SIGNING_JSON,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package signer signs the attestations created by kritis, with the PGP keys of
+// AttestationAuthorities or with keys held in a key management service.
+package signer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	attestationpb "google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/attestation"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/common"
+
+	"github.com/grafeas/kritis/pkg/kritis/faults"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+const (
+	// AWSKMSPrefix prefixes the ARN of AWS KMS keys, e.g. awskms://arn:aws:kms:us-east-1:111122223333:key/<id>.
+	AWSKMSPrefix = "awskms://"
+	// AzureKeyVaultPrefix prefixes the Azure Key Vault keys, e.g. azurekv://my-vault.vault.azure.net/keys/kritis/<version>.
+	AzureKeyVaultPrefix = "azurekv://"
+)
+
+// Signer creates the attestations of payloads.
+type Signer interface {
+	// KeyID identifies the key verifying the attestations.
+	KeyID() string
+	// Attest signs the payload and returns its attestation.
+	Attest(ctx context.Context, payload []byte) (*attestationpb.Attestation, error)
+}
+
+// KeySigner signs payloads with a PKIX key of a key management service. Signatures of ECDSA
+// keys are ASN.1 encoded, signatures of RSA keys are PKCS #1 v1.5 or PSS.
+type KeySigner interface {
+	KeyID() string
+	Sign(ctx context.Context, payload []byte) ([]byte, error)
+}
+
+var (
+	// For testing
+	newGCPKMS        = newGCPKMSSigner
+	newAWSKMS        = newAWSKMSSigner
+	newAzureKeyVault = newAzureKeyVaultSigner
+)
+
+// New returns the Signer of the secret of an AttestationAuthority. Secrets naming a KMS key
+// sign with the key, AWS KMS and Azure Key Vault keys are recognized by their prefix and other
+// names are Google Cloud KMS CryptoKeyVersions. Other secrets sign with their PGP key.
+func New(secret *secrets.PGPSigningSecret) (Signer, error) {
+	if secret.KMSKeyName == "" {
+		if secret.PgpKey == nil {
+			return nil, fmt.Errorf("secret %s has no signing key", secret.SecretName)
+		}
+		return NewPGP(secret.PgpKey), nil
+	}
+	var s KeySigner
+	var err error
+	switch {
+	case strings.HasPrefix(secret.KMSKeyName, AWSKMSPrefix):
+		s, err = newAWSKMS(strings.TrimPrefix(secret.KMSKeyName, AWSKMSPrefix))
+	case strings.HasPrefix(secret.KMSKeyName, AzureKeyVaultPrefix):
+		s, err = newAzureKeyVault(strings.TrimPrefix(secret.KMSKeyName, AzureKeyVaultPrefix))
+	default:
+		s, err = newGCPKMS(secret.KMSKeyName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewGeneric(s), nil
+}
+
+// KeyID returns the key ID of the attestations signed with the KMS key keyName.
+func KeyID(keyName string) string {
+	switch {
+	case strings.HasPrefix(keyName, AWSKMSPrefix):
+		return strings.TrimPrefix(keyName, AWSKMSPrefix)
+	case strings.HasPrefix(keyName, AzureKeyVaultPrefix):
+		return azureKeyVaultKeyID(strings.TrimPrefix(keyName, AzureKeyVaultPrefix))
+	default:
+		return gcpKMSKeyID(keyName)
+	}
+}
+
+// NewGeneric returns a Signer creating generic signed attestations with s.
+func NewGeneric(s KeySigner) Signer {
+	return genericSigner{s}
+}
+
+type genericSigner struct {
+	KeySigner
+}
+
+func (s genericSigner) Attest(ctx context.Context, payload []byte) (*attestationpb.Attestation, error) {
+	if err := faults.Inject(ctx, faults.KMS); err != nil {
+		return nil, err
+	}
+	sig, err := s.Sign(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	return &attestationpb.Attestation{
+		Signature: &attestationpb.Attestation_GenericSignedAttestation{
+			GenericSignedAttestation: &attestationpb.GenericSignedAttestation{
+				ContentType:       attestationpb.GenericSignedAttestation_SIMPLE_SIGNING_JSON,
+				SerializedPayload: payload,
+				Signatures: []*common.Signature{{
+					Signature:   sig,
+					PublicKeyId: s.KeyID(),
+				}},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/attestation"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+const testGCPKey = "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1"
+
+func pemPublicKey(t *testing.T, pub crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// ecdsaSigner signs with a local key, standing in for a key management service.
+type ecdsaSigner struct {
+	key *ecdsa.PrivateKey
+	err error
+}
+
+func (s ecdsaSigner) KeyID() string {
+	return "test-key"
+}
+
+func (s ecdsaSigner) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return ecdsa.SignASN1(rand.Reader, s.key, hash(crypto.SHA256, payload))
+}
+
+func TestNew(t *testing.T) {
+	original := []func(string) (KeySigner, error){newGCPKMS, newAWSKMS, newAzureKeyVault}
+	defer func() {
+		newGCPKMS, newAWSKMS, newAzureKeyVault = original[0], original[1], original[2]
+	}()
+	var created []string
+	fake := func(backend string) func(string) (KeySigner, error) {
+		return func(key string) (KeySigner, error) {
+			created = append(created, backend+" "+key)
+			return ecdsaSigner{}, nil
+		}
+	}
+	newGCPKMS, newAWSKMS, newAzureKeyVault = fake("gcp"), fake("aws"), fake("azure")
+	pub, priv := testutil.CreateKeyPair(t, "test")
+	pgpKey, err := secrets.NewPgpKey(priv, "", pub)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	tests := []struct {
+		name    string
+		secret  *secrets.PGPSigningSecret
+		created []string
+		keyID   string
+		shdErr  bool
+	}{
+		{"pgp", &secrets.PGPSigningSecret{PgpKey: pgpKey}, nil, pgpKey.Fingerprint(), false},
+		{"gcp", &secrets.PGPSigningSecret{KMSKeyName: testGCPKey}, []string{"gcp " + testGCPKey}, "test-key", false},
+		{"aws", &secrets.PGPSigningSecret{KMSKeyName: "awskms://arn:aws:kms:us-east-1:111122223333:key/k"}, []string{"aws arn:aws:kms:us-east-1:111122223333:key/k"}, "test-key", false},
+		{"azure", &secrets.PGPSigningSecret{KMSKeyName: "azurekv://v.vault.azure.net/keys/k/1"}, []string{"azure v.vault.azure.net/keys/k/1"}, "test-key", false},
+		{"no key", &secrets.PGPSigningSecret{SecretName: "empty"}, nil, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			created = nil
+			s, err := New(test.secret)
+			testutil.CheckError(t, test.shdErr, err)
+			if err != nil {
+				return
+			}
+			testutil.DeepEqual(t, test.created, created)
+			if s.KeyID() != test.keyID {
+				t.Errorf("expected key ID %s, got %s", test.keyID, s.KeyID())
+			}
+		})
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{testGCPKey, "//cloudkms.googleapis.com/v1/" + testGCPKey},
+		{"awskms://arn:aws:kms:us-east-1:111122223333:key/k", "arn:aws:kms:us-east-1:111122223333:key/k"},
+		{"azurekv://v.vault.azure.net/keys/k/1", "https://v.vault.azure.net/keys/k/1"},
+	}
+	for _, test := range tests {
+		if id := KeyID(test.key); id != test.expected {
+			t.Errorf("expected key ID of %s to be %s, got %s", test.key, test.expected, id)
+		}
+	}
+}
+
+func TestGenericAttest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	payload := []byte(`{"critical":{}}`)
+	a, err := NewGeneric(ecdsaSigner{key: key}).Attest(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	generic := a.GetGenericSignedAttestation()
+	testutil.DeepEqual(t, payload, generic.GetSerializedPayload())
+	if len(generic.GetSignatures()) != 1 || generic.GetSignatures()[0].GetPublicKeyId() != "test-key" {
+		t.Fatalf("unexpected signatures %v", generic.GetSignatures())
+	}
+	if err := attestation.VerifyPKIXSignature(pemPublicKey(t, &key.PublicKey), payload, generic.GetSignatures()[0].GetSignature()); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+	if _, err := NewGeneric(ecdsaSigner{err: fmt.Errorf("unavailable")}).Attest(context.Background(), payload); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestPGPAttest(t *testing.T) {
+	pub, priv := testutil.CreateKeyPair(t, "test")
+	pgpKey, err := secrets.NewPgpKey(priv, "", pub)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	a, err := NewPGP(pgpKey).Attest(context.Background(), []byte("payload"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	pgp := a.GetPgpSignedAttestation()
+	if pgp.GetPgpKeyId() != pgpKey.Fingerprint() {
+		t.Errorf("expected key ID %s, got %s", pgpKey.Fingerprint(), pgp.GetPgpKeyId())
+	}
+	if err := attestation.VerifyMessageAttestation(pub, pgp.GetSignature(), "payload"); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+}
@@ -0,0 +1,407 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/common"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// legacyKinds maps the kind names used by containeranalysis filters to note kinds.
+var legacyKinds = map[string]string{
+	"PACKAGE_VULNERABILITY": common.NoteKind_VULNERABILITY.String(),
+	"ATTESTATION_AUTHORITY": common.NoteKind_ATTESTATION.String(),
+	"BUILD_DETAILS":         common.NoteKind_BUILD.String(),
+}
+
+// FakeGrafeasServer is an in-memory GrafeasV1Beta1 server, used to test
+// Container Analysis clients without GCP.
+// Occurrences can be listed with filters on resource_url and kind, joined by AND.
+type FakeGrafeasServer struct {
+	grafeas.UnimplementedGrafeasV1Beta1Server
+
+	// MaxPageSize caps the page size requested by clients, so that
+	// tests can exercise pagination.
+	MaxPageSize int
+
+	mu          sync.Mutex
+	notes       []*grafeas.Note
+	occurrences []*grafeas.Occurrence
+	lastID      int
+	listCalls   int
+
+	server   *grpc.Server
+	listener *bufconn.Listener
+}
+
+// NewFakeGrafeasServer starts a FakeGrafeasServer on an in-process listener.
+func NewFakeGrafeasServer() *FakeGrafeasServer {
+	f := &FakeGrafeasServer{
+		server:   grpc.NewServer(),
+		listener: bufconn.Listen(1 << 20),
+	}
+	grafeas.RegisterGrafeasV1Beta1Server(f.server, f)
+	go f.server.Serve(f.listener)
+	return f
+}
+
+// Conn returns a client connection to the server.
+func (f *FakeGrafeasServer) Conn(ctx context.Context) (*grpc.ClientConn, error) {
+	return grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return f.listener.Dial()
+		}),
+		grpc.WithInsecure())
+}
+
+// Stop stops the server.
+func (f *FakeGrafeasServer) Stop() {
+	f.server.Stop()
+}
+
+// ListCalls returns the number of ListOccurrences calls served, one per page.
+func (f *FakeGrafeasServer) ListCalls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.listCalls
+}
+
+// GetOccurrence returns the named occurrence.
+func (f *FakeGrafeasServer) GetOccurrence(ctx context.Context, req *grafeas.GetOccurrenceRequest) (*grafeas.Occurrence, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := f.occurrence(req.GetName())
+	if i < 0 {
+		return nil, status.Errorf(codes.NotFound, "occurrence %q not found", req.GetName())
+	}
+	return proto.Clone(f.occurrences[i]).(*grafeas.Occurrence), nil
+}
+
+// ListOccurrences lists the occurrences in the parent project matching the filter.
+func (f *FakeGrafeasServer) ListOccurrences(ctx context.Context, req *grafeas.ListOccurrencesRequest) (*grafeas.ListOccurrencesResponse, error) {
+	match, err := parseFilter(req.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listCalls++
+	var occs []*grafeas.Occurrence
+	for _, occ := range f.occurrences {
+		if strings.HasPrefix(occ.GetName(), req.GetParent()+"/") && match(occ) {
+			occs = append(occs, occ)
+		}
+	}
+	start, end, next, err := f.page(len(occs), req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	resp := &grafeas.ListOccurrencesResponse{NextPageToken: next}
+	for _, occ := range occs[start:end] {
+		resp.Occurrences = append(resp.Occurrences, proto.Clone(occ).(*grafeas.Occurrence))
+	}
+	return resp, nil
+}
+
+// DeleteOccurrence deletes the named occurrence.
+func (f *FakeGrafeasServer) DeleteOccurrence(ctx context.Context, req *grafeas.DeleteOccurrenceRequest) (*empty.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := f.occurrence(req.GetName())
+	if i < 0 {
+		return nil, status.Errorf(codes.NotFound, "occurrence %q not found", req.GetName())
+	}
+	f.occurrences = append(f.occurrences[:i], f.occurrences[i+1:]...)
+	return &empty.Empty{}, nil
+}
+
+// CreateOccurrence creates an occurrence in the parent project.
+func (f *FakeGrafeasServer) CreateOccurrence(ctx context.Context, req *grafeas.CreateOccurrenceRequest) (*grafeas.Occurrence, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.createOccurrence(req.GetParent(), req.GetOccurrence())
+}
+
+// BatchCreateOccurrences creates occurrences in the parent project.
+func (f *FakeGrafeasServer) BatchCreateOccurrences(ctx context.Context, req *grafeas.BatchCreateOccurrencesRequest) (*grafeas.BatchCreateOccurrencesResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &grafeas.BatchCreateOccurrencesResponse{}
+	for _, occ := range req.GetOccurrences() {
+		created, err := f.createOccurrence(req.GetParent(), occ)
+		if err != nil {
+			return nil, err
+		}
+		resp.Occurrences = append(resp.Occurrences, created)
+	}
+	return resp, nil
+}
+
+// UpdateOccurrence replaces the named occurrence. The update mask is ignored.
+func (f *FakeGrafeasServer) UpdateOccurrence(ctx context.Context, req *grafeas.UpdateOccurrenceRequest) (*grafeas.Occurrence, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := f.occurrence(req.GetName())
+	if i < 0 {
+		return nil, status.Errorf(codes.NotFound, "occurrence %q not found", req.GetName())
+	}
+	occ := proto.Clone(req.GetOccurrence()).(*grafeas.Occurrence)
+	occ.Name = req.GetName()
+	occ.Kind = occurrenceKind(occ)
+	f.occurrences[i] = occ
+	return proto.Clone(occ).(*grafeas.Occurrence), nil
+}
+
+// GetOccurrenceNote returns the note attached to the named occurrence.
+func (f *FakeGrafeasServer) GetOccurrenceNote(ctx context.Context, req *grafeas.GetOccurrenceNoteRequest) (*grafeas.Note, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := f.occurrence(req.GetName())
+	if i < 0 {
+		return nil, status.Errorf(codes.NotFound, "occurrence %q not found", req.GetName())
+	}
+	j := f.note(f.occurrences[i].GetNoteName())
+	if j < 0 {
+		return nil, status.Errorf(codes.NotFound, "note %q not found", f.occurrences[i].GetNoteName())
+	}
+	return proto.Clone(f.notes[j]).(*grafeas.Note), nil
+}
+
+// GetNote returns the named note.
+func (f *FakeGrafeasServer) GetNote(ctx context.Context, req *grafeas.GetNoteRequest) (*grafeas.Note, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := f.note(req.GetName())
+	if i < 0 {
+		return nil, status.Errorf(codes.NotFound, "note %q not found", req.GetName())
+	}
+	return proto.Clone(f.notes[i]).(*grafeas.Note), nil
+}
+
+// ListNotes lists the notes in the parent project. Filters are not supported.
+func (f *FakeGrafeasServer) ListNotes(ctx context.Context, req *grafeas.ListNotesRequest) (*grafeas.ListNotesResponse, error) {
+	if req.GetFilter() != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "note filters are not supported")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var notes []*grafeas.Note
+	for _, n := range f.notes {
+		if strings.HasPrefix(n.GetName(), req.GetParent()+"/") {
+			notes = append(notes, n)
+		}
+	}
+	start, end, next, err := f.page(len(notes), req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	resp := &grafeas.ListNotesResponse{NextPageToken: next}
+	for _, n := range notes[start:end] {
+		resp.Notes = append(resp.Notes, proto.Clone(n).(*grafeas.Note))
+	}
+	return resp, nil
+}
+
+// DeleteNote deletes the named note.
+func (f *FakeGrafeasServer) DeleteNote(ctx context.Context, req *grafeas.DeleteNoteRequest) (*empty.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := f.note(req.GetName())
+	if i < 0 {
+		return nil, status.Errorf(codes.NotFound, "note %q not found", req.GetName())
+	}
+	f.notes = append(f.notes[:i], f.notes[i+1:]...)
+	return &empty.Empty{}, nil
+}
+
+// CreateNote creates a note in the parent project.
+func (f *FakeGrafeasServer) CreateNote(ctx context.Context, req *grafeas.CreateNoteRequest) (*grafeas.Note, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.createNote(req.GetParent(), req.GetNoteId(), req.GetNote())
+}
+
+// BatchCreateNotes creates notes in the parent project.
+func (f *FakeGrafeasServer) BatchCreateNotes(ctx context.Context, req *grafeas.BatchCreateNotesRequest) (*grafeas.BatchCreateNotesResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &grafeas.BatchCreateNotesResponse{}
+	for id, n := range req.GetNotes() {
+		created, err := f.createNote(req.GetParent(), id, n)
+		if err != nil {
+			return nil, err
+		}
+		resp.Notes = append(resp.Notes, created)
+	}
+	return resp, nil
+}
+
+// UpdateNote replaces the named note. The update mask is ignored.
+func (f *FakeGrafeasServer) UpdateNote(ctx context.Context, req *grafeas.UpdateNoteRequest) (*grafeas.Note, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := f.note(req.GetName())
+	if i < 0 {
+		return nil, status.Errorf(codes.NotFound, "note %q not found", req.GetName())
+	}
+	n := proto.Clone(req.GetNote()).(*grafeas.Note)
+	n.Name = req.GetName()
+	f.notes[i] = n
+	return proto.Clone(n).(*grafeas.Note), nil
+}
+
+func (f *FakeGrafeasServer) createOccurrence(parent string, occ *grafeas.Occurrence) (*grafeas.Occurrence, error) {
+	if occ == nil || occ.GetResource().GetUri() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "occurrence resource is required")
+	}
+	f.lastID++
+	occ = proto.Clone(occ).(*grafeas.Occurrence)
+	occ.Name = fmt.Sprintf("%s/occurrences/%d", parent, f.lastID)
+	occ.Kind = occurrenceKind(occ)
+	occ.CreateTime = timestamppb.Now()
+	f.occurrences = append(f.occurrences, occ)
+	return proto.Clone(occ).(*grafeas.Occurrence), nil
+}
+
+func (f *FakeGrafeasServer) createNote(parent string, id string, n *grafeas.Note) (*grafeas.Note, error) {
+	if n == nil || id == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "note and note id are required")
+	}
+	name := fmt.Sprintf("%s/notes/%s", parent, id)
+	if f.note(name) >= 0 {
+		return nil, status.Errorf(codes.AlreadyExists, "note %q already exists", name)
+	}
+	n = proto.Clone(n).(*grafeas.Note)
+	n.Name = name
+	f.notes = append(f.notes, n)
+	return proto.Clone(n).(*grafeas.Note), nil
+}
+
+func (f *FakeGrafeasServer) occurrence(name string) int {
+	for i, occ := range f.occurrences {
+		if occ.GetName() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *FakeGrafeasServer) note(name string) int {
+	for i, n := range f.notes {
+		if n.GetName() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// page returns the bounds of the page of n items starting at token, and the
+// token of the next page. Tokens are offsets into the listing.
+func (f *FakeGrafeasServer) page(n int, size int32, token string) (int, int, string, error) {
+	start := 0
+	if token != "" {
+		var err error
+		if start, err = strconv.Atoi(token); err != nil || start < 0 || start > n {
+			return 0, 0, "", status.Errorf(codes.InvalidArgument, "invalid page token %q", token)
+		}
+	}
+	limit := int(size)
+	if f.MaxPageSize > 0 && (limit <= 0 || limit > f.MaxPageSize) {
+		limit = f.MaxPageSize
+	}
+	if limit <= 0 || start+limit >= n {
+		return start, n, "", nil
+	}
+	return start, start + limit, strconv.Itoa(start + limit), nil
+}
+
+// occurrenceKind returns the note kind matching the occurrence details.
+func occurrenceKind(occ *grafeas.Occurrence) common.NoteKind {
+	switch occ.GetDetails().(type) {
+	case *grafeas.Occurrence_Vulnerability:
+		return common.NoteKind_VULNERABILITY
+	case *grafeas.Occurrence_Build:
+		return common.NoteKind_BUILD
+	case *grafeas.Occurrence_DerivedImage:
+		return common.NoteKind_IMAGE
+	case *grafeas.Occurrence_Installation:
+		return common.NoteKind_PACKAGE
+	case *grafeas.Occurrence_Deployment:
+		return common.NoteKind_DEPLOYMENT
+	case *grafeas.Occurrence_Discovered:
+		return common.NoteKind_DISCOVERY
+	case *grafeas.Occurrence_Attestation:
+		return common.NoteKind_ATTESTATION
+	}
+	return common.NoteKind_NOTE_KIND_UNSPECIFIED
+}
+
+// parseFilter parses filters of the form `key="value" AND key="value"`,
+// where key is resource_url or kind.
+func parseFilter(filter string) (func(*grafeas.Occurrence) bool, error) {
+	var matchers []func(*grafeas.Occurrence) bool
+	if strings.TrimSpace(filter) == "" {
+		return func(*grafeas.Occurrence) bool { return true }, nil
+	}
+	for _, clause := range strings.Split(filter, " AND ") {
+		parts := strings.SplitN(strings.TrimSpace(clause), "=", 2)
+		if len(parts) != 2 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid filter clause %q", clause)
+		}
+		value, err := strconv.Unquote(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid filter value in %q", clause)
+		}
+		switch strings.TrimSpace(parts[0]) {
+		case "resource_url":
+			matchers = append(matchers, func(occ *grafeas.Occurrence) bool {
+				return occ.GetResource().GetUri() == value
+			})
+		case "kind":
+			if k, ok := legacyKinds[value]; ok {
+				value = k
+			}
+			matchers = append(matchers, func(occ *grafeas.Occurrence) bool {
+				return occ.GetKind().String() == value
+			})
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unsupported filter key in %q", clause)
+		}
+	}
+	return func(occ *grafeas.Occurrence) bool {
+		for _, m := range matchers {
+			if !m(occ) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
@@ -17,7 +17,10 @@ limitations under the License.
 package testutil
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
@@ -25,22 +28,114 @@ import (
 	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
 )
 
+// MockMetadataClient is a metadata.Fetcher serving fixed metadata.
+// Vulnz, PGPAttestations, Build and OccurrencesV1 are served for all images
+// without an entry in Images. It is safe for concurrent use.
 type MockMetadataClient struct {
 	Vulnz           []metadata.Vulnerability
 	PGPAttestations []metadata.PGPAttestation
 	Build           []metadata.Build
+	OccurrencesV1   []*metadata.OccurenceV1
 	Occ             map[string]string
+	Discovery       map[string]string
+	// Images holds the metadata served for specific images.
+	Images map[string]MockImage
+	// Errors holds the errors returned by successive calls to a method, by method name.
+	// Calls succeed once the errors of their method are used up.
+	Errors map[string][]error
+	// ScanStatuses holds the statuses returned by successive calls to ScanStatus.
+	// The last status is returned once they are used up.
+	ScanStatuses []metadata.ScanStatus
+	// Err is returned by all reads of image metadata when set.
+	Err error
+	// Latency delays all calls, or until their context is done.
+	Latency time.Duration
+
+	mu         sync.Mutex
+	calls      map[string]int
+	imageCalls map[string]map[string]int
+}
+
+// MockImage is the metadata MockMetadataClient serves for an image.
+type MockImage struct {
+	Vulnz           []metadata.Vulnerability
+	PGPAttestations []metadata.PGPAttestation
+	Build           []metadata.Build
+	OccurrencesV1   []*metadata.OccurenceV1
+}
+
+// Calls returns the number of calls made to method, for image or for all
+// images if image is empty.
+func (m *MockMetadataClient) Calls(method string, image string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if image == "" {
+		return m.calls[method]
+	}
+	return m.imageCalls[method][image]
+}
+
+// call records a call to method and returns its injected error, if any.
+func (m *MockMetadataClient) call(ctx context.Context, method string, image string) error {
+	m.mu.Lock()
+	if m.calls == nil {
+		m.calls = map[string]int{}
+		m.imageCalls = map[string]map[string]int{}
+	}
+	m.calls[method]++
+	if m.imageCalls[method] == nil {
+		m.imageCalls[method] = map[string]int{}
+	}
+	m.imageCalls[method][image]++
+	var err error
+	if errs := m.Errors[method]; len(errs) > 0 {
+		err, m.Errors[method] = errs[0], errs[1:]
+	}
+	m.mu.Unlock()
+	if m.Latency > 0 {
+		select {
+		case <-time.After(m.Latency):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+// read records a read of image metadata and returns the metadata served for image.
+func (m *MockMetadataClient) read(ctx context.Context, method string, image string) (MockImage, error) {
+	if err := m.call(ctx, method, image); err != nil {
+		return MockImage{}, err
+	}
+	if m.Err != nil {
+		return MockImage{}, m.Err
+	}
+	if i, ok := m.Images[image]; ok {
+		return i, nil
+	}
+	return MockImage{
+		Vulnz:           m.Vulnz,
+		PGPAttestations: m.PGPAttestations,
+		Build:           m.Build,
+		OccurrencesV1:   m.OccurrencesV1,
+	}, nil
 }
 
 func (m *MockMetadataClient) Close() {
 	// No Ops
 }
-func (m *MockMetadataClient) Vulnerabilities(containerImage string) ([]metadata.Vulnerability, error) {
-	return m.Vulnz, nil
+func (m *MockMetadataClient) Vulnerabilities(ctx context.Context, containerImage string) ([]metadata.Vulnerability, error) {
+	i, err := m.read(ctx, "Vulnerabilities", containerImage)
+	return i.Vulnz, err
 }
 
-func (m *MockMetadataClient) CreateAttestationOccurence(n *grafeas.Note, image string,
+func (m *MockMetadataClient) CreateAttestationOccurence(ctx context.Context, n *grafeas.Note, image string,
 	s *secrets.PGPSigningSecret) (*grafeas.Occurrence, error) {
+	if err := m.call(ctx, "CreateAttestationOccurence", image); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.Occ == nil {
 		m.Occ = map[string]string{}
 	}
@@ -48,7 +143,10 @@ func (m *MockMetadataClient) CreateAttestationOccurence(n *grafeas.Note, image s
 	return nil, nil
 }
 
-func (m *MockMetadataClient) AttestationNote(aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+func (m *MockMetadataClient) AttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	if err := m.call(ctx, "AttestationNote", ""); err != nil {
+		return nil, err
+	}
 	if aa == nil {
 		return nil, fmt.Errorf("could not get note")
 	}
@@ -57,22 +155,75 @@ func (m *MockMetadataClient) AttestationNote(aa *kritisv1beta1.AttestationAuthor
 	}, nil
 }
 
-func (m *MockMetadataClient) CreateAttestationNote(aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+func (m *MockMetadataClient) CreateAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	if err := m.call(ctx, "CreateAttestationNote", ""); err != nil {
+		return nil, err
+	}
 	return &grafeas.Note{
 		Name: aa.Spec.NoteReference,
 	}, nil
 }
 
-func (m *MockMetadataClient) Attestations(containerImage string) ([]metadata.PGPAttestation, error) {
-	return m.PGPAttestations, nil
+func (m *MockMetadataClient) Attestations(ctx context.Context, containerImage string) ([]metadata.PGPAttestation, error) {
+	i, err := m.read(ctx, "Attestations", containerImage)
+	return i.PGPAttestations, err
 }
 
-func (m *MockMetadataClient) OccurencesV1(containerImage string) ([]*metadata.OccurenceV1, error) {
-	return nil, nil
+func (m *MockMetadataClient) OccurencesV1(ctx context.Context, containerImage string) ([]*metadata.OccurenceV1, error) {
+	i, err := m.read(ctx, "OccurencesV1", containerImage)
+	return i.OccurrencesV1, err
+}
+
+func (m *MockMetadataClient) Builds(ctx context.Context, containerImage string) ([]metadata.Build, error) {
+	i, err := m.read(ctx, "Builds", containerImage)
+	return i.Build, err
+}
+
+func (m *MockMetadataClient) DiscoveryNote(ctx context.Context, image string, noteID string) (*grafeas.Note, error) {
+	if err := m.call(ctx, "DiscoveryNote", image); err != nil {
+		return nil, err
+	}
+	return &grafeas.Note{
+		Name: noteID,
+	}, nil
+}
+
+func (m *MockMetadataClient) CreateDiscoveryNote(ctx context.Context, image string, noteID string) (*grafeas.Note, error) {
+	if err := m.call(ctx, "CreateDiscoveryNote", image); err != nil {
+		return nil, err
+	}
+	return &grafeas.Note{
+		Name: noteID,
+	}, nil
 }
 
-func (m *MockMetadataClient) Builds(containerImage string) ([]metadata.Build, error) {
-	return m.Build, nil
+func (m *MockMetadataClient) ScanStatus(ctx context.Context, image string) (metadata.ScanStatus, error) {
+	if _, err := m.read(ctx, "ScanStatus", image); err != nil {
+		return metadata.ScanStatusUnknown, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.ScanStatuses) == 0 {
+		return metadata.ScanStatusUnknown, nil
+	}
+	s := m.ScanStatuses[0]
+	if len(m.ScanStatuses) > 1 {
+		m.ScanStatuses = m.ScanStatuses[1:]
+	}
+	return s, nil
+}
+
+func (m *MockMetadataClient) CreateDiscoveryOccurrence(ctx context.Context, n *grafeas.Note, image string, message string) (*grafeas.Occurrence, error) {
+	if err := m.call(ctx, "CreateDiscoveryOccurrence", image); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Discovery == nil {
+		m.Discovery = map[string]string{}
+	}
+	m.Discovery[fmt.Sprintf("%s-%s", image, n.Name)] = message
+	return nil, nil
 }
 
 func NilFetcher() func() (metadata.Fetcher, error) {
@@ -0,0 +1,55 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+)
+
+func TestMockMetadataClient(t *testing.T) {
+	m := &MockMetadataClient{
+		Vulnz:  []metadata.Vulnerability{{CVE: "default"}},
+		Images: map[string]MockImage{"image": {Vulnz: []metadata.Vulnerability{{CVE: "image"}}}},
+		Errors: map[string][]error{"Vulnerabilities": {nil, fmt.Errorf("unavailable")}},
+	}
+	ctx := context.Background()
+	v, err := m.Vulnerabilities(ctx, "image")
+	CheckErrorAndDeepEqual(t, false, err, []metadata.Vulnerability{{CVE: "image"}}, v)
+	_, err = m.Vulnerabilities(ctx, "image")
+	CheckError(t, true, err)
+	v, err = m.Vulnerabilities(ctx, "other")
+	CheckErrorAndDeepEqual(t, false, err, []metadata.Vulnerability{{CVE: "default"}}, v)
+	DeepEqual(t, 2, m.Calls("Vulnerabilities", "image"))
+	DeepEqual(t, 3, m.Calls("Vulnerabilities", ""))
+
+	m.Err = fmt.Errorf("down")
+	_, err = m.Attestations(ctx, "image")
+	CheckError(t, true, err)
+}
+
+func TestMockMetadataClientLatency(t *testing.T) {
+	m := &MockMetadataClient{Latency: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := m.Builds(ctx, "image")
+	CheckErrorAndDeepEqual(t, true, err, context.DeadlineExceeded, err)
+}
@@ -17,9 +17,11 @@ limitations under the License.
 package testutil
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
 	"k8s.io/api/core/v1"
 )
 
@@ -41,3 +43,22 @@ func (r *ReviewerMock) Review(images []string, isps []v1beta1.ImageSecurityPolic
 	}
 	return fmt.Errorf(r.message)
 }
+
+func (r *ReviewerMock) ReviewContext(ctx context.Context, images []string, isps []v1beta1.ImageSecurityPolicy, pod *v1.Pod) error {
+	return r.Review(images, isps, pod)
+}
+
+func (r *ReviewerMock) ReviewGAP(ctx context.Context, images []string, gaps []v1beta1.GenericAttestationPolicy, pod *v1.Pod) error {
+	return nil
+}
+
+func (r *ReviewerMock) Validate(ctx context.Context, image string, isps []v1beta1.ImageSecurityPolicy) ([][]policy.Violation, error) {
+	return make([][]policy.Violation, len(isps)), nil
+}
+
+func (r *ReviewerMock) ValidateGAP(ctx context.Context, image string, gaps []v1beta1.GenericAttestationPolicy) ([][]policy.Violation, error) {
+	return make([][]policy.Violation, len(gaps)), nil
+}
+
+func (r *ReviewerMock) Promote(ctx context.Context, images []string, isps []v1beta1.ImageSecurityPolicy, pod *v1.Pod) {
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// KeyPair serves the certificate of a certificate and key file, read again when either
+// file changes, e.g. when the Secret they are mounted from is updated.
+type KeyPair struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// NewKeyPair returns a KeyPair serving the certificate of certFile and keyFile.
+func NewKeyPair(certFile, keyFile string) (*KeyPair, error) {
+	k := &KeyPair{certFile: certFile, keyFile: keyFile}
+	modTime, err := k.modified()
+	if err != nil {
+		return nil, err
+	}
+	if err := k.load(modTime); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// GetCertificate returns the current certificate, see tls.Config.GetCertificate. The
+// previous certificate is served if the files changed but can't be loaded.
+func (k *KeyPair) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	modTime, err := k.modified()
+	if err == nil && modTime.After(k.modTime) {
+		err = k.load(modTime)
+	}
+	if err != nil {
+		glog.Errorf("serving the previous certificate: %v", err)
+	}
+	return k.cert, nil
+}
+
+// modified returns the latest modification time of the files.
+func (k *KeyPair) modified() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{k.certFile, k.keyFile} {
+		info, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "failed to read the TLS certificate")
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (k *KeyPair) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(k.certFile, k.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load the TLS certificate")
+	}
+	k.cert, k.modTime = &cert, modTime
+	return nil
+}
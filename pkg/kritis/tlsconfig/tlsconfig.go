@@ -0,0 +1,121 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tlsconfig builds the TLS configuration shared by all servers run by Kritis.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/pkg/errors"
+)
+
+var (
+	// DefaultCipherSuites are the TLS 1.2 cipher suites allowed if none are configured.
+	DefaultCipherSuites = []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+	}
+
+	versions = map[string]uint16{
+		"":    tls.VersionTLS12,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+
+	clientAuthTypes = map[string]tls.ClientAuthType{
+		"":                 tls.NoClientCert,
+		"none":             tls.NoClientCert,
+		"request":          tls.RequestClientCert,
+		"require":          tls.RequireAnyClientCert,
+		"verifyIfGiven":    tls.VerifyClientCertIfGiven,
+		"requireAndVerify": tls.RequireAndVerifyClientCert,
+	}
+)
+
+// New returns a server TLS configuration for spec. It defaults to TLS 1.2 with
+// DefaultCipherSuites and no client certificates.
+func New(spec kritisv1beta1.TLSConfigSpec) (*tls.Config, error) {
+	minVersion, ok := versions[spec.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported minimum TLS version %q", spec.MinVersion)
+	}
+	ciphers, err := cipherSuites(spec.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	clientAuth, ok := clientAuthTypes[spec.ClientAuth]
+	if !ok {
+		return nil, fmt.Errorf("unsupported client auth %q", spec.ClientAuth)
+	}
+	config := &tls.Config{
+		MinVersion:   minVersion,
+		CipherSuites: ciphers,
+		ClientAuth:   clientAuth,
+	}
+	if clientAuth >= tls.VerifyClientCertIfGiven {
+		if spec.ClientCAPath == "" {
+			return nil, fmt.Errorf("client auth %q requires a client CA", spec.ClientAuth)
+		}
+		pool, err := certPool(spec.ClientCAPath)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+	}
+	return config, nil
+}
+
+// cipherSuites returns the IDs of the named cipher suites. Only suites without
+// known security issues are accepted.
+func cipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return DefaultCipherSuites, nil
+	}
+	secure := map[string]uint16{}
+	for _, c := range tls.CipherSuites() {
+		secure[c.Name] = c.ID
+	}
+	var ids []uint16
+	for _, n := range names {
+		id, ok := secure[n]
+		if !ok {
+			return nil, fmt.Errorf("unsupported or insecure cipher suite %q", n)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func certPool(path string) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read client CA %s", path)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in client CA %s", path)
+	}
+	return pool, nil
+}
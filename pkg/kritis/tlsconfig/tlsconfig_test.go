@@ -0,0 +1,205 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func TestNew(t *testing.T) {
+	caPath := writeCA(t)
+	defer os.Remove(caPath)
+
+	tcs := []struct {
+		name       string
+		spec       kritisv1beta1.TLSConfigSpec
+		shouldErr  bool
+		minVersion uint16
+		ciphers    []uint16
+		clientAuth tls.ClientAuthType
+	}{
+		{
+			name:       "defaults",
+			minVersion: tls.VersionTLS12,
+			ciphers:    DefaultCipherSuites,
+			clientAuth: tls.NoClientCert,
+		},
+		{
+			name: "tls 1.3 and cipher allowlist",
+			spec: kritisv1beta1.TLSConfigSpec{
+				MinVersion:   "1.3",
+				CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+				ClientAuth:   "request",
+			},
+			minVersion: tls.VersionTLS13,
+			ciphers:    []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+			clientAuth: tls.RequestClientCert,
+		},
+		{
+			name: "verified client certificates",
+			spec: kritisv1beta1.TLSConfigSpec{
+				ClientAuth:   "requireAndVerify",
+				ClientCAPath: caPath,
+			},
+			minVersion: tls.VersionTLS12,
+			ciphers:    DefaultCipherSuites,
+			clientAuth: tls.RequireAndVerifyClientCert,
+		},
+		{
+			name:      "old version",
+			spec:      kritisv1beta1.TLSConfigSpec{MinVersion: "1.0"},
+			shouldErr: true,
+		},
+		{
+			name:      "insecure cipher",
+			spec:      kritisv1beta1.TLSConfigSpec{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+			shouldErr: true,
+		},
+		{
+			name:      "unknown client auth",
+			spec:      kritisv1beta1.TLSConfigSpec{ClientAuth: "always"},
+			shouldErr: true,
+		},
+		{
+			name:      "verified client certificates without CA",
+			spec:      kritisv1beta1.TLSConfigSpec{ClientAuth: "verifyIfGiven"},
+			shouldErr: true,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := New(tc.spec)
+			testutil.CheckError(t, tc.shouldErr, err)
+			if err != nil {
+				return
+			}
+			testutil.DeepEqual(t, tc.minVersion, config.MinVersion)
+			testutil.DeepEqual(t, tc.ciphers, config.CipherSuites)
+			testutil.DeepEqual(t, tc.clientAuth, config.ClientAuth)
+			if (tc.spec.ClientCAPath != "") != (config.ClientCAs != nil) {
+				t.Errorf("unexpected client CAs %v", config.ClientCAs)
+			}
+		})
+	}
+}
+
+func TestKeyPair(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := dir+"/tls.crt", dir+"/tls.key"
+	writeKeyPair(t, certFile, keyFile, "first")
+	k, err := NewKeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject := func() string {
+		cert, err := k.GetCertificate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.Subject.CommonName
+	}
+	testutil.DeepEqual(t, "first", subject())
+
+	// Updated files are loaded again, invalid ones are ignored
+	writeKeyPair(t, certFile, keyFile, "second")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	testutil.DeepEqual(t, "second", subject())
+	if err := ioutil.WriteFile(keyFile, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later = later.Add(time.Minute)
+	os.Chtimes(keyFile, later, later)
+	testutil.DeepEqual(t, "second", subject())
+
+	if _, err := NewKeyPair(dir+"/missing.crt", keyFile); err == nil {
+		t.Error("expected error for a missing certificate")
+	}
+}
+
+func writeKeyPair(t *testing.T, certFile, keyFile, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeCA(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := ioutil.TempFile("", "ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
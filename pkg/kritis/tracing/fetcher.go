@@ -0,0 +1,136 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/secrets"
+)
+
+const imageKey = attribute.Key("kritis.image")
+
+// tracingFetcher wraps a Fetcher and records a span for each call to the backend.
+type tracingFetcher struct {
+	metadata.Fetcher
+}
+
+// NewFetcher returns a Fetcher recording its calls as children of the span in their context.
+func NewFetcher(f metadata.Fetcher) metadata.Fetcher {
+	return tracingFetcher{Fetcher: f}
+}
+
+// start starts the span of a call.
+func (t tracingFetcher) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Start(ctx, "metadata."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+}
+
+// Vulnerabilities records a span while getting vulnerabilities.
+func (t tracingFetcher) Vulnerabilities(ctx context.Context, containerImage string) ([]metadata.Vulnerability, error) {
+	ctx, span := t.start(ctx, "Vulnerabilities", imageKey.String(containerImage))
+	v, err := t.Fetcher.Vulnerabilities(ctx, containerImage)
+	End(span, err)
+	return v, err
+}
+
+// Attestations records a span while getting attestations.
+func (t tracingFetcher) Attestations(ctx context.Context, containerImage string) ([]metadata.PGPAttestation, error) {
+	ctx, span := t.start(ctx, "Attestations", imageKey.String(containerImage))
+	a, err := t.Fetcher.Attestations(ctx, containerImage)
+	End(span, err)
+	return a, err
+}
+
+// OccurencesV1 records a span while getting V1 occurrences.
+func (t tracingFetcher) OccurencesV1(ctx context.Context, containerImage string) ([]*metadata.OccurenceV1, error) {
+	ctx, span := t.start(ctx, "OccurencesV1", imageKey.String(containerImage))
+	o, err := t.Fetcher.OccurencesV1(ctx, containerImage)
+	End(span, err)
+	return o, err
+}
+
+// Builds records a span while getting builds.
+func (t tracingFetcher) Builds(ctx context.Context, containerImage string) ([]metadata.Build, error) {
+	ctx, span := t.start(ctx, "Builds", imageKey.String(containerImage))
+	b, err := t.Fetcher.Builds(ctx, containerImage)
+	End(span, err)
+	return b, err
+}
+
+// AttestationNote records a span while getting an attestation note.
+func (t tracingFetcher) AttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	ctx, span := t.start(ctx, "AttestationNote", attribute.String("kritis.attestation_authority", aa.Name))
+	n, err := t.Fetcher.AttestationNote(ctx, aa)
+	End(span, err)
+	return n, err
+}
+
+// CreateAttestationNote records a span while creating an attestation note.
+func (t tracingFetcher) CreateAttestationNote(ctx context.Context, aa *kritisv1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	ctx, span := t.start(ctx, "CreateAttestationNote", attribute.String("kritis.attestation_authority", aa.Name))
+	n, err := t.Fetcher.CreateAttestationNote(ctx, aa)
+	End(span, err)
+	return n, err
+}
+
+// CreateAttestationOccurence records a span while creating an attestation occurrence.
+func (t tracingFetcher) CreateAttestationOccurence(ctx context.Context, note *grafeas.Note, containerImage string, pgpSigningKey *secrets.PGPSigningSecret) (*grafeas.Occurrence, error) {
+	ctx, span := t.start(ctx, "CreateAttestationOccurence", imageKey.String(containerImage))
+	o, err := t.Fetcher.CreateAttestationOccurence(ctx, note, containerImage, pgpSigningKey)
+	End(span, err)
+	return o, err
+}
+
+// DiscoveryNote records a span while getting a discovery note.
+func (t tracingFetcher) DiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	ctx, span := t.start(ctx, "DiscoveryNote", imageKey.String(containerImage))
+	n, err := t.Fetcher.DiscoveryNote(ctx, containerImage, noteID)
+	End(span, err)
+	return n, err
+}
+
+// CreateDiscoveryNote records a span while creating a discovery note.
+func (t tracingFetcher) CreateDiscoveryNote(ctx context.Context, containerImage string, noteID string) (*grafeas.Note, error) {
+	ctx, span := t.start(ctx, "CreateDiscoveryNote", imageKey.String(containerImage))
+	n, err := t.Fetcher.CreateDiscoveryNote(ctx, containerImage, noteID)
+	End(span, err)
+	return n, err
+}
+
+// ScanStatus records a span while getting the status of the scan of an image.
+func (t tracingFetcher) ScanStatus(ctx context.Context, containerImage string) (metadata.ScanStatus, error) {
+	ctx, span := t.start(ctx, "ScanStatus", imageKey.String(containerImage))
+	s, err := t.Fetcher.ScanStatus(ctx, containerImage)
+	End(span, err)
+	return s, err
+}
+
+// CreateDiscoveryOccurrence records a span while creating a discovery occurrence.
+func (t tracingFetcher) CreateDiscoveryOccurrence(ctx context.Context, note *grafeas.Note, containerImage string, message string) (*grafeas.Occurrence, error) {
+	ctx, span := t.start(ctx, "CreateDiscoveryOccurrence", imageKey.String(containerImage))
+	o, err := t.Fetcher.CreateDiscoveryOccurrence(ctx, note, containerImage, message)
+	End(span, err)
+	return o, err
+}
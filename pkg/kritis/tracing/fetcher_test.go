@@ -0,0 +1,82 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/grafeas/kritis/pkg/kritis/metadata"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+// contextFetcher records the context of its calls.
+type contextFetcher struct {
+	*testutil.MockMetadataClient
+	ctxs *[]context.Context
+}
+
+func (c contextFetcher) Vulnerabilities(ctx context.Context, image string) ([]metadata.Vulnerability, error) {
+	*c.ctxs = append(*c.ctxs, ctx)
+	return nil, fmt.Errorf("no vulnerabilities for %s", image)
+}
+
+func (c contextFetcher) Builds(ctx context.Context, image string) ([]metadata.Build, error) {
+	*c.ctxs = append(*c.ctxs, ctx)
+	return nil, nil
+}
+
+func TestFetcher(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	var ctxs []context.Context
+	ctx, parent := Start(context.Background(), "parent")
+	f := NewFetcher(contextFetcher{MockMetadataClient: &testutil.MockMetadataClient{}, ctxs: &ctxs})
+	_, vulnErr := f.Vulnerabilities(ctx, testutil.QualifiedImage)
+	_, buildErr := f.Builds(ctx, testutil.QualifiedImage)
+	parent.End()
+	testutil.CheckError(t, true, vulnErr)
+	testutil.CheckError(t, false, buildErr)
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	var names []string
+	for i, s := range spans[:2] {
+		names = append(names, s.Name())
+		if s.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %s is not a child of the review span", s.Name())
+		}
+		// The wrapped Fetcher is called with the context of its span.
+		if trace.SpanContextFromContext(ctxs[i]).SpanID() != s.SpanContext().SpanID() {
+			t.Errorf("%s was not called with the context of its span", s.Name())
+		}
+	}
+	testutil.DeepEqual(t, []string{"metadata.Vulnerabilities", "metadata.Builds"}, names)
+	testutil.DeepEqual(t, []codes.Code{codes.Error, codes.Unset}, []codes.Code{spans[0].Status().Code, spans[1].Status().Code})
+}
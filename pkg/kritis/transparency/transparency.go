@@ -0,0 +1,238 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package transparency keeps a record of the attestations created by Kritis,
+// so security teams can audit which images the signer has vouched for.
+package transparency
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/grafeas/kritis/pkg/kritis/reference"
+)
+
+// Record describes an attestation created by Kritis.
+type Record struct {
+	Image      string    `json:"image"`
+	Digest     string    `json:"digest,omitempty"`
+	Note       string    `json:"note"`
+	Authority  string    `json:"authority"`
+	KeyID      string    `json:"keyId"`
+	Namespace  string    `json:"namespace"`
+	Policy     string    `json:"policy"`
+	CreateTime time.Time `json:"createTime"`
+}
+
+// Filter selects records, empty fields match all records.
+type Filter struct {
+	// Image matches records of the same image if it has a digest, otherwise of the same repository.
+	Image     string
+	Namespace string
+	Policy    string
+	Since     time.Time
+}
+
+const (
+	// DefaultPath is the file the attestations are recorded to if the KritisConfig sets none.
+	DefaultPath = "/var/lib/kritis/attestations.log"
+	// DefaultMaxRecords is the number of records kept if the KritisConfig sets no limit.
+	DefaultMaxRecords = 10000
+)
+
+// For testing
+var now = time.Now
+
+// Log holds records in memory, and appends them to a file if it has a path.
+// Only the latest maxRecords records are kept, the file is rewritten with them
+// once it holds a tenth more.
+type Log struct {
+	mu         sync.Mutex
+	path       string
+	maxRecords int
+	records    []Record
+}
+
+// New returns a Log persisted at path, loading the records already written to it.
+// Records are only kept in memory if path is empty. At most maxRecords records are
+// kept, DefaultMaxRecords if it is not positive.
+func New(path string, maxRecords int) (*Log, error) {
+	if maxRecords <= 0 {
+		maxRecords = DefaultMaxRecords
+	}
+	l := &Log{path: path, maxRecords: maxRecords}
+	if path == "" {
+		return l, nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open attestation log %s", path)
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var r Record
+		if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse attestation log %s", path)
+		}
+		l.records = append(l.records, r)
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read attestation log %s", path)
+	}
+	if len(l.records) > maxRecords {
+		l.records = l.records[len(l.records)-maxRecords:]
+	}
+	return l, nil
+}
+
+// Append adds a record to the log. The record is timestamped if it has no CreateTime.
+func (l *Log) Append(r Record) error {
+	if r.CreateTime.IsZero() {
+		r.CreateTime = now().UTC()
+	}
+	if r.Digest == "" {
+		if ref, err := reference.Parse(r.Image); err == nil {
+			r.Digest = ref.Digest
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.path != "" {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return errors.Wrapf(err, "failed to open attestation log %s", l.path)
+		}
+		if _, err := f.Write(append(b, '\n')); err != nil {
+			f.Close()
+			return errors.Wrapf(err, "failed to write attestation log %s", l.path)
+		}
+		if err := f.Close(); err != nil {
+			return errors.Wrapf(err, "failed to write attestation log %s", l.path)
+		}
+	}
+	l.records = append(l.records, r)
+	if len(l.records) > l.maxRecords+l.maxRecords/10 {
+		return l.compact()
+	}
+	return nil
+}
+
+// compact drops the oldest records, and rewrites the file with the others.
+func (l *Log) compact() error {
+	l.records = append([]Record(nil), l.records[len(l.records)-l.maxRecords:]...)
+	if l.path == "" {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, r := range l.records {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(b, '\n'))
+	}
+	tmp := l.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return errors.Wrapf(err, "failed to rotate attestation log %s", l.path)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return errors.Wrapf(err, "failed to rotate attestation log %s", l.path)
+	}
+	return nil
+}
+
+// Records returns the records matching the filter, oldest first.
+func (l *Log) Records(filter Filter) []Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	records := []Record{}
+	for _, r := range l.records {
+		if filter.matches(r) {
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+func (f Filter) matches(r Record) bool {
+	if f.Namespace != "" && f.Namespace != r.Namespace {
+		return false
+	}
+	if f.Policy != "" && f.Policy != r.Policy {
+		return false
+	}
+	if !f.Since.IsZero() && r.CreateTime.Before(f.Since) {
+		return false
+	}
+	if f.Image == "" {
+		return true
+	}
+	if reference.HasDigest(f.Image) {
+		return reference.Equal(f.Image, r.Image)
+	}
+	return reference.SameRepository(f.Image, r.Image)
+}
+
+// Handler serves the records of the log as JSON. Records are filtered with the
+// "image", "namespace", "policy" and "since" (RFC 3339) query parameters.
+func Handler(l *Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		filter := Filter{
+			Image:     q.Get("image"),
+			Namespace: q.Get("namespace"),
+			Policy:    q.Get("policy"),
+		}
+		if since := q.Get("since"); since != "" {
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			filter.Since = t
+		}
+		payload, err := json.Marshal(l.Records(filter))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(payload); err != nil {
+			glog.Errorf("unable to write attestation records: %v", err)
+		}
+	}
+}
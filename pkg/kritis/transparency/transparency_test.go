@@ -0,0 +1,140 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transparency
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+const digest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+func TestLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transparency")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "attestations.log")
+
+	created := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return created }
+	defer func() { now = time.Now }()
+
+	l, err := New(path, 0)
+	testutil.CheckError(t, false, err)
+	records := []Record{
+		{Image: "gcr.io/foo/bar@" + digest, Note: "projects/foo/notes/a", Authority: "a", Namespace: "ns", Policy: "isp"},
+		{Image: "gcr.io/foo/baz@" + digest, Note: "projects/foo/notes/a", Authority: "a", Namespace: "other", Policy: "isp",
+			CreateTime: created.Add(time.Hour)},
+	}
+	for _, r := range records {
+		testutil.CheckError(t, false, l.Append(r))
+	}
+	records[0].CreateTime = created
+	for i := range records {
+		records[i].Digest = digest
+	}
+
+	// Records are loaded back from the file
+	l, err = New(path, 0)
+	testutil.CheckErrorAndDeepEqual(t, false, err, records, l.Records(Filter{}))
+
+	var tests = []struct {
+		name     string
+		filter   Filter
+		expected []Record
+	}{
+		{"image by digest", Filter{Image: "gcr.io/foo/bar@" + digest}, records[:1]},
+		{"image by repository", Filter{Image: "gcr.io/foo/baz"}, records[1:]},
+		{"namespace", Filter{Namespace: "ns"}, records[:1]},
+		{"policy", Filter{Policy: "isp"}, records},
+		{"since", Filter{Since: created.Add(time.Minute)}, records[1:]},
+		{"no match", Filter{Policy: "missing"}, []Record{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testutil.DeepEqual(t, test.expected, l.Records(test.filter))
+		})
+	}
+}
+
+func TestLogRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transparency")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "attestations.log")
+
+	l, err := New(path, 10)
+	testutil.CheckError(t, false, err)
+	for i := 0; i < 12; i++ {
+		testutil.CheckError(t, false, l.Append(Record{Image: fmt.Sprintf("gcr.io/foo/bar:%d", i)}))
+	}
+	records := l.Records(Filter{})
+	testutil.DeepEqual(t, 10, len(records))
+	testutil.DeepEqual(t, "gcr.io/foo/bar:2", records[0].Image)
+
+	// The file only holds the records kept
+	l, err = New(path, 100)
+	testutil.CheckErrorAndDeepEqual(t, false, err, records, l.Records(Filter{}))
+}
+
+func TestHandler(t *testing.T) {
+	l, err := New("", 0)
+	testutil.CheckError(t, false, err)
+	r := Record{Image: "gcr.io/foo/bar@" + digest, Namespace: "ns", Policy: "isp", CreateTime: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)}
+	testutil.CheckError(t, false, l.Append(r))
+	r.Digest = digest
+
+	var tests = []struct {
+		name     string
+		url      string
+		status   int
+		expected []Record
+	}{
+		{"all", "/attestations", http.StatusOK, []Record{r}},
+		{"filtered", "/attestations?namespace=other", http.StatusOK, []Record{}},
+		{"since", "/attestations?since=2019-01-02T00:00:00Z", http.StatusOK, []Record{}},
+		{"invalid since", "/attestations?since=yesterday", http.StatusBadRequest, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Handler(l)(w, httptest.NewRequest(http.MethodGet, test.url, nil))
+			if w.Code != test.status {
+				t.Fatalf("expected status %d, got %d: %s", test.status, w.Code, w.Body.String())
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+			var actual []Record
+			err := json.Unmarshal(w.Body.Bytes(), &actual)
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, actual)
+		})
+	}
+}
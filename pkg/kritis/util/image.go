@@ -5,37 +5,56 @@ import (
 	"strings"
 
 	"github.com/golang/glog"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
-	"github.com/google/go-containerregistry/pkg/v1/google"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
 	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
 )
 
-func ResolveImageToDigest(image string) (string, error) {
+// PodSpecImages returns the images of the init containers and the containers of a pod
+// spec, e.g. of a pod or of the template of a workload.
+func PodSpecImages(spec v1.PodSpec) []string {
+	images := []string{}
+	for _, ic := range spec.InitContainers {
+		images = append(images, ic.Image)
+	}
+	for _, c := range spec.Containers {
+		images = append(images, c.Image)
+	}
+	return images
+}
+
+// ResolveImageToDigest resolves a tagged image to its digest, authenticating with kc.
+// Images of registries other than GCR and Artifact Registry are kept as is if they
+// can not be resolved, e.g. because the registry is not reachable from the cluster.
+func ResolveImageToDigest(image string, kc authn.Keychain) (string, error) {
 	if isRefDigest(image) {
 		// Image already has a digest
 		return image, nil
 	}
 
 	// WeakValidation allow images without tags and consider it as `latest`
-	tag, err := name.NewTag(image, name.WeakValidation)
+	tag, err := reference.ParseTag(image)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to create new image tag")
 	}
 
-	if !isRegistryGCR(tag.RegistryStr()) {
-		// Ignore if registry is not GCR
-		// TODO (@vbanthia): Support other registry also
-		glog.Warningf("only GCR images are supported, found %q registry instead", tag.RegistryStr())
-		return image, nil
-	}
-
-	auth, err := google.NewEnvAuthenticator()
+	resolved, err := resolveTag(tag, kc)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to authenticate GCR")
+		if !isRegistryGCR(tag.RegistryStr()) && !reference.IsArtifactRegistry(tag.RegistryStr()) {
+			glog.Warningf("failed to resolve %q, keeping the tag: %v", image, err)
+			return image, nil
+		}
+		return "", err
 	}
+	return resolved, nil
+}
 
-	img, err := remote.Image(tag, remote.WithAuth(auth))
+// For testing
+var resolveTag = func(tag name.Tag, kc authn.Keychain) (string, error) {
+	img, err := remote.Image(tag, remote.WithAuthFromKeychain(kc))
 	if err != nil {
 		return "", errors.Wrap(err, "failed to create remote image")
 	}
@@ -70,10 +89,5 @@ func isRegistryGCR(registry string) bool {
 }
 
 func isRefDigest(image string) bool {
-	// WeakValidation allow images without registries
-	_, err := name.NewDigest(image, name.WeakValidation)
-	if err == nil {
-		return true
-	}
-	return false
+	return reference.HasDigest(image)
 }
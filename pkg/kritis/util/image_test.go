@@ -1,11 +1,30 @@
 package util
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "k8s.io/api/core/v1"
+
 	"github.com/grafeas/kritis/pkg/kritis/testutil"
 )
 
+func TestPodSpecImages(t *testing.T) {
+	spec := v1.PodSpec{
+		InitContainers: []v1.Container{{Name: "init", Image: "gcr.io/kritis-project/init:latest"}},
+		Containers:     []v1.Container{{Name: "web", Image: "gcr.io/kritis-project/web:latest"}, {Name: "proxy", Image: "gcr.io/kritis-project/proxy:latest"}},
+	}
+	expected := []string{
+		"gcr.io/kritis-project/init:latest",
+		"gcr.io/kritis-project/web:latest",
+		"gcr.io/kritis-project/proxy:latest",
+	}
+	testutil.DeepEqual(t, expected, PodSpecImages(spec))
+	testutil.DeepEqual(t, []string{}, PodSpecImages(v1.PodSpec{}))
+}
+
 func Test_isRegistryGCR(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -91,3 +110,53 @@ func Test_isRefDigest(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveImageToDigest(t *testing.T) {
+	original := resolveTag
+	defer func() { resolveTag = original }()
+	resolveTag = func(tag name.Tag, kc authn.Keychain) (string, error) {
+		if tag.RegistryStr() == "registry.example.com" {
+			return "", errors.New("unauthorized")
+		}
+		return tag.Context().String() + "@sha256:1234cc2d8ea3d7c8a456caeffbaedcc946ab3fcf9be25af4b1b2658099425e03", nil
+	}
+	tests := []struct {
+		name      string
+		image     string
+		expected  string
+		shouldErr bool
+	}{
+		{
+			name:     "digest",
+			image:    "gcr.io/project/image@sha256:1234cc2d8ea3d7c8a456caeffbaedcc946ab3fcf9be25af4b1b2658099425e03",
+			expected: "gcr.io/project/image@sha256:1234cc2d8ea3d7c8a456caeffbaedcc946ab3fcf9be25af4b1b2658099425e03",
+		},
+		{
+			name:      "wrong digest",
+			image:     "gcr.io/project/image@sha256:1234",
+			shouldErr: true,
+		},
+		{
+			name:     "private registry",
+			image:    "registry.internal:5000/team/image:v1",
+			expected: "registry.internal:5000/team/image@sha256:1234cc2d8ea3d7c8a456caeffbaedcc946ab3fcf9be25af4b1b2658099425e03",
+		},
+		{
+			name:     "unresolved image of other registry",
+			image:    "registry.example.com/image:v1",
+			expected: "registry.example.com/image:v1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := ResolveImageToDigest(test.image, authn.DefaultKeychain)
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, actual)
+		})
+	}
+
+	resolveTag = func(tag name.Tag, kc authn.Keychain) (string, error) {
+		return "", errors.New("unauthorized")
+	}
+	_, err := ResolveImageToDigest("gcr.io/project/image:v1", authn.DefaultKeychain)
+	testutil.CheckError(t, true, err)
+}
@@ -17,17 +17,27 @@ limitations under the License.
 package util
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
+	"strings"
+	"time"
 
+	admissionconstants "github.com/grafeas/kritis/pkg/kritis/admission/constants"
 	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
 	"github.com/grafeas/kritis/pkg/kritis/attestation"
 	"github.com/grafeas/kritis/pkg/kritis/constants"
 	"github.com/grafeas/kritis/pkg/kritis/container"
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
 	"github.com/grafeas/kritis/pkg/kritis/secrets"
+	"github.com/grafeas/kritis/pkg/kritis/signer"
+	attestationpb "google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/attestation"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/common"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/discovery"
 	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
 	pkg "google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/package"
 	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/vulnerability"
+	"google.golang.org/genproto/googleapis/rpc/status"
 )
 
 func GetVulnerabilityFromOccurrence(occ *grafeas.Occurrence) *metadata.Vulnerability {
@@ -40,7 +50,18 @@ func GetVulnerabilityFromOccurrence(occ *grafeas.Occurrence) *metadata.Vulnerabi
 		Severity:        vulnerability.Severity_name[int32(vulnDetails.Severity)],
 		HasFixAvailable: hasFixAvailable,
 		CVE:             occ.GetNoteName(),
+		CVSSScore:       float64(vulnDetails.GetCvssScore()),
 	}
+	if pis := vulnDetails.GetPackageIssue(); len(pis) > 0 {
+		vulnerability.Package = pis[0].GetAffectedLocation().GetPackage()
+		vulnerability.Version = VersionString(pis[0].GetAffectedLocation().GetVersion())
+		vulnerability.CPEURI = pis[0].GetAffectedLocation().GetCpeUri()
+		if hasFixAvailable {
+			vulnerability.FixedVersion = VersionString(pis[0].GetFixedLocation().GetVersion())
+		}
+	}
+	// FixAvailableSince is not set: occurrences are updated by every rescan, so their
+	// times are not when the fix was published.
 	return &vulnerability
 }
 
@@ -63,6 +84,26 @@ func GetResource(image string) *grafeas.Resource {
 }
 
 func GetPgpAttestationFromOccurrence(occ *grafeas.Occurrence) metadata.PGPAttestation {
+	a := pgpAttestationFromOccurrence(occ)
+	if t := occ.GetCreateTime(); t != nil {
+		created := t.AsTime()
+		a.CreateTime = &created
+	}
+	return a
+}
+
+func pgpAttestationFromOccurrence(occ *grafeas.Occurrence) metadata.PGPAttestation {
+	if generic := occ.GetAttestation().GetAttestation().GetGenericSignedAttestation(); generic != nil {
+		a := metadata.PGPAttestation{
+			Payload: string(generic.GetSerializedPayload()),
+			OccID:   occ.GetName(),
+		}
+		if sigs := generic.GetSignatures(); len(sigs) > 0 {
+			a.Signature = base64.StdEncoding.EncodeToString(sigs[0].GetSignature())
+			a.KeyID = sigs[0].GetPublicKeyId()
+		}
+		return a
+	}
 	pgp := occ.GetAttestation().GetAttestation().GetPgpSignedAttestation()
 	return metadata.PGPAttestation{
 		Signature: pgp.GetSignature(),
@@ -84,16 +125,117 @@ func CreateAttestationSignature(image string, pgpSigningKey *secrets.PGPSigningS
 }
 
 func GetAttestationKeyFingerprint(pgpSigningKey *secrets.PGPSigningSecret) string {
+	if pgpSigningKey.KMSKeyName != "" {
+		return signer.KeyID(pgpSigningKey.KMSKeyName)
+	}
 	return pgpSigningKey.PgpKey.Fingerprint()
 }
 
+// CreateAttestation signs the image with the signer of the secret, see signer.New.
+func CreateAttestation(ctx context.Context, image string, pgpSigningKey *secrets.PGPSigningSecret) (*attestationpb.Attestation, error) {
+	s, err := signer.New(pgpSigningKey)
+	if err != nil {
+		return nil, err
+	}
+	hostSig, err := container.NewAtomicContainerSig(image, map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+	payload, err := hostSig.JSON()
+	if err != nil {
+		return nil, err
+	}
+	return s.Attest(ctx, []byte(payload))
+}
+
 // GetOrCreateAttestationNote returns a note if exists and creates one if it does not exist.
-func GetOrCreateAttestationNote(c metadata.Fetcher, a *v1beta1.AttestationAuthority) (*grafeas.Note, error) {
-	n, err := c.AttestationNote(a)
+func GetOrCreateAttestationNote(ctx context.Context, c metadata.Fetcher, a *v1beta1.AttestationAuthority) (*grafeas.Note, error) {
+	n, err := c.AttestationNote(ctx, a)
 	if err == nil {
 		return n, nil
 	}
-	return c.CreateAttestationNote(a)
+	return c.CreateAttestationNote(ctx, a)
+}
+
+// GetOrCreateDiscoveryNote returns a discovery note if exists and creates one if it does not exist.
+func GetOrCreateDiscoveryNote(ctx context.Context, c metadata.Fetcher, image string, noteID string) (*grafeas.Note, error) {
+	n, err := c.DiscoveryNote(ctx, image, noteID)
+	if err == nil && n != nil {
+		return n, nil
+	}
+	return c.CreateDiscoveryNote(ctx, image, noteID)
+}
+
+// NewDiscoveryNote returns the Discovery note used by Kritis to record audit results.
+func NewDiscoveryNote(project string, noteID string) *grafeas.Note {
+	return &grafeas.Note{
+		Name:             fmt.Sprintf("projects/%s/notes/%s", project, noteID),
+		ShortDescription: "Kritis audit",
+		LongDescription:  "Audit records written by Kritis for admission decisions",
+		Type: &grafeas.Note_Discovery{
+			Discovery: &discovery.Discovery{
+				AnalysisKind: common.NoteKind_DISCOVERY,
+			},
+		},
+	}
+}
+
+// NewDiscoveryOccurrence returns a Discovery occurrence for image carrying message
+// as its analysis status message.
+func NewDiscoveryOccurrence(note *grafeas.Note, image string, message string) *grafeas.Occurrence {
+	return &grafeas.Occurrence{
+		Resource: GetResource(image),
+		NoteName: note.GetName(),
+		Details: &grafeas.Occurrence_Discovered{
+			Discovered: &discovery.Details{
+				Discovered: &discovery.Discovered{
+					ContinuousAnalysis:  discovery.Discovered_INACTIVE,
+					AnalysisStatus:      discovery.Discovered_FINISHED_SUCCESS,
+					AnalysisStatusError: &status.Status{Message: message},
+				},
+			},
+		},
+	}
+}
+
+// GetDiscoveryMessageFromOccurrence returns the audit message recorded in a Discovery occurrence.
+func GetDiscoveryMessageFromOccurrence(occ *grafeas.Occurrence) string {
+	return occ.GetDiscovered().GetDiscovered().GetAnalysisStatusError().GetMessage()
+}
+
+// GetScanStatusFromOccurrences returns the status of the vulnerability scan reported by the most
+// recent Discovery occurrence of the scanner, ignoring those recorded by Kritis.
+func GetScanStatusFromOccurrences(occs []*grafeas.Occurrence) metadata.ScanStatus {
+	var latest *grafeas.Occurrence
+	for _, occ := range occs {
+		d := occ.GetDiscovered().GetDiscovered()
+		if d == nil || d.AnalysisStatus == discovery.Discovered_ANALYSIS_STATUS_UNSPECIFIED {
+			continue
+		}
+		note := occ.GetNoteName()
+		if strings.HasSuffix(note, "/notes/"+constants.AuditNoteID) || strings.HasSuffix(note, "/notes/"+admissionconstants.BreakglassNoteID) {
+			continue
+		}
+		if latest == nil || updateTime(occ).After(updateTime(latest)) {
+			latest = occ
+		}
+	}
+	if latest == nil {
+		return metadata.ScanStatusUnknown
+	}
+	return metadata.ScanStatus(discovery.Discovered_AnalysisStatus_name[int32(latest.GetDiscovered().GetDiscovered().AnalysisStatus)])
+}
+
+// updateTime returns when the occurrence was last updated, or created.
+func updateTime(occ *grafeas.Occurrence) time.Time {
+	t := occ.GetUpdateTime()
+	if t == nil {
+		t = occ.GetCreateTime()
+	}
+	if t == nil {
+		return time.Time{}
+	}
+	return t.AsTime()
 }
 
 func GetBuildFromOccurrence(occ *grafeas.Occurrence) *metadata.Build {
@@ -108,3 +250,36 @@ func GetBuildFromOccurrence(occ *grafeas.Occurrence) *metadata.Build {
 		},
 	}
 }
+
+// normalizeKeyID returns PGP fingerprints and key IDs as upper case hex digits, without
+// spaces or 0x prefix. Other key IDs, such as KMS key names, are returned unchanged.
+func normalizeKeyID(id string) string {
+	h := strings.ToUpper(strings.Replace(strings.TrimSpace(id), " ", "", -1))
+	h = strings.TrimPrefix(h, "0X")
+	if !isHex(h) {
+		return id
+	}
+	return h
+}
+
+// SameKeyID returns true if the key IDs a and b identify the same key. A PGP fingerprint
+// matches its long key ID, the last 16 hex digits, which some backends report instead.
+// Short key IDs of 8 digits are not matched, since they are easily forged.
+func SameKeyID(a, b string) bool {
+	a, b = normalizeKeyID(a), normalizeKeyID(b)
+	if a == "" || b == "" {
+		return false
+	}
+	if a == b {
+		return true
+	}
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	return len(b) >= 16 && isHex(a) && isHex(b) && strings.HasSuffix(a, b)
+}
+
+// isHex returns true if id only has upper case hex digits.
+func isHex(id string) bool {
+	return id != "" && strings.Trim(id, "0123456789ABCDEF") == ""
+}
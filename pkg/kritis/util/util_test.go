@@ -18,12 +18,17 @@ package util
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/grafeas/kritis/pkg/kritis/metadata"
 	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	attestationpb "google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/attestation"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/common"
+	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/discovery"
 	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/grafeas"
 	pkg "google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/package"
 	"google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/vulnerability"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestGetVulnerabilityFromOccurence(t *testing.T) {
@@ -82,8 +87,149 @@ func TestGetVulnerabilityFromOccurence(t *testing.T) {
 	}
 }
 
+func TestGetVulnerabilityFixAvailableSince(t *testing.T) {
+	created := time.Date(2018, 9, 1, 0, 0, 0, 0, time.UTC)
+	updated := time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+	occ := func(kind pkg.Version_VersionKind, update *timestamppb.Timestamp) *grafeas.Occurrence {
+		return &grafeas.Occurrence{
+			CreateTime: timestamppb.New(created),
+			UpdateTime: update,
+			Details: &grafeas.Occurrence_Vulnerability{
+				Vulnerability: &vulnerability.Details{
+					PackageIssue: []*vulnerability.PackageIssue{{
+						FixedLocation: &vulnerability.VulnerabilityLocation{Version: &pkg.Version{Kind: kind}},
+					}},
+				},
+			},
+		}
+	}
+	// Rescans update occurrences, so their times are not when the fix was published.
+	testutil.DeepEqual(t, (*time.Time)(nil), GetVulnerabilityFromOccurrence(occ(pkg.Version_NORMAL, timestamppb.New(updated))).FixAvailableSince)
+	testutil.DeepEqual(t, (*time.Time)(nil), GetVulnerabilityFromOccurrence(occ(pkg.Version_NORMAL, nil)).FixAvailableSince)
+	testutil.DeepEqual(t, (*time.Time)(nil), GetVulnerabilityFromOccurrence(occ(pkg.Version_MAXIMUM, timestamppb.New(updated))).FixAvailableSince)
+}
+
+func TestGetVulnerabilityFixedVersion(t *testing.T) {
+	occ := &grafeas.Occurrence{
+		Details: &grafeas.Occurrence_Vulnerability{
+			Vulnerability: &vulnerability.Details{
+				PackageIssue: []*vulnerability.PackageIssue{{
+					AffectedLocation: &vulnerability.VulnerabilityLocation{
+						Package: "openssl",
+						Version: &pkg.Version{Kind: pkg.Version_NORMAL, Name: "1.1.1n", Revision: "0"},
+					},
+					FixedLocation: &vulnerability.VulnerabilityLocation{
+						Version: &pkg.Version{Kind: pkg.Version_NORMAL, Name: "1.1.1n", Revision: "0+deb11u3"},
+					},
+				}},
+			},
+		},
+	}
+	v := GetVulnerabilityFromOccurrence(occ)
+	testutil.DeepEqual(t, []string{"openssl", "1.1.1n-0", "1.1.1n-0+deb11u3"}, []string{v.Package, v.Version, v.FixedVersion})
+}
+
+func TestGetPgpAttestationFromOccurrence(t *testing.T) {
+	generic := &grafeas.Occurrence{
+		Name: "occ",
+		Details: &grafeas.Occurrence_Attestation{
+			Attestation: &attestationpb.Details{
+				Attestation: &attestationpb.Attestation{
+					Signature: &attestationpb.Attestation_GenericSignedAttestation{
+						GenericSignedAttestation: &attestationpb.GenericSignedAttestation{
+							SerializedPayload: []byte("payload"),
+							Signatures:        []*common.Signature{{Signature: []byte("sig"), PublicKeyId: "//cloudkms.googleapis.com/v1/key"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	pgp := &grafeas.Occurrence{
+		Name: "occ",
+		Details: &grafeas.Occurrence_Attestation{
+			Attestation: &attestationpb.Details{
+				Attestation: &attestationpb.Attestation{
+					Signature: &attestationpb.Attestation_PgpSignedAttestation{
+						PgpSignedAttestation: &attestationpb.PgpSignedAttestation{
+							Signature: "sig",
+							KeyId:     &attestationpb.PgpSignedAttestation_PgpKeyId{PgpKeyId: "fpr"},
+						},
+					},
+				},
+			},
+		},
+	}
+	testutil.DeepEqual(t, metadata.PGPAttestation{Signature: "c2ln", KeyID: "//cloudkms.googleapis.com/v1/key", OccID: "occ", Payload: "payload"}, GetPgpAttestationFromOccurrence(generic))
+	testutil.DeepEqual(t, metadata.PGPAttestation{Signature: "sig", KeyID: "fpr", OccID: "occ"}, GetPgpAttestationFromOccurrence(pgp))
+}
+
 func TestGetResource(t *testing.T) {
 	r := GetResource("gcr.io/test/image:sha")
 	e := &grafeas.Resource{Uri: "https://gcr.io/test/image:sha"}
 	testutil.DeepEqual(t, e, r)
 }
+
+func TestNewDiscoveryOccurrence(t *testing.T) {
+	n := NewDiscoveryNote("test", "kritis-breakglass")
+	testutil.DeepEqual(t, "projects/test/notes/kritis-breakglass", n.GetName())
+	occ := NewDiscoveryOccurrence(n, "gcr.io/test/image@sha256:abc", "admitted")
+	testutil.DeepEqual(t, "projects/test/notes/kritis-breakglass", occ.GetNoteName())
+	testutil.DeepEqual(t, "https://gcr.io/test/image@sha256:abc", occ.GetResource().GetUri())
+	testutil.DeepEqual(t, "admitted", GetDiscoveryMessageFromOccurrence(occ))
+}
+
+func TestGetScanStatusFromOccurrences(t *testing.T) {
+	scan := func(note string, status discovery.Discovered_AnalysisStatus, updated int64) *grafeas.Occurrence {
+		return &grafeas.Occurrence{
+			NoteName:   note,
+			UpdateTime: timestamppb.New(time.Unix(updated, 0)),
+			Details: &grafeas.Occurrence_Discovered{
+				Discovered: &discovery.Details{Discovered: &discovery.Discovered{AnalysisStatus: status}},
+			},
+		}
+	}
+	scanner := "projects/goog-analysis/notes/PACKAGE_VULNERABILITY"
+	tests := []struct {
+		name     string
+		occs     []*grafeas.Occurrence
+		expected metadata.ScanStatus
+	}{
+		{"no occurrences", nil, metadata.ScanStatusUnknown},
+		{"scanning", []*grafeas.Occurrence{scan(scanner, discovery.Discovered_SCANNING, 1)}, metadata.ScanStatusScanning},
+		{"latest occurrence", []*grafeas.Occurrence{
+			scan(scanner, discovery.Discovered_FINISHED_SUCCESS, 2),
+			scan(scanner, discovery.Discovered_PENDING, 1),
+		}, metadata.ScanStatusFinished},
+		{"kritis occurrences", []*grafeas.Occurrence{
+			scan(scanner, discovery.Discovered_SCANNING, 1),
+			scan("projects/test/notes/kritis-audit", discovery.Discovered_FINISHED_SUCCESS, 2),
+			scan("projects/test/notes/kritis-breakglass", discovery.Discovered_FINISHED_SUCCESS, 3),
+		}, metadata.ScanStatusScanning},
+		{"unspecified status", []*grafeas.Occurrence{scan(scanner, discovery.Discovered_ANALYSIS_STATUS_UNSPECIFIED, 1)}, metadata.ScanStatusUnknown},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testutil.DeepEqual(t, test.expected, GetScanStatusFromOccurrences(test.occs))
+		})
+	}
+}
+
+func TestSameKeyID(t *testing.T) {
+	tcs := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"0123456789ABCDEF0123456789ABCDEF01234567", "0123456789abcdef0123456789abcdef01234567", true},
+		{"89ABCDEF01234567", "0123456789ABCDEF0123456789ABCDEF01234567", true},
+		{"0123456789ABCDEF0123456789ABCDEF01234567", "FFFFFFFF01234567", false},
+		{"projects/p/locations/l/keyRings/r/cryptoKeys/k", "projects/p/locations/l/keyRings/r/cryptoKeys/k", true},
+		{"projects/p/locations/l/keyRings/r/cryptoKeys/k", "Projects/p/locations/l/keyRings/r/cryptoKeys/k", false},
+		{"", "", false},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.a+"/"+tc.b, func(t *testing.T) {
+			testutil.DeepEqual(t, tc.expected, SameKeyID(tc.a, tc.b))
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	pkg "google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/package"
+)
+
+// CompareVersions returns -1, 0 or 1 if package version a is lower than, equal to or greater than b.
+// Versions are compared like dpkg does: numeric epochs first, then alternating non-digit parts,
+// compared lexically with ~ sorting before anything, and digit parts, compared numerically.
+// Semantic and RPM versions are ordered as expected too.
+func CompareVersions(a, b string) int {
+	ea, va := splitEpoch(a)
+	eb, vb := splitEpoch(b)
+	if ea != eb {
+		return sign(ea - eb)
+	}
+	return compareVersionParts(va, vb)
+}
+
+// VersionString formats a Grafeas package version as [epoch:]name[-revision].
+func VersionString(v *pkg.Version) string {
+	if v == nil || v.GetName() == "" {
+		return ""
+	}
+	s := v.GetName()
+	if v.GetEpoch() != 0 {
+		s = fmt.Sprintf("%d:%s", v.GetEpoch(), s)
+	}
+	if v.GetRevision() != "" {
+		s = fmt.Sprintf("%s-%s", s, v.GetRevision())
+	}
+	return s
+}
+
+func splitEpoch(v string) (int, string) {
+	if i := strings.Index(v, ":"); i > 0 {
+		if e, err := strconv.Atoi(v[:i]); err == nil {
+			return e, v[i+1:]
+		}
+	}
+	return 0, v
+}
+
+func compareVersionParts(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		// Compare the non-digit parts
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := 0, 0
+			if i < len(a) && !isDigit(a[i]) {
+				ac = versionOrder(a[i])
+			}
+			if j < len(b) && !isDigit(b[j]) {
+				bc = versionOrder(b[j])
+			}
+			if ac != bc {
+				return sign(ac - bc)
+			}
+			i++
+			j++
+		}
+		// Compare the digit parts, ignoring leading zeros
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		first := 0
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if first == 0 {
+				first = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if first != 0 {
+			return sign(first)
+		}
+	}
+	return 0
+}
+
+// versionOrder sorts ~ first, then the end of a part, letters and other characters.
+func versionOrder(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	pkg "google.golang.org/genproto/googleapis/devtools/containeranalysis/v1beta1/package"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.10", "1.2.9", 1},
+		{"1.2", "1.2.1", -1},
+		{"1.02", "1.2", 0},
+		{"1:1.0", "2.0", 1},
+		{"1.1.1n-0+deb11u3", "1.1.1n-0+deb11u4", -1},
+		{"1.1.1n-0+deb11u3", "1.1.1n-0", 1},
+		{"2.0~rc1", "2.0", -1},
+		{"2.0a", "2.0", 1},
+		{"2.0a", "2.0+", -1},
+		{"9.16.1-r0", "9.16.10-r0", -1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.a+" "+tc.b, func(t *testing.T) {
+			if got := CompareVersions(tc.a, tc.b); got != tc.expected {
+				t.Errorf("CompareVersions(%q, %q) = %d, expected %d", tc.a, tc.b, got, tc.expected)
+			}
+			if got := CompareVersions(tc.b, tc.a); got != -tc.expected {
+				t.Errorf("CompareVersions(%q, %q) = %d, expected %d", tc.b, tc.a, got, -tc.expected)
+			}
+		})
+	}
+}
+
+func TestVersionString(t *testing.T) {
+	tests := []struct {
+		version  *pkg.Version
+		expected string
+	}{
+		{nil, ""},
+		{&pkg.Version{Kind: pkg.Version_MAXIMUM}, ""},
+		{&pkg.Version{Name: "1.2.3"}, "1.2.3"},
+		{&pkg.Version{Epoch: 1, Name: "2.36.1", Revision: "8+deb11u1"}, "1:2.36.1-8+deb11u1"},
+	}
+	for _, tc := range tests {
+		if got := VersionString(tc.version); got != tc.expected {
+			t.Errorf("VersionString(%v) = %q, expected %q", tc.version, got, tc.expected)
+		}
+	}
+}
@@ -17,40 +17,22 @@ limitations under the License.
 package util
 
 import (
-	"github.com/golang/glog"
-	"github.com/google/go-containerregistry/pkg/name"
-
-	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/reference"
 )
 
-// RemoveGloballyWhitelistedImages returns all images that aren't globally whitelisted
-func RemoveGloballyWhitelistedImages(images []string) []string {
-	notWhitelisted := []string{}
-	for _, image := range images {
-		whitelisted, err := ImageInWhitelist(constants.GlobalImageWhitelist, image)
-		if err != nil {
-			glog.Errorf("couldn't check if %s is in global whitelist: %v", image, err)
-		}
-		if !whitelisted {
-			notWhitelisted = append(notWhitelisted, image)
-		}
-	}
-	return notWhitelisted
-}
-
 func ImageInWhitelist(whitelist []string, image string) (bool, error) {
 	for _, w := range whitelist {
-		whitelistRef, err := name.ParseReference(w, name.WeakValidation)
+		whitelistRef, err := reference.Parse(w)
 		if err != nil {
 			return false, err
 		}
-		imageRef, err := name.ParseReference(image, name.WeakValidation)
+		imageRef, err := reference.Parse(image)
 		if err != nil {
 			return false, err
 		}
 
 		// Make sure images have the same name
-		if whitelistRef.Context().Name() == imageRef.Context().Name() {
+		if whitelistRef.Name() == imageRef.Name() {
 			return true, nil
 		}
 	}
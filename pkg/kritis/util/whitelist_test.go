@@ -21,37 +21,6 @@ import (
 	"github.com/grafeas/kritis/pkg/kritis/testutil"
 )
 
-func Test_RemoveGloballyWhitelistedImages(t *testing.T) {
-	tests := []struct {
-		name     string
-		images   []string
-		expected []string
-	}{
-		{
-			name: "images in whitelist",
-			images: []string{
-				"gcr.io/kritis-project/kritis-server:tag",
-				"gcr.io/kritis-project/kritis-server@sha256:0000000000000000000000000000000000000000000000000000000000000000",
-			},
-			expected: []string{},
-		},
-		{
-			name: "some images not whitelisted",
-			images: []string{
-				"gcr.io/kritis-project/kritis-server:tag",
-				"gcr.io/some/image@sha256:0000000000000000000000000000000000000000000000000000000000000000",
-			},
-			expected: []string{"gcr.io/some/image@sha256:0000000000000000000000000000000000000000000000000000000000000000"},
-		},
-	}
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			actual := RemoveGloballyWhitelistedImages(test.images)
-			testutil.DeepEqual(t, test.expected, actual)
-		})
-	}
-}
-
 func Test_ImageInWhitelist(t *testing.T) {
 	tests := []struct {
 		name      string
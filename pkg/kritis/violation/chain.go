@@ -0,0 +1,195 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package violation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	kubernetesutil "github.com/grafeas/kritis/pkg/kritis/kubernetes"
+	"github.com/grafeas/kritis/pkg/kritis/metrics"
+	"github.com/grafeas/kritis/pkg/kritis/notify"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+)
+
+// Names of the stages of a Chain
+const (
+	LogStage       = "log"
+	EventStage     = "event"
+	AnnotateStage  = "annotate"
+	RemediateStage = "remediate"
+	NotifyStage    = "notify"
+	DenyStage      = "deny"
+)
+
+// ViolationEventReason is the reason of the events recorded by the event stage
+const ViolationEventReason = "ImageSecurityPolicyViolation"
+
+// For testing
+var recordEvent = kubernetesutil.CreateEvent
+
+// Stage is a named Strategy of a Chain.
+type Stage struct {
+	Name     string
+	Strategy Strategy
+	// podOnly stages are skipped when there is no pod, e.g. when reviewing replica sets
+	podOnly bool
+}
+
+// Chain handles violations with each of its stages in order. A failing stage is
+// logged and counted, and does not stop the following stages, so the chain never
+// fails. Whether violations are denied is decided by Denies.
+type Chain struct {
+	Stages []Stage
+	deny   bool
+}
+
+var _ PolicyStrategy = &Chain{}
+
+// NewChain returns the Chain of the named stages. The notify stage sends violations
+// to sender, and is skipped with a warning if sender is nil.
+func NewChain(names []string, sender notify.Sender) (*Chain, error) {
+	c := &Chain{}
+	for _, name := range names {
+		switch name {
+		case LogStage:
+			c.Stages = append(c.Stages, Stage{Name: name, Strategy: &LoggingStrategy{}})
+		case EventStage:
+			c.Stages = append(c.Stages, Stage{Name: name, Strategy: &EventStrategy{}})
+		case AnnotateStage:
+			c.Stages = append(c.Stages, Stage{Name: name, Strategy: &AnnotationStrategy{}, podOnly: true})
+		case RemediateStage:
+			c.Stages = append(c.Stages, Stage{Name: name, Strategy: remediateStrategy{}, podOnly: true})
+		case NotifyStage:
+			if sender == nil {
+				glog.Warningf("no notifications configured, skipping the %s violation strategy", name)
+				continue
+			}
+			c.Stages = append(c.Stages, Stage{Name: name, Strategy: WithNotifications(nopStrategy{}, sender)})
+		case DenyStage:
+			c.deny = true
+		default:
+			return nil, fmt.Errorf("unknown violation strategy %q, must be one of %s", name,
+				strings.Join([]string{LogStage, EventStage, AnnotateStage, RemediateStage, NotifyStage, DenyStage}, ", "))
+		}
+	}
+	return c, nil
+}
+
+// Denies returns true if the chain has a deny stage, i.e. images with violations
+// are rejected by the webhook.
+func (c *Chain) Denies() bool {
+	return c.deny
+}
+
+func (c *Chain) HandleViolation(image string, pod *v1.Pod, violations []policy.Violation) error {
+	c.run(pod, "violations", image, func(s Strategy) error {
+		return s.HandleViolation(image, pod, violations)
+	})
+	return nil
+}
+
+func (c *Chain) HandlePolicyViolation(isp v1beta1.ImageSecurityPolicy, image string, pod *v1.Pod, violations []policy.Violation) error {
+	c.run(pod, "violations", image, func(s Strategy) error {
+		if ps, ok := s.(PolicyStrategy); ok {
+			return ps.HandlePolicyViolation(isp, image, pod, violations)
+		}
+		return s.HandleViolation(image, pod, violations)
+	})
+	return nil
+}
+
+func (c *Chain) HandleAttestation(image string, pod *v1.Pod, isAttested bool) error {
+	c.run(pod, "attestation", image, func(s Strategy) error {
+		return s.HandleAttestation(image, pod, isAttested)
+	})
+	return nil
+}
+
+func (c *Chain) run(pod *v1.Pod, what, image string, handle func(Strategy) error) {
+	for _, s := range c.Stages {
+		if s.podOnly && pod == nil {
+			continue
+		}
+		if err := handle(s.Strategy); err != nil {
+			metrics.ViolationStrategyErrors.Add(s.Name, 1)
+			glog.Errorf("violation strategy %s failed to handle the %s of %q: %v", s.Name, what, image, err)
+		}
+	}
+}
+
+// EventStrategy records a warning event for violations, on the pod, or on the
+// violated ImageSecurityPolicy when reviewing objects which are not pods.
+type EventStrategy struct {
+}
+
+func (e *EventStrategy) HandleViolation(image string, pod *v1.Pod, violations []policy.Violation) error {
+	if len(violations) == 0 || pod == nil || pod.Name == "" {
+		return nil
+	}
+	return recordEvent(kubernetesutil.NewEvent(v1.ObjectReference{
+		Kind:       "Pod",
+		APIVersion: "v1",
+		Namespace:  pod.Namespace,
+		Name:       pod.Name,
+		UID:        pod.UID,
+	}, v1.EventTypeWarning, ViolationEventReason, violationsMessage(image, violations)))
+}
+
+func (e *EventStrategy) HandlePolicyViolation(isp v1beta1.ImageSecurityPolicy, image string, pod *v1.Pod, violations []policy.Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	if pod != nil && pod.Name != "" {
+		return e.HandleViolation(image, pod, violations)
+	}
+	return recordEvent(kubernetesutil.NewEvent(v1.ObjectReference{
+		Kind:       "ImageSecurityPolicy",
+		APIVersion: v1beta1.SchemeGroupVersion.String(),
+		Namespace:  isp.Namespace,
+		Name:       isp.Name,
+		UID:        isp.UID,
+	}, v1.EventTypeWarning, ViolationEventReason, violationsMessage(image, violations)))
+}
+
+func (e *EventStrategy) HandleAttestation(image string, pod *v1.Pod, isAttested bool) error {
+	return nil
+}
+
+func violationsMessage(image string, violations []policy.Violation) string {
+	reasons := []string{}
+	for _, v := range violations {
+		reasons = append(reasons, string(v.Reason()))
+	}
+	return fmt.Sprintf("found %d violations in %q: %s", len(violations), image, strings.Join(reasons, ", "))
+}
+
+// nopStrategy handles nothing, for strategies wrapping another one.
+type nopStrategy struct {
+}
+
+func (nopStrategy) HandleViolation(image string, pod *v1.Pod, violations []policy.Violation) error {
+	return nil
+}
+
+func (nopStrategy) HandleAttestation(image string, pod *v1.Pod, isAttested bool) error {
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package violation
+
+import (
+	"fmt"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/metrics"
+	"github.com/grafeas/kritis/pkg/kritis/notify"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+type failingStrategy struct {
+	nopStrategy
+}
+
+func (failingStrategy) HandleViolation(image string, pod *v1.Pod, violations []policy.Violation) error {
+	return fmt.Errorf("failed")
+}
+
+func stageNames(c *Chain) []string {
+	names := []string{}
+	for _, s := range c.Stages {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestNewChain(t *testing.T) {
+	tests := []struct {
+		name      string
+		stages    []string
+		sender    notify.Sender
+		shouldErr bool
+		expected  []string
+		denies    bool
+	}{
+		{"all stages", []string{"log", "event", "annotate", "remediate", "notify", "deny"}, &fakeSender{}, false,
+			[]string{"log", "event", "annotate", "remediate", "notify"}, true},
+		{"notify without sender", []string{"log", "notify"}, nil, false, []string{"log"}, false},
+		{"unknown stage", []string{"log", "page"}, nil, true, nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := NewChain(test.stages, test.sender)
+			testutil.CheckError(t, test.shouldErr, err)
+			if err != nil {
+				return
+			}
+			testutil.DeepEqual(t, test.expected, stageNames(c))
+			testutil.DeepEqual(t, test.denies, c.Denies())
+		})
+	}
+}
+
+func TestChainIsolatesFailures(t *testing.T) {
+	memory := &MemoryStrategy{Violations: map[string]bool{}, Attestations: map[string]bool{}}
+	podOnly := &MemoryStrategy{Violations: map[string]bool{}, Attestations: map[string]bool{}}
+	c := &Chain{Stages: []Stage{
+		{Name: "failing", Strategy: failingStrategy{}},
+		{Name: "memory", Strategy: memory},
+		{Name: "pod", Strategy: podOnly, podOnly: true},
+	}}
+	before := int64(0)
+	if v, ok := metrics.ViolationStrategyErrors.Get("failing").(interface{ Value() int64 }); ok {
+		before = v.Value()
+	}
+	violations := []policy.Violation{fakeViolation{}}
+
+	err := c.HandlePolicyViolation(v1beta1.ImageSecurityPolicy{}, "image", nil, violations)
+	testutil.CheckErrorAndDeepEqual(t, false, err, map[string]bool{"image": true}, memory.Violations)
+	// The pod stage is skipped without a pod
+	testutil.DeepEqual(t, map[string]bool{}, podOnly.Violations)
+	testutil.DeepEqual(t, fmt.Sprint(before+1), metrics.ViolationStrategyErrors.Get("failing").String())
+}
+
+func TestEventStrategy(t *testing.T) {
+	var events []*v1.Event
+	original := recordEvent
+	defer func() { recordEvent = original }()
+	recordEvent = func(event *v1.Event) error {
+		events = append(events, event)
+		return nil
+	}
+	isp := v1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: "ns"}}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}
+	violations := []policy.Violation{fakeViolation{}}
+	e := &EventStrategy{}
+
+	if err := e.HandlePolicyViolation(isp, "image", pod, violations); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.HandlePolicyViolation(isp, "image", nil, violations); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.HandlePolicyViolation(isp, "image", pod, nil); err != nil {
+		t.Fatal(err)
+	}
+	var objects []string
+	for _, ev := range events {
+		testutil.DeepEqual(t, ViolationEventReason, ev.Reason)
+		testutil.DeepEqual(t, `found 1 violations in "image": hosted on Docker Hub`, ev.Message)
+		objects = append(objects, ev.InvolvedObject.Kind+" "+ev.InvolvedObject.Namespace+"/"+ev.InvolvedObject.Name)
+	}
+	testutil.DeepEqual(t, []string{"Pod ns/pod", "ImageSecurityPolicy ns/isp"}, objects)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package violation
+
+import (
+	"github.com/golang/glog"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/notify"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+)
+
+// NotificationStrategy handles violations with Strategy, then sends their summary with Sender.
+// Pods already labeled out of policy are not notified again.
+type NotificationStrategy struct {
+	Strategy Strategy
+	Sender   notify.Sender
+}
+
+// WithNotifications returns a Strategy also notifying sender of violations, or s if sender is nil.
+func WithNotifications(s Strategy, sender notify.Sender) Strategy {
+	if sender == nil {
+		return s
+	}
+	return &NotificationStrategy{Strategy: s, Sender: sender}
+}
+
+func (n *NotificationStrategy) HandleViolation(image string, pod *v1.Pod, violations []policy.Violation) error {
+	n.notify(image, "", "", pod, violations)
+	return n.Strategy.HandleViolation(image, pod, violations)
+}
+
+// HandlePolicyViolation also notifies the namespace of the policy when reviewing replica sets, which have no pod.
+func (n *NotificationStrategy) HandlePolicyViolation(isp v1beta1.ImageSecurityPolicy, image string, pod *v1.Pod, violations []policy.Violation) error {
+	n.notify(image, isp.Namespace, isp.Name, pod, violations)
+	if ps, ok := n.Strategy.(PolicyStrategy); ok {
+		return ps.HandlePolicyViolation(isp, image, pod, violations)
+	}
+	return n.Strategy.HandleViolation(image, pod, violations)
+}
+
+func (n *NotificationStrategy) HandleAttestation(image string, pod *v1.Pod, isAttested bool) error {
+	return n.Strategy.HandleAttestation(image, pod, isAttested)
+}
+
+// notify sends the violations, failures are only logged.
+func (n *NotificationStrategy) notify(image, namespace, isp string, pod *v1.Pod, violations []policy.Violation) {
+	if len(violations) == 0 {
+		return
+	}
+	name := ""
+	if pod != nil {
+		if _, ok := pod.Labels[constants.InvalidImageSecPolicy]; ok {
+			return
+		}
+		namespace, name = pod.Namespace, pod.Name
+	}
+	notification := notify.NewNotification(image, namespace, name, violations)
+	notification.Policy = isp
+	if err := n.Sender.Send(notification); err != nil {
+		glog.Errorf("failed to notify violations of %q: %v", image, err)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package violation
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/notify"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+type fakeSender struct {
+	sent []notify.Notification
+}
+
+func (f *fakeSender) Send(n notify.Notification) error {
+	f.sent = append(f.sent, n)
+	return nil
+}
+
+type fakeViolation struct{}
+
+func (fakeViolation) Type() policy.ViolationType { return policy.DockerHubViolation }
+func (fakeViolation) Reason() policy.Reason      { return "hosted on Docker Hub" }
+func (fakeViolation) Details() interface{}       { return nil }
+
+func TestNotificationStrategy(t *testing.T) {
+	isp := v1beta1.ImageSecurityPolicy{ObjectMeta: metav1.ObjectMeta{Name: "isp", Namespace: "ns"}}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}
+	labeled := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: "labeled", Namespace: "ns", Labels: map[string]string{constants.InvalidImageSecPolicy: constants.InvalidImageSecPolicyLabelValue},
+	}}
+	violations := []policy.Violation{fakeViolation{}}
+	var tests = []struct {
+		name       string
+		pod        *v1.Pod
+		violations []policy.Violation
+		expected   []string
+	}{
+		{"pod", pod, violations, []string{"ns/pod"}},
+		{"replica set", nil, violations, []string{"ns/"}},
+		{"already labeled", labeled, violations, nil},
+		{"no violations", pod, nil, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sender := &fakeSender{}
+			inner := &MemoryStrategy{Violations: map[string]bool{}}
+			s := WithNotifications(inner, sender).(PolicyStrategy)
+			testutil.CheckError(t, false, s.HandlePolicyViolation(isp, testutil.QualifiedImage, test.pod, test.violations))
+			var sent []string
+			for _, n := range sender.sent {
+				sent = append(sent, n.Namespace+"/"+n.Pod)
+			}
+			testutil.DeepEqual(t, test.expected, sent)
+			testutil.DeepEqual(t, true, inner.Violations[testutil.QualifiedImage])
+		})
+	}
+}
+
+func TestWithoutNotifications(t *testing.T) {
+	s := &LoggingStrategy{}
+	if WithNotifications(s, nil) != s {
+		t.Errorf("expected the strategy to be returned as is without sender")
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package violation
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	kubernetesutil "github.com/grafeas/kritis/pkg/kritis/kubernetes"
+	"github.com/grafeas/kritis/pkg/kritis/pods"
+	"github.com/grafeas/kritis/pkg/kritis/policy"
+)
+
+// For testing
+var clientset = kubernetesutil.GetClientset
+
+// PolicyStrategy is a Strategy whose handling of violations depends on the violated policy.
+type PolicyStrategy interface {
+	Strategy
+	HandlePolicyViolation(isp v1beta1.ImageSecurityPolicy, image string, pod *v1.Pod, violations []policy.Violation) error
+}
+
+// RemediationStrategy labels and annotates pods like AnnotationStrategy, then takes the
+// remediation action of the violated ImageSecurityPolicy.
+type RemediationStrategy struct {
+	AnnotationStrategy
+}
+
+// HandleViolation also removes the quarantine label of pods back in policy.
+func (r *RemediationStrategy) HandleViolation(image string, pod *v1.Pod, violations []policy.Violation) error {
+	if err := (remediateStrategy{}).HandleViolation(image, pod, violations); err != nil {
+		return err
+	}
+	return r.AnnotationStrategy.HandleViolation(image, pod, violations)
+}
+
+func (r *RemediationStrategy) HandlePolicyViolation(isp v1beta1.ImageSecurityPolicy, image string, pod *v1.Pod, violations []policy.Violation) error {
+	if err := r.HandleViolation(image, pod, violations); err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return remediate(isp.Spec.RemediationAction, pod)
+}
+
+// remediateStrategy takes the remediation action of the violated ImageSecurityPolicy
+// like RemediationStrategy, without labeling and annotating pods.
+type remediateStrategy struct {
+}
+
+// HandleViolation removes the quarantine label of pods back in policy.
+func (remediateStrategy) HandleViolation(image string, pod *v1.Pod, violations []policy.Violation) error {
+	if _, ok := pod.Labels[constants.Quarantined]; ok && len(violations) == 0 {
+		return pods.DeleteLabelsAndAnnotations(*pod, []string{constants.Quarantined}, nil)
+	}
+	return nil
+}
+
+func (r remediateStrategy) HandlePolicyViolation(isp v1beta1.ImageSecurityPolicy, image string, pod *v1.Pod, violations []policy.Violation) error {
+	if err := r.HandleViolation(image, pod, violations); err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return remediate(isp.Spec.RemediationAction, pod)
+}
+
+func (remediateStrategy) HandleAttestation(image string, pod *v1.Pod, isAttested bool) error {
+	return nil
+}
+
+// remediate takes the remediation action on a pod out of policy.
+func remediate(action string, pod *v1.Pod) error {
+	switch action {
+	case "", constants.RemediationNone:
+		return nil
+	case constants.RemediationQuarantine, constants.RemediationScaleToZero, constants.RemediationEvict:
+	default:
+		return fmt.Errorf("unknown remediation action %q", action)
+	}
+	cs, err := clientset()
+	if err != nil {
+		return err
+	}
+	glog.Warningf("taking remediation action %s on pod %s/%s", action, pod.Namespace, pod.Name)
+	switch action {
+	case constants.RemediationQuarantine:
+		patch := fmt.Sprintf(`{"metadata":{"labels":{%q:%q}}}`, constants.Quarantined, constants.QuarantinedLabelValue)
+		_, err = cs.CoreV1().Pods(pod.Namespace).Patch(pod.Name, types.StrategicMergePatchType, []byte(patch))
+		return errors.Wrapf(err, "failed to quarantine pod %q", pod.Name)
+	case constants.RemediationScaleToZero:
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil || owner.Kind != "ReplicaSet" {
+			return fmt.Errorf("pod %q is not owned by a Deployment", pod.Name)
+		}
+		rs, err := cs.AppsV1().ReplicaSets(pod.Namespace).Get(owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get replica set %q", owner.Name)
+		}
+		owner = metav1.GetControllerOf(rs)
+		if owner == nil || owner.Kind != "Deployment" {
+			return fmt.Errorf("pod %q is not owned by a Deployment", pod.Name)
+		}
+		_, err = cs.AppsV1().Deployments(pod.Namespace).Patch(owner.Name, types.StrategicMergePatchType, []byte(`{"spec":{"replicas":0}}`))
+		return errors.Wrapf(err, "failed to scale deployment %q to zero", owner.Name)
+	default:
+		err = cs.CoreV1().Pods(pod.Namespace).Evict(&policyv1beta1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		})
+		return errors.Wrapf(err, "failed to evict pod %q", pod.Name)
+	}
+}
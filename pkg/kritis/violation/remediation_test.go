@@ -0,0 +1,89 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package violation
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+func controllerRef(kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+func TestRemediate(t *testing.T) {
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name: "app-5d4f", Namespace: "ns", OwnerReferences: controllerRef("Deployment", "app"),
+	}}
+	deployed := v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name: "app-5d4f-x2k", Namespace: "ns", OwnerReferences: controllerRef("ReplicaSet", "app-5d4f"),
+	}}
+	bare := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bare", Namespace: "ns"}}
+	var tests = []struct {
+		action    string
+		pod       v1.Pod
+		shouldErr bool
+		expected  []string
+	}{
+		{"", bare, false, nil},
+		{constants.RemediationNone, bare, false, nil},
+		{constants.RemediationQuarantine, bare, false, []string{"patch pods"}},
+		{constants.RemediationEvict, bare, false, []string{"create pods/eviction"}},
+		{constants.RemediationScaleToZero, deployed, false, []string{"get replicasets", "patch deployments"}},
+		{constants.RemediationScaleToZero, bare, true, nil},
+		{"DELETE", bare, true, nil},
+	}
+	original := clientset
+	defer func() { clientset = original }()
+	for _, test := range tests {
+		t.Run(test.action+"/"+test.pod.Name, func(t *testing.T) {
+			cs := fake.NewSimpleClientset(rs, &deployed, &bare)
+			// The fake clientset does not set the namespace of evictions
+			cs.PrependReactor("create", "pods", func(a k8stesting.Action) (bool, runtime.Object, error) {
+				return a.GetSubresource() == "eviction", nil, nil
+			})
+			clientset = func() (kubernetes.Interface, error) {
+				return cs, nil
+			}
+			err := remediate(test.action, &test.pod)
+			var actions []string
+			for _, a := range cs.Actions() {
+				actions = append(actions, action(a))
+			}
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, actions)
+		})
+	}
+}
+
+func action(a k8stesting.Action) string {
+	r := a.GetResource().Resource
+	if a.GetSubresource() != "" {
+		r += "/" + a.GetSubresource()
+	}
+	return a.GetVerb() + " " + r
+}
@@ -17,15 +17,20 @@ package violation
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/golang/glog"
 	v1 "k8s.io/api/core/v1"
 
 	"github.com/grafeas/kritis/pkg/kritis/constants"
+	"github.com/grafeas/kritis/pkg/kritis/metrics"
 	"github.com/grafeas/kritis/pkg/kritis/pods"
 	"github.com/grafeas/kritis/pkg/kritis/policy"
 )
 
+// For testing
+var now = time.Now
+
 type Strategy interface {
 	HandleViolation(image string, pod *v1.Pod, violations []policy.Violation) error
 	HandleAttestation(image string, pod *v1.Pod, isAttested bool) error
@@ -56,29 +61,49 @@ func (l *LoggingStrategy) HandleAttestation(image string, pod *v1.Pod, isAtteste
 	return nil
 }
 
-// AnnotationStrategy adds "InvalidImageSecPolicy" as a label and an annotation, with detailed info in the annotations
+// AnnotationStrategy adds "InvalidImageSecPolicy" as a label and an annotation, with detailed info in the annotations.
+// Pods newly out of policy are also annotated with the time the violation was detected.
 type AnnotationStrategy struct {
 }
 
+// annotatedViolations are the violation types whose reason is added to the annotation,
+// since they are not about a CVE.
+var annotatedViolations = map[policy.ViolationType]bool{
+	policy.UnqualifiedImageViolation:     true,
+	policy.TagNotPinnedViolation:         true,
+	policy.DockerHubViolation:            true,
+	policy.MissingSBOMViolation:          true,
+	policy.LicenseViolation:              true,
+	policy.DisallowedBaseImageViolation:  true,
+	policy.ProvenanceViolation:           true,
+	policy.DisallowedRepositoryViolation: true,
+}
+
 func (a *AnnotationStrategy) HandleViolation(image string, pod *v1.Pod, violations []policy.Violation) error {
 	// First, remove "kritis.grafeas.io/invalidImageSecPolicy" label/annotation in case it doesn't apply anymore
 	if err := pods.DeleteLabelsAndAnnotations(*pod, []string{constants.InvalidImageSecPolicy}, []string{constants.InvalidImageSecPolicy}); err != nil {
 		return err
 	}
 	if len(violations) == 0 {
-		return nil
+		return pods.DeleteLabelsAndAnnotations(*pod, nil, []string{constants.ViolationDetected})
 	}
 	// Now, construct labels and annotations
 	labelValue := constants.InvalidImageSecPolicyLabelValue
 	annotationValue := fmt.Sprintf("found %d CVEs", len(violations))
 	for _, v := range violations {
-		if v.Type() == policy.UnqualifiedImageViolation {
+		if annotatedViolations[v.Type()] {
 			annotationValue += fmt.Sprintf(", %s", v.Reason())
 			break
 		}
 	}
 	labels := map[string]string{constants.InvalidImageSecPolicy: labelValue}
 	annotations := map[string]string{constants.InvalidImageSecPolicy: annotationValue}
+	if _, ok := pod.Labels[constants.InvalidImageSecPolicy]; !ok {
+		// The pod passed its last review, e.g. a CVE was published for the image since
+		glog.Warningf("pod %q newly violates its image security policy: %s", pod.Name, image)
+		metrics.NewViolations.Add(pod.Namespace, 1)
+		annotations[constants.ViolationDetected] = now().UTC().Format(time.RFC3339)
+	}
 	glog.Info(fmt.Sprintf("adding label %q and annotation %q", labelValue, annotationValue))
 	return pods.AddLabelsAndAnnotations(*pod, labels, annotations)
 }
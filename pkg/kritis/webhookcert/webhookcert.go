@@ -0,0 +1,479 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhookcert provisions and rotates the serving certificate of the admission
+// webhooks, and keeps the caBundle of their webhook configurations up to date.
+package webhookcert
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+// Certificate management modes.
+const (
+	SelfSigned  = "selfSigned"
+	CertManager = "certManager"
+
+	caCertKey = "ca.crt"
+	caKeyKey  = "ca.key"
+
+	// CheckInterval is how often the certificate is checked for renewal.
+	CheckInterval = time.Hour
+)
+
+var (
+	defaultServices           = []string{"kritis-validation-hook", "kritis-validation-hook-deployments"}
+	defaultValidatingWebhooks = []string{"kritis-validation-hook", "kritis-validation-hook-deployments"}
+	defaultMutatingWebhooks   = []string{"kritis-mutation-hook"}
+)
+
+// For testing
+var (
+	now           = time.Now
+	namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// Manager serves the certificate stored in a secret, issuing and renewing it in
+// SelfSigned mode, and patches the caBundle of the webhooks to trust it.
+type Manager struct {
+	client             kubernetes.Interface
+	mode               string
+	namespace          string
+	secretName         string
+	services           []string
+	validatingWebhooks []string
+	mutatingWebhooks   []string
+	validity           time.Duration
+	renewBefore        time.Duration
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// New returns a Manager for spec, or nil if the certificate is read from files.
+func New(client kubernetes.Interface, spec kritisv1beta1.CertificatesSpec) (*Manager, error) {
+	if spec.Mode == "" {
+		return nil, nil
+	}
+	if spec.Mode != SelfSigned && spec.Mode != CertManager {
+		return nil, fmt.Errorf("unsupported certificate mode %q, must be %s or %s", spec.Mode, SelfSigned, CertManager)
+	}
+	m := &Manager{
+		client:             client,
+		mode:               spec.Mode,
+		namespace:          spec.Namespace,
+		secretName:         spec.SecretName,
+		services:           spec.Services,
+		validatingWebhooks: spec.ValidatingWebhooks,
+		mutatingWebhooks:   spec.MutatingWebhooks,
+		validity:           90 * 24 * time.Hour,
+		renewBefore:        30 * 24 * time.Hour,
+	}
+	if m.secretName == "" {
+		m.secretName = "tls-webhook-secret"
+	}
+	if len(m.services) == 0 {
+		m.services = defaultServices
+	}
+	if len(m.validatingWebhooks) == 0 {
+		m.validatingWebhooks = defaultValidatingWebhooks
+	}
+	if len(m.mutatingWebhooks) == 0 {
+		m.mutatingWebhooks = defaultMutatingWebhooks
+	}
+	if m.namespace == "" {
+		b, err := ioutil.ReadFile(namespaceFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the namespace of Kritis, set certificates.namespace")
+		}
+		m.namespace = strings.TrimSpace(string(b))
+	}
+	var err error
+	if spec.Validity != "" {
+		if m.validity, err = time.ParseDuration(spec.Validity); err != nil {
+			return nil, errors.Wrap(err, "invalid certificate validity")
+		}
+	}
+	if spec.RenewBefore != "" {
+		if m.renewBefore, err = time.ParseDuration(spec.RenewBefore); err != nil {
+			return nil, errors.Wrap(err, "invalid certificate renewBefore")
+		}
+	}
+	if m.renewBefore >= m.validity {
+		return nil, fmt.Errorf("certificate renewBefore %s must be shorter than its validity %s", m.renewBefore, m.validity)
+	}
+	return m, nil
+}
+
+// Start syncs the certificate, then keeps syncing it every CheckInterval until stopCh is closed.
+func (m *Manager) Start(stopCh <-chan struct{}) error {
+	if err := m.Sync(); err != nil {
+		return err
+	}
+	go wait.Until(func() {
+		if err := m.Sync(); err != nil {
+			glog.Errorf("failed to sync the webhook certificate: %v", err)
+		}
+	}, CheckInterval, stopCh)
+	return nil
+}
+
+// GetCertificate returns the current serving certificate, see tls.Config.GetCertificate.
+func (m *Manager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.cert == nil {
+		return nil, fmt.Errorf("no webhook certificate available yet")
+	}
+	return m.cert, nil
+}
+
+// Sync issues or renews the certificate if needed, patches the caBundle of the webhooks,
+// then serves the certificate. The caBundle is patched before a certificate signed by a
+// new CA is served, and keeps previous CAs, so that the API server keeps trusting the webhooks.
+func (m *Manager) Sync() error {
+	err := m.sync()
+	if cause := errors.Cause(err); k8serrors.IsConflict(cause) || k8serrors.IsAlreadyExists(cause) {
+		// Another replica renewed the certificate first, serve its certificate instead.
+		err = m.sync()
+	}
+	return err
+}
+
+func (m *Manager) sync() error {
+	secret, err := m.client.CoreV1().Secrets(m.namespace).Get(m.secretName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		secret = nil
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to get secret %s/%s", m.namespace, m.secretName)
+	}
+	var data map[string][]byte
+	if m.mode == SelfSigned {
+		data, err = m.issue(secret)
+		if err != nil {
+			return err
+		}
+	} else {
+		if secret == nil {
+			return fmt.Errorf("secret %s/%s was not created by cert-manager yet", m.namespace, m.secretName)
+		}
+		data = secret.Data
+	}
+	cert, err := tls.X509KeyPair(data[v1.TLSCertKey], data[v1.TLSPrivateKeyKey])
+	if err != nil {
+		return errors.Wrapf(err, "invalid certificate in secret %s/%s", m.namespace, m.secretName)
+	}
+	if len(data[caCertKey]) == 0 {
+		return fmt.Errorf("no %s in secret %s/%s", caCertKey, m.namespace, m.secretName)
+	}
+	if m.mode == SelfSigned && (secret == nil || !equalData(secret.Data, data)) {
+		if err := m.writeSecret(secret, data); err != nil {
+			return err
+		}
+	}
+	if err := m.patchCABundles(data[caCertKey]); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.cert = &cert
+	m.mu.Unlock()
+	return nil
+}
+
+// issue returns the secret data with a valid CA and serving certificate, renewing
+// those expiring within renewBefore. The CA bundle keeps previous CAs until they expire.
+func (m *Manager) issue(secret *v1.Secret) (map[string][]byte, error) {
+	data := map[string][]byte{}
+	if secret != nil {
+		for k, v := range secret.Data {
+			data[k] = v
+		}
+	}
+	t := now()
+	bundle := validCerts(data[caCertKey], t)
+	ca, caKey, err := parseCA(bundle, data[caKeyKey])
+	if err != nil || ca.NotAfter.Before(t.Add(m.validity+m.renewBefore)) {
+		if err != nil && secret != nil {
+			glog.Warningf("issuing a new webhook CA: %v", err)
+		}
+		// The CA outlives the certificates it signs.
+		if ca, caKey, err = newCA(t, 4*m.validity); err != nil {
+			return nil, err
+		}
+		bundle = append([]*x509.Certificate{ca}, bundle...)
+		if data[caKeyKey], err = encodeKey(caKey); err != nil {
+			return nil, err
+		}
+		delete(data, v1.TLSCertKey)
+	}
+	data[caCertKey] = encodeCerts(bundle)
+	if !m.validServingCert(data[v1.TLSCertKey], data[v1.TLSPrivateKeyKey], ca, t) {
+		glog.Infof("issuing a new webhook certificate for %s", strings.Join(m.dnsNames(), ", "))
+		cert, key, err := newServingCert(t, m.validity, m.dnsNames(), ca, caKey)
+		if err != nil {
+			return nil, err
+		}
+		data[v1.TLSCertKey] = cert
+		if data[v1.TLSPrivateKeyKey], err = encodeKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// validServingCert returns true if the certificate is signed by ca for the
+// webhook services, and does not expire within renewBefore.
+func (m *Manager) validServingCert(certPEM, keyPEM []byte, ca *x509.Certificate, t time.Time) bool {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil || cert.CheckSignatureFrom(ca) != nil {
+		return false
+	}
+	if cert.NotAfter.Before(t.Add(m.renewBefore)) {
+		return false
+	}
+	for _, name := range m.dnsNames() {
+		if cert.VerifyHostname(name) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Manager) dnsNames() []string {
+	var names []string
+	for _, s := range m.services {
+		names = append(names, s, s+"."+m.namespace, s+"."+m.namespace+".svc")
+	}
+	return names
+}
+
+func (m *Manager) writeSecret(secret *v1.Secret, data map[string][]byte) error {
+	secrets := m.client.CoreV1().Secrets(m.namespace)
+	var err error
+	if secret == nil {
+		_, err = secrets.Create(&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: m.secretName, Namespace: m.namespace},
+			Type:       v1.SecretTypeTLS,
+			Data:       data,
+		})
+	} else {
+		secret = secret.DeepCopy()
+		secret.Data = data
+		_, err = secrets.Update(secret)
+	}
+	return errors.Wrapf(err, "failed to write secret %s/%s", m.namespace, m.secretName)
+}
+
+// patchCABundles sets the caBundle of all webhooks of the webhook configurations.
+// Configurations which do not exist are skipped, e.g. the mutating webhook if it is not enabled.
+func (m *Manager) patchCABundles(bundle []byte) error {
+	admission := m.client.AdmissionregistrationV1beta1()
+	for _, name := range m.validatingWebhooks {
+		c, err := admission.ValidatingWebhookConfigurations().Get(name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			glog.V(2).Infof("skipping caBundle of missing ValidatingWebhookConfiguration %s", name)
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to get ValidatingWebhookConfiguration %s", name)
+		}
+		changed := false
+		for i := range c.Webhooks {
+			if !bytes.Equal(c.Webhooks[i].ClientConfig.CABundle, bundle) {
+				c.Webhooks[i].ClientConfig.CABundle = bundle
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if _, err := admission.ValidatingWebhookConfigurations().Update(c); err != nil {
+			return errors.Wrapf(err, "failed to update caBundle of ValidatingWebhookConfiguration %s", name)
+		}
+		glog.Infof("updated caBundle of ValidatingWebhookConfiguration %s", name)
+	}
+	for _, name := range m.mutatingWebhooks {
+		c, err := admission.MutatingWebhookConfigurations().Get(name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			glog.V(2).Infof("skipping caBundle of missing MutatingWebhookConfiguration %s", name)
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to get MutatingWebhookConfiguration %s", name)
+		}
+		changed := false
+		for i := range c.Webhooks {
+			if !bytes.Equal(c.Webhooks[i].ClientConfig.CABundle, bundle) {
+				c.Webhooks[i].ClientConfig.CABundle = bundle
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if _, err := admission.MutatingWebhookConfigurations().Update(c); err != nil {
+			return errors.Wrapf(err, "failed to update caBundle of MutatingWebhookConfiguration %s", name)
+		}
+		glog.Infof("updated caBundle of MutatingWebhookConfiguration %s", name)
+	}
+	return nil
+}
+
+func newCA(t time.Time, validity time.Duration) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "kritis-webhook-ca"},
+		NotBefore:             t.Add(-time.Hour),
+		NotAfter:              t.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create the webhook CA")
+	}
+	ca, err := x509.ParseCertificate(der)
+	return ca, key, err
+}
+
+func newServingCert(t time.Time, validity time.Duration, dnsNames []string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) ([]byte, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    t.Add(-time.Hour),
+		NotAfter:     t.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create the webhook certificate")
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), key, nil
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// parseCA returns the first certificate of the bundle with its key.
+func parseCA(bundle []*x509.Certificate, keyPEM []byte) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	if len(bundle) == 0 {
+		return nil, nil, fmt.Errorf("no valid CA certificate")
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, nil, fmt.Errorf("no CA key")
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid CA key")
+	}
+	if !key.PublicKey.Equal(bundle[0].PublicKey) {
+		return nil, nil, fmt.Errorf("CA key does not match the CA certificate")
+	}
+	return bundle[0], key, nil
+}
+
+// validCerts returns the certificates of the PEM bundle which have not expired.
+func validCerts(bundle []byte, t time.Time) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, bundle = pem.Decode(bundle)
+		if block == nil {
+			return certs
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || t.After(cert.NotAfter) {
+			continue
+		}
+		certs = append(certs, cert)
+	}
+}
+
+func encodeCerts(certs []*x509.Certificate) []byte {
+	var b []byte
+	for _, c := range certs {
+		b = append(b, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	return b
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode key")
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+func equalData(a, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if !bytes.Equal(v, b[k]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhookcert
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+	admissionv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      kritisv1beta1.CertificatesSpec
+		shouldErr bool
+		nilMgr    bool
+	}{
+		{"files", kritisv1beta1.CertificatesSpec{}, false, true},
+		{"self signed", kritisv1beta1.CertificatesSpec{Mode: SelfSigned, Namespace: "kritis"}, false, false},
+		{"cert-manager", kritisv1beta1.CertificatesSpec{Mode: CertManager, Namespace: "kritis"}, false, false},
+		{"unknown mode", kritisv1beta1.CertificatesSpec{Mode: "acme", Namespace: "kritis"}, true, true},
+		{"invalid validity", kritisv1beta1.CertificatesSpec{Mode: SelfSigned, Namespace: "kritis", Validity: "1y"}, true, true},
+		{"renewal after expiry", kritisv1beta1.CertificatesSpec{Mode: SelfSigned, Namespace: "kritis", Validity: "24h", RenewBefore: "48h"}, true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := New(fake.NewSimpleClientset(), test.spec)
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.nilMgr, m == nil)
+		})
+	}
+}
+
+func webhookConfig(name string) *admissionv1beta1.ValidatingWebhookConfiguration {
+	return &admissionv1beta1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Webhooks:   []admissionv1beta1.Webhook{{Name: name + ".grafeas.io"}},
+	}
+}
+
+func TestSyncSelfSigned(t *testing.T) {
+	start := time.Date(2018, 10, 31, 0, 0, 0, 0, time.UTC)
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return start }
+
+	client := fake.NewSimpleClientset(webhookConfig("kritis-validation-hook"), webhookConfig("kritis-validation-hook-deployments"))
+	m, err := New(client, kritisv1beta1.CertificatesSpec{Mode: SelfSigned, Namespace: "kritis"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// state returns the secret and checks the webhooks trust its certificate.
+	state := func() *v1.Secret {
+		if err := m.Sync(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		secret, err := client.CoreV1().Secrets("kritis").Get("tls-webhook-secret", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		for _, name := range defaultValidatingWebhooks {
+			c, err := client.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Get(name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			testutil.DeepEqual(t, secret.Data[caCertKey], c.Webhooks[0].ClientConfig.CABundle)
+		}
+		served, err := m.GetCertificate(nil)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		cert, err := x509.ParseCertificate(served.Certificate[0])
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		roots := x509.NewCertPool()
+		roots.AppendCertsFromPEM(secret.Data[caCertKey])
+		if _, err := cert.Verify(x509.VerifyOptions{DNSName: "kritis-validation-hook.kritis.svc", Roots: roots, CurrentTime: now()}); err != nil {
+			t.Fatalf("served certificate is not trusted: %v", err)
+		}
+		return secret
+	}
+
+	issued := state()
+	testutil.DeepEqual(t, issued.Data, state().Data)
+
+	// The certificate is renewed 30 days before it expires, with the same CA.
+	now = func() time.Time { return start.AddDate(0, 0, 61) }
+	renewed := state()
+	if string(renewed.Data[v1.TLSCertKey]) == string(issued.Data[v1.TLSCertKey]) {
+		t.Errorf("expected the certificate to be renewed")
+	}
+	testutil.DeepEqual(t, issued.Data[caCertKey], renewed.Data[caCertKey])
+
+	// The CA is rotated when it would expire before the next certificate,
+	// and the previous CA is trusted until it expires.
+	now = func() time.Time { return start.AddDate(0, 0, 250) }
+	rotated := state()
+	if len(validCerts(rotated.Data[caCertKey], now())) != 2 {
+		t.Errorf("expected the bundle to hold both CAs")
+	}
+	now = func() time.Time { return start.AddDate(0, 0, 365) }
+	testutil.DeepEqual(t, 1, len(validCerts(state().Data[caCertKey], now())))
+}
+
+func TestSyncCertManager(t *testing.T) {
+	client := fake.NewSimpleClientset(webhookConfig("kritis-validation-hook"))
+	m, err := New(client, kritisv1beta1.CertificatesSpec{Mode: CertManager, Namespace: "kritis"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := m.Sync(); err == nil {
+		t.Fatalf("expected an error before cert-manager creates the secret")
+	}
+
+	ca, caKey, err := newCA(now(), time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	cert, key, err := newServingCert(now(), time.Hour, m.dnsNames(), ca, caKey)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tls-webhook-secret", Namespace: "kritis"},
+		Data: map[string][]byte{
+			v1.TLSCertKey:       cert,
+			v1.TLSPrivateKeyKey: keyPEM,
+			caCertKey:           encodeCerts([]*x509.Certificate{ca}),
+		},
+	}
+	if _, err := client.CoreV1().Secrets("kritis").Create(secret); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := m.Sync(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	c, err := client.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Get("kritis-validation-hook", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	testutil.DeepEqual(t, secret.Data[caCertKey], c.Webhooks[0].ClientConfig.CABundle)
+	// The secret is left to cert-manager.
+	actual, err := client.CoreV1().Secrets("kritis").Get("tls-webhook-secret", metav1.GetOptions{})
+	testutil.CheckErrorAndDeepEqual(t, false, err, secret.Data, actual.Data)
+}
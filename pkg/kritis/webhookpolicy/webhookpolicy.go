@@ -0,0 +1,226 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhookpolicy splits the validating webhooks of Kritis into one webhook per
+// set of namespaces, so that the API server applies a different failurePolicy to each.
+package webhookpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	admissionv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+)
+
+const (
+	// TemplateAnnotation keeps the webhooks of a configuration as they were before they
+	// were split, which are restored once no namespace sets a failurePolicy anymore.
+	TemplateAnnotation = "kritis.grafeas.io/webhookTemplate"
+
+	// ReconcileInterval is how often the webhook configurations are reconciled.
+	ReconcileInterval = 5 * time.Minute
+)
+
+var defaultValidatingWebhooks = []string{"kritis-validation-hook", "kritis-validation-hook-deployments"}
+
+// Reconciler keeps the webhooks of the validating webhook configurations split
+// according to the failure policies of a KritisConfig.
+type Reconciler struct {
+	client         kubernetes.Interface
+	configurations []string
+	namespaces     []kritisv1beta1.NamespaceFailurePolicySpec
+	requirements   []metav1.LabelSelectorRequirement
+}
+
+// New returns a Reconciler for spec. Without failure policies it restores the
+// webhooks split by previous configurations.
+func New(client kubernetes.Interface, spec kritisv1beta1.WebhookFailurePoliciesSpec) (*Reconciler, error) {
+	r := &Reconciler{
+		client:         client,
+		configurations: spec.ValidatingWebhooks,
+		namespaces:     spec.Namespaces,
+	}
+	if len(r.configurations) == 0 {
+		r.configurations = defaultValidatingWebhooks
+	}
+	names := map[string]bool{}
+	for _, n := range spec.Namespaces {
+		if n.Name == "" {
+			return nil, fmt.Errorf("webhook failure policy without name")
+		}
+		if names[n.Name] {
+			return nil, fmt.Errorf("duplicate webhook failure policy %q", n.Name)
+		}
+		names[n.Name] = true
+		if p := admissionv1beta1.FailurePolicyType(n.FailurePolicy); p != admissionv1beta1.Fail && p != admissionv1beta1.Ignore {
+			return nil, fmt.Errorf("invalid failurePolicy %q of %q, must be %s or %s", n.FailurePolicy, n.Name, admissionv1beta1.Fail, admissionv1beta1.Ignore)
+		}
+		req, err := requirement(n.NamespaceSelector)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid namespaceSelector of %q", n.Name)
+		}
+		r.requirements = append(r.requirements, req)
+	}
+	return r, nil
+}
+
+// requirement returns the only requirement of sel. Selectors are limited to a
+// single requirement so that the namespaces they select can be excluded from other webhooks.
+func requirement(sel *metav1.LabelSelector) (metav1.LabelSelectorRequirement, error) {
+	if sel == nil || len(sel.MatchLabels)+len(sel.MatchExpressions) != 1 {
+		return metav1.LabelSelectorRequirement{}, fmt.Errorf("must have exactly one requirement")
+	}
+	if _, err := metav1.LabelSelectorAsSelector(sel); err != nil {
+		return metav1.LabelSelectorRequirement{}, err
+	}
+	for k, v := range sel.MatchLabels {
+		return metav1.LabelSelectorRequirement{Key: k, Operator: metav1.LabelSelectorOpIn, Values: []string{v}}, nil
+	}
+	return sel.MatchExpressions[0], nil
+}
+
+// negate returns the requirement matching the namespaces req does not match.
+func negate(req metav1.LabelSelectorRequirement) metav1.LabelSelectorRequirement {
+	n := *req.DeepCopy()
+	switch req.Operator {
+	case metav1.LabelSelectorOpIn:
+		n.Operator = metav1.LabelSelectorOpNotIn
+	case metav1.LabelSelectorOpNotIn:
+		n.Operator = metav1.LabelSelectorOpIn
+	case metav1.LabelSelectorOpExists:
+		n.Operator = metav1.LabelSelectorOpDoesNotExist
+	case metav1.LabelSelectorOpDoesNotExist:
+		n.Operator = metav1.LabelSelectorOpExists
+	}
+	return n
+}
+
+// Start reconciles the webhook configurations in the background, every
+// ReconcileInterval until stopCh is closed.
+func (r *Reconciler) Start(stopCh <-chan struct{}) {
+	go wait.Until(func() {
+		if err := r.Reconcile(); err != nil {
+			glog.Errorf("failed to reconcile the webhook failure policies: %v", err)
+		}
+	}, ReconcileInterval, stopCh)
+}
+
+// Reconcile splits each webhook of the configurations into one webhook per failure
+// policy, in order, followed by the webhook for the other namespaces.
+// Configurations which do not exist are skipped.
+func (r *Reconciler) Reconcile() error {
+	admission := r.client.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations()
+	for _, name := range r.configurations {
+		c, err := admission.Get(name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			glog.V(2).Infof("skipping failure policies of missing ValidatingWebhookConfiguration %s", name)
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to get ValidatingWebhookConfiguration %s", name)
+		}
+		updated, err := r.reconcile(c.DeepCopy())
+		if err != nil {
+			return errors.Wrapf(err, "invalid ValidatingWebhookConfiguration %s", name)
+		}
+		if reflect.DeepEqual(updated, c) {
+			continue
+		}
+		if _, err := admission.Update(updated); err != nil {
+			return errors.Wrapf(err, "failed to update ValidatingWebhookConfiguration %s", name)
+		}
+		glog.Infof("updated failure policies of ValidatingWebhookConfiguration %s", name)
+	}
+	return nil
+}
+
+// reconcile returns c with the webhooks split from its template.
+func (r *Reconciler) reconcile(c *admissionv1beta1.ValidatingWebhookConfiguration) (*admissionv1beta1.ValidatingWebhookConfiguration, error) {
+	template := c.Webhooks
+	if t, ok := c.Annotations[TemplateAnnotation]; ok {
+		template = nil
+		if err := json.Unmarshal([]byte(t), &template); err != nil {
+			return nil, errors.Wrap(err, "invalid webhook template")
+		}
+	}
+	if len(r.namespaces) == 0 {
+		if _, ok := c.Annotations[TemplateAnnotation]; ok {
+			c.Webhooks = withClientConfigs(template, c.Webhooks)
+			delete(c.Annotations, TemplateAnnotation)
+		}
+		return c, nil
+	}
+	b, err := json.Marshal(template)
+	if err != nil {
+		return nil, err
+	}
+	if c.Annotations == nil {
+		c.Annotations = map[string]string{}
+	}
+	c.Annotations[TemplateAnnotation] = string(b)
+	var webhooks []admissionv1beta1.Webhook
+	for _, w := range template {
+		var excluded []metav1.LabelSelectorRequirement
+		for i, n := range r.namespaces {
+			split := *w.DeepCopy()
+			split.Name = n.Name + "." + w.Name
+			policy := admissionv1beta1.FailurePolicyType(n.FailurePolicy)
+			split.FailurePolicy = &policy
+			split.NamespaceSelector = withRequirements(w.NamespaceSelector, append(excluded, r.requirements[i]))
+			webhooks = append(webhooks, split)
+			excluded = append(excluded, negate(r.requirements[i]))
+		}
+		rest := *w.DeepCopy()
+		rest.NamespaceSelector = withRequirements(w.NamespaceSelector, excluded)
+		webhooks = append(webhooks, rest)
+	}
+	c.Webhooks = withClientConfigs(webhooks, c.Webhooks)
+	return c, nil
+}
+
+// withRequirements returns sel with the requirements added to its expressions.
+func withRequirements(sel *metav1.LabelSelector, reqs []metav1.LabelSelectorRequirement) *metav1.LabelSelector {
+	s := &metav1.LabelSelector{}
+	if sel != nil {
+		s = sel.DeepCopy()
+	}
+	for _, req := range reqs {
+		s.MatchExpressions = append(s.MatchExpressions, *req.DeepCopy())
+	}
+	return s
+}
+
+// withClientConfigs sets the caBundle of webhooks to the one of the current webhooks,
+// since it is rotated by the webhook certificate manager after the template is recorded.
+func withClientConfigs(webhooks []admissionv1beta1.Webhook, current []admissionv1beta1.Webhook) []admissionv1beta1.Webhook {
+	if len(current) == 0 {
+		return webhooks
+	}
+	for i := range webhooks {
+		webhooks[i].ClientConfig.CABundle = current[0].ClientConfig.CABundle
+	}
+	return webhooks
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2018 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhookpolicy
+
+import (
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	kritisv1beta1 "github.com/grafeas/kritis/pkg/kritis/apis/kritis/v1beta1"
+	"github.com/grafeas/kritis/pkg/kritis/testutil"
+)
+
+var (
+	fail    = admissionv1beta1.Fail
+	disable = metav1.LabelSelectorRequirement{Key: "kritis-validation", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"disabled"}}
+	dev     = metav1.LabelSelectorRequirement{Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"dev"}}
+	notDev  = metav1.LabelSelectorRequirement{Key: "env", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"dev"}}
+	pci     = metav1.LabelSelectorRequirement{Key: "pci", Operator: metav1.LabelSelectorOpExists}
+	notPCI  = metav1.LabelSelectorRequirement{Key: "pci", Operator: metav1.LabelSelectorOpDoesNotExist}
+)
+
+func webhookConfig(name string, caBundle string) *admissionv1beta1.ValidatingWebhookConfiguration {
+	return &admissionv1beta1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Webhooks: []admissionv1beta1.Webhook{{
+			Name:              name + ".grafeas.io",
+			FailurePolicy:     &fail,
+			NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{disable}},
+			ClientConfig:      admissionv1beta1.WebhookClientConfig{CABundle: []byte(caBundle)},
+		}},
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		policies  []kritisv1beta1.NamespaceFailurePolicySpec
+		shouldErr bool
+	}{
+		{"no policies", nil, false},
+		{"valid", []kritisv1beta1.NamespaceFailurePolicySpec{
+			{Name: "dev", FailurePolicy: "Ignore", NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}},
+		}, false},
+		{"missing name", []kritisv1beta1.NamespaceFailurePolicySpec{
+			{FailurePolicy: "Ignore", NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}},
+		}, true},
+		{"duplicate name", []kritisv1beta1.NamespaceFailurePolicySpec{
+			{Name: "dev", FailurePolicy: "Ignore", NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}},
+			{Name: "dev", FailurePolicy: "Fail", NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}},
+		}, true},
+		{"unknown failure policy", []kritisv1beta1.NamespaceFailurePolicySpec{
+			{Name: "dev", FailurePolicy: "Allow", NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}},
+		}, true},
+		{"two requirements", []kritisv1beta1.NamespaceFailurePolicySpec{
+			{Name: "dev", FailurePolicy: "Ignore", NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels:      map[string]string{"env": "dev"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{pci},
+			}},
+		}, true},
+		{"invalid operator", []kritisv1beta1.NamespaceFailurePolicySpec{
+			{Name: "dev", FailurePolicy: "Ignore", NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Bogus"}},
+			}},
+		}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := New(fake.NewSimpleClientset(), kritisv1beta1.WebhookFailurePoliciesSpec{Namespaces: test.policies})
+			testutil.CheckError(t, test.shouldErr, err)
+		})
+	}
+}
+
+// summary returns the name, failure policy and namespace requirements of each webhook.
+func summary(c *admissionv1beta1.ValidatingWebhookConfiguration) [][]interface{} {
+	var s [][]interface{}
+	for _, w := range c.Webhooks {
+		s = append(s, []interface{}{w.Name, string(*w.FailurePolicy), w.NamespaceSelector.MatchExpressions, string(w.ClientConfig.CABundle)})
+	}
+	return s
+}
+
+func TestReconcile(t *testing.T) {
+	client := fake.NewSimpleClientset(webhookConfig("kritis-validation-hook", "ca1"))
+	get := func() *admissionv1beta1.ValidatingWebhookConfiguration {
+		c, err := client.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Get("kritis-validation-hook", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		return c
+	}
+	reconcile := func(policies []kritisv1beta1.NamespaceFailurePolicySpec) {
+		r, err := New(client, kritisv1beta1.WebhookFailurePoliciesSpec{Namespaces: policies})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if err := r.Reconcile(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	policies := []kritisv1beta1.NamespaceFailurePolicySpec{
+		{Name: "dev", FailurePolicy: "Ignore", NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}},
+		{Name: "pci", FailurePolicy: "Fail", NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{pci}}},
+	}
+	reconcile(policies)
+	split := [][]interface{}{
+		{"dev.kritis-validation-hook.grafeas.io", "Ignore", []metav1.LabelSelectorRequirement{disable, dev}, "ca1"},
+		{"pci.kritis-validation-hook.grafeas.io", "Fail", []metav1.LabelSelectorRequirement{disable, notDev, pci}, "ca1"},
+		{"kritis-validation-hook.grafeas.io", "Fail", []metav1.LabelSelectorRequirement{disable, notDev, notPCI}, "ca1"},
+	}
+	testutil.DeepEqual(t, split, summary(get()))
+
+	// Reconciling again splits the template, not the split webhooks, and keeps the rotated caBundle.
+	c := get()
+	for i := range c.Webhooks {
+		c.Webhooks[i].ClientConfig.CABundle = []byte("ca2")
+	}
+	if _, err := client.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Update(c); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	reconcile(policies)
+	for i := range split {
+		split[i][3] = "ca2"
+	}
+	testutil.DeepEqual(t, split, summary(get()))
+
+	// Without policies the template is restored.
+	reconcile(nil)
+	c = get()
+	testutil.DeepEqual(t, [][]interface{}{
+		{"kritis-validation-hook.grafeas.io", "Fail", []metav1.LabelSelectorRequirement{disable}, "ca2"},
+	}, summary(c))
+	if _, ok := c.Annotations[TemplateAnnotation]; ok {
+		t.Errorf("expected the template annotation to be removed")
+	}
+}
+
+func TestReconcileMissingConfiguration(t *testing.T) {
+	r, err := New(fake.NewSimpleClientset(), kritisv1beta1.WebhookFailurePoliciesSpec{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	testutil.CheckError(t, false, r.Reconcile())
+}